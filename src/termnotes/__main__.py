@@ -4,32 +4,760 @@ Entry point for termnotes editor
 
 import sys
 import argparse
+import platform
+import shutil
+import signal
+import tempfile
+from datetime import timedelta
+from pathlib import Path
 from .ui import EditorUI
-from .config import get_example_config
+from .config import get_config, get_example_config
+from .importers import ATTACHING_IMPORTERS, IMPORTERS
+from .archive import write_archive
+from .backup import BackupError, read_backup, write_backup
+from .calendar import write_ics
+from .keymap import KeyMap
+from .plugins import PluginManager, load_plugins
+from .reminders import format_time
+from .export import FORMATS, export_notes
+from .export.site import publish_site
+from .joplin import write_jex
+from .note_list import SORT_ORDERS
+from .notebook import Notebook, get_note_notebook
+from .storage import (
+    StorageLock, create_default_storage, find_sync_backend, open_storage, prompt_sync_passphrase, storage_lock_path,
+)
+from .sync.protocol import SyncError
+from .sync.server import serve
+from .feed import FeedSettings
+from .mcp import McpScope, McpServer
+from .daemon import run_daemon, stop_daemon
+from .credentials import SECRETS, SecretsError, delete_secret, get_secret, keyring_available, set_secret
+from .i18n import t
+from .log import event, get_logger, setup_logging
 from . import __version__
 
+log = get_logger("main")
+
+
+def add_note(args) -> int:
+    """
+    Create a note from stdin, or append stdin to an existing note
+
+    Args:
+        args: Parsed "add" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    if sys.stdin.isatty():
+        print(t("cli.add_no_input"), file=sys.stderr)
+        return 1
+    content = sys.stdin.read()
+
+    lock = StorageLock(storage_lock_path())
+    try:
+        storage = open_storage(lock)
+    except RuntimeError as e:
+        # An editor has the notes open
+        print(e, file=sys.stderr)
+        return 1
+    try:
+        action = "save" if args.append else "create"
+        if args.append:
+            note = storage.get_note(args.append)
+            if note is None:
+                print(t("cli.add_note_not_found", note_id=args.append), file=sys.stderr)
+                return 1
+            # Start the appended text on its own line
+            if note.content and not note.content.endswith("\n"):
+                note.content += "\n"
+            note.content += content
+        else:
+            note = storage.create_note()
+            note.content = f"# {args.title}\n\n{content}" if args.title else content
+
+        with storage.journal_operation(action, [note.id]):
+            storage.save_note(note)
+        print(note.id)
+        return 0
+    finally:
+        storage.close()
+        lock.release()
+        wait_for_hooks(storage)
+
+
+def run_mcp(args) -> int:
+    """
+    Answer MCP requests on stdin and stdout until stdin closes
+
+    Args:
+        args: Parsed "mcp" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    config = get_config()
+    scope = McpScope(
+        write=(args.write or config.mcp_write) and not config.storage_read_only,
+        notebooks=args.notebook if args.notebook else config.mcp_notebooks,
+    )
+    storage = open_storage()
+    try:
+        McpServer(storage, scope).run()
+    except KeyboardInterrupt:
+        pass
+    finally:
+        storage.close()
+        wait_for_hooks(storage)
+    return 0
+
+
+def daemon(args) -> int:
+    """
+    Run the daemon holding the notes for editors and commands, or stop it
+
+    Args:
+        args: Parsed "daemon" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    if args.stop:
+        if not stop_daemon():
+            print(t("daemon.not_running"), file=sys.stderr)
+            return 1
+        print(t("daemon.stopped"))
+        return 0
+    try:
+        return run_daemon(args.idle_timeout)
+    except RuntimeError as e:
+        # Another editor has the notes open, or the storage is misconfigured
+        print(e, file=sys.stderr)
+        return 1
+
+
+def e2e(args) -> int:
+    """
+    List the devices sharing end-to-end encrypted sync, rotate its key, or revoke a device
+
+    Opens the notes itself, as the key change re-encrypts them, so no editor
+    or daemon may have them open.
+
+    Args:
+        args: Parsed "e2e" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    if args.action == "revoke" and not args.device:
+        print(t("cli.e2e_no_device"), file=sys.stderr)
+        return 1
+    lock = StorageLock(storage_lock_path())
+    try:
+        lock.acquire()
+    except RuntimeError as e:
+        print(e, file=sys.stderr)
+        return 1
+    storage = None
+    try:
+        storage = create_default_storage()
+        sync = find_sync_backend(storage)
+        if sync is None or sync.keys is None:
+            print(t("storage.e2e_off"), file=sys.stderr)
+            return 1
+        if args.action == "devices":
+            for device in sync.list_devices():
+                marker = "*" if device["current"] else " "
+                print(f"{marker} {device['id']}  {device['added_at'][:10]}  {device['name']}")
+            return 0
+        passphrase = prompt_sync_passphrase(False)
+        if not passphrase:
+            return 1
+        if args.action == "rotate":
+            sync.rotate_key(passphrase)
+            print(t("cli.e2e_rotated"))
+        else:
+            # The revoked device knows the passphrase, so it's changed too
+            print(t("cli.e2e_change_passphrase"))
+            new_passphrase = prompt_sync_passphrase(True)
+            if not new_passphrase:
+                return 1
+            sync.revoke_device(args.device, passphrase, new_passphrase)
+            print(t("cli.e2e_revoked", device=args.device))
+        return 0
+    except SyncError as e:
+        print(e, file=sys.stderr)
+        return 1
+    finally:
+        if storage is not None:
+            storage.close()
+        lock.release()
+
+
+def interrupt_on_signals():
+    """
+    Treat SIGTERM and SIGHUP (the terminal closing) like Ctrl+C
+
+    Commands then run their cleanup, closing the storage so it writes the
+    changes it's holding back, instead of the process dying with them. The
+    editor and the daemon handle these signals themselves while they run.
+    """
+    def interrupt(signum, frame):
+        event(log, "signal", signal=signal.Signals(signum).name)
+        raise KeyboardInterrupt()
+
+    for name in ("SIGTERM", "SIGHUP"):
+        if hasattr(signal, name):  # No SIGHUP on Windows
+            signal.signal(getattr(signal, name), interrupt)
+
+
+def wait_for_hooks(storage):
+    """Let the hooks run by a command finish before exiting"""
+    if storage.hooks:
+        storage.hooks.wait()
+
+
+def secret(args) -> int:
+    """
+    List the secrets in the system keyring, or store or remove one
+
+    Args:
+        args: Parsed "secret" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    if not keyring_available():
+        print(t("secret.no_keyring"), file=sys.stderr)
+        return 1
+    if args.action == "list":
+        for name, description in SECRETS.items():
+            marker = "*" if get_secret(name) else " "
+            print(f"{marker} {name:<11} {t(description)}")
+        return 0
+    if not args.name:
+        print(t("cli.secret_no_name"), file=sys.stderr)
+        return 1
+    try:
+        if args.action == "delete":
+            if not delete_secret(args.name):
+                print(t("cli.secret_not_stored", name=args.name), file=sys.stderr)
+                return 1
+            print(t("cli.secret_deleted", name=args.name))
+            return 0
+        if sys.stdin.isatty():
+            from prompt_toolkit import prompt
+            try:
+                value = prompt(t("cli.secret_prompt", name=args.name), is_password=True)
+            except (EOFError, KeyboardInterrupt):
+                return 1
+        else:
+            # Piped in, e.g. from a password manager
+            value = sys.stdin.readline().rstrip("\r\n")
+        if not value:
+            print(t("cli.secret_empty"), file=sys.stderr)
+            return 1
+        set_secret(args.name, value)
+        print(t("cli.secret_stored", name=args.name))
+        return 0
+    except SecretsError as e:
+        print(e, file=sys.stderr)
+        return 1
+
+
+def export(args) -> int:
+    """
+    Export notes to files
+
+    Args:
+        args: Parsed "export" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    storage = open_storage()
+    try:
+        if args.note:
+            notes = []
+            for note_id in args.note:
+                note = storage.get_note(note_id)
+                if note is None:
+                    print(t("cli.add_note_not_found", note_id=note_id), file=sys.stderr)
+                    return 1
+                notes.append(note)
+        elif args.archive:
+            notes = storage.get_all_notes()  # Trashed notes too, to move everything
+        else:
+            notes = [note for note in storage.get_all_notes() if not note.is_trashed]
+
+        try:
+            if args.archive:
+                paths = [write_archive(notes, storage.attachments_dir, args.archive)]
+            elif args.jex:
+                paths = [write_jex(notes, storage.attachments_dir, args.jex)]
+            elif args.ics:
+                paths = [write_ics(notes, args.ics)]
+            else:
+                paths = export_notes(notes, args.output, args.format)
+        except OSError as e:
+            print(t("cli.export_failed", error=e), file=sys.stderr)
+            return 1
+        for path in paths:
+            print(path)
+        return 0
+    finally:
+        storage.close()
+
+
+def publish(args) -> int:
+    """
+    Publish notes as a static HTML site
+
+    Args:
+        args: Parsed "publish" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    notebooks = [Notebook.normalize_path(notebook) for notebook in args.notebook or []]
+    storage = open_storage()
+    try:
+        # Secure notes are never published
+        notes = [note for note in storage.get_all_notes() if not note.is_trashed and not note.is_secure]
+    finally:
+        storage.close()
+    if args.note or notebooks or args.tag:
+        missing = [note_id for note_id in args.note or [] if note_id not in {note.id for note in notes}]
+        if missing:
+            print(t("cli.add_note_not_found", note_id=missing[0]), file=sys.stderr)
+            return 1
+        notes = [note for note in notes
+                 if note.id in (args.note or [])
+                 or any(tag for tag in args.tag or [] if note.has_tag(tag))
+                 or any(notebook in Notebook.ancestor_paths(get_note_notebook(note)) for notebook in notebooks)]
+
+    try:
+        paths = publish_site(notes, args.out, args.title or "")
+    except OSError as e:
+        print(t("cli.export_failed", error=e), file=sys.stderr)
+        return 1
+    print(t("cli.publish_done", count=len(paths) - 2, path=paths[0]))
+    return 0
+
+
+def import_notes(args) -> int:
+    """
+    Import notes from another app's files
+
+    Args:
+        args: Parsed "import" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    lock = StorageLock(storage_lock_path())
+    try:
+        storage = open_storage(lock)
+    except RuntimeError as e:
+        print(e, file=sys.stderr)
+        return 1
+    try:
+        # Attached files are copied aside while reading, and only kept for the notes stored
+        with tempfile.TemporaryDirectory(prefix="termnotes-import-") as staging:
+            options = {"attachments_dir": Path(staging)} if args.source in ATTACHING_IMPORTERS else {}
+            try:
+                notes = IMPORTERS[args.source](args.path, notebook=args.notebook or "", **options)
+            except (OSError, ValueError) as e:
+                print(t("cli.import_failed", error=e), file=sys.stderr)
+                return 1
+
+            # Notes imported before keep what has been edited in them since
+            existing = {note.id for note in storage.get_all_notes()}
+            skipped = sum(1 for note in notes if note.id in existing)
+            notes = [note for note in notes if note.id not in existing]
+            with storage.journal_operation("import", [note.id for note in notes]):
+                for note in notes:
+                    staged = Path(staging) / note.id
+                    if staged.is_dir():
+                        shutil.copytree(staged, storage.attachment_path(note.id, ""), dirs_exist_ok=True)
+                count = storage.import_notes(notes)
+    finally:
+        storage.close()
+        lock.release()
+        wait_for_hooks(storage)
+    print(t("cli.import_done", count=count, path=args.path))
+    if skipped:
+        print(t("cli.import_skipped", count=skipped))
+    return 0
+
+
+def backup(args) -> int:
+    """
+    Write a backup of every note and attached file
+
+    Args:
+        args: Parsed "backup" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    storage = open_storage()
+    try:
+        path = write_backup(storage.get_all_notes(), storage.attachments_dir,
+                            args.output or get_config().backup_directory)
+    except OSError as e:
+        print(t("cli.backup_failed", error=e), file=sys.stderr)
+        return 1
+    finally:
+        storage.close()
+    print(path)
+    return 0
+
+
+def restore(args) -> int:
+    """
+    Store the notes from a backup back, replacing notes with the same IDs
+
+    Args:
+        args: Parsed "restore" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    lock = StorageLock(storage_lock_path())
+    try:
+        storage = open_storage(lock)
+    except RuntimeError as e:
+        print(e, file=sys.stderr)
+        return 1
+    try:
+        try:
+            notes = read_backup(args.path)
+        except (OSError, BackupError) as e:
+            print(t("cli.restore_failed", error=e), file=sys.stderr)
+            return 1
+        # The automatic backup of the notes as they are is written first
+        with storage.journal_operation("restore_backup", [note.id for note in notes]):
+            read_backup(args.path, storage.attachments_dir)
+            count = storage.import_notes(notes)
+    finally:
+        storage.close()
+        lock.release()
+        wait_for_hooks(storage)
+    print(t("cli.restore_done", count=count, path=args.path))
+    return 0
+
+
+def show_stats(args) -> int:
+    """
+    Print statistics about all notes
+
+    Args:
+        args: Parsed "stats" subcommand arguments
+
+    Returns:
+        Process exit code
+    """
+    storage = open_storage()
+    try:
+        stats = storage.stats()
+    finally:
+        storage.close()
+
+    print(t("cli.stats_notes", count=stats.notes, archived=stats.archived, trashed=stats.trashed))
+    print(t("cli.stats_words", words=stats.words, minutes=stats.reading_minutes))
+    print(t("cli.stats_characters", characters=stats.characters))
+
+    if stats.tags:
+        print()
+        print(t("cli.stats_tags"))
+        width = max(len(tag) for tag in stats.tags) + 1
+        for tag, count in stats.tags.items():
+            print(f"  {'#' + tag:<{width}}  {count:>5}")
+
+    if stats.created_per_month:
+        print()
+        print(t("cli.stats_growth"))
+        # Bars scaled to the busiest month
+        busiest = max(stats.created_per_month.values())
+        total = 0
+        for month, count in stats.created_per_month.items():
+            total += count
+            bar = "#" * max(1, round(count * 30 / busiest))
+            print(f"  {month}  {count:>5}  ({total:>5})  {bar}")
+    return 0
+
+
+def show_agenda(args) -> int:
+    """
+    Print the overdue notes and the notes coming due
+
+    Args:
+        args: Parsed "agenda" subcommand arguments
+
+    Returns:
+        Process exit code (1 if any note is overdue, for scripts)
+    """
+    days = args.days if args.days is not None else get_config().reminders_upcoming_days
+    storage = open_storage()
+    try:
+        overdue = storage.overdue_notes()
+        upcoming = storage.upcoming_notes(timedelta(days=days))
+    finally:
+        storage.close()
+
+    if not overdue and not upcoming:
+        print(t("cli.agenda_empty", days=days))
+        return 0
+    for heading, notes in ((t("cli.agenda_overdue"), overdue), (t("cli.agenda_upcoming", days=days), upcoming)):
+        if not notes:
+            continue
+        print(heading)
+        for note in notes:
+            print(f"  {format_time(note.due_at)}  {note.title or t('note.empty_preview')}  ({note.id})")
+    return 1 if overdue else 0
+
 
 def main():
     """Main entry point for the editor"""
-    parser = argparse.ArgumentParser(description="A vim-like terminal note-taking application")
+    parser = argparse.ArgumentParser(description=t("cli.description"))
     parser.add_argument("--version", action="version", version=f"termnotes {__version__}")
     parser.add_argument("--print-config", action="store_true",
-                       help="Print example configuration and exit")
+                       help=t("cli.print_config_help"))
+    parser.add_argument("--print-keys", action="store_true",
+                       help=t("cli.print_keys_help"))
+    parser.add_argument("--accessible", action="store_true", default=None,
+                       help=t("cli.accessible_help"))
+    parser.add_argument("--no-alt-screen", dest="alt_screen", action="store_false", default=None,
+                       help=t("cli.no_alt_screen_help"))
+    parser.add_argument("--backend",
+                       choices=["sqlite", "gdrive", "filesystem", "markdown", "git", "sync", "webdav", "postgres", "encrypted"],
+                       help=t("cli.backend_help"))
+    parser.add_argument("--notes-path", metavar="PATH", help=t("cli.notes_path_help"))
+    parser.add_argument("--theme", help=t("cli.theme_help"))
+    parser.add_argument("--colors", help=t("cli.colors_help"))
+    parser.add_argument("--sidebar-width", type=float, metavar="WIDTH",
+                       help=t("cli.sidebar_width_help"))
+    parser.add_argument("--sort", choices=SORT_ORDERS, help=t("cli.sort_help"))
+    parser.add_argument("--debug", action="store_true", help=t("cli.debug_help"))
+    parser.add_argument("--seed-demo", action="store_true", help=t("cli.seed_demo_help"))
+    parser.add_argument("--readonly", action="store_true", help=t("cli.readonly_help"))
+
+    subparsers = parser.add_subparsers(dest="command")
+    add_parser = subparsers.add_parser("add", help=t("cli.add_help"),
+                                       description=t("cli.add_description"))
+    add_target = add_parser.add_mutually_exclusive_group()
+    add_target.add_argument("--title", help=t("cli.add_title_help"))
+    add_target.add_argument("--append", metavar="ID", help=t("cli.add_append_help"))
+
+    serve_parser = subparsers.add_parser("serve", help=t("cli.serve_help"),
+                                         description=t("cli.serve_description"))
+    serve_parser.add_argument("--host", help=t("cli.serve_host_help"))
+    serve_parser.add_argument("--port", type=int, help=t("cli.serve_port_help"))
+    serve_parser.add_argument("--token", help=t("cli.serve_token_help"))
+    serve_parser.add_argument("--feed", action="store_true", help=t("cli.serve_feed_help"))
+    serve_parser.add_argument("--grpc", action="store_true", help=t("cli.serve_grpc_help"))
+    serve_parser.add_argument("--grpc-port", type=int, help=t("cli.serve_grpc_port_help"))
+
+    mcp_parser = subparsers.add_parser("mcp", help=t("cli.mcp_help"),
+                                       description=t("cli.mcp_description"))
+    mcp_parser.add_argument("--write", action="store_true", help=t("cli.mcp_write_help"))
+    mcp_parser.add_argument("--notebook", action="append", help=t("cli.mcp_notebook_help"))
+
+    daemon_parser = subparsers.add_parser("daemon", help=t("cli.daemon_help"),
+                                          description=t("cli.daemon_description"))
+    daemon_parser.add_argument("--stop", action="store_true", help=t("cli.daemon_stop_help"))
+    daemon_parser.add_argument("--idle-timeout", type=float, help=t("cli.daemon_idle_timeout_help"))
+
+    e2e_parser = subparsers.add_parser("e2e", help=t("cli.e2e_help"),
+                                       description=t("cli.e2e_description"))
+    e2e_parser.add_argument("action", choices=["devices", "rotate", "revoke"], help=t("cli.e2e_action_help"))
+    e2e_parser.add_argument("device", nargs="?", metavar="DEVICE", help=t("cli.e2e_device_help"))
+
+    secret_parser = subparsers.add_parser("secret", help=t("cli.secret_help"),
+                                          description=t("cli.secret_description"))
+    secret_parser.add_argument("action", choices=["list", "set", "delete"], help=t("cli.secret_action_help"))
+    secret_parser.add_argument("name", nargs="?", choices=list(SECRETS), metavar="NAME",
+                               help=t("cli.secret_name_help", names=", ".join(SECRETS)))
+
+    export_parser = subparsers.add_parser("export", help=t("cli.export_help"),
+                                          description=t("cli.export_description"))
+    export_parser.add_argument("--format", choices=list(FORMATS), default="md",
+                               help=t("cli.export_format_help"))
+    export_parser.add_argument("--note", metavar="ID", action="append",
+                               help=t("cli.export_note_help"))
+    export_parser.add_argument("-o", "--output", metavar="DIR", default=".",
+                               help=t("cli.export_output_help"))
+    export_file = export_parser.add_mutually_exclusive_group()
+    export_file.add_argument("--archive", metavar="FILE", help=t("cli.export_archive_help"))
+    export_file.add_argument("--jex", metavar="FILE", help=t("cli.export_jex_help"))
+    export_file.add_argument("--ics", metavar="FILE", help=t("cli.export_ics_help"))
+
+    publish_parser = subparsers.add_parser("publish", help=t("cli.publish_help"),
+                                           description=t("cli.publish_description"))
+    publish_parser.add_argument("--out", metavar="DIR", required=True, help=t("cli.publish_out_help"))
+    publish_parser.add_argument("--notebook", action="append", help=t("cli.publish_notebook_help"))
+    publish_parser.add_argument("--tag", action="append", help=t("cli.publish_tag_help"))
+    publish_parser.add_argument("--note", metavar="ID", action="append", help=t("cli.publish_note_help"))
+    publish_parser.add_argument("--title", help=t("cli.publish_title_help"))
+
+    import_parser = subparsers.add_parser("import", help=t("cli.import_help"),
+                                          description=t("cli.import_description"))
+    import_parser.add_argument("--from", dest="source", choices=list(IMPORTERS), required=True,
+                               help=t("cli.import_from_help"))
+    import_parser.add_argument("--notebook", help=t("cli.import_notebook_help"))
+    import_parser.add_argument("path", metavar="PATH", help=t("cli.import_path_help"))
+
+    backup_parser = subparsers.add_parser("backup", help=t("cli.backup_help"),
+                                          description=t("cli.backup_description"))
+    backup_parser.add_argument("-o", "--output", metavar="DIR", help=t("cli.backup_output_help"))
+
+    restore_parser = subparsers.add_parser("restore", help=t("cli.restore_help"),
+                                           description=t("cli.restore_description"))
+    restore_parser.add_argument("path", metavar="FILE", help=t("cli.restore_path_help"))
+
+    subparsers.add_parser("stats", help=t("cli.stats_help"), description=t("cli.stats_description"))
+
+    agenda_parser = subparsers.add_parser("agenda", help=t("cli.agenda_help"),
+                                          description=t("cli.agenda_description"))
+    agenda_parser.add_argument("--days", type=int, help=t("cli.agenda_days_help"))
 
     args = parser.parse_args()
 
+    # Command line flags override the config file
+    config = get_config()
+    if args.backend:
+        config.set("storage.backend", args.backend)
+    if args.notes_path:
+        config.set_notes_path(args.notes_path)
+    if args.theme:
+        config.set("ui.theme", args.theme)
+    if args.colors:
+        config.set("ui.colors", args.colors)
+    if args.sidebar_width:
+        config.set("ui.sidebar_width", args.sidebar_width)
+    if args.sort:
+        config.set("ui.sort", args.sort)
+    if args.debug:
+        config.set("debug.enabled", True)
+    if args.readonly:
+        config.set("storage.read_only", True)
+    if config.debug_enabled:
+        try:
+            log_path = setup_logging(config.debug_log_path)
+        except OSError as e:
+            print(t("cli.debug_log_failed", error=e), file=sys.stderr)
+        else:
+            event(log, "start", version=__version__, command=args.command or "edit",
+                  backend=config.storage_backend, python=platform.python_version(), platform=sys.platform)
+            if args.debug:
+                print(t("cli.debug_logging", path=log_path), file=sys.stderr)
+    interrupt_on_signals()
+
+    # "add", "import" and "restore" only change notes, as do key changes, which re-encrypt them
+    if ((args.command in ("add", "import", "restore") or args.command == "e2e" and args.action != "devices")
+            and config.storage_read_only):
+        print(t("storage.read_only"), file=sys.stderr)
+        sys.exit(1)
+
+    # Handle "add": read a note from stdin without starting the editor
+    if args.command == "add":
+        sys.exit(add_note(args))
+
+    # Handle "mcp": serve notes to AI assistants over stdio
+    if args.command == "mcp":
+        sys.exit(run_mcp(args))
+
+    # Handle "daemon": hold the notes for other editors and commands
+    if args.command == "daemon":
+        sys.exit(daemon(args))
+
+    # Handle "e2e": manage the keys of end-to-end encrypted sync
+    if args.command == "e2e":
+        sys.exit(e2e(args))
+
+    # Handle "secret": keep passwords and tokens in the system keyring
+    if args.command == "secret":
+        sys.exit(secret(args))
+
+    # Handle "export": write notes to files without starting the editor
+    if args.command == "export":
+        sys.exit(export(args))
+
+    # Handle "publish": write a static site without starting the editor
+    if args.command == "publish":
+        sys.exit(publish(args))
+
+    # Handle "import": read notes from another app's files
+    if args.command == "import":
+        sys.exit(import_notes(args))
+
+    # Handle "backup" and "restore": save every note to a file, or bring them back
+    if args.command == "backup":
+        sys.exit(backup(args))
+    if args.command == "restore":
+        sys.exit(restore(args))
+
+    # Handle "stats": print totals about the notes
+    if args.command == "stats":
+        sys.exit(show_stats(args))
+
+    # Handle "agenda": list notes that are overdue or coming due
+    if args.command == "agenda":
+        sys.exit(show_agenda(args))
+
+    # Handle "serve": run the sync server instead of the editor
+    if args.command == "serve":
+        grpc_port = args.grpc_port if args.grpc_port is not None else config.server_grpc_port
+        try:
+            serve(
+                config.server_path,
+                args.host or config.server_host,
+                args.port if args.port is not None else config.server_port,
+                args.token if args.token is not None else config.server_token or get_secret("server"),
+                config.server_request_timeout or None,
+                FeedSettings(config.server_feed_title, config.server_feed_tag, config.server_feed_limit,
+                             config.server_feed_public) if args.feed or config.server_feed else None,
+                grpc_port if args.grpc or args.grpc_port is not None or config.server_grpc else None
+            )
+        except RuntimeError as e:
+            # grpcio isn't installed, or the gRPC port is taken
+            print(e, file=sys.stderr)
+            sys.exit(1)
+        sys.exit(0)
+
     # Handle --print-config flag
     if args.print_config:
         print(get_example_config())
         sys.exit(0)
 
+    # Handle --print-keys flag
+    if args.print_keys:
+        plugins = load_plugins(config.plugins_directory) if config.plugins_enabled else PluginManager()
+        keymap = KeyMap(config.keys, plugins.key_actions(), read_only=config.storage_read_only)
+        for error in plugins.errors + keymap.errors:
+            print(error, file=sys.stderr)
+        for action, keys, description in keymap.help_lines():
+            print(f"{action:<16} {keys:<22} {description}")
+        sys.exit(0)
+
     # Create and run the editor
-    editor = EditorUI()
+    try:
+        editor = EditorUI(accessible=args.accessible, alt_screen=args.alt_screen, seed_demo=args.seed_demo)
+    except RuntimeError as e:
+        # Another editor has the notes open, or the storage is misconfigured
+        print(e, file=sys.stderr)
+        sys.exit(1)
     try:
         editor.run()
     except KeyboardInterrupt:
         # Clean exit on Ctrl+C
         pass
+    except Exception:
+        log.exception("crash")
+        raise
+    event(log, "exit")
 
 
 if __name__ == "__main__":
@@ -0,0 +1,295 @@
+"""
+Assistance from a language model: summaries, tag suggestions and titles
+
+Nothing is sent anywhere unless `[ai] provider` is set; it's "off" by
+default. The providers:
+
+- "openai": an OpenAI-compatible chat completions API (OpenAI itself, or
+  local servers such as llama.cpp, LM Studio or vLLM at another url). The
+  key comes from [ai] api_key, $OPENAI_API_KEY or the system keyring
+  (service "termnotes", user "openai").
+- "ollama": an Ollama server, local by default.
+- "command": a program run with the prompt on stdin, printing the answer
+  (e.g. `llm -m mistral`), for models termnotes can't talk to itself.
+
+:summarize, :suggesttags and :gentitle send the note in the editor to the
+provider; the answer is put in the note as an edit `u` undoes, or offered
+in the confirmation dialog first.
+"""
+
+import json
+import os
+import re
+import shlex
+import subprocess
+import urllib.error
+import urllib.request
+from abc import ABC, abstractmethod
+from typing import List, Optional
+from .credentials import get_secret
+from .note import Note
+from .i18n import t
+
+PROVIDERS = ("off", "openai", "ollama", "command")
+DEFAULT_URLS = {"openai": "https://api.openai.com/v1", "ollama": "http://localhost:11434"}
+DEFAULT_MODELS = {"openai": "gpt-4o-mini", "ollama": "llama3.2"}
+MAX_NOTE_CHARS = 12000  # Characters of the note sent; longer notes are cut
+MAX_TAGS = 5  # Most tags suggested at once
+
+SYSTEM_PROMPT = ("You help organize a personal markdown note. Answer with only what is asked for, "
+                 "in the language the note is written in, without any preamble.")
+SUMMARY_PROMPT = "Summarize this note in two or three sentences:\n\n{note}"
+TAGS_PROMPT = ("Suggest up to {count} short, lowercase tags for this note, as a comma-separated list. "
+               "Prefer these existing tags where they fit: {tags}\n\n{note}")
+TITLE_PROMPT = "Write a short title (at most eight words) for this note, as plain text:\n\n{note}"
+
+
+class AIError(Exception):
+    """The provider couldn't be reached or gave no usable answer"""
+
+
+class AIProvider(ABC):
+    """A language model that answers prompts"""
+
+    @abstractmethod
+    def complete(self, system: str, prompt: str) -> str:
+        """
+        Get the model's answer to a prompt
+
+        Args:
+            system: Instructions for how to answer
+            prompt: The request
+
+        Returns:
+            The answer text
+
+        Raises:
+            AIError: If there's no answer
+        """
+
+
+def _post_json(url: str, body: dict, timeout: float, token: str = "") -> dict:
+    """POST JSON to a URL, returning the decoded JSON answer"""
+    request = urllib.request.Request(url, data=json.dumps(body).encode("utf-8"), method="POST")
+    request.add_header("Content-Type", "application/json")
+    if token:
+        request.add_header("Authorization", f"Bearer {token}")
+    try:
+        with urllib.request.urlopen(request, timeout=timeout) as response:
+            return json.loads(response.read().decode("utf-8"))
+    except urllib.error.HTTPError as e:
+        raise AIError(f"{url}: HTTP {e.code} {e.reason}")
+    except (urllib.error.URLError, OSError) as e:
+        raise AIError(f"{url}: {getattr(e, 'reason', e)}")
+    except ValueError:
+        raise AIError(t("ai.bad_response", url=url))
+
+
+class OpenAIProvider(AIProvider):
+    """An OpenAI-compatible chat completions API"""
+
+    def __init__(self, url: str, model: str, api_key: str, timeout: float):
+        self.url = url.rstrip("/")
+        self.model = model
+        self.api_key = api_key
+        self.timeout = timeout
+
+    def complete(self, system: str, prompt: str) -> str:
+        """Ask the chat completions endpoint"""
+        url = f"{self.url}/chat/completions"
+        answer = _post_json(url, {
+            "model": self.model,
+            "messages": [{"role": "system", "content": system}, {"role": "user", "content": prompt}],
+        }, self.timeout, self.api_key)
+        try:
+            return str(answer["choices"][0]["message"]["content"]).strip()
+        except (KeyError, IndexError, TypeError):
+            raise AIError(t("ai.bad_response", url=url))
+
+
+class OllamaProvider(AIProvider):
+    """An Ollama server"""
+
+    def __init__(self, url: str, model: str, timeout: float):
+        self.url = url.rstrip("/")
+        self.model = model
+        self.timeout = timeout
+
+    def complete(self, system: str, prompt: str) -> str:
+        """Ask Ollama's chat endpoint for the whole answer at once"""
+        url = f"{self.url}/api/chat"
+        answer = _post_json(url, {
+            "model": self.model,
+            "stream": False,
+            "messages": [{"role": "system", "content": system}, {"role": "user", "content": prompt}],
+        }, self.timeout)
+        try:
+            return str(answer["message"]["content"]).strip()
+        except (KeyError, TypeError):
+            raise AIError(t("ai.bad_response", url=url))
+
+
+class CommandProvider(AIProvider):
+    """A program given the prompt on stdin that prints the answer"""
+
+    def __init__(self, command: str, timeout: float):
+        self.command = command
+        self.timeout = timeout
+
+    def complete(self, system: str, prompt: str) -> str:
+        """Run the command"""
+        try:
+            result = subprocess.run(shlex.split(self.command), input=f"{system}\n\n{prompt}",
+                                    capture_output=True, text=True, timeout=self.timeout)
+        except (OSError, ValueError, subprocess.TimeoutExpired) as e:
+            raise AIError(f"{self.command}: {e}")
+        if result.returncode != 0:
+            error = result.stderr.strip().split("\n")[-1] if result.stderr.strip() else f"exit status {result.returncode}"
+            raise AIError(f"{self.command}: {error}")
+        return result.stdout.strip()
+
+
+def create_provider(config) -> Optional[AIProvider]:
+    """
+    Create the provider set in [ai]
+
+    Args:
+        config: Config to read
+
+    Returns:
+        The provider, or None if it's off
+
+    Raises:
+        AIError: If the provider is unknown or missing a setting
+    """
+    name = config.ai_provider
+    if name == "off":
+        return None
+    if name not in PROVIDERS:
+        raise AIError(t("config.unknown_ai", provider=name))
+    timeout = config.ai_timeout or None
+    url = config.ai_url or DEFAULT_URLS.get(name, "")
+    model = config.ai_model or DEFAULT_MODELS.get(name, "")
+    if name == "openai":
+        return OpenAIProvider(url, model, config.ai_api_key or os.environ.get("OPENAI_API_KEY", "")
+                              or get_secret("openai"), timeout)
+    if name == "ollama":
+        return OllamaProvider(url, model, timeout)
+    if not config.ai_command:
+        raise AIError(t("ai.no_command"))
+    return CommandProvider(config.ai_command, timeout)
+
+
+def _note_text(note: Note) -> str:
+    """Get the text of a note to send, cut to MAX_NOTE_CHARS"""
+    text = note.content.strip()
+    if not text:
+        raise AIError(t("ai.empty_note"))
+    return text[:MAX_NOTE_CHARS]
+
+
+def _clean_line(text: str) -> str:
+    """Take the first line of an answer, without markdown or quotes around it"""
+    line = next((line for line in text.split("\n") if line.strip()), "")
+    return line.strip().lstrip("#").strip().strip("*_\"'`").strip()
+
+
+def summarize(provider: AIProvider, note: Note) -> str:
+    """
+    Summarize a note
+
+    Returns:
+        A few sentences on one line
+
+    Raises:
+        AIError: If the provider fails or answers with nothing
+    """
+    summary = " ".join(provider.complete(SYSTEM_PROMPT, SUMMARY_PROMPT.format(note=_note_text(note))).split())
+    if not summary:
+        raise AIError(t("ai.no_answer"))
+    return summary
+
+
+def suggest_tags(provider: AIProvider, note: Note, existing: List[str]) -> List[str]:
+    """
+    Suggest tags for a note
+
+    Args:
+        provider: Model to ask
+        note: Note to tag
+        existing: Tags already in use, which the model is asked to prefer
+
+    Returns:
+        Normalized tags the note doesn't have yet (may be empty)
+
+    Raises:
+        AIError: If the provider fails
+    """
+    prompt = TAGS_PROMPT.format(count=MAX_TAGS, tags=", ".join(existing[:100]) or "-", note=_note_text(note))
+    answer = provider.complete(SYSTEM_PROMPT, prompt)
+    tags = []
+    for part in re.split(r"[,\n]", answer):
+        tag = Note.normalize_tag(part.strip().strip("-*`\"'").strip())
+        tag = re.sub(r"\s+", "-", tag.lower())
+        if tag and len(tag) <= 40 and tag not in tags and not note.has_tag(tag):
+            tags.append(tag)
+    return tags[:MAX_TAGS]
+
+
+def suggest_title(provider: AIProvider, note: Note) -> str:
+    """
+    Suggest a title for a note
+
+    Returns:
+        The title as plain text
+
+    Raises:
+        AIError: If the provider fails or answers with nothing
+    """
+    title = _clean_line(provider.complete(SYSTEM_PROMPT, TITLE_PROMPT.format(note=_note_text(note))))
+    if not title:
+        raise AIError(t("ai.no_answer"))
+    return title
+
+
+def with_summary(content: str, summary: str) -> str:
+    """
+    Put a summary in a note, as a quote below its title
+
+    Args:
+        content: Note text
+        summary: Summary to add
+
+    Returns:
+        Note text with the summary
+    """
+    block = [f"> **{t('ai.summary_label')}** {summary}", ""]
+    lines = content.split("\n")
+    first = next((i for i, line in enumerate(lines) if line.strip()), None)
+    if first is None:
+        return "\n".join(block)
+    rest = lines[first + 1:]
+    while rest and not rest[0].strip():
+        rest.pop(0)
+    return "\n".join(lines[:first + 1] + [""] + block + rest)
+
+
+def with_title(content: str, title: str) -> str:
+    """
+    Give a note a title, replacing the heading it starts with or adding one
+
+    Args:
+        content: Note text
+        title: New title
+
+    Returns:
+        Note text with the title as its first heading
+    """
+    lines = content.split("\n")
+    first = next((i for i, line in enumerate(lines) if line.strip()), None)
+    if first is None:
+        return f"# {title}\n"
+    heading = re.match(r"\s*(#{1,6})\s", lines[first])
+    if heading:
+        return "\n".join(lines[:first] + [f"{heading.group(1)} {title}"] + lines[first + 1:])
+    return "\n".join(lines[:first] + [f"# {title}", ""] + lines[first:])
@@ -0,0 +1,173 @@
+"""
+App lock: a passphrase screen over the notes on launch and when left idle
+
+With `[lock] enabled`, the editor starts locked and locks again after
+`idle_minutes` without a key press (or on :lock), hiding every note until
+the passphrase is typed. With the encrypted backend the passphrase is the
+one that unlocks it, so there's nothing more to remember; otherwise it's a
+lock passphrase chosen on first use, of which only a salted PBKDF2 hash is
+kept in `[lock] file`.
+
+It keeps people at a shared or unattended laptop out of the editor; only
+the encrypted backend protects the notes on disk.
+"""
+
+import base64
+import hashlib
+import hmac
+import json
+import os
+import sys
+import time
+from pathlib import Path
+from typing import Callable, Optional
+from .i18n import t
+
+HASH_ITERATIONS = 600_000  # PBKDF2 iterations for new lock files
+SALT_SIZE = 16
+
+
+def _hash(passphrase: str, salt: bytes, iterations: int) -> bytes:
+    """Hash a passphrase with PBKDF2-HMAC-SHA256"""
+    return hashlib.pbkdf2_hmac("sha256", passphrase.encode("utf-8"), salt, iterations)
+
+
+def load_lock_file(path: Path) -> Optional[Callable[[str], bool]]:
+    """
+    Read the lock passphrase's hash
+
+    Args:
+        path: Lock file
+
+    Returns:
+        A function checking a passphrase against it, or None if there's no
+        lock passphrase yet
+    """
+    try:
+        with open(path, "r", encoding="utf-8") as f:
+            data = json.load(f)
+        salt = base64.b64decode(data["salt"])
+        expected = base64.b64decode(data["hash"])
+        iterations = int(data["iterations"])
+    except (OSError, ValueError, KeyError, TypeError):
+        return None
+    return lambda passphrase: hmac.compare_digest(_hash(passphrase, salt, iterations), expected)
+
+
+def save_lock_file(path: Path, passphrase: str):
+    """
+    Store a salted hash of the lock passphrase, readable only by the user
+
+    Args:
+        path: Lock file
+        passphrase: The new lock passphrase
+    """
+    salt = os.urandom(SALT_SIZE)
+    data = {
+        "salt": base64.b64encode(salt).decode("ascii"),
+        "iterations": HASH_ITERATIONS,
+        "hash": base64.b64encode(_hash(passphrase, salt, HASH_ITERATIONS)).decode("ascii"),
+    }
+    path.parent.mkdir(parents=True, exist_ok=True)
+    temp_path = path.with_suffix(".tmp")
+    fd = os.open(temp_path, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+    with os.fdopen(fd, "w", encoding="utf-8") as f:
+        json.dump(data, f)
+    temp_path.replace(path)
+
+
+def choose_lock_passphrase(path: Path, max_attempts: int = 3) -> Callable[[str], bool]:
+    """
+    Have the user choose the lock passphrase on the terminal, before the editor starts
+
+    Args:
+        path: Lock file to store it in
+        max_attempts: Number of tries before giving up
+
+    Returns:
+        A function checking a passphrase against the new one
+
+    Raises:
+        SystemExit: If no passphrase is chosen
+    """
+    from prompt_toolkit import prompt
+
+    print(t("lock.choose_intro"))
+    try:
+        for _ in range(max_attempts):
+            passphrase = prompt(t("lock.choose_passphrase"), is_password=True)
+            if not passphrase:
+                print(t("storage.passphrase_empty"))
+                continue
+            if prompt(t("storage.confirm_passphrase"), is_password=True) != passphrase:
+                print(t("storage.passphrase_mismatch"))
+                continue
+            save_lock_file(path, passphrase)
+            return load_lock_file(path)
+    except (EOFError, KeyboardInterrupt):
+        sys.exit(1)
+    sys.exit(t("lock.not_chosen"))
+
+
+class AppLock:
+    """State of the lock screen: whether it's shown, and what was typed into it"""
+
+    def __init__(self, verify: Callable[[str], bool], idle_timeout: float = 0, locked: bool = True):
+        """
+        Initialize the lock
+
+        Args:
+            verify: Checks a typed passphrase
+            idle_timeout: Seconds without a key press after which to lock (0 to only lock on launch and :lock)
+            locked: Whether to start locked
+        """
+        self.verify = verify
+        self.idle_timeout = idle_timeout
+        self.is_locked = locked
+        self.typed = ""
+        self.error = ""
+        self.last_activity = time.monotonic()
+
+    def touch(self):
+        """Note a key press, putting off the idle lock"""
+        self.last_activity = time.monotonic()
+
+    def is_idle(self) -> bool:
+        """Check whether the editor is unlocked but has been idle for idle_timeout"""
+        return (not self.is_locked and self.idle_timeout > 0
+                and time.monotonic() - self.last_activity >= self.idle_timeout)
+
+    def lock(self):
+        """Show the lock screen"""
+        self.is_locked = True
+        self.typed = ""
+        self.error = ""
+
+    def type(self, text: str):
+        """Add typed characters to the passphrase"""
+        self.typed += text
+        self.error = ""
+
+    def backspace(self):
+        """Remove the last character typed"""
+        self.typed = self.typed[:-1]
+
+    def clear(self):
+        """Remove everything typed"""
+        self.typed = ""
+
+    def submit(self) -> bool:
+        """
+        Check the typed passphrase, unlocking if it's right
+
+        Returns:
+            Whether the editor was unlocked
+        """
+        typed, self.typed = self.typed, ""
+        if typed and self.verify(typed):
+            self.is_locked = False
+            self.error = ""
+            self.touch()
+            return True
+        self.error = t("lock.wrong")
+        return False
@@ -0,0 +1,128 @@
+"""
+Portable archives: notes and their attached files in one .tnx file
+
+`termnotes export --archive notes.tnx` writes one, and
+`termnotes import --from archive notes.tnx` reads it into any backend, so
+notes can move between machines or from one backend to another.
+
+An archive is a zip file holding notes.json and the attached files as
+attachments/<note id>/<name>. notes.json has the archive's format and
+version, when and by which termnotes version it was written, and the notes
+encoded like the sync protocol does: content, timestamps, and properties,
+which hold tags, notebook, color, and the rest of a note's metadata.
+Unlike the other importers, reading an archive keeps the notes' IDs, so
+links between them still work and importing it again replaces the same
+notes instead of adding copies.
+"""
+
+import json
+import zipfile
+from pathlib import Path
+from typing import Iterable, List, Optional
+from .note import Note
+from .attachments import note_attachments
+from .backup import ATTACHMENTS_DIR, NOTES_FILE, attachment_target
+from .notebook import Notebook, get_note_notebook
+from .sync.protocol import note_from_dict, note_to_dict
+from .utils import utc_now
+from .i18n import t
+from .log import event, get_logger
+from . import __version__
+
+log = get_logger("archive")
+
+ARCHIVE_FORMAT = "termnotes-archive"  # Written to notes.json to tell an archive from other zip files
+ARCHIVE_VERSION = 1  # An archive from a newer version can't be read
+
+
+class ArchiveError(ValueError):
+    """A file isn't a termnotes archive, or is from a newer version"""
+
+
+def write_archive(notes: Iterable[Note], attachments_dir: Path, path: str) -> Path:
+    """
+    Write notes and their attached files to an archive
+
+    Args:
+        notes: Notes to write
+        attachments_dir: Where the attached files are kept (StorageBackend.attachments_dir)
+        path: Archive file to write (replaced if it exists)
+
+    Returns:
+        Path of the archive
+
+    Raises:
+        OSError: If the archive can't be written
+    """
+    notes = list(notes)
+    target = Path(path).expanduser()
+    target.parent.mkdir(parents=True, exist_ok=True)
+    tmp_path = target.with_name(f".{target.name}.tmp")
+    try:
+        with zipfile.ZipFile(tmp_path, "w", zipfile.ZIP_DEFLATED) as archive:
+            data = {
+                "format": ARCHIVE_FORMAT,
+                "version": ARCHIVE_VERSION,
+                "termnotes": __version__,
+                "exported_at": utc_now().isoformat(),
+                "notes": [note_to_dict(note) for note in notes],
+            }
+            archive.writestr(NOTES_FILE, json.dumps(data, ensure_ascii=False, indent=1))
+            for note in notes:
+                for attachment in note_attachments(note):
+                    file_path = Path(attachments_dir) / note.id / attachment.name
+                    if file_path.is_file():
+                        archive.write(file_path, f"{ATTACHMENTS_DIR}/{note.id}/{attachment.name}")
+        tmp_path.replace(target)
+    finally:
+        tmp_path.unlink(missing_ok=True)
+    event(log, "archive_written", notes=len(notes))
+    return target
+
+
+def read_archive(path: str, notebook: str = "", attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read the notes from an archive, copying its attached files
+
+    Args:
+        path: Archive file
+        notebook: Notebook to put the notes under, keeping their own
+            notebooks inside it ("" to keep them as they were)
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        The notes in the archive, with their IDs (not yet stored)
+
+    Raises:
+        OSError: If the file can't be read or the files can't be copied
+        ArchiveError: If the file isn't an archive termnotes can read
+    """
+    try:
+        with zipfile.ZipFile(Path(path).expanduser()) as archive:
+            try:
+                data = json.loads(archive.read(NOTES_FILE).decode("utf-8"))
+            except KeyError:
+                raise ArchiveError(t("archive.invalid", path=path))
+            if (not isinstance(data, dict) or data.get("format") != ARCHIVE_FORMAT
+                    or data.get("version", 0) > ARCHIVE_VERSION):
+                raise ArchiveError(t("archive.invalid", path=path))
+            notes = [note_from_dict(entry) for entry in data.get("notes", [])]
+
+            if attachments_dir is not None:
+                for member in archive.infolist():
+                    target = None if member.is_dir() else attachment_target(member.filename, attachments_dir)
+                    if target is None:
+                        continue
+                    target.parent.mkdir(parents=True, exist_ok=True)
+                    target.write_bytes(archive.read(member))
+    except ArchiveError:
+        raise
+    except (zipfile.BadZipFile, ValueError, KeyError, TypeError) as e:
+        raise ArchiveError(t("archive.invalid", path=path)) from e
+
+    notebook = Notebook.normalize_path(notebook)
+    if notebook:
+        for note in notes:
+            inner = get_note_notebook(note)
+            note.set_property("notebook", f"{notebook}/{inner}" if inner else notebook)
+    return notes
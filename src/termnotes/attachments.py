@@ -0,0 +1,130 @@
+"""
+Files attached to notes
+
+Attached files are copied into the attachments directory, in a subdirectory
+per note, and listed in the note's "attachments" property so every backend
+keeps track of them the same way:
+
+    "attachments": [{"name": "diagram.png", "size": 52311, "added_at": "2025-01-02T08:30:00"}]
+"""
+
+import os
+import subprocess
+import sys
+from dataclasses import dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Union
+from .note import Note
+from .utils import utc_now
+
+ATTACHMENTS_PROPERTY = "attachments"
+
+
+@dataclass
+class Attachment:
+    """A file attached to a note"""
+    name: str  # File name, unique within the note
+    size: int  # Bytes
+    added_at: datetime
+
+    def to_dict(self) -> Dict[str, Any]:
+        """Convert to the form kept in the note's properties"""
+        return {"name": self.name, "size": self.size, "added_at": self.added_at.isoformat()}
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "Attachment":
+        """Read an entry of the note's attachments property"""
+        try:
+            added_at = datetime.fromisoformat(data.get("added_at", ""))
+        except (TypeError, ValueError):
+            added_at = utc_now()
+        return cls(name=str(data.get("name", "")), size=int(data.get("size", 0)), added_at=added_at)
+
+
+def note_attachments(note: Note) -> List[Attachment]:
+    """
+    Get the files attached to a note, in the order they were added
+
+    Args:
+        note: Note to look at
+
+    Returns:
+        Attachments listed in the note's properties
+    """
+    entries = note.get_property(ATTACHMENTS_PROPERTY) or []
+    return [Attachment.from_dict(entry) for entry in entries if isinstance(entry, dict) and entry.get("name")]
+
+
+def unique_name(name: str, taken: List[str]) -> str:
+    """
+    Pick a file name that isn't already attached to the note
+
+    Args:
+        name: Name of the file being attached
+        taken: Names already attached
+
+    Returns:
+        The name, or "stem 2.ext", "stem 3.ext"... if taken
+    """
+    stem, suffix = os.path.splitext(name)
+    candidate = name
+    counter = 2
+    while candidate in taken:
+        candidate = f"{stem} {counter}{suffix}"
+        counter += 1
+    return candidate
+
+
+def find_attachment(attachments: List[Attachment], key: str) -> Optional[Attachment]:
+    """
+    Find an attachment by its number in the list (from 1) or its name
+
+    Args:
+        attachments: The note's attachments
+        key: Number or file name
+
+    Returns:
+        The attachment, or None if there's no match
+    """
+    key = key.strip()
+    if key.isdigit():
+        index = int(key) - 1
+        return attachments[index] if 0 <= index < len(attachments) else None
+    return next((attachment for attachment in attachments if attachment.name == key), None)
+
+
+def format_size(size: int) -> str:
+    """Format a file size for display, e.g. "512 B", "12 KB", "3.4 MB\""""
+    if size < 1024:
+        return f"{size} B"
+    for unit in ("KB", "MB", "GB"):
+        size /= 1024
+        if size < 1024 or unit == "GB":
+            return f"{size:.0f} {unit}" if size >= 10 else f"{size:.1f} {unit}"
+
+
+def open_with_system(path: Union[Path, str]):
+    """
+    Open a file, or a URL, with the program the system associates with it
+
+    Uses "open" on macOS, the shell on Windows and xdg-open elsewhere. The
+    program runs in the background, detached from the terminal.
+
+    Args:
+        path: File or URL to open
+
+    Raises:
+        OSError: If the opener can't be started
+    """
+    if sys.platform == "win32":
+        os.startfile(str(path))
+        return
+    opener = "open" if sys.platform == "darwin" else "xdg-open"
+    subprocess.Popen(
+        [opener, str(path)],
+        stdin=subprocess.DEVNULL,
+        stdout=subprocess.DEVNULL,
+        stderr=subprocess.DEVNULL,
+        start_new_session=True,
+    )
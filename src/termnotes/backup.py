@@ -0,0 +1,218 @@
+"""
+Backups: every note, and the files attached to them, in one tar.gz file
+
+`termnotes backup` writes one when asked. Before a bulk operation
+(BULK_ACTIONS: importing, emptying the trash, replacing in every note,
+deleting many notes, restoring a backup) one is written to the backups
+directory automatically, keeping only the newest few of those.
+
+An archive holds notes.json, {"version": 1, "notes": [...]} with each note
+encoded like the sync protocol does, and the attached files as
+attachments/<note id>/<name>. `termnotes restore <file>` stores the notes
+back, replacing notes with the same ID and leaving other notes alone.
+"""
+
+import io
+import logging
+import json
+import tarfile
+from datetime import datetime
+from pathlib import Path
+from typing import Iterable, List, Optional
+from .note import Note
+from .attachments import note_attachments
+from .sync.protocol import note_from_dict, note_to_dict
+from .i18n import t
+from .log import event, get_logger
+
+log = get_logger("backup")
+
+FORMAT_VERSION = 1  # Written to notes.json; a newer version can't be restored
+NOTES_FILE = "notes.json"
+ATTACHMENTS_DIR = "attachments"
+
+# Journal actions that change many notes at once, written a backup first
+BULK_ACTIONS = frozenset(("import", "purge", "replace_all", "delete_many", "restore_backup"))
+
+
+class BackupError(Exception):
+    """A file isn't a termnotes backup, or is from a newer version"""
+
+
+def backup_name(reason: Optional[str] = None, now: Optional[datetime] = None) -> str:
+    """
+    Get the file name of a backup written now
+
+    Args:
+        reason: Journal action an automatic backup was written before
+        now: Local time to name it after (default: now)
+
+    Returns:
+        "termnotes-<date>-<time>.tar.gz", with "-before-<reason>" for automatic backups
+    """
+    stamp = (now or datetime.now()).strftime("%Y%m%d-%H%M%S")
+    return f"termnotes-{stamp}-before-{reason}.tar.gz" if reason else f"termnotes-{stamp}.tar.gz"
+
+
+def _unique_path(directory: Path, name: str) -> Path:
+    """Get a path in a directory for a new file, numbering the name if it's taken"""
+    path = directory / name
+    stem = name[:-len(".tar.gz")]
+    number = 2
+    while path.exists():
+        path = directory / f"{stem}-{number}.tar.gz"
+        number += 1
+    return path
+
+
+def _add_bytes(archive: tarfile.TarFile, name: str, data: bytes):
+    """Add a file with some contents to an archive"""
+    info = tarfile.TarInfo(name)
+    info.size = len(data)
+    info.mtime = int(datetime.now().timestamp())
+    archive.addfile(info, io.BytesIO(data))
+
+
+def write_backup(notes: Iterable[Note], attachments_dir: Path, directory: str,
+                 reason: Optional[str] = None) -> Path:
+    """
+    Write a backup of notes and their attached files
+
+    Args:
+        notes: Every note to back up, including trashed and archived ones
+        attachments_dir: Where the attached files are kept (StorageBackend.attachments_dir)
+        directory: Directory to write the backup into (created if missing)
+        reason: Journal action an automatic backup is written before
+
+    Returns:
+        Path of the backup
+
+    Raises:
+        OSError: If the backup can't be written
+    """
+    notes = list(notes)
+    target_dir = Path(directory).expanduser()
+    target_dir.mkdir(parents=True, exist_ok=True)
+    path = _unique_path(target_dir, backup_name(reason))
+    tmp_path = path.with_name(f".{path.name}.tmp")
+    try:
+        with tarfile.open(tmp_path, "w:gz") as archive:
+            data = {"version": FORMAT_VERSION, "notes": [note_to_dict(note) for note in notes]}
+            _add_bytes(archive, NOTES_FILE, json.dumps(data, ensure_ascii=False, indent=1).encode("utf-8"))
+            for note in notes:
+                for attachment in note_attachments(note):
+                    file_path = Path(attachments_dir) / note.id / attachment.name
+                    if file_path.is_file():
+                        archive.add(file_path, f"{ATTACHMENTS_DIR}/{note.id}/{attachment.name}")
+        tmp_path.replace(path)
+    finally:
+        tmp_path.unlink(missing_ok=True)
+    event(log, "backup", notes=len(notes), reason=reason or "manual")
+    return path
+
+
+def attachment_target(member_name: str, attachments_dir: Path) -> Optional[Path]:
+    """
+    Get where an attached file in a backup or archive goes
+
+    Args:
+        member_name: Name of the file in the backup, "attachments/<note id>/<name>"
+        attachments_dir: Where the attached files are kept
+
+    Returns:
+        Path to copy it to, or None if it isn't an attached file (or would
+        land outside the directory)
+    """
+    parts = member_name.split("/")
+    if len(parts) != 3 or parts[0] != ATTACHMENTS_DIR:
+        return None
+    note_id, name = parts[1], parts[2]
+    if note_id in ("", ".", "..") or name in ("", ".", "..") or "\\" in member_name:
+        return None
+    return Path(attachments_dir) / note_id / name
+
+
+def read_backup(path: str, attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read the notes from a backup, copying its attached files back
+
+    Args:
+        path: Backup file
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        The notes in the backup
+
+    Raises:
+        OSError: If the file can't be read or the files can't be copied
+        BackupError: If the file isn't a backup termnotes can read
+    """
+    try:
+        with tarfile.open(Path(path).expanduser(), "r:gz") as archive:
+            try:
+                notes_file = archive.extractfile(NOTES_FILE)
+            except KeyError:
+                raise BackupError(t("backup.invalid", path=path))
+            data = json.loads(notes_file.read().decode("utf-8"))
+            if not isinstance(data, dict) or data.get("version", 0) > FORMAT_VERSION:
+                raise BackupError(t("backup.invalid", path=path))
+            notes = [note_from_dict(entry) for entry in data.get("notes", [])]
+
+            if attachments_dir is not None:
+                for member in archive.getmembers():
+                    target = attachment_target(member.name, attachments_dir) if member.isfile() else None
+                    if target is None:
+                        continue
+                    target.parent.mkdir(parents=True, exist_ok=True)
+                    target.write_bytes(archive.extractfile(member).read())
+    except (tarfile.TarError, ValueError, KeyError, TypeError) as e:
+        raise BackupError(t("backup.invalid", path=path)) from e
+    return notes
+
+
+class AutoBackup:
+    """Writes a backup before each bulk operation, keeping the newest few"""
+
+    def __init__(self, directory: str, keep: int):
+        """
+        Initialize
+
+        Args:
+            directory: Directory the backups are written to
+            keep: Number of automatic backups to keep; older ones are deleted
+        """
+        self.directory = Path(directory).expanduser()
+        self.keep = keep
+
+    def write(self, notes: Iterable[Note], attachments_dir: Path, action: str) -> Optional[Path]:
+        """
+        Back up the notes before an operation, then delete the oldest automatic backups
+
+        A failure is logged rather than raised, so a full disk doesn't also
+        keep the trash from being emptied.
+
+        Args:
+            notes: Every stored note
+            attachments_dir: Where the attached files are kept
+            action: Journal action about to run
+
+        Returns:
+            Path of the backup, or None if it couldn't be written
+        """
+        try:
+            path = write_backup(notes, attachments_dir, str(self.directory), reason=action)
+        except OSError as e:
+            event(log, "backup_failed", logging.WARNING, reason=action, error=str(e))
+            return None
+        self.rotate()
+        return path
+
+    def rotate(self):
+        """Delete the oldest automatic backups beyond the number to keep (manual ones stay)"""
+        backups = sorted(self.directory.glob("termnotes-*-before-*.tar.gz"),
+                         key=lambda path: (path.stat().st_mtime_ns, path.name))
+        for path in backups[:max(0, len(backups) - self.keep)]:
+            try:
+                path.unlink()
+            except OSError:
+                pass
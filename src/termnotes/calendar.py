@@ -0,0 +1,148 @@
+"""
+Calendar feed: dated notes as iCalendar (.ics) events
+
+`termnotes export --ics notes.ics` writes one, and the sync server
+serves one at /v1/calendar.ics, so calendar apps can show or subscribe to
+what's coming due.
+
+- A note with a due date is an event at that time, with an alarm at its
+  reminder time if it has one.
+- A note with only a reminder is an event at the reminder, with an alarm.
+- A note whose title has a date in it ("Friday, 2026-10-16", as the
+  journal template writes) is an all-day event on that day.
+
+Trashed notes are left out. Event UIDs are made from the note ID, so
+calendar apps update events rather than duplicating them.
+"""
+
+import re
+from datetime import date, datetime, timedelta
+from pathlib import Path
+from typing import Iterable, List, Optional
+from .note import Note
+from . import __version__
+
+PRODUCT_ID = f"-//termnotes//termnotes {__version__}//EN"
+LINE_LIMIT = 75  # Octets per line before folding (RFC 5545)
+DESCRIPTION_LENGTH = 500  # Characters of the note's text in each event
+
+TITLE_DATE = re.compile(r'\b(\d{4})-(\d{2})-(\d{2})\b')
+
+
+def title_date(note: Note) -> Optional[date]:
+    """Get the date a note's title names, as journal entries have, or None"""
+    match = TITLE_DATE.search(note.title)
+    if not match:
+        return None
+    try:
+        return date(*(int(part) for part in match.groups()))
+    except ValueError:
+        return None
+
+
+def _escape(text: str) -> str:
+    """Escape text for an iCalendar property value"""
+    return (text.replace("\\", "\\\\").replace(";", "\\;").replace(",", "\\,")
+            .replace("\r\n", "\\n").replace("\n", "\\n"))
+
+
+def _fold(line: str) -> List[str]:
+    """Split a content line into lines of at most LINE_LIMIT octets, continuations starting with a space"""
+    lines = []
+    current = ""
+    for char in line:
+        limit = LINE_LIMIT if not lines else LINE_LIMIT - 1
+        if len((current + char).encode("utf-8")) > limit:
+            lines.append(current)
+            current = ""
+        current += char
+    lines.append(current)
+    return [lines[0]] + [f" {part}" for part in lines[1:]]
+
+
+def _utc(value: datetime) -> str:
+    """Format a UTC time for iCalendar"""
+    return value.strftime("%Y%m%dT%H%M%SZ")
+
+
+def _event(note: Note, kind: str, start: List[str], alarm: Optional[datetime]) -> List[str]:
+    """Build a VEVENT for a note"""
+    body = note.content.split("\n", 1)[1].strip() if "\n" in note.content else ""
+    lines = [
+        "BEGIN:VEVENT",
+        f"UID:{note.id}-{kind}@termnotes",
+        f"DTSTAMP:{_utc(note.updated_at)}",
+        *start,
+        f"SUMMARY:{_escape(note.title or note.id)}",
+    ]
+    if body:
+        lines.append(f"DESCRIPTION:{_escape(body[:DESCRIPTION_LENGTH])}")
+    if note.tags:
+        lines.append(f"CATEGORIES:{','.join(_escape(tag) for tag in note.tags)}")
+    if alarm is not None:
+        lines += [
+            "BEGIN:VALARM",
+            "ACTION:DISPLAY",
+            f"DESCRIPTION:{_escape(note.title or note.id)}",
+            f"TRIGGER;VALUE=DATE-TIME:{_utc(alarm)}",
+            "END:VALARM",
+        ]
+    lines.append("END:VEVENT")
+    return lines
+
+
+def notes_to_ics(notes: Iterable[Note]) -> str:
+    """
+    Build a calendar of the dated notes
+
+    Args:
+        notes: Notes to look through; undated and trashed ones are skipped
+
+    Returns:
+        iCalendar text, with CRLF line endings
+    """
+    lines = [
+        "BEGIN:VCALENDAR",
+        "VERSION:2.0",
+        f"PRODID:{PRODUCT_ID}",
+        "CALSCALE:GREGORIAN",
+        "X-WR-CALNAME:termnotes",
+    ]
+    for note in notes:
+        if note.is_trashed:
+            continue
+        if note.due_at is not None:
+            lines += _event(note, "due", [f"DTSTART:{_utc(note.due_at)}", f"DTEND:{_utc(note.due_at)}"],
+                            note.remind_at)
+        elif note.remind_at is not None:
+            lines += _event(note, "reminder", [f"DTSTART:{_utc(note.remind_at)}", f"DTEND:{_utc(note.remind_at)}"],
+                            note.remind_at)
+        day = title_date(note)
+        if day is not None:
+            lines += _event(note, "day", [
+                f"DTSTART;VALUE=DATE:{day.strftime('%Y%m%d')}",
+                f"DTEND;VALUE=DATE:{(day + timedelta(days=1)).strftime('%Y%m%d')}",
+            ], None)
+    lines.append("END:VCALENDAR")
+    return "".join(f"{folded}\r\n" for line in lines for folded in _fold(line))
+
+
+def write_ics(notes: Iterable[Note], path: str) -> Path:
+    """
+    Write a calendar of the dated notes to a file
+
+    Args:
+        notes: Notes to look through
+        path: .ics file to write (replaced if it exists)
+
+    Returns:
+        Path of the file
+
+    Raises:
+        OSError: If the file can't be written
+    """
+    target = Path(path).expanduser()
+    target.parent.mkdir(parents=True, exist_ok=True)
+    with open(target, "w", encoding="utf-8", newline="") as f:
+        f.write(notes_to_ics(notes))
+    return target
@@ -0,0 +1,246 @@
+"""
+System clipboard access
+
+Text is copied with the platform's clipboard tool: pbcopy on macOS,
+wl-copy on Wayland, xclip or xsel on X11 and clip.exe on Windows and WSL.
+Without one (typically over SSH), copying falls back to the OSC 52
+terminal escape sequence, which most terminals turn into a clipboard
+write on the machine the terminal runs on. Pasting needs a clipboard
+tool; terminals don't let programs read the clipboard through OSC 52.
+
+Images (a screenshot, one copied in a browser) are pasted as PNG with
+pngpaste on macOS, wl-paste on Wayland, xclip on X11 and PowerShell on
+Windows and WSL.
+"""
+
+import base64
+import os
+import re
+import shutil
+import subprocess
+import sys
+from typing import List, Optional
+from .images import image_format
+
+TIMEOUT = 2  # Seconds to wait for a clipboard tool
+MODES = ("auto", "system", "osc52", "off")
+
+# PowerShell writing the clipboard's image to stdout as PNG, if it holds one
+WINDOWS_PASTE_IMAGE = (
+    "Add-Type -AssemblyName System.Windows.Forms; $image = [Windows.Forms.Clipboard]::GetImage(); "
+    "if ($image) { $stream = New-Object IO.MemoryStream; $image.Save($stream, [Drawing.Imaging.ImageFormat]::Png); "
+    "$out = [Console]::OpenStandardOutput(); $out.Write($stream.ToArray(), 0, $stream.Length); $out.Flush() }"
+)
+
+HEADING = re.compile(r'^#{1,6}\s+(.*?)\s*#*\s*$')
+FENCE = re.compile(r'^\s*(```|~~~)')
+IMAGE = re.compile(r'!\[([^\]]*)\]\([^)]*\)')
+LINK = re.compile(r'\[([^\]]+)\]\([^)]*\)')
+WIKI_LINK = re.compile(r'\[\[([^\]|#]+)(?:#[^\]|]*)?(?:\|([^\]]+))?\]\]')  # [[Title#Heading|alias]]
+MARKS = re.compile(r'(\*\*\*|\*\*|__|~~|`)')
+
+
+def markdown_to_text(content: str) -> str:
+    """
+    Render a note's markdown as plain text for pasting elsewhere
+
+    Heading marks, emphasis and code fences are dropped and links become
+    their text; list bullets, checkboxes and code are kept as they are.
+
+    Args:
+        content: Markdown text
+
+    Returns:
+        Plain text
+    """
+    lines = []
+    in_code = False
+    for line in content.split('\n'):
+        if FENCE.match(line):
+            in_code = not in_code
+            continue
+        if in_code:
+            lines.append(line)
+            continue
+        heading = HEADING.match(line)
+        if heading:
+            line = heading.group(1)
+        line = IMAGE.sub(r'\1', line)
+        line = LINK.sub(r'\1', line)
+        line = WIKI_LINK.sub(lambda match: match.group(2) or match.group(1), line)
+        lines.append(MARKS.sub('', line))
+    return '\n'.join(lines)
+
+
+def _copy_commands() -> List[List[str]]:
+    """Get the clipboard tools that can copy here, best first"""
+    commands = []
+    if sys.platform == "darwin":
+        commands.append(["pbcopy"])
+    if os.environ.get("WAYLAND_DISPLAY"):
+        commands.append(["wl-copy"])
+    if os.environ.get("DISPLAY"):
+        commands.append(["xclip", "-selection", "clipboard"])
+        commands.append(["xsel", "--clipboard", "--input"])
+    commands.append(["clip.exe"])
+    return [command for command in commands if shutil.which(command[0])]
+
+
+def _paste_commands() -> List[List[str]]:
+    """Get the clipboard tools that can paste here, best first"""
+    commands = []
+    if sys.platform == "darwin":
+        commands.append(["pbpaste"])
+    if os.environ.get("WAYLAND_DISPLAY"):
+        commands.append(["wl-paste", "--no-newline"])
+    if os.environ.get("DISPLAY"):
+        commands.append(["xclip", "-selection", "clipboard", "-o"])
+        commands.append(["xsel", "--clipboard", "--output"])
+    commands.append(["powershell.exe", "-NoProfile", "-Command", "Get-Clipboard -Raw"])
+    return [command for command in commands if shutil.which(command[0])]
+
+
+def _image_paste_commands() -> List[List[str]]:
+    """Get the clipboard tools that can paste an image here as PNG, best first"""
+    commands = []
+    if sys.platform == "darwin":
+        commands.append(["pngpaste", "-"])
+    if os.environ.get("WAYLAND_DISPLAY"):
+        commands.append(["wl-paste", "--type", "image/png"])
+    if os.environ.get("DISPLAY"):
+        commands.append(["xclip", "-selection", "clipboard", "-target", "image/png", "-o"])
+    commands.append(["powershell.exe", "-NoProfile", "-Command", WINDOWS_PASTE_IMAGE])
+    return [command for command in commands if shutil.which(command[0])]
+
+
+def osc52_sequence(text: str) -> str:
+    """
+    Build the escape sequence asking the terminal to set its clipboard
+
+    Inside tmux ($TMUX) or screen ($STY) the sequence is wrapped so that it
+    passes through to the outer terminal.
+
+    Args:
+        text: Text to copy
+
+    Returns:
+        Escape sequence to write to the terminal
+    """
+    sequence = f"\x1b]52;c;{base64.b64encode(text.encode('utf-8')).decode('ascii')}\x07"
+    if os.environ.get("TMUX"):
+        return f"\x1bPtmux;{sequence.replace(chr(27), chr(27) * 2)}\x1b\\"
+    if os.environ.get("STY"):
+        return f"\x1bP{sequence}\x1b\\"
+    return sequence
+
+
+class Clipboard:
+    """Copies to and pastes from the system clipboard"""
+
+    def __init__(self, mode: str = "auto"):
+        """
+        Initialize
+
+        Args:
+            mode: "auto" (a clipboard tool, else OSC 52), "system" (a clipboard
+                  tool only), "osc52" (OSC 52 only) or "off"
+        """
+        self.mode = mode
+        self.last_copied: Optional[str] = None  # Last text copied, to tell our copies from others'
+
+    def copy(self, text: str) -> Optional[str]:
+        """
+        Put text on the clipboard
+
+        Args:
+            text: Text to copy
+
+        Returns:
+            How it was copied ("system" or "osc52"), or None if it couldn't be
+        """
+        if self.mode == "off":
+            return None
+        if self.mode in ("auto", "system"):
+            for command in _copy_commands():
+                if self._run(command, text) is not None:
+                    self.last_copied = text
+                    return "system"
+        if self.mode in ("auto", "osc52") and self._write_osc52(text):
+            self.last_copied = text
+            return "osc52"
+        return None
+
+    def paste(self) -> Optional[str]:
+        """
+        Get the text on the clipboard
+
+        Returns:
+            The text, or None if there's no clipboard tool or it failed
+        """
+        if self.mode not in ("auto", "system"):
+            return None
+        for command in _paste_commands():
+            text = self._run(command)
+            if text is not None:
+                return text.replace('\r\n', '\n')
+        return None
+
+    def paste_image(self) -> Optional[bytes]:
+        """
+        Get the image on the clipboard
+
+        Returns:
+            The image file's content (PNG, or what the tool gave: GIF, JPEG,
+            WebP), or None if the clipboard holds no image or there's no tool
+        """
+        if self.mode not in ("auto", "system"):
+            return None
+        for command in _image_paste_commands():
+            try:
+                result = subprocess.run(command, stdin=subprocess.DEVNULL, stdout=subprocess.PIPE,
+                                        stderr=subprocess.DEVNULL, timeout=TIMEOUT)
+            except (OSError, subprocess.TimeoutExpired):
+                continue
+            # Without an image the tools fail, or print nothing or text
+            if result.returncode == 0 and image_format(result.stdout):
+                return result.stdout
+        return None
+
+    @staticmethod
+    def _run(command: List[str], text: Optional[str] = None) -> Optional[str]:
+        """
+        Run a clipboard tool
+
+        Args:
+            command: Tool and arguments
+            text: Text to copy (None to paste)
+
+        Returns:
+            The tool's output ("" when copying), or None if it failed
+        """
+        # Copy tools like xclip keep running to serve the clipboard, so
+        # their output isn't waited for
+        output = subprocess.PIPE if text is None else subprocess.DEVNULL
+        try:
+            result = subprocess.run(
+                command, input=(text or "").encode("utf-8"), stdout=output, stderr=subprocess.DEVNULL,
+                timeout=TIMEOUT
+            )
+        except (OSError, subprocess.TimeoutExpired):
+            return None
+        if result.returncode != 0:
+            return None
+        return (result.stdout or b"").decode("utf-8", "replace")
+
+    @staticmethod
+    def _write_osc52(text: str) -> bool:
+        """Write the OSC 52 sequence to the terminal"""
+        try:
+            with open("/dev/tty", "w") as tty:
+                tty.write(osc52_sequence(text))
+        except OSError:
+            if not sys.stdout.isatty():
+                return False
+            sys.stdout.write(osc52_sequence(text))
+            sys.stdout.flush()
+        return True
@@ -3,7 +3,7 @@
 import os
 import tomllib
 from pathlib import Path
-from typing import Any, Dict, Optional
+from typing import Any, Dict, List, Optional
 
 
 class Config:
@@ -28,8 +28,9 @@ class Config:
         else:
             paths.append(Path.home() / ".config" / "termnotes" / "config.toml")
 
-        # Fallback home directory config
+        # Fallback home directory configs
         paths.append(Path.home() / ".termnotes.toml")
+        paths.append(Path.home() / ".termnotes" / "config.toml")
 
         return paths
 
@@ -55,6 +56,10 @@ class Config:
         return {
             "storage": {
                 "backend": "sqlite",
+                "attachments": "~/.local/share/termnotes/attachments/",
+                "undo_levels": 50,
+                "write_delay_ms": 200,
+                "read_only": False,
                 "sqlite": {
                     "path": "~/.local/share/termnotes/notes.db"
                 },
@@ -64,15 +69,191 @@ class Config:
                     "folder_name": "termnotes"
                 },
                 "filesystem": {
-                    "directory": "~/.local/share/termnotes/notes/"
+                    "directory": "~/.local/share/termnotes/notes/",
+                    "fsync": True,
+                    "coalesce_ms": 0,
+                    "paranoid": False,
+                    "backup": True
+                },
+                "markdown": {
+                    "directory": "~/.local/share/termnotes/markdown/"
+                },
+                "git": {
+                    "directory": "~/.local/share/termnotes/git/",
+                    "remote": "",
+                    "pull_on_start": True,
+                    "push_on_exit": False
+                },
+                "sync": {
+                    "url": "",
+                    "token": "",
+                    "path": "~/.local/share/termnotes/sync.db",
+                    "e2e": False,
+                    "device_name": ""
+                },
+                "webdav": {
+                    "url": "",
+                    "username": "",
+                    "password": ""
+                },
+                "postgres": {
+                    "dsn": "",
+                    "password": ""
                 },
                 "encrypted": {
                     "wraps": "filesystem",
-                    "key_file": "~/.config/termnotes/encryption.key"
+                    "key_file": "~/.config/termnotes/encryption.key",
+                    "key_store": "file",
+                    "prompt_passphrase": False
                 }
+            },
+            "ui": {
+                "locale": "auto",
+                "external_editor": "",
+                "theme": "auto",
+                "colors": "auto",
+                "sidebar_width": 30,
+                "zen_width": 80,
+                "live_preview": False,
+                "wrap": False,
+                "borders": True,
+                "tour": True,
+                "descriptions": True,
+                "metadata": True,
+                "status_line": True,
+                "mouse": True,
+                "sort": "updated",
+                "live_reload": True,
+                "draft_interval": 5,
+                "images": "auto",
+                "editing": "vim",
+                "templates": "~/.config/termnotes/templates/"
+            },
+            "accessibility": {
+                "enabled": False,
+                "alt_screen": True
+            },
+            "keys": {},
+            "snippets": {
+                ";date": "{{date}}",
+                ";time": "{{time}}",
+                ";now": "{{datetime}}",
+                ";mtg": {"template": "meeting"}
+            },
+            "colors": {},
+            "hooks": {},
+            "reminders": {
+                "notify": "desktop",
+                "upcoming_days": 7
+            },
+            "clipboard": {
+                "mode": "auto",
+                "yank": True,
+                "images": True
+            },
+            "share": {
+                "service": "gist",
+                "token": "",
+                "public": False,
+                "paste_url": "https://paste.rs/",
+                "paste_token": ""
+            },
+            "ai": {
+                "provider": "off",
+                "model": "",
+                "url": "",
+                "api_key": "",
+                "command": "",
+                "timeout": 60
+            },
+            "plugins": {
+                "enabled": True,
+                "directory": "~/.termnotes/plugins/"
+            },
+            "server": {
+                "host": "127.0.0.1",
+                "port": 8765,
+                "token": "",
+                "path": "~/.local/share/termnotes/server.db",
+                "request_timeout": 10,
+                "feed": False,
+                "feed_title": "termnotes",
+                "feed_tag": "",
+                "feed_limit": 20,
+                "feed_public": False,
+                "grpc": False,
+                "grpc_port": 8766
+            },
+            "mcp": {
+                "write": False,
+                "notebooks": []
+            },
+            "daemon": {
+                "enabled": False,
+                "socket": "",
+                "idle_timeout": 0
+            },
+            "lock": {
+                "enabled": False,
+                "idle_minutes": 0,
+                "file": "~/.config/termnotes/lock.json"
+            },
+            "spellcheck": {
+                "enabled": False,
+                "languages": ["en"],
+                "dictionaries": "~/.config/termnotes/dictionaries/",
+                "personal_dictionary": "~/.config/termnotes/dictionary.txt"
+            },
+            "backup": {
+                "auto": True,
+                "directory": "~/.local/share/termnotes/backups/",
+                "keep": 10
+            },
+            "debug": {
+                "enabled": False,
+                "log_path": "~/.termnotes/termnotes.log"
             }
         }
 
+    def set(self, name: str, value: Any):
+        """
+        Override a config value for this run, e.g. from a command line flag.
+
+        Args:
+            name: Dotted key path (e.g. "storage.backend", "ui.theme")
+            value: New value
+        """
+        section = self._config
+        *parents, key = name.split(".")
+        for parent in parents:
+            section = section.setdefault(parent, {})
+        section[key] = value
+
+    def set_notes_path(self, path: str):
+        """
+        Override where the configured backend keeps notes for this run.
+
+        Sets the database path for sqlite and sync, the directory for filesystem,
+        markdown and git, the folder name for gdrive, the folder URL for webdav and the connection string
+        for postgres. For the encrypted
+        backend, the wrapped backend's location is set.
+
+        Args:
+            path: Database file, directory, Google Drive folder name, WebDAV URL or
+                  PostgreSQL connection string
+        """
+        backend = self.storage_backend
+        if backend == "encrypted":
+            backend = self.encrypted_wraps
+        setting = {
+            "sqlite": "path",
+            "sync": "path",
+            "gdrive": "folder_name",
+            "webdav": "url",
+            "postgres": "dsn",
+        }.get(backend, "directory")
+        self.set(f"storage.{backend}.{setting}", path)
+
     def _expand_path(self, path: str) -> str:
         """Expand ~ and environment variables in path."""
         return os.path.expanduser(os.path.expandvars(path))
@@ -82,6 +263,27 @@ class Config:
         """Get the configured storage backend."""
         return self._config.get("storage", {}).get("backend", "sqlite")
 
+    @property
+    def attachments_directory(self) -> str:
+        """Get the directory attached files are copied into."""
+        path = self._config.get("storage", {}).get("attachments", "~/.local/share/termnotes/attachments/")
+        return self._expand_path(path)
+
+    @property
+    def undo_levels(self) -> int:
+        """Get how many changes to notes can be undone (0 to turn undo off)."""
+        return self._config.get("storage", {}).get("undo_levels", 50)
+
+    @property
+    def write_delay_ms(self) -> int:
+        """Get the milliseconds changes wait before being written to the backend (0 to write at once)."""
+        return self._config.get("storage", {}).get("write_delay_ms", 200)
+
+    @property
+    def storage_read_only(self) -> bool:
+        """Get whether notes are opened read-only, for browsing without changing them."""
+        return self._config.get("storage", {}).get("read_only", False)
+
     @property
     def sqlite_path(self) -> str:
         """Get the SQLite database path."""
@@ -121,6 +323,110 @@ class Config:
         )
         return self._expand_path(path)
 
+    @property
+    def filesystem_fsync(self) -> bool:
+        """Get whether the filesystem backend fsyncs each saved note."""
+        return self._config.get("storage", {}).get("filesystem", {}).get("fsync", True)
+
+    @property
+    def filesystem_coalesce_ms(self) -> int:
+        """Get the filesystem backend write coalescing window in milliseconds."""
+        return self._config.get("storage", {}).get("filesystem", {}).get("coalesce_ms", 0)
+
+    @property
+    def filesystem_paranoid(self) -> bool:
+        """Get whether the filesystem backend verifies written files before replacing."""
+        return self._config.get("storage", {}).get("filesystem", {}).get("paranoid", False)
+
+    @property
+    def filesystem_backup(self) -> bool:
+        """Get whether the filesystem backend keeps the previous version of each note."""
+        return self._config.get("storage", {}).get("filesystem", {}).get("backup", True)
+
+    @property
+    def markdown_directory(self) -> str:
+        """Get the markdown backend directory."""
+        path = self._config.get("storage", {}).get("markdown", {}).get(
+            "directory", "~/.local/share/termnotes/markdown/"
+        )
+        return self._expand_path(path)
+
+    @property
+    def git_directory(self) -> str:
+        """Get the git backend repository directory."""
+        path = self._config.get("storage", {}).get("git", {}).get(
+            "directory", "~/.local/share/termnotes/git/"
+        )
+        return self._expand_path(path)
+
+    @property
+    def git_remote(self) -> str:
+        """Get the git backend remote URL ("" for none)."""
+        return self._config.get("storage", {}).get("git", {}).get("remote", "")
+
+    @property
+    def git_pull_on_start(self) -> bool:
+        """Get whether the git backend pulls from the remote on startup."""
+        return self._config.get("storage", {}).get("git", {}).get("pull_on_start", True)
+
+    @property
+    def git_push_on_exit(self) -> bool:
+        """Get whether the git backend pushes to the remote on exit."""
+        return self._config.get("storage", {}).get("git", {}).get("push_on_exit", False)
+
+    @property
+    def sync_url(self) -> str:
+        """Get the sync server URL."""
+        return self._config.get("storage", {}).get("sync", {}).get("url", "")
+
+    @property
+    def sync_token(self) -> str:
+        """Get the token for the sync server ("" for none, or to use the system keyring)."""
+        return self._config.get("storage", {}).get("sync", {}).get("token", "")
+
+    @property
+    def sync_path(self) -> str:
+        """Get the path of the sync backend's local SQLite database."""
+        path = self._config.get("storage", {}).get("sync", {}).get(
+            "path", "~/.local/share/termnotes/sync.db"
+        )
+        return self._expand_path(path)
+
+    @property
+    def sync_e2e(self) -> bool:
+        """Get whether synced notes are encrypted before they reach the server."""
+        return self._config.get("storage", {}).get("sync", {}).get("e2e", False)
+
+    @property
+    def sync_device_name(self) -> str:
+        """Get the name of this device in the end-to-end encryption keyring ("" for the host name)."""
+        return self._config.get("storage", {}).get("sync", {}).get("device_name", "")
+
+    @property
+    def webdav_url(self) -> str:
+        """Get the URL of the WebDAV folder holding the notes."""
+        return self._config.get("storage", {}).get("webdav", {}).get("url", "")
+
+    @property
+    def webdav_username(self) -> str:
+        """Get the WebDAV account name ("" for no authentication)."""
+        return self._config.get("storage", {}).get("webdav", {}).get("username", "")
+
+    @property
+    def webdav_password(self) -> str:
+        """Get the WebDAV password or app token ("" to use the system keyring)."""
+        return self._config.get("storage", {}).get("webdav", {}).get("password", "")
+
+    @property
+    def postgres_dsn(self) -> str:
+        """Get the PostgreSQL connection string."""
+        return self._config.get("storage", {}).get("postgres", {}).get("dsn", "")
+
+    @property
+    def postgres_password(self) -> str:
+        """Get the PostgreSQL password, if it isn't in the connection string ("" to use the system keyring)."""
+        return self._config.get("storage", {}).get("postgres", {}).get("password", "")
+
     @property
     def encrypted_wraps(self) -> str:
         """Get the backend that encryption wraps."""
@@ -136,6 +442,393 @@ class Config:
         )
         return self._expand_path(path)
 
+    @property
+    def encrypted_key_store(self) -> str:
+        """Get where the generated passphrase is kept: "file" (key_file) or "keyring"."""
+        return self._config.get("storage", {}).get("encrypted", {}).get("key_store", "file")
+
+    @property
+    def encrypted_prompt_passphrase(self) -> bool:
+        """Get whether to ask for the passphrase on startup instead of using the key file."""
+        return self._config.get("storage", {}).get("encrypted", {}).get("prompt_passphrase", False)
+
+    @property
+    def ui_locale(self) -> str:
+        """Get the UI locale ("auto" detects from the environment)."""
+        return self._config.get("ui", {}).get("locale", "auto")
+
+    @property
+    def external_editor(self) -> str:
+        """Get the external editor command ("" uses $VISUAL, then $EDITOR, then vi)."""
+        return self._config.get("ui", {}).get("external_editor", "")
+
+    @property
+    def theme(self) -> str:
+        """Get the code highlighting theme ("auto", "ansi" or a Pygments style name)."""
+        return self._config.get("ui", {}).get("theme", "auto")
+
+    @property
+    def colors(self) -> str:
+        """Get the interface color theme ("auto", "dark", "light", "solarized", "gruvbox"...)."""
+        return self._config.get("ui", {}).get("colors", "auto")
+
+    @property
+    def sidebar_width(self) -> float:
+        """Get the sidebar width in columns, or as a fraction of the terminal width if below 1."""
+        return self._config.get("ui", {}).get("sidebar_width", 30)
+
+    @property
+    def borders(self) -> bool:
+        """Get whether to draw lines around the panes, highlighting the focused one."""
+        return self._config.get("ui", {}).get("borders", True)
+
+    @property
+    def tour(self) -> bool:
+        """Get whether to show the tour of the main keys on first run."""
+        return self._config.get("ui", {}).get("tour", True)
+
+    @property
+    def descriptions(self) -> bool:
+        """Get whether the note list shows the first sentence of each note after its title."""
+        return self._config.get("ui", {}).get("descriptions", True)
+
+    @property
+    def metadata(self) -> bool:
+        """Get whether to show how long ago notes were edited in the list, and a metadata line above the editor."""
+        return self._config.get("ui", {}).get("metadata", True)
+
+    @property
+    def status_line(self) -> bool:
+        """Get whether to show a line under the status bar with note counts, sort order, storage and sync state."""
+        return self._config.get("ui", {}).get("status_line", True)
+
+    @property
+    def mouse(self) -> bool:
+        """Get whether clicks and the scroll wheel work in the note list and editor."""
+        return self._config.get("ui", {}).get("mouse", True)
+
+    @property
+    def zen_width(self) -> int:
+        """Get the width in columns of the editor in zen mode."""
+        return self._config.get("ui", {}).get("zen_width", 80)
+
+    @property
+    def live_preview(self) -> bool:
+        """Check if the editor starts with the live preview of the note next to it."""
+        return self._config.get("ui", {}).get("live_preview", False)
+
+    @property
+    def wrap_lines(self) -> bool:
+        """Check if the editor wraps long lines instead of scrolling them sideways."""
+        return self._config.get("ui", {}).get("wrap", False)
+
+    @property
+    def sort_order(self) -> str:
+        """Get the note list sort order ("updated", "created", "title", "manual", or "due")."""
+        return self._config.get("ui", {}).get("sort", "updated")
+
+    @property
+    def live_reload(self) -> bool:
+        """Get whether to show notes changed by other programs while termnotes runs."""
+        return self._config.get("ui", {}).get("live_reload", True)
+
+    @property
+    def draft_interval(self) -> float:
+        """Get the seconds between saves of unsaved edits to the draft file (0 to turn drafts off)."""
+        return self._config.get("ui", {}).get("draft_interval", 5)
+
+    @property
+    def images(self) -> str:
+        """Get how to show images ("auto", "kitty", "iterm2", "sixel", or "off")."""
+        return self._config.get("ui", {}).get("images", "auto")
+
+    @property
+    def editing(self) -> str:
+        """Get how the editor takes keys ("vim" for modal editing, or "simple")."""
+        return self._config.get("ui", {}).get("editing", "vim")
+
+    @property
+    def templates_directory(self) -> str:
+        """Get the directory holding note templates."""
+        path = self._config.get("ui", {}).get("templates", "~/.config/termnotes/templates/")
+        return self._expand_path(path)
+
+    @property
+    def keys(self) -> Dict[str, Any]:
+        """Get key binding overrides (action name to key sequence or list of them)."""
+        return self._config.get("keys", {})
+
+    @property
+    def snippets(self) -> Dict[str, Any]:
+        """Get the abbreviations expanded in the editor (abbreviation to text, or to a table naming a template)."""
+        return self._config.get("snippets", {
+            ";date": "{{date}}",
+            ";time": "{{time}}",
+            ";now": "{{datetime}}",
+            ";mtg": {"template": "meeting"}
+        })
+
+    @property
+    def color_overrides(self) -> Dict[str, str]:
+        """Get style overrides for elements of the color theme (element name to style)."""
+        return self._config.get("colors", {})
+
+    @property
+    def hooks(self) -> Dict[str, Any]:
+        """Get shell commands to run on events (event name to command or list of them)."""
+        return self._config.get("hooks", {})
+
+    @property
+    def reminders_notify(self) -> str:
+        """Get how due reminders are shown ("desktop", "banner", or "off")."""
+        return self._config.get("reminders", {}).get("notify", "desktop")
+
+    @property
+    def reminders_upcoming_days(self) -> int:
+        """Get how many days ahead "termnotes agenda" lists notes coming due."""
+        return self._config.get("reminders", {}).get("upcoming_days", 7)
+
+    @property
+    def clipboard_mode(self) -> str:
+        """Get how the system clipboard is reached ("auto", "system", "osc52", or "off")."""
+        return self._config.get("clipboard", {}).get("mode", "auto")
+
+    @property
+    def clipboard_yank(self) -> bool:
+        """Get whether editor yanks go to the system clipboard and p pastes from it."""
+        return self._config.get("clipboard", {}).get("yank", True)
+
+    @property
+    def clipboard_images(self) -> bool:
+        """Get whether Ctrl+V in insert mode attaches an image on the clipboard to the note and links it."""
+        return self._config.get("clipboard", {}).get("images", True)
+
+    @property
+    def share_service(self) -> str:
+        """Get where :share uploads notes ("gist" or "paste")."""
+        return self._config.get("share", {}).get("service", "gist")
+
+    @property
+    def share_token(self) -> str:
+        """Get the GitHub token for gists ("" to use the system keyring)."""
+        return self._config.get("share", {}).get("token", "")
+
+    @property
+    def share_public(self) -> bool:
+        """Get whether shared gists are public rather than secret."""
+        return self._config.get("share", {}).get("public", False)
+
+    @property
+    def share_paste_url(self) -> str:
+        """Get the paste service endpoint notes are posted to."""
+        return self._config.get("share", {}).get("paste_url", "https://paste.rs/")
+
+    @property
+    def share_paste_token(self) -> str:
+        """Get the paste service's token ("" for none, or to use the system keyring)."""
+        return self._config.get("share", {}).get("paste_token", "")
+
+    @property
+    def ai_provider(self) -> str:
+        """Get the language model provider for :summarize and the like ("off" for none)."""
+        return self._config.get("ai", {}).get("provider", "off")
+
+    @property
+    def ai_model(self) -> str:
+        """Get the model to ask ("" for the provider's default)."""
+        return self._config.get("ai", {}).get("model", "")
+
+    @property
+    def ai_url(self) -> str:
+        """Get the provider's API address ("" for the provider's default)."""
+        return self._config.get("ai", {}).get("url", "")
+
+    @property
+    def ai_api_key(self) -> str:
+        """Get the API key for the openai provider ("" for $OPENAI_API_KEY or the system keyring)."""
+        return self._config.get("ai", {}).get("api_key", "")
+
+    @property
+    def ai_command(self) -> str:
+        """Get the program the command provider runs."""
+        return self._config.get("ai", {}).get("command", "")
+
+    @property
+    def ai_timeout(self) -> float:
+        """Get the seconds to wait for an answer (0 for no limit)."""
+        return self._config.get("ai", {}).get("timeout", 60)
+
+    @property
+    def plugins_enabled(self) -> bool:
+        """Get whether to load plugins."""
+        return self._config.get("plugins", {}).get("enabled", True)
+
+    @property
+    def plugins_directory(self) -> str:
+        """Get the directory plugins are loaded from."""
+        path = self._config.get("plugins", {}).get("directory", "~/.termnotes/plugins/")
+        return self._expand_path(path)
+
+    @property
+    def server_host(self) -> str:
+        """Get the address the sync server listens on."""
+        return self._config.get("server", {}).get("host", "127.0.0.1")
+
+    @property
+    def server_port(self) -> int:
+        """Get the port the sync server listens on."""
+        return self._config.get("server", {}).get("port", 8765)
+
+    @property
+    def server_token(self) -> str:
+        """Get the token sync clients must send ("" for the system keyring's, or to allow anyone)."""
+        return self._config.get("server", {}).get("token", "")
+
+    @property
+    def server_path(self) -> str:
+        """Get the path of the sync server's SQLite database."""
+        path = self._config.get("server", {}).get("path", "~/.local/share/termnotes/server.db")
+        return self._expand_path(path)
+
+    @property
+    def server_request_timeout(self) -> float:
+        """Get the seconds a sync server request may wait for the database (0 for no limit)."""
+        return self._config.get("server", {}).get("request_timeout", 10)
+
+    @property
+    def server_feed(self) -> bool:
+        """Get whether the sync server serves an Atom feed of recently updated notes."""
+        return self._config.get("server", {}).get("feed", False)
+
+    @property
+    def server_feed_title(self) -> str:
+        """Get the title of the sync server's feed."""
+        return self._config.get("server", {}).get("feed_title", "termnotes")
+
+    @property
+    def server_feed_tag(self) -> str:
+        """Get the tag a note needs to be in the sync server's feed ("" for every note)."""
+        return self._config.get("server", {}).get("feed_tag", "")
+
+    @property
+    def server_feed_limit(self) -> int:
+        """Get the most notes the sync server's feed shows."""
+        return self._config.get("server", {}).get("feed_limit", 20)
+
+    @property
+    def server_feed_public(self) -> bool:
+        """Get whether the sync server's feed can be read without the token."""
+        return self._config.get("server", {}).get("feed_public", False)
+
+    @property
+    def server_grpc(self) -> bool:
+        """Get whether the sync server also serves its gRPC API."""
+        return self._config.get("server", {}).get("grpc", False)
+
+    @property
+    def server_grpc_port(self) -> int:
+        """Get the port of the sync server's gRPC API."""
+        return self._config.get("server", {}).get("grpc_port", 8766)
+
+    @property
+    def mcp_write(self) -> bool:
+        """Get whether MCP clients may create notes and append to them."""
+        return self._config.get("mcp", {}).get("write", False)
+
+    @property
+    def mcp_notebooks(self) -> List[str]:
+        """Get the notebooks MCP clients are limited to (empty for every note)."""
+        return list(self._config.get("mcp", {}).get("notebooks", []))
+
+    @property
+    def daemon_enabled(self) -> bool:
+        """Get whether editors and commands start the daemon when it isn't running."""
+        return self._config.get("daemon", {}).get("enabled", False)
+
+    @property
+    def daemon_socket(self) -> str:
+        """Get the daemon's socket ("" for next to the notes)."""
+        return self._config.get("daemon", {}).get("socket", "")
+
+    @property
+    def daemon_idle_timeout(self) -> float:
+        """Get the seconds without clients after which the daemon exits (0 to keep running)."""
+        return self._config.get("daemon", {}).get("idle_timeout", 0)
+
+    @property
+    def lock_enabled(self) -> bool:
+        """Get whether the editor asks for a passphrase on launch and when idle."""
+        return self._config.get("lock", {}).get("enabled", False)
+
+    @property
+    def lock_idle_minutes(self) -> float:
+        """Get the minutes without a key press after which the editor locks (0 for only on launch)."""
+        return self._config.get("lock", {}).get("idle_minutes", 0)
+
+    @property
+    def lock_file(self) -> str:
+        """Get the path of the lock passphrase's hash, used without the encrypted backend."""
+        return self._expand_path(self._config.get("lock", {}).get("file", "~/.config/termnotes/lock.json"))
+
+    @property
+    def spellcheck_enabled(self) -> bool:
+        """Get whether the editor underlines misspelled words."""
+        return self._config.get("spellcheck", {}).get("enabled", False)
+
+    @property
+    def spellcheck_languages(self) -> List[str]:
+        """Get the language codes of the dictionaries to check spelling with."""
+        languages = self._config.get("spellcheck", {}).get("languages", ["en"])
+        return [languages] if isinstance(languages, str) else languages
+
+    @property
+    def spellcheck_dictionaries(self) -> str:
+        """Get the directory of word lists for languages without a built-in dictionary."""
+        path = self._config.get("spellcheck", {}).get("dictionaries", "~/.config/termnotes/dictionaries/")
+        return self._expand_path(path)
+
+    @property
+    def spellcheck_personal_dictionary(self) -> str:
+        """Get the path of the personal dictionary, the words added with zg."""
+        path = self._config.get("spellcheck", {}).get("personal_dictionary", "~/.config/termnotes/dictionary.txt")
+        return self._expand_path(path)
+
+    @property
+    def accessibility_enabled(self) -> bool:
+        """Get whether the screen-reader-friendly mode is enabled."""
+        return self._config.get("accessibility", {}).get("enabled", False)
+
+    @property
+    def alt_screen(self) -> bool:
+        """Get whether to draw the UI on the terminal's alternate screen."""
+        return self._config.get("accessibility", {}).get("alt_screen", True)
+
+    @property
+    def backup_auto(self) -> bool:
+        """Get whether to back up every note before bulk operations."""
+        return self._config.get("backup", {}).get("auto", True)
+
+    @property
+    def backup_directory(self) -> str:
+        """Get the directory backups are written to."""
+        path = self._config.get("backup", {}).get("directory", "~/.local/share/termnotes/backups/")
+        return self._expand_path(path)
+
+    @property
+    def backup_keep(self) -> int:
+        """Get how many automatic backups to keep."""
+        return self._config.get("backup", {}).get("keep", 10)
+
+    @property
+    def debug_enabled(self) -> bool:
+        """Get whether to write the debug log."""
+        return self._config.get("debug", {}).get("enabled", False)
+
+    @property
+    def debug_log_path(self) -> str:
+        """Get the path of the debug log."""
+        return self._config.get("debug", {}).get("log_path", "~/.termnotes/termnotes.log")
+
 
 # Global config instance
 _config: Optional[Config] = None
@@ -155,15 +848,41 @@ def get_example_config() -> str:
 # Copy this to one of the following locations:
 #   - ~/.config/termnotes/config.toml (recommended)
 #   - ~/.termnotes.toml
+#   - ~/.termnotes/config.toml
 #   - ./termnotes.toml (in your working directory)
+#
+# Command line flags (see "termnotes --help") override these settings.
 
 [storage]
-# Backend type: "sqlite", "gdrive", "filesystem", or "encrypted"
+# Backend type: "sqlite", "gdrive", "filesystem", "markdown", "git", "sync", "webdav", "postgres", or "encrypted"
 backend = "sqlite"
 
+# Directory files attached to notes (:attach) are copied into, a subdirectory per note.
+# Attachments are stored as they are, even with the encrypted backend.
+# Default: ~/.local/share/termnotes/attachments/
+attachments = "~/.local/share/termnotes/attachments/"
+
+# How many changes to notes (saving, deleting, trashing, tagging...) u in the sidebar
+# can undo, and Ctrl+R redo, while termnotes runs. 0 turns undo off.
+# Default: 50
+undo_levels = 50
+
+# Milliseconds to wait for more changes before writing them to the backend, in the
+# background, so saving doesn't wait for the disk or network. Queued changes are
+# written on exit. 0 writes each change before going on. Not used by the sqlite backend.
+# Default: 200
+write_delay_ms = 200
+
+# Open the notes read-only: they can be browsed and searched, but not created, changed
+# or deleted, and the keys that would are unbound. For looking through a directory
+# another program syncs, or a kiosk showing demo notes. Also set by --readonly.
+# Default: false
+read_only = false
+
 # SQLite backend configuration
 [storage.sqlite]
-# Path to SQLite database file
+# Path to SQLite database file. It is opened directly in write-ahead-log mode;
+# when it is first created, notes in the filesystem backend's directory are copied in.
 # Default: ~/.local/share/termnotes/notes.db
 path = "~/.local/share/termnotes/notes.db"
 
@@ -187,9 +906,111 @@ folder_name = "termnotes"
 # Default: ~/.local/share/termnotes/notes/
 directory = "~/.local/share/termnotes/notes/"
 
+# Flush each note to disk (fsync) before it replaces the previous version
+# Default: true
+fsync = true
+
+# Wait this many milliseconds for further edits before writing a note,
+# so rapid saves of the same note become a single write (0 = write immediately)
+# Default: 0
+coalesce_ms = 0
+
+# Re-read each written file and check it parses before replacing the old one
+# Default: false
+paranoid = false
+
+# Keep the previous version of each note in a hidden ".<id>.json.bak" file,
+# used if the note's file is ever found damaged
+# Default: true
+backup = true
+
+# Markdown backend configuration
+[storage.markdown]
+# Directory to store notes as .md files named after their titles, with the
+# note ID and timestamps in a frontmatter header. Notebooks are subdirectories.
+# Point this at an Obsidian vault folder to share notes with it.
+# Default: ~/.local/share/termnotes/markdown/
+directory = "~/.local/share/termnotes/markdown/"
+
+# Git backend configuration (markdown files in a git repository)
+[storage.git]
+# Repository directory; created and initialized if it doesn't exist.
+# Every save and delete is committed. Requires the git command.
+# Default: ~/.local/share/termnotes/git/
+directory = "~/.local/share/termnotes/git/"
+
+# URL of a remote to sync with, e.g. "git@github.com:me/notes.git" (empty = no remote)
+# Default: ""
+remote = ""
+
+# Pull (with rebase) from the remote on startup
+# Default: true
+pull_on_start = true
+
+# Push to the remote on exit
+# Default: false
+push_on_exit = false
+
+# Sync backend configuration (notes shared through a "termnotes serve" server)
+[storage.sync]
+# URL of the sync server, e.g. "http://192.168.1.10:8765"
+url = ""
+
+# Token the server requires (its [server] token). When empty, the system
+# keyring's "sync" secret is used: termnotes secret set sync
+# Default: ""
+token = ""
+
+# Local copy of the notes, used offline; changes are pushed when the server is back
+# Default: ~/.local/share/termnotes/sync.db
+path = "~/.local/share/termnotes/sync.db"
+
+# Encrypt notes before they reach the server, so it never sees their text.
+# Turn it on for every device: the passphrase is chosen on the first and
+# asked for once on each of the others ("termnotes e2e" lists, rotates and
+# revokes devices). The server's calendar, feed and gRPC API can't read
+# encrypted notes.
+# Default: false
+e2e = false
+
+# Name of this device in "termnotes e2e devices"
+# Default: "" (the host name)
+device_name = ""
+
+# WebDAV backend configuration (markdown files on Nextcloud, ownCloud or another WebDAV server)
+[storage.webdav]
+# URL of the folder holding the notes; created if it doesn't exist. For Nextcloud:
+# "https://cloud.example.com/remote.php/dav/files/<user>/Notes/"
+url = ""
+
+# Account name (empty for a server without authentication)
+# Default: ""
+username = ""
+
+# Password, or better an app password (Nextcloud: Settings > Security > Devices & sessions).
+# When empty, the system keyring's "webdav" secret is used: termnotes secret set webdav
+# Default: ""
+password = ""
+
+# PostgreSQL backend configuration (one database shared by a team; needs PostgreSQL 12+
+# and the psycopg package: pip install 'psycopg[binary]')
+[storage.postgres]
+# Connection string, as a URL or key=value pairs, e.g.
+# "postgresql://alice@db.example.com/notes" or "host=db.example.com dbname=notes user=alice".
+# The PGHOST, PGUSER, ... environment variables and ~/.pgpass are used too.
+# Tables are created and upgraded on startup.
+# Default: ""
+dsn = ""
+
+# Password, if it isn't in the connection string or ~/.pgpass. When empty, the
+# system keyring's "postgres" secret is used: termnotes secret set postgres
+# Default: ""
+password = ""
+
 # Encrypted backend configuration (wraps another backend)
 [storage.encrypted]
-# Backend to wrap with encryption: "sqlite", "gdrive", or "filesystem"
+# Backend to wrap with encryption: "sqlite", "gdrive", "filesystem", "markdown", "git", "sync", "webdav", or "postgres"
+# (wrapping sync keeps notes encrypted on the server)
 wraps = "filesystem"
 
 # Path to store passphrase (auto-generated if not exists)
@@ -201,4 +1022,443 @@ key_file = "~/.config/termnotes/encryption.key"
 # Note: On first run, a random memorable passphrase will be generated
 # using xkcdpass (e.g., "correct-horse-battery-staple-random-words")
 # Only the passphrase is stored; salt is derived deterministically.
+
+# Where the passphrase is kept: "file" (key_file) or "keyring" (the system
+# keyring's "encryption" secret, so it is never written to disk in plain
+# text; needs the keyring package). Switching to "keyring" moves an existing
+# key_file's passphrase into the keyring.
+# Default: file
+key_store = "file"
+
+# Ask for the passphrase on startup instead of reading key_file, so it is
+# never written to disk. On first use you choose the passphrase (entered twice);
+# afterwards a wrong passphrase is rejected and asked for again.
+# Default: false
+prompt_passphrase = false
+
+[ui]
+# Language for messages and help text: "auto", "en", or "es"
+# "auto" picks the language from LC_ALL, LC_MESSAGES, or LANG
+# Default: auto
+locale = "auto"
+
+# Command used by "E" to edit a note outside termnotes, e.g. "nvim" or "code --wait"
+# Empty uses $VISUAL, then $EDITOR, then vi
+# Default: ""
+external_editor = ""
+
+# Interface colors: "dark" and "light" use the terminal's palette; "solarized" and
+# "gruvbox" have dark and light variants ("solarized-dark", "gruvbox-light"...).
+# "auto", "solarized" and "gruvbox" pick dark or light from the terminal background
+# ($COLORFGBG, else dark). Override single elements in the [colors] section.
+# Default: auto
+colors = "auto"
+
+# Colors for code blocks: "auto" follows the colors setting, "ansi" uses the
+# terminal's palette, or use a Pygments style name such as "monokai" or "dracula"
+# Default: auto
+theme = "auto"
+
+# Width of the note list: columns, or a fraction of the terminal width (e.g. 0.3)
+# Default: 30
+sidebar_width = 30
+
+# Draw a line between the note list and the editor and a title line above each,
+# highlighted on the pane that has focus (Tab switches)
+# Default: true
+borders = true
+
+# Show a short tour of the main keys the first time termnotes runs (on an empty
+# notes store) or with --seed-demo; :tour shows it again
+# Default: true
+tour = true
+
+# Show the first sentence of each note after its title in the note list, where
+# there's room (markdown, headings and code blocks are left out)
+# Default: true
+descriptions = true
+
+# Show how long ago each note was edited at the end of its row in the note list
+# (created, when sorted by creation), and a line above the editor with the note's
+# dates, word count and tags
+# Default: true
+metadata = true
+
+# Show a line under the status bar with how many notes are listed out of how many,
+# the sort order, the storage backend, when the note was last saved and, when
+# syncing, how many changes haven't reached the server
+# Default: true
+status_line = true
+
+# Click a note in the list to open it, click in the editor to put the cursor there
+# (opening a web link clicked on), and scroll either with the wheel. Hold Shift
+# to select text with the mouse in the terminal instead
+# Default: true
+mouse = true
+
+# Width in columns of the editor in zen mode (:zen or Ctrl+W o), which hides the
+# note list and status bar and centers the note for long-form writing
+# Default: 80
+zen_width = 80
+
+# Show the note rendered next to the editor while it has focus, updated as you type
+# (once typing pauses). :preview or Ctrl+W p shows or hides it while termnotes runs
+# Default: false
+live_preview = false
+
+# Wrap lines longer than the editor onto the rows below instead of scrolling them
+# sideways. :wrap or zw turns it on or off while termnotes runs
+# Default: false
+wrap = false
+
+# Note list order: "updated" (most recent first), "created" (newest first), "title",
+# "manual" (arranged with J/K in the note list), or "due" (soonest due date first).
+# Pinned notes always come first.
+# Default: updated
+sort = "updated"
+
+# Show notes changed by other programs (a sync tool, another editor) while termnotes
+# runs. The open note is reloaded unless it has unsaved changes.
+# Default: true
+live_reload = true
+
+# Seconds between copies of unsaved edits to a draft file, offered back on the next
+# start if termnotes didn't exit normally (the terminal closed, the machine crashed).
+# 0 turns drafts off.
+# Default: 5
+draft_interval = 5
+
+# How Enter on an image link (or :image) shows the image: "auto" detects the terminal,
+# "kitty", "iterm2" or "sixel" picks a graphics protocol, "off" only names the image
+# Default: auto
+images = "auto"
+
+# Editor keys: "vim" for modal editing (normal, insert and visual modes, with dd, yy,
+# p, ciw...), or "simple" to type straight away whenever the editor has focus, with
+# Esc going back to the note list
+# Default: vim
+editing = "vim"
+
+# Directory of note templates (T in the note list, or :template <name> [title]).
+# Each .md file is a template named after the file; {{title}}, {{date}}, {{time}},
+# {{datetime}} and {{weekday}} are filled in, and {{cursor}} marks where to start typing.
+# Files named meeting.md, todo.md or journal.md replace the built-in templates.
+# Default: ~/.config/termnotes/templates/
+templates = "~/.config/termnotes/templates/"
+
+[snippets]
+# Abbreviations expanded while typing in the editor: type one at the start of a
+# line or after a space, then Space, Enter or Tab (which only expands). Each is
+# text, with the placeholders of templates ({{date}}, {{time}}, {{datetime}},
+# {{weekday}}, and {{cursor}} for where to keep typing), or {template = "name"}
+# for a template. Set one to "" to turn it off.
+# Default: the four below
+";date" = "{{date}}"
+";time" = "{{time}}"
+";now" = "{{datetime}}"
+";mtg" = {template = "meeting"}
+# ";todo" = "- [ ] {{cursor}} (added {{date}})"
+
+[keys]
+# Remap keys by action. Each value is a key sequence or a list of them; keys in
+# a sequence are separated by spaces ("c-w h" is Ctrl+W then h). Key names are
+# prompt_toolkit's: letters, "c-x" for Ctrl+X, "enter", "escape", "up", "pagedown"...
+# Run "termnotes --print-keys" to list every action and its current keys.
+# An empty list unbinds an action. Vim editing commands (x, p, u, v...) are fixed.
+# Examples:
+# new_note = "n"
+# down = ["j", "down", "c-n"]
+# delete_note = "d d"
+# quit = "c-q"
+
+[colors]
+# Style of single elements of the color theme, in prompt_toolkit's format: colors
+# as "#rrggbb" or ANSI names ("ansiblue"), "bg:" for the background, and "bold",
+# "italic", "underline" or "reverse". Elements: heading, code, quote, bullet, rule,
+# emphasis, link, image, backlink, muted, match, search, misspelled, cursor, selection, selected,
+# notebook, due, overdue, diff.added, diff.removed, diff.hunk, status, status.info, status.pending, border, border.focused, zen.footer,
+# color.red/yellow/green/cyan/blue/magenta, toast.info/success/error, label, dialog, and syntax.keyword/string/comment/number/function/class/operator/builtin
+# Examples:
+# heading = "#ff8700 bold"
+# selection = "bg:#3a3a3a"
+
+[hooks]
+# Shell commands run in the background on events: note_created, note_updated,
+# note_deleted, start and exit. Each value is a command or a list of them.
+# Note events set TERMNOTES_NOTE_ID, TERMNOTES_NOTE_TITLE, TERMNOTES_NOTE_TAGS
+# (comma-separated) and TERMNOTES_NOTE_NOTEBOOK, and every hook gets
+# TERMNOTES_EVENT. Stdin is JSON: {"event": ..., "note": {"id", "title",
+# "content", "tags", "notebook", "created_at", "updated_at", "properties"}}.
+# Hooks taking longer than 30 seconds are stopped.
+# Examples:
+# note_created = 'notify-send "New note" "$TERMNOTES_NOTE_TITLE"'
+# note_updated = "cat > ~/backups/$TERMNOTES_NOTE_ID.json"
+# exit = ["rsync -a ~/.local/share/termnotes/ backup:notes/"]
+
+[reminders]
+# Due dates (:due <when>) and reminders (:remind <when>) on notes. <when> is a
+# local date and time ("2026-10-20", "2026-10-20 14:30", "14:30"), "today",
+# "tomorrow", a weekday, or a time from now ("+30m", "+2h", "+3d", "+1w").
+# How a reminder is shown when it comes due while termnotes runs: "desktop" (a
+# desktop notification and the status bar), "banner" (the status bar only), or
+# "off" (reminders aren't checked)
+# Default: desktop
+notify = "desktop"
+
+# Days ahead "termnotes agenda" lists notes coming due
+# Default: 7
+upcoming_days = 7
+
+[clipboard]
+# y in the note list copies the selected note's markdown to the system
+# clipboard, Y its text without markdown. How the clipboard is reached: "auto"
+# (pbcopy, wl-copy, xclip, xsel or clip.exe, else the OSC 52 terminal escape,
+# which works over SSH in most terminals), "system" (a clipboard tool only),
+# "osc52" (the terminal only; pasting then needs the terminal's own paste), or
+# "off"
+# Default: auto
+mode = "auto"
+
+# Editor yanks (yy, y in visual mode...) also go to the clipboard, and p, P and
+# Ctrl+V in insert mode paste what another program put there
+# Default: true
+yank = true
+
+# Ctrl+V in insert mode with an image on the clipboard (a screenshot) attaches it
+# to the note as image-<date>-<time>.png and puts a markdown link to it at the cursor
+# Default: true
+images = true
+
+[share]
+# :share uploads the selected note and copies its URL to the clipboard. Where
+# to: "gist" (a GitHub gist) or "paste" (the paste service at paste_url);
+# ":share gist" and ":share paste" pick one for a single note
+# Default: gist
+service = "gist"
+
+# GitHub token with the "gist" scope. When empty, it's read from the system
+# keyring (service "termnotes", user "github") if the keyring package is
+# installed, e.g. after: termnotes secret set github
+# Default: ""
+token = ""
+
+# Make gists public instead of secret (secret gists are unlisted, but anyone
+# with the URL can read them)
+# Default: false
+public = false
+
+# Paste service the note's markdown is posted to; it must answer with the URL
+# Default: https://paste.rs/
+paste_url = "https://paste.rs/"
+
+# Bearer token for the paste service ("" for none; the keyring's "paste" user
+# is tried when empty)
+# Default: ""
+paste_token = ""
+
+[ai]
+# A language model for :summarize, :suggesttags and :gentitle, which send the
+# note in the editor to it. Nothing is sent while this is "off". Providers:
+# "openai" (an OpenAI-compatible API: OpenAI, or a local llama.cpp, LM Studio
+# or vLLM server at url), "ollama", or "command" (a program given the prompt
+# on stdin that prints the answer)
+# Default: off
+provider = "off"
+
+# Model name ("" for gpt-4o-mini with openai, llama3.2 with ollama)
+# Default: ""
+model = ""
+
+# API address ("" for https://api.openai.com/v1 or http://localhost:11434)
+# Default: ""
+url = ""
+
+# Key for the openai provider. When empty, $OPENAI_API_KEY is used, or the
+# system keyring (service "termnotes", user "openai") if the keyring package
+# is installed
+# Default: ""
+api_key = ""
+
+# Program for the command provider, e.g. "llm -m mistral"
+# Default: ""
+command = ""
+
+# Seconds to wait for an answer (0 for no limit)
+# Default: 60
+timeout = 60
+
+[plugins]
+# Python plugins adding commands, keys and note transforms. Each .py file (or
+# package directory) in the directory defines register(api); see the README.
+# Plugin key actions can be remapped in [keys] and are listed by --print-keys.
+# Default: true
+enabled = true
+
+# Directory plugins are loaded from
+# Default: ~/.termnotes/plugins/
+directory = "~/.termnotes/plugins/"
+
+[server]
+# Settings for "termnotes serve", the sync server other machines connect to
+# Address to listen on ("0.0.0.0" for all interfaces)
+# Default: 127.0.0.1
+host = "127.0.0.1"
+
+# Port to listen on
+# Default: 8765
+port = 8765
+
+# Token clients must send; set one when the server is reachable from other machines.
+# The server speaks plain HTTP: use it on a trusted network or behind a TLS proxy.
+# When empty, the system keyring's "server" secret is used if there is one.
+# Default: ""
+token = ""
+
+# Database holding the notes
+# Default: ~/.local/share/termnotes/server.db
+path = "~/.local/share/termnotes/server.db"
+
+# Seconds a request may wait for the database before it is answered with 503
+# (0 for no limit)
+# Default: 10
+request_timeout = 10
+
+# Serve an Atom feed of recently updated notes at /v1/feed.atom, to publish
+# a changelog or microblog from notes ("termnotes serve --feed" for one run)
+# Default: false
+feed = false
+
+# Title feed readers show for the feed
+# Default: "termnotes"
+feed_title = "termnotes"
+
+# Only notes with this tag are published ("" for every note); readers can
+# narrow the feed further with ?tag=
+# Default: ""
+feed_tag = ""
+
+# Most notes in the feed, newest first
+# Default: 20
+feed_limit = 20
+
+# Let anyone who can reach the server read the feed without the token
+# (otherwise it's sent as a header or ?token=<token>)
+# Default: false
+feed_public = false
+
+# Also serve the gRPC API (see notes.proto in the sync package), which needs
+# pip install 'termnotes[grpc]' ("termnotes serve --grpc" for one run).
+# It uses the same token, as "authorization: Bearer <token>" metadata.
+# Default: false
+grpc = false
+
+# Port of the gRPC API
+# Default: 8766
+grpc_port = 8766
+
+[mcp]
+# Settings for "termnotes mcp", which lets AI assistants (Claude Desktop and
+# other MCP clients) search and read notes
+# Let them create notes and append to existing ones ("termnotes mcp --write"
+# for one run); nothing is ever deleted
+# Default: false
+write = false
+
+# Notebooks they may see and change, including the ones inside them; new notes
+# go in the first (empty for every note)
+# Default: []
+notebooks = []
+
+[daemon]
+# "termnotes daemon" holds the notes for every editor and command started
+# while it runs, so several terminal windows can edit the same notes at once
+# and see each other's changes live.
+# Start the daemon in the background when an editor or command needs it
+# Default: false
+enabled = false
+
+# Socket the daemon listens on ("" for next to the notes, e.g.
+# ~/.local/share/termnotes/notes.db.sock)
+# Default: ""
+socket = ""
+
+# Seconds without editors or commands connected after which the daemon exits
+# (0 to keep running until "termnotes daemon --stop")
+# Default: 0
+idle_timeout = 0
+
+[lock]
+# Ask for a passphrase before showing any note, on launch and after being
+# idle, for a laptop that's shared or left unattended (:lock locks at once).
+# With the encrypted backend it's the encryption passphrase; otherwise a lock
+# passphrase chosen on first launch.
+# Default: false
+enabled = false
+
+# Minutes without a key press after which the editor locks (0 for only on launch)
+# Default: 0
+idle_minutes = 0
+
+# Where the lock passphrase's salted hash is kept (not with the encrypted backend);
+# delete it to choose a new one
+# Default: ~/.config/termnotes/lock.json
+file = "~/.config/termnotes/lock.json"
+
+[spellcheck]
+# Underline misspelled words while editing a note: z= on a word suggests
+# replacements, zg adds it to the personal dictionary, and ]s / [s move to the
+# next or previous one. Needs pyspellchecker: pip install 'termnotes[spellcheck]'
+# Default: false
+enabled = false
+
+# Languages to check with; a word any of them knows is spelled right. Built in:
+# en, es, fr, pt, de, it, ru, ar, eu, lv, nl, fa
+# Default: ["en"]
+languages = ["en"]
+
+# Directory of word lists (one word per line) for other languages, each named
+# after its language code (e.g. sv.txt); one named after a built-in language
+# replaces it
+# Default: ~/.config/termnotes/dictionaries/
+dictionaries = "~/.config/termnotes/dictionaries/"
+
+# Words added with zg, one per line
+# Default: ~/.config/termnotes/dictionary.txt
+personal_dictionary = "~/.config/termnotes/dictionary.txt"
+
+[accessibility]
+# Screen-reader-friendly mode: shows one pane at a time with a label line,
+# uses the real terminal cursor, and spells out mode and position in words
+# Default: false
+enabled = false
+
+# Draw on the terminal's alternate screen (disable to keep output in scrollback)
+# Default: true
+alt_screen = true
+
+[backup]
+# Before importing, emptying the trash, replacing in every note, deleting several
+# notes or restoring a backup, write a backup of every note (and attached file)
+# Default: true
+auto = true
+
+# Directory the backups are written to, as termnotes-<date>-<time>.tar.gz files;
+# also where `termnotes backup` writes unless given -o
+# Default: ~/.local/share/termnotes/backups/
+directory = "~/.local/share/termnotes/backups/"
+
+# Number of automatic backups to keep; older ones are deleted. Backups written
+# by `termnotes backup` are never deleted.
+# Default: 10
+keep = 10
+
+[debug]
+# Write a log of storage operations, sync timings and mode and focus changes, to
+# attach to bug reports (same as --debug). Note text is never logged.
+# Default: false
+enabled = false
+
+# Log file; rotated at 5 MB, keeping one old file
+# Default: ~/.termnotes/termnotes.log
+log_path = "~/.termnotes/termnotes.log"
 """
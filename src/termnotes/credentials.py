@@ -0,0 +1,120 @@
+"""
+Credentials kept in the system keyring instead of the config file
+
+Passwords and tokens (the WebDAV password, the sync token, the encryption
+passphrase and the like) can be stored in the OS keyring: the macOS
+Keychain, the Secret Service (GNOME Keyring, KWallet) or the Windows
+Credential Manager, through the optional `keyring` package. Each is kept
+under the "termnotes" service with one of the names in SECRETS, and is used
+whenever its config setting is empty:
+
+    termnotes secret set webdav
+
+Without the keyring package, or with a locked keyring, nothing is found and
+the config file's settings are all there is.
+"""
+
+from typing import Dict
+from .i18n import t
+
+SERVICE = "termnotes"
+
+# Names a secret can be stored under, and the locale key describing each
+SECRETS: Dict[str, str] = {
+    "sync": "secret.sync",
+    "webdav": "secret.webdav",
+    "postgres": "secret.postgres",
+    "encryption": "secret.encryption",
+    "server": "secret.server",
+    "github": "secret.github",
+    "paste": "secret.paste",
+    "openai": "secret.openai",
+}
+
+
+class SecretsError(RuntimeError):
+    """The keyring is missing or refused to store or remove a secret"""
+
+
+def _keyring():
+    """
+    Import the keyring package
+
+    Raises:
+        SecretsError: If it isn't installed
+    """
+    try:
+        import keyring
+    except ImportError:
+        raise SecretsError(t("secret.no_keyring"))
+    return keyring
+
+
+def keyring_available() -> bool:
+    """Check whether the keyring package is installed"""
+    try:
+        _keyring()
+    except SecretsError:
+        return False
+    return True
+
+
+def get_secret(name: str) -> str:
+    """
+    Read a secret from the system keyring
+
+    Args:
+        name: Keyring user name under the "termnotes" service, e.g. "github"
+
+    Returns:
+        The secret, or "" if there's no keyring or no such secret in it
+    """
+    try:
+        return _keyring().get_password(SERVICE, name) or ""
+    except SecretsError:
+        return ""
+    except Exception:
+        # Any keyring backend error (locked, no backend) means no secret
+        return ""
+
+
+def set_secret(name: str, value: str):
+    """
+    Store a secret in the system keyring, replacing any stored before
+
+    Args:
+        name: Keyring user name under the "termnotes" service
+        value: The secret
+
+    Raises:
+        SecretsError: If there's no keyring or it can't store the secret
+    """
+    keyring = _keyring()
+    try:
+        keyring.set_password(SERVICE, name, value)
+    except Exception as e:
+        # Backend errors differ between keyrings (locked, no backend, denied)
+        raise SecretsError(t("secret.store_failed", name=name, error=e))
+
+
+def delete_secret(name: str) -> bool:
+    """
+    Remove a secret from the system keyring
+
+    Args:
+        name: Keyring user name under the "termnotes" service
+
+    Returns:
+        False if no such secret was stored
+
+    Raises:
+        SecretsError: If there's no keyring or it can't remove the secret
+    """
+    keyring = _keyring()
+    if not get_secret(name):
+        return False
+    try:
+        keyring.delete_password(SERVICE, name)
+    except Exception as e:
+        raise SecretsError(t("secret.delete_failed", name=name, error=e))
+    return True
@@ -0,0 +1,346 @@
+"""
+Daemon: one process holding the notes for every editor and command
+
+`termnotes daemon` opens the configured storage, takes its lock and serves
+it on a unix socket next to the notes. Editors and commands started while
+it runs connect to it (see DaemonBackend) instead of opening the storage
+themselves, so several terminal windows can view and edit the same notes:
+each save reaches the others at once, and a save based on a version another
+window has since replaced is caught as a conflict rather than overwriting
+it.
+
+With `[daemon] enabled`, editors and commands start the daemon when none is
+running. It can also be started by systemd socket activation (the socket
+is then passed in as LISTEN_FDS), and it exits after `idle_timeout` seconds
+without clients.
+
+Undo and hooks stay with each client: `u` undoes that window's own changes.
+"""
+
+import json
+import os
+import signal
+import socket
+import threading
+import time
+from pathlib import Path
+from typing import Any, Dict, Optional, Set, Tuple
+from .config import get_config
+from .storage import (
+    NoteConflict, ReadOnlyBackend, ReadOnlyStorage, StorageBackend, StorageLock,
+    create_default_storage, storage_lock_path, storage_socket_path,
+)
+from .sync.protocol import SyncError, note_from_dict, note_to_dict
+from .i18n import t
+from .log import event, get_logger
+from . import __version__
+
+log = get_logger("daemon")
+
+CHECK_INTERVAL = 1.0  # Seconds between checks for other programs' changes and for idleness
+SYSTEMD_FIRST_FD = 3  # First socket systemd passes in socket activation
+
+# Methods that change notes, after which the other clients are told
+WRITE_METHODS = {"save_note", "update_note", "import_notes", "delete_note"}
+
+
+class DaemonClient:
+    """A connected editor or command"""
+
+    def __init__(self, connection: socket.socket):
+        self.connection = connection
+        self.send_lock = threading.Lock()
+
+    def send(self, message: Dict[str, Any]):
+        """Send one message, ignoring a client that went away"""
+        try:
+            with self.send_lock:
+                self.connection.sendall((json.dumps(message) + "\n").encode("utf-8"))
+        except OSError:
+            pass
+
+
+class NoteDaemon:
+    """Serves one storage backend to the clients connecting to a socket"""
+
+    def __init__(self, storage: StorageBackend, idle_timeout: float = 0):
+        """
+        Initialize the daemon
+
+        Args:
+            storage: Backend holding the notes
+            idle_timeout: Seconds without clients after which serve() returns (0 to keep serving)
+        """
+        self.storage = storage
+        self.idle_timeout = idle_timeout
+        self.lock = threading.Lock()  # Backends are used from one thread at a time
+        self.clients: Set[DaemonClient] = set()
+        self.clients_lock = threading.Lock()
+        self.stopped = threading.Event()
+
+    def call(self, method: str, params: Dict[str, Any]) -> Any:
+        """
+        Run one request on the storage
+
+        Args:
+            method: Storage method
+            params: Its arguments
+
+        Returns:
+            JSON-serializable result
+        """
+        storage = self.storage
+        if method == "hello":
+            hello = {
+                "version": __version__,
+                "pid": os.getpid(),
+                "supports_revisions": storage.supports_revisions,
+                "supports_sync": storage.supports_sync,
+                "first_run": storage.first_run,
+            }
+            storage.first_run = False  # Only the first editor shows the tour
+            return hello
+        if method == "get_all_notes":
+            return [note_to_dict(note) for note in storage.get_all_notes()]
+        if method == "get_note":
+            note = storage.get_note(params["note_id"])
+            return note_to_dict(note) if note is not None else None
+        if method == "save_note":
+            storage.save_note(note_from_dict(params["note"]))
+            return None
+        if method == "update_note":
+            storage.update_note(note_from_dict(params["note"]), params.get("expected_version"))
+            return None
+        if method == "import_notes":
+            return storage.import_notes([note_from_dict(data) for data in params["notes"]])
+        if method == "delete_note":
+            storage.delete_note(params["note_id"])
+            return None
+        if method == "search_notes":
+            return [{"note": note_to_dict(result.note), "snippet": result.snippet, "rank": result.rank}
+                    for result in storage.search_notes(params["query"])]
+        if method == "fuzzy_search_notes":
+            return [{"note": note_to_dict(result.note), "snippet": result.snippet, "rank": result.rank}
+                    for result in storage.fuzzy_search_notes(params["query"])]
+        if method == "list_revisions":
+            return [{"rev": revision.rev, "content": revision.content, "saved_at": revision.saved_at.isoformat()}
+                    for revision in storage.list_revisions(params["note_id"])]
+        if method == "flush":
+            storage.flush()
+            return None
+        if method == "sync":
+            return storage.sync()
+        if method == "pending_sync":
+            return storage.pending_sync()
+        if method == "shutdown":
+            self.stopped.set()
+            return None
+        raise ValueError(f"unknown method: {method}")
+
+    def respond(self, request: Any) -> Tuple[Dict[str, Any], bool]:
+        """
+        Answer a request
+
+        Args:
+            request: Decoded request
+
+        Returns:
+            (response, changed) where changed says whether notes were changed
+        """
+        if not isinstance(request, dict) or not isinstance(request.get("method"), str):
+            return {"id": None, "error": {"type": "error", "message": "invalid request"}}, False
+        method = request["method"]
+        response: Dict[str, Any] = {"id": request.get("id")}
+        try:
+            with self.lock:
+                response["result"] = self.call(method, request.get("params") or {})
+        except NoteConflict as e:
+            response["error"] = {"type": "conflict", "message": str(e), "stored": note_to_dict(e.stored)}
+        except ReadOnlyStorage as e:
+            response["error"] = {"type": "read_only", "message": str(e)}
+        except SyncError as e:
+            response["error"] = {"type": "sync", "message": str(e)}
+        except Exception as e:
+            # Any backend error (disk full, bad request): the client shows it
+            event(log, "request_failed", method=method, error=str(e))
+            response["error"] = {"type": "error", "message": str(e) or type(e).__name__}
+        if "error" in response:
+            return response, False
+        return response, method in WRITE_METHODS or (method == "sync" and bool(response["result"]))
+
+    def broadcast(self, exclude: Optional[DaemonClient] = None):
+        """Tell every client (but the one that made the change) that the notes changed"""
+        with self.clients_lock:
+            clients = [client for client in self.clients if client is not exclude]
+        for client in clients:
+            client.send({"event": "changed"})
+
+    def serve_client(self, client: DaemonClient):
+        """Answer a client's requests until it disconnects"""
+        try:
+            with client.connection.makefile("r", encoding="utf-8") as stream:
+                for line in stream:
+                    if not line.strip():
+                        continue
+                    try:
+                        request = json.loads(line)
+                    except ValueError:
+                        request = None
+                    response, changed = self.respond(request)
+                    if changed:
+                        self.broadcast(exclude=client)
+                    client.send(response)
+        except (OSError, ValueError):
+            pass  # Disconnected
+        finally:
+            with self.clients_lock:
+                self.clients.discard(client)
+            client.connection.close()
+
+    def serve(self, listener: socket.socket):
+        """
+        Accept clients until stopped, or idle for idle_timeout
+
+        Also checks every CHECK_INTERVAL whether another program changed the
+        notes (a synced directory, another machine), telling every client.
+
+        Args:
+            listener: Listening socket
+        """
+        listener.settimeout(CHECK_INTERVAL)
+        idle_since = time.monotonic()
+        while not self.stopped.is_set():
+            try:
+                connection, _ = listener.accept()
+            except socket.timeout:
+                connection = None
+            if connection is not None:
+                connection.settimeout(None)
+                client = DaemonClient(connection)
+                with self.clients_lock:
+                    self.clients.add(client)
+                threading.Thread(target=self.serve_client, args=(client,), daemon=True).start()
+
+            try:
+                with self.lock:
+                    changed = self.storage.poll_changes()
+            except OSError:
+                changed = False  # Try again on the next check
+            if changed:
+                self.broadcast()
+
+            with self.clients_lock:
+                has_clients = bool(self.clients)
+            if has_clients:
+                idle_since = time.monotonic()
+            elif self.idle_timeout and time.monotonic() - idle_since >= self.idle_timeout:
+                break
+
+    def close_clients(self):
+        """Disconnect every client"""
+        with self.clients_lock:
+            clients = list(self.clients)
+        for client in clients:
+            try:
+                client.connection.shutdown(socket.SHUT_RDWR)
+            except OSError:
+                pass
+
+
+def _activated_socket() -> Optional[socket.socket]:
+    """Get the socket systemd passed in, if the daemon was socket-activated"""
+    if os.environ.get("LISTEN_PID") != str(os.getpid()) or int(os.environ.get("LISTEN_FDS", "0") or 0) < 1:
+        return None
+    for name in ("LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES"):
+        os.environ.pop(name, None)  # Not for the programs hooks run
+    return socket.socket(fileno=SYSTEMD_FIRST_FD)
+
+
+def _listen(path: Path) -> socket.socket:
+    """
+    Listen on a unix socket only this user can connect to
+
+    A socket file left by a daemon that crashed is replaced; the caller
+    holds the storage lock, so no other daemon is using it.
+    """
+    path.parent.mkdir(parents=True, exist_ok=True)
+    if path.exists() or path.is_symlink():
+        path.unlink()
+    listener = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+    umask = os.umask(0o177)
+    try:
+        listener.bind(str(path))
+    finally:
+        os.umask(umask)
+    listener.listen(16)
+    return listener
+
+
+def run_daemon(idle_timeout: Optional[float] = None) -> int:
+    """
+    Serve the configured storage until stopped, idle, interrupted or terminated
+
+    Args:
+        idle_timeout: Seconds without clients before exiting (None for [daemon] idle_timeout)
+
+    Returns:
+        Process exit code
+
+    Raises:
+        RuntimeError: If another editor or daemon has the notes open, or the
+            storage can't be opened
+    """
+    if not hasattr(socket, "AF_UNIX"):
+        raise RuntimeError(t("daemon.unsupported"))
+    config = get_config()
+    path = storage_socket_path(config)
+    lock = StorageLock(storage_lock_path(config))
+    lock.acquire()
+    storage = None
+    listener = None
+    activated = False
+    try:
+        storage = create_default_storage()
+        listener = _activated_socket()
+        activated = listener is not None
+        if not activated:
+            listener = _listen(path)
+        daemon = NoteDaemon(storage, config.daemon_idle_timeout if idle_timeout is None else idle_timeout)
+        # Stop cleanly (closing the storage writes held-back changes) when terminated
+        signal.signal(signal.SIGTERM, lambda signum, frame: daemon.stopped.set())
+        event(log, "start", socket=str(path), read_only=isinstance(storage, ReadOnlyBackend))
+        print(t("daemon.listening", path=path), flush=True)
+        try:
+            daemon.serve(listener)
+        except KeyboardInterrupt:
+            pass
+        daemon.close_clients()
+        event(log, "stop")
+    finally:
+        if listener is not None:
+            listener.close()
+            if not activated and path.is_socket():
+                path.unlink()
+        if storage is not None:
+            storage.close()
+        lock.release()
+    return 0
+
+
+def stop_daemon() -> bool:
+    """
+    Ask the running daemon to exit
+
+    Returns:
+        True if a daemon was running
+    """
+    path = storage_socket_path(get_config())
+    try:
+        with socket.socket(socket.AF_UNIX, socket.SOCK_STREAM) as connection:
+            connection.settimeout(5)
+            connection.connect(str(path))
+            connection.sendall(b'{"id": 1, "method": "shutdown"}\n')
+            connection.recv(4096)  # Wait for it to acknowledge
+    except (OSError, AttributeError):
+        return False
+    return True
@@ -0,0 +1,58 @@
+"""
+Demo notes, to try termnotes with more than the welcome note
+
+`termnotes --seed-demo` adds them before the editor starts: a markdown
+showcase, a project plan in a notebook with a due date and headings for
+the outline, and a task list, linked to each other with [[wiki links]].
+Their text comes from the locale, like the welcome note's. They have
+fixed IDs, so seeding again doesn't add them twice.
+"""
+
+from datetime import timedelta
+from typing import List
+from .note import Note
+from .storage import StorageBackend
+from .utils import utc_now
+from .i18n import t
+
+# (ID, locale key of the content, notebook, tags, days until due or None)
+DEMO_NOTES = [
+    ("demo-markdown", "demo.markdown", "demo", ["demo"], None),
+    ("demo-project", "demo.project", "demo/project", ["demo", "work"], 3),
+    ("demo-tasks", "demo.tasks", "demo/project", ["demo", "todo"], None),
+]
+
+
+def demo_notes() -> List[Note]:
+    """
+    Create the demo notes
+
+    Returns:
+        New notes, not stored yet
+    """
+    notes = []
+    for note_id, content_key, notebook, tags, due_days in DEMO_NOTES:
+        note = Note(note_id=note_id, content=t(content_key))
+        note.set_property("notebook", notebook)
+        for tag in tags:
+            note.add_tag(tag)
+        if due_days is not None:
+            note.set_property("due_at", (utc_now() + timedelta(days=due_days)).isoformat())
+        notes.append(note)
+    return notes
+
+
+def add_demo_notes(storage: StorageBackend) -> int:
+    """
+    Store the demo notes that aren't stored yet
+
+    Args:
+        storage: Storage to add them to
+
+    Returns:
+        Number of notes added
+    """
+    notes = [note for note in demo_notes() if storage.get_note(note.id) is None]
+    if not notes:
+        return 0
+    return storage.import_notes(notes)
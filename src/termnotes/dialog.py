@@ -0,0 +1,85 @@
+"""
+Modal confirmation dialog
+
+While the dialog is open its own key bindings replace all others: y or
+Enter runs the action it asks about, n or Esc cancels. A dialog opened
+with choose() offers several actions instead, each run by its own key.
+"""
+
+from typing import Callable, List, Optional, Tuple
+
+# Keys for the answers of a choose() dialog listing things to pick from, in order
+CHOICE_KEYS = "123456789abcdefghijklmoprstuvwxz"
+
+
+class ConfirmDialog:
+    """State of the dialog asking the user to confirm an action"""
+
+    def __init__(self):
+        """Initialize a closed dialog"""
+        self.question: str = ""
+        self.on_confirm: Optional[Callable[[], None]] = None
+        self.options: List[Tuple[str, str, Callable[[], None]]] = []  # (key, label, action) of choose()
+        self.listed = False  # Whether choose()'s answers are shown one per line
+        self.is_open = False
+
+    def open(self, question: str, on_confirm: Callable[[], None]):
+        """
+        Ask a question, running an action if the user confirms
+
+        Args:
+            question: What the user is asked to confirm
+            on_confirm: Action to run on confirmation
+        """
+        self.question = question
+        self.on_confirm = on_confirm
+        self.is_open = True
+
+    def choose(self, question: str, options: List[Tuple[str, str, Callable[[], None]]], listed: bool = False):
+        """
+        Ask a question with several answers, each picked by a key
+
+        Args:
+            question: What the user is asked
+            options: (key, label, action) of each answer; keys other than y, n and q
+            listed: Show the answers one per line, for long or many labels
+        """
+        self.question = question
+        self.on_confirm = None
+        self.options = options
+        self.listed = listed
+        self.is_open = True
+
+    def pick(self, key: str) -> bool:
+        """
+        Close the dialog and run the action of the answer with a key
+
+        Args:
+            key: Key pressed
+
+        Returns:
+            False if no answer has that key (the dialog stays open)
+        """
+        for option_key, _, action in self.options:
+            if option_key == key:
+                self.close()
+                action()
+                return True
+        return False
+
+    def confirm(self):
+        """Close the dialog and run its action (nothing for a dialog with several answers)"""
+        if self.options:
+            return
+        on_confirm = self.on_confirm
+        self.close()
+        if on_confirm:
+            on_confirm()
+
+    def close(self):
+        """Close the dialog without running its action"""
+        self.question = ""
+        self.on_confirm = None
+        self.options = []
+        self.listed = False
+        self.is_open = False
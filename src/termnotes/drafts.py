@@ -0,0 +1,114 @@
+"""
+Drafts: unsaved edits copied to disk while editing
+
+Every few seconds the open note's unsaved title and content are written to
+a draft file next to the storage lock. Saving or discarding the edits
+removes it, so a draft is only still there on the next start if termnotes
+didn't exit normally: the terminal was closed, the machine crashed. The
+editor then offers to restore it.
+"""
+
+import json
+import os
+from dataclasses import dataclass, field
+from datetime import datetime
+from pathlib import Path
+from typing import Optional
+from .utils import utc_now
+
+
+@dataclass
+class Draft:
+    """Unsaved edits to one note"""
+    note_id: str
+    content: str
+    is_new: bool  # The note had never been saved
+    saved_at: datetime  # UTC time the draft was written
+    properties: dict = field(default_factory=dict)  # Of a new note: notebook, tags
+    base_content: Optional[str] = None  # Stored content the edits started from
+
+
+class DraftFile:
+    """The draft file of a notes location, holding at most one draft"""
+
+    def __init__(self, path: Path):
+        """
+        Initialize
+
+        Args:
+            path: Draft file (see storage_draft_path())
+        """
+        self.path = Path(path)
+        self._written: Optional[tuple] = None  # (note ID, content, base) last written, to skip rewrites
+
+    def load(self) -> Optional[Draft]:
+        """
+        Read the draft left by an earlier session
+
+        Returns:
+            The draft, or None if there's none or it can't be read
+        """
+        try:
+            data = json.loads(self.path.read_text(encoding="utf-8"))
+            return Draft(
+                note_id=data["note_id"],
+                content=data["content"],
+                is_new=bool(data.get("is_new")),
+                saved_at=datetime.fromisoformat(data["saved_at"]),
+                properties=data.get("properties") or {},
+                base_content=data.get("base_content")
+            )
+        except (OSError, ValueError, KeyError, TypeError):
+            return None
+
+    def save(self, note_id: str, content: str, is_new: bool = False, properties: Optional[dict] = None,
+             base_content: Optional[str] = None):
+        """
+        Write the draft of a note, replacing any other
+
+        Written to a temporary file and renamed into place, so a crash while
+        writing leaves the previous draft. Nothing is written if the content
+        hasn't changed since the last call.
+
+        Args:
+            note_id: ID of the note being edited
+            content: Its unsaved content
+            is_new: Whether the note has never been saved
+            properties: Properties of a new note, kept until it is saved
+            base_content: Stored content the edits started from
+
+        Raises:
+            OSError: If the file can't be written
+        """
+        if self._written == (note_id, content, base_content):
+            return
+        data = {
+            "note_id": note_id,
+            "content": content,
+            "is_new": is_new,
+            "saved_at": utc_now().isoformat(),
+            "properties": properties or {},
+            "base_content": base_content,
+        }
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        tmp_path = self.path.with_name(f".{self.path.name}.tmp")
+        try:
+            # Owner-only: a draft is note content outside the notes storage
+            fd = os.open(tmp_path, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+            with os.fdopen(fd, "w", encoding="utf-8") as f:
+                json.dump(data, f)
+            os.replace(tmp_path, self.path)
+        except BaseException:
+            tmp_path.unlink(missing_ok=True)
+            raise
+        self._written = (note_id, content, base_content)
+
+    def clear(self):
+        """Remove the draft (nothing to do if there's none)"""
+        if self._written is None and not self.path.exists():
+            return
+        try:
+            self.path.unlink(missing_ok=True)
+        except OSError:
+            return  # Left behind; the next start offers it once more
+        self._written = None
@@ -2,9 +2,10 @@
 Editor buffer and main editor class
 """
 
-from typing import List, Optional, Tuple
+from typing import Callable, List, Optional, Tuple
 from enum import Enum
 from dataclasses import dataclass
+from .textwidth import cluster_end, cluster_start, column_of, text_width
 
 
 class Mode(Enum):
@@ -24,7 +25,9 @@ class ChangeType(Enum):
     DELETE_LINE = "delete_line"
     DELETE_SELECTION = "delete_selection"
     DELETE_LINES = "delete_lines"
+    INSERT_LINES = "insert_lines"
     PASTE_TEXT = "paste_text"
+    REPLACE_TEXT = "replace_text"
 
 
 @dataclass
@@ -42,6 +45,15 @@ class Change:
     end_col: Optional[int] = None
 
 
+def _char_class(ch: str) -> int:
+    """Classify a character for word motions: 0 whitespace, 1 word, 2 punctuation"""
+    if ch.isspace():
+        return 0
+    if ch.isalnum() or ch == '_':
+        return 1
+    return 2
+
+
 class UndoManager:
     """Manages undo/redo history with linear stack"""
 
@@ -138,9 +150,12 @@ class EditorBuffer:
         self.is_dirty: bool = False  # Track if buffer has unsaved changes
         self.current_note_id: str = None  # Track which note is currently loaded
         self.is_new_unsaved: bool = False  # Track if this is a new note not yet in storage
+        self.base_content: str = initial_text  # Stored content the edits started from, to detect conflicts
         self.mode_manager = mode_manager  # Reference to mode manager for mode-aware cursor behavior
         self.yank_register: str = ""  # Store yanked text for paste operations
         self.yank_is_linewise: bool = False  # Track if yanked text is line-wise or character-wise
+        self.on_yank: Optional[Callable[[str], None]] = None  # Told the text of each yank, e.g. to copy it
+        self.on_load: Optional[Callable[[str], None]] = None  # Told the ID of each stored note loaded
         self.undo_manager: UndoManager = UndoManager()  # Undo/redo manager
 
     @property
@@ -157,7 +172,7 @@ class EditorBuffer:
         """
         Get the maximum cursor column position for the current mode.
 
-        In Normal mode: cursor must be ON a character (max = len(line) - 1)
+        In Normal mode: cursor must be ON a character (the last grapheme cluster's start)
         In Insert mode: cursor can be AFTER the last character (max = len(line))
 
         Args:
@@ -172,8 +187,8 @@ class EditorBuffer:
         # If we have a mode_manager and we're in normal mode
         if self.mode_manager and self.mode_manager.is_normal_mode():
             # Normal mode: cursor must be on a character
-            # Empty line: cursor at 0, otherwise max is len-1
-            return max(0, len(line) - 1) if line else 0
+            # Empty line: cursor at 0, otherwise on the last character with its combining marks
+            return cluster_start(line, len(line) - 1) if line else 0
         else:
             # Insert mode (or no mode_manager): cursor can be after last char
             return len(line)
@@ -214,24 +229,46 @@ class EditorBuffer:
         # Ensure scroll_offset is non-negative
         self.scroll_offset = max(0, self.scroll_offset)
 
+    def scroll_lines(self, lines: int, visible_height: int):
+        """
+        Scroll the view without moving the cursor, unless it would leave the view (vim Ctrl+E and Ctrl+Y)
+
+        Args:
+            lines: Lines to scroll down (negative: up)
+            visible_height: Number of lines visible in the editor window
+        """
+        if visible_height <= 0:
+            return
+        self.scroll_offset = max(0, min(self.scroll_offset + lines, len(self.lines) - visible_height))
+        row = min(max(self.cursor_row, self.scroll_offset), self.scroll_offset + visible_height - 1)
+        if row != self.cursor_row:
+            self.cursor_row = row
+            self.cursor_col = cluster_start(self.current_line, min(self.cursor_col, self.get_max_cursor_col()))
+
     def adjust_horizontal_scroll(self, visible_width: int):
         """
         Adjust horizontal scroll offset to keep cursor visible within the window.
         Scrolls only when cursor reaches the edge (no margin).
 
+        The offset is in display columns, which wide characters take two of.
+
         Args:
             visible_width: Number of columns visible in the editor window
         """
         if visible_width <= 0:
             return
 
+        line = self.current_line
+        column = column_of(line, self.cursor_col)
+        width = max(1, text_width(line[self.cursor_col:cluster_end(line, self.cursor_col)]))
+
         # Scroll right if cursor is at or beyond right edge
-        if self.cursor_col >= self.horizontal_scroll_offset + visible_width:
-            self.horizontal_scroll_offset = self.cursor_col - visible_width + 1
+        if column + width > self.horizontal_scroll_offset + visible_width:
+            self.horizontal_scroll_offset = column + width - visible_width
 
         # Scroll left if cursor is before left edge
-        if self.cursor_col < self.horizontal_scroll_offset:
-            self.horizontal_scroll_offset = self.cursor_col
+        if column < self.horizontal_scroll_offset:
+            self.horizontal_scroll_offset = column
 
         # Ensure horizontal_scroll_offset is non-negative
         self.horizontal_scroll_offset = max(0, self.horizontal_scroll_offset)
@@ -307,27 +344,30 @@ class EditorBuffer:
         self.current_note_id = note_id
         self.is_dirty = False
         self.is_new_unsaved = is_new
+        self.base_content = content
         # Clear undo history when loading new content
         self.undo_manager.clear()
+        if note_id and not is_new and self.on_load:
+            self.on_load(note_id)
 
     # Cursor movement
     def move_cursor_left(self):
-        """Move cursor left"""
+        """Move cursor left, past a whole character with its combining marks"""
         if self.cursor_col > 0:
-            self.cursor_col -= 1
+            self.cursor_col = cluster_start(self.current_line, self.cursor_col - 1)
 
     def move_cursor_right(self):
-        """Move cursor right"""
+        """Move cursor right, past a whole character with its combining marks"""
         max_col = self.get_max_cursor_col()
         if self.cursor_col < max_col:
-            self.cursor_col += 1
+            self.cursor_col = min(max_col, cluster_end(self.current_line, self.cursor_col))
 
     def move_cursor_up(self, visible_height: int = None):
         """Move cursor up"""
         if self.cursor_row > 0:
             self.cursor_row -= 1
-            # Adjust column if necessary
-            self.cursor_col = min(self.cursor_col, self.get_max_cursor_col())
+            # Adjust column if necessary, onto the start of a character
+            self.cursor_col = cluster_start(self.current_line, min(self.cursor_col, self.get_max_cursor_col()))
             # Adjust scroll if visible_height provided
             if visible_height is not None:
                 self.adjust_scroll(visible_height)
@@ -336,12 +376,17 @@ class EditorBuffer:
         """Move cursor down"""
         if self.cursor_row < len(self.lines) - 1:
             self.cursor_row += 1
-            # Adjust column if necessary
-            self.cursor_col = min(self.cursor_col, self.get_max_cursor_col())
+            # Adjust column if necessary, onto the start of a character
+            self.cursor_col = cluster_start(self.current_line, min(self.cursor_col, self.get_max_cursor_col()))
             # Adjust scroll if visible_height provided
             if visible_height is not None:
                 self.adjust_scroll(visible_height)
 
+    def move_cursor_to(self, row: int, col: int):
+        """Move cursor to a position (clicked on), kept within the text and onto the start of a character"""
+        self.cursor_row = max(0, min(row, len(self.lines) - 1))
+        self.cursor_col = cluster_start(self.current_line, min(max(0, col), self.get_max_cursor_col()))
+
     def move_cursor_to_line_start(self):
         """Move cursor to start of line"""
         self.cursor_col = 0
@@ -350,6 +395,91 @@ class EditorBuffer:
         """Move cursor to end of line"""
         self.cursor_col = self.get_max_cursor_col()
 
+    def move_word_forward(self, visible_height: int = None):
+        """Move cursor to the start of the next word (vim w), crossing lines"""
+        row, col = self.cursor_row, self.cursor_col
+        line = self.lines[row]
+
+        # Skip the rest of the current word
+        if col < len(line) and _char_class(line[col]) != 0:
+            cls = _char_class(line[col])
+            while col < len(line) and _char_class(line[col]) == cls:
+                col += 1
+
+        # Skip whitespace, moving to following lines; an empty line counts as a word
+        while True:
+            while col < len(line) and _char_class(line[col]) == 0:
+                col += 1
+            if col < len(line) or row == len(self.lines) - 1:
+                break
+            row += 1
+            col = 0
+            line = self.lines[row]
+            if not line:
+                break
+
+        self.cursor_row = row
+        self.cursor_col = min(col, self.get_max_cursor_col(line))
+        if visible_height is not None:
+            self.adjust_scroll(visible_height)
+
+    def move_word_backward(self, visible_height: int = None):
+        """Move cursor to the start of the previous word (vim b), crossing lines"""
+        row, col = self.cursor_row, min(self.cursor_col, len(self.lines[self.cursor_row]))
+        line = self.lines[row]
+        col -= 1
+
+        # Skip whitespace, moving to previous lines; an empty line counts as a word
+        while True:
+            while col >= 0 and _char_class(line[col]) == 0:
+                col -= 1
+            if col >= 0 or row == 0:
+                break
+            row -= 1
+            line = self.lines[row]
+            col = len(line) - 1
+            if not line:
+                break
+
+        # Move to the start of the word
+        if col >= 0:
+            cls = _char_class(line[col])
+            while col > 0 and _char_class(line[col - 1]) == cls:
+                col -= 1
+
+        self.cursor_row = row
+        self.cursor_col = max(0, col)
+        if visible_height is not None:
+            self.adjust_scroll(visible_height)
+
+    def move_word_end(self, visible_height: int = None):
+        """Move cursor to the end of the current or next word (vim e), crossing lines"""
+        row, col = self.cursor_row, self.cursor_col + 1
+        line = self.lines[row]
+
+        # Skip whitespace, moving to following lines
+        while True:
+            while col < len(line) and _char_class(line[col]) == 0:
+                col += 1
+            if col < len(line):
+                break
+            if row == len(self.lines) - 1:
+                self.cursor_col = self.get_max_cursor_col()
+                return
+            row += 1
+            col = 0
+            line = self.lines[row]
+
+        # Move to the last character of the word
+        cls = _char_class(line[col])
+        while col + 1 < len(line) and _char_class(line[col + 1]) == cls:
+            col += 1
+
+        self.cursor_row = row
+        self.cursor_col = col
+        if visible_height is not None:
+            self.adjust_scroll(visible_height)
+
     def jump_to_top(self, visible_height: int = None):
         """Jump to the first line of the file (vim gg)"""
         self.cursor_row = 0
@@ -494,7 +624,7 @@ class EditorBuffer:
         self.lines[self.cursor_row] = (
             line[:self.cursor_col] + char + line[self.cursor_col:]
         )
-        self.cursor_col += 1
+        self.cursor_col += len(char)
 
         change.cursor_pos_after = (self.cursor_row, self.cursor_col)
         self.undo_manager.add_change_block([change])
@@ -562,10 +692,11 @@ class EditorBuffer:
             self.adjust_scroll(visible_height)
 
     def delete_char_at_cursor(self):
-        """Delete character at cursor position"""
+        """Delete character at cursor position, with its combining marks"""
         line = self.lines[self.cursor_row]
         if self.cursor_col < len(line):
-            deleted_char = line[self.cursor_col]
+            end = cluster_end(line, self.cursor_col)
+            deleted_char = line[self.cursor_col:end]
 
             # Record change for undo
             change = Change(
@@ -577,7 +708,7 @@ class EditorBuffer:
             )
 
             self.lines[self.cursor_row] = (
-                line[:self.cursor_col] + line[self.cursor_col + 1:]
+                line[:self.cursor_col] + line[end:]
             )
 
             change.cursor_pos_after = (self.cursor_row, self.cursor_col)
@@ -586,24 +717,25 @@ class EditorBuffer:
             self.mark_dirty()
 
     def backspace(self):
-        """Delete character before cursor"""
+        """Delete character before cursor, with its combining marks"""
         if self.cursor_col > 0:
             line = self.lines[self.cursor_row]
-            deleted_char = line[self.cursor_col - 1]
+            start = cluster_start(line, self.cursor_col - 1)
+            deleted_char = line[start:self.cursor_col]
 
             # Record change for undo
             change = Change(
                 type=ChangeType.BACKSPACE,
                 row=self.cursor_row,
-                col=self.cursor_col - 1,
+                col=start,
                 text=deleted_char,
                 cursor_pos_before=(self.cursor_row, self.cursor_col)
             )
 
             self.lines[self.cursor_row] = (
-                line[:self.cursor_col - 1] + line[self.cursor_col:]
+                line[:start] + line[self.cursor_col:]
             )
-            self.cursor_col -= 1
+            self.cursor_col = start
 
             change.cursor_pos_after = (self.cursor_row, self.cursor_col)
             self.undo_manager.add_change_block([change])
@@ -635,6 +767,25 @@ class EditorBuffer:
 
             self.mark_dirty()
 
+    def delete_word_before_cursor(self):
+        """Delete from the start of the previous word to the cursor (insert mode Ctrl+W)"""
+        if self.cursor_col == 0:
+            # At line start, join with the previous line like backspace
+            self.backspace()
+            return
+
+        line = self.lines[self.cursor_row]
+        end_col = min(self.cursor_col, len(line))
+        start_col = end_col
+        while start_col > 0 and _char_class(line[start_col - 1]) == 0:
+            start_col -= 1
+        if start_col > 0:
+            cls = _char_class(line[start_col - 1])
+            while start_col > 0 and _char_class(line[start_col - 1]) == cls:
+                start_col -= 1
+
+        self.delete_selection(self.cursor_row, start_col, self.cursor_row, end_col - 1)
+
     def insert_newline(self, visible_height: int = None):
         """Insert a new line at cursor position"""
         line = self.lines[self.cursor_row]
@@ -854,6 +1005,26 @@ class EditorBuffer:
 
         return False
 
+    def find_matches(self, query: str) -> List[Tuple[int, int]]:
+        """
+        Find every occurrence of query, as search_forward() matches it
+
+        Args:
+            query: The search string
+
+        Returns:
+            (row, column) of each match, in order
+        """
+        matches = []
+        if not query:
+            return matches
+        for row, line in enumerate(self.lines):
+            col = line.find(query)
+            while col != -1:
+                matches.append((row, col))
+                col = line.find(query, col + len(query))
+        return matches
+
     # Visual mode operations
     def get_selection_text(self, start_row: int, start_col: int, end_row: int, end_col: int) -> str:
         """
@@ -895,6 +1066,8 @@ class EditorBuffer:
         """
         self.yank_register = self.get_selection_text(start_row, start_col, end_row, end_col)
         self.yank_is_linewise = False
+        if self.on_yank:
+            self.on_yank(self.yank_register)
 
     def delete_selection(self, start_row: int, start_col: int, end_row: int, end_col: int, visible_height: int = None):
         """
@@ -980,6 +1153,16 @@ class EditorBuffer:
                 # Paste before current line (P)
                 insert_pos = self.cursor_row
 
+            # Record change for undo
+            change = Change(
+                type=ChangeType.INSERT_LINES,
+                row=insert_pos,
+                col=0,
+                text=text_to_paste,
+                cursor_pos_before=(self.cursor_row, self.cursor_col),
+                end_row=insert_pos + len(lines_to_paste) - 1
+            )
+
             # Insert the lines
             for i, line in enumerate(lines_to_paste):
                 self.lines.insert(insert_pos + i, line)
@@ -988,6 +1171,9 @@ class EditorBuffer:
             self.cursor_row = insert_pos
             self.cursor_col = 0
 
+            change.cursor_pos_after = (self.cursor_row, self.cursor_col)
+            self.undo_manager.add_change_block([change])
+
             self.mark_dirty()
 
             if visible_height is not None:
@@ -999,6 +1185,39 @@ class EditorBuffer:
                 self.move_cursor_right()
             self.paste_text(self.yank_register, visible_height)
 
+    def replace_text(self, text: str, visible_height: int = None):
+        """
+        Replace the whole text as one change that can be undone
+
+        The cursor stays on the same line and column where they still exist.
+
+        Args:
+            text: New text
+            visible_height: Height of visible editor area for scroll adjustment
+        """
+        if text == self.get_text():
+            return
+
+        change = Change(
+            type=ChangeType.REPLACE_TEXT,
+            row=0,
+            col=0,
+            text=text,
+            lines_deleted=list(self.lines),
+            cursor_pos_before=(self.cursor_row, self.cursor_col)
+        )
+        self.lines = text.split('\n')
+        self.cursor_row = min(self.cursor_row, len(self.lines) - 1)
+        self.cursor_col = min(self.cursor_col, self.get_max_cursor_col())
+
+        change.cursor_pos_after = (self.cursor_row, self.cursor_col)
+        self.undo_manager.add_change_block([change])
+
+        self.mark_dirty()
+
+        if visible_height is not None:
+            self.adjust_scroll(visible_height)
+
     # Visual line mode operations
     def yank_lines(self, start_row: int, end_row: int):
         """
@@ -1012,6 +1231,20 @@ class EditorBuffer:
         # Join with newlines and add trailing newline to indicate line-wise yank
         self.yank_register = '\n'.join(lines_to_yank) + '\n'
         self.yank_is_linewise = True
+        if self.on_yank:
+            self.on_yank(self.yank_register)
+
+    def load_register(self, text: str):
+        """
+        Put text from elsewhere (the system clipboard) in the yank register
+
+        Text ending in a newline is pasted line-wise, like lines yanked here.
+
+        Args:
+            text: Text to paste with p
+        """
+        self.yank_register = text
+        self.yank_is_linewise = text.endswith('\n')
 
     def delete_lines(self, start_row: int, end_row: int, visible_height: int = None):
         """
@@ -1061,6 +1294,150 @@ class EditorBuffer:
         if visible_height is not None:
             self.adjust_scroll(visible_height)
 
+    # Operator ranges (vim d, c and y with a motion or text object)
+    def word_object(self, around: bool = False) -> Optional[Tuple[int, int]]:
+        """
+        Get the columns of the word under the cursor (vim iw, or aw with around)
+
+        Like in vim, a run of whitespace or punctuation is a word too. Around a
+        word also takes the whitespace after it, or before it if there is none after.
+
+        Args:
+            around: Include the surrounding whitespace
+
+        Returns:
+            Tuple of (start column, end column), both inclusive, or None on an empty line
+        """
+        line = self.current_line
+        if not line:
+            return None
+        col = min(self.cursor_col, len(line) - 1)
+        cls = _char_class(line[col])
+        start = end = col
+        while start > 0 and _char_class(line[start - 1]) == cls:
+            start -= 1
+        while end + 1 < len(line) and _char_class(line[end + 1]) == cls:
+            end += 1
+
+        if around and cls == 0:
+            # Whitespace and the word after it
+            if end + 1 < len(line):
+                next_cls = _char_class(line[end + 1])
+                while end + 1 < len(line) and _char_class(line[end + 1]) == next_cls:
+                    end += 1
+        elif around:
+            if end + 1 < len(line) and _char_class(line[end + 1]) == 0:
+                while end + 1 < len(line) and _char_class(line[end + 1]) == 0:
+                    end += 1
+            else:
+                while start > 0 and _char_class(line[start - 1]) == 0:
+                    start -= 1
+        return start, end
+
+    def word_motion_end(self, change: bool = False) -> Optional[int]:
+        """
+        Get the last column vim dw (or cw, with change) acts on, from the cursor
+
+        dw takes the rest of the word and the whitespace after it, stopping at
+        the end of the line. cw leaves the whitespace, as in vim.
+
+        Args:
+            change: Leave the whitespace after the word
+
+        Returns:
+            End column (inclusive), or None on an empty line
+        """
+        line = self.current_line
+        if not line:
+            return None
+        col = min(self.cursor_col, len(line) - 1)
+        cls = _char_class(line[col])
+        while col + 1 < len(line) and _char_class(line[col + 1]) == cls:
+            col += 1
+        if not change or cls == 0:
+            while col + 1 < len(line) and _char_class(line[col + 1]) == 0:
+                col += 1
+        return col
+
+    def delete_range(self, start_col: int, end_col: int, visible_height: int = None):
+        """
+        Yank and delete characters of the current line
+
+        Args:
+            start_col: First column to delete
+            end_col: Last column to delete (inclusive)
+            visible_height: Height of visible editor area for scroll adjustment
+        """
+        row = self.cursor_row
+        self.yank_selection(row, start_col, row, end_col)
+        self.delete_selection(row, start_col, row, end_col, visible_height)
+
+    def change_line(self, visible_height: int = None):
+        """
+        Yank the current line and clear it, keeping its indentation (vim cc)
+
+        Args:
+            visible_height: Height of visible editor area for scroll adjustment
+        """
+        line = self.current_line
+        indent = len(line) - len(line.lstrip())
+        self.yank_lines(self.cursor_row, self.cursor_row)
+        if indent < len(line):
+            self.delete_selection(self.cursor_row, indent, self.cursor_row, len(line) - 1, visible_height)
+        self.cursor_col = indent
+
+    def indent_line(self, width: int):
+        """
+        Indent the cursor line, the cursor staying on the same character
+
+        Args:
+            width: Spaces to add at the start of the line
+        """
+        change = Change(
+            type=ChangeType.PASTE_TEXT,
+            row=self.cursor_row,
+            col=0,
+            text=" " * width,
+            cursor_pos_before=(self.cursor_row, self.cursor_col)
+        )
+        self.lines[self.cursor_row] = " " * width + self.lines[self.cursor_row]
+        self.cursor_col += width
+
+        change.cursor_pos_after = (self.cursor_row, self.cursor_col)
+        self.undo_manager.add_change_block([change])
+        self.mark_dirty()
+
+    def outdent_line(self, width: int) -> bool:
+        """
+        Remove up to a number of spaces from the start of the cursor line, the cursor staying on the same character
+
+        Args:
+            width: Spaces to remove at most
+
+        Returns:
+            False if the line doesn't start with a space
+        """
+        line = self.current_line
+        spaces = min(width, len(line) - len(line.lstrip(' ')))
+        if spaces == 0:
+            return False
+        change = Change(
+            type=ChangeType.DELETE_SELECTION,
+            row=self.cursor_row,
+            col=0,
+            text=line[:spaces],
+            cursor_pos_before=(self.cursor_row, self.cursor_col),
+            end_row=self.cursor_row,
+            end_col=spaces - 1
+        )
+        self.lines[self.cursor_row] = line[spaces:]
+        self.cursor_col = max(0, self.cursor_col - spaces)
+
+        change.cursor_pos_after = (self.cursor_row, self.cursor_col)
+        self.undo_manager.add_change_block([change])
+        self.mark_dirty()
+        return True
+
     # Undo/Redo operations
     def undo(self, visible_height: int = None) -> bool:
         """
@@ -1130,7 +1507,7 @@ class EditorBuffer:
         if change.type == ChangeType.INSERT_CHAR:
             # Undo insert: delete the character
             line = self.lines[change.row]
-            self.lines[change.row] = line[:change.col] + line[change.col + 1:]
+            self.lines[change.row] = line[:change.col] + line[change.col + len(change.text):]
 
         elif change.type == ChangeType.DELETE_CHAR:
             # Undo delete: insert the character back
@@ -1181,6 +1558,16 @@ class EditorBuffer:
                         # Check if last line should be removed (was added when buffer became empty)
                         pass
 
+        elif change.type == ChangeType.INSERT_LINES:
+            # Undo line-wise paste: remove the inserted lines
+            del self.lines[change.row:change.end_row + 1]
+            if not self.lines:
+                self.lines = [""]
+
+        elif change.type == ChangeType.REPLACE_TEXT:
+            # Undo replacing the whole text: put the old lines back
+            self.lines = list(change.lines_deleted)
+
         elif change.type == ChangeType.PASTE_TEXT:
             # Undo paste: delete the pasted text
             paste_lines = change.text.split('\n')
@@ -1220,7 +1607,7 @@ class EditorBuffer:
         elif change.type == ChangeType.DELETE_CHAR:
             # Redo delete: delete the character
             line = self.lines[change.row]
-            self.lines[change.row] = line[:change.col] + line[change.col + 1:]
+            self.lines[change.row] = line[:change.col] + line[change.col + len(change.text):]
 
         elif change.type == ChangeType.BACKSPACE:
             if change.text == "\n":
@@ -1233,7 +1620,7 @@ class EditorBuffer:
             else:
                 # Redo backspace: delete the character
                 line = self.lines[change.row]
-                self.lines[change.row] = line[:change.col] + line[change.col + 1:]
+                self.lines[change.row] = line[:change.col] + line[change.col + len(change.text):]
 
         elif change.type == ChangeType.INSERT_NEWLINE:
             # Redo newline: split the line
@@ -1271,6 +1658,15 @@ class EditorBuffer:
                 if not self.lines:
                     self.lines = [""]
 
+        elif change.type == ChangeType.INSERT_LINES:
+            # Redo line-wise paste: insert the lines again
+            for i, line in enumerate(change.text.split('\n')):
+                self.lines.insert(change.row + i, line)
+
+        elif change.type == ChangeType.REPLACE_TEXT:
+            # Redo replacing the whole text
+            self.lines = change.text.split('\n')
+
         elif change.type == ChangeType.PASTE_TEXT:
             # Redo paste: paste the text again
             paste_lines = change.text.split('\n')
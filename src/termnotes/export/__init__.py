@@ -0,0 +1,82 @@
+"""
+Exporting notes to files for reading or sharing outside termnotes
+
+- html: standalone HTML pages rendered from the notes' markdown
+- pdf: PDF documents written without a PDF library
+- site: a static website of linked pages (see site.py, "termnotes publish")
+
+Exported files are named after note titles and placed in subdirectories for
+notebooks, like the markdown backend's files. Each file's modification time
+is set to the note's updated time.
+"""
+
+import calendar
+import os
+from pathlib import Path
+from typing import Callable, Dict, Iterable, List, Tuple, Union
+from .html import note_to_html
+from .pdf import note_to_pdf
+from ..note import Note
+from ..notebook import get_note_notebook
+from ..storage.frontmatter import note_to_markdown
+from ..storage.markdown_backend import MarkdownBackend
+
+
+def _markdown(note: Note) -> str:
+    """Markdown with a frontmatter header; the directory records the notebook"""
+    properties = {k: v for k, v in note.properties.items() if k != "notebook"}
+    return note_to_markdown(Note(note.id, note.content, note.created_at, note.updated_at, properties))
+
+
+# Format name to (file extension, renderer returning text or bytes)
+FORMATS: Dict[str, Tuple[str, Callable[[Note], Union[str, bytes]]]] = {
+    "md": (".md", _markdown),
+    "html": (".html", note_to_html),
+    "pdf": (".pdf", note_to_pdf),
+}
+
+
+def export_notes(notes: Iterable[Note], directory: str, fmt: str = "md") -> List[Path]:
+    """
+    Write notes to files in a directory
+
+    Files that already exist are overwritten, so exporting again into the
+    same directory refreshes it; notes with the same title in one run get
+    numbered names ("Title 2").
+
+    Args:
+        notes: Notes to export
+        directory: Directory to write to (created if missing)
+        fmt: Format name from FORMATS
+
+    Returns:
+        Paths of the written files
+    """
+    extension, render = FORMATS[fmt]
+    root = Path(directory).expanduser()
+    written: List[Path] = []
+    taken = set()
+
+    for note in notes:
+        notebook = get_note_notebook(note)
+        folder = root / notebook if notebook else root
+        base = MarkdownBackend.filename_for(note)
+        path = folder / f"{base}{extension}"
+        counter = 2
+        while path in taken:
+            path = folder / f"{base} {counter}{extension}"
+            counter += 1
+        taken.add(path)
+
+        folder.mkdir(parents=True, exist_ok=True)
+        output = render(note)
+        if isinstance(output, bytes):
+            path.write_bytes(output)
+        else:
+            path.write_text(output, encoding="utf-8")
+
+        # Timestamps are naive UTC
+        updated = calendar.timegm(note.updated_at.timetuple())
+        os.utime(path, (updated, updated))
+        written.append(path)
+    return written
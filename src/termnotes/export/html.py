@@ -0,0 +1,254 @@
+"""
+Standalone HTML export: notes rendered from markdown into self-contained pages
+"""
+
+import html
+import re
+from typing import List, Tuple
+from pygments import highlight
+from pygments.formatters import HtmlFormatter
+from pygments.lexers import TextLexer, get_lexer_by_name
+from pygments.util import ClassNotFound
+from ..note import Note
+from ..i18n import t
+
+PAGE_STYLE = """
+body { max-width: 48em; margin: 2em auto; padding: 0 1em; font-family: sans-serif; line-height: 1.5; color: #222; }
+header.meta { color: #777; font-size: 0.85em; border-bottom: 1px solid #ddd; margin-bottom: 1.5em; }
+code { font-family: monospace; background: #f4f4f4; padding: 0 0.2em; }
+.highlight { background: #f8f8f8; padding: 0.5em 1em; overflow-x: auto; }
+.highlight pre { margin: 0; }
+blockquote { border-left: 3px solid #ccc; margin-left: 0; padding-left: 1em; color: #555; }
+li.task { list-style: none; }
+"""
+
+HEADING = re.compile(r'^(#{1,6})\s+(.*?)\s*#*\s*$')
+LIST_ITEM = re.compile(r'^(\s*)([-*+]|\d+[.)])\s+(.*)$')
+TASK = re.compile(r'^\[([ xX])\]\s+(.*)$')
+RULE = re.compile(r'^\s*([-*_])(\s*\1){2,}\s*$')
+FENCE = re.compile(r'^\s*(```|~~~)\s*([\w+-]*)')
+
+# Inline patterns, tried in order at each position
+INLINE = [
+    (re.compile(r'!\[([^\]]*)\]\(([^)\s]+)\)'), lambda m: f'<img src="{m.group(2)}" alt="{m.group(1)}">'),
+    (re.compile(r'\[([^\]]+)\]\(([^)\s]+)\)'), lambda m: f'<a href="{m.group(2)}">{render_inline(m.group(1))}</a>'),
+    (re.compile(r'(\*\*\*|(?<!\w)___)(.+?)\1'), lambda m: f'<strong><em>{render_inline(m.group(2))}</em></strong>'),
+    (re.compile(r'(\*\*|(?<!\w)__)(.+?)\1'), lambda m: f'<strong>{render_inline(m.group(2))}</strong>'),
+    (re.compile(r'(\*|(?<!\w)_)(.+?)\1(?!\w)'), lambda m: f'<em>{render_inline(m.group(2))}</em>'),
+    (re.compile(r'~~(.+?)~~'), lambda m: f'<del>{render_inline(m.group(1))}</del>'),
+    (re.compile(r'https?://[^\s<]+[^\s<.,;:!?)\]]'), lambda m: f'<a href="{m.group(0)}">{m.group(0)}</a>'),
+]
+CODE_SPAN = re.compile(r'`([^`]+)`')
+
+
+def render_inline(text: str) -> str:
+    """
+    Render inline markdown (code, emphasis, links, images) in escaped text
+
+    Args:
+        text: HTML-escaped text of a line
+
+    Returns:
+        HTML
+    """
+    result = []
+    pos = 0
+    while pos < len(text):
+        for pattern, render in INLINE:
+            match = pattern.match(text, pos)
+            if match:
+                result.append(render(match))
+                pos = match.end()
+                break
+        else:
+            result.append(text[pos])
+            pos += 1
+    return "".join(result)
+
+
+def render_line(line: str) -> str:
+    """
+    Escape a line and render its inline markdown, leaving code spans as written
+
+    Args:
+        line: Markdown text
+
+    Returns:
+        HTML
+    """
+    parts = []
+    pos = 0
+    for match in CODE_SPAN.finditer(line):
+        parts.append(render_inline(html.escape(line[pos:match.start()])))
+        parts.append(f"<code>{html.escape(match.group(1))}</code>")
+        pos = match.end()
+    parts.append(render_inline(html.escape(line[pos:])))
+    return "".join(parts)
+
+
+def render_code_block(code: str, language: str) -> str:
+    """
+    Render a fenced code block with syntax highlighting
+
+    Args:
+        code: Code inside the fences
+        language: Language after the opening fence ("" for none)
+
+    Returns:
+        HTML
+    """
+    try:
+        lexer = get_lexer_by_name(language) if language else TextLexer()
+    except ClassNotFound:
+        lexer = TextLexer()
+    return highlight(code, lexer, HtmlFormatter())
+
+
+def render_list(items: List[Tuple[int, str, str]]) -> str:
+    """
+    Render consecutive list items, nesting them by indentation
+
+    Args:
+        items: (indent, marker, text) for each item; marker is "-" or "1"
+
+    Returns:
+        HTML
+    """
+    out = []
+    stack: List[Tuple[int, str]] = []  # (indent, tag) of each open list
+    for indent, marker, text in items:
+        tag = "ul" if marker == "-" else "ol"
+        while stack and indent < stack[-1][0]:
+            out.append(f"</li></{stack.pop()[1]}>")
+        if stack and indent == stack[-1][0] and tag != stack[-1][1]:
+            out.append(f"</li></{stack.pop()[1]}>")  # A different kind of list starts
+        if stack and indent == stack[-1][0]:
+            out.append("</li>")
+        else:
+            out.append(f"<{tag}>")
+            stack.append((indent, tag))
+
+        task = TASK.match(text)
+        if task:
+            checked = " checked" if task.group(1) != " " else ""
+            out.append(f'<li class="task"><input type="checkbox" disabled{checked}> {render_line(task.group(2))}')
+        else:
+            out.append(f"<li>{render_line(text)}")
+    while stack:
+        out.append(f"</li></{stack.pop()[1]}>")
+    return "\n".join(out)
+
+
+def markdown_to_html(text: str) -> str:
+    """
+    Convert note markdown to HTML
+
+    Covers what notes commonly use: headings, paragraphs, lists and task
+    lists, block quotes, rules, fenced code blocks, and inline code,
+    emphasis, links and images. Line breaks inside a paragraph are kept,
+    since notes are written line by line.
+
+    Args:
+        text: Markdown text
+
+    Returns:
+        HTML fragment
+    """
+    out = []
+    lines = text.split("\n")
+    paragraph: List[str] = []
+    items: List[Tuple[int, str, str]] = []
+    quote: List[str] = []
+
+    def flush():
+        if paragraph:
+            out.append("<p>" + "<br>\n".join(render_line(line) for line in paragraph) + "</p>")
+            paragraph.clear()
+        if items:
+            out.append(render_list(items))
+            items.clear()
+        if quote:
+            out.append("<blockquote>\n" + markdown_to_html("\n".join(quote)) + "\n</blockquote>")
+            quote.clear()
+
+    i = 0
+    while i < len(lines):
+        line = lines[i]
+        fence = FENCE.match(line)
+        if fence:
+            flush()
+            code = []
+            i += 1
+            while i < len(lines) and not lines[i].strip().startswith(fence.group(1)):
+                code.append(lines[i])
+                i += 1
+            out.append(render_code_block("\n".join(code) + "\n", fence.group(2)))
+        elif not line.strip():
+            flush()
+        elif line.lstrip().startswith(">"):
+            if not quote:
+                flush()
+            quote.append(re.sub(r'^\s*>\s?', "", line))
+        elif HEADING.match(line):
+            flush()
+            heading = HEADING.match(line)
+            level = len(heading.group(1))
+            out.append(f"<h{level}>{render_line(heading.group(2))}</h{level}>")
+        elif RULE.match(line):
+            flush()
+            out.append("<hr>")
+        elif LIST_ITEM.match(line):
+            if not items:
+                flush()
+            item = LIST_ITEM.match(line)
+            marker = "1" if item.group(2)[0].isdigit() else "-"
+            items.append((len(item.group(1).expandtabs(4)), marker, item.group(3)))
+        elif items and line.startswith((" ", "\t")):
+            # Continuation of the previous list item
+            indent, marker, item_text = items[-1]
+            items[-1] = (indent, marker, f"{item_text} {line.strip()}")
+        else:
+            if items or quote:
+                flush()
+            paragraph.append(line)
+        i += 1
+    flush()
+    return "\n".join(out)
+
+
+def note_to_html(note: Note) -> str:
+    """
+    Render a note as a standalone HTML page
+
+    The page has the note's title, its creation and update times in a header
+    and in meta tags, and all styles inline so it needs no other files.
+
+    Args:
+        note: Note to render
+
+    Returns:
+        HTML document
+    """
+    title = html.escape(note.title or "Untitled")
+    created = note.created_at.strftime("%Y-%m-%d %H:%M UTC")
+    updated = note.updated_at.strftime("%Y-%m-%d %H:%M UTC")
+    code_style = HtmlFormatter().get_style_defs(".highlight")
+    return f"""<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{title}</title>
+<meta name="created" content="{note.created_at.isoformat()}Z">
+<meta name="modified" content="{note.updated_at.isoformat()}Z">
+<style>{PAGE_STYLE}
+{code_style}
+</style>
+</head>
+<body>
+<header class="meta">{html.escape(t("export.timestamps", created=created, updated=updated))}</header>
+<article>
+{markdown_to_html(note.content)}
+</article>
+</body>
+</html>
+"""
@@ -0,0 +1,175 @@
+"""
+PDF export: notes laid out as plain text pages using the standard PDF fonts
+
+The writer needs no PDF library. Text uses the Helvetica and Courier fonts
+every PDF viewer has, so characters outside Latin-1 (WinAnsi) are replaced
+with "?". Headings are set in bold, code blocks in Courier, and other lines
+wrapped to the page width.
+"""
+
+import re
+import textwrap
+from datetime import datetime
+from typing import List, Tuple
+from ..note import Note
+from ..i18n import t
+
+PAGE_WIDTH = 595  # A4, in points
+PAGE_HEIGHT = 842
+MARGIN = 56
+
+# (font resource, size, leading) for each kind of line
+STYLES = {
+    "title": ("F2", 18, 24),
+    "meta": ("F1", 9, 16),
+    "heading": ("F2", 13, 20),
+    "text": ("F1", 11, 15),
+    "code": ("F3", 9.5, 12),
+}
+FONTS = {"F1": "Helvetica", "F2": "Helvetica-Bold", "F3": "Courier"}
+
+# Helvetica is proportional; wrapping assumes an average character width
+# of half the font size, which keeps ordinary text inside the margins
+AVERAGE_CHAR_WIDTH = {"F1": 0.5, "F2": 0.55, "F3": 0.6}
+
+HEADING = re.compile(r'^#{1,6}\s+(.*?)\s*#*\s*$')
+FENCE = re.compile(r'^\s*(```|~~~)')
+INLINE_MARKS = re.compile(r'(\*\*\*|\*\*|__|~~|`)')
+
+
+def _pdf_string(text: str) -> bytes:
+    """Encode text as a PDF literal string in WinAnsi encoding"""
+    data = text.encode("cp1252", errors="replace")
+    data = data.replace(b"\\", b"\\\\").replace(b"(", b"\\(").replace(b")", b"\\)")
+    return b"(" + data + b")"
+
+
+def _pdf_date(value: datetime) -> str:
+    """Format a UTC timestamp as a PDF date"""
+    return value.strftime("D:%Y%m%d%H%M%SZ")
+
+
+def _wrap(text: str, style: str) -> List[str]:
+    """Wrap a line to the page width for a style"""
+    font, size, _ = STYLES[style]
+    width = int((PAGE_WIDTH - 2 * MARGIN) / (size * AVERAGE_CHAR_WIDTH[font]))
+    if style == "code":
+        # Keep indentation; break long lines without reflowing them
+        text = text.expandtabs(4)
+        return [text[i:i + width] for i in range(0, len(text), width)] or [""]
+    return textwrap.wrap(text, width) or [""]
+
+
+def layout_note(note: Note) -> List[Tuple[str, str]]:
+    """
+    Turn a note into (style, text) lines ready to be placed on pages
+
+    The first line of the note becomes the title, followed by the note's
+    creation and update times.
+
+    Args:
+        note: Note to lay out
+
+    Returns:
+        Wrapped lines with their styles
+    """
+    lines: List[Tuple[str, str]] = []
+    content = note.content.split("\n")
+    if content and note.title and content[0].strip().lstrip("#").strip() == note.title:
+        content = content[1:]
+
+    def add(style: str, text: str):
+        lines.extend((style, part) for part in _wrap(text, style))
+
+    add("title", note.title or "Untitled")
+    add("meta", t("export.timestamps",
+                  created=note.created_at.strftime("%Y-%m-%d %H:%M UTC"),
+                  updated=note.updated_at.strftime("%Y-%m-%d %H:%M UTC")))
+
+    in_code = False
+    for line in content:
+        if FENCE.match(line):
+            in_code = not in_code
+        elif in_code:
+            add("code", line)
+        elif HEADING.match(line):
+            add("heading", INLINE_MARKS.sub("", HEADING.match(line).group(1)))
+        else:
+            add("text", INLINE_MARKS.sub("", line))
+    return lines
+
+
+def _page_stream(lines: List[Tuple[str, str]]) -> bytes:
+    """Build the content stream drawing lines from the top of a page"""
+    out = [b"BT"]
+    y = PAGE_HEIGHT - MARGIN
+    for style, text in lines:
+        font, size, leading = STYLES[style]
+        y -= leading
+        out.append(f"/{font} {size} Tf 1 0 0 1 {MARGIN} {y} Tm".encode("ascii"))
+        out.append(_pdf_string(text) + b" Tj")
+    out.append(b"ET")
+    return b"\n".join(out)
+
+
+def _paginate(lines: List[Tuple[str, str]]) -> List[List[Tuple[str, str]]]:
+    """Split lines into pages by the space each line's style takes"""
+    pages: List[List[Tuple[str, str]]] = [[]]
+    used = 0
+    for style, text in lines:
+        leading = STYLES[style][2]
+        if used + leading > PAGE_HEIGHT - 2 * MARGIN and pages[-1]:
+            pages.append([])
+            used = 0
+        pages[-1].append((style, text))
+        used += leading
+    return pages
+
+
+def note_to_pdf(note: Note) -> bytes:
+    """
+    Render a note as a PDF document
+
+    The document info records the note's title and its creation and
+    modification times.
+
+    Args:
+        note: Note to render
+
+    Returns:
+        PDF file content
+    """
+    pages = _paginate(layout_note(note))
+
+    # Objects 1-2 are the catalog and page tree, 3 is the info dictionary,
+    # 4-6 are the fonts, then each page is followed by its content stream
+    font_refs = " ".join(f"/{name} {4 + i} 0 R" for i, name in enumerate(FONTS))
+    page_ids = [7 + 2 * i for i in range(len(pages))]
+    objects: List[bytes] = [
+        b"<< /Type /Catalog /Pages 2 0 R >>",
+        f"<< /Type /Pages /Kids [{' '.join(f'{i} 0 R' for i in page_ids)}] /Count {len(pages)} >>".encode("ascii"),
+        b"<< /Title " + _pdf_string(note.title or "Untitled")
+        + f" /CreationDate ({_pdf_date(note.created_at)}) /ModDate ({_pdf_date(note.updated_at)})"
+          f" /Producer (termnotes) >>".encode("ascii"),
+    ]
+    for base_font in FONTS.values():
+        objects.append(f"<< /Type /Font /Subtype /Type1 /BaseFont /{base_font} /Encoding /WinAnsiEncoding >>".encode("ascii"))
+    for page_id, page in zip(page_ids, pages):
+        objects.append(
+            f"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 {PAGE_WIDTH} {PAGE_HEIGHT}]"
+            f" /Resources << /Font << {font_refs} >> >> /Contents {page_id + 1} 0 R >>".encode("ascii")
+        )
+        stream = _page_stream(page)
+        objects.append(f"<< /Length {len(stream)} >>\nstream\n".encode("ascii") + stream + b"\nendstream")
+
+    data = bytearray(b"%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+    offsets = []
+    for number, body in enumerate(objects, start=1):
+        offsets.append(len(data))
+        data += f"{number} 0 obj\n".encode("ascii") + body + b"\nendobj\n"
+    xref = len(data)
+    data += f"xref\n0 {len(objects) + 1}\n0000000000 65535 f \n".encode("ascii")
+    for offset in offsets:
+        data += f"{offset:010d} 00000 n \n".encode("ascii")
+    data += f"trailer\n<< /Size {len(objects) + 1} /Root 1 0 R /Info 3 0 R >>\nstartxref\n{xref}\n%%EOF\n".encode("ascii")
+    return bytes(data)
@@ -0,0 +1,187 @@
+"""
+Static site: notes published as linked HTML pages
+
+`termnotes publish --out ./site` writes a page per note, named after its
+title ("meeting-notes.html"), an index.html listing them by notebook, and
+a style.css they share. [[Wiki links]] between published notes become
+hyperlinks, and each page lists the published notes linking to it. Links to
+notes that weren't published are left as plain text, so a site never points
+at (or reveals the title of a link to) a page it doesn't have.
+
+Publishing again into the same directory rewrites the pages; pages of notes
+no longer published are left for the user to remove.
+"""
+
+import html
+import re
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Iterable, List
+from urllib.parse import quote
+from pygments.formatters import HtmlFormatter
+from .html import FENCE, PAGE_STYLE, markdown_to_html
+from ..links import WIKI_LINK, link_targets, normalize_link
+from ..note import Note
+from ..notebook import get_note_notebook
+from ..i18n import t
+
+SITE_STYLE = """
+nav { font-size: 0.9em; margin-bottom: 1em; }
+nav a, section.backlinks a, ul.notes a { text-decoration: none; }
+ul.notes { list-style: none; padding-left: 0; }
+ul.notes .date, footer { color: #777; font-size: 0.85em; }
+section.backlinks { border-top: 1px solid #ddd; margin-top: 2em; }
+"""
+
+SLUG_CHARACTERS = re.compile(r'[^\w]+')
+
+
+def page_names(notes: Iterable[Note]) -> Dict[str, str]:
+    """
+    Name each note's page after its title
+
+    Args:
+        notes: Notes to name, in the order they should claim names
+
+    Returns:
+        Note ID to file name ("meeting-notes.html"; "-2" and on for repeated titles)
+    """
+    names = {}
+    taken = {"index"}
+    for note in notes:
+        slug = SLUG_CHARACTERS.sub("-", note.title.casefold()).strip("-_")[:80].strip("-_") or "untitled"
+        name = slug
+        counter = 2
+        while name in taken:
+            name = f"{slug}-{counter}"
+            counter += 1
+        taken.add(name)
+        names[note.id] = f"{name}.html"
+    return names
+
+
+def link_pages(content: str, pages: Dict[str, str]) -> str:
+    """
+    Turn [[wiki links]] into markdown links to published pages
+
+    Links in code blocks are left alone, and links to notes without a page
+    become their text.
+
+    Args:
+        content: Note markdown
+        pages: Normalized title to file name of each published note
+
+    Returns:
+        Markdown
+    """
+    def replace(match: re.Match) -> str:
+        written = match.group(0)[2:-2]
+        label = written.split("|", 1)[1] if "|" in written else match.group(1)
+        label = label.strip() or match.group(1).strip()
+        page = pages.get(normalize_link(match.group(1)))
+        return f"[{label}]({quote(page)})" if page else label
+
+    lines = content.split("\n")
+    fence = None
+    for i, line in enumerate(lines):
+        opening = FENCE.match(line)
+        if fence is not None:
+            if line.strip().startswith(fence):
+                fence = None
+        elif opening:
+            fence = opening.group(1)
+        else:
+            lines[i] = WIKI_LINK.sub(replace, line)
+    return "\n".join(lines)
+
+
+def _page(title: str, site_title: str, body: str) -> str:
+    """Wrap a page's body in the site's HTML"""
+    return f"""<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{html.escape(title)}</title>
+<link rel="stylesheet" href="style.css">
+</head>
+<body>
+<nav><a href="index.html">{html.escape(site_title)}</a></nav>
+{body}
+</body>
+</html>
+"""
+
+
+def _date(value: datetime) -> str:
+    """Format a note time for a page"""
+    return value.strftime("%Y-%m-%d")
+
+
+def publish_site(notes: Iterable[Note], directory: str, title: str = "") -> List[Path]:
+    """
+    Write notes as a static HTML site
+
+    Args:
+        notes: Notes to publish (trashed ones are skipped)
+        directory: Directory to write the site to (created if missing)
+        title: Title of the site ("" for the default)
+
+    Returns:
+        Paths of the written files, index.html first
+
+    Raises:
+        OSError: If the files can't be written
+    """
+    title = title or t("site.title")
+    notes = sorted((note for note in notes if not note.is_trashed),
+                   key=lambda note: (note.title.casefold(), note.created_at))
+    names = page_names(notes)
+    by_title: Dict[str, str] = {}
+    for note in notes:
+        by_title.setdefault(normalize_link(note.title), names[note.id])
+    titles = {names[note.id]: note.title or t("note.empty_preview") for note in notes}
+
+    backlinks: Dict[str, List[str]] = {}
+    for note in notes:
+        for target in link_targets(note.content):
+            page = by_title.get(target)
+            if page and page != names[note.id] and names[note.id] not in backlinks.setdefault(page, []):
+                backlinks[page].append(names[note.id])
+
+    root = Path(directory).expanduser()
+    root.mkdir(parents=True, exist_ok=True)
+    written = [root / "index.html", root / "style.css"]
+
+    for note in notes:
+        name = names[note.id]
+        body = [f"<article>\n{markdown_to_html(link_pages(note.content, by_title))}\n</article>"]
+        if name in backlinks:
+            items = "\n".join(f'<li><a href="{quote(page)}">{html.escape(titles[page])}</a></li>'
+                              for page in backlinks[name])
+            body.append(f'<section class="backlinks">\n<h2>{html.escape(t("site.backlinks"))}</h2>\n'
+                        f'<ul>\n{items}\n</ul>\n</section>')
+        dates = t("export.timestamps", created=_date(note.created_at), updated=_date(note.updated_at))
+        body.append(f"<footer>{html.escape(dates)}</footer>")
+        path = root / name
+        path.write_text(_page(titles[name], title, "\n".join(body)), encoding="utf-8")
+        written.append(path)
+
+    notebooks: Dict[str, List[Note]] = {}
+    for note in notes:
+        notebooks.setdefault(get_note_notebook(note), []).append(note)
+    sections = [f"<h1>{html.escape(title)}</h1>"]
+    for notebook in sorted(notebooks, key=lambda path: (path != "", path.casefold())):
+        if notebook:
+            sections.append(f"<h2>{html.escape(notebook)}</h2>")
+        items = "\n".join(
+            f'<li><a href="{quote(names[note.id])}">{html.escape(titles[names[note.id]])}</a> '
+            f'<span class="date">{_date(note.updated_at)}</span></li>'
+            for note in notebooks[notebook])
+        sections.append(f'<ul class="notes">\n{items}\n</ul>')
+    if not notes:
+        sections.append(f"<p>{html.escape(t('site.empty'))}</p>")
+    (root / "index.html").write_text(_page(title, title, "\n".join(sections)), encoding="utf-8")
+    (root / "style.css").write_text(f"{PAGE_STYLE}{SITE_STYLE}\n{HtmlFormatter().get_style_defs('.highlight')}\n",
+                                    encoding="utf-8")
+    return written
@@ -0,0 +1,108 @@
+"""
+Atom feed of recently updated notes
+
+The sync server serves one at /v1/feed.atom when `[server] feed` is on, so
+notes (or just those with `[server] feed_tag`) can be published as a
+changelog or microblog that feed readers follow.
+
+Each note is an entry with its title, its text rendered to HTML, and its
+tags as categories; the newest changes come first. Trashed notes are left
+out. Entry IDs are made from the note ID, so a feed reader shows an edited
+note as updated rather than as a new entry.
+"""
+
+import uuid
+from dataclasses import dataclass
+from datetime import datetime
+from typing import Iterable, List, Optional
+from xml.sax.saxutils import escape, quoteattr
+from .note import Note
+from .export.html import markdown_to_html
+from .utils import utc_now
+from . import __version__
+
+
+@dataclass
+class FeedSettings:
+    """What the sync server's feed publishes"""
+    title: str = "termnotes"
+    tag: str = ""  # Only notes with this tag ("" for every note)
+    limit: int = 20  # Most entries in the feed
+    public: bool = False  # Whether readers need no token
+
+
+def _time(value: datetime) -> str:
+    """Format a naive UTC time for Atom"""
+    return value.strftime("%Y-%m-%dT%H:%M:%SZ")
+
+
+def feed_notes(notes: Iterable[Note], tags: Iterable[str] = (), limit: int = 20) -> List[Note]:
+    """
+    Pick the notes a feed shows
+
+    Args:
+        notes: Notes to pick from
+        tags: Tags a note must all have
+        limit: Most notes to pick
+
+    Returns:
+        The most recently updated notes outside the trash, newest first
+    """
+    wanted = [Note.normalize_tag(tag) for tag in tags if Note.normalize_tag(tag)]
+    picked = [note for note in notes if not note.is_trashed and all(note.has_tag(tag) for tag in wanted)]
+    picked.sort(key=lambda note: note.updated_at, reverse=True)
+    return picked[:max(limit, 0)]
+
+
+def _entry(note: Note) -> List[str]:
+    """Build an Atom entry for a note"""
+    body = note.content.split("\n", 1)[1].strip() if "\n" in note.content else ""
+    lines = [
+        "  <entry>",
+        f"    <id>urn:uuid:{uuid.uuid5(uuid.NAMESPACE_URL, f'termnotes:{note.id}')}</id>",
+        f"    <title>{escape(note.title or note.id)}</title>",
+        f"    <published>{_time(note.created_at)}</published>",
+        f"    <updated>{_time(note.updated_at)}</updated>",
+    ]
+    lines += [f"    <category term={quoteattr(tag)}/>" for tag in note.tags]
+    lines += [
+        f"    <content type=\"html\">{escape(markdown_to_html(body))}</content>",
+        "  </entry>",
+    ]
+    return lines
+
+
+def notes_to_atom(notes: Iterable[Note], title: str = "termnotes", tags: Iterable[str] = (),
+                  limit: int = 20, url: Optional[str] = None) -> str:
+    """
+    Build an Atom feed of the most recently updated notes
+
+    Args:
+        notes: Notes to pick from
+        title: Title of the feed
+        tags: Tags a note must all have to be in the feed
+        limit: Most entries in the feed
+        url: Address the feed is served at, for its self link (None for none)
+
+    Returns:
+        Atom XML
+    """
+    tags = list(tags)
+    entries = feed_notes(notes, tags, limit)
+    updated = max((note.updated_at for note in entries), default=utc_now())
+    feed_key = ",".join(sorted(Note.normalize_tag(tag) for tag in tags))
+    lines = [
+        '<?xml version="1.0" encoding="utf-8"?>',
+        '<feed xmlns="http://www.w3.org/2005/Atom">',
+        f"  <id>urn:uuid:{uuid.uuid5(uuid.NAMESPACE_URL, f'termnotes-feed:{title}:{feed_key}')}</id>",
+        f"  <title>{escape(title)}</title>",
+        f"  <updated>{_time(updated)}</updated>",
+        f"  <author><name>{escape(title)}</name></author>",
+        f"  <generator version={quoteattr(__version__)}>termnotes</generator>",
+    ]
+    if url:
+        lines.append(f"  <link rel=\"self\" type=\"application/atom+xml\" href={quoteattr(url)}/>")
+    for note in entries:
+        lines += _entry(note)
+    lines.append("</feed>")
+    return "\n".join(lines) + "\n"
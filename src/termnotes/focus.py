@@ -3,6 +3,10 @@ Focus management for multi-pane UI
 """
 
 from enum import Enum
+from .i18n import t
+from .log import event, get_logger
+
+log = get_logger("ui")
 
 
 class FocusState(Enum):
@@ -23,6 +27,7 @@ class FocusManager:
         """
         self.current_focus = initial_focus
         self.sidebar_visible = True
+        self.zen = False  # Zen mode: only the editor, centered, for long-form writing
 
     def is_sidebar_focused(self) -> bool:
         """Check if sidebar has focus"""
@@ -35,11 +40,17 @@ class FocusManager:
     def switch_to_sidebar(self):
         """Switch focus to sidebar"""
         self.ensure_sidebar_visible()
-        self.current_focus = FocusState.SIDEBAR
+        self._set_focus(FocusState.SIDEBAR)
 
     def switch_to_editor(self):
         """Switch focus to editor"""
-        self.current_focus = FocusState.EDITOR
+        self._set_focus(FocusState.EDITOR)
+
+    def _set_focus(self, focus: FocusState):
+        """Give a pane focus, logging the change"""
+        if focus != self.current_focus:
+            event(log, "focus", pane=focus.value.lower())
+        self.current_focus = focus
 
     def toggle_focus(self):
         """Toggle focus between sidebar and editor"""
@@ -50,15 +61,29 @@ class FocusManager:
 
     def get_focus_name(self) -> str:
         """Get name of currently focused pane for display"""
-        return self.current_focus.value
+        return t(f"focus.{self.current_focus.value.lower()}")
 
     def toggle_sidebar(self):
         """Toggle sidebar visibility"""
+        if self.zen:
+            # The sidebar is hidden by zen mode; showing it again ends zen mode
+            self.ensure_sidebar_visible()
+            return
         self.sidebar_visible = not self.sidebar_visible
         # If hiding sidebar while it has focus, switch to editor
         if not self.sidebar_visible and self.is_sidebar_focused():
             self.switch_to_editor()
 
     def ensure_sidebar_visible(self):
-        """Ensure sidebar is visible (used when switching focus to it)"""
+        """Ensure sidebar is visible (used when switching focus to it), leaving zen mode"""
         self.sidebar_visible = True
+        if self.zen:
+            self.zen = False
+            event(log, "zen", on=False)
+
+    def toggle_zen(self):
+        """Enter or leave zen mode, which hides the sidebar and gives the editor focus"""
+        self.zen = not self.zen
+        event(log, "zen", on=self.zen)
+        if self.zen:
+            self.switch_to_editor()
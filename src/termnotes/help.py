@@ -0,0 +1,50 @@
+"""
+Help overlay: every key binding, over the middle of the screen
+
+The lines come from KeyMap.help_sections() each time the overlay opens,
+so remapped keys and plugin actions are listed as they are bound.
+"""
+
+from typing import List, Tuple
+
+
+class HelpView:
+    """State of the help overlay: its lines and how far it is scrolled"""
+
+    def __init__(self):
+        """Initialize a closed overlay"""
+        self.is_open = False
+        self.sections: List[Tuple[str, List[Tuple[str, str]]]] = []
+        self.scroll_offset = 0
+
+    def open(self, sections: List[Tuple[str, List[Tuple[str, str]]]]):
+        """
+        Show the key bindings, scrolled to the top
+
+        Args:
+            sections: (section title, [(keys, description)]) to list
+        """
+        self.is_open = True
+        self.sections = sections
+        self.scroll_offset = 0
+
+    def close(self):
+        """Close the overlay"""
+        self.is_open = False
+        self.sections = []
+
+    @property
+    def line_count(self) -> int:
+        """Get the number of lines listed: each section's title, entries and a blank line between sections"""
+        return sum(len(entries) + 1 for _, entries in self.sections) + max(0, len(self.sections) - 1)
+
+    def scroll(self, lines: int, page_height: int):
+        """
+        Scroll the list, keeping the last page full
+
+        Args:
+            lines: Number of lines to scroll (negative scrolls up)
+            page_height: Number of lines visible at once
+        """
+        max_offset = max(0, self.line_count - page_height)
+        self.scroll_offset = min(max(0, self.scroll_offset + lines), max_offset)
@@ -0,0 +1,182 @@
+"""
+Note version history: revisions, diffs and the history viewer state
+"""
+
+import difflib
+from dataclasses import dataclass
+from datetime import datetime
+from typing import List, Optional, Tuple
+
+
+@dataclass
+class Revision:
+    """A saved version of a note's content"""
+    note_id: str
+    rev: int  # 1 for the first save, increasing with every change
+    content: str
+    saved_at: datetime
+
+
+def diff_lines(old: str, new: str) -> List[str]:
+    """
+    Diff two versions of a note
+
+    Args:
+        old: Earlier content
+        new: Later content
+
+    Returns:
+        Unified diff lines ("+", "-", " " or "@@" prefixed) without file headers
+    """
+    lines = difflib.unified_diff(old.split('\n'), new.split('\n'), lineterm="", n=2)
+    return [line for line in lines if not line.startswith(("---", "+++"))]
+
+
+def _changes(base: List[str], other: List[str]) -> List[Tuple[int, int, List[str]]]:
+    """Get the (start, end, replacement lines) of the base lines another version changed"""
+    matcher = difflib.SequenceMatcher(None, base, other, autojunk=False)
+    return [(i1, i2, other[j1:j2]) for tag, i1, i2, j1, j2 in matcher.get_opcodes() if tag != "equal"]
+
+
+def _apply_changes(base: List[str], start: int, end: int, changes) -> List[str]:
+    """Get base[start:end] with changes inside that range applied"""
+    lines, position = [], start
+    for i1, i2, replacement in changes:
+        lines.extend(base[position:i1])
+        lines.extend(replacement)
+        position = i2
+    lines.extend(base[position:end])
+    return lines
+
+
+def merge_versions(base: str, mine: str, theirs: str, mine_label: str, theirs_label: str) -> Tuple[str, bool]:
+    """
+    Combine two versions edited from the same one, line by line
+
+    Changes to different parts of the note are both kept. Where both
+    versions changed the same lines (or adjacent ones) differently, both
+    are kept between git-style conflict markers.
+
+    Args:
+        base: The version both started from
+        mine: One edited version
+        theirs: The other
+        mine_label: Name of mine in the conflict markers
+        theirs_label: Name of theirs in the conflict markers
+
+    Returns:
+        (merged content, whether it has conflict markers)
+    """
+    base_lines = base.split('\n')
+    changes = sorted(
+        [(i1, i2, lines, 0) for i1, i2, lines in _changes(base_lines, mine.split('\n'))] +
+        [(i1, i2, lines, 1) for i1, i2, lines in _changes(base_lines, theirs.split('\n'))]
+    )
+
+    # Group changes that touch or overlap, then settle each group
+    groups = []
+    for change in changes:
+        if groups and change[0] <= groups[-1][1]:
+            groups[-1][1] = max(groups[-1][1], change[1])
+            groups[-1][2].append(change)
+        else:
+            groups.append([change[0], change[1], [change]])
+
+    merged, position, conflicted = [], 0, False
+    for start, end, group in groups:
+        merged.extend(base_lines[position:start])
+        sides = [[change[:3] for change in group if change[3] == side] for side in (0, 1)]
+        versions = [_apply_changes(base_lines, start, end, side) for side in sides]
+        if not sides[1] or versions[0] == versions[1]:
+            merged.extend(versions[0])
+        elif not sides[0]:
+            merged.extend(versions[1])
+        else:
+            merged.append(f"<<<<<<< {mine_label}")
+            merged.extend(versions[0])
+            merged.append("=======")
+            merged.extend(versions[1])
+            merged.append(f">>>>>>> {theirs_label}")
+            conflicted = True
+        position = end
+    merged.extend(base_lines[position:])
+    return '\n'.join(merged), conflicted
+
+
+class HistoryView:
+    """State of the history viewer: the revisions of one note and the selected one"""
+
+    def __init__(self):
+        """Initialize a closed history viewer"""
+        self.note_id: Optional[str] = None
+        self.revisions: List[Revision] = []  # Newest first
+        self.selected_index: int = 0
+        self.scroll_offset: int = 0  # First diff line shown
+
+    @property
+    def is_open(self) -> bool:
+        """Check if the viewer is showing a note's history"""
+        return self.note_id is not None
+
+    def open(self, note_id: str, revisions: List[Revision]):
+        """
+        Show a note's history, selecting the newest revision
+
+        Args:
+            note_id: ID of the note
+            revisions: The note's revisions in any order
+        """
+        self.note_id = note_id
+        self.revisions = sorted(revisions, key=lambda revision: revision.rev, reverse=True)
+        self.selected_index = 0
+        self.scroll_offset = 0
+
+    def close(self):
+        """Close the viewer"""
+        self.note_id = None
+        self.revisions = []
+
+    @property
+    def selected_revision(self) -> Optional[Revision]:
+        """Get the selected revision"""
+        if 0 <= self.selected_index < len(self.revisions):
+            return self.revisions[self.selected_index]
+        return None
+
+    def move_selection_down(self):
+        """Select the next older revision"""
+        if self.selected_index < len(self.revisions) - 1:
+            self.selected_index += 1
+            self.scroll_offset = 0
+
+    def move_selection_up(self):
+        """Select the next newer revision"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
+            self.scroll_offset = 0
+
+    def get_diff(self) -> List[str]:
+        """
+        Get the changes made by the selected revision
+
+        The first revision is diffed against an empty note.
+
+        Returns:
+            Unified diff lines
+        """
+        revision = self.selected_revision
+        if revision is None:
+            return []
+        previous = self.revisions[self.selected_index + 1] if self.selected_index + 1 < len(self.revisions) else None
+        return diff_lines(previous.content if previous else "", revision.content)
+
+    def scroll(self, lines: int, page_height: int):
+        """
+        Scroll the diff, keeping at least one line visible
+
+        Args:
+            lines: Number of lines to scroll (negative scrolls up)
+            page_height: Number of diff lines visible at once
+        """
+        max_offset = max(0, len(self.get_diff()) - page_height)
+        self.scroll_offset = min(max(0, self.scroll_offset + lines), max_offset)
@@ -0,0 +1,158 @@
+"""
+Hooks: shell commands run on note events
+
+The [hooks] config section maps events to a command or a list of them:
+
+    [hooks]
+    note_created = 'notify-send "New note" "$TERMNOTES_NOTE_TITLE"'
+    note_updated = ["~/bin/backup-note"]
+    exit = "git -C ~/notes push"
+
+Commands run in the background through the shell. The note's ID, title,
+tags and notebook are in TERMNOTES_NOTE_* environment variables, and
+stdin gets the event and the whole note as JSON. A secure note's title and
+text are left out (its content is the encrypted block if that's what was
+stored, else empty), so hooks never see what the passphrase protects.
+Hooks still running when termnotes exits are waited for, up to a timeout.
+"""
+
+import json
+import os
+import subprocess
+import sys
+import threading
+from typing import Any, Callable, Dict, Iterable, List, Mapping, Optional, Union
+from .note import Note
+from .secure import is_sealed
+from .i18n import t
+
+EVENTS = ("note_created", "note_updated", "note_deleted", "start", "exit")
+TIMEOUT = 30  # Seconds a hook may run before it's killed
+
+
+def _hook_title(note: Note) -> str:
+    """Get a note's title for hooks, empty for a secure note"""
+    return "" if note.is_secure else note.title
+
+
+def note_payload(note: Note) -> Dict:
+    """
+    Describe a note for a hook's JSON input
+
+    Args:
+        note: Note the event is about
+
+    Returns:
+        JSON-serializable dictionary; a secure note's text is only there as its encrypted block
+    """
+    content = note.content
+    if note.is_secure and not is_sealed(content):
+        content = ""  # Opened by the secure notes' vault: never handed to a hook
+    return {
+        "id": note.id,
+        "title": _hook_title(note),
+        "content": content,
+        "tags": note.tags,
+        "notebook": note.get_property("notebook", ""),
+        "created_at": note.created_at.isoformat(),
+        "updated_at": note.updated_at.isoformat(),
+        "properties": note.properties,
+    }
+
+
+class HookRunner:
+    """Runs the configured commands for each event"""
+
+    def __init__(self, commands: Mapping[str, Union[str, List[str]]]):
+        """
+        Initialize from the [hooks] config section
+
+        Args:
+            commands: Event name to a command or list of commands
+        """
+        self.commands: Dict[str, List[str]] = {}
+        self.errors: List[str] = []  # Problems with the config, for the status bar
+        self.on_error: Callable[[str], None] = self.print_error  # Told when a hook fails
+        self._threads: List[threading.Thread] = []
+        for event, value in commands.items():
+            if event not in EVENTS:
+                self.errors.append(t("config.unknown_hook", event=event, events=", ".join(EVENTS)))
+                continue
+            self.commands[event] = [value] if isinstance(value, str) else [str(command) for command in value]
+
+    def run(self, event: str, note: Optional[Note] = None):
+        """
+        Start the commands for an event in the background
+
+        Args:
+            event: One of EVENTS
+            note: Note the event is about (None for start and exit)
+        """
+        commands = self.commands.get(event)
+        if not commands:
+            return
+        env = dict(os.environ, TERMNOTES_EVENT=event)
+        payload: Dict = {"event": event}
+        if note is not None:
+            env.update(
+                TERMNOTES_NOTE_ID=note.id,
+                TERMNOTES_NOTE_TITLE=_hook_title(note),
+                TERMNOTES_NOTE_TAGS=",".join(note.tags),
+                TERMNOTES_NOTE_NOTEBOOK=note.get_property("notebook", ""),
+            )
+            payload["note"] = note_payload(note)
+        data = json.dumps(payload, default=str).encode("utf-8")
+
+        self._threads = [thread for thread in self._threads if thread.is_alive()]
+        for command in commands:
+            thread = threading.Thread(target=self._run_command, args=(event, command, env, data))
+            thread.start()
+            self._threads.append(thread)
+
+    def notes_changed(self, changes: Iterable[Any]):
+        """
+        Run the note hooks for an operation's changes
+
+        Args:
+            changes: Journal Changes, each note's state before and after (None: didn't exist)
+        """
+        for change in changes:
+            if change.before is None and change.after is not None:
+                self.run("note_created", change.after)
+            elif change.after is None and change.before is not None:
+                self.run("note_deleted", change.before)
+            elif change.after is not None:
+                self.run("note_updated", change.after)
+
+    @staticmethod
+    def print_error(message: str):
+        """Report a failed hook on stderr, outside the interface"""
+        print(message, file=sys.stderr)
+
+    def wait(self):
+        """Wait for the hooks still running (each is killed after the timeout)"""
+        for thread in self._threads:
+            thread.join()
+        self._threads = []
+
+    def _run_command(self, event: str, command: str, env: Dict[str, str], data: bytes):
+        """Run one command with the event's JSON on stdin, reporting failures"""
+        try:
+            process = subprocess.Popen(
+                command, shell=True, env=env,
+                stdin=subprocess.PIPE, stdout=subprocess.DEVNULL, stderr=subprocess.PIPE
+            )
+        except OSError as e:
+            self.on_error(t("msg.hook_failed", event=event, command=command, error=e))
+            return
+        try:
+            _, stderr = process.communicate(data, timeout=TIMEOUT)
+        except subprocess.TimeoutExpired:
+            process.kill()
+            process.communicate()
+            self.on_error(t("msg.hook_timeout", event=event, command=command, seconds=TIMEOUT))
+            return
+        if process.returncode != 0:
+            error = (stderr.decode("utf-8", "replace").strip().split("\n")[-1] or
+                     t("msg.hook_status", status=process.returncode))
+            self.on_error(t("msg.hook_failed", event=event, command=command, error=error))
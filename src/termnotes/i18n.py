@@ -0,0 +1,106 @@
+"""
+Localization of user-facing strings
+
+All text shown to the user (status messages, prompts, help text) is looked
+up by key in a per-locale message catalog. Missing translations fall back
+to English so partially translated catalogs still work.
+"""
+
+import os
+from typing import List, Optional
+from .locales import CATALOGS
+
+DEFAULT_LOCALE = "en"
+
+# Active locale, resolved lazily from config/environment on first use
+_current_locale: Optional[str] = None
+
+
+def available_locales() -> List[str]:
+    """Get the list of locales that ship with termnotes"""
+    return sorted(CATALOGS.keys())
+
+
+def _normalize_locale(locale: Optional[str]) -> Optional[str]:
+    """
+    Reduce a locale string to a supported catalog name
+
+    Accepts POSIX-style values such as "es_ES.UTF-8" and returns "es".
+
+    Args:
+        locale: Locale string to normalize
+
+    Returns:
+        Catalog name if supported, None otherwise
+    """
+    if not locale:
+        return None
+
+    locale = locale.split('.')[0].split('@')[0].replace('-', '_')
+    if locale in CATALOGS:
+        return locale
+
+    language = locale.split('_')[0].lower()
+    if language in CATALOGS:
+        return language
+
+    return None
+
+
+def detect_locale() -> str:
+    """
+    Detect the locale from the environment
+
+    Checks LC_ALL, LC_MESSAGES and LANG in the same order as gettext.
+
+    Returns:
+        Supported catalog name, or the default locale
+    """
+    for var in ("LC_ALL", "LC_MESSAGES", "LANG"):
+        locale = _normalize_locale(os.environ.get(var))
+        if locale:
+            return locale
+    return DEFAULT_LOCALE
+
+
+def set_locale(locale: Optional[str]):
+    """
+    Set the active locale
+
+    Args:
+        locale: Locale name (e.g. "es"), or None/"auto" to detect from environment
+    """
+    global _current_locale
+    if not locale or locale == "auto":
+        _current_locale = detect_locale()
+    else:
+        _current_locale = _normalize_locale(locale) or DEFAULT_LOCALE
+
+
+def get_locale() -> str:
+    """Get the active locale, resolving it from config on first use"""
+    if _current_locale is None:
+        from .config import get_config
+        set_locale(get_config().ui_locale)
+    return _current_locale
+
+
+def t(key: str, **kwargs) -> str:
+    """
+    Translate a message key into the active locale
+
+    Args:
+        key: Message key (e.g. "status.note_saved")
+        **kwargs: Values substituted into the message with str.format
+
+    Returns:
+        Translated message, the English message if untranslated,
+        or the key itself if it is unknown
+    """
+    message = CATALOGS[get_locale()].get(key)
+    if message is None:
+        message = CATALOGS[DEFAULT_LOCALE].get(key, key)
+
+    if kwargs:
+        return message.format(**kwargs)
+    return message
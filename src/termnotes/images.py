@@ -0,0 +1,407 @@
+"""
+Showing images linked from notes in terminals with graphics support
+
+Notes link images with markdown ("![alt](path/to/image.png)") or Obsidian
+embeds ("![[image.png]]"). Terminals show them through one of three
+protocols:
+
+- kitty: kitty, Ghostty and others; PNG images are sent as they are
+- iterm2: iTerm2 and WezTerm; any format the terminal can decode
+- sixel: foot, mlterm, xterm with sixel support...; PNG images are decoded
+  here and drawn with a 216-color palette
+
+Without a protocol (or for formats it can't carry) termnotes shows a
+placeholder naming the image instead.
+"""
+
+import base64
+import os
+import re
+import struct
+import zlib
+from dataclasses import dataclass
+from typing import Dict, List, Optional, Tuple
+
+try:
+    import fcntl
+    import termios
+except ImportError:  # Windows
+    fcntl = None
+
+PROTOCOLS = ("kitty", "iterm2", "sixel")
+
+IMAGE_LINK = re.compile(
+    r'!\[([^\]\n]*)\]\(<?([^)\s>]+)>?(?:\s+"[^"\n]*")?\)'  # ![alt](target "title")
+    r'|!\[\[([^\[\]|\n]+)(?:\|[^\[\]\n]*)?\]\]'  # ![[target|size]]
+)
+
+PNG_SIGNATURE = b"\x89PNG\r\n\x1a\n"
+
+# Fallback terminal cell size in pixels, when the terminal doesn't report it
+DEFAULT_CELL_SIZE = (8, 16)
+
+# Kitty reads base64 image data in chunks of at most this size
+KITTY_CHUNK = 4096
+
+
+@dataclass
+class ImageLink:
+    """An image linked from a line of a note"""
+    alt: str
+    target: str  # Path, URL, or attachment name as written
+    start: int  # Column range of the link in its line
+    end: int
+
+
+def find_image_links(line: str) -> List[ImageLink]:
+    """
+    Find the image links in a line
+
+    Args:
+        line: Line of a note
+
+    Returns:
+        Links in the order they appear
+    """
+    links = []
+    for match in IMAGE_LINK.finditer(line):
+        if match.group(3):
+            target = match.group(3).strip()
+            links.append(ImageLink(target, target, match.start(), match.end()))
+        else:
+            links.append(ImageLink(match.group(1), match.group(2), match.start(), match.end()))
+    return links
+
+
+def image_link_at(line: str, col: int) -> Optional[ImageLink]:
+    """
+    Get the image link under a cursor position
+
+    Args:
+        line: Line of text
+        col: Cursor column
+
+    Returns:
+        The link, or None if the cursor isn't on an image link
+    """
+    return next((link for link in find_image_links(line) if link.start <= col < link.end), None)
+
+
+def detect_protocol(setting: str = "auto", environ: Optional[Dict[str, str]] = None) -> Optional[str]:
+    """
+    Pick the graphics protocol to show images with
+
+    Args:
+        setting: "auto" to detect from the environment, "off", or a protocol name
+        environ: Environment variables (default: os.environ)
+
+    Returns:
+        Protocol name from PROTOCOLS, or None to show placeholders
+    """
+    if setting in PROTOCOLS:
+        return setting
+    if setting != "auto":
+        return None
+
+    env = os.environ if environ is None else environ
+    term = env.get("TERM", "")
+    program = env.get("TERM_PROGRAM", "")
+    if "kitty" in term or env.get("KITTY_WINDOW_ID") or program == "ghostty":
+        return "kitty"
+    if program in ("iTerm.app", "WezTerm") or env.get("LC_TERMINAL") == "iTerm2":
+        return "iterm2"
+    if term.startswith(("foot", "mlterm")) or program == "contour":
+        return "sixel"
+    return None
+
+
+def image_format(data: bytes) -> Optional[str]:
+    """Identify an image's format from its first bytes ("png", "gif", "jpeg" or "webp")"""
+    if data.startswith(PNG_SIGNATURE):
+        return "png"
+    if data[:6] in (b"GIF87a", b"GIF89a"):
+        return "gif"
+    if data.startswith(b"\xff\xd8"):
+        return "jpeg"
+    if data[:4] == b"RIFF" and data[8:12] == b"WEBP":
+        return "webp"
+    return None
+
+
+def image_size(data: bytes) -> Optional[Tuple[int, int]]:
+    """
+    Read an image's width and height from its header
+
+    Args:
+        data: PNG, GIF or JPEG file content
+
+    Returns:
+        (width, height) in pixels, or None if unknown
+    """
+    fmt = image_format(data)
+    if fmt == "png" and len(data) >= 24:
+        return struct.unpack(">II", data[16:24])
+    if fmt == "gif" and len(data) >= 10:
+        return struct.unpack("<HH", data[6:10])
+    if fmt == "jpeg":
+        # Walk the segments to the frame header (SOF0-SOF15 but not DHT, JPG or DAC)
+        pos = 2
+        while pos + 9 < len(data):
+            if data[pos] != 0xFF:
+                return None
+            marker = data[pos + 1]
+            length = struct.unpack(">H", data[pos + 2:pos + 4])[0]
+            if 0xC0 <= marker <= 0xCF and marker not in (0xC4, 0xC8, 0xCC):
+                height, width = struct.unpack(">HH", data[pos + 5:pos + 9])
+                return width, height
+            pos += 2 + length
+    return None
+
+
+def terminal_cell_size() -> Tuple[int, int]:
+    """Get the size of a terminal cell in pixels, as (width, height)"""
+    if fcntl is not None:
+        try:
+            rows, columns, width, height = struct.unpack(
+                "HHHH", fcntl.ioctl(1, termios.TIOCGWINSZ, b"\0" * 8)
+            )
+            if rows and columns and width and height:
+                return width // columns, height // rows
+        except OSError:
+            pass
+    return DEFAULT_CELL_SIZE
+
+
+def decode_png(data: bytes) -> Optional[Tuple[int, int, bytearray]]:
+    """
+    Decode a PNG image to RGBA pixels
+
+    Handles every standard color type and bit depth, but not interlaced
+    images.
+
+    Args:
+        data: PNG file content
+
+    Returns:
+        (width, height, RGBA bytes row by row), or None if it can't be decoded
+    """
+    if not data.startswith(PNG_SIGNATURE):
+        return None
+    header = None
+    palette = b""
+    transparency = b""
+    chunks = []
+    pos = len(PNG_SIGNATURE)
+    while pos + 8 <= len(data):
+        length, kind = struct.unpack(">I4s", data[pos:pos + 8])
+        body = data[pos + 8:pos + 8 + length]
+        pos += 12 + length
+        if kind == b"IHDR":
+            header = struct.unpack(">IIBBBBB", body)
+        elif kind == b"PLTE":
+            palette = body
+        elif kind == b"tRNS":
+            transparency = body
+        elif kind == b"IDAT":
+            chunks.append(body)
+        elif kind == b"IEND":
+            break
+    if header is None:
+        return None
+    width, height, depth, color_type, _, _, interlace = header
+    channels = {0: 1, 2: 3, 3: 1, 4: 2, 6: 4}.get(color_type)
+    if channels is None or interlace or depth not in (1, 2, 4, 8, 16):
+        return None
+
+    try:
+        raw = zlib.decompress(b"".join(chunks))
+    except zlib.error:
+        return None
+    bits_per_pixel = channels * depth
+    stride = (width * bits_per_pixel + 7) // 8
+    step = max(1, bits_per_pixel // 8)  # Bytes to the same channel of the previous pixel
+    if len(raw) < height * (stride + 1):
+        return None
+
+    pixels = bytearray(width * height * 4)
+    previous = bytearray(stride)
+    offset = 0
+    for y in range(height):
+        kind = raw[offset]
+        line = bytearray(raw[offset + 1:offset + 1 + stride])
+        offset += stride + 1
+        _unfilter(kind, line, previous, step)
+        previous = line
+        _expand_row(line, y, width, depth, color_type, palette, transparency, pixels)
+    return width, height, pixels
+
+
+def _unfilter(kind: int, line: bytearray, previous: bytearray, step: int):
+    """Undo a PNG scanline filter in place"""
+    if kind == 1:  # Sub
+        for i in range(step, len(line)):
+            line[i] = (line[i] + line[i - step]) & 0xFF
+    elif kind == 2:  # Up
+        for i in range(len(line)):
+            line[i] = (line[i] + previous[i]) & 0xFF
+    elif kind == 3:  # Average
+        for i in range(len(line)):
+            left = line[i - step] if i >= step else 0
+            line[i] = (line[i] + ((left + previous[i]) >> 1)) & 0xFF
+    elif kind == 4:  # Paeth
+        for i in range(len(line)):
+            a = line[i - step] if i >= step else 0
+            b = previous[i]
+            c = previous[i - step] if i >= step else 0
+            p = a + b - c
+            pa, pb, pc = abs(p - a), abs(p - b), abs(p - c)
+            predictor = a if pa <= pb and pa <= pc else (b if pb <= pc else c)
+            line[i] = (line[i] + predictor) & 0xFF
+
+
+def _expand_row(line: bytearray, y: int, width: int, depth: int, color_type: int,
+                palette: bytes, transparency: bytes, pixels: bytearray):
+    """Convert one unfiltered scanline to RGBA pixels"""
+    if depth < 8:
+        # Unpack 1, 2 or 4-bit samples, most significant bits first
+        per_byte = 8 // depth
+        mask = (1 << depth) - 1
+        samples = [(line[x // per_byte] >> (8 - depth * (x % per_byte + 1))) & mask for x in range(width)]
+        if color_type == 0:
+            samples = [sample * 255 // mask for sample in samples]
+    elif depth == 16:
+        samples = line[::2]  # High byte of each sample
+    else:
+        samples = line
+
+    base = y * width * 4
+    for x in range(width):
+        out = base + x * 4
+        if color_type == 0:
+            gray = samples[x]
+            pixels[out:out + 4] = bytes((gray, gray, gray, 255))
+        elif color_type == 2:
+            pixels[out:out + 3] = samples[x * 3:x * 3 + 3]
+            pixels[out + 3] = 255
+        elif color_type == 3:
+            index = samples[x]
+            pixels[out:out + 3] = palette[index * 3:index * 3 + 3].ljust(3, b"\0")
+            pixels[out + 3] = transparency[index] if index < len(transparency) else 255
+        elif color_type == 4:
+            gray, alpha = samples[x * 2], samples[x * 2 + 1]
+            pixels[out:out + 4] = bytes((gray, gray, gray, alpha))
+        else:
+            pixels[out:out + 4] = samples[x * 4:x * 4 + 4]
+
+
+# Sixel data bytes are the six-pixel column bits plus 63
+SIXEL_CHARS = bytes(min(value + 63, 255) for value in range(256))
+SIXEL_RUN = re.compile(rb"(.)\1{3,}")
+
+
+def encode_sixel(width: int, height: int, pixels: bytearray, max_width: int, max_height: int) -> str:
+    """
+    Draw RGBA pixels as sixel graphics, scaled down to fit
+
+    Colors are reduced to a 6x6x6 color cube; mostly transparent pixels
+    are left undrawn.
+
+    Args:
+        width: Image width in pixels
+        height: Image height in pixels
+        pixels: RGBA bytes row by row
+        max_width: Largest width to draw, in pixels
+        max_height: Largest height to draw, in pixels
+
+    Returns:
+        Sixel escape sequence
+    """
+    scale = min(1.0, max_width / width, max_height / height)
+    out_width, out_height = max(1, int(width * scale)), max(1, int(height * scale))
+    columns = [int(x / scale) for x in range(out_width)]
+
+    out = ["\x1bP0;1;0q", f'"1;1;{out_width};{out_height}']
+    out.extend(f"#{i};2;{i // 36 * 20};{i // 6 % 6 * 20};{i % 6 * 20}" for i in range(216))
+    for top in range(0, out_height, 6):
+        rows: Dict[int, bytearray] = {}  # Color to the bits of each column in this band
+        for dy in range(min(6, out_height - top)):
+            bit = 1 << dy
+            row_start = int((top + dy) / scale) * width * 4
+            for x, source_x in enumerate(columns):
+                p = row_start + source_x * 4
+                if pixels[p + 3] < 128:
+                    continue
+                color = (pixels[p] * 5 + 127) // 255 * 36 + (pixels[p + 1] * 5 + 127) // 255 * 6 \
+                    + (pixels[p + 2] * 5 + 127) // 255
+                bits = rows.get(color)
+                if bits is None:
+                    bits = rows[color] = bytearray(out_width)
+                bits[x] |= bit
+        layers = []
+        for color, bits in rows.items():
+            data = SIXEL_RUN.sub(lambda m: b"!%d%s" % (len(m.group(0)), m.group(1)), bits.translate(SIXEL_CHARS))
+            layers.append(f"#{color}" + data.decode("ascii"))
+        out.append("$".join(layers) + "-")
+    out.append("\x1b\\")
+    return "".join(out)
+
+
+def render_image(data: bytes, protocol: str, columns: int, rows: int) -> Optional[str]:
+    """
+    Build the escape sequence that draws an image at the cursor
+
+    Images larger than the space given are scaled down to fit.
+
+    Args:
+        data: Image file content
+        protocol: Protocol name from PROTOCOLS
+        columns: Terminal columns available
+        rows: Terminal rows available
+
+    Returns:
+        Escape sequence, or None if the protocol can't show this image
+    """
+    fmt = image_format(data)
+    size = image_size(data)
+    cell_width, cell_height = terminal_cell_size()
+
+    # Columns to draw in, to shrink large images; None leaves the size alone
+    fit = None
+    if size:
+        needed_columns = -(-size[0] // cell_width)
+        needed_rows = -(-size[1] // cell_height)
+        if needed_columns > columns or needed_rows > rows:
+            fit = max(1, min(columns, int(needed_columns * rows / needed_rows)))
+
+    if protocol == "kitty":
+        if fmt != "png":
+            return None
+        encoded = base64.standard_b64encode(data).decode("ascii")
+        chunks = [encoded[i:i + KITTY_CHUNK] for i in range(0, len(encoded), KITTY_CHUNK)] or [""]
+        parts = []
+        for i, chunk in enumerate(chunks):
+            more = 1 if i < len(chunks) - 1 else 0
+            control = f"a=T,f=100{f',c={fit}' if fit else ''},m={more}" if i == 0 else f"m={more}"
+            parts.append(f"\x1b_G{control};{chunk}\x1b\\")
+        return "".join(parts)
+
+    if protocol == "iterm2":
+        if fmt is None:
+            return None
+        width = f";width={fit}" if fit else ""
+        encoded = base64.standard_b64encode(data).decode("ascii")
+        return f"\x1b]1337;File=inline=1;size={len(data)}{width};preserveAspectRatio=1:{encoded}\x07"
+
+    if protocol == "sixel":
+        decoded = decode_png(data) if fmt == "png" else None
+        if decoded is None:
+            return None
+        width, height, pixels = decoded
+        return encode_sixel(width, height, pixels, columns * cell_width, rows * cell_height)
+
+    return None
+
+
+def clear_images(protocol: str) -> str:
+    """Get the escape sequence removing shown images, for protocols that keep them on screen"""
+    return "\x1b_Ga=d\x1b\\" if protocol == "kitty" else ""
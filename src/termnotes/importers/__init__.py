@@ -0,0 +1,42 @@
+"""
+Importing notes from other note apps
+
+- markdown: directories of markdown files, such as Obsidian vaults
+- enex: Evernote's ENEX export files
+- archive: termnotes' own .tnx archives (see termnotes.archive)
+- joplin: Joplin's JEX export files (see termnotes.joplin)
+- notion: Notion's markdown or HTML ZIP exports, databases flattened into tagged notes
+- keep: Google Keep notes downloaded with Google Takeout
+- apple-notes: Apple Notes exported to a folder of HTML, markdown or text files
+
+IMPORTERS maps each source name accepted by "termnotes import --from" to a
+function that reads a path into new notes (with fresh IDs, except from an
+archive, Joplin, Notion, Keep or Apple Notes, not yet stored).
+Notes are stored in one batch with StorageBackend.import_notes().
+"""
+
+from functools import partial
+from typing import Callable, Dict, List
+from .apple_notes import read_apple_notes
+from .enex import read_enex_file
+from .keep import read_keep_takeout
+from .markdown import read_markdown_directory
+from .notion import read_notion_export
+from ..archive import read_archive
+from ..joplin import read_jex_file
+from ..note import Note
+
+# Source name to reader taking (path, notebook=...)
+IMPORTERS: Dict[str, Callable[..., List[Note]]] = {
+    "obsidian": partial(read_markdown_directory, obsidian=True),
+    "markdown": read_markdown_directory,
+    "enex": read_enex_file,
+    "archive": read_archive,
+    "joplin": read_jex_file,
+    "notion": read_notion_export,
+    "keep": read_keep_takeout,
+    "apple-notes": read_apple_notes,
+}
+
+# Sources whose readers also copy the notes' attached files, given attachments_dir=...
+ATTACHING_IMPORTERS = frozenset(("archive", "joplin", "notion", "keep", "apple-notes"))
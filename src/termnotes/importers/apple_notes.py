@@ -0,0 +1,121 @@
+"""
+Importing notes from Apple Notes, as exported to folders
+
+Apple Notes can't export everything itself, so export tools (such as
+Exporter) write each note to an HTML, markdown or text file named after
+it, in a folder per Apple Notes folder, with the note's dates as the
+file's creation and modification times and its images beside it.
+
+Folders become notebooks, inline #tags become tags, and images and files
+the notes link to are attached.
+"""
+
+import errno
+import os
+import posixpath
+import uuid
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import List, Optional
+from urllib.parse import unquote, urlparse
+from .enex import EnmlConverter
+from .markdown import inline_tags, read_markdown_file
+from .notion import MARKDOWN_LINK
+from ..note import Note
+from ..attachments import ATTACHMENTS_PROPERTY, Attachment, unique_name
+from ..notebook import Notebook
+from ..i18n import t
+
+HTML_EXTENSIONS = (".html", ".htm")
+TEXT_EXTENSIONS = (".md", ".markdown", ".txt")
+
+
+def _read_html_file(path: Path) -> Note:
+    """Read an HTML note, with its file's modification time"""
+    converter = EnmlConverter()
+    converter.feed(path.read_text(encoding="utf-8", errors="replace"))
+    converter.close()
+    mtime = datetime.fromtimestamp(path.stat().st_mtime, timezone.utc).replace(tzinfo=None)
+    return Note("", converter.markdown(), mtime, mtime)
+
+
+def read_apple_notes(directory: str, notebook: str = "", attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read every note in a folder of exported Apple Notes, copying the files attached to them
+
+    Args:
+        directory: Folder the notes were exported to
+        notebook: Notebook to put the imported notebooks under ("" for the root)
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        Notes with IDs that are the same each time the folder is read, not yet stored
+
+    Raises:
+        OSError: If the folder doesn't exist, or a file can't be read or copied
+    """
+    root = Path(directory).expanduser()
+    if not root.is_dir():
+        raise NotADirectoryError(errno.ENOTDIR, os.strerror(errno.ENOTDIR), directory)
+    paths = [path for path in sorted(root.rglob("*"))
+             if path.is_file() and path.suffix.lower() in HTML_EXTENSIONS + TEXT_EXTENSIONS
+             and not any(part.startswith(".") for part in path.relative_to(root).parts)]
+
+    note_files = {path.resolve() for path in paths}
+    notes = []
+    for path in paths:
+        relative = path.relative_to(root)
+        if path.suffix.lower() in HTML_EXTENSIONS:
+            note = _read_html_file(path)
+        else:
+            note = read_markdown_file(path)
+        for tag in inline_tags(note.content):
+            note.add_tag(tag)
+        # The ID is the same on the second read, which copies the attached files
+        note.id = str(uuid.uuid5(uuid.NAMESPACE_URL, f"apple-notes:{relative.as_posix()}"))
+        birthtime = getattr(path.stat(), "st_birthtime", None)
+        if birthtime:
+            note.created_at = min(note.created_at,
+                                  datetime.fromtimestamp(birthtime, timezone.utc).replace(tzinfo=None))
+
+        # Apple Notes titles a note by its first line; make it the heading
+        lines = note.content.split("\n")
+        first = next((i for i, line in enumerate(lines) if line.strip()), None)
+        if first is None:
+            note.content = f"# {path.stem}\n"
+        elif not lines[first].startswith("#"):
+            lines[first:first + 1] = [f"# {lines[first].strip()}", ""]
+            if first + 2 < len(lines) and not lines[first + 2].strip():
+                del lines[first + 1]
+            note.content = "\n".join(lines[first:])
+
+        attachments: List[Attachment] = []
+        attached = {}  # File to attached file name
+
+        def replace_link(match) -> str:
+            """Attach a linked file from the export"""
+            target = match.group(3)
+            if urlparse(target).scheme:
+                return match.group(0)
+            source = (path.parent / unquote(target)).resolve()
+            if not source.is_file() or root.resolve() not in source.parents or source in note_files:
+                return match.group(0)
+            if source not in attached:
+                attached[source] = unique_name(source.name, list(attached.values()))
+                if attachments_dir is not None:
+                    file_path = Path(attachments_dir) / note.id / attached[source]
+                    file_path.parent.mkdir(parents=True, exist_ok=True)
+                    file_path.write_bytes(source.read_bytes())
+                attachments.append(Attachment(name=attached[source], size=source.stat().st_size,
+                                              added_at=note.updated_at))
+            return f"_{t('import.attachment', name=attached[source])}_"
+
+        note.content = MARKDOWN_LINK.sub(replace_link, note.content)
+        if attachments:
+            note.set_property(ATTACHMENTS_PROPERTY, [attachment.to_dict() for attachment in attachments])
+        folder = posixpath.join(*relative.parent.parts) if relative.parent.parts else ""
+        path_in_notebook = Notebook.normalize_path(f"{notebook}{Notebook.SEPARATOR}{folder}")
+        if path_in_notebook:
+            note.set_property("notebook", path_in_notebook)
+        notes.append(note)
+    return notes
@@ -0,0 +1,296 @@
+"""
+Importing notes from Evernote's ENEX export files
+
+An ENEX file is XML with one <note> element per note. Each note has a
+title, created/updated times, tags, and its content as ENML (a subset of
+XHTML) in a CDATA section, which is converted to markdown here.
+"""
+
+import base64
+import binascii
+import hashlib
+import re
+import uuid
+import xml.etree.ElementTree as ElementTree
+from datetime import datetime
+from html.parser import HTMLParser
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+from ..note import Note
+from ..utils import utc_now
+from ..i18n import t
+
+TIME_FORMAT = "%Y%m%dT%H%M%SZ"
+
+# Note attributes kept as note properties
+ATTRIBUTES = {"source-url": "source_url", "author": "author"}
+
+BLOCK_TAGS = {"div", "p", "table", "tr", "blockquote", "pre", "hr"}
+SKIP_TAGS = {"head", "title", "style", "script"}
+INLINE_MARKS = {"b": "**", "strong": "**", "i": "_", "em": "_", "s": "~~", "strike": "~~", "del": "~~"}
+
+
+class EnmlConverter(HTMLParser):
+    """
+    Convert ENML note content to markdown
+
+    Handles what Evernote produces: a <div> per line, headings, emphasis,
+    links, lists, checkboxes (<en-todo>), code blocks, block quotes and
+    tables. Attachments (<en-media>) become a placeholder naming the file.
+    """
+
+    def __init__(self, media_names: Optional[Dict[str, str]] = None):
+        """
+        Initialize the converter
+
+        Args:
+            media_names: Attachment hash to file name, for <en-media> placeholders
+        """
+        super().__init__(convert_charrefs=True)
+        self.media_names = media_names or {}
+        self.out: List[str] = []
+        self.lists: List[List] = []  # [tag, item count] for each open list
+        self.open: List[Tuple[str, int, Optional[str]]] = []  # (tag, output index, href) for quotes and links
+        self.pre_depth = 0
+        self.skip_depth = 0
+        self.first_cell = True
+
+    def _tail(self) -> str:
+        """Get the last non-empty chunk of output"""
+        return next((chunk for chunk in reversed(self.out) if chunk), "")
+
+    def _newlines(self, count: int = 1):
+        """End the output with at least this many line breaks (none at the start)"""
+        # Drop trailing spaces, keeping the chunk count so saved indices stay valid
+        for i in range(len(self.out) - 1, -1, -1):
+            self.out[i] = self.out[i].rstrip(" ")
+            if self.out[i]:
+                break
+        trailing = 0
+        for chunk in reversed(self.out):
+            stripped = chunk.rstrip("\n")
+            trailing += len(chunk) - len(stripped)
+            if stripped:
+                break
+        if self._tail():
+            self.out.append("\n" * max(0, count - trailing))
+
+    def handle_starttag(self, tag, attrs):
+        attrs = dict(attrs)
+        if tag in SKIP_TAGS:
+            self.skip_depth += 1
+        elif tag == "pre" or (tag == "div" and "-en-codeblock" in (attrs.get("style") or "")):
+            self._newlines()
+            self.out.append("```\n")
+            self.pre_depth += 1
+            self.open.append((tag, len(self.out), None))
+        elif self.pre_depth:
+            if tag == "br":
+                self.out.append("\n")
+            elif tag == "div":
+                # Evernote code blocks have a <div> per line
+                self._newlines()
+                self.open.append(("line", len(self.out), None))
+        elif tag == "br":
+            self.out.append("\n")
+        elif tag == "p":
+            self._newlines(2)
+        elif tag in ("table", "blockquote"):
+            self._newlines(2)
+            if tag == "blockquote":
+                self.open.append((tag, len(self.out), None))
+        elif tag in BLOCK_TAGS and tag != "hr":
+            self._newlines()
+            if tag == "tr":
+                self.first_cell = True
+        elif tag == "hr":
+            self._newlines(2)
+            self.out.append("---\n")
+        elif re.fullmatch(r"h[1-6]", tag):
+            self._newlines(2)
+            self.out.append("#" * int(tag[1]) + " ")
+        elif tag in ("ul", "ol"):
+            self._newlines()
+            self.lists.append([tag, 0])
+        elif tag == "li":
+            self._newlines()
+            depth = max(len(self.lists), 1)
+            marker = "- "
+            if self.lists and self.lists[-1][0] == "ol":
+                self.lists[-1][1] += 1
+                marker = f"{self.lists[-1][1]}. "
+            self.out.append("  " * (depth - 1) + marker)
+        elif tag in ("td", "th"):
+            if not self.first_cell:
+                self.out.append(" | ")
+            self.first_cell = False
+        elif tag == "en-todo":
+            box = "[x] " if attrs.get("checked") == "true" else "[ ] "
+            self.out.append(box if self.lists else "- " + box)
+        elif tag == "en-media":
+            name = self.media_names.get(attrs.get("hash", ""), attrs.get("type", ""))
+            self.out.append(f"_{t('import.attachment', name=name)}_")
+        elif tag == "img":
+            self.out.append(f"![{attrs.get('alt') or ''}]({attrs.get('src') or ''})")
+        elif tag in INLINE_MARKS:
+            self.out.append(INLINE_MARKS[tag])
+        elif tag == "code":
+            self.out.append("`")
+        elif tag == "a":
+            self.open.append((tag, len(self.out), attrs.get("href")))
+
+    def handle_startendtag(self, tag, attrs):
+        self.handle_starttag(tag, attrs)
+        if tag not in ("br", "hr", "en-todo", "en-media", "img"):
+            self.handle_endtag(tag)
+
+    def handle_endtag(self, tag):
+        if tag in SKIP_TAGS:
+            self.skip_depth = max(0, self.skip_depth - 1)
+        elif self.pre_depth and tag == "div" and self.open and self.open[-1][0] == "line":
+            self.open.pop()
+            self._newlines()
+        elif self.pre_depth and self.open and self.open[-1][0] == tag and tag in ("pre", "div"):
+            self.open.pop()
+            self.pre_depth -= 1
+            self._newlines()
+            self.out.append("```\n")
+        elif tag in ("p", "table") or re.fullmatch(r"h[1-6]", tag):
+            self._newlines(2)
+        elif tag in ("ul", "ol"):
+            if self.lists:
+                self.lists.pop()
+            self._newlines(1 if self.lists else 2)
+        elif tag == "blockquote" and self.open and self.open[-1][0] == tag:
+            _, start, _ = self.open.pop()
+            quoted = "".join(self.out[start:]).strip("\n")
+            self.out[start:] = ["\n".join(f"> {line}".rstrip() for line in quoted.split("\n")) + "\n"]
+            self._newlines(2)
+        elif tag in BLOCK_TAGS:
+            self._newlines()
+        elif tag in INLINE_MARKS:
+            self.out.append(INLINE_MARKS[tag])
+        elif tag == "code":
+            self.out.append("`")
+        elif tag == "a" and self.open and self.open[-1][0] == "a":
+            _, start, href = self.open.pop()
+            text = "".join(self.out[start:])
+            if href and text.strip() and text.strip() != href:
+                self.out[start:] = [f"[{text.strip()}]({href})"]
+            elif href:
+                self.out[start:] = [href]
+
+    def handle_data(self, data):
+        if self.skip_depth:
+            return
+        data = data.replace("\xa0", " ")
+        if self.pre_depth:
+            self.out.append(data)
+            return
+        data = re.sub(r"\s+", " ", data)
+        if not self._tail() or self._tail()[-1].isspace():
+            data = data.lstrip()
+        self.out.append(data)
+
+    def markdown(self) -> str:
+        """Get the converted markdown"""
+        text = "\n".join(line.rstrip() for line in "".join(self.out).split("\n"))
+        text = re.sub(r"\n{3,}", "\n\n", text).strip("\n")
+        return text + "\n" if text else ""
+
+
+def enml_to_markdown(enml: str, media_names: Optional[Dict[str, str]] = None) -> str:
+    """
+    Convert ENML note content to markdown
+
+    Args:
+        enml: ENML document
+        media_names: Attachment hash to file name, for attachment placeholders
+
+    Returns:
+        Markdown text
+    """
+    converter = EnmlConverter(media_names)
+    converter.feed(enml)
+    converter.close()
+    return converter.markdown()
+
+
+def _parse_time(value: Optional[str]) -> Optional[datetime]:
+    """Parse an ENEX timestamp (UTC, e.g. "20240131T120000Z")"""
+    try:
+        return datetime.strptime((value or "").strip(), TIME_FORMAT)
+    except ValueError:
+        return None
+
+
+def _media_names(element: ElementTree.Element) -> Dict[str, str]:
+    """Map each attachment's MD5 hash, as used by <en-media>, to its file name"""
+    names = {}
+    for resource in element.iter("resource"):
+        name = resource.findtext("resource-attributes/file-name") or resource.findtext("mime") or ""
+        try:
+            data = base64.b64decode(resource.findtext("data") or "")
+        except (binascii.Error, ValueError):
+            continue
+        names[hashlib.md5(data).hexdigest()] = name
+    return names
+
+
+def note_from_enex(element: ElementTree.Element, notebook: str = "") -> Note:
+    """
+    Convert an ENEX <note> element to a new note
+
+    The title becomes the note's first heading. Tags, timestamps and the
+    source URL and author are kept.
+
+    Args:
+        element: <note> element
+        notebook: Notebook to place the note in
+
+    Returns:
+        Note with a new ID
+    """
+    title = (element.findtext("title") or "").strip()
+    body = enml_to_markdown(element.findtext("content") or "", _media_names(element))
+    content = f"# {title}\n\n{body}" if title else body
+
+    created_at = _parse_time(element.findtext("created")) or utc_now()
+    updated_at = _parse_time(element.findtext("updated")) or created_at
+
+    note = Note(str(uuid.uuid4()), content, created_at, updated_at)
+    for tag in element.findall("tag"):
+        note.add_tag(tag.text or "")
+    for attribute, key in ATTRIBUTES.items():
+        value = element.findtext(f"note-attributes/{attribute}")
+        if value:
+            note.set_property(key, value.strip())
+    if notebook:
+        note.set_property("notebook", notebook)
+    return note
+
+
+def read_enex_file(path: str, notebook: str = "") -> List[Note]:
+    """
+    Read every note in an ENEX file
+
+    Args:
+        path: ENEX file exported from Evernote
+        notebook: Notebook to put the notes in ("" for the root)
+
+    Returns:
+        Notes with new IDs, not yet stored
+
+    Raises:
+        OSError: If the file can't be read
+        ValueError: If the file isn't valid ENEX
+    """
+    notes = []
+    try:
+        for _, element in ElementTree.iterparse(Path(path).expanduser(), events=("end",)):
+            if element.tag == "note":
+                notes.append(note_from_enex(element, notebook))
+                element.clear()  # Attachments can be large; free each note once read
+    except ElementTree.ParseError as e:
+        raise ValueError(f"{path}: {e}")
+    return notes
@@ -0,0 +1,57 @@
+"""
+Reading the files of another app's export, zipped or not
+
+Exports such as Notion's and Google Takeout are downloaded as a ZIP file;
+importers that take one also take the directory it was extracted to.
+"""
+
+import zipfile
+from contextlib import ExitStack
+from datetime import datetime, timezone
+from io import BytesIO
+from pathlib import Path
+from typing import Callable, Dict, Tuple
+from ..utils import normalize_to_utc
+
+# A file in an export: a function reading its contents, and its modification time (UTC)
+ExportFile = Tuple[Callable[[], bytes], datetime]
+
+
+def list_export_files(path: Path, stack: ExitStack) -> Dict[str, ExportFile]:
+    """
+    List the files in an export
+
+    ZIP files inside a ZIP file are read as part of it, as large exports
+    are split into parts that way.
+
+    Args:
+        path: ZIP file or directory
+        stack: Keeps the ZIP files open until the import is done
+
+    Returns:
+        Slash-separated path within the export to the file
+
+    Raises:
+        OSError: If the export can't be read
+        zipfile.BadZipFile: If the path is a file but not a ZIP file
+    """
+    files: Dict[str, ExportFile] = {}
+    if path.is_dir():
+        for file_path in sorted(path.rglob("*")):
+            if file_path.is_file():
+                mtime = datetime.fromtimestamp(file_path.stat().st_mtime, timezone.utc).replace(tzinfo=None)
+                files[file_path.relative_to(path).as_posix()] = (file_path.read_bytes, mtime)
+        return files
+
+    def add_zip(archive: zipfile.ZipFile):
+        for info in archive.infolist():
+            if info.is_dir():
+                continue
+            if info.filename.lower().endswith(".zip"):
+                add_zip(stack.enter_context(zipfile.ZipFile(BytesIO(archive.read(info)))))
+                continue
+            mtime = normalize_to_utc(datetime(*info.date_time).astimezone())
+            files[info.filename] = (lambda archive=archive, info=info: archive.read(info), mtime)
+
+    add_zip(stack.enter_context(zipfile.ZipFile(path)))
+    return files
@@ -0,0 +1,151 @@
+"""
+Importing notes from Google Keep, as downloaded with Google Takeout
+
+Takeout's Keep folder has a JSON file per note, with its title, text or
+checklist, labels, color, pinned/archived/trashed state and timestamps in
+microseconds, plus the attached images and recordings beside it. The
+Takeout ZIP file, or the directory it was extracted to, can be imported.
+"""
+
+import json
+import posixpath
+import uuid
+import zipfile
+from contextlib import ExitStack
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Any, Dict, List, Optional
+from .export_files import ExportFile, list_export_files
+from ..note import Note
+from ..attachments import ATTACHMENTS_PROPERTY, Attachment, unique_name
+from ..i18n import t
+
+# Keep's note colors to the nearest color label
+COLORS = {
+    "RED": "red", "PINK": "magenta", "PURPLE": "magenta",
+    "ORANGE": "yellow", "YELLOW": "yellow", "BROWN": "yellow",
+    "GREEN": "green", "TEAL": "cyan", "BLUE": "blue", "CERULEAN": "blue",
+}
+
+
+def _parse_time(microseconds: Any) -> Optional[datetime]:
+    """Parse a Keep timestamp (microseconds since 1970, UTC)"""
+    try:
+        return datetime(1970, 1, 1) + timedelta(microseconds=int(microseconds)) if microseconds else None
+    except (TypeError, ValueError, OverflowError):
+        return None
+
+
+def _find_attachment(name: str, directory: str, files: Dict[str, ExportFile]) -> Optional[str]:
+    """
+    Find an attached file in the export
+
+    Keep's JSON sometimes names a file with a different extension than
+    it's saved with (".jpeg" for ".jpg"), so another file with the same
+    name apart from that is used.
+    """
+    path = posixpath.join(directory, name)
+    if path in files:
+        return path
+    stem = posixpath.splitext(path)[0]
+    return next((other for other in files if posixpath.splitext(other)[0] == stem), None)
+
+
+def keep_note_content(data: Dict[str, Any]) -> str:
+    """
+    Build a note's markdown from a Keep note
+
+    Args:
+        data: Keep note JSON
+
+    Returns:
+        Title as a heading, then the text or checklist, then any links
+    """
+    parts = []
+    title = (data.get("title") or "").strip()
+    if title:
+        parts.append(f"# {title}")
+    text = (data.get("textContent") or "").strip("\n")
+    if text:
+        parts.append(text)
+    items = data.get("listContent") or []
+    if items:
+        parts.append("\n".join(f"- [{'x' if item.get('isChecked') else ' '}] {item.get('text', '')}"
+                               for item in items))
+    links = [annotation for annotation in data.get("annotations") or [] if annotation.get("url")]
+    if links:
+        parts.append("\n".join(f"- [{link.get('title') or link['url']}]({link['url']})" for link in links))
+    return "\n\n".join(parts) + "\n" if parts else ""
+
+
+def read_keep_takeout(path: str, notebook: str = "", attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read every note in a Google Keep Takeout export, copying the files attached to them
+
+    Labels become tags; colors, pinning, archiving and the trash are kept.
+
+    Args:
+        path: Takeout ZIP file, or the directory it was extracted to
+        notebook: Notebook to put the notes in ("" for the root)
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        Notes with IDs that are the same each time the export is read, not yet stored
+
+    Raises:
+        OSError: If the export can't be read or the files can't be copied
+        ValueError: If the file isn't a ZIP file
+    """
+    notes = []
+    with ExitStack() as stack:
+        try:
+            files = list_export_files(Path(path).expanduser(), stack)
+        except zipfile.BadZipFile:
+            raise ValueError(t("import.invalid_keep", path=path))
+
+        for name in sorted(files):
+            if not name.lower().endswith(".json"):
+                continue
+            try:
+                data = json.loads(files[name][0]().decode("utf-8"))
+            except (UnicodeDecodeError, ValueError):
+                continue
+            # Other Takeout JSON files, such as Keep's labels list, aren't notes
+            if not isinstance(data, dict) or "userEditedTimestampUsec" not in data:
+                continue
+
+            note_id = str(uuid.uuid5(uuid.NAMESPACE_URL, f"keep:{name}"))
+            updated_at = _parse_time(data.get("userEditedTimestampUsec")) or files[name][1]
+            created_at = _parse_time(data.get("createdTimestampUsec")) or updated_at
+            note = Note(note_id, keep_note_content(data), created_at, updated_at)
+
+            for label in data.get("labels") or []:
+                if label.get("name"):
+                    note.add_tag(label["name"])
+            if COLORS.get(data.get("color")):
+                note.set_property("color", COLORS[data["color"]])
+            if data.get("isPinned"):
+                note.set_property("pinned", True)
+            if data.get("isArchived"):
+                note.set_property("archived", True)
+            if data.get("isTrashed"):
+                note.set_property("deleted_at", updated_at.isoformat())
+            if notebook:
+                note.set_property("notebook", notebook)
+
+            attachments: List[Attachment] = []
+            for entry in data.get("attachments") or []:
+                source = entry.get("filePath") and _find_attachment(entry["filePath"], posixpath.dirname(name), files)
+                if not source:
+                    continue
+                attached_name = unique_name(posixpath.basename(source), [a.name for a in attachments])
+                contents = files[source][0]()
+                if attachments_dir is not None:
+                    file_path = Path(attachments_dir) / note_id / attached_name
+                    file_path.parent.mkdir(parents=True, exist_ok=True)
+                    file_path.write_bytes(contents)
+                attachments.append(Attachment(name=attached_name, size=len(contents), added_at=updated_at))
+            if attachments:
+                note.set_property(ATTACHMENTS_PROPERTY, [attachment.to_dict() for attachment in attachments])
+            notes.append(note)
+    return notes
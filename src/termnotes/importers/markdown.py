@@ -0,0 +1,201 @@
+"""
+Importing notes from a directory of markdown files, such as an Obsidian vault
+"""
+
+import errno
+import json
+import os
+import re
+import uuid
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+from ..note import Note
+from ..notebook import Notebook
+from ..utils import normalize_to_utc
+
+FENCE = "---"
+
+# Frontmatter keys holding timestamps, most specific first
+CREATED_KEYS = ("created_at", "created", "date")
+UPDATED_KEYS = ("updated_at", "updated", "modified", "lastmod")
+
+# Obsidian tags: "#" then letters, digits, "_", "-" or "/", with at least one non-digit
+INLINE_TAG = re.compile(r'(?<![\w#&/])#([\w/-]*[^\W\d][\w/-]*)')
+CODE_FENCE = re.compile(r'^\s*(```|~~~)')
+CODE_SPAN = re.compile(r'`[^`]*`')
+
+
+def _parse_value(value: str) -> Any:
+    """Parse a simple YAML scalar or inline list"""
+    value = value.strip()
+    if value.startswith("[") and value.endswith("]"):
+        return [_parse_value(item) for item in value[1:-1].split(",") if item.strip()]
+    if len(value) >= 2 and value[0] == value[-1] and value[0] in "'\"":
+        return value[1:-1]
+    try:
+        return json.loads(value)
+    except json.JSONDecodeError:
+        return value
+
+
+def split_yaml_frontmatter(text: str) -> Tuple[Dict[str, Any], str]:
+    """
+    Split a file into its YAML frontmatter and body
+
+    Handles the YAML that note apps write: "key: value" lines with quoted or
+    plain scalars, inline lists ("[a, b]") and block lists ("- a" lines
+    under "key:"). Anything else is kept as a plain string.
+
+    Args:
+        text: File content
+
+    Returns:
+        Tuple of (header dict, body); the header is empty if there is none
+    """
+    if not text.startswith(FENCE + "\n"):
+        return {}, text
+    end = text.find("\n" + FENCE, len(FENCE))
+    if end == -1:
+        return {}, text
+
+    header: Dict[str, Any] = {}
+    key = None
+    for line in text[len(FENCE) + 1:end].split("\n"):
+        stripped = line.strip()
+        if key and stripped.startswith("- "):
+            if not isinstance(header[key], list):
+                header[key] = []
+            header[key].append(_parse_value(stripped[2:]))
+            continue
+        name, sep, value = line.partition(":")
+        if not sep or not name.strip() or line[0].isspace():
+            continue
+        key = name.strip()
+        header[key] = _parse_value(value) if value.strip() else ""
+
+    body_start = end + len(FENCE) + 1
+    if text.startswith("\n", body_start):
+        body_start += 1
+    return header, text[body_start:]
+
+
+def _parse_time(value: Any) -> Optional[datetime]:
+    """Parse a frontmatter timestamp as naive UTC, or None if it isn't one"""
+    try:
+        return normalize_to_utc(datetime.fromisoformat(str(value).strip()))
+    except ValueError:
+        return None
+
+
+def _header_tags(value: Any) -> List[str]:
+    """Get tags from a frontmatter value: a list, or a string separated by commas or spaces"""
+    if isinstance(value, list):
+        return [str(tag) for tag in value]
+    if isinstance(value, str):
+        return value.replace(",", " ").split()
+    return []
+
+
+def inline_tags(body: str) -> List[str]:
+    """Find "#tag" words in a note body, outside code"""
+    tags = []
+    in_code = False
+    for line in body.split("\n"):
+        if CODE_FENCE.match(line):
+            in_code = not in_code
+        elif not in_code:
+            tags.extend(INLINE_TAG.findall(CODE_SPAN.sub("", line)))
+    return tags
+
+
+def _iter_files(directory: Path, extensions: Tuple[str, ...]) -> Iterator[Path]:
+    """Yield note files, skipping hidden files and directories such as .obsidian and .trash"""
+    for path in sorted(directory.rglob("*")):
+        relative = path.relative_to(directory)
+        if path.is_file() and path.suffix.lower() in extensions \
+                and not any(part.startswith(".") for part in relative.parts):
+            yield path
+
+
+def read_markdown_file(path: Path, notebook: str = "", obsidian: bool = False) -> Note:
+    """
+    Read a markdown file as a new note
+
+    Frontmatter timestamps become the note's times, falling back to the
+    file's modification time. Tags become the note's tags, and other keys
+    are kept as properties. For Obsidian, whose notes are titled by their
+    file name, the name is added as a heading if the note doesn't start with
+    it, and inline "#tags" are added to the note's tags.
+
+    Args:
+        path: File to read
+        notebook: Notebook to place the note in
+        obsidian: Apply Obsidian's conventions
+
+    Returns:
+        Note with a new ID
+    """
+    header, body = split_yaml_frontmatter(path.read_text(encoding="utf-8", errors="replace"))
+    mtime = datetime.fromtimestamp(path.stat().st_mtime, timezone.utc).replace(tzinfo=None)
+
+    def header_time(keys: Tuple[str, ...]) -> Optional[datetime]:
+        for key in keys:
+            if key in header and _parse_time(header[key]):
+                return _parse_time(header[key])
+        return None
+
+    updated_at = header_time(UPDATED_KEYS) or mtime
+    created_at = header_time(CREATED_KEYS) or min(mtime, updated_at)
+
+    tags = _header_tags(header.get("tags", header.get("tag")))
+    if obsidian:
+        tags.extend(inline_tags(body))
+        if Note("", body).title != path.stem:
+            body = f"# {path.stem}\n\n{body}"
+
+    # Keys that are neither timestamps, tags nor termnotes' own fields stay as properties
+    skip = set(CREATED_KEYS + UPDATED_KEYS) | {"tags", "tag", "id", "properties", "notebook"}
+    properties = {key: value for key, value in header.items() if key not in skip}
+    if isinstance(header.get("properties"), dict):
+        properties.update(header["properties"])  # A file exported by termnotes
+    # The notebook comes from the file's folder; one the file names is only kept normalized
+    written = properties.pop("notebook", header.get("notebook"))
+    if not notebook and isinstance(written, str):
+        notebook = Notebook.normalize_path(written)
+
+    note = Note(str(uuid.uuid4()), body, created_at, updated_at, properties)
+    for tag in tags:
+        note.add_tag(tag)
+    if notebook:
+        note.set_property("notebook", notebook)
+    return note
+
+
+def read_markdown_directory(directory: str, obsidian: bool = False, notebook: str = "") -> List[Note]:
+    """
+    Read every markdown file in a directory tree as new notes
+
+    Subdirectories become notebooks.
+
+    Args:
+        directory: Directory to read, e.g. an Obsidian vault
+        obsidian: Apply Obsidian's conventions (see read_markdown_file)
+        notebook: Notebook to put the imported notebooks under ("" for the root)
+
+    Returns:
+        Notes with new IDs, not yet stored
+
+    Raises:
+        OSError: If the directory doesn't exist or a file can't be read
+    """
+    root = Path(directory).expanduser()
+    if not root.is_dir():
+        raise NotADirectoryError(errno.ENOTDIR, os.strerror(errno.ENOTDIR), directory)
+    extensions = (".md",) if obsidian else (".md", ".markdown", ".txt")
+    notes = []
+    for path in _iter_files(root, extensions):
+        folder = Notebook.SEPARATOR.join(path.relative_to(root).parent.parts)
+        path_in_notebook = Notebook.normalize_path(f"{notebook}{Notebook.SEPARATOR}{folder}")
+        notes.append(read_markdown_file(path, path_in_notebook, obsidian))
+    return notes
@@ -0,0 +1,265 @@
+"""
+Importing notes from Notion's exports
+
+Notion exports a workspace or page as a ZIP file (sometimes a ZIP of ZIP
+files) of "Markdown & CSV" or "HTML" files; the ZIP file or the directory
+it was extracted to can be imported. Each page is a file named after its
+title and Notion's page ID ("Plans 0123...cdef.md"), and its subpages are
+in a directory of the same name, which becomes a notebook.
+
+A database is a CSV file (or, in HTML exports, a page listing it) with a
+directory holding a page per row. Rows become notes tagged with the
+database's name and the values of their tag-like properties (TAG_PROPERTIES);
+all their properties are listed at the top of the note.
+
+Notion's own markup is made standard markdown: links to other pages
+become [[wiki links]], links to files in the export attach the files
+(shown as "[attachment: name]"), and callouts (<aside>) become block quotes.
+"""
+
+import html
+import posixpath
+import re
+import uuid
+import zipfile
+from contextlib import ExitStack
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+from urllib.parse import unquote, urlparse
+from .enex import EnmlConverter
+from .export_files import ExportFile, list_export_files
+from ..note import Note
+from ..attachments import ATTACHMENTS_PROPERTY, Attachment, unique_name
+from ..notebook import Notebook
+from ..utils import normalize_to_utc
+from ..i18n import t
+
+PAGE_EXTENSIONS = (".md", ".html")
+
+# Properties whose values become tags (compared casefolded); multiple values are separated by ", "
+TAG_PROPERTIES = ("tags", "tag", "labels", "label", "category", "categories", "type", "status")
+
+# Properties holding the page's timestamps, compared casefolded
+CREATED_PROPERTIES = ("created", "created time", "date created")
+UPDATED_PROPERTIES = ("last edited time", "last edited", "updated", "last updated")
+
+# How Notion writes dates in property values
+TIME_FORMATS = ("%B %d, %Y %I:%M %p", "%B %d, %Y")
+
+# Notion's ID at the end of a file or directory name
+NOTION_ID = re.compile(r'\s+([0-9a-f]{32})$')
+MARKDOWN_LINK = re.compile(r'(!?)\[([^\]\n]*)\]\(([^)\s]+)\)')
+PROPERTY_LINE = re.compile(r'^([^:\n]{1,60}): (.*)$')
+HTML_PROPERTY_ROW = re.compile(r'<tr class="property-row[^"]*">\s*<th>(.*?)</th>\s*<td>(.*?)</td>\s*</tr>', re.S)
+HTML_PROPERTIES = re.compile(r'<table class="properties">.*?</table>', re.S)
+HTML_SELECTED_VALUE = re.compile(r'<span class="selected-value[^"]*">(.*?)</span>', re.S)
+HTML_TAG = re.compile(r'<[^>]+>')
+
+
+def _split_id(name: str) -> Tuple[str, Optional[str]]:
+    """Split a file or directory name (without extension) into its title and Notion ID"""
+    match = NOTION_ID.search(name)
+    if match:
+        return name[:match.start()].strip(), match.group(1)
+    return name.strip(), None
+
+
+def _parse_time(value: str) -> Optional[datetime]:
+    """Parse a date as Notion writes it, in local time, as naive UTC"""
+    for time_format in TIME_FORMATS:
+        try:
+            return normalize_to_utc(datetime.strptime(value.strip(), time_format).astimezone())
+        except ValueError:
+            continue
+    return None
+
+
+def _html_page(text: str) -> Tuple[Dict[str, str], str]:
+    """
+    Convert an HTML page to markdown
+
+    Returns:
+        (properties from the page's header table, markdown without the table)
+    """
+    properties = {}
+    for key, value in HTML_PROPERTY_ROW.findall(text):
+        selected = HTML_SELECTED_VALUE.findall(value)
+        if selected:
+            value = ", ".join(html.unescape(HTML_TAG.sub("", item)).strip() for item in selected)
+        else:
+            value = " ".join(html.unescape(HTML_TAG.sub(" ", value)).split())
+        properties[" ".join(html.unescape(HTML_TAG.sub("", key)).split())] = value
+    converter = EnmlConverter()
+    converter.feed(HTML_PROPERTIES.sub("", text))
+    converter.close()
+    return properties, converter.markdown()
+
+
+def _markdown_properties(text: str) -> Tuple[Dict[str, str], str]:
+    """
+    Take a database row's properties from the top of its markdown page
+
+    Notion writes them as "Key: value" lines between the title and the body.
+
+    Returns:
+        (properties, page without them)
+    """
+    lines = text.split("\n")
+    start = 0
+    if lines and lines[0].startswith("# "):
+        start = 1
+        while start < len(lines) and not lines[start].strip():
+            start += 1
+    properties = {}
+    end = start
+    while end < len(lines) and PROPERTY_LINE.match(lines[end]):
+        key, value = PROPERTY_LINE.match(lines[end]).groups()
+        properties[key.strip()] = value.strip()
+        end += 1
+    if not properties:
+        return {}, text
+    return properties, "\n".join(lines[:start] + lines[end:])
+
+
+def _callouts_to_quotes(text: str) -> str:
+    """Turn Notion's <aside> callouts into block quotes"""
+    out = []
+    in_aside = False
+    for line in text.split("\n"):
+        if line.strip() == "<aside>":
+            in_aside = True
+        elif line.strip() == "</aside>" and in_aside:
+            in_aside = False
+            while out and out[-1] == ">":
+                out.pop()
+        elif in_aside:
+            out.append(f"> {line}".rstrip())
+        else:
+            out.append(line)
+    return "\n".join(out)
+
+
+def _is_database(directory: str, files: Dict[str, ExportFile]) -> bool:
+    """Check whether a directory in the export holds a database's rows"""
+    if f"{directory}.csv" in files or f"{directory}_all.csv" in files:
+        return True
+    page = files.get(f"{directory}.html")
+    return page is not None and b'class="collection-content"' in page[0]()
+
+
+def read_notion_export(path: str, notebook: str = "", attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read every page in a Notion export as a note, copying the files attached to them
+
+    Args:
+        path: ZIP file exported from Notion, or the directory it was extracted to
+        notebook: Notebook to put the imported notebooks under ("" for the root)
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        Notes with IDs from Notion's page IDs, not yet stored
+
+    Raises:
+        OSError: If the export can't be read or the files can't be copied
+        ValueError: If the file isn't a ZIP file
+    """
+    with ExitStack() as stack:
+        try:
+            files = list_export_files(Path(path).expanduser(), stack)
+        except zipfile.BadZipFile:
+            raise ValueError(t("import.invalid_notion", path=path))
+        pages = {name: _split_id(posixpath.splitext(posixpath.basename(name))[0])
+                 for name in files if posixpath.splitext(name)[1].lower() in PAGE_EXTENSIONS}
+        row_directories = {directory for directory in {posixpath.dirname(name) for name in pages}
+                           if directory and _is_database(directory, files)}
+        # Database tables in HTML exports list the rows, which are imported themselves
+        for directory in row_directories:
+            pages.pop(f"{directory}.html", None)
+        databases = ({name for name in files if name.endswith(".csv")}
+                     | {f"{directory}.html" for directory in row_directories})
+        texts = {name: files[name][0]().decode("utf-8", errors="replace") for name in pages}
+
+        def note_id(name: str) -> str:
+            """Get the ID of the note for a page: Notion's, or one that's the same each time"""
+            page_id = pages[name][1]
+            return str(uuid.UUID(hex=page_id)) if page_id else str(uuid.uuid5(uuid.NAMESPACE_URL, f"notion:{name}"))
+
+        notes = []
+        converted = {}
+        for name, text in texts.items():
+            row = posixpath.dirname(name) in row_directories
+            if name.endswith(".html"):
+                converted[name] = _html_page(text)
+            else:
+                converted[name] = _markdown_properties(text) if row else ({}, text)
+        # A page's title is its first heading, as Notion writes it, or else its file name
+        titles = {name: Note("", body).title if body.lstrip().startswith("# ") else pages[name][0]
+                  for name, (_, body) in converted.items()}
+
+        for name in sorted(pages):
+            directory = posixpath.dirname(name)
+            row = directory in row_directories
+            properties, body = converted[name]
+
+            attachments: List[Attachment] = []
+            attached: Dict[str, str] = {}  # File in the export to attached file name
+            this_id = note_id(name)
+
+            def replace_link(match: re.Match) -> str:
+                """Turn a link to another page into a wiki link, and one to a file into an attachment"""
+                text, target = match.group(2), match.group(3)
+                if urlparse(target).scheme:
+                    return match.group(0)
+                target = posixpath.normpath(posixpath.join(directory, unquote(target)))
+                if target in titles:
+                    linked = titles[target]
+                    return f"[[{linked}]]" if text in ("", linked) else f"[[{linked}|{text}]]"
+                if target in databases:
+                    return text
+                if target not in files:
+                    return match.group(0)
+                if target not in attached:
+                    attached[target] = unique_name(posixpath.basename(target), list(attached.values()))
+                    data = files[target][0]()
+                    if attachments_dir is not None:
+                        file_path = Path(attachments_dir) / this_id / attached[target]
+                        file_path.parent.mkdir(parents=True, exist_ok=True)
+                        file_path.write_bytes(data)
+                    attachments.append(Attachment(name=attached[target], size=len(data), added_at=files[target][1]))
+                return f"_{t('import.attachment', name=attached[target])}_"
+
+            body = _callouts_to_quotes(MARKDOWN_LINK.sub(replace_link, body)).strip("\n")
+            title = titles[name]
+            if not body.startswith("# "):
+                body = f"# {title}\n\n{body}" if body else f"# {title}"
+            if properties:
+                lines = body.split("\n")
+                listed = [f"- **{key}:** {value}" for key, value in properties.items()]
+                body = "\n".join(lines[:1] + [""] + listed + ([""] + lines[1:] if len(lines) > 1 else []))
+                body = re.sub(r"\n{3,}", "\n\n", body)
+
+            by_key = {key.casefold(): value for key, value in properties.items()}
+            created_at = next((_parse_time(by_key[key]) for key in CREATED_PROPERTIES
+                               if key in by_key and _parse_time(by_key[key])), None)
+            updated_at = next((_parse_time(by_key[key]) for key in UPDATED_PROPERTIES
+                               if key in by_key and _parse_time(by_key[key])), None)
+            updated_at = updated_at or files[name][1]
+            created_at = created_at or min(files[name][1], updated_at)
+
+            note = Note(this_id, body + "\n", created_at, updated_at)
+            if row:
+                note.add_tag(_split_id(posixpath.basename(directory))[0])
+            for key, value in by_key.items():
+                if key in TAG_PROPERTIES:
+                    for tag in value.split(","):
+                        if tag.strip():
+                            note.add_tag(tag)
+            folders = [_split_id(part)[0].replace(Notebook.SEPARATOR, "-") for part in directory.split("/") if part]
+            path_in_notebook = Notebook.normalize_path(Notebook.SEPARATOR.join([notebook] + folders))
+            if path_in_notebook:
+                note.set_property("notebook", path_in_notebook)
+            if attachments:
+                note.set_property(ATTACHMENTS_PROPERTY, [attachment.to_dict() for attachment in attachments])
+            notes.append(note)
+    return notes
@@ -0,0 +1,467 @@
+"""
+Joplin's JEX export files, read and written
+
+`termnotes import --from joplin notes.jex` reads one and
+`termnotes export --jex notes.jex` writes one that Joplin can import
+(File > Import > JEX), so notes can move either way.
+
+A JEX file is a tar archive with one "<id>.md" file per Joplin item and
+the files attached to notes under resources/. An item is its title, a
+blank line, its body (for notes), a blank line, then "key: value"
+properties ending with "type_", the kind of item (the ITEM_ constants).
+
+- Notes keep their IDs (Joplin's 32 hex digits, as a UUID here), title,
+  timestamps, source URL, author, due date, and trash.
+- Notebooks (Joplin's folders, nested through parent_id) and tags (tag
+  items, linked to notes by note_tag items) map onto termnotes' own.
+- Resources become attached files. Links to them in a note's body become
+  the "[attachment: name]" placeholder the importers use; links to other
+  notes become [[wiki links]]. Exporting turns both back into Joplin links.
+
+Encrypted items can't be read and are skipped.
+"""
+
+import io
+import mimetypes
+import re
+import tarfile
+import uuid
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+from .note import Note
+from .attachments import ATTACHMENTS_PROPERTY, Attachment, note_attachments, unique_name
+from .links import WIKI_LINK, find_linked_note
+from .notebook import Notebook, get_note_notebook
+from .utils import utc_now
+from .i18n import t
+from .log import event, get_logger
+
+log = get_logger("joplin")
+
+ITEM_NOTE = 1
+ITEM_FOLDER = 2
+ITEM_RESOURCE = 4
+ITEM_TAG = 5
+ITEM_NOTE_TAG = 6
+
+RESOURCES_DIR = "resources"
+TIME_FORMAT = "%Y-%m-%dT%H:%M:%S.%fZ"
+
+# Note properties kept as Joplin note properties of the same name
+PROPERTIES = ("source_url", "author")
+
+# Joplin link to a note or resource: [text](:/<id>), or ![text](:/<id>) for images
+JOPLIN_LINK = re.compile(r'(!?)\[([^\]\n]*)\]\(:/([0-9a-fA-F]{32})\)')
+
+
+def _unescape(value: str) -> str:
+    """Undo Joplin's escaping of line breaks in property values"""
+    return (value.replace("\\n", "\n").replace("\\r", "\r")
+            .replace("\\\n", "\\n").replace("\\\r", "\\r"))
+
+
+def _escape(value: str) -> str:
+    """Escape line breaks in a property value like Joplin does"""
+    return (value.replace("\\n", "\\\\n").replace("\\r", "\\\\r")
+            .replace("\n", "\\n").replace("\r", "\\r"))
+
+
+def parse_item(text: str) -> Tuple[str, str, Dict[str, str]]:
+    """
+    Split a JEX item into its title, body and properties
+
+    Properties are read from the end up to the first blank line; what's
+    above is the title, a blank line, then the body.
+
+    Args:
+        text: Contents of an item's .md file
+
+    Returns:
+        (title, body, properties)
+
+    Raises:
+        ValueError: If a property line has no "key: value", or there's no "type_"
+    """
+    lines = text.rstrip("\n").split("\n")
+    properties: Dict[str, str] = {}
+    index = len(lines) - 1
+    while index >= 0 and lines[index].strip():
+        key, colon, value = lines[index].strip().partition(":")
+        if not colon:
+            raise ValueError(f"not a Joplin item property: {lines[index]!r}")
+        properties[key.strip()] = _unescape(value.strip())
+        index -= 1
+    if "type_" not in properties:
+        raise ValueError("Joplin item without a type")
+    body = lines[:max(index, 0)]
+    title = body[0] if body else ""
+    return title, "\n".join(body[2:]), properties
+
+
+def format_item(title: Optional[str], body: str, properties: List[Tuple[str, object]]) -> str:
+    """
+    Write a JEX item
+
+    Args:
+        title: Title (None for items without one, like note_tag)
+        body: Body ("" for none)
+        properties: (key, value) pairs, in order, ending with "type_"
+
+    Returns:
+        Contents of the item's .md file
+    """
+    parts = []
+    if title is not None:
+        parts.append(title)
+    if body:
+        parts.append(body)
+    parts.append("\n".join(f"{key}: {_escape(str(value))}" for key, value in properties))
+    return "\n\n".join(parts)
+
+
+def _parse_time(value: Optional[str]) -> Optional[datetime]:
+    """Parse a Joplin time: ISO in UTC ("2024-01-31T12:00:00.000Z") or milliseconds since 1970"""
+    value = (value or "").strip()
+    if not value or value == "0":
+        return None
+    if value.isdigit():
+        return datetime(1970, 1, 1) + timedelta(milliseconds=int(value))
+    try:
+        return datetime.strptime(value, TIME_FORMAT)
+    except ValueError:
+        return None
+
+
+def _format_time(value: Optional[datetime]) -> str:
+    """Format a UTC time like Joplin, with milliseconds"""
+    return f"{value.strftime('%Y-%m-%dT%H:%M:%S')}.{value.microsecond // 1000:03d}Z" if value else ""
+
+
+def _milliseconds(value: Optional[datetime]) -> int:
+    """Get a UTC time as milliseconds since 1970 (0 for none), as Joplin's trash and to-do times are kept"""
+    return int((value - datetime(1970, 1, 1)).total_seconds() * 1000) if value else 0
+
+
+def note_id_from_joplin(joplin_id: str) -> str:
+    """
+    Get the note ID for a Joplin ID: the same 32 hex digits, written as a UUID
+
+    An ID that isn't one (a crafted file's "../x", or none) gets a UUID
+    derived from it instead, since note IDs name files and directories.
+    """
+    try:
+        return str(uuid.UUID(hex=joplin_id))
+    except ValueError:
+        return str(uuid.uuid5(uuid.NAMESPACE_URL, f"joplin:{joplin_id}"))
+
+
+def joplin_id(key: str) -> str:
+    """
+    Get a Joplin ID for a termnotes note or other thing
+
+    Args:
+        key: Note ID, which is kept if it's a UUID, or a name like "tag:work"
+            that always gets the same new ID
+
+    Returns:
+        32 lowercase hex digits
+    """
+    try:
+        return uuid.UUID(key).hex
+    except ValueError:
+        return uuid.uuid5(uuid.NAMESPACE_URL, f"termnotes:{key}").hex
+
+
+def _folder_path(folder_id: str, folders: Dict[str, Tuple[str, str]]) -> str:
+    """Get the notebook path of a Joplin folder from its title and its parents'"""
+    names = []
+    seen = set()
+    while folder_id in folders and folder_id not in seen:
+        seen.add(folder_id)
+        title, folder_id = folders[folder_id]
+        names.append(title.replace("/", "-"))
+    return "/".join(reversed(names))
+
+
+def read_jex_file(path: str, notebook: str = "", attachments_dir: Optional[Path] = None) -> List[Note]:
+    """
+    Read every note in a JEX file, copying the files attached to them
+
+    Args:
+        path: JEX file exported from Joplin
+        notebook: Notebook to put the notes under, keeping their own
+            notebooks inside it ("" to keep them as they were)
+        attachments_dir: Where to copy the attached files (None to leave them)
+
+    Returns:
+        Notes with the IDs they had in Joplin, not yet stored
+
+    Raises:
+        OSError: If the file can't be read or the files can't be copied
+        ValueError: If the file isn't a valid JEX file
+    """
+    items: List[Tuple[str, str, Dict[str, str]]] = []
+    resource_files: Dict[str, tarfile.TarInfo] = {}
+    try:
+        archive = tarfile.open(Path(path).expanduser())
+    except tarfile.TarError:
+        raise ValueError(t("import.invalid_jex", path=path))
+    with archive:
+        for member in archive.getmembers():
+            if not member.isfile():
+                continue
+            if member.name.startswith(f"{RESOURCES_DIR}/"):
+                resource_id = Path(member.name).name.split(".")[0]
+                resource_files[resource_id] = member
+            elif member.name.endswith(".md") and "/" not in member.name:
+                items.append(parse_item(archive.extractfile(member).read().decode("utf-8")))
+
+        by_type: Dict[int, List[Tuple[str, str, Dict[str, str]]]] = {}
+        for item in items:
+            if item[2].get("encryption_applied", "0") != "0":
+                continue
+            try:
+                by_type.setdefault(int(item[2]["type_"]), []).append(item)
+            except ValueError:
+                continue
+
+        folders = {props.get("id", ""): (title, props.get("parent_id", ""))
+                   for title, _, props in by_type.get(ITEM_FOLDER, [])}
+        tags = {props.get("id", ""): title for title, _, props in by_type.get(ITEM_TAG, [])}
+        resources = {props.get("id", ""): (title or props.get("filename") or props.get("id", ""), props)
+                     for title, _, props in by_type.get(ITEM_RESOURCE, [])}
+        note_titles = {props.get("id", ""): title for title, _, props in by_type.get(ITEM_NOTE, [])}
+        note_tags: Dict[str, List[str]] = {}
+        for _, _, props in by_type.get(ITEM_NOTE_TAG, []):
+            if props.get("tag_id") in tags:
+                note_tags.setdefault(props.get("note_id", ""), []).append(tags[props["tag_id"]])
+
+        notebook = Notebook.normalize_path(notebook)
+        notes = []
+        for title, body, props in by_type.get(ITEM_NOTE, []):
+            attachments: List[Attachment] = []
+            attached: Dict[str, str] = {}  # Resource ID to attached file name
+            note_id = note_id_from_joplin(props.get("id", ""))
+
+            def replace_link(match: re.Match) -> str:
+                """Turn a Joplin link into a wiki link or attachment placeholder"""
+                text, target = match.group(2), match.group(3).lower()
+                if target in note_titles:
+                    linked = note_titles[target]
+                    return f"[[{linked}]]" if text in ("", linked) else f"[[{linked}|{text}]]"
+                if target not in resources:
+                    return text
+                if target not in attached:
+                    name, resource = resources[target]
+                    extension = resource.get("file_extension", "")
+                    if extension and not name.lower().endswith(f".{extension.lower()}"):
+                        name = f"{name}.{extension}"
+                    name = Path(name.replace("\\", "/")).name
+                    if name in ("", ".", ".."):
+                        name = target
+                    name = unique_name(name, list(attached.values()))
+                    attached[target] = name
+                    member = resource_files.get(target)
+                    if member is not None:
+                        if attachments_dir is not None:
+                            file_path = Path(attachments_dir) / note_id / name
+                            file_path.parent.mkdir(parents=True, exist_ok=True)
+                            file_path.write_bytes(archive.extractfile(member).read())
+                        added_at = _parse_time(resource.get("created_time")) or utc_now()
+                        attachments.append(Attachment(name=name, size=member.size, added_at=added_at))
+                return f"_{t('import.attachment', name=attached[target])}_"
+
+            body = JOPLIN_LINK.sub(replace_link, body)
+            content = (f"# {title}\n\n{body}" if body else f"# {title}") if title else body
+            created_at = (_parse_time(props.get("user_created_time"))
+                          or _parse_time(props.get("created_time")) or utc_now())
+            updated_at = (_parse_time(props.get("user_updated_time"))
+                          or _parse_time(props.get("updated_time")) or created_at)
+
+            note = Note(note_id, content, created_at, updated_at)
+            for tag in note_tags.get(props.get("id", ""), []):
+                note.add_tag(tag)
+            inner = _folder_path(props.get("parent_id", ""), folders)
+            path_in_notebook = "/".join(part for part in (notebook, inner) if part)
+            if path_in_notebook:
+                note.set_property("notebook", path_in_notebook)
+            for key in PROPERTIES:
+                if props.get(key):
+                    note.set_property(key, props[key])
+            due_at = _parse_time(props.get("todo_due"))
+            if props.get("is_todo") == "1" and due_at:
+                note.set_property("due_at", due_at.isoformat())
+            deleted_at = _parse_time(props.get("deleted_time"))
+            if deleted_at:
+                note.set_property("deleted_at", deleted_at.isoformat())
+            if attachments:
+                note.set_property(ATTACHMENTS_PROPERTY, [attachment.to_dict() for attachment in attachments])
+            notes.append(note)
+    return notes
+
+
+def _common_properties(created_at: datetime, updated_at: datetime) -> List[Tuple[str, object]]:
+    """Timestamps and encryption properties every Joplin item has"""
+    return [
+        ("created_time", _format_time(created_at)),
+        ("updated_time", _format_time(updated_at)),
+        ("user_created_time", _format_time(created_at)),
+        ("user_updated_time", _format_time(updated_at)),
+        ("encryption_cipher_text", ""),
+        ("encryption_applied", 0),
+    ]
+
+
+def _title_and_body(note: Note) -> Tuple[str, str]:
+    """Split a note into a Joplin title and body, dropping the heading the title came from"""
+    lines = note.content.split("\n")
+    index = 0
+    while index < len(lines) and not lines[index].strip():
+        index += 1
+    if index < len(lines) and lines[index].lstrip().startswith("#"):
+        index += 1
+        while index < len(lines) and not lines[index].strip():
+            index += 1
+    return note.title, "\n".join(lines[index:])
+
+
+def write_jex(notes: Iterable[Note], attachments_dir: Path, path: str) -> Path:
+    """
+    Write notes, their notebooks, tags and attached files to a JEX file
+
+    Notes at the root aren't in a Joplin folder; Joplin puts them in a new
+    one when importing.
+
+    Args:
+        notes: Notes to write
+        attachments_dir: Where the attached files are kept (StorageBackend.attachments_dir)
+        path: JEX file to write (replaced if it exists)
+
+    Returns:
+        Path of the file
+
+    Raises:
+        OSError: If the file can't be written
+    """
+    notes = list(notes)
+    now = utc_now()
+    target = Path(path).expanduser()
+    target.parent.mkdir(parents=True, exist_ok=True)
+    tmp_path = target.with_name(f".{target.name}.tmp")
+
+    def add(archive: tarfile.TarFile, name: str, data: bytes, mtime: datetime):
+        """Add a file to the archive"""
+        info = tarfile.TarInfo(name)
+        info.size = len(data)
+        info.mtime = int(_milliseconds(mtime) / 1000)
+        archive.addfile(info, io.BytesIO(data))
+
+    def add_item(archive: tarfile.TarFile, item_id: str, text: str, mtime: datetime):
+        """Add an item's .md file to the archive"""
+        add(archive, f"{item_id}.md", text.encode("utf-8"), mtime)
+
+    try:
+        with tarfile.open(tmp_path, "w") as archive:
+            folders: Dict[str, str] = {}  # Notebook path to folder ID
+            for note in notes:
+                for notebook in Notebook.ancestor_paths(get_note_notebook(note)):
+                    if notebook not in folders:
+                        folders[notebook] = joplin_id(f"notebook:{notebook}")
+                        parent = notebook.rpartition("/")[0]
+                        add_item(archive, folders[notebook], format_item(notebook.rpartition("/")[2], "", [
+                            ("id", folders[notebook]),
+                            *_common_properties(now, now),
+                            ("parent_id", folders.get(parent, "")),
+                            ("is_shared", 0),
+                            ("type_", ITEM_FOLDER),
+                        ]), now)
+
+            tags: Dict[str, str] = {}  # Tag to tag ID
+            for note in notes:
+                note_joplin_id = joplin_id(note.id)
+                for tag in note.tags:
+                    if tag not in tags:
+                        tags[tag] = joplin_id(f"tag:{tag}")
+                        add_item(archive, tags[tag], format_item(tag, "", [
+                            ("id", tags[tag]),
+                            *_common_properties(now, now),
+                            ("is_shared", 0),
+                            ("parent_id", ""),
+                            ("type_", ITEM_TAG),
+                        ]), now)
+                    link_id = joplin_id(f"note_tag:{note.id}:{tag}")
+                    add_item(archive, link_id, format_item(None, "", [
+                        ("id", link_id),
+                        ("note_id", note_joplin_id),
+                        ("tag_id", tags[tag]),
+                        *_common_properties(note.updated_at, note.updated_at),
+                        ("is_shared", 0),
+                        ("type_", ITEM_NOTE_TAG),
+                    ]), note.updated_at)
+
+                links = {}  # Attached file name to its Joplin link
+                for attachment in note_attachments(note):
+                    file_path = Path(attachments_dir) / note.id / attachment.name
+                    if not file_path.is_file():
+                        continue
+                    resource_id = joplin_id(f"attachment:{note.id}/{attachment.name}")
+                    extension = Path(attachment.name).suffix.lstrip(".")
+                    mime = mimetypes.guess_type(attachment.name)[0] or "application/octet-stream"
+                    data = file_path.read_bytes()
+                    add(archive, f"{RESOURCES_DIR}/{resource_id}" + (f".{extension}" if extension else ""),
+                        data, attachment.added_at)
+                    add_item(archive, resource_id, format_item(attachment.name, "", [
+                        ("id", resource_id),
+                        ("mime", mime),
+                        ("filename", attachment.name),
+                        *_common_properties(attachment.added_at, attachment.added_at),
+                        ("file_extension", extension),
+                        ("encryption_blob_encrypted", 0),
+                        ("size", len(data)),
+                        ("is_shared", 0),
+                        ("type_", ITEM_RESOURCE),
+                    ]), attachment.added_at)
+                    image = "!" if mime.startswith("image/") else ""
+                    links[attachment.name] = f"{image}[{attachment.name}](:/{resource_id})"
+
+                title, body = _title_and_body(note)
+
+                def replace_wiki_link(match: re.Match) -> str:
+                    """Turn a wiki link to an exported note into a Joplin link"""
+                    linked = find_linked_note(notes, match.group(1).strip())
+                    if linked is None:
+                        return match.group(0)
+                    text = match.group(0)[2:-2].partition("|")[2] or linked.title
+                    return f"[{text}](:/{joplin_id(linked.id)})"
+
+                body = WIKI_LINK.sub(replace_wiki_link, body)
+                for name, link in links.items():
+                    placeholder = f"_{t('import.attachment', name=name)}_"
+                    if placeholder in body:
+                        body = body.replace(placeholder, link)
+                    else:
+                        body = f"{body.rstrip()}\n\n{link}" if body.strip() else link
+
+                due_at = note.due_at
+                add_item(archive, note_joplin_id, format_item(title, body, [
+                    ("id", note_joplin_id),
+                    ("parent_id", folders.get(get_note_notebook(note), "")),
+                    *_common_properties(note.created_at, note.updated_at),
+                    ("is_conflict", 0),
+                    *((key, note.get_property(key) or "") for key in PROPERTIES),
+                    ("is_todo", 1 if due_at else 0),
+                    ("todo_due", _milliseconds(due_at)),
+                    ("todo_completed", 0),
+                    ("source", "termnotes"),
+                    ("source_application", "termnotes"),
+                    ("markup_language", 1),
+                    ("is_shared", 0),
+                    ("deleted_time", _milliseconds(note.deleted_at)),
+                    ("type_", ITEM_NOTE),
+                ]), note.updated_at)
+        tmp_path.replace(target)
+    finally:
+        tmp_path.unlink(missing_ok=True)
+    event(log, "jex_written", notes=len(notes))
+    return target
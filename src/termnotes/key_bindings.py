@@ -2,13 +2,22 @@
 Key binding handlers for different modes
 """
 
-from prompt_toolkit.key_binding import KeyBindings
+import functools
+from prompt_toolkit.key_binding import ConditionalKeyBindings, KeyBindings, KeyBindingsBase, merge_key_bindings
 from prompt_toolkit.filters import Condition
 from prompt_toolkit.keys import Keys
 from .editor import EditorBuffer
 from .modes import ModeManager
 from .note_list import NoteListManager
 from .focus import FocusManager
+from .storage import ReadOnlyStorage
+from .notebook import get_note_notebook
+from .note import COLORS, parse_color
+from .lists import INDENT
+from .i18n import t
+from .log import event as log_event, get_logger
+
+log = get_logger("ui")
 
 
 def create_key_bindings(
@@ -17,9 +26,20 @@ def create_key_bindings(
     note_list_manager: NoteListManager,
     focus_manager: FocusManager,
     ui  # EditorUI instance for save/load operations
-) -> KeyBindings:
+) -> KeyBindingsBase:
     """Create key bindings for the editor with sidebar support"""
     kb = KeyBindings()
+    history_kb = KeyBindings()  # Replace all other bindings while the history viewer is open
+    picker_kb = KeyBindings()  # Likewise while the template picker is open
+    dialog_kb = KeyBindings()  # Likewise while a confirmation dialog is open
+    replace_kb = KeyBindings()  # Likewise while reviewing a replacement in every note
+    tasks_kb = KeyBindings()  # Likewise while the tasks view is open
+    outline_kb = KeyBindings()  # Likewise while the outline is open
+    help_kb = KeyBindings()  # Likewise while the help overlay is open
+    tour_kb = KeyBindings()  # Likewise while the tour is open
+    switcher_kb = KeyBindings()  # Likewise while the quick switcher is open
+    secure_kb = KeyBindings()  # Likewise while asking for the secure notes' passphrase
+    lock_kb = KeyBindings()  # Replace every other binding, views' included, while the lock screen is shown
 
     # Create filter conditions
     is_normal_mode = Condition(lambda: mode_manager.is_normal_mode())
@@ -31,193 +51,433 @@ def create_key_bindings(
     is_search_mode = Condition(lambda: mode_manager.is_search_mode())
     is_sidebar_focused = Condition(lambda: focus_manager.is_sidebar_focused())
     is_editor_focused = Condition(lambda: focus_manager.is_editor_focused())
+    is_history_open = Condition(lambda: ui.history_view.is_open)
+    is_picker_open = Condition(lambda: ui.template_picker.is_open)
+    is_dialog_open = Condition(lambda: ui.confirm_dialog.is_open)
+    is_replace_open = Condition(lambda: ui.replace_view.is_open)
+    is_tasks_open = Condition(lambda: ui.tasks_view.is_open)
+    is_outline_open = Condition(lambda: ui.outline_view.is_open)
+    is_help_open = Condition(lambda: ui.help_view.is_open)
+    is_tour_open = Condition(lambda: ui.tour.is_open)
+    is_switcher_open = Condition(lambda: ui.switcher.is_open)
+    is_secure_prompt_open = Condition(lambda: ui.secure_prompt.is_open)
+    is_locked = Condition(lambda: ui.is_locked())
+    # Keys that change a note are off with read-only storage, and on a locked secure note
+    is_writable = Condition(lambda: not ui.read_only and not ui.is_current_note_locked())
+
+    keymap = ui.keymap
+
+    def bind(action: str, filter=True, registry: KeyBindings = kb):
+        """Decorator binding a handler to every key sequence the key map has for an action"""
+        def decorator(handler):
+            for sequence in keymap.sequences(action):
+                registry.add(*sequence, filter=filter)(handler)
+            return handler
+        return decorator
+
+    # ===== PLUGIN KEYS =====
+    # Added first: for the same keys, the built-in binding added later wins
+
+    def plugin_key_handler(plugin_key):
+        """Make the handler running a plugin's key action"""
+        def handler(event):
+            ui.run_plugin(plugin_key.plugin, plugin_key.handler)
+        return handler
+
+    for plugin_key in ui.plugins.keys.values():
+        bind(plugin_key.action, filter=is_normal_mode & ~is_command_mode & ~is_search_mode)(
+            plugin_key_handler(plugin_key))
 
     # ===== SIDEBAR NAVIGATION (NORMAL MODE, SIDEBAR FOCUSED) =====
 
-    @kb.add('j', filter=is_sidebar_focused & is_normal_mode)
-    @kb.add('down', filter=is_sidebar_focused & is_normal_mode)
+    @bind('down', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_move_down(event):
         """Move selection down in sidebar"""
         note_list_manager.move_selection_down()
 
-    @kb.add('k', filter=is_sidebar_focused & is_normal_mode)
-    @kb.add('up', filter=is_sidebar_focused & is_normal_mode)
+    @bind('up', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_move_up(event):
         """Move selection up in sidebar"""
         note_list_manager.move_selection_up()
 
-    @kb.add('enter', filter=is_sidebar_focused & is_normal_mode)
+    @bind('open', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_select_note(event):
-        """Select note and load into editor (keep focus on sidebar)"""
-        selected_note = note_list_manager.selected_note
-        if selected_note:
-            # Use UI's load_note which checks for unsaved changes
-            ui.load_note(selected_note)
-            # Keep focus on sidebar
+        """Select note and load into editor, or expand/collapse a notebook (keep focus on sidebar)"""
+        ui.open_selected_row()
 
-    @kb.add('o', filter=is_sidebar_focused & is_normal_mode)
+    @bind('new_note', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_create_note(event):
         """Create a new empty note from sidebar, focus editor, and enter Insert mode"""
         ui.create_new_note()
         # Enter Insert mode after creating the note
         mode_manager.enter_insert_mode()
 
-    @kb.add('i', filter=is_sidebar_focused & is_normal_mode)
+    @bind('new_from_template', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_new_from_template(event):
+        """Pick a template for a new note"""
+        ui.open_template_picker()
+
+    @bind('edit', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_switch_to_insert(event):
         """Switch focus to editor and enter insert mode"""
         focus_manager.switch_to_editor()
         mode_manager.enter_insert_mode()
 
-    @kb.add('d', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode)
-    def sidebar_delete_first_d(event):
-        """Handle first 'd' in sidebar for dd deletion"""
-        if mode_manager.command_buffer == 'd':
-            # Second 'd' pressed - confirm deletion
-            selected_note = note_list_manager.selected_note
-            if selected_note:
-                if ui.pending_deletion == selected_note.id:
-                    # Confirmed - delete the note
-                    ui.delete_note(selected_note.id)
-                    mode_manager.clear_command_buffer()
-                else:
-                    # First dd - set pending deletion
-                    ui.pending_deletion = selected_note.id
-                    mode_manager.set_message("Delete note? Press dd again to confirm")
-                    mode_manager.clear_command_buffer()
+    @bind('delete_note', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_delete_note(event):
+        """
+        Move the selected note, or the marked notes, to the trash (dd) after confirming in a dialog
+
+        In the trash, they are deleted permanently instead.
+        """
+        selected_note = note_list_manager.selected_note
+        if note_list_manager.marked_ids:
+            ui.delete_marked_notes()
+        elif selected_note:
+            question = "dialog.purge_note" if selected_note.is_trashed else "dialog.trash_note"
+            ui.ask_confirmation(
+                t(question, title=selected_note.title),
+                lambda: ui.delete_note(selected_note.id)
+            )
+        mode_manager.clear_command_buffer()
+
+    @bind('mark', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_mark(event):
+        """Mark the selected note for a bulk action, or unmark it"""
+        ui.toggle_mark()
+
+    @bind('undo', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_undo(event):
+        """Undo the last change to notes"""
+        ui.undo_operation()
+
+    @bind('redo', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_redo(event):
+        """Redo the last undone change to notes"""
+        ui.undo_operation(redo=True)
+
+    @bind('trash', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_trash(event):
+        """Switch the sidebar between the trash and the other notes"""
+        ui.toggle_trash()
+
+    @bind('restore', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_restore_note(event):
+        """Take the selected note out of the trash"""
+        selected_note = note_list_manager.selected_note
+        if selected_note:
+            ui.restore_note(selected_note.id)
+
+    @bind('archive', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_archived(event):
+        """Archive the selected note, or move it back to the main list"""
+        selected_note = note_list_manager.selected_note
+        if note_list_manager.marked_ids:
+            ui.archive_marked_notes()
+        elif selected_note:
+            ui.set_note_archived(selected_note, not selected_note.is_archived)
+
+    @bind('show_archive', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_archive_view(event):
+        """Show or hide the archived notes"""
+        ui.toggle_archive()
+
+    @bind('pin', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_pin(event):
+        """Pin the selected note to the top of the list, or unpin it"""
+        selected_note = note_list_manager.selected_note
+        if selected_note:
+            ui.set_note_pinned(selected_note, not selected_note.pinned)
+
+    @bind('star', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_star(event):
+        """Star the selected note as a favorite, or unstar it"""
+        selected_note = note_list_manager.selected_note
+        if selected_note:
+            ui.set_note_starred(selected_note, not selected_note.starred)
+
+    @bind('show_starred', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_starred_view(event):
+        """List only the starred notes, or every note again"""
+        ui.toggle_starred()
+
+    @bind('cycle_color', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_cycle_color(event):
+        """Give the selected note the next color label"""
+        selected_note = note_list_manager.selected_note
+        if selected_note:
+            ui.cycle_note_color(selected_note)
+
+    @bind('cycle_color_filter', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_cycle_color_filter(event):
+        """List only the notes with the next color label in use, then every note again"""
+        ui.cycle_color_filter()
+
+    @bind('move_note_up', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_move_note_up(event):
+        """Move the selected note up (manual sort order)"""
+        ui.move_selected_note(-1)
+
+    @bind('move_note_down', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_move_note_down(event):
+        """Move the selected note down (manual sort order)"""
+        ui.move_selected_note(1)
+
+    @bind('cycle_tag', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_cycle_tag_filter(event):
+        """Cycle the sidebar through notes with each tag, then all notes"""
+        tag = note_list_manager.cycle_tag_filter()
+        if tag:
+            mode_manager.set_message(t("msg.tag_filter", tag=tag, count=len(note_list_manager.notes)))
         else:
-            # First 'd' pressed
-            mode_manager.add_to_command_buffer('d')
+            mode_manager.set_message(t("msg.tag_filter_cleared"))
+
+    @bind('cycle_sort', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_cycle_sort(event):
+        """Sort the list by the next order, keeping the selected note selected"""
+        order = note_list_manager.cycle_sort_order()
+        mode_manager.set_message(t("msg.sort_order", order=t(f"sort.{order}")))
+
+    @bind('toggle_notebook', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_toggle_notebook(event):
+        """Expand or collapse the selected notebook, or the notebook holding the selected note"""
+        selected_notebook = note_list_manager.selected_notebook
+        if selected_notebook:
+            note_list_manager.toggle_notebook(selected_notebook.path)
+        elif note_list_manager.selected_note:
+            path = get_note_notebook(note_list_manager.selected_note)
+            if path and note_list_manager.selected_note is not note_list_manager.in_memory_note:
+                note_list_manager.toggle_notebook(path)
+                note_list_manager.select_notebook(path)
+        mode_manager.clear_command_buffer()
+
+    @bind('collapse_all', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_collapse_all(event):
+        """Collapse all notebooks"""
+        note_list_manager.collapse_all()
+        mode_manager.clear_command_buffer()
+
+    @bind('expand_all', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_expand_all(event):
+        """Expand all notebooks"""
+        note_list_manager.expand_all()
+        mode_manager.clear_command_buffer()
+
+    @bind('history', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_show_history(event):
+        """Show the version history of the selected note"""
+        selected_note = note_list_manager.selected_note
+        if selected_note:
+            ui.open_history(selected_note)
+
+    @bind('tasks', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_show_tasks(event):
+        """Show the open tasks of every note"""
+        ui.open_tasks()
+
+    @bind('outline', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_show_outline(event):
+        """List the selected note's headings"""
+        ui.open_outline()
+
+    @bind('external_editor', filter=is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def edit_in_external_editor(event):
+        """Edit the selected (sidebar) or loaded (editor) note in $EDITOR"""
+        ui.edit_in_external_editor()
+        mode_manager.clear_command_buffer()
+
+    @bind('copy_note', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_copy_note(event):
+        """Copy the selected note's markdown to the system clipboard"""
+        ui.copy_selected_note()
+
+    @bind('copy_note_text', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def sidebar_copy_note_text(event):
+        """Copy the selected note's text, without markdown, to the system clipboard"""
+        ui.copy_selected_note(rendered=True)
 
     # ===== EDITOR NORMAL MODE BINDINGS (ONLY WHEN EDITOR FOCUSED) =====
 
-    @kb.add('h', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    @kb.add('left', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('follow_link', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def follow_link(event):
+        """Show the image or open the note linked under the cursor"""
+        ui.follow_link()
+        mode_manager.clear_command_buffer()
+
+    @bind('open_url', filter=is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def open_url(event):
+        """Open a web link of the selected (sidebar) or loaded (editor) note, asking which if there are several"""
+        ui.open_note_url()
+        mode_manager.clear_command_buffer()
+
+    @bind('toggle_checkbox', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def toggle_checkbox(event):
+        """Check or uncheck the task on the cursor line"""
+        ui.toggle_checkbox()
+        mode_manager.clear_command_buffer()
+
+    @bind('spell_suggest', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def spell_suggest(event):
+        """Offer replacements for the misspelled word under the cursor"""
+        ui.suggest_spelling()
+        mode_manager.clear_command_buffer()
+
+    @bind('spell_add', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def spell_add(event):
+        """Add the word under the cursor to the personal dictionary"""
+        ui.add_word_under_cursor()
+        mode_manager.clear_command_buffer()
+
+    @bind('toggle_wrap', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def toggle_wrap(event):
+        """Wrap long lines or scroll them sideways"""
+        ui.toggle_wrap()
+        mode_manager.clear_command_buffer()
+
+    @bind('spell_next', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def spell_next(event):
+        """Move to the next misspelled word"""
+        ui.move_to_misspelled(1)
+        mode_manager.clear_command_buffer()
+
+    @bind('spell_previous', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def spell_previous(event):
+        """Move to the previous misspelled word"""
+        ui.move_to_misspelled(-1)
+        mode_manager.clear_command_buffer()
+
+    @bind('left', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_left(event):
         """Move cursor left in normal mode"""
         buffer.move_cursor_left()
         mode_manager.clear_command_buffer()
 
-    @kb.add('j', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    @kb.add('down', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('down', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_down(event):
         """Move cursor down in normal mode"""
         buffer.move_cursor_down(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('k', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    @kb.add('up', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('up', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_up(event):
         """Move cursor up in normal mode"""
         buffer.move_cursor_up(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('l', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    @kb.add('right', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('right', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_right(event):
         """Move cursor right in normal mode"""
         buffer.move_cursor_right()
         mode_manager.clear_command_buffer()
 
-    @kb.add('0', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('line_start', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_line_start(event):
         """Move to start of line"""
         buffer.move_cursor_to_line_start()
         mode_manager.clear_command_buffer()
 
-    @kb.add('$', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('line_end', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def move_line_end(event):
         """Move to end of line"""
         buffer.move_cursor_to_line_end()
         mode_manager.clear_command_buffer()
 
-    @kb.add('home', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    def move_line_start_home(event):
-        """Move to start of line with Home key"""
-        buffer.move_cursor_to_line_start()
+    @bind('word_forward', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def move_word_forward(event):
+        """Move to start of next word in normal mode"""
+        buffer.move_word_forward(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('end', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    def move_line_end_end(event):
-        """Move to end of line with End key"""
-        buffer.move_cursor_to_line_end()
+    @bind('word_backward', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def move_word_backward(event):
+        """Move to start of previous word in normal mode"""
+        buffer.move_word_backward(ui.editor_window_height)
+        mode_manager.clear_command_buffer()
+
+    @bind('word_end', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def move_word_end(event):
+        """Move to end of word in normal mode"""
+        buffer.move_word_end(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('c-d', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('half_page_down', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def half_page_down(event):
         """Scroll down half a page"""
         buffer.half_page_down(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('c-u', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('half_page_up', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def half_page_up(event):
         """Scroll up half a page"""
         buffer.half_page_up(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('pagedown', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('page_down', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def page_down_key(event):
         """Scroll down one page"""
         buffer.page_down(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('pageup', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('page_up', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def page_up_key(event):
         """Scroll up one page"""
         buffer.page_up(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
-    @kb.add('i', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('i', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def enter_insert_mode(event):
         """Enter insert mode"""
         mode_manager.enter_insert_mode()
         mode_manager.clear_command_buffer()
 
-    @kb.add('a', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('a', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def append_mode(event):
         """Enter insert mode after cursor"""
         buffer.move_cursor_right()
         mode_manager.enter_insert_mode()
         mode_manager.clear_command_buffer()
 
-    @kb.add('o', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('o', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def open_line_below(event):
         """Open new line below and enter insert mode"""
         buffer.insert_line_below(ui.editor_window_height)
         mode_manager.enter_insert_mode()
         mode_manager.clear_command_buffer()
 
-    @kb.add('O', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('O', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def open_line_above(event):
         """Open new line above and enter insert mode"""
         buffer.insert_line_above(ui.editor_window_height)
         mode_manager.enter_insert_mode()
         mode_manager.clear_command_buffer()
 
-    @kb.add('x', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
-    @kb.add('delete', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('x', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
+    @kb.add('delete', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def delete_char(event):
         """Delete character under cursor"""
         buffer.delete_char_at_cursor()
         mode_manager.clear_command_buffer()
 
-    @kb.add('p', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('p', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def paste_after(event):
-        """Paste from yank register after cursor/line"""
+        """Paste from the system clipboard or yank register after cursor/line"""
+        ui.load_clipboard()
         if buffer.yank_register:
             buffer.paste_from_register(after=True, visible_height=ui.editor_window_height)
             mode_manager.clear_message()
         else:
-            mode_manager.set_message("Nothing in register to paste")
+            mode_manager.set_message(t("msg.nothing_to_paste"))
         mode_manager.clear_command_buffer()
 
-    @kb.add('P', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('P', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
     def paste_before(event):
-        """Paste from yank register before cursor/line"""
+        """Paste from the system clipboard or yank register before cursor/line"""
+        ui.load_clipboard()
         if buffer.yank_register:
             buffer.paste_from_register(after=False, visible_height=ui.editor_window_height)
             mode_manager.clear_message()
         else:
-            mode_manager.set_message("Nothing in register to paste")
+            mode_manager.set_message(t("msg.nothing_to_paste"))
         mode_manager.clear_command_buffer()
 
     @kb.add('u', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
@@ -226,7 +486,7 @@ def create_key_bindings(
         if buffer.undo(ui.editor_window_height):
             mode_manager.clear_message()
         else:
-            mode_manager.set_message("Already at oldest change")
+            mode_manager.set_message(t("msg.oldest_change"))
         mode_manager.clear_command_buffer()
 
     @kb.add('c-r', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
@@ -235,7 +495,7 @@ def create_key_bindings(
         if buffer.redo(ui.editor_window_height):
             mode_manager.clear_message()
         else:
-            mode_manager.set_message("Already at newest change")
+            mode_manager.set_message(t("msg.newest_change"))
         mode_manager.clear_command_buffer()
 
     @kb.add('g', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
@@ -249,7 +509,7 @@ def create_key_bindings(
             # First 'g' pressed
             mode_manager.add_to_command_buffer('g')
 
-    @kb.add('G', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('bottom', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def jump_to_bottom_key(event):
         """Jump to bottom of file (vim G)"""
         buffer.jump_to_bottom(ui.editor_window_height)
@@ -265,7 +525,81 @@ def create_key_bindings(
         """Enter visual line mode"""
         mode_manager.enter_visual_line_mode(buffer.cursor_row)
 
-    @kb.add('n', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    # Operators: d (delete), c (change) and y (yank) with a text object or
+    # w, or doubled for the whole line
+
+    @kb.add('d', 'd', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
+    def delete_current_line(event):
+        """Delete the current line into the yank register (vim dd)"""
+        buffer.delete_lines(buffer.cursor_row, buffer.cursor_row, ui.editor_window_height)
+        mode_manager.clear_command_buffer()
+
+    @kb.add('y', 'y', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @kb.add('Y', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def yank_current_line(event):
+        """Yank the current line (vim yy)"""
+        buffer.yank_lines(buffer.cursor_row, buffer.cursor_row)
+        mode_manager.set_message(t("msg.yanked_lines", count=1))
+        mode_manager.clear_command_buffer()
+
+    @kb.add('c', 'c', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)
+    def change_current_line(event):
+        """Clear the current line and enter insert mode (vim cc)"""
+        mode_manager.enter_insert_mode()
+        buffer.change_line(ui.editor_window_height)
+
+    def operate(operator: str, span):
+        """Delete, change or yank a column span of the current line, if there is one"""
+        if span is None:
+            mode_manager.clear_command_buffer()
+            return
+        start, end = span
+        if operator == 'y':
+            buffer.yank_selection(buffer.cursor_row, start, buffer.cursor_row, end)
+            buffer.cursor_col = start
+            mode_manager.clear_command_buffer()
+            return
+        if operator == 'c':
+            # Insert mode first, so the cursor may stay after the line's last character
+            mode_manager.enter_insert_mode()
+        buffer.delete_range(start, end, ui.editor_window_height)
+        mode_manager.clear_command_buffer()
+
+    def text_object(operator: str, around: bool):
+        """Handler for an operator on the word under the cursor (iw, or aw with around)"""
+        def handler(event):
+            operate(operator, buffer.word_object(around))
+        handler.__doc__ = f"Apply {operator} to the word under the cursor"
+        return handler
+
+    def word_motion(operator: str):
+        """Handler for an operator from the cursor to the next word (w)"""
+        def handler(event):
+            end = buffer.word_motion_end(change=operator == 'c')
+            operate(operator, None if end is None else (min(buffer.cursor_col, end), end))
+        handler.__doc__ = f"Apply {operator} from the cursor to the next word"
+        return handler
+
+    def line_end_motion(operator: str):
+        """Handler for an operator from the cursor to the end of the line (D, C)"""
+        def handler(event):
+            line = buffer.current_line
+            operate(operator, (min(buffer.cursor_col, len(line) - 1), len(line) - 1) if line else None)
+        handler.__doc__ = f"Apply {operator} from the cursor to the end of the line"
+        return handler
+
+    for operator in ('d', 'c', 'y'):
+        # Yanking is the one operator that doesn't change the note
+        operator_filter = is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode
+        if operator != 'y':
+            operator_filter &= is_writable
+        kb.add(operator, 'i', 'w', filter=operator_filter)(text_object(operator, around=False))
+        kb.add(operator, 'a', 'w', filter=operator_filter)(text_object(operator, around=True))
+        kb.add(operator, 'w', filter=operator_filter)(word_motion(operator))
+    kb.add('D', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)(line_end_motion('d'))
+    kb.add('C', filter=is_editor_focused & is_normal_mode & is_writable & ~is_command_mode & ~is_search_mode)(line_end_motion('c'))
+
+    @bind('next_match', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def repeat_search(event):
         """Repeat last search in same direction in editor"""
         if mode_manager.last_search:
@@ -273,15 +607,16 @@ def create_key_bindings(
                 found = buffer.search_forward(mode_manager.last_search, ui.editor_window_height)
             else:
                 found = buffer.search_backward(mode_manager.last_search, ui.editor_window_height)
+            mode_manager.highlight_query = mode_manager.last_search
             if not found:
-                mode_manager.set_message(f"Pattern not found: {mode_manager.last_search}")
+                mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.last_search))
             else:
-                mode_manager.clear_message()
+                ui.show_search_match(mode_manager.last_search)
         else:
-            mode_manager.set_message("No previous search pattern")
+            mode_manager.set_message(t("msg.no_previous_search"))
         mode_manager.clear_command_buffer()
 
-    @kb.add('N', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('previous_match', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def repeat_search_opposite(event):
         """Repeat last search in opposite direction in editor"""
         if mode_manager.last_search:
@@ -289,15 +624,16 @@ def create_key_bindings(
                 found = buffer.search_backward(mode_manager.last_search, ui.editor_window_height)
             else:
                 found = buffer.search_forward(mode_manager.last_search, ui.editor_window_height)
+            mode_manager.highlight_query = mode_manager.last_search
             if not found:
-                mode_manager.set_message(f"Pattern not found: {mode_manager.last_search}")
+                mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.last_search))
             else:
-                mode_manager.clear_message()
+                ui.show_search_match(mode_manager.last_search)
         else:
-            mode_manager.set_message("No previous search pattern")
+            mode_manager.set_message(t("msg.no_previous_search"))
         mode_manager.clear_command_buffer()
 
-    @kb.add('n', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('next_match', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_search_next(event):
         """Jump to next note matching search in sidebar"""
         if mode_manager.last_search:
@@ -306,14 +642,14 @@ def create_key_bindings(
             else:
                 found = note_list_manager.search_previous()
             if not found:
-                mode_manager.set_message(f"Pattern not found: {mode_manager.last_search}")
+                mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.last_search))
             else:
                 mode_manager.clear_message()
         else:
-            mode_manager.set_message("No previous search pattern")
+            mode_manager.set_message(t("msg.no_previous_search"))
         mode_manager.clear_command_buffer()
 
-    @kb.add('N', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('previous_match', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_search_previous(event):
         """Jump to previous note matching search in sidebar"""
         if mode_manager.last_search:
@@ -322,11 +658,11 @@ def create_key_bindings(
             else:
                 found = note_list_manager.search_next()
             if not found:
-                mode_manager.set_message(f"Pattern not found: {mode_manager.last_search}")
+                mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.last_search))
             else:
                 mode_manager.clear_message()
         else:
-            mode_manager.set_message("No previous search pattern")
+            mode_manager.set_message(t("msg.no_previous_search"))
         mode_manager.clear_command_buffer()
 
     # ===== VISUAL MODE BINDINGS (EDITOR ONLY) =====
@@ -339,58 +675,67 @@ def create_key_bindings(
         buffer.clamp_cursor()
 
     # Visual mode movement (extends selection)
-    @kb.add('h', filter=is_editor_focused & is_visual_mode)
-    @kb.add('left', filter=is_editor_focused & is_visual_mode)
+    @bind('left', filter=is_editor_focused & is_visual_mode)
     def visual_move_left(event):
         """Move cursor left in visual mode"""
         buffer.move_cursor_left()
 
-    @kb.add('j', filter=is_editor_focused & is_visual_mode)
-    @kb.add('down', filter=is_editor_focused & is_visual_mode)
+    @bind('down', filter=is_editor_focused & is_visual_mode)
     def visual_move_down(event):
         """Move cursor down in visual mode"""
         buffer.move_cursor_down(ui.editor_window_height)
 
-    @kb.add('k', filter=is_editor_focused & is_visual_mode)
-    @kb.add('up', filter=is_editor_focused & is_visual_mode)
+    @bind('up', filter=is_editor_focused & is_visual_mode)
     def visual_move_up(event):
         """Move cursor up in visual mode"""
         buffer.move_cursor_up(ui.editor_window_height)
 
-    @kb.add('l', filter=is_editor_focused & is_visual_mode)
-    @kb.add('right', filter=is_editor_focused & is_visual_mode)
+    @bind('right', filter=is_editor_focused & is_visual_mode)
     def visual_move_right(event):
         """Move cursor right in visual mode"""
         buffer.move_cursor_right()
 
-    @kb.add('0', filter=is_editor_focused & is_visual_mode)
-    @kb.add('home', filter=is_editor_focused & is_visual_mode)
+    @bind('line_start', filter=is_editor_focused & is_visual_mode)
     def visual_move_line_start(event):
         """Move to start of line in visual mode"""
         buffer.move_cursor_to_line_start()
 
-    @kb.add('$', filter=is_editor_focused & is_visual_mode)
-    @kb.add('end', filter=is_editor_focused & is_visual_mode)
+    @bind('line_end', filter=is_editor_focused & is_visual_mode)
     def visual_move_line_end(event):
         """Move to end of line in visual mode"""
         buffer.move_cursor_to_line_end()
 
-    @kb.add('c-d', filter=is_editor_focused & is_visual_mode)
+    @bind('word_forward', filter=is_editor_focused & is_visual_mode)
+    def visual_move_word_forward(event):
+        """Move to start of next word in visual mode"""
+        buffer.move_word_forward(ui.editor_window_height)
+
+    @bind('word_backward', filter=is_editor_focused & is_visual_mode)
+    def visual_move_word_backward(event):
+        """Move to start of previous word in visual mode"""
+        buffer.move_word_backward(ui.editor_window_height)
+
+    @bind('word_end', filter=is_editor_focused & is_visual_mode)
+    def visual_move_word_end(event):
+        """Move to end of word in visual mode"""
+        buffer.move_word_end(ui.editor_window_height)
+
+    @bind('half_page_down', filter=is_editor_focused & is_visual_mode)
     def visual_half_page_down(event):
         """Scroll down half a page in visual mode"""
         buffer.half_page_down(ui.editor_window_height)
 
-    @kb.add('c-u', filter=is_editor_focused & is_visual_mode)
+    @bind('half_page_up', filter=is_editor_focused & is_visual_mode)
     def visual_half_page_up(event):
         """Scroll up half a page in visual mode"""
         buffer.half_page_up(ui.editor_window_height)
 
-    @kb.add('pagedown', filter=is_editor_focused & is_visual_mode)
+    @bind('page_down', filter=is_editor_focused & is_visual_mode)
     def visual_page_down(event):
         """Scroll down one page in visual mode"""
         buffer.page_down(ui.editor_window_height)
 
-    @kb.add('pageup', filter=is_editor_focused & is_visual_mode)
+    @bind('page_up', filter=is_editor_focused & is_visual_mode)
     def visual_page_up(event):
         """Scroll up one page in visual mode"""
         buffer.page_up(ui.editor_window_height)
@@ -406,15 +751,15 @@ def create_key_bindings(
             # First 'g' pressed
             mode_manager.add_to_command_buffer('g')
 
-    @kb.add('G', filter=is_editor_focused & is_visual_mode)
+    @bind('bottom', filter=is_editor_focused & is_visual_mode)
     def visual_jump_to_bottom(event):
         """Jump to bottom of file in visual mode"""
         buffer.jump_to_bottom(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
     # Visual mode operations
-    @kb.add('d', filter=is_editor_focused & is_visual_mode)
-    @kb.add('x', filter=is_editor_focused & is_visual_mode)
+    @kb.add('d', filter=is_editor_focused & is_visual_mode & is_writable)
+    @kb.add('x', filter=is_editor_focused & is_visual_mode & is_writable)
     def visual_delete(event):
         """Delete selected text and return to normal mode"""
         start_row, start_col, end_row, end_col = mode_manager.get_visual_selection(
@@ -434,9 +779,9 @@ def create_key_bindings(
         buffer.yank_selection(start_row, start_col, end_row, end_col)
         mode_manager.enter_normal_mode()
         buffer.clamp_cursor()
-        mode_manager.set_message(f"Yanked {end_row - start_row + 1} line(s)")
+        mode_manager.set_message(t("msg.yanked_lines", count=end_row - start_row + 1))
 
-    @kb.add('c', filter=is_editor_focused & is_visual_mode)
+    @kb.add('c', filter=is_editor_focused & is_visual_mode & is_writable)
     def visual_change(event):
         """Delete selected text and enter insert mode"""
         start_row, start_col, end_row, end_col = mode_manager.get_visual_selection(
@@ -456,34 +801,32 @@ def create_key_bindings(
         buffer.clamp_cursor()
 
     # Visual line mode movement (extends selection by lines)
-    @kb.add('j', filter=is_editor_focused & is_visual_line_mode)
-    @kb.add('down', filter=is_editor_focused & is_visual_line_mode)
+    @bind('down', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_move_down(event):
         """Move cursor down in visual line mode"""
         buffer.move_cursor_down(ui.editor_window_height)
 
-    @kb.add('k', filter=is_editor_focused & is_visual_line_mode)
-    @kb.add('up', filter=is_editor_focused & is_visual_line_mode)
+    @bind('up', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_move_up(event):
         """Move cursor up in visual line mode"""
         buffer.move_cursor_up(ui.editor_window_height)
 
-    @kb.add('c-d', filter=is_editor_focused & is_visual_line_mode)
+    @bind('half_page_down', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_half_page_down(event):
         """Scroll down half a page in visual line mode"""
         buffer.half_page_down(ui.editor_window_height)
 
-    @kb.add('c-u', filter=is_editor_focused & is_visual_line_mode)
+    @bind('half_page_up', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_half_page_up(event):
         """Scroll up half a page in visual line mode"""
         buffer.half_page_up(ui.editor_window_height)
 
-    @kb.add('pagedown', filter=is_editor_focused & is_visual_line_mode)
+    @bind('page_down', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_page_down(event):
         """Scroll down one page in visual line mode"""
         buffer.page_down(ui.editor_window_height)
 
-    @kb.add('pageup', filter=is_editor_focused & is_visual_line_mode)
+    @bind('page_up', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_page_up(event):
         """Scroll up one page in visual line mode"""
         buffer.page_up(ui.editor_window_height)
@@ -499,15 +842,15 @@ def create_key_bindings(
             # First 'g' pressed
             mode_manager.add_to_command_buffer('g')
 
-    @kb.add('G', filter=is_editor_focused & is_visual_line_mode)
+    @bind('bottom', filter=is_editor_focused & is_visual_line_mode)
     def visual_line_jump_to_bottom(event):
         """Jump to bottom of file in visual line mode"""
         buffer.jump_to_bottom(ui.editor_window_height)
         mode_manager.clear_command_buffer()
 
     # Visual line mode operations
-    @kb.add('d', filter=is_editor_focused & is_visual_line_mode)
-    @kb.add('x', filter=is_editor_focused & is_visual_line_mode)
+    @kb.add('d', filter=is_editor_focused & is_visual_line_mode & is_writable)
+    @kb.add('x', filter=is_editor_focused & is_visual_line_mode & is_writable)
     def visual_line_delete(event):
         """Delete selected lines and return to normal mode"""
         start_row, end_row = mode_manager.get_visual_line_selection(buffer.cursor_row)
@@ -523,9 +866,9 @@ def create_key_bindings(
         mode_manager.enter_normal_mode()
         buffer.clamp_cursor()
         num_lines = end_row - start_row + 1
-        mode_manager.set_message(f"Yanked {num_lines} line(s)")
+        mode_manager.set_message(t("msg.yanked_lines", count=num_lines))
 
-    @kb.add('c', filter=is_editor_focused & is_visual_line_mode)
+    @kb.add('c', filter=is_editor_focused & is_visual_line_mode & is_writable)
     def visual_line_change(event):
         """Delete selected lines and enter insert mode"""
         start_row, end_row = mode_manager.get_visual_line_selection(buffer.cursor_row)
@@ -566,49 +909,83 @@ def create_key_bindings(
 
     # ===== FOCUS SWITCHING (CTRL+W combinations in NORMAL MODE) =====
 
-    @kb.add('c-w', 'h', filter=is_normal_mode & ~is_any_visual_mode)
-    @kb.add('c-w', 'left', filter=is_normal_mode & ~is_any_visual_mode)
+    @bind('focus_sidebar', filter=is_normal_mode & ~is_any_visual_mode)
     def switch_to_sidebar(event):
         """Switch focus to sidebar"""
         focus_manager.switch_to_sidebar()
         mode_manager.clear_command_buffer()
 
-    @kb.add('c-w', 'l', filter=is_normal_mode & ~is_any_visual_mode)
-    @kb.add('c-w', 'right', filter=is_normal_mode & ~is_any_visual_mode)
+    @bind('focus_editor', filter=is_normal_mode & ~is_any_visual_mode)
     def switch_to_editor(event):
         """Switch focus to editor"""
-        focus_manager.switch_to_editor()
+        ui.focus_editor()
+        mode_manager.clear_command_buffer()
+
+    @bind('toggle_focus', filter=is_normal_mode & ~is_any_visual_mode & ~is_command_mode & ~is_search_mode)
+    def switch_pane(event):
+        """Give focus to the other pane"""
         mode_manager.clear_command_buffer()
+        ui.toggle_pane_focus()
+
+    @bind('toggle_zen', filter=is_normal_mode & ~is_any_visual_mode)
+    def toggle_zen(event):
+        """Enter or leave zen mode"""
+        mode_manager.clear_command_buffer()
+        ui.toggle_zen()
+
+    @bind('toggle_preview', filter=is_normal_mode & ~is_any_visual_mode)
+    def toggle_preview(event):
+        """Show or hide the live preview"""
+        mode_manager.clear_command_buffer()
+        ui.toggle_live_preview()
 
     # ===== COMMAND MODE (works in both sidebar and editor) =====
 
-    @kb.add(':', filter=is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('command', filter=is_normal_mode & ~is_command_mode & ~is_search_mode)
     def command_mode(event):
         """Enter command mode"""
         mode_manager.add_to_command_buffer(':')
 
+    # ===== QUICK SWITCHER =====
+
+    @bind('quick_switch', filter=~is_command_mode & ~is_search_mode)
+    def show_switcher(event):
+        """Jump to a recently viewed note by typing part of its title"""
+        ui.open_switcher()
+
+    # ===== HELP =====
+
+    # Bound before search_backward, so "?" still searches backward in the editor
+    @bind('help', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    def show_help(event):
+        """List every key binding"""
+        ui.open_help()
+
     # ===== SEARCH MODE (editor and sidebar) =====
 
-    @kb.add('/', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('search', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def search_forward_mode(event):
         """Enter forward search mode in editor"""
         mode_manager.start_search_forward()
 
-    @kb.add('?', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('search_backward', filter=is_editor_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def search_backward_mode(event):
         """Enter backward search mode in editor"""
         mode_manager.start_search_backward()
 
-    @kb.add('/', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('search', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_search_forward_mode(event):
         """Enter forward search mode in sidebar"""
         mode_manager.start_search_forward()
 
-    @kb.add('?', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
+    @bind('search_backward', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)
     def sidebar_search_backward_mode(event):
         """Enter backward search mode in sidebar"""
         mode_manager.start_search_backward()
 
+    # In the sidebar, where "/" filters the list the same way, "?" shows the help instead
+    bind('help', filter=is_sidebar_focused & is_normal_mode & ~is_command_mode & ~is_search_mode)(show_help)
+
     @kb.add('enter', filter=is_search_mode)
     def execute_search(event):
         """Execute the search when Enter is pressed"""
@@ -617,25 +994,35 @@ def create_key_bindings(
         # Perform the search
         if mode_manager.search_query:
             if focus_manager.is_sidebar_focused():
-                # Search across notes in sidebar
+                # Search across notes in sidebar, listing ranked results
                 found = note_list_manager.search_notes(mode_manager.search_query)
                 if not found:
-                    mode_manager.set_message(f"Pattern not found: {mode_manager.search_query}")
+                    mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.search_query))
                 else:
-                    mode_manager.clear_message()
+                    mode_manager.set_message(t(
+                        "msg.search_results",
+                        count=len(note_list_manager.search_results),
+                        query=mode_manager.search_query
+                    ))
             else:
                 # Search within current note in editor
                 if is_forward:
                     found = buffer.search_forward(mode_manager.search_query, ui.editor_window_height)
                 else:
                     found = buffer.search_backward(mode_manager.search_query, ui.editor_window_height)
+                mode_manager.highlight_query = mode_manager.search_query
                 if not found:
-                    mode_manager.set_message(f"Pattern not found: {mode_manager.search_query}")
+                    mode_manager.set_message(t("msg.pattern_not_found", pattern=mode_manager.search_query))
                 else:
-                    mode_manager.clear_message()
+                    ui.show_search_match(mode_manager.search_query)
         else:
             mode_manager.clear_message()
 
+    def update_sidebar_filter():
+        """Narrow the sidebar to notes matching the search typed so far"""
+        if focus_manager.is_sidebar_focused():
+            note_list_manager.filter_notes(mode_manager.command_buffer[1:])
+
     @kb.add('backspace', filter=is_search_mode)
     def search_backspace(event):
         """Remove last character from search buffer"""
@@ -644,11 +1031,14 @@ def create_key_bindings(
         else:
             # If only '/' or '?' left, exit search mode
             mode_manager.clear_command_buffer()
+        update_sidebar_filter()
 
     @kb.add('escape', filter=is_search_mode)
     def cancel_search(event):
-        """Cancel search mode with Escape"""
+        """Cancel search mode with Escape, showing the whole sidebar list again"""
         mode_manager.clear_command_buffer()
+        if focus_manager.is_sidebar_focused():
+            note_list_manager.clear_search()
 
     # When in search mode (after /), capture printable characters
     @kb.add('<any>', filter=is_search_mode)
@@ -656,19 +1046,23 @@ def create_key_bindings(
         """Add character to search buffer"""
         if len(event.data) == 1 and event.data.isprintable():
             mode_manager.add_to_command_buffer(event.data)
+            update_sidebar_filter()
 
     @kb.add('enter', filter=is_command_mode)
     def execute_command(event):
         """Execute the command when Enter is pressed"""
         command = mode_manager.command_buffer
+        # The command's name only; arguments may be note text or tokens
+        log_event(log, "command", command=command.split()[0] if command.strip() else "")
 
         if command == ':q':
             if buffer.is_dirty:
-                mode_manager.set_message("Unsaved changes! :w to save, :q! to quit without saving")
+                mode_manager.set_message(t("msg.unsaved_quit"))
                 mode_manager.clear_command_buffer()
             else:
                 event.app.exit()
         elif command == ':q!':
+            ui.discard_draft()
             event.app.exit()
         elif command == ':w':
             ui.save_current_note()
@@ -686,14 +1080,18 @@ def create_key_bindings(
                     # Load pending note, discarding current changes
                     ui.force_load_note(ui.pending_note_switch)
                     if focus_manager.is_sidebar_focused():
-                        focus_manager.switch_to_editor()
+                        ui.focus_editor()
             else:
-                mode_manager.set_message("No pending note to load")
+                mode_manager.set_message(t("msg.no_pending_note"))
             mode_manager.clear_command_buffer()
         elif command == ':new' or command == ':n':
             # Create a new empty note
             ui.create_new_note()
             mode_manager.clear_command_buffer()
+        elif command.startswith(':template ') or command == ':template':
+            # Create a note from a template ("meeting Weekly sync"), or pick one
+            ui.new_note_from_template(command[len(':template'):])
+            mode_manager.clear_command_buffer()
         elif command == ':delete' or command == ':d':
             # Delete current note with confirmation
             if buffer.current_note_id:
@@ -703,16 +1101,213 @@ def create_key_bindings(
                 else:
                     # Set pending deletion
                     ui.pending_deletion = buffer.current_note_id
-                    mode_manager.set_message("Delete note? :d again to confirm, :d! to force")
+                    if ui.is_note_trashed(buffer.current_note_id):
+                        mode_manager.set_message(t("msg.confirm_purge_cmd"))
+                    else:
+                        mode_manager.set_message(t("msg.confirm_delete_cmd"))
             else:
-                mode_manager.set_message("No note loaded")
+                mode_manager.set_message(t("msg.no_note_loaded"))
             mode_manager.clear_command_buffer()
         elif command == ':d!':
             # Force delete current note without confirmation
             if buffer.current_note_id:
                 ui.delete_note(buffer.current_note_id)
             else:
-                mode_manager.set_message("No note loaded")
+                mode_manager.set_message(t("msg.no_note_loaded"))
+            mode_manager.clear_command_buffer()
+        elif command == ':restore':
+            # Take the current note out of the trash
+            if buffer.current_note_id:
+                ui.restore_note(buffer.current_note_id)
+            else:
+                mode_manager.set_message(t("msg.no_note_loaded"))
+            mode_manager.clear_command_buffer()
+        elif command == ':trash':
+            # Switch the sidebar between the trash and the other notes
+            ui.toggle_trash()
+            mode_manager.clear_command_buffer()
+        elif command == ':emptytrash':
+            # Permanently delete trashed notes (confirmed in a dialog)
+            ui.empty_trash()
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':tag ') or command == ':tag':
+            # Add a tag to the current note, or to the notes marked in the sidebar
+            if focus_manager.is_sidebar_focused() and note_list_manager.marked_ids:
+                ui.tag_marked_notes(command[len(':tag'):])
+            else:
+                ui.tag_current_note(command[len(':tag'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':untag ') or command == ':untag':
+            # Remove a tag from the current note, or from the marked notes
+            if focus_manager.is_sidebar_focused() and note_list_manager.marked_ids:
+                ui.tag_marked_notes(command[len(':untag'):], remove=True)
+            else:
+                ui.tag_current_note(command[len(':untag'):], remove=True)
+            mode_manager.clear_command_buffer()
+        elif command == ':archive' or command == ':unarchive':
+            # Archive the current note, or move it back to the main list
+            ui.set_note_archived(ui.get_current_note(), command == ':archive')
+            mode_manager.clear_command_buffer()
+        elif command == ':split':
+            # Split the current note at its top-level headings, leaving an index of the parts
+            ui.split_note(ui.get_current_note())
+            mode_manager.clear_command_buffer()
+        elif command == ':archived':
+            # Show or hide the archived notes
+            ui.toggle_archive()
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':due ') or command == ':due':
+            # Set the note's due date, or clear it without an argument
+            ui.schedule_current_note("due", command[len(':due'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':remind ') or command == ':remind':
+            # Set a reminder on the note, or clear it without an argument
+            ui.schedule_current_note("remind", command[len(':remind'):])
+            mode_manager.clear_command_buffer()
+        elif command == ':pin' or command == ':unpin':
+            # Pin or unpin the current note
+            ui.set_note_pinned(ui.get_current_note(), command == ':pin')
+            mode_manager.clear_command_buffer()
+        elif command == ':star' or command == ':unstar':
+            # Star or unstar the current note
+            ui.set_note_starred(ui.get_current_note(), command == ':star')
+            mode_manager.clear_command_buffer()
+        elif command == ':starred':
+            # List only the starred notes, or every note again
+            ui.toggle_starred()
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':color ') or command == ':color':
+            # Label the current note with a color, or clear it with no argument
+            name = command[len(':color'):].strip()
+            color = parse_color(name) if name else None
+            if name and color is None:
+                mode_manager.set_message(t("msg.color_usage", colors=", ".join(t(f"color.{c}") for c in COLORS)))
+            else:
+                ui.set_note_color(ui.get_current_note(), color)
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':colorfilter ') or command == ':colorfilter':
+            # List only notes with a color label, or every note with no argument
+            name = command[len(':colorfilter'):].strip()
+            color = parse_color(name) if name else None
+            if name and color is None:
+                mode_manager.set_message(t("msg.color_usage", colors=", ".join(t(f"color.{c}") for c in COLORS)))
+            else:
+                ui.set_color_filter(color)
+            mode_manager.clear_command_buffer()
+        elif command == ':tags':
+            # List all tags in use
+            tags = ui.storage.list_tags()
+            if tags:
+                mode_manager.set_message(t("msg.tags_list", tags=" ".join(f"#{tag}" for tag in tags)))
+            else:
+                mode_manager.set_message(t("msg.no_tags"))
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':filter ') or command == ':filter':
+            # Filter sidebar by tag, or clear the filter with no argument
+            tag = command[len(':filter'):].strip()
+            note_list_manager.set_tag_filter(tag or None)
+            if note_list_manager.tag_filter:
+                mode_manager.set_message(t(
+                    "msg.tag_filter", tag=note_list_manager.tag_filter, count=len(note_list_manager.notes)
+                ))
+            else:
+                mode_manager.set_message(t("msg.tag_filter_cleared"))
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':notebook ') or command == ':notebook' or command.startswith(':nb ') or command == ':nb':
+            # Create a notebook (slash-separated path for nesting)
+            ui.create_notebook(command.split(' ', 1)[1] if ' ' in command else '')
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':move ') or command == ':move' or command.startswith(':mv ') or command == ':mv':
+            # Move current note into a notebook, or to the top level with no argument
+            ui.move_current_note(command.split(' ', 1)[1] if ' ' in command else '')
+            mode_manager.clear_command_buffer()
+        elif command == ':notebooks':
+            # List all notebooks
+            notebooks = ui.storage.list_notebooks()
+            if notebooks:
+                mode_manager.set_message(t("msg.notebooks_list", notebooks=", ".join(notebooks)))
+            else:
+                mode_manager.set_message(t("msg.no_notebooks"))
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':attach ') or command == ':attach':
+            # Attach a copy of a file to the current note
+            ui.attach_file(command[len(':attach'):])
+            mode_manager.clear_command_buffer()
+        elif command == ':attachments':
+            # List the files attached to the current note
+            ui.list_attachments()
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':open ') or command == ':open':
+            # Open an attachment (number or name) with the system's default program
+            ui.open_attachment(command[len(':open'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':detach ') or command == ':detach':
+            # Remove an attachment from the current note
+            ui.remove_attachment(command[len(':detach'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':image ') or command == ':image':
+            # Show an image linked from the current note (by position, default the first)
+            ui.show_image_number(command[len(':image'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':table ') or command == ':table':
+            # Align the table under the cursor or start one, or add a row or column
+            ui.table_command(command[len(':table'):])
+            mode_manager.clear_command_buffer()
+        elif command.startswith(':replaceall ') or command == ':replaceall':
+            # Replace text in every note, after reviewing the changes
+            ui.replace_all_notes(command[len(':replaceall'):])
+            mode_manager.clear_command_buffer()
+        elif command == ':history':
+            # Show the version history of the current note
+            ui.open_history(ui.get_current_note())
+            mode_manager.clear_command_buffer()
+        elif command == ':tasks':
+            # Show the open tasks of every note
+            ui.open_tasks()
+            mode_manager.clear_command_buffer()
+        elif command == ':noh' or command == ':nohlsearch':
+            # Stop highlighting the last search's matches (n/N bring them back)
+            mode_manager.highlight_query = ""
+            mode_manager.clear_command_buffer()
+        elif command == ':help' or command == ':h':
+            # List every key binding
+            mode_manager.clear_command_buffer()
+            ui.open_help()
+        elif command == ':tour':
+            # Show the first-run tour again
+            mode_manager.clear_command_buffer()
+            ui.open_tour()
+        elif command == ':outline' or command == ':toc':
+            # List the open note's headings to jump between sections
+            mode_manager.clear_command_buffer()
+            ui.open_outline()
+        elif command == ':share' or command.startswith(':share '):
+            # Upload the note to a gist or paste service
+            ui.share_selected_note(command[len(':share'):])
+            mode_manager.clear_command_buffer()
+        elif command in (':summarize', ':suggesttags', ':gentitle'):
+            # Ask the [ai] provider for a summary, tags or a title for the note
+            mode_manager.clear_command_buffer()
+            ui.assist({':summarize': "summarize", ':suggesttags': "tags", ':gentitle': "title"}[command])
+        elif command == ':lock':
+            # Hide the notes until the passphrase is typed
+            mode_manager.clear_command_buffer()
+            ui.lock_app()
+        elif command == ':secure' or command == ':unsecure':
+            # Encrypt the current note at rest, or store it as plain text again
+            mode_manager.clear_command_buffer()
+            ui.set_note_secure(ui.get_current_note(), command == ':secure')
+        elif command == ':unlock':
+            # Ask for the secure notes' passphrase
+            mode_manager.clear_command_buffer()
+            ui.unlock_secure_notes()
+        elif command == ':recover':
+            # Salvage what can be read of damaged note files
+            mode_manager.clear_command_buffer()
+            ui.recover_damaged_notes()
+        elif command == ':sync':
+            # Exchange changes with the sync server
+            ui.sync_notes()
             mode_manager.clear_command_buffer()
         elif command == ':sidebar' or command == ':sb':
             # Toggle sidebar visibility (only when editor is focused)
@@ -720,10 +1315,35 @@ def create_key_bindings(
                 focus_manager.toggle_sidebar()
                 mode_manager.clear_command_buffer()
             else:
-                mode_manager.set_message("Sidebar toggle only available when editor is focused")
+                mode_manager.set_message(t("msg.sidebar_toggle_editor_only"))
                 mode_manager.clear_command_buffer()
+        elif command == ':zen':
+            # Hide everything but the editor, or bring it back
+            mode_manager.clear_command_buffer()
+            ui.toggle_zen()
+        elif command == ':preview':
+            # Show or hide the note rendered next to the editor
+            mode_manager.clear_command_buffer()
+            ui.toggle_live_preview()
+        elif command == ':wrap':
+            # Wrap long lines in the editor, or scroll them sideways
+            mode_manager.clear_command_buffer()
+            ui.toggle_wrap()
+        elif command.startswith(':transform ') or command == ':transform':
+            # Rewrite the editor's text with a plugin's transform, or list them
+            ui.apply_transform(command[len(':transform'):])
+            mode_manager.clear_command_buffer()
+        elif command == ':plugins':
+            ui.show_plugins()
+            mode_manager.clear_command_buffer()
+        elif command[1:].split(' ', 1)[0] in ui.plugins.commands:
+            # A command added by a plugin, given the rest of the line
+            name, _, args = command[1:].partition(' ')
+            plugin_command = ui.plugins.commands[name]
+            ui.run_plugin(plugin_command.plugin, plugin_command.handler, args.strip())
+            mode_manager.clear_command_buffer()
         else:
-            mode_manager.set_message(f"Unknown command: {command}")
+            mode_manager.set_message(t("msg.unknown_command", command=command))
             mode_manager.clear_command_buffer()
 
     @kb.add('backspace', filter=is_command_mode)
@@ -751,15 +1371,21 @@ def create_key_bindings(
 
     @kb.add('escape', filter=is_editor_focused & is_insert_mode)
     def exit_insert_mode(event):
-        """Exit insert mode"""
+        """Exit insert mode (lining up the table the cursor is in), and without modal editing the editor too"""
+        ui.align_table()
         mode_manager.enter_normal_mode()
         # Clamp cursor to valid position for normal mode
         buffer.clamp_cursor()
+        if not ui.modal_editing:
+            focus_manager.switch_to_sidebar()
 
     @kb.add('enter', filter=is_editor_focused & is_insert_mode)
     def insert_newline(event):
-        """Insert new line in insert mode"""
-        buffer.insert_newline(ui.editor_window_height)
+        """Insert new line in insert mode, after expanding a snippet's abbreviation, continuing a list (or a table's next row)"""
+        if ui.expand_snippet():
+            return
+        if not ui.move_table_row() and not ui.continue_list():
+            buffer.insert_newline(ui.editor_window_height)
 
     @kb.add('backspace', filter=is_editor_focused & is_insert_mode)
     def backspace_char(event):
@@ -768,10 +1394,18 @@ def create_key_bindings(
 
     @kb.add('tab', filter=is_editor_focused & is_insert_mode)
     def insert_tab(event):
-        """Insert tab as 4 spaces in insert mode"""
+        """Expand a snippet's abbreviation, move to a table's next cell, indent a list item, or insert tab as 4 spaces"""
+        if ui.expand_snippet() is not None or ui.move_table_cell(1) or ui.indent_list_item():
+            return
         for _ in range(4):
             buffer.insert_char(' ')
 
+    @kb.add('s-tab', filter=is_editor_focused & is_insert_mode)
+    def insert_outdent(event):
+        """Move to a table's previous cell, or outdent the line (e.g. a list item) in insert mode"""
+        if not ui.move_table_cell(-1):
+            buffer.outdent_line(INDENT)
+
     # Arrow keys in insert mode
     @kb.add('left', filter=is_editor_focused & is_insert_mode)
     def insert_move_left(event):
@@ -803,6 +1437,21 @@ def create_key_bindings(
         """Move to end of line in insert mode"""
         buffer.move_cursor_to_line_end()
 
+    @kb.add('c-right', filter=is_editor_focused & is_insert_mode)
+    def insert_move_word_forward(event):
+        """Move to start of next word in insert mode"""
+        buffer.move_word_forward(ui.editor_window_height)
+
+    @kb.add('c-left', filter=is_editor_focused & is_insert_mode)
+    def insert_move_word_backward(event):
+        """Move to start of previous word in insert mode"""
+        buffer.move_word_backward(ui.editor_window_height)
+
+    @kb.add('c-w', filter=is_editor_focused & is_insert_mode)
+    def insert_delete_word(event):
+        """Delete the word before the cursor in insert mode"""
+        buffer.delete_word_before_cursor()
+
     @kb.add('delete', filter=is_editor_focused & is_insert_mode)
     def insert_delete_char(event):
         """Delete character under cursor in insert mode"""
@@ -821,10 +1470,17 @@ def create_key_bindings(
     # Catch all printable characters in insert mode
     @kb.add('<any>', filter=is_editor_focused & is_insert_mode)
     def insert_character(event):
-        """Insert any printable character in insert mode"""
+        """Insert any printable character in insert mode (a space after expanding a snippet's abbreviation)"""
         if len(event.data) == 1 and event.data.isprintable():
+            if event.data == ' ' and ui.expand_snippet():
+                return
             buffer.insert_char(event.data)
 
+    @bind('paste_clipboard', filter=is_editor_focused & is_insert_mode)
+    def insert_paste_clipboard(event):
+        """Paste the system clipboard at the cursor"""
+        ui.paste_clipboard()
+
     # ===== BRACKETED PASTE (NATIVE TERMINAL PASTE) =====
 
     @kb.add(Keys.BracketedPaste, filter=is_editor_focused & is_insert_mode)
@@ -845,16 +1501,397 @@ def create_key_bindings(
     # Additional normal mode bindings to clear command buffer on other keys
     @kb.add('escape', filter=is_normal_mode & ~is_command_mode)
     def clear_command(event):
-        """Clear command buffer and pending states in normal mode"""
+        """Clear command buffer, pending states, search highlights, and sidebar search results and marks"""
         mode_manager.clear_command_buffer()
         mode_manager.clear_message()
         ui.pending_deletion = None
+        if focus_manager.is_sidebar_focused():
+            note_list_manager.clear_search()
+            if note_list_manager.marked_ids:
+                ui.clear_marks()
+        else:
+            mode_manager.highlight_query = ""
+
+    # ===== HISTORY VIEWER =====
+
+    @bind('down', registry=history_kb)
+    def history_move_down(event):
+        """Select the next older revision"""
+        ui.history_view.move_selection_down()
+
+    @bind('up', registry=history_kb)
+    def history_move_up(event):
+        """Select the next newer revision"""
+        ui.history_view.move_selection_up()
+
+    @bind('half_page_down', registry=history_kb)
+    @bind('page_down', registry=history_kb)
+    def history_scroll_down(event):
+        """Scroll the diff down half a page"""
+        ui.history_view.scroll(ui.editor_window_height // 2, ui.editor_window_height)
+
+    @bind('half_page_up', registry=history_kb)
+    @bind('page_up', registry=history_kb)
+    def history_scroll_up(event):
+        """Scroll the diff up half a page"""
+        ui.history_view.scroll(-(ui.editor_window_height // 2), ui.editor_window_height)
+
+    @bind('open', registry=history_kb)
+    def history_restore(event):
+        """Roll the note back to the selected revision"""
+        ui.restore_selected_revision()
+
+    @history_kb.add('escape')
+    @history_kb.add('q')
+    @bind('history', registry=history_kb)
+    def history_close(event):
+        """Close the history viewer"""
+        ui.close_history()
+
+    # ===== TEMPLATE PICKER =====
+
+    @bind('down', registry=picker_kb)
+    def picker_move_down(event):
+        """Select the next template"""
+        ui.template_picker.move_selection_down()
+
+    @bind('up', registry=picker_kb)
+    def picker_move_up(event):
+        """Select the previous template"""
+        ui.template_picker.move_selection_up()
+
+    @bind('open', registry=picker_kb)
+    def picker_choose(event):
+        """Create a note from the selected template"""
+        ui.choose_template()
+
+    @picker_kb.add('escape')
+    @picker_kb.add('q')
+    @bind('new_from_template', registry=picker_kb)
+    def picker_close(event):
+        """Close the template picker"""
+        ui.close_template_picker()
+
+    # ===== REPLACEMENT REVIEW =====
+
+    @bind('down', registry=replace_kb)
+    def replace_move_down(event):
+        """Select the next note"""
+        ui.replace_view.move_selection_down()
+
+    @bind('up', registry=replace_kb)
+    def replace_move_up(event):
+        """Select the previous note"""
+        ui.replace_view.move_selection_up()
+
+    @bind('half_page_down', registry=replace_kb)
+    @bind('page_down', registry=replace_kb)
+    def replace_scroll_down(event):
+        """Scroll the changes down half a page"""
+        ui.replace_view.scroll(ui.editor_window_height // 2, ui.editor_window_height)
+
+    @bind('half_page_up', registry=replace_kb)
+    @bind('page_up', registry=replace_kb)
+    def replace_scroll_up(event):
+        """Scroll the changes up half a page"""
+        ui.replace_view.scroll(-(ui.editor_window_height // 2), ui.editor_window_height)
+
+    @bind('mark', registry=replace_kb)
+    def replace_toggle_note(event):
+        """Skip the selected note, or include it again"""
+        ui.replace_view.toggle_selected()
+
+    @bind('open', registry=replace_kb)
+    def replace_apply(event):
+        """Replace the text in the included notes"""
+        ui.apply_replacements()
+
+    @replace_kb.add('escape')
+    @replace_kb.add('q')
+    def replace_close(event):
+        """Close the review without changing any note"""
+        ui.close_replace_view()
+
+    # ===== TASKS VIEW =====
+
+    @bind('down', registry=tasks_kb)
+    def tasks_move_down(event):
+        """Select the next task"""
+        ui.tasks_view.move_selection_down()
+
+    @bind('up', registry=tasks_kb)
+    def tasks_move_up(event):
+        """Select the previous task"""
+        ui.tasks_view.move_selection_up()
+
+    @bind('mark', registry=tasks_kb)
+    def tasks_toggle(event):
+        """Check or uncheck the selected task in its note"""
+        ui.toggle_selected_task()
+
+    @bind('undo', registry=tasks_kb)
+    def tasks_undo(event):
+        """Undo the last checkbox change"""
+        ui.undo_task_toggle()
+
+    @bind('open', registry=tasks_kb)
+    def tasks_open_note(event):
+        """Open the selected task's note at the task"""
+        ui.open_selected_task()
+
+    @tasks_kb.add('tab')
+    def tasks_toggle_done(event):
+        """Show or hide checked tasks"""
+        ui.toggle_done_tasks()
+
+    @tasks_kb.add('escape')
+    @tasks_kb.add('q')
+    @bind('tasks', registry=tasks_kb)
+    def tasks_close(event):
+        """Close the tasks view"""
+        ui.close_tasks()
+
+    # ===== OUTLINE =====
+
+    @bind('down', registry=outline_kb)
+    def outline_move_down(event):
+        """Select the next heading"""
+        ui.outline_view.move_selection_down()
+        ui.show_selected_heading()
+
+    @bind('up', registry=outline_kb)
+    def outline_move_up(event):
+        """Select the previous heading"""
+        ui.outline_view.move_selection_up()
+        ui.show_selected_heading()
+
+    @bind('open', registry=outline_kb)
+    def outline_jump(event):
+        """Move the cursor to the selected heading"""
+        ui.close_outline(jump=True)
+
+    @outline_kb.add('escape')
+    @outline_kb.add('q')
+    @bind('outline', registry=outline_kb)
+    def outline_close(event):
+        """Close the outline, going back to where the cursor was"""
+        ui.close_outline()
+
+    # ===== HELP OVERLAY =====
+
+    @bind('down', registry=help_kb)
+    def help_scroll_down(event):
+        """Scroll the key list down a line"""
+        ui.help_view.scroll(1, ui.get_help_height())
+
+    @bind('up', registry=help_kb)
+    def help_scroll_up(event):
+        """Scroll the key list up a line"""
+        ui.help_view.scroll(-1, ui.get_help_height())
+
+    @bind('half_page_down', registry=help_kb)
+    @bind('page_down', registry=help_kb)
+    def help_page_down(event):
+        """Scroll the key list down a page"""
+        ui.help_view.scroll(ui.get_help_height(), ui.get_help_height())
+
+    @bind('half_page_up', registry=help_kb)
+    @bind('page_up', registry=help_kb)
+    def help_page_up(event):
+        """Scroll the key list up a page"""
+        ui.help_view.scroll(-ui.get_help_height(), ui.get_help_height())
+
+    @help_kb.add('escape')
+    @help_kb.add('q')
+    @bind('help', registry=help_kb)
+    def help_close(event):
+        """Close the help overlay"""
+        ui.help_view.close()
+
+    # ===== QUICK SWITCHER (open) =====
+
+    @switcher_kb.add('down')
+    @switcher_kb.add('c-n')
+    @bind('quick_switch', registry=switcher_kb)
+    def switcher_move_down(event):
+        """Select the next note (pressing the switcher's key again does too)"""
+        ui.switcher.move_selection_down()
+
+    @switcher_kb.add('up')
+    def switcher_move_up(event):
+        """Select the previous note"""
+        ui.switcher.move_selection_up()
+
+    @switcher_kb.add('c-m')
+    def switcher_open(event):
+        """Open the selected note"""
+        ui.open_switched_note()
+
+    @switcher_kb.add('escape')
+    def switcher_close(event):
+        """Close the switcher"""
+        ui.switcher.close()
+
+    @switcher_kb.add('backspace')
+    def switcher_backspace(event):
+        """Remove the last character typed"""
+        ui.switcher.backspace()
+
+    @switcher_kb.add(Keys.Any)
+    def switcher_type(event):
+        """Narrow the list to titles matching what is typed"""
+        if event.data.isprintable():
+            ui.switcher.type(event.data)
+
+    # ===== TOUR =====
+
+    @tour_kb.add('c-m')
+    @tour_kb.add('space')
+    @bind('right', registry=tour_kb)
+    def tour_next(event):
+        """Show the next step of the tour, closing it after the last"""
+        ui.tour.next()
+
+    @tour_kb.add('backspace')
+    @bind('left', registry=tour_kb)
+    def tour_previous(event):
+        """Show the previous step of the tour"""
+        ui.tour.previous()
+
+    @tour_kb.add('escape')
+    @tour_kb.add('q')
+    def tour_close(event):
+        """Skip the rest of the tour"""
+        ui.tour.close()
+
+    # ===== LOCK SCREEN =====
+
+    @lock_kb.add('c-m')
+    def lock_submit(event):
+        """Unlock if the typed passphrase is right"""
+        ui.app_lock.submit()
+
+    @lock_kb.add('backspace')
+    def lock_backspace(event):
+        """Remove the last character typed"""
+        ui.app_lock.backspace()
+
+    @lock_kb.add('c-u')
+    @lock_kb.add('escape')
+    def lock_clear(event):
+        """Remove everything typed"""
+        ui.app_lock.clear()
+
+    @lock_kb.add(Keys.Any)
+    def lock_type(event):
+        """Type the passphrase"""
+        if event.data.isprintable():
+            ui.app_lock.type(event.data)
+
+    # ===== SECURE NOTES' PASSPHRASE =====
+
+    @secure_kb.add('c-m')
+    def secure_submit(event):
+        """Unlock the secure notes if the typed passphrase is right"""
+        ui.secure_prompt.submit()
+
+    @secure_kb.add('backspace')
+    def secure_backspace(event):
+        """Remove the last character typed"""
+        ui.secure_prompt.backspace()
+
+    @secure_kb.add('c-u')
+    def secure_clear(event):
+        """Remove everything typed"""
+        ui.secure_prompt.clear()
+
+    @secure_kb.add('escape')
+    def secure_cancel(event):
+        """Leave the secure notes locked"""
+        ui.secure_prompt.close()
+
+    @secure_kb.add(Keys.Any)
+    def secure_type(event):
+        """Type the passphrase"""
+        if event.data.isprintable():
+            ui.secure_prompt.type(event.data)
+
+    # ===== CONFIRMATION DIALOG =====
+
+    @dialog_kb.add('y')
+    @dialog_kb.add('Y')
+    @dialog_kb.add('c-m')
+    def dialog_confirm(event):
+        """Run the action the dialog asks about"""
+        ui.confirm_dialog.confirm()
+
+    @dialog_kb.add('n')
+    @dialog_kb.add('N')
+    @dialog_kb.add('escape')
+    @dialog_kb.add('q')
+    def dialog_cancel(event):
+        """Close the dialog without running its action"""
+        ui.cancel_confirmation()
+
+    @dialog_kb.add(Keys.Any)
+    def dialog_pick(event):
+        """Run the answer with the pressed key, in a dialog with several answers"""
+        ui.confirm_dialog.pick(event.data)
 
     # Global bindings
-    @kb.add('c-c')
-    @kb.add('c-q')
+    @bind('quit')
+    @bind('quit', registry=history_kb)
+    @bind('quit', registry=picker_kb)
+    @bind('quit', registry=dialog_kb)
+    @bind('quit', registry=replace_kb)
+    @bind('quit', registry=tasks_kb)
+    @bind('quit', registry=outline_kb)
+    @bind('quit', registry=help_kb)
+    @bind('quit', registry=tour_kb)
+    @bind('quit', registry=switcher_kb)
+    @bind('quit', registry=secure_kb)
+    @bind('quit', registry=lock_kb)
     def force_quit(event):
-        """Force quit with Ctrl+C or Ctrl+Q"""
+        """Force quit (Ctrl+Q or Ctrl+C by default)"""
         event.app.exit()
 
-    return kb
+    def show_read_only(handler):
+        """Wrap a handler so a change refused by read-only storage is shown, not raised"""
+        @functools.wraps(handler)
+        def wrapper(event):
+            try:
+                return handler(event)
+            except ReadOnlyStorage as e:
+                mode_manager.clear_command_buffer()
+                mode_manager.set_message(str(e))
+        return wrapper
+
+    if ui.read_only:
+        # The keys that change notes are unbound or filtered out, but commands,
+        # plugins and the views can still ask storage for a change
+        for registry in (kb, history_kb, picker_kb, dialog_kb, replace_kb, tasks_kb, outline_kb, help_kb, tour_kb,
+                         switcher_kb, secure_kb):
+            for binding in registry.bindings:
+                binding.handler = show_read_only(binding.handler)
+
+    unlocked_kb = merge_key_bindings([
+        ConditionalKeyBindings(
+            kb, ~is_history_open & ~is_picker_open & ~is_dialog_open & ~is_replace_open & ~is_tasks_open &
+            ~is_outline_open & ~is_help_open & ~is_tour_open &
+            ~is_switcher_open & ~is_secure_prompt_open
+        ),
+        ConditionalKeyBindings(history_kb, is_history_open),
+        ConditionalKeyBindings(picker_kb, is_picker_open),
+        ConditionalKeyBindings(dialog_kb, is_dialog_open),
+        ConditionalKeyBindings(replace_kb, is_replace_open),
+        ConditionalKeyBindings(tasks_kb, is_tasks_open),
+        ConditionalKeyBindings(outline_kb, is_outline_open),
+        ConditionalKeyBindings(help_kb, is_help_open),
+        ConditionalKeyBindings(tour_kb, is_tour_open),
+        ConditionalKeyBindings(switcher_kb, is_switcher_open),
+        ConditionalKeyBindings(secure_kb, is_secure_prompt_open),
+    ])
+    return merge_key_bindings([
+        ConditionalKeyBindings(unlocked_kb, ~is_locked),
+        ConditionalKeyBindings(lock_kb, is_locked),
+    ])
@@ -0,0 +1,313 @@
+"""
+Remappable key bindings
+
+Each action has a list of key sequences that trigger it. Defaults can be
+overridden in the [keys] section of the config file:
+
+    [keys]
+    new_note = "n"
+    down = ["j", "down", "c-n"]
+    delete_note = "d d"
+
+A sequence is one or more prompt_toolkit key names separated by spaces
+("c-w h" is Ctrl+W followed by h). Status messages and help text ask the
+KeyMap for the current keys, so they stay accurate after remapping.
+"""
+
+from typing import Any, Dict, List, Optional, Tuple
+from prompt_toolkit.key_binding import KeyBindings
+from .i18n import t
+
+# Default key sequences for each action, in the order they're listed in help
+DEFAULT_KEYS: Dict[str, List[str]] = {
+    # Navigation (sidebar, editor and visual mode)
+    "up": ["k", "up"],
+    "down": ["j", "down"],
+    "left": ["h", "left"],
+    "right": ["l", "right"],
+    "word_forward": ["w"],
+    "word_backward": ["b"],
+    "word_end": ["e"],
+    "line_start": ["0", "home"],
+    "line_end": ["$", "end"],
+    "half_page_down": ["c-d"],
+    "half_page_up": ["c-u"],
+    "page_down": ["pagedown"],
+    "page_up": ["pageup"],
+    "bottom": ["G"],
+    "focus_sidebar": ["c-w h", "c-w left"],
+    "focus_editor": ["c-w l", "c-w right"],
+    "toggle_focus": ["tab"],
+    "toggle_zen": ["c-w o"],
+    "toggle_preview": ["c-w p"],
+    "quick_switch": ["c-p"],
+    "follow_link": ["enter"],
+    "open_url": ["U"],
+    "toggle_checkbox": ["space"],
+    "paste_clipboard": ["c-v"],
+    "spell_suggest": ["z ="],
+    "spell_add": ["z g"],
+    "spell_next": ["] s"],
+    "spell_previous": ["[ s"],
+    "toggle_wrap": ["z w"],
+
+    # Notes (sidebar)
+    "open": ["enter"],
+    "new_note": ["o"],
+    "new_from_template": ["T"],
+    "edit": ["i"],
+    "delete_note": ["d d"],
+    "mark": ["space"],
+    "undo": ["u"],
+    "redo": ["c-r"],
+    "history": ["h"],
+    "trash": ["t"],
+    "restore": ["r"],
+    "archive": ["a"],
+    "show_archive": ["A"],
+    "tasks": ["X"],
+    "outline": ["O"],
+    "pin": ["p"],
+    "star": ["*"],
+    "show_starred": ["S"],
+    "cycle_color": ["C"],
+    "cycle_color_filter": ["F"],
+    "move_note_up": ["K"],
+    "move_note_down": ["J"],
+    "cycle_tag": ["#"],
+    "cycle_sort": ["s"],
+    "toggle_notebook": ["z a"],
+    "collapse_all": ["z M"],
+    "expand_all": ["z R"],
+    "external_editor": ["E"],
+    "copy_note": ["y"],
+    "copy_note_text": ["Y"],
+
+    # Commands and search
+    "command": [":"],
+    "search": ["/"],
+    "search_backward": ["?"],
+    "next_match": ["n"],
+    "previous_match": ["N"],
+    "help": ["?", "f1"],
+    "quit": ["c-q", "c-c"],
+}
+
+# Sections of the help overlay, in order, and the actions listed in each;
+# actions not listed here (e.g. added later) go under "notes"
+HELP_SECTIONS = ("navigation", "editing", "notes", "search", "plugins")
+ACTION_SECTIONS: Dict[str, str] = {
+    **{action: "navigation" for action in (
+        "up", "down", "left", "right", "word_forward", "word_backward", "word_end", "line_start", "line_end",
+        "half_page_down", "half_page_up", "page_down", "page_up", "bottom", "focus_sidebar", "focus_editor",
+        "toggle_focus", "toggle_zen", "toggle_preview", "toggle_wrap", "quick_switch",
+    )},
+    **{action: "editing" for action in (
+        "follow_link", "open_url", "toggle_checkbox", "paste_clipboard", "spell_suggest", "spell_add", "spell_next",
+        "spell_previous", "external_editor",
+    )},
+    **{action: "search" for action in (
+        "command", "search", "search_backward", "next_match", "previous_match", "help", "quit",
+    )},
+}
+
+# Editor keys in normal mode that can't be remapped, listed in the help
+# overlay's editing section: (keys, locale key of the description)
+FIXED_EDITOR_KEYS: List[Tuple[str, str]] = [
+    ("i / a", "keys.fixed.insert"),
+    ("o / O", "keys.fixed.open_line"),
+    ("Esc", "keys.fixed.normal"),
+    ("x", "keys.fixed.delete_char"),
+    ("dd / yy / cc", "keys.fixed.line"),
+    ("dw / cw / yw, diw / ciw / yiw", "keys.fixed.word"),
+    ("D / C", "keys.fixed.line_end"),
+    ("p / P", "keys.fixed.paste"),
+    ("u / Ctrl+R", "keys.fixed.undo"),
+    ("v / V", "keys.fixed.visual"),
+    ("gg", "keys.fixed.top"),
+    ("zh / zl / zH / zL", "keys.fixed.scroll"),
+]
+
+# Actions and fixed editor keys (by description) that change notes; with
+# read-only storage the actions are unbound and neither is listed in help
+EDIT_ACTIONS = frozenset((
+    "toggle_checkbox", "paste_clipboard", "spell_suggest", "new_note", "new_from_template", "edit", "delete_note", "undo", "redo",
+    "trash", "restore", "archive", "pin", "star", "cycle_color", "move_note_up", "move_note_down", "external_editor",
+))
+FIXED_EDIT_KEYS = frozenset((
+    "keys.fixed.insert", "keys.fixed.open_line", "keys.fixed.delete_char", "keys.fixed.line", "keys.fixed.word",
+    "keys.fixed.line_end", "keys.fixed.paste", "keys.fixed.undo",
+))
+
+
+def format_key(key: str) -> str:
+    """
+    Format a prompt_toolkit key name for display
+
+    Args:
+        key: Key name (e.g. "c-w", "enter", "G")
+
+    Returns:
+        Display name (e.g. "Ctrl+W", "Enter", "G")
+    """
+    if len(key) == 1:
+        return key
+    if key.startswith("c-"):
+        return "Ctrl+" + key[2:].capitalize()
+    if key == "escape":
+        return "Esc"
+    return key.capitalize()
+
+
+def format_sequence(sequence: Tuple[str, ...]) -> str:
+    """
+    Format a key sequence for display, vim style
+
+    Sequences of plain characters are run together ("dd", "za"); others
+    are separated by spaces ("Ctrl+W h").
+
+    Args:
+        sequence: Key names
+
+    Returns:
+        Display text
+    """
+    if all(len(key) == 1 for key in sequence):
+        return "".join(sequence)
+    return " ".join(format_key(key) for key in sequence)
+
+
+class KeyMap:
+    """Key sequences for each remappable action"""
+
+    def __init__(self, overrides: Optional[Dict[str, Any]] = None,
+                 extra_actions: Optional[Dict[str, Tuple[List[str], str]]] = None, read_only: bool = False):
+        """
+        Initialize the key map from the defaults and user overrides
+
+        Invalid overrides are skipped, keeping the default, and described in
+        `errors` so they can be shown to the user. A key sequence assigned by
+        the user is removed from the defaults of other actions, so it isn't
+        bound twice.
+
+        Args:
+            overrides: Action name to a key sequence string or list of them
+            extra_actions: Actions added by plugins: name to (default key
+                sequences, description)
+            read_only: Leave EDIT_ACTIONS unbound, for read-only storage
+        """
+        self.errors: List[str] = []
+        self.read_only = read_only
+        self._defaults: Dict[str, List[str]] = dict(DEFAULT_KEYS)
+        self._descriptions: Dict[str, str] = {}
+        for action, (sequences, description) in (extra_actions or {}).items():
+            valid = [sequence for sequence in sequences if self._is_valid(tuple(sequence.split()))]
+            if len(valid) != len(sequences):
+                self.errors.append(t("plugin.invalid_keys", action=action, keys=", ".join(sequences)))
+            self._defaults[action] = valid
+            self._descriptions[action] = description
+        self._keys: Dict[str, List[Tuple[str, ...]]] = {
+            action: [tuple(sequence.split()) for sequence in sequences]
+            for action, sequences in self._defaults.items()
+        }
+
+        overridden = set()
+        for action, value in (overrides or {}).items():
+            if action not in self._defaults:
+                self.errors.append(t("keys.unknown_action", action=action))
+                continue
+
+            sequences = [value] if isinstance(value, str) else value
+            if not isinstance(sequences, list) or not all(isinstance(s, str) for s in sequences):
+                self.errors.append(t("keys.invalid", action=action, keys=value))
+                continue
+
+            parsed = [tuple(sequence.split()) for sequence in sequences if sequence.strip()]
+            invalid = [sequence for sequence in parsed if not self._is_valid(sequence)]
+            if invalid:
+                self.errors.append(t("keys.invalid", action=action, keys=" ".join(invalid[0])))
+                continue
+            self._keys[action] = parsed
+            overridden.add(action)
+
+        taken = {sequence for action in overridden for sequence in self._keys[action]}
+        for action in self._defaults:
+            if action not in overridden:
+                self._keys[action] = [sequence for sequence in self._keys[action] if sequence not in taken]
+        if read_only:
+            for action in EDIT_ACTIONS:
+                self._keys[action] = []
+
+    @staticmethod
+    def _is_valid(sequence: Tuple[str, ...]) -> bool:
+        """Check that prompt_toolkit understands every key in a sequence"""
+        try:
+            KeyBindings().add(*sequence)
+        except ValueError:
+            return False
+        return True
+
+    def sequences(self, action: str) -> List[Tuple[str, ...]]:
+        """
+        Get the key sequences bound to an action
+
+        Args:
+            action: Action name from DEFAULT_KEYS or a plugin
+
+        Returns:
+            Key sequences (empty if the user unbound the action)
+        """
+        return self._keys[action]
+
+    def label(self, action: str) -> str:
+        """
+        Get the display text of an action's first key sequence, for messages
+
+        Args:
+            action: Action name from DEFAULT_KEYS
+
+        Returns:
+            Display text, or "" if the action is unbound
+        """
+        sequences = self._keys[action]
+        return format_sequence(sequences[0]) if sequences else ""
+
+    def labels(self, action: str) -> str:
+        """
+        Get the display text of all of an action's key sequences, for help
+
+        Args:
+            action: Action name from DEFAULT_KEYS
+
+        Returns:
+            Display text of each sequence separated by " / "
+        """
+        return " / ".join(format_sequence(sequence) for sequence in self._keys[action])
+
+    def help_lines(self) -> List[Tuple[str, str, str]]:
+        """
+        Get help text for every action
+
+        Returns:
+            List of (action, keys, description) in DEFAULT_KEYS order, then plugin actions
+        """
+        return [(action, self.labels(action), self._descriptions.get(action) or t(f"keys.{action}"))
+                for action in self._defaults]
+
+    def help_sections(self) -> List[Tuple[str, List[Tuple[str, str]]]]:
+        """
+        Get the help overlay's contents, from the current keys
+
+        Returns:
+            List of (section title, [(keys, description)]) in HELP_SECTIONS
+            order, leaving out empty sections and unbound actions
+        """
+        sections: Dict[str, List[Tuple[str, str]]] = {section: [] for section in HELP_SECTIONS}
+        for action, keys, description in self.help_lines():
+            if not keys:
+                continue
+            section = "plugins" if action in self._descriptions else ACTION_SECTIONS.get(action, "notes")
+            sections[section].append((keys, description))
+        sections["editing"].extend((keys, t(description)) for keys, description in FIXED_EDITOR_KEYS
+                                   if not (self.read_only and description in FIXED_EDIT_KEYS))
+        return [(t(f"help.{section}"), entries) for section, entries in sections.items() if entries]
@@ -0,0 +1,149 @@
+"""
+Wiki-style links between notes, and web links in them
+
+A note links to another by its title in double brackets: "[[Meeting notes]]".
+A heading after "#" and a label after "|" are allowed, as in Obsidian
+("[[Meeting notes#Actions|the actions]]"), but only the title is used to find
+the note. Titles match case-insensitively, ignoring repeated spaces.
+
+Web links are bare URLs ("https://example.com", "www.example.com") and
+markdown links to them ("[the docs](https://example.com/docs)").
+"""
+
+import re
+from typing import Iterable, List, Optional
+from .note import Note
+
+WIKI_LINK = re.compile(r'\[\[([^\[\]|#\n]+)(?:#[^\[\]|\n]*)?(?:\|[^\[\]\n]*)?\]\]')
+URL = re.compile(r'\b(?:https?://|www\.)[^\s<>"`]+', re.IGNORECASE)
+MARKDOWN_LINK = re.compile(r'\[[^\]\n]*\]\(<?((?:https?://|www\.)[^\s)>]+)>?(?:\s+"[^"\n]*")?\)', re.IGNORECASE)
+
+# Punctuation ending a sentence after a URL rather than belonging to it
+URL_TRAILING = ".,;:!?'\"*_~"
+
+
+def normalize_link(title: str) -> str:
+    """
+    Normalize a title for matching links against note titles
+
+    Args:
+        title: Link target or note title
+
+    Returns:
+        Casefolded title with runs of whitespace collapsed
+    """
+    return " ".join(title.split()).casefold()
+
+
+def link_targets(content: str) -> List[str]:
+    """
+    Get the normalized titles a note's content links to
+
+    Args:
+        content: Note content
+
+    Returns:
+        Distinct link targets in the order they first appear
+    """
+    targets = []
+    for match in WIKI_LINK.finditer(content):
+        target = normalize_link(match.group(1))
+        if target and target not in targets:
+            targets.append(target)
+    return targets
+
+
+def link_at(line: str, col: int) -> Optional[str]:
+    """
+    Get the title of the link under a cursor position
+
+    Args:
+        line: Line of text
+        col: Cursor column
+
+    Returns:
+        The link's title as written, or None if the cursor isn't on a link
+    """
+    for match in WIKI_LINK.finditer(line):
+        if match.start() <= col < match.end():
+            return match.group(1).strip()
+    return None
+
+
+def _trim_url(url: str) -> str:
+    """Take trailing punctuation off a URL found in text, and closing brackets it didn't open"""
+    while url:
+        if url[-1] in URL_TRAILING:
+            url = url[:-1]
+        elif url[-1] == ")" and url.count(")") > url.count("("):
+            url = url[:-1]
+        elif url[-1] == "]" and url.count("]") > url.count("["):
+            url = url[:-1]
+        else:
+            break
+    return url
+
+
+def _openable(url: str) -> str:
+    """Add the scheme a "www." URL leaves out"""
+    return url if "://" in url else f"https://{url}"
+
+
+def find_urls(content: str) -> List[str]:
+    """
+    Get the web addresses a note links to
+
+    Args:
+        content: Note content
+
+    Returns:
+        Distinct URLs in the order they first appear, with "https://" added to "www." ones
+    """
+    urls = []
+    for match in URL.finditer(content):
+        url = _openable(_trim_url(match.group()))
+        if url not in urls:
+            urls.append(url)
+    return urls
+
+
+def url_at(line: str, col: int) -> Optional[str]:
+    """
+    Get the web address linked under a cursor position
+
+    Anywhere on a markdown link counts, its text included.
+
+    Args:
+        line: Line of text
+        col: Cursor column
+
+    Returns:
+        The URL, with "https://" added to a "www." one, or None if the cursor isn't on a web link
+    """
+    for match in MARKDOWN_LINK.finditer(line):
+        if match.start() <= col < match.end():
+            return _openable(match.group(1))
+    for match in URL.finditer(line):
+        url = _trim_url(match.group())
+        if match.start() <= col < match.start() + len(url):
+            return _openable(url)
+    return None
+
+
+def find_linked_note(notes: Iterable[Note], title: str) -> Optional[Note]:
+    """
+    Find the note a link points to
+
+    A note outside the trash wins over a trashed one with the same title.
+
+    Args:
+        notes: Notes to look through
+        title: Link title
+
+    Returns:
+        The linked note, or None if no note has that title
+    """
+    target = normalize_link(title)
+    matches = [note for note in notes if normalize_link(note.title) == target]
+    matches.sort(key=lambda note: note.is_trashed)
+    return matches[0] if matches else None
@@ -0,0 +1,61 @@
+"""
+Markdown lists in the editor: continuing them and indenting their items
+
+Enter in insert mode after the marker of a list item ("- ", "* ", "1. ",
+"2) ", with or without a task box) starts the next item: the same bullet,
+the next number, an empty task box. Enter on an item with nothing after its
+marker ends the list instead, or outdents the item if it's nested. Tab and
+Shift+Tab indent and outdent a list item by INDENT spaces.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Optional
+from .preview import RULE
+
+LIST_ITEM = re.compile(r'^(\s*)(?:([-*+])|(\d{1,9})([.)]))( +)(\[[ xX]\](?: +|$))?')
+
+# Spaces a list item is indented or outdented by
+INDENT = 4
+
+
+@dataclass
+class ListItem:
+    """The marker at the start of a line of a list"""
+    indent: str
+    bullet: str  # "-", "*" or "+" ("" for a numbered item)
+    number: Optional[int]  # Number of a numbered item
+    delimiter: str  # "." or ")" after the number
+    spacing: str  # Spaces after the marker
+    task: bool  # Whether it has a task box
+    end: int  # Index where the item's text starts
+
+    def next_marker(self) -> str:
+        """Get the marker of the item after this one: same bullet or the next number, and an empty task box"""
+        marker = self.bullet or f"{self.number + 1}{self.delimiter}"
+        return self.indent + marker + self.spacing + ("[ ] " if self.task else "")
+
+
+def parse_list_item(line: str) -> Optional[ListItem]:
+    """
+    Find the list marker a line starts with
+
+    Args:
+        line: Line of a note
+
+    Returns:
+        The item, or None if the line isn't one (a rule like "* * *" isn't)
+    """
+    match = LIST_ITEM.match(line)
+    if match is None or RULE.match(line):
+        return None
+    indent, bullet, number, delimiter, spacing, task = match.groups()
+    return ListItem(
+        indent=indent,
+        bullet=bullet or "",
+        number=int(number) if number else None,
+        delimiter=delimiter or "",
+        spacing=spacing,
+        task=task is not None,
+        end=match.end(),
+    )
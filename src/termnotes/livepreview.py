@@ -0,0 +1,273 @@
+"""
+Live preview of the note being edited
+
+With live preview on (:preview, Ctrl+W p or [ui] live_preview), a pane to
+the right of the editor shows the note rendered while the editor has focus:
+headings without their marks, emphasis and code styled instead of marked up,
+links by their text, bullets, task boxes, quotes, rules and tables drawn, and
+long lines wrapped to the pane. It scrolls along with the editor.
+
+Rendering waits until typing pauses for DELAY seconds, so long notes don't
+slow typing down; until then the pane shows the note as it was.
+"""
+
+import re
+import time
+from typing import Callable, List, Optional, Tuple
+from prompt_toolkit.formatted_text import StyleAndTextTuples
+from .lists import parse_list_item
+from .preview import FENCE, HEADING, HTML_TAG, QUOTE, RULE
+from .tables import find_pipes, find_table, format_table
+from .textwidth import text_width
+
+# Seconds typing has to pause for before the preview catches up
+DELAY = 0.3
+
+# Columns each of the editor and the preview needs for the preview to show
+MIN_WIDTH = 20
+
+INLINE = re.compile(
+    r'(?P<code>`+)(?P<code_text>.+?)(?P=code)'
+    r'|!\[\[(?P<embed>[^\[\]\n]+)\]\]'
+    r'|!\[(?P<alt>[^\]]*)\]\([^)]*\)'
+    r'|\[\[(?P<wiki>[^\[\]|#\n]+)(?:#[^\[\]|\n]*)?(?:\|(?P<wiki_text>[^\[\]\n]*))?\]\]'
+    r'|\[(?P<link>[^\]]+)\]\([^)]*\)'
+    r'|(?P<strong>\*\*\*|___|\*\*|__|~~|\*|_)(?=\S)(?P<strong_text>.+?)(?<=\S)(?P=strong)'
+    r'|' + HTML_TAG.pattern
+)
+DONE_TASK = re.compile(r'^\s*(?:[-*+]|\d+[.)]) +\[[xX]\]')
+EMPHASIS_STYLES = {
+    '***': 'class:emphasis bold italic', '___': 'class:emphasis bold italic',
+    '**': 'class:emphasis bold', '__': 'class:emphasis bold',
+    '*': 'class:emphasis italic', '_': 'class:emphasis italic',
+    '~~': 'strike',
+}
+
+# A rendered line: the line of the note it comes from, and its text
+PreviewLine = Tuple[int, StyleAndTextTuples]
+
+
+def render_inline(text: str, style: str = '') -> StyleAndTextTuples:
+    """
+    Style a line's inline markdown, leaving out the markup
+
+    Args:
+        text: Text of a line, after its heading, list or quote marker
+        style: Style of the text around the markup
+
+    Returns:
+        List of (style, text) fragments
+    """
+    fragments = []
+    pos = 0
+    for match in INLINE.finditer(text):
+        if match.start() > pos:
+            fragments.append((style, text[pos:match.start()]))
+        pos = match.end()
+        if match.group('code'):
+            fragments.append(('class:code', match.group('code_text')))
+        elif match.group('embed'):
+            fragments.append(('class:image', match.group('embed')))
+        elif match.group('alt') is not None:
+            fragments.append(('class:image', match.group('alt') or "🖼"))
+        elif match.group('wiki'):
+            fragments.append(('class:link', match.group('wiki_text') or match.group('wiki')))
+        elif match.group('link'):
+            fragments.extend(render_inline(match.group('link'), 'class:link'))
+        elif match.group('strong'):
+            inner = f"{style} {EMPHASIS_STYLES[match.group('strong')]}".strip()
+            fragments.extend(render_inline(match.group('strong_text'), inner))
+        # HTML tags are left out
+    if pos < len(text):
+        fragments.append((style, text[pos:]))
+    return fragments
+
+
+def wrap(fragments: StyleAndTextTuples, width: int, first: StyleAndTextTuples = (),
+         rest: StyleAndTextTuples = ()) -> List[StyleAndTextTuples]:
+    """
+    Wrap styled text to a width at spaces
+
+    Args:
+        fragments: Text to wrap
+        width: Columns to fit in
+        first: Prefix of the first line, like a list bullet
+        rest: Prefix of the lines after it, like the bullet's indent
+
+    Returns:
+        The lines; a word wider than a line is broken up
+    """
+    lines = []
+    line, used, empty = list(first), sum(text_width(text) for _, text in first), True
+    rest_width = sum(text_width(text) for _, text in rest)
+
+    def break_line():
+        nonlocal line, used, empty
+        lines.append(line)
+        line, used, empty = list(rest), rest_width, True
+
+    for style, text in fragments:
+        for chunk in re.findall(r'\s+|\S+', text):
+            chunk_width = text_width(chunk)
+            if chunk.isspace():
+                if used + chunk_width <= width:
+                    line.append((style, chunk))
+                    used += chunk_width
+                elif not empty:
+                    break_line()  # Spaces at a line break are dropped
+                continue
+            if used + chunk_width > width and not empty:
+                while line and line[-1][1].isspace():
+                    used -= text_width(line.pop()[1])
+                break_line()
+            while used + chunk_width > width and width > used:
+                # Longer than a whole line: fill the line with what fits
+                cut = 0
+                while cut < len(chunk) and used + text_width(chunk[:cut + 1]) <= width:
+                    cut += 1
+                if not cut:
+                    break
+                line.append((style, chunk[:cut]))
+                break_line()
+                chunk = chunk[cut:]
+                chunk_width = text_width(chunk)
+            line.append((style, chunk))
+            used += chunk_width
+            empty = False
+    lines.append(line)
+    return lines
+
+
+def _render_table(lines: List[str]) -> List[StyleAndTextTuples]:
+    """Draw a table with its columns lined up and box lines between them"""
+    table = find_table(lines, 0)
+    if table is None or table.end != len(lines) - 1:
+        return [[('', line)] for line in lines]
+    rendered = []
+    for index, line in enumerate(format_table(table)):
+        line = line.strip()
+        if index == 1:
+            rule = re.sub(r'[-: ]', '─', line).replace('|', '┼')
+            rendered.append([('class:muted', '├' + rule[1:-1] + '┤')])
+            continue
+        fragments, pos = [], 0
+        for pipe in find_pipes(line):
+            fragments.extend(render_inline(line[pos:pipe].replace('\\|', '|'), 'bold' if index == 0 else ''))
+            fragments.append(('class:muted', '│'))
+            pos = pipe + 1
+        rendered.append(fragments)
+    return rendered
+
+
+def render(text: str, width: int, highlight: Callable[[str, Optional[str]], StyleAndTextTuples]) -> List[PreviewLine]:
+    """
+    Render a note's markdown for the preview pane
+
+    Args:
+        text: The note's content
+        width: Columns of the pane
+        highlight: Styles a line of code in a language (or None for none)
+
+    Returns:
+        The rendered lines, each with the line of the note it comes from
+    """
+    lines = text.split('\n')
+    rendered: List[PreviewLine] = []
+    index = 0
+
+    # Frontmatter at the top shows muted, as it is
+    if lines and lines[0].strip() == '---':
+        closing = next((i for i in range(1, len(lines)) if lines[i].strip() in ('---', '...')), None)
+        if closing is not None:
+            rendered.extend((i, [('class:muted', lines[i])]) for i in range(closing + 1))
+            index = closing + 1
+
+    while index < len(lines):
+        line = lines[index]
+        fence = FENCE.match(line)
+        if fence:
+            # A code block, highlighted, without its fences
+            language = line.strip()[len(fence.group(1)):].strip().split(' ')[0] or None
+            index += 1
+            while index < len(lines) and not lines[index].strip().startswith(fence.group(1)):
+                rendered.append((index, [('class:code', '  ')] + highlight(lines[index], language)))
+                index += 1
+            index += 1
+            continue
+
+        table = find_table(lines, index) if '|' in line else None
+        if table is not None and table.start == index:
+            rendered.extend((index + i, row) for i, row in enumerate(_render_table(lines[index:table.end + 1])))
+            index = table.end + 1
+            continue
+
+        heading = HEADING.match(line)
+        quote = QUOTE.match(line)
+        item = parse_list_item(line)
+        if not line.strip():
+            wrapped = [[]]
+        elif heading:
+            level = len(line.lstrip()) - len(line.lstrip().lstrip('#'))
+            style = 'class:heading underline' if level == 1 else 'class:heading'
+            wrapped = wrap(render_inline(heading.group(1), style), width)
+        elif RULE.match(line):
+            wrapped = [[('class:rule', '─' * width)]]
+        elif quote:
+            bar = [('class:quote', '│ ' * quote.group().count('>'))]
+            wrapped = wrap(render_inline(line[quote.end():], 'class:quote'), width, bar, bar)
+        elif item:
+            marker = item.bullet and '•' or f"{item.number}{item.delimiter}"
+            done = item.task and DONE_TASK.match(line) is not None
+            if item.task:
+                marker += ' ☑' if done else ' ☐'
+            first = [('', item.indent), ('class:bullet', marker + ' ')]
+            rest = [('', ' ' * sum(text_width(text) for _, text in first))]
+            style = 'class:muted strike' if done else ''
+            wrapped = wrap(render_inline(line[item.end:], style), width, first, rest)
+        else:
+            indent = line[:len(line) - len(line.lstrip())]
+            wrapped = wrap(render_inline(line.strip()), width, [('', indent)], [('', indent)])
+        rendered.extend((index, fragments) for fragments in wrapped)
+        index += 1
+    return rendered
+
+
+class LivePreview:
+    """The rendered note of the preview pane, brought up to date once typing pauses"""
+
+    def __init__(self, delay: float = DELAY):
+        self.delay = delay
+        self._source: Optional[Tuple[str, str, int]] = None  # (note id, text, width) rendered
+        self._lines: List[PreviewLine] = []
+        self._pending: Optional[Tuple[str, str, int]] = None  # Source changed since, not rendered yet
+        self._changed_at = 0.0
+
+    def lines(self, note_id: str, text: str, width: int,
+              highlight: Callable[[str, Optional[str]], StyleAndTextTuples]) -> List[PreviewLine]:
+        """
+        Get the rendered lines of a note
+
+        Another note, or a new width, renders right away; edits render once
+        they've stopped for the delay, the lines from before showing until
+        then (is_due() says when to redraw).
+
+        Args:
+            note_id: ID of the note
+            text: Its content, as in the editor
+            width: Columns of the pane
+            highlight: Styles a line of code, as in render()
+        """
+        source = (note_id, text, width)
+        if source == self._source:
+            return self._lines
+        now = time.monotonic()
+        if source != self._pending:
+            self._pending, self._changed_at = source, now
+        if self._source is None or self._source[0] != note_id or self._source[2] != width or self.is_due():
+            self._lines = render(text, width, highlight)
+            self._source, self._pending = source, None
+        return self._lines
+
+    def is_due(self) -> bool:
+        """Check if edits have paused long enough to render them"""
+        return self._pending is not None and time.monotonic() - self._changed_at >= self.delay
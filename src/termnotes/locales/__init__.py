@@ -0,0 +1,13 @@
+"""
+Message catalogs for termnotes
+
+Each locale module defines a MESSAGES dict mapping message keys to
+translated strings. English is the reference catalog.
+"""
+
+from . import en, es
+
+CATALOGS = {
+    "en": en.MESSAGES,
+    "es": es.MESSAGES,
+}
@@ -0,0 +1,995 @@
+"""
+English message catalog (reference)
+"""
+
+MESSAGES = {
+    # Command line
+    "cli.description": "A vim-like terminal note-taking application",
+    "cli.print_config_help": "Print example configuration and exit",
+    "cli.accessible_help": "Use the screen-reader-friendly accessible mode",
+    "cli.no_alt_screen_help": "Do not switch to the terminal's alternate screen",
+    "cli.print_keys_help": "Print the key bindings and exit",
+    "cli.backend_help": "Storage backend to use instead of the configured one",
+    "cli.notes_path_help": "Where the backend keeps notes: database file, directory, or Google Drive folder name",
+    "cli.theme_help": "Code block colors: \"auto\", \"ansi\" or a Pygments style name (e.g. monokai)",
+    "cli.colors_help": "Interface colors: auto, dark, light, solarized or gruvbox (or a -dark/-light variant)",
+    "cli.sidebar_width_help": "Note list width in columns, or a fraction of the terminal width (e.g. 0.3)",
+    "cli.sort_help": "Note list order",
+    "cli.debug_help": "Write a debug log (default ~/.termnotes/termnotes.log) to attach to bug reports",
+    "cli.seed_demo_help": "Add a few demo notes (in a \"demo\" notebook) and show the tour",
+    "cli.readonly_help": "Open the notes read-only, to browse them without changing anything",
+    "cli.debug_logging": "Writing the debug log to {path}",
+    "cli.debug_log_failed": "Could not open the debug log: {error}",
+    "cli.add_help": "Create a note from stdin",
+    "cli.add_description": "Read note content from stdin (e.g. make 2>&1 | termnotes add --title \"Build log\") and print the note's ID",
+    "cli.add_title_help": "Title of the new note, added as a heading above the content",
+    "cli.add_append_help": "Append to the note with this ID instead of creating one",
+    "cli.add_no_input": "Error: no input; pipe the note content into termnotes add",
+    "cli.add_note_not_found": "Error: no note with ID {note_id}",
+    "cli.serve_help": "Run a sync server for other machines",
+    "cli.serve_description": "Store notes centrally for machines using the sync storage backend (settings in the [server] config section)",
+    "cli.serve_host_help": "Address to listen on",
+    "cli.serve_port_help": "Port to listen on",
+    "cli.serve_token_help": "Token clients must send",
+    "cli.serve_feed_help": "Also serve an Atom feed of recently updated notes (see [server] feed)",
+    "cli.serve_grpc_help": "Also serve the gRPC API (see [server] grpc)",
+    "cli.serve_grpc_port_help": "Port for the gRPC API (implies --grpc)",
+    "cli.mcp_help": "Let AI assistants search and read notes over MCP",
+    "cli.mcp_description": "Speak the Model Context Protocol on stdin and stdout, for clients such as Claude Desktop to start as a server; they can search, list and read notes, and with --write create notes and append to them (settings in the [mcp] config section)",
+    "cli.mcp_write_help": "Let clients create notes and append to them",
+    "cli.daemon_help": "Hold the notes for several editors at once",
+    "cli.daemon_description": "Serve the notes on a unix socket so editors and commands in several terminals can change them at once and see each other's changes (settings in the [daemon] config section)",
+    "cli.daemon_stop_help": "Stop the running daemon",
+    "cli.daemon_idle_timeout_help": "Exit after this many seconds without editors or commands connected (0 to keep running)",
+    "cli.e2e_help": "Manage the keys of end-to-end encrypted sync",
+    "cli.e2e_description": "List the devices that can read the synced notes, encrypt them with a new key, or revoke a device so it can't read notes written from now on (with e2e = true in [storage.sync]; no editor or daemon may have the notes open)",
+    "cli.e2e_action_help": "devices: list the devices; rotate: encrypt with a new key; revoke: remove a device and change the passphrase",
+    "cli.e2e_device_help": "ID of the device to revoke, as listed by \"termnotes e2e devices\"",
+    "cli.e2e_no_device": "Error: give the ID of the device to revoke (see \"termnotes e2e devices\")",
+    "cli.e2e_rotated": "Notes are now encrypted with a new key; other devices pick it up on their next sync.",
+    "cli.e2e_revoked": "Revoked device {device}; other devices ask for the new passphrase once to get the new key.",
+    "cli.e2e_change_passphrase": "The revoked device knows the passphrase, so choose a new one; other devices will ask for it once.",
+    "cli.secret_help": "Keep passwords and tokens in the system keyring",
+    "cli.secret_description": "List, store or remove the passwords and tokens termnotes keeps in the system keyring (macOS Keychain, Secret Service, Windows Credential Manager; needs the keyring package). A stored secret is used when its config setting is empty.",
+    "cli.secret_action_help": "list: show which are stored; set: store one (asked for, or read from stdin); delete: remove one",
+    "cli.secret_name_help": "Secret: {names}",
+    "cli.secret_no_name": "Error: give the name of the secret (see \"termnotes secret list\")",
+    "cli.secret_prompt": "Value for {name}: ",
+    "cli.secret_empty": "Error: the secret cannot be empty",
+    "cli.secret_stored": "Stored {name} in the system keyring; leave its setting in the config file empty.",
+    "cli.secret_deleted": "Removed {name} from the system keyring.",
+    "cli.secret_not_stored": "No {name} secret in the system keyring",
+    "cli.mcp_notebook_help": "Limit clients to the notes in this notebook and the ones inside it (can be repeated; new notes go in the first)",
+    "cli.export_help": "Export notes to markdown, HTML or PDF files",
+    "cli.export_description": "Write notes (all but those in the trash, or the ones given with --note) to files named after their titles, with notebooks as subdirectories",
+    "cli.export_format_help": "File format (default: md)",
+    "cli.export_note_help": "Export only the note with this ID (can be repeated)",
+    "cli.export_output_help": "Directory to write to (default: the current directory)",
+    "cli.export_archive_help": "Write one archive file instead (such as notes.tnx) with the notes, trashed ones included, and their attached files, to import on another machine or backend",
+    "cli.export_jex_help": "Write one Joplin export file instead (such as notes.jex) with the notes, their notebooks, tags and attached files, to import in Joplin",
+    "cli.export_ics_help": "Write a calendar file instead (such as notes.ics) with an event for each note with a due date or reminder, and an all-day event for each note with a date in its title",
+    "cli.export_failed": "Error: export failed: {error}",
+    "cli.publish_help": "Publish notes as a static HTML site",
+    "cli.publish_description": "Write a page per note (all but those in the trash, or the ones picked with --notebook, --tag and --note), with [[wiki links]] as hyperlinks, and an index of them by notebook",
+    "cli.publish_out_help": "Directory to write the site to",
+    "cli.publish_notebook_help": "Publish the notes in this notebook and the ones inside it (can be repeated)",
+    "cli.publish_tag_help": "Publish the notes with this tag (can be repeated)",
+    "cli.publish_note_help": "Publish the note with this ID (can be repeated)",
+    "cli.publish_title_help": "Title of the site, shown on every page (default: Notes)",
+    "cli.publish_done": "Published {count} notes; open {path}",
+    "cli.import_help": "Import notes from another app",
+    "cli.import_description": "Add notes from another app's files, keeping their titles, tags and timestamps; folders of markdown files become notebooks",
+    "cli.import_from_help": "Where the files come from: \"obsidian\" (a vault; titles from file names, inline #tags), \"markdown\" (.md, .markdown and .txt files), \"enex\" (an Evernote export file) \"joplin\" (a Joplin JEX export file; notebooks, tags and attached files are kept), \"notion\" (a Notion export ZIP file or its directory; database rows become tagged notes), \"keep\" (a Google Takeout ZIP file or its directory; labels become tags), \"apple-notes\" (a folder of notes exported from Apple Notes) or \"archive\" (a termnotes archive from export --archive, kept as it was, IDs included)",
+    "cli.import_notebook_help": "Put the imported notes in this notebook",
+    "cli.import_path_help": "Directory or file to import",
+    "cli.import_failed": "Error: import failed: {error}",
+    "cli.import_done": "Imported {count} notes from {path}",
+    "cli.import_skipped": "Skipped {count} notes that are already stored; delete them to import them again",
+    "cli.backup_help": "Write a backup of every note to a tar.gz file",
+    "cli.backup_description": "Write every note, including trashed and archived ones, and the files attached to them to a timestamped tar.gz file, and print its path.",
+    "cli.backup_output_help": "Directory to write the backup to (default: the [backup] directory)",
+    "cli.backup_failed": "Error: backup failed: {error}",
+    "cli.restore_help": "Bring back the notes in a backup",
+    "cli.restore_description": "Store the notes and attached files in a backup written by \"termnotes backup\", replacing notes with the same IDs. Other notes are left alone, and a backup of the notes as they are is written first.",
+    "cli.restore_path_help": "Backup file (.tar.gz)",
+    "cli.restore_failed": "Error: restore failed: {error}",
+    "cli.restore_done": "Restored {count} notes from {path}",
+    "cli.stats_help": "Show word counts and other statistics about your notes",
+    "cli.stats_description": "Print note, word and character totals, the number of notes with each tag, and how many notes were created each month",
+    "cli.stats_notes": "Notes: {count} ({archived} archived, {trashed} in the trash)",
+    "cli.stats_words": "Words: {words} (about {minutes} min of reading)",
+    "cli.stats_characters": "Characters: {characters}",
+    "cli.stats_tags": "Notes per tag:",
+    "cli.stats_growth": "Notes created per month (total):",
+    "cli.agenda_help": "List notes that are overdue or coming due",
+    "cli.agenda_description": "Print the notes whose due date has passed and the notes due in the next days; exits with status 1 if any note is overdue",
+    "cli.agenda_days_help": "How many days ahead to look (default: [reminders] upcoming_days)",
+    "cli.agenda_overdue": "Overdue:",
+    "cli.agenda_upcoming": "Due in the next {days} day(s):",
+    "cli.agenda_empty": "Nothing due in the next {days} day(s)",
+
+    # Mode and focus indicators
+    "mode.insert": "-- INSERT --",
+    "mode.visual": "-- VISUAL --",
+    "mode.visual_line": "-- VISUAL LINE --",
+    "focus.sidebar": "SIDEBAR",
+    "focus.editor": "EDITOR",
+    "focus.history": "HISTORY",
+    "focus.templates": "TEMPLATES",
+    "focus.replace": "REPLACE",
+    "focus.tasks": "TASKS",
+    "focus.outline": "OUTLINE",
+    "focus.help": "HELP",
+    "focus.tour": "TOUR",
+    "focus.switcher": "SWITCH",
+    "pane.notes": "Notes",
+    "pane.preview": "Preview",
+
+    # Sidebar and status bar
+    "note.empty_preview": "(empty note)",
+    "due.overdue": "overdue",
+    "due.today": "today",
+    "due.tomorrow": "tomorrow",
+    "age.now": "now",
+    "age.minutes": "{count}m",
+    "age.hours": "{count}h",
+    "age.days": "{count}d",
+    "ago.now": "just now",
+    "ago.minutes": "{count} min ago",
+    "ago.hours": "{count}h ago",
+    "ago.days": "{count}d ago",
+    "ago.date": "on {date}",
+    "metadata.created": "Created {date}",
+    "metadata.edited": "edited {ago}",
+    "metadata.words": "{count} words",
+    "status.notes": "{listed} of {total} notes",
+    "status.notes_all": "{count} notes",
+    "status.sort": "sort: {order}",
+    "status.encrypted": "{backend}, encrypted",
+    "status.daemon": "{backend} via daemon",
+    "status.saved": "saved {ago}",
+    "status.synced": "synced",
+    "status.unsynced": "{count} not synced",
+    "export.timestamps": "Created {created} · Updated {updated}",
+    "site.title": "Notes",
+    "site.backlinks": "Linked from",
+    "site.empty": "No notes published.",
+    "share.unknown_service": "Unknown share service: {service} (use {services})",
+    "share.no_token": "Sharing a gist needs a GitHub token: set [share] token or keep it in the keyring (keyring set termnotes github)",
+    "share.no_paste_url": "No paste service set ([share] paste_url)",
+    "share.bad_response": "{url} didn't answer with the note's URL",
+    "ai.bad_response": "{url} sent an answer that couldn't be read",
+    "ai.no_command": "The command provider needs a program in [ai] command",
+    "ai.empty_note": "The note is empty",
+    "ai.no_answer": "The model gave no answer",
+    "ai.summary_label": "Summary:",
+    "import.attachment": "[attachment: {name}]",
+    "import.invalid_jex": "{path} is not a Joplin export (JEX) file",
+    "import.invalid_notion": "{path} is not a Notion export (a ZIP file, or the directory it was extracted to)",
+    "import.invalid_keep": "{path} is not a Google Takeout export (a ZIP file, or the directory it was extracted to)",
+    "indicator.new": "[NEW]",
+    "indicator.note_stats": "{words} words, {characters} chars, {minutes} min read",
+    "indicator.zen_words": "{words} words",
+    "indicator.pinned": "^",
+    "indicator.starred": "+",
+    "indicator.reminder": "@",
+    "indicator.secure": "🔒",
+    "indicator.trash": "[TRASH]",
+    "indicator.read_only": "[READ-ONLY]",
+    "indicator.archive": "[ARCHIVE]",
+    "indicator.starred_view": "[STARRED]",
+    "indicator.color_filter": "[● {color}]",
+    "indicator.table": "[TABLE]",
+
+    # Color labels
+    "color.red": "red",
+    "color.yellow": "yellow",
+    "color.green": "green",
+    "color.cyan": "cyan",
+    "color.blue": "blue",
+    "color.magenta": "magenta",
+
+    "indicator.marked": "*",
+    "links.linked_from": "Linked from:",
+    "links.more": "…and {count} more",
+    "images.placeholder": "[image: {name}, {size}]",
+
+    # Accessible mode labels
+    "a11y.pane_notes": "Notes list, {count} notes",
+    "a11y.pane_editor": "Editor, {title}",
+    "a11y.pane_history": "History of {title}",
+    "a11y.pane_templates": "Templates, {count} templates",
+    "a11y.pane_replace": "Replace {pattern} with {replacement}, {count} notes",
+    "a11y.pane_tasks": "Tasks, {count} listed",
+    "a11y.pane_outline": "Outline, {count} headings",
+    "a11y.pane_help": "Key bindings, Escape closes",
+    "a11y.help_lines": "Lines {start} to {end} of {total}",
+    "a11y.pane_tour": "Tour, Enter for the next step, Escape skips it",
+    "a11y.tour_step": "Step {step} of {total}",
+    "a11y.pane_switcher": "Go to note: {query}",
+    "a11y.switcher_note": "{index} of {total}: {title}",
+    "a11y.dialog": "{question} {choices}",
+    "a11y.mode_normal": "Normal mode",
+    "a11y.mode_insert": "Insert mode",
+    "a11y.mode_visual": "Visual mode",
+    "a11y.mode_visual_line": "Visual line mode",
+    "a11y.command": "Command {command}",
+    "a11y.position": "Line {row} of {total}, column {col}",
+    "a11y.note_position": "Item {index} of {total}",
+    "a11y.notebook_expanded": "Notebook {path}, {count} notes, expanded",
+    "a11y.notebook_collapsed": "Notebook {path}, {count} notes, collapsed",
+    "a11y.modified": "Modified",
+    "a11y.note_stats": "{words} words, {characters} characters, about {minutes} minutes to read",
+    "a11y.new_note": "New note, not saved",
+    "a11y.selection": "{count} line(s) selected",
+    "a11y.tag_filter": "Filtered by tag {tag}",
+    "a11y.color_filter": "Filtered by color {color}",
+    "a11y.trash": "Showing trash",
+    "a11y.read_only": "Read-only",
+    "a11y.archive": "Showing archive",
+    "a11y.search_results": "Search results for {query}, {count} notes",
+    "a11y.search_match": "Match: {snippet}",
+    "a11y.revision": "Revision {index} of {total}, {revision}",
+    "a11y.template": "Template {index} of {total}, {name}",
+    "a11y.replacement": "Note {index} of {total}, {title}, {count} matches",
+    "a11y.replacement_skipped": "Note {index} of {total}, {title}, {count} matches, skipped",
+    "a11y.task": "Task {index} of {total}, open, {task}, in {title}",
+    "a11y.task_done": "Task {index} of {total}, done, {task}, in {title}",
+    "a11y.heading": "Heading {index} of {total}, level {level}, {heading}",
+
+    # Status messages
+    "msg.note_saved": "Note saved",
+    "toast.save_failed": "Could not save the note, your edits are kept: {error}",
+    "toast.draft_restored": "Restored unsaved edits; :w saves them",
+    "toast.read_from_backup": "{count} damaged note file(s) were read from their backups",
+    "toast.draft_failed": "Could not write the draft of your edits: {error}",
+    "msg.no_note_loaded": "No note loaded",
+    "msg.new_unsaved_load": "New note not saved! :w to save, :e! to discard and load",
+    "msg.unsaved_load": "Unsaved changes! :w to save, :e! to discard and load",
+    "msg.new_unsaved_create": "New note not saved! :w to save, :e! to discard and create new",
+    "msg.unsaved_create": "Unsaved changes! :w to save, :e! to discard and create new",
+    "msg.unsaved_quit": "Unsaved changes! :w to save, :q! to quit without saving",
+    "msg.new_note_discarded": "New note discarded",
+    "msg.note_deleted": "Note deleted",
+    "msg.note_trashed": "Note moved to trash ({trash} to show the trash, {restore} there to restore)",
+    "msg.note_restored": "Note restored from trash",
+    "msg.not_in_trash": "Note is not in the trash",
+    "msg.trash_shown": "Trash: {count} note(s). {restore} to restore, {delete} to delete permanently, {trash} to go back",
+    "msg.trash_hidden": "Showing notes",
+    "msg.note_archived": "Archived note ({archive} shows the archive)",
+    "msg.note_unarchived": "Moved note back to the main list",
+    "msg.already_archived": "Note is already archived",
+    "msg.not_archived": "Note isn't archived",
+    "msg.save_before_archive": "Save the note (:w) before archiving it",
+    "msg.save_before_split": "Save the note (:w) before splitting it",
+    "msg.nothing_to_split": "The note has fewer than two top-level headings to split at",
+    "msg.note_split": "Split into {count} notes, linked from this one ({undo} in the note list undoes it)",
+    "split.index_title": "{title} (index)",
+    "split.back_link": "Back to {link}",
+    "msg.archive_shown": "Archive: {count} note(s). {archive} to unarchive, {show_archive} to go back",
+    "msg.archive_hidden": "Showing notes",
+    "msg.trash_empty": "The trash is empty",
+    "msg.trash_emptied": "Deleted {count} note(s) from the trash",
+    "msg.sync_unsupported": "Storage backend doesn't sync; set backend = \"sync\" in the config",
+    "msg.sync_failed": "Sync failed: {error}",
+    "msg.synced": "Synced: {count} note(s) changed",
+    "msg.shared": "Shared at {url}",
+    "msg.shared_copied": "Shared at {url} (URL copied)",
+    "msg.share_failed": "Couldn't share the note: {error}",
+    "msg.ai_off": "No language model set up: choose an [ai] provider in the config (nothing is sent while it's off)",
+    "msg.ai_summarized": "Summary added below the title (u to undo)",
+    "msg.ai_titled": "Titled \"{title}\" (u to undo)",
+    "msg.ai_no_tags": "No new tags suggested",
+    "msg.ai_tagged": "Tagged {tags}",
+    "msg.ai_failed": "Language model request failed: {error}",
+    "msg.hook_failed": "Hook for {event} failed ({command}): {error}",
+    "msg.hook_timeout": "Hook for {event} stopped after {seconds}s: {command}",
+    "msg.hook_status": "exit status {status}",
+    "msg.transforms": "Transforms: {names} (:transform <name>)",
+    "msg.no_transforms": "No plugin adds a transform",
+    "msg.unknown_transform": "No transform named \"{name}\"",
+    "msg.transformed": "Applied {name} (u to undo)",
+    "msg.transform_unchanged": "{name} didn't change the note",
+    "msg.plugins": "Plugins: {plugins}; commands: {commands}",
+    "msg.no_plugins": "No plugins loaded",
+    "msg.due_set": "Due {when}",
+    "msg.due_cleared": "Due date removed",
+    "msg.remind_set": "Reminder set for {when}",
+    "msg.remind_cleared": "Reminder removed",
+    "msg.when_usage": "Couldn't read \"{text}\" as a time; try 2026-10-20, 2026-10-20 14:30, 14:30, tomorrow, friday or +2h",
+    "msg.reminder_title": "termnotes reminder",
+    "msg.reminder": "Reminder: {titles}",
+    "msg.no_tasks": "No open tasks (- [ ] lines) in any note",
+    "msg.tasks_help": "{count} open task(s): {down}/{up} to select, {mark} to check, {open} to open the note, Tab to show done tasks, Esc to close",
+    "msg.no_headings": "This note has no headings (# lines)",
+    "msg.outline_help": "{count} heading(s): {down}/{up} to go through them, {open} to move the cursor there, Esc to go back",
+    "msg.tasks_done_shown": "Showing done tasks too",
+    "msg.tasks_done_hidden": "Showing open tasks",
+    "msg.task_done": "Checked \"{task}\" ({undo} to undo)",
+    "msg.task_undone": "Unchecked \"{task}\" ({undo} to undo)",
+    "msg.task_moved": "That task changed in its note; the list was updated",
+    "msg.unsaved_task": "The task's note has unsaved changes; save it (:w) first",
+    "msg.no_checkbox": "No - [ ] task on this line",
+    "msg.checkbox_unsaved_done": "Checked \"{task}\" (not saved yet: the note has other unsaved changes)",
+    "msg.checkbox_unsaved_undone": "Unchecked \"{task}\" (not saved yet: the note has other unsaved changes)",
+    "msg.reloaded_external": "Notes changed outside termnotes; reloaded",
+    "msg.changed_externally": "This note changed outside termnotes; saving will ask whether to merge or overwrite",
+    "msg.merged": "Merged with the stored version; check it and save with :w",
+    "msg.merge_conflicts": "Merged; both versions of the lines changed on both sides are between <<<<<<< and >>>>>>>, fix them and :w",
+    "msg.conflict_discarded": "Edits discarded; showing the stored version",
+    "merge.yours": "your edits",
+    "merge.stored": "stored version",
+    "msg.no_link": "No [[link]] under the cursor",
+    "msg.link_not_found": "No note titled \"{title}\"",
+    "msg.attach_usage": "Usage: :attach <file path>",
+    "msg.attach_unsaved": "Save the new note (:w) before attaching files",
+    "msg.attach_failed": "Could not attach file: {error}",
+    "msg.attached": "Attached {name} ({size})",
+    "msg.image_pasted": "Pasted the clipboard's image as {name} ({size}), attached to the note",
+    "msg.no_attachments": "This note has no attachments",
+    "msg.attachments_list": "Attachments: {attachments}",
+    "msg.attachment_which": "This note has several attachments; give a number or name (:attachments lists them)",
+    "msg.attachment_not_found": "No attachment {name}",
+    "msg.attachment_missing": "Attached file is missing: {path}",
+    "msg.attachment_open_failed": "Could not open attachment: {error}",
+    "msg.attachment_opened": "Opened {name}",
+    "msg.url_open_failed": "Could not open {url}: {error}",
+    "msg.url_opened": "Opened {url}",
+    "msg.no_urls": "No web links in this note",
+    "msg.spell_off": "Spellcheck is off (enable it in the [spellcheck] section of the config)",
+    "msg.spell_no_word": "No word to check under the cursor",
+    "msg.spell_known": "\"{word}\" is spelled right",
+    "msg.spell_added": "Added \"{word}\" to the personal dictionary",
+    "msg.spell_add_failed": "Couldn't add to the personal dictionary: {error}",
+    "msg.spell_none": "No misspelled words",
+    "msg.table_usage": "Usage: :table, :table row or :table column",
+    "msg.not_in_table": "The cursor isn't in a table",
+    "msg.attachment_removed": "Removed attachment {name}",
+    "msg.no_images": "This note has no images",
+    "msg.image_number": "Give an image number from 1 to {count}",
+    "msg.image_remote": "Images on the web aren't downloaded: {target}",
+    "msg.image_not_found": "Image not found: {target}",
+    "msg.image_read_failed": "Could not read image: {error}",
+    "msg.image_unsupported": "{image} (this terminal can't show images; set images in [ui] or use :open)",
+    "msg.confirm_delete_cmd": "Move note to trash? :d again to confirm, :d! to force",
+    "msg.confirm_purge_cmd": "Delete note permanently? :d again to confirm, :d! to force",
+    "msg.nothing_to_paste": "Nothing in register to paste",
+    "msg.copied": "Copied \"{title}\" to the clipboard",
+    "msg.copied_text": "Copied the text of \"{title}\" to the clipboard",
+    "msg.copied_osc52": "Sent \"{title}\" to the terminal's clipboard",
+    "msg.copy_failed": "No clipboard to copy to (see [clipboard] mode)",
+    "msg.oldest_change": "Already at oldest change",
+    "msg.newest_change": "Already at newest change",
+    "msg.pattern_not_found": "Pattern not found: {pattern}",
+    "msg.search_match": "/{query}: match {index} of {count}",
+    "msg.search_results": "{count} notes match {query} (Esc to close)",
+    "msg.no_previous_search": "No previous search pattern",
+    "msg.yanked_lines": "Yanked {count} line(s)",
+    "msg.no_pending_note": "No pending note to load",
+    "msg.sidebar_toggle_editor_only": "Sidebar toggle only available when editor is focused",
+    "msg.zen_on": "Zen mode (:zen or Ctrl+W o to leave)",
+    "msg.zen_off": "Left zen mode",
+    "msg.preview_on": "Live preview on (:preview or Ctrl+W p to hide)",
+    "msg.preview_off": "Live preview off",
+    "msg.wrap_on": "Wrapping long lines (:wrap or zw to scroll them sideways)",
+    "msg.wrap_off": "Long lines scroll sideways",
+    "msg.demo_added": "Added {count} demo note(s) in the \"demo\" notebook",
+    "msg.damaged_notes": "{count} note file(s) are damaged and can't be read; :recover salvages what it can",
+    "msg.no_damaged_notes": "No damaged note files",
+    "msg.notes_salvaged": "Salvaged {count} of {total} damaged note(s), tagged #recovered; the files are in {path}",
+    "msg.no_other_notes": "No other notes to switch to",
+    "switcher.title": "Go to note (type to filter, Enter opens)",
+    "switcher.no_match": "No note title matches",
+    "lock.title": "termnotes is locked",
+    "lock.prompt": "Passphrase: ",
+    "lock.wrong": "Wrong passphrase",
+    "lock.off": "The app lock is off: set enabled = true in [lock]",
+    "lock.choose_intro": "termnotes will ask for this passphrase on launch and when left idle ([lock] in the config).",
+    "lock.choose_passphrase": "Choose a lock passphrase: ",
+    "lock.not_chosen": "No lock passphrase chosen; set enabled = false in [lock] to start without one.",
+    "secure.locked_title": "Secure note (locked)",
+    "secure.locked_hint": ":unlock and type the secure notes' passphrase to read it",
+    "secure.locked_save": "Secure notes are locked: :unlock them first",
+    "secure.damaged": "The secure note's encrypted block is damaged: {error}",
+    "secure.wrong_passphrase": "Wrong passphrase for the secure notes",
+    "secure.unlock_title": "Unlock secure notes",
+    "secure.new_title": "Choose a passphrase for secure notes",
+    "secure.unlocked": "Unlocked the secure notes (:lock to lock them again)",
+    "secure.locked": "Locked the secure notes",
+    "secure.already_unlocked": "The secure notes are already unlocked",
+    "secure.none": "No secure notes (:secure encrypts the current note)",
+    "secure.on": "Note is secure: encrypted at rest and hidden from search while locked",
+    "secure.off": "Note is no longer secure",
+    "secure.already_on": "The note is already secure",
+    "secure.already_off": "The note isn't secure",
+    "msg.zen_accessible": "Zen mode isn't available in accessible mode, which already shows one pane at a time",
+    "msg.preview_accessible": "The live preview isn't available in accessible mode, which shows one pane at a time",
+    "msg.unknown_command": "Unknown command: {command}",
+    "msg.tag_usage": "Usage: :tag <name> or :untag <name>",
+    "msg.tag_added": "Tagged note #{tag}",
+    "msg.tag_removed": "Removed tag #{tag}",
+    "msg.note_pinned": "Pinned note to the top of the list",
+    "msg.note_unpinned": "Unpinned note",
+    "msg.note_starred": "Starred note",
+    "msg.note_unstarred": "Unstarred note",
+    "msg.starred_shown": "Starred: {count} note(s). {show_starred} to list every note",
+    "msg.starred_hidden": "Listing every note",
+    "msg.color_set": "Labeled note {color}",
+    "msg.color_cleared": "Removed color label",
+    "msg.color_usage": "Colors: {colors} (no color clears it)",
+    "msg.color_filter": "Color {color}: {count} note(s)",
+    "msg.color_filter_cleared": "Color filter cleared",
+    "msg.reorder_needs_manual": "Set sort = \"manual\" under [ui] in the config (or --sort manual) to reorder notes",
+    "msg.tag_not_found": "Note is not tagged #{tag}",
+    "msg.tags_list": "Tags: {tags}",
+    "msg.no_tags": "No tags yet. Add one with :tag <name>",
+    "msg.tag_filter": "Showing {count} note(s) tagged #{tag}",
+    "msg.tag_filter_cleared": "Showing all notes",
+    "msg.sort_order": "Sorted by {order}",
+    "sort.updated": "last updated",
+    "sort.created": "newest",
+    "sort.title": "title",
+    "sort.manual": "manual order",
+    "sort.due": "due date",
+    "msg.notebook_usage": "Usage: :notebook <path>",
+    "msg.notebook_created": "Created notebook {path}",
+    "msg.note_moved": "Moved note to {path}",
+    "msg.note_moved_top": "Moved note to top level",
+    "msg.notebooks_list": "Notebooks: {notebooks}",
+    "msg.no_notebooks": "No notebooks",
+    "msg.external_editor_failed": "Could not run {command}: {error}",
+    "msg.external_editor_exit": "{command} exited with status {code}; note not changed",
+    "msg.external_editor_unchanged": "No changes from external editor",
+    "msg.no_history": "No saved versions of this note yet",
+    "msg.history_help": "{count} version(s): {down}/{up} to select, {open} to restore, Esc to close",
+    "msg.template_help": "{down}/{up} to choose a template, {open} to create the note, Esc to cancel",
+    "msg.template_not_found": "No template named {name}",
+    "msg.template_created": "New note from template {name}",
+    "msg.unsaved_restore": "Unsaved changes! :w before restoring a version",
+    "msg.revision_restored": "Restored version #{rev}",
+    "msg.cancelled": "Cancelled",
+    "msg.save_before_mark": "Save the note (:w) before marking it",
+    "msg.marked": "{count} marked: {delete} to delete, {archive} to archive, :tag <name> to tag, Esc to unmark",
+    "msg.marks_cleared": "Unmarked all notes",
+    "msg.notes_trashed": "Moved {count} note(s) to the trash ({trash} to show the trash, {restore} there to restore)",
+    "msg.notes_deleted": "Deleted {count} note(s)",
+    "msg.notes_archived": "Archived {count} note(s)",
+    "msg.notes_unarchived": "Moved {count} note(s) back to the main list",
+    "msg.notes_tagged": "Tagged {count} note(s) #{tag}",
+    "msg.notes_untagged": "Removed tag #{tag} from {count} note(s)",
+    "msg.replace_usage": "Usage: :replaceall /text/replacement/ (r after the last / for a regular expression)",
+    "msg.bad_pattern": "Invalid pattern: {error}",
+    "msg.no_replacements": "No notes contain {pattern}",
+    "msg.replace_help": "{matches} match(es) in {count} note(s): {down}/{up} to select, {mark} to skip a note, {open} to replace, Esc to cancel",
+    "msg.all_skipped": "Every note is skipped; {mark} includes the selected note",
+    "msg.unsaved_replace": "Unsaved changes! :w or :e! before replacing text in this note",
+    "msg.replaced": "Replaced {matches} match(es) in {count} note(s) ({undo} in the note list to undo)",
+    "msg.undone": "Undone: {action}",
+    "msg.redone": "Redone: {action}",
+    "msg.nothing_to_undo": "Nothing to undo",
+    "msg.nothing_to_redo": "Nothing to redo",
+    "msg.undo_unsaved": "Unsaved changes! :w or :e! before undoing changes to this note",
+
+    # Config file settings
+    "config.unknown_theme": "Unknown theme: {theme}",
+    "config.unknown_colors": "Unknown colors setting: {colors} (use {names})",
+    "config.unknown_color_element": "Unknown element in [colors]: {element}",
+    "config.invalid_color": "Invalid style for {element} in [colors]: {style}",
+    "config.unknown_hook": "Unknown event in [hooks]: {event} (events: {events})",
+    "config.unknown_notify": "Unknown [reminders] notify setting: {notify} (use desktop, banner or off)",
+    "config.unknown_clipboard": "Unknown [clipboard] mode: {mode} (use auto, system, osc52 or off)",
+    "config.unknown_share": "Unknown [share] service: {service} (use gist or paste)",
+    "config.unknown_ai": "Unknown [ai] provider: {provider} (use off, openai, ollama or command)",
+    "config.unknown_sort": "Unknown sort order: {sort} (use updated, created, title, manual or due)",
+    "config.unknown_images": "Unknown images setting: {images} (use auto, kitty, iterm2, sixel or off)",
+    "config.unknown_editing": "Unknown editing setting: {editing} (use vim or simple)",
+    "config.invalid_sidebar_width": "Invalid sidebar_width: {width}",
+    "config.invalid_zen_width": "Invalid zen_width: {width} (use a number of columns, at least 20)",
+    "config.invalid_draft_interval": "Invalid draft_interval: {interval}",
+    "config.invalid_spell_languages": "Invalid spellcheck languages: {languages}",
+    "config.invalid_snippet": "Invalid snippet: {abbreviation}",
+    "config.invalid_lock_idle": "Invalid idle_minutes in [lock]: {minutes}",
+
+    # Confirmation dialog
+    "dialog.title": "Confirm",
+    "dialog.choices": "y: Yes   n: No",
+    "dialog.open_url": "Open which link?",
+    "dialog.spell_suggest": "Replace \"{word}\" with:",
+    "dialog.spell_add": "Add to the personal dictionary",
+    "dialog.trash_note": "Move \"{title}\" to the trash?",
+    "dialog.purge_note": "Delete \"{title}\" permanently?",
+    "dialog.trash_marked": "Move {count} marked note(s) to the trash?",
+    "dialog.purge_marked": "Permanently delete {count} marked note(s)?",
+    "dialog.empty_trash": "Permanently delete {count} note(s) in the trash?",
+    "dialog.restore_draft": "Restore unsaved edits to \"{title}\" from {time}?",
+    "dialog.salvage_notes": "{count} note file(s) are damaged, with no good backup. Salvage what can be read of them?",
+    "dialog.ai_tags": "Add the suggested tags {tags}?",
+    "dialog.conflict": "\"{title}\" changed in storage while you were editing it",
+    "dialog.conflict_merge": "Merge",
+    "dialog.conflict_overwrite": "Overwrite",
+    "dialog.conflict_discard": "Discard mine",
+    "dialog.choice_cancel": "Esc: Cancel",
+
+    # Operations that can be undone, after "Undone:" or "Redone:"
+    "journal.create": "creating \"{title}\"",
+    "journal.save": "saving \"{title}\"",
+    "journal.delete": "deleting \"{title}\"",
+    "journal.trash": "moving \"{title}\" to the trash",
+    "journal.restore": "restoring \"{title}\" from the trash",
+    "journal.archive": "archiving \"{title}\"",
+    "journal.unarchive": "unarchiving \"{title}\"",
+    "journal.tag": "tagging \"{title}\"",
+    "journal.untag": "removing a tag from \"{title}\"",
+    "journal.pin": "pinning \"{title}\"",
+    "journal.due": "setting the due date of \"{title}\"",
+    "journal.remind": "setting a reminder on \"{title}\"",
+    "journal.toggle_task": "toggling a task in \"{title}\"",
+    "journal.unpin": "unpinning \"{title}\"",
+    "journal.star": "starring \"{title}\"",
+    "journal.unstar": "unstarring \"{title}\"",
+    "journal.secure": "making \"{title}\" secure",
+    "journal.unsecure": "making \"{title}\" no longer secure",
+    "journal.color": "labeling \"{title}\"",
+    "journal.uncolor": "removing the color label of \"{title}\"",
+    "journal.move": "moving \"{title}\" to another notebook",
+    "journal.reorder": "reordering notes",
+    "journal.purge": "emptying the trash ({count} note(s))",
+    "journal.revision": "restoring a version of \"{title}\"",
+    "journal.trash_many": "moving {count} note(s) to the trash",
+    "journal.delete_many": "deleting {count} note(s)",
+    "journal.archive_many": "archiving {count} note(s)",
+    "journal.unarchive_many": "unarchiving {count} note(s)",
+    "journal.tag_many": "tagging {count} note(s)",
+    "journal.untag_many": "removing a tag from {count} note(s)",
+    "journal.replace_all": "replacing text in {count} note(s)",
+    "journal.import": "importing {count} note(s)",
+    "journal.restore_backup": "restoring {count} note(s) from a backup",
+    "journal.split": "splitting \"{title}\" into notes",
+
+    # Key binding actions
+    "keys.unknown_action": "Unknown action in [keys]: {action}",
+    "keys.invalid": "Invalid keys for {action} in [keys]: {keys}",
+    "plugin.load_failed": "Plugin {plugin} failed to load: {error}",
+    "plugin.no_register": "Plugin {plugin} has no register() function",
+    "plugin.builtin_action": "Plugin {plugin} can't add the built-in action {action}",
+    "plugin.invalid_keys": "Invalid keys for the plugin action {action}: {keys}",
+    "plugin.failed": "Plugin {plugin} failed: {error}",
+    "plugin.transform_not_text": "transform returned {type}, not text",
+    "keys.up": "Move up",
+    "keys.down": "Move down",
+    "keys.left": "Move left (editor)",
+    "keys.right": "Move right (editor)",
+    "keys.word_forward": "Next word",
+    "keys.word_backward": "Previous word",
+    "keys.word_end": "End of word",
+    "keys.line_start": "Start of line",
+    "keys.line_end": "End of line",
+    "keys.half_page_down": "Half page down",
+    "keys.half_page_up": "Half page up",
+    "keys.page_down": "Page down",
+    "keys.page_up": "Page up",
+    "keys.bottom": "Last line",
+    "keys.focus_sidebar": "Focus the note list",
+    "keys.focus_editor": "Focus the editor",
+    "keys.toggle_focus": "Switch focus between the note list and the editor",
+    "keys.toggle_zen": "Enter or leave zen mode (only the editor, centered)",
+    "keys.toggle_preview": "Show or hide the live preview of the note next to the editor",
+    "keys.toggle_wrap": "Wrap long lines in the editor, or scroll them sideways",
+    "keys.quick_switch": "Jump to a recently viewed note by typing part of its title",
+    "keys.follow_link": "Show the image or open the note a [[link]] points to (editor)",
+    "keys.open_url": "Open a web link of the note, choosing one if there are several",
+    "keys.toggle_checkbox": "Check or uncheck the - [ ] task on the cursor line, saving the note (editor)",
+    "keys.paste_clipboard": "Paste the system clipboard (editor, insert mode)",
+    "keys.spell_suggest": "Suggest spellings for the word under the cursor",
+    "keys.spell_add": "Add the word under the cursor to the personal dictionary",
+    "keys.spell_next": "Next misspelled word",
+    "keys.spell_previous": "Previous misspelled word",
+    "keys.open": "Open note or notebook; restore version in history",
+    "keys.new_note": "New note",
+    "keys.new_from_template": "New note from a template",
+    "keys.edit": "Edit selected note in insert mode",
+    "keys.delete_note": "Move note to trash (delete permanently in trash)",
+    "keys.mark": "Mark or unmark note for dd, a or :tag on all marked notes",
+    "keys.undo": "Undo the last change to notes (saving, deleting, tagging...)",
+    "keys.redo": "Redo the last undone change to notes",
+    "keys.history": "Show note history",
+    "keys.trash": "Show or hide the trash",
+    "keys.restore": "Restore note from trash",
+    "keys.archive": "Archive or unarchive note",
+    "keys.show_archive": "Show or hide the archive",
+    "keys.tasks": "Show the open tasks of every note",
+    "keys.outline": "Show the headings of the note to jump between them",
+    "keys.pin": "Pin or unpin note",
+    "keys.star": "Star or unstar note (favorites, without moving it)",
+    "keys.show_starred": "List only starred notes, or every note",
+    "keys.cycle_color": "Label note with the next color, or none after the last",
+    "keys.cycle_color_filter": "List only notes with the next color in use, or every note",
+    "keys.move_note_up": "Move note up (manual sort)",
+    "keys.move_note_down": "Move note down (manual sort)",
+    "keys.cycle_tag": "Cycle tag filter",
+    "keys.cycle_sort": "Cycle sort order",
+    "keys.toggle_notebook": "Expand or collapse notebook",
+    "keys.collapse_all": "Collapse all notebooks",
+    "keys.expand_all": "Expand all notebooks",
+    "keys.external_editor": "Edit note in external editor",
+    "keys.copy_note": "Copy the selected note's markdown to the system clipboard",
+    "keys.copy_note_text": "Copy the selected note's text, without markdown, to the system clipboard",
+    "keys.command": "Enter a command",
+    "keys.search": "Search forward",
+    "keys.search_backward": "Search backward",
+    "keys.next_match": "Next match",
+    "keys.previous_match": "Previous match",
+    "keys.quit": "Quit immediately",
+    "keys.help": "Show this list of keys",
+    "keys.fixed.insert": "Insert before or after the cursor",
+    "keys.fixed.open_line": "Open a line below or above",
+    "keys.fixed.normal": "Back to normal mode",
+    "keys.fixed.delete_char": "Delete the character under the cursor",
+    "keys.fixed.line": "Delete, copy or change the line",
+    "keys.fixed.word": "Delete, change or copy a word, or the word under the cursor",
+    "keys.fixed.line_end": "Delete or change to the end of the line",
+    "keys.fixed.paste": "Paste after or before the cursor",
+    "keys.fixed.undo": "Undo or redo",
+    "keys.fixed.visual": "Select characters or lines",
+    "keys.fixed.top": "Go to the first line",
+    "keys.fixed.scroll": "Scroll long lines sideways",
+
+    # Help overlay
+    "help.title": "Keys (j/k scroll, Esc closes)",
+    "help.navigation": "Navigation",
+    "help.editing": "Editing",
+    "help.notes": "Notes",
+    "help.search": "Search and commands",
+    "help.plugins": "Plugins",
+
+    # Console output during startup
+    "prompt.press_enter": "Press Enter to continue...",
+    "storage.key_file_empty": "Warning: Key file {path} is empty, regenerating",
+    "storage.key_file_read_failed": "Warning: Failed to read key file {path}: {error}",
+    "storage.generating_passphrase": "Generating new encryption passphrase...",
+    "storage.generated_passphrase": "✓ Generated passphrase: {passphrase}",
+    "storage.saved_to": "✓ Saved to {path}",
+    "storage.keep_passphrase_secure": "⚠️  Keep this passphrase secure! Write it down in a safe place.",
+    "storage.passphrase_required": "⚠️  Without it, encrypted notes cannot be decrypted.",
+    "storage.key_file_save_failed": "Warning: Failed to save key file: {error}",
+    "storage.decrypt_failed": "Warning: Failed to decrypt note {note_id}: {error}",
+    "storage.decrypt_failed_content": "[DECRYPTION FAILED: {error}]",
+    "storage.migrated_notes": "✓ Migrated {count} unencrypted note(s) to encrypted storage",
+    "storage.migrate_failed": "Warning: Failed to migrate notes: {error}",
+    "storage.enter_passphrase": "Passphrase for encrypted notes: ",
+    "storage.new_passphrase": "Choose a passphrase for encrypted notes: ",
+    "storage.confirm_passphrase": "Enter the passphrase again: ",
+    "storage.passphrase_empty": "The passphrase cannot be empty.",
+    "storage.passphrase_mismatch": "The passphrases do not match.",
+    "storage.passphrase_wrong": "Wrong passphrase.",
+    "storage.passphrase_attempts": "Too many failed attempts.",
+    "storage.git_missing": "Error: the git backend requires git, which was not found",
+    "storage.git_failed": "Error: could not set up the git repository: {error}",
+    "storage.git_pull_failed": "Warning: git pull failed: {error}",
+    "storage.git_push_failed": "Warning: git push failed: {error}",
+    "storage.sync_no_url": "Error: the sync backend needs the server's url in [storage.sync]",
+    "storage.copied_filesystem_notes": "Copied {count} notes from {path} into the SQLite database",
+    "storage.locked": "Error: these notes are open in another termnotes ({owner}). Close it first, so neither overwrites the other's changes, or share the notes with \"termnotes daemon\". (Lock file: {path})",
+    "storage.sync_failed": "Warning: could not sync, working offline: {error}",
+    "storage.push_failed": "Warning: could not push changes to the sync server, they stay queued: {error}",
+    "storage.e2e_new_passphrase": "Choose a passphrase for end-to-end encrypted sync (needed on each new device): ",
+    "storage.e2e_enter_passphrase": "Passphrase for end-to-end encrypted sync: ",
+    "storage.e2e_wrong_passphrase": "wrong passphrase for end-to-end encrypted sync",
+    "storage.e2e_locked": "notes are end-to-end encrypted and need the passphrase; restart termnotes in a terminal to enter it",
+    "storage.e2e_unknown_format": "the server's end-to-end encryption keys were written by a newer termnotes",
+    "storage.e2e_revoked": "this device ({device}) was revoked from end-to-end encrypted sync",
+    "storage.e2e_no_device": "no device {device} in end-to-end encrypted sync",
+    "storage.e2e_revoke_self": "this device can't revoke itself; revoke it from another device",
+    "storage.e2e_revoke_passphrase": "revoking a device needs a new passphrase, as the device knows the old one",
+    "storage.e2e_unknown_key": "note {note_id} is encrypted with a key this device doesn't have",
+    "storage.e2e_tampered": "note {note_id} can't be decrypted: it was changed on the server or encrypted with another passphrase",
+    "storage.e2e_unsealed": "note {note_id} on the server isn't encrypted, though it was changed after end-to-end encryption was turned on",
+    "storage.e2e_keyring_busy": "the end-to-end encryption keys were changed by another device at the same time; try again",
+    "storage.e2e_off": "End-to-end encrypted sync is off: set e2e = true in [storage.sync] and use the sync backend",
+    "storage.unknown_key_store": "Error: unknown key_store \"{store}\" in [storage.encrypted] (use \"file\" or \"keyring\")",
+    "storage.saved_to_keyring": "✓ Saved to the system keyring",
+    "storage.key_file_moved": "✓ Moved the passphrase from {path} to the system keyring; delete the file once you have a copy elsewhere.",
+    "secret.no_keyring": "The system keyring needs the keyring package: pip install keyring",
+    "spell.no_library": "Spellcheck needs the pyspellchecker package: pip install 'termnotes[spellcheck]'",
+    "spell.no_dictionary": "No spellcheck dictionary for {languages}; add a word list named after the language to {directory}",
+    "spell.load_failed": "Couldn't load the word list {path}: {error}",
+    "secret.store_failed": "Error: the system keyring could not store {name}: {error}",
+    "secret.delete_failed": "Error: the system keyring could not remove {name}: {error}",
+    "secret.sync": "Token for the sync server ([storage.sync] token)",
+    "secret.webdav": "WebDAV password ([storage.webdav] password)",
+    "secret.postgres": "PostgreSQL password ([storage.postgres] password)",
+    "secret.encryption": "Passphrase of the encrypted backend (with key_store = \"keyring\")",
+    "secret.server": "Token \"termnotes serve\" requires ([server] token)",
+    "secret.github": "GitHub token for :share gist ([share] token)",
+    "secret.paste": "Paste service token ([share] paste_token)",
+    "secret.openai": "API key for the openai AI provider ([ai] api_key)",
+    "storage.webdav_no_url": "Error: the webdav backend needs the folder's url in [storage.webdav]",
+    "storage.webdav_http": "WebDAV server error at {url}: HTTP {status} {reason}",
+    "storage.webdav_unreachable": "Could not reach the WebDAV server at {url}: {error}",
+    "storage.webdav_bad_listing": "The WebDAV server at {url} sent a folder listing that couldn't be read",
+    "storage.postgres_no_dsn": "Error: the postgres backend needs a connection string in [storage.postgres] dsn",
+    "storage.postgres_no_driver": "Error: the postgres backend needs the psycopg package: pip install 'psycopg[binary]'",
+    "storage.postgres_connect_failed": "Could not connect to the PostgreSQL database: {error}",
+    "storage.postgres_newer_schema": "The PostgreSQL database was set up by a newer termnotes (schema version {version}); upgrade termnotes to use it",
+    "storage.sqlite_newer_schema": "The database {path} was set up by a newer termnotes (schema version {version}); upgrade termnotes to use it",
+    "storage.note_format_newer": "The note file {path} was written by a newer termnotes (format version {version}); upgrade termnotes to read it",
+    "storage.cancelled": "The operation was cancelled",
+    "storage.deadline_exceeded": "The operation took too long and was stopped",
+    "storage.write_failed": "Could not write a change to storage, will retry: {error}",
+    "storage.conflict": "\"{title}\" was changed by someone else since it was loaded",
+    "storage.invalid_note_id": "Invalid note ID: {note_id!r}",
+    "storage.notebook_outside": "Notebook {notebook!r} would be outside the notes directory",
+    "storage.read_only": "The notes are open read-only",
+    "backup.invalid": "{path} is not a termnotes backup, or is from a newer version",
+    "archive.invalid": "{path} is not a termnotes archive, or is from a newer version",
+    "server.listening": "Sync server listening on http://{host}:{port}, notes in {path}",
+    "server.no_token": "Warning: no token set; anyone who can reach the server can read and change notes",
+    "server.feed": "Atom feed of recent notes at http://{host}:{port}/v1/feed.atom",
+    "server.grpc": "gRPC API (termnotes.v1.Notes) listening on {host}:{port}",
+    "server.grpc_no_package": "Error: the gRPC API needs the grpcio package: pip install 'termnotes[grpc]'",
+    "server.grpc_bind_failed": "Error: the gRPC API can't listen on {host}:{port}",
+    "daemon.listening": "Holding the notes for editors and commands on {path} (Ctrl+C or \"termnotes daemon --stop\" to stop)",
+    "daemon.stopped": "Daemon stopped",
+    "daemon.not_running": "No daemon is running",
+    "daemon.start_failed": "Error: the daemon didn't start listening on {path}; run \"termnotes daemon\" to see why",
+    "daemon.disconnected": "The daemon on {path} is gone; restart the editor",
+    "daemon.timeout": "The daemon took too long to answer ({method})",
+    "daemon.unsupported": "Error: the daemon needs unix sockets, which this system doesn't have",
+    "mcp.instructions": "These are the user's personal notes, in markdown; each starts with a \"# Title\" heading, and [[Title]] links to another note. Search or list them to find IDs, then read a note for its full text.",
+
+    # Built-in note templates
+    "template.meeting": """# {{title}}
+
+**Date:** {{date}} {{time}}
+**Attendees:**
+
+## Agenda
+- {{cursor}}
+
+## Notes
+
+## Action items
+- [ ]
+""",
+    "template.todo": """# {{title}}
+
+- [ ] {{cursor}}
+""",
+    "template.journal": """# {{weekday}}, {{date}}
+
+{{cursor}}
+""",
+
+    # First-run tour ({keys} are the current keys of each action)
+    "tour.title": "Tour",
+    "tour.footer": "{step}/{total}  Enter: next  Backspace: back  Esc: skip",
+    "tour.welcome.title": "Welcome to termnotes",
+    "tour.welcome": "Notes are markdown, kept on your machine (or wherever [storage] in the config points). This tour shows the keys you need first; it takes a minute.",
+    "tour.list.title": "The note list",
+    "tour.list": "The note list is on the left. {down} and {up} move through it, {open} opens the selected note and {new_note} starts a new one. {toggle_focus} switches between the list and the editor.",
+    "tour.editor.title": "Writing",
+    "tour.editor": "The editor works like vim: {edit} starts typing, Esc goes back to normal mode, and {command}w saves the note ({command}q quits). Unsaved edits are kept in a draft if the terminal closes.",
+    "tour.find.title": "Finding things",
+    "tour.find": "{search} in the note list searches every note; in the editor it highlights matches in the note, and {next_match} jumps between them. {outline} lists a note's headings and {tasks} the open tasks of every note.",
+    "tour.help.title": "Getting help",
+    "tour.help": "{help} lists every key, and the welcome note describes every feature. {command}tour shows this tour again. Happy note-taking!",
+
+    # Demo notes added by --seed-demo
+    "demo.markdown": """# Markdown tour
+
+termnotes styles markdown as you type. This note shows what it knows.
+
+## Text
+
+Make words **bold**, *italic*, ~~struck out~~ or `code`.
+
+> Quotes look like this.
+
+## Lists
+
+- Bullets with `-`
+  - and nested ones
+1. Numbered items
+2. count up
+
+## Links
+
+Link to another note by its title: [[Project plan]]. Press Enter on the link to open it.
+Web links work too: <https://github.com/bjia56/termnotes>
+
+## Code
+
+```python
+def greet(name):
+    return f"Hello, {name}!"
+```
+
+| Key | Does |
+|-----|------|
+| `/` | Search |
+| `?` | Help |
+""",
+    "demo.project": """# Project plan
+
+This note is in the "demo/project" notebook, tagged #work, and due in three days.
+Press O in the note list to jump between its headings.
+
+## Goals
+
+- Ship the first version
+- Write the docs
+
+## Timeline
+
+1. Draft the design
+2. Build it
+3. Test with a few users
+
+## Open questions
+
+- Which platforms come first?
+
+See [[Shopping list]] for the tasks.
+""",
+    "demo.tasks": """# Shopping list
+
+Press Space on a task in the editor to check it off, or X in the note list to see the open tasks of every note.
+
+- [ ] Milk
+- [ ] Bread
+- [x] Coffee
+- [ ] A notebook for offline notes
+
+Back to [[Project plan]].
+""",
+
+    # First-run welcome note
+    "welcome.content": """# Welcome to termnotes!
+
+A vim-like terminal note-taking application with markdown support.
+
+## Quick Start Guide
+
+### Navigation
+- `Ctrl+W h` - Switch to sidebar
+- `Ctrl+W l` - Switch to editor
+- `Tab` - Switch between the note list and the editor; the focused pane's title line is highlighted
+- `?` (note list) / `F1` / `:help` - List every key
+- `Ctrl+P` - Jump to a note: the other notes are listed most recently viewed first (`Ctrl+P` `Enter` goes back to the previous note), and typing filters them by title
+- `j/k` - Move down/up (in both sidebar and editor)
+- `h/l` - Move left/right in editor
+- `w/b/e` - Move by word in editor (`Ctrl+Left/Right` in Insert mode, `Ctrl+W` deletes a word)
+
+### Creating Notes
+- `:new` or `:n` - Create new empty note
+- `o` - Create new note (when sidebar is focused)
+- `T` / `:template <name> [title]` - Create a note from a template (meeting, todo, journal, or your own)
+- `:split` - Split the current note at its top-level headings into one note per section, leaving it as an index linking to them
+
+### Pinning and Ordering
+- `p` - Pin the selected note to the top of the list, or unpin it (`:pin` / `:unpin` for the current note)
+- `*` - Star the selected note as a favorite, or unstar it, without moving it (`:star` / `:unstar` for the current note); `S` / `:starred` lists only the starred notes (`+` marks them)
+- `C` - Label the selected note with the next color: red, yellow, green, cyan, blue, magenta, then none (`:color <name>` for the current note, `:color` alone clears it); a colored bullet shows it in the list, and `F` / `:colorfilter <name>` lists only notes with a color
+- With `sort = "manual"` in the config, `J/K` move the selected note down/up
+- `s` - Sort the list by the next order: last updated, newest, title, manual, due date (the selected note stays selected)
+
+### Archive
+- `a` - Archive the selected note, taking it out of the list without deleting it (`a` again in the archive brings it back)
+- `A` / `:archived` - Show or hide the archived notes; `:archive` / `:unarchive` act on the current note
+
+### Tasks
+- `X` / `:tasks` - List the open `- [ ]` tasks of every note; `Space` checks one off in its note, `Enter` opens the note at it, `Tab` shows done tasks too
+- `Space` (or `Enter` on a task line) in the editor - Check or uncheck the task under the cursor and save the note
+
+### Due Dates and Reminders
+- `:due friday` - Set the current note's due date (also `2026-10-20`, `tomorrow`, `+3d`); `:due` alone clears it
+- `:remind 14:30` - Get a reminder at that time (also `+2h`, `2026-10-20 09:00`); `:remind` alone clears it
+- The list shows when notes are due, and `overdue` in red; `sort = "due"` lists the soonest first
+- `termnotes agenda` prints the overdue notes and the ones due this week, and `termnotes export --ics notes.ics` puts them in a calendar file
+
+### Deleting Notes
+- `dd` - Move the selected note to the trash (when sidebar is focused, after confirming)
+- `:delete` or `:d` - Delete current note (confirms with second :d)
+- `:d!` - Force delete current note without confirmation
+- Deleted notes go to the trash: `t` shows it (when sidebar is focused), `r` there restores the selected note
+- Deleting a note in the trash removes it for good; `:emptytrash` deletes everything in the trash
+- `Space` - Mark the selected note; `dd`, `a` and `:tag`/`:untag` then act on every marked note (`Esc` unmarks them)
+- `u` / `Ctrl+R` - Undo or redo the last change to notes, even a permanent delete (when sidebar is focused)
+
+### Editing
+- `i` - Enter Insert mode
+- `Esc` - Return to Normal mode
+- `dd` - Delete current line (when editor is focused)
+- `yy` / `p` - Copy the current line / paste it below (through the system clipboard, so `p` also pastes what other programs copied); `dw`, `cw`, `diw`, `ciw`, `yiw` and `D`/`C` act on words and the rest of the line
+- `y` / `Y` in the note list - Copy the selected note's markdown / its text without markdown to the system clipboard; `Ctrl+V` pastes the clipboard in insert mode, attaching an image (a screenshot) and linking it
+- `v` / `V` - Select characters / lines, then `d`, `y` or `c`
+- Set `editing = "simple"` in `[ui]` to type as soon as the editor has focus (`Esc` goes back to the note list)
+- `o` - Insert new line below (when editor is focused)
+- `O` - Insert new line above
+- `E` - Edit the note in your external editor ($VISUAL or $EDITOR)
+- `:table` - Start a table, or line up the pipes of the one under the cursor; while typing in a table, `Tab`/`Shift+Tab` move between cells and `Enter` to the row below, keeping it aligned, and `:table row` / `:table column` add a row or column
+- The status bar shows the selected note's words, characters and reading time; run `termnotes stats` for totals across all notes
+- `:zen` / `Ctrl+W o` - Zen mode for long-form writing: only the note, centered (`zen_width` in `[ui]`), with a word count below
+- `:preview` / `Ctrl+W p` - Live preview: the note rendered next to the editor as you type (`live_preview` in `[ui]` to start with it)
+- `:wrap` / `zw` - Wrap long lines onto the rows below instead of scrolling them sideways (`wrap` in `[ui]` to start with it)
+
+### Searching
+- `/` - Search inside the current note (editor) or across all notes (sidebar)
+- In the editor, every match is highlighted as you type and the status bar counts them; `Esc` or `:noh` clears the highlighting
+- In the sidebar, the list narrows as you type, with fuzzy matches (`mtg` finds "Meeting") after whole words and a preview of the selected note
+- `n/N` - Jump to next/previous match; `Esc` closes sidebar results
+- `O` / `:outline` - List the headings of the selected / current note; `j/k` scroll the note to each section, `Enter` moves the cursor there, `Esc` goes back
+- `:replaceall /old/new/` - Replace text in every note after reviewing the changes (`Space` skips a note, `Enter` replaces); add `r` at the end for a regular expression
+
+### Tags
+- `:tag <name>` / `:untag <name>` - Add/remove a tag on the current note
+- `:tags` - List all tags
+- `#` - Cycle the sidebar through notes with each tag (when sidebar is focused)
+- `:filter <name>` / `:filter` - Show only notes with a tag / show all notes
+
+### Notebooks
+- `:notebook <path>` - Create a notebook (use `work/projects` to nest)
+- `:move <path>` / `:move` - Move the current note into a notebook / to the top level
+- `:notebooks` - List all notebooks
+- `Enter` / `za` - Expand or collapse the selected notebook (when sidebar is focused)
+- `zM` / `zR` - Collapse / expand all notebooks
+
+### History
+- `h` / `:history` - Show the saved versions of the selected / current note
+- In the history, `j/k` select a version and show what it changed, `Enter` restores it, `Esc` closes
+
+### Attachments
+- `:attach <path>` - Attach a copy of a file to the current note
+- `:attachments` - List the current note's attachments
+- `:open <n>` / `:detach <n>` - Open an attachment with the system's default program / remove it
+- `Enter` on an image link / `:image <n>` - Show the image (in terminals with kitty, iTerm2 or sixel graphics)
+
+### Vim Commands
+- `:w` - Save current note; if it changed elsewhere meanwhile, choose to merge, overwrite or discard your edits
+- `:e!` - Discard changes and reload
+- `:q` - Quit (prompts if unsaved changes); `:q!` quits without saving
+- Unsaved edits are copied to a draft every few seconds; if the terminal closes before you save, termnotes offers to restore them on the next start
+- `:recover` - Salvage what can be read of damaged note files into notes tagged #recovered (also offered on start)
+- `:wq` - Save and quit
+- `:sync` - Exchange changes with the sync server (with the sync storage backend)
+- `:lock` - Hide the notes until the passphrase is typed (with `enabled = true` in `[lock]`)
+- `:secure` / `:unsecure` - Encrypt the current note at rest, behind its own passphrase / store it as plain text again; `:unlock` asks for the passphrase to read secure notes, `:lock` locks them again
+- `:summarize`, `:gentitle`, `:suggesttags` - Have a language model summarize, title or tag the note (off until an `[ai]` provider is set)
+- `:share` - Upload the selected note as a GitHub gist (or to a paste service, `:share paste`) and copy its URL
+
+### Custom Keys
+- Remap keys in the `[keys]` section of ~/.termnotes/config.toml (e.g. `new_note = "n"`)
+- `termnotes --print-keys` lists every action and its keys
+- `:tour` shows the first-run tour again; `termnotes --seed-demo` adds a few demo notes to try things on
+- `termnotes --readonly` opens the notes read-only, for browsing without changing anything
+- `enabled = true` in `[lock]` asks for a passphrase on launch and, with `idle_minutes`, after leaving termnotes idle
+- `termnotes backup` saves every note to a .tar.gz file and `termnotes restore <file>` brings them back; one is also saved before bulk changes like emptying the trash
+- `termnotes import --from joplin notes.jex` and `termnotes export --jex notes.jex` move notes from and to Joplin
+- `termnotes daemon` lets editors in several terminals work on the same notes at once
+- `e2e = true` in `[storage.sync]` encrypts notes before they reach the sync server; `termnotes e2e devices` lists the devices that can read them
+- `termnotes mcp` lets AI assistants such as Claude Desktop search and read notes (`--write` to let them add notes too)
+- `termnotes publish --out ./site` writes notes as a static website with [[wiki links]] as hyperlinks (`--notebook` or `--tag` to pick some)
+- `termnotes export --archive notes.tnx` packs every note and attached file into one file; `termnotes import --from archive notes.tnx` unpacks it on another machine or backend
+- `colors = "light"` (or `dark`, `solarized`, `gruvbox`) in `[ui]` changes the color theme, and a `[colors]` section recolors single elements
+- Python plugins in ~/.termnotes/plugins/ add commands, keys and `:transform`s; `:plugins` lists them
+- A `[hooks]` section runs shell commands when notes are created, updated or deleted (e.g. `note_updated = "~/bin/backup"`)
+
+## Code Highlighting Example
+
+termnotes supports syntax highlighting for code blocks fenced with ``` or ~~~ and a language name, highlighted as you type them:
+
+```python
+def fibonacci(n):
+    \"\"\"Calculate the nth Fibonacci number\"\"\"
+    if n <= 1:
+        return n
+    return fibonacci(n - 1) + fibonacci(n - 2)
+
+# Generate first 10 Fibonacci numbers
+for i in range(10):
+    print(f"F({i}) = {fibonacci(i)}")
+```
+
+Happy note-taking!
+""",
+}
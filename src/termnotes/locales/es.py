@@ -0,0 +1,995 @@
+"""
+Spanish message catalog
+"""
+
+MESSAGES = {
+    # Command line
+    "cli.description": "Una aplicación de notas para la terminal al estilo de vim",
+    "cli.print_config_help": "Mostrar una configuración de ejemplo y salir",
+    "cli.accessible_help": "Usar el modo accesible compatible con lectores de pantalla",
+    "cli.no_alt_screen_help": "No cambiar a la pantalla alternativa de la terminal",
+    "cli.print_keys_help": "Mostrar los atajos de teclado y salir",
+    "cli.backend_help": "Almacenamiento a usar en lugar del configurado",
+    "cli.notes_path_help": "Dónde guarda las notas el almacenamiento: archivo de base de datos, directorio o carpeta de Google Drive",
+    "cli.theme_help": "Colores de los bloques de código: \"auto\", \"ansi\" o un estilo de Pygments (p. ej. monokai)",
+    "cli.colors_help": "Colores de la interfaz: auto, dark, light, solarized o gruvbox (o una variante -dark/-light)",
+    "cli.sidebar_width_help": "Ancho de la lista de notas en columnas, o fracción del ancho de la terminal (p. ej. 0.3)",
+    "cli.sort_help": "Orden de la lista de notas",
+    "cli.debug_help": "Escribir un registro de depuración (por defecto ~/.termnotes/termnotes.log) para adjuntar a los informes de errores",
+    "cli.seed_demo_help": "Añadir unas notas de ejemplo (en un cuaderno \"demo\") y mostrar el recorrido",
+    "cli.readonly_help": "Abrir las notas en solo lectura, para verlas sin cambiar nada",
+    "cli.debug_logging": "Escribiendo el registro de depuración en {path}",
+    "cli.debug_log_failed": "No se pudo abrir el registro de depuración: {error}",
+    "cli.add_help": "Crear una nota desde la entrada estándar",
+    "cli.add_description": "Lee el contenido de la nota desde la entrada estándar (p. ej. make 2>&1 | termnotes add --title \"Registro\") y muestra el ID de la nota",
+    "cli.add_title_help": "Título de la nota nueva, añadido como encabezado sobre el contenido",
+    "cli.add_append_help": "Añadir al final de la nota con este ID en lugar de crear una",
+    "cli.add_no_input": "Error: no hay entrada; pasa el contenido de la nota a termnotes add con una tubería",
+    "cli.add_note_not_found": "Error: no hay ninguna nota con el ID {note_id}",
+    "cli.serve_help": "Ejecutar un servidor de sincronización para otras máquinas",
+    "cli.serve_description": "Guarda las notas de forma central para las máquinas que usan el almacenamiento sync (ajustes en la sección [server] de la configuración)",
+    "cli.serve_host_help": "Dirección en la que escuchar",
+    "cli.serve_port_help": "Puerto en el que escuchar",
+    "cli.serve_token_help": "Token que deben enviar los clientes",
+    "cli.serve_feed_help": "Servir también un feed Atom de las notas cambiadas hace poco (ver [server] feed)",
+    "cli.serve_grpc_help": "Servir también la API gRPC (ver [server] grpc)",
+    "cli.serve_grpc_port_help": "Puerto de la API gRPC (implica --grpc)",
+    "cli.mcp_help": "Dejar que los asistentes de IA busquen y lean notas por MCP",
+    "cli.mcp_description": "Habla el Model Context Protocol por la entrada y salida estándar, para que clientes como Claude Desktop lo inicien como servidor; pueden buscar, listar y leer notas, y con --write crear notas y añadirles texto (ajustes en la sección [mcp] de la configuración)",
+    "cli.mcp_write_help": "Dejar que los clientes creen notas y les añadan texto",
+    "cli.daemon_help": "Mantener las notas para varios editores a la vez",
+    "cli.daemon_description": "Servir las notas en un socket unix para que editores y comandos en varias terminales puedan cambiarlas a la vez y ver los cambios de los demás (ajustes en la sección [daemon] de la configuración)",
+    "cli.daemon_stop_help": "Detener el daemon en marcha",
+    "cli.daemon_idle_timeout_help": "Salir tras estos segundos sin editores ni comandos conectados (0 para seguir en marcha)",
+    "cli.e2e_help": "Gestionar las claves de la sincronización cifrada de extremo a extremo",
+    "cli.e2e_description": "Listar los dispositivos que pueden leer las notas sincronizadas, cifrarlas con una clave nueva o revocar un dispositivo para que no pueda leer las notas escritas a partir de ahora (con e2e = true en [storage.sync]; ningún editor ni daemon puede tener las notas abiertas)",
+    "cli.e2e_action_help": "devices: listar los dispositivos; rotate: cifrar con una clave nueva; revoke: quitar un dispositivo y cambiar la frase de contraseña",
+    "cli.e2e_device_help": "ID del dispositivo a revocar, como lo lista \"termnotes e2e devices\"",
+    "cli.e2e_no_device": "Error: indica el ID del dispositivo a revocar (ver \"termnotes e2e devices\")",
+    "cli.e2e_rotated": "Las notas se cifran ahora con una clave nueva; los demás dispositivos la obtienen en su próxima sincronización.",
+    "cli.e2e_revoked": "Dispositivo {device} revocado; los demás dispositivos piden la nueva frase de contraseña una vez para obtener la clave nueva.",
+    "cli.e2e_change_passphrase": "El dispositivo revocado conoce la frase de contraseña, así que elige una nueva; los demás dispositivos la pedirán una vez.",
+    "cli.secret_help": "Guardar contraseñas y tokens en el llavero del sistema",
+    "cli.secret_description": "Listar, guardar o quitar las contraseñas y tokens que termnotes guarda en el llavero del sistema (Llavero de macOS, Secret Service, Administrador de credenciales de Windows; necesita el paquete keyring). Un secreto guardado se usa cuando su ajuste en la configuración está vacío.",
+    "cli.secret_action_help": "list: mostrar cuáles están guardados; set: guardar uno (se pide, o se lee de stdin); delete: quitar uno",
+    "cli.secret_name_help": "Secreto: {names}",
+    "cli.secret_no_name": "Error: indica el nombre del secreto (ver \"termnotes secret list\")",
+    "cli.secret_prompt": "Valor de {name}: ",
+    "cli.secret_empty": "Error: el secreto no puede estar vacío",
+    "cli.secret_stored": "{name} guardado en el llavero del sistema; deja vacío su ajuste en el archivo de configuración.",
+    "cli.secret_deleted": "{name} quitado del llavero del sistema.",
+    "cli.secret_not_stored": "No hay ningún secreto {name} en el llavero del sistema",
+    "cli.mcp_notebook_help": "Limitar los clientes a las notas de este cuaderno y de los que contiene (se puede repetir; las notas nuevas van al primero)",
+    "cli.export_help": "Exportar notas a archivos markdown, HTML o PDF",
+    "cli.export_description": "Escribe las notas (todas salvo las de la papelera, o las indicadas con --note) en archivos con el nombre de su título, con los cuadernos como subdirectorios",
+    "cli.export_format_help": "Formato de archivo (por defecto: md)",
+    "cli.export_note_help": "Exportar solo la nota con este ID (se puede repetir)",
+    "cli.export_output_help": "Directorio de destino (por defecto: el directorio actual)",
+    "cli.export_archive_help": "Escribir en su lugar un único archivo (como notes.tnx) con las notas, incluidas las de la papelera, y sus archivos adjuntos, para importarlo en otra máquina o backend",
+    "cli.export_jex_help": "Escribir en su lugar un archivo de exportación de Joplin (como notes.jex) con las notas, sus cuadernos, etiquetas y archivos adjuntos, para importarlo en Joplin",
+    "cli.export_ics_help": "Escribir en su lugar un archivo de calendario (como notes.ics) con un evento por cada nota con fecha de vencimiento o recordatorio, y un evento de todo el día por cada nota con una fecha en el título",
+    "cli.export_failed": "Error: la exportación falló: {error}",
+    "cli.publish_help": "Publicar las notas como un sitio HTML estático",
+    "cli.publish_description": "Escribe una página por nota (todas menos las de la papelera, o las elegidas con --notebook, --tag y --note), con los [[enlaces wiki]] como hipervínculos, y un índice de ellas por cuaderno",
+    "cli.publish_out_help": "Directorio donde escribir el sitio",
+    "cli.publish_notebook_help": "Publicar las notas de este cuaderno y de los que contiene (se puede repetir)",
+    "cli.publish_tag_help": "Publicar las notas con esta etiqueta (se puede repetir)",
+    "cli.publish_note_help": "Publicar la nota con este ID (se puede repetir)",
+    "cli.publish_title_help": "Título del sitio, mostrado en cada página (por defecto: Notas)",
+    "cli.publish_done": "Se publicaron {count} notas; abre {path}",
+    "cli.import_help": "Importar notas de otra aplicación",
+    "cli.import_description": "Añade notas desde los archivos de otra aplicación, conservando sus títulos, etiquetas y fechas; las carpetas de archivos markdown se convierten en cuadernos",
+    "cli.import_from_help": "De dónde vienen los archivos: \"obsidian\" (una bóveda; títulos a partir del nombre de archivo, #etiquetas en línea), \"markdown\" (archivos .md, .markdown y .txt), \"enex\" (un archivo exportado de Evernote) \"joplin\" (un archivo JEX exportado de Joplin; se conservan cuadernos, etiquetas y archivos adjuntos), \"notion\" (un ZIP exportado de Notion o su directorio; las filas de las bases de datos se convierten en notas etiquetadas), \"keep\" (un ZIP de Google Takeout o su directorio; las etiquetas de Keep se conservan), \"apple-notes\" (una carpeta de notas exportadas de Apple Notes) o \"archive\" (un archivo de termnotes creado con export --archive, que se conserva tal cual, con sus IDs)",
+    "cli.import_notebook_help": "Poner las notas importadas en este cuaderno",
+    "cli.import_path_help": "Directorio o archivo a importar",
+    "cli.import_failed": "Error: la importación falló: {error}",
+    "cli.import_done": "Se importaron {count} notas de {path}",
+    "cli.import_skipped": "Se omitieron {count} notas que ya están guardadas; elimínalas para importarlas de nuevo",
+    "cli.backup_help": "Escribir una copia de seguridad de todas las notas en un archivo tar.gz",
+    "cli.backup_description": "Escribir todas las notas, incluidas las de la papelera y las archivadas, y sus archivos adjuntos en un archivo tar.gz con fecha y hora, e imprimir su ruta.",
+    "cli.backup_output_help": "Directorio donde escribir la copia (por defecto: el directorio de [backup])",
+    "cli.backup_failed": "Error: falló la copia de seguridad: {error}",
+    "cli.restore_help": "Recuperar las notas de una copia de seguridad",
+    "cli.restore_description": "Guardar las notas y los archivos adjuntos de una copia escrita por \"termnotes backup\", reemplazando las notas con los mismos ID. Las demás notas no se tocan, y antes se escribe una copia de las notas tal como están.",
+    "cli.restore_path_help": "Archivo de copia de seguridad (.tar.gz)",
+    "cli.restore_failed": "Error: falló la restauración: {error}",
+    "cli.restore_done": "Se restauraron {count} notas de {path}",
+    "cli.stats_help": "Mostrar el recuento de palabras y otras estadísticas de tus notas",
+    "cli.stats_description": "Muestra el total de notas, palabras y caracteres, el número de notas con cada etiqueta y cuántas notas se crearon cada mes",
+    "cli.stats_notes": "Notas: {count} ({archived} archivadas, {trashed} en la papelera)",
+    "cli.stats_words": "Palabras: {words} (unos {minutes} min de lectura)",
+    "cli.stats_characters": "Caracteres: {characters}",
+    "cli.stats_tags": "Notas por etiqueta:",
+    "cli.stats_growth": "Notas creadas por mes (total):",
+    "cli.agenda_help": "Listar las notas vencidas o que vencen pronto",
+    "cli.agenda_description": "Muestra las notas cuya fecha límite ha pasado y las que vencen en los próximos días; sale con código 1 si alguna nota está vencida",
+    "cli.agenda_days_help": "Cuántos días hacia delante mirar (por defecto: [reminders] upcoming_days)",
+    "cli.agenda_overdue": "Vencidas:",
+    "cli.agenda_upcoming": "Vencen en los próximos {days} día(s):",
+    "cli.agenda_empty": "Nada vence en los próximos {days} día(s)",
+
+    # Mode and focus indicators
+    "mode.insert": "-- INSERTAR --",
+    "mode.visual": "-- VISUAL --",
+    "mode.visual_line": "-- VISUAL LÍNEA --",
+    "focus.sidebar": "LISTA",
+    "focus.editor": "EDITOR",
+    "focus.history": "HISTORIAL",
+    "focus.templates": "PLANTILLAS",
+    "focus.replace": "REEMPLAZAR",
+    "focus.tasks": "TAREAS",
+    "focus.outline": "ESQUEMA",
+    "focus.help": "AYUDA",
+    "focus.tour": "RECORRIDO",
+    "focus.switcher": "CAMBIAR",
+    "pane.notes": "Notas",
+    "pane.preview": "Vista previa",
+
+    # Sidebar and status bar
+    "note.empty_preview": "(nota vacía)",
+    "due.overdue": "vencida",
+    "due.today": "hoy",
+    "due.tomorrow": "mañana",
+    "age.now": "ahora",
+    "age.minutes": "{count}m",
+    "age.hours": "{count}h",
+    "age.days": "{count}d",
+    "ago.now": "justo ahora",
+    "ago.minutes": "hace {count} min",
+    "ago.hours": "hace {count} h",
+    "ago.days": "hace {count} d",
+    "ago.date": "el {date}",
+    "metadata.created": "Creada {date}",
+    "metadata.edited": "editada {ago}",
+    "metadata.words": "{count} palabras",
+    "status.notes": "{listed} de {total} notas",
+    "status.notes_all": "{count} notas",
+    "status.sort": "orden: {order}",
+    "status.encrypted": "{backend}, cifrado",
+    "status.daemon": "{backend} vía daemon",
+    "status.saved": "guardado {ago}",
+    "status.synced": "sincronizado",
+    "status.unsynced": "{count} sin sincronizar",
+    "export.timestamps": "Creada {created} · Actualizada {updated}",
+    "site.title": "Notas",
+    "site.backlinks": "Enlazada desde",
+    "site.empty": "No se publicaron notas.",
+    "share.unknown_service": "Servicio para compartir desconocido: {service} (usa {services})",
+    "share.no_token": "Compartir un gist necesita un token de GitHub: pon [share] token o guárdalo en el llavero (keyring set termnotes github)",
+    "share.no_paste_url": "No hay ningún servicio de pegado configurado ([share] paste_url)",
+    "share.bad_response": "{url} no respondió con la URL de la nota",
+    "ai.bad_response": "{url} envió una respuesta que no se pudo leer",
+    "ai.no_command": "El proveedor command necesita un programa en [ai] command",
+    "ai.empty_note": "La nota está vacía",
+    "ai.no_answer": "El modelo no dio ninguna respuesta",
+    "ai.summary_label": "Resumen:",
+    "import.attachment": "[adjunto: {name}]",
+    "import.invalid_jex": "{path} no es un archivo exportado de Joplin (JEX)",
+    "import.invalid_notion": "{path} no es una exportación de Notion (un archivo ZIP, o el directorio donde se extrajo)",
+    "import.invalid_keep": "{path} no es una exportación de Google Takeout (un archivo ZIP, o el directorio donde se extrajo)",
+    "indicator.new": "[NUEVA]",
+    "indicator.note_stats": "{words} palabras, {characters} car., {minutes} min de lectura",
+    "indicator.zen_words": "{words} palabras",
+    "indicator.pinned": "^",
+    "indicator.starred": "+",
+    "indicator.reminder": "@",
+    "indicator.secure": "🔒",
+    "indicator.trash": "[PAPELERA]",
+    "indicator.read_only": "[SOLO LECTURA]",
+    "indicator.archive": "[ARCHIVO]",
+    "indicator.starred_view": "[FAVORITAS]",
+    "indicator.color_filter": "[● {color}]",
+    "indicator.table": "[TABLA]",
+
+    # Color labels
+    "color.red": "rojo",
+    "color.yellow": "amarillo",
+    "color.green": "verde",
+    "color.cyan": "cian",
+    "color.blue": "azul",
+    "color.magenta": "magenta",
+
+    "indicator.marked": "*",
+    "links.linked_from": "Enlazada desde:",
+    "links.more": "…y {count} más",
+    "images.placeholder": "[imagen: {name}, {size}]",
+
+    # Accessible mode labels
+    "a11y.pane_notes": "Lista de notas, {count} notas",
+    "a11y.pane_editor": "Editor, {title}",
+    "a11y.pane_history": "Historial de {title}",
+    "a11y.pane_templates": "Plantillas, {count} plantillas",
+    "a11y.pane_replace": "Reemplazar {pattern} por {replacement}, {count} notas",
+    "a11y.pane_tasks": "Tareas, {count} en la lista",
+    "a11y.pane_outline": "Esquema, {count} encabezados",
+    "a11y.pane_help": "Atajos de teclado, Escape cierra",
+    "a11y.help_lines": "Líneas {start} a {end} de {total}",
+    "a11y.pane_tour": "Recorrido, Intro para el siguiente paso, Escape lo salta",
+    "a11y.tour_step": "Paso {step} de {total}",
+    "a11y.pane_switcher": "Ir a la nota: {query}",
+    "a11y.switcher_note": "{index} de {total}: {title}",
+    "a11y.dialog": "{question} {choices}",
+    "a11y.mode_normal": "Modo normal",
+    "a11y.mode_insert": "Modo insertar",
+    "a11y.mode_visual": "Modo visual",
+    "a11y.mode_visual_line": "Modo visual de líneas",
+    "a11y.command": "Comando {command}",
+    "a11y.position": "Línea {row} de {total}, columna {col}",
+    "a11y.note_position": "Elemento {index} de {total}",
+    "a11y.notebook_expanded": "Cuaderno {path}, {count} notas, expandido",
+    "a11y.notebook_collapsed": "Cuaderno {path}, {count} notas, contraído",
+    "a11y.modified": "Modificada",
+    "a11y.note_stats": "{words} palabras, {characters} caracteres, unos {minutes} minutos de lectura",
+    "a11y.new_note": "Nota nueva, sin guardar",
+    "a11y.selection": "{count} línea(s) seleccionada(s)",
+    "a11y.tag_filter": "Filtrado por la etiqueta {tag}",
+    "a11y.color_filter": "Filtrado por el color {color}",
+    "a11y.trash": "Mostrando la papelera",
+    "a11y.read_only": "Solo lectura",
+    "a11y.archive": "Mostrando el archivo",
+    "a11y.search_results": "Resultados de búsqueda de {query}, {count} notas",
+    "a11y.search_match": "Coincidencia: {snippet}",
+    "a11y.revision": "Versión {index} de {total}, {revision}",
+    "a11y.template": "Plantilla {index} de {total}, {name}",
+    "a11y.replacement": "Nota {index} de {total}, {title}, {count} coincidencias",
+    "a11y.replacement_skipped": "Nota {index} de {total}, {title}, {count} coincidencias, omitida",
+    "a11y.task": "Tarea {index} de {total}, pendiente, {task}, en {title}",
+    "a11y.task_done": "Tarea {index} de {total}, hecha, {task}, en {title}",
+    "a11y.heading": "Encabezado {index} de {total}, nivel {level}, {heading}",
+
+    # Status messages
+    "msg.note_saved": "Nota guardada",
+    "toast.save_failed": "No se pudo guardar la nota, tus cambios se conservan: {error}",
+    "toast.draft_restored": "Cambios sin guardar restaurados; :w los guarda",
+    "toast.read_from_backup": "{count} archivo(s) de nota dañado(s) se leyeron de sus copias de seguridad",
+    "toast.draft_failed": "No se pudo escribir el borrador de tus cambios: {error}",
+    "msg.no_note_loaded": "No hay ninguna nota cargada",
+    "msg.new_unsaved_load": "¡Nota nueva sin guardar! :w para guardar, :e! para descartar y cargar",
+    "msg.unsaved_load": "¡Cambios sin guardar! :w para guardar, :e! para descartar y cargar",
+    "msg.new_unsaved_create": "¡Nota nueva sin guardar! :w para guardar, :e! para descartar y crear otra",
+    "msg.unsaved_create": "¡Cambios sin guardar! :w para guardar, :e! para descartar y crear otra",
+    "msg.unsaved_quit": "¡Cambios sin guardar! :w para guardar, :q! para salir sin guardar",
+    "msg.new_note_discarded": "Nota nueva descartada",
+    "msg.note_deleted": "Nota eliminada",
+    "msg.note_trashed": "Nota movida a la papelera ({trash} para ver la papelera, {restore} allí para restaurarla)",
+    "msg.note_restored": "Nota restaurada de la papelera",
+    "msg.not_in_trash": "La nota no está en la papelera",
+    "msg.trash_shown": "Papelera: {count} nota(s). {restore} para restaurar, {delete} para eliminar definitivamente, {trash} para volver",
+    "msg.trash_hidden": "Mostrando las notas",
+    "msg.note_archived": "Nota archivada ({archive} muestra el archivo)",
+    "msg.note_unarchived": "Nota devuelta a la lista principal",
+    "msg.already_archived": "La nota ya está archivada",
+    "msg.not_archived": "La nota no está archivada",
+    "msg.save_before_archive": "Guarda la nota (:w) antes de archivarla",
+    "msg.save_before_split": "Guarda la nota (:w) antes de dividirla",
+    "msg.nothing_to_split": "La nota tiene menos de dos encabezados de primer nivel por los que dividirla",
+    "msg.note_split": "Dividida en {count} notas, enlazadas desde esta ({undo} en la lista de notas lo deshace)",
+    "split.index_title": "{title} (índice)",
+    "split.back_link": "Volver a {link}",
+    "msg.archive_shown": "Archivo: {count} nota(s). {archive} para desarchivar, {show_archive} para volver",
+    "msg.archive_hidden": "Mostrando las notas",
+    "msg.trash_empty": "La papelera está vacía",
+    "msg.trash_emptied": "{count} nota(s) eliminada(s) de la papelera",
+    "msg.sync_unsupported": "El almacenamiento no se sincroniza; configura backend = \"sync\"",
+    "msg.sync_failed": "Falló la sincronización: {error}",
+    "msg.synced": "Sincronizado: {count} nota(s) cambiada(s)",
+    "msg.shared": "Compartida en {url}",
+    "msg.shared_copied": "Compartida en {url} (URL copiada)",
+    "msg.share_failed": "No se pudo compartir la nota: {error}",
+    "msg.ai_off": "No hay ningún modelo de lenguaje configurado: elige un proveedor en [ai] (no se envía nada mientras esté en off)",
+    "msg.ai_summarized": "Resumen añadido bajo el título (u para deshacer)",
+    "msg.ai_titled": "Titulada \"{title}\" (u para deshacer)",
+    "msg.ai_no_tags": "No se sugirieron etiquetas nuevas",
+    "msg.ai_tagged": "Etiquetada con {tags}",
+    "msg.ai_failed": "Falló la petición al modelo de lenguaje: {error}",
+    "msg.hook_failed": "Falló el hook de {event} ({command}): {error}",
+    "msg.hook_timeout": "Hook de {event} detenido tras {seconds} s: {command}",
+    "msg.hook_status": "código de salida {status}",
+    "msg.transforms": "Transformaciones: {names} (:transform <nombre>)",
+    "msg.no_transforms": "Ningún plugin añade transformaciones",
+    "msg.unknown_transform": "No hay ninguna transformación llamada \"{name}\"",
+    "msg.transformed": "Aplicada {name} (u para deshacer)",
+    "msg.transform_unchanged": "{name} no cambió la nota",
+    "msg.plugins": "Plugins: {plugins}; comandos: {commands}",
+    "msg.no_plugins": "No hay plugins cargados",
+    "msg.due_set": "Vence el {when}",
+    "msg.due_cleared": "Fecha límite eliminada",
+    "msg.remind_set": "Recordatorio para el {when}",
+    "msg.remind_cleared": "Recordatorio eliminado",
+    "msg.when_usage": "No se entiende \"{text}\" como fecha; prueba 2026-10-20, 2026-10-20 14:30, 14:30, tomorrow, friday o +2h",
+    "msg.reminder_title": "Recordatorio de termnotes",
+    "msg.reminder": "Recordatorio: {titles}",
+    "msg.no_tasks": "No hay tareas pendientes (líneas - [ ]) en ninguna nota",
+    "msg.tasks_help": "{count} tarea(s) pendiente(s): {down}/{up} para elegir, {mark} para marcar, {open} para abrir la nota, Tab para ver las hechas, Esc para cerrar",
+    "msg.no_headings": "Esta nota no tiene encabezados (líneas #)",
+    "msg.outline_help": "{count} encabezado(s): {down}/{up} para recorrerlos, {open} para llevar el cursor allí, Esc para volver",
+    "msg.tasks_done_shown": "Mostrando también las tareas hechas",
+    "msg.tasks_done_hidden": "Mostrando las tareas pendientes",
+    "msg.task_done": "Marcada \"{task}\" ({undo} para deshacer)",
+    "msg.task_undone": "Desmarcada \"{task}\" ({undo} para deshacer)",
+    "msg.task_moved": "Esa tarea cambió en su nota; se actualizó la lista",
+    "msg.unsaved_task": "La nota de la tarea tiene cambios sin guardar; guárdala (:w) primero",
+    "msg.no_checkbox": "No hay ninguna tarea - [ ] en esta línea",
+    "msg.checkbox_unsaved_done": "Marcada \"{task}\" (aún sin guardar: la nota tiene otros cambios sin guardar)",
+    "msg.checkbox_unsaved_undone": "Desmarcada \"{task}\" (aún sin guardar: la nota tiene otros cambios sin guardar)",
+    "msg.reloaded_external": "Las notas cambiaron fuera de termnotes; recargadas",
+    "msg.changed_externally": "Esta nota cambió fuera de termnotes; al guardar se preguntará si combinar o sobrescribir",
+    "msg.merged": "Combinada con la versión guardada; revísala y guarda con :w",
+    "msg.merge_conflicts": "Combinada; las dos versiones de las líneas cambiadas en ambos lados están entre <<<<<<< y >>>>>>>, corrígelas y :w",
+    "msg.conflict_discarded": "Cambios descartados; se muestra la versión guardada",
+    "merge.yours": "tus cambios",
+    "merge.stored": "versión guardada",
+    "msg.no_link": "No hay ningún [[enlace]] bajo el cursor",
+    "msg.link_not_found": "No hay ninguna nota titulada \"{title}\"",
+    "msg.attach_usage": "Uso: :attach <ruta del archivo>",
+    "msg.attach_unsaved": "Guarda la nota nueva (:w) antes de adjuntar archivos",
+    "msg.attach_failed": "No se pudo adjuntar el archivo: {error}",
+    "msg.attached": "Adjuntado {name} ({size})",
+    "msg.image_pasted": "Imagen del portapapeles pegada como {name} ({size}), adjunta a la nota",
+    "msg.no_attachments": "Esta nota no tiene adjuntos",
+    "msg.attachments_list": "Adjuntos: {attachments}",
+    "msg.attachment_which": "Esta nota tiene varios adjuntos; indica un número o nombre (:attachments los muestra)",
+    "msg.attachment_not_found": "No hay ningún adjunto {name}",
+    "msg.attachment_missing": "Falta el archivo adjunto: {path}",
+    "msg.attachment_open_failed": "No se pudo abrir el adjunto: {error}",
+    "msg.attachment_opened": "Abierto {name}",
+    "msg.url_open_failed": "No se pudo abrir {url}: {error}",
+    "msg.url_opened": "Abierto {url}",
+    "msg.no_urls": "Esta nota no tiene enlaces web",
+    "msg.spell_off": "La corrección ortográfica está desactivada (actívala en la sección [spellcheck] de la configuración)",
+    "msg.spell_no_word": "No hay ninguna palabra que revisar bajo el cursor",
+    "msg.spell_known": "\"{word}\" está bien escrita",
+    "msg.spell_added": "\"{word}\" se añadió al diccionario personal",
+    "msg.spell_add_failed": "No se pudo añadir al diccionario personal: {error}",
+    "msg.spell_none": "No hay palabras mal escritas",
+    "msg.table_usage": "Uso: :table, :table row o :table column",
+    "msg.not_in_table": "El cursor no está en una tabla",
+    "msg.attachment_removed": "Adjunto {name} eliminado",
+    "msg.no_images": "Esta nota no tiene imágenes",
+    "msg.image_number": "Indica un número de imagen del 1 al {count}",
+    "msg.image_remote": "Las imágenes de la web no se descargan: {target}",
+    "msg.image_not_found": "No se encontró la imagen: {target}",
+    "msg.image_read_failed": "No se pudo leer la imagen: {error}",
+    "msg.image_unsupported": "{image} (esta terminal no puede mostrar imágenes; configura images en [ui] o usa :open)",
+    "msg.confirm_delete_cmd": "¿Mover la nota a la papelera? :d de nuevo para confirmar, :d! para forzar",
+    "msg.confirm_purge_cmd": "¿Eliminar la nota definitivamente? :d de nuevo para confirmar, :d! para forzar",
+    "msg.nothing_to_paste": "No hay nada en el registro para pegar",
+    "msg.copied": "\"{title}\" copiada al portapapeles",
+    "msg.copied_text": "Texto de \"{title}\" copiado al portapapeles",
+    "msg.copied_osc52": "\"{title}\" enviada al portapapeles de la terminal",
+    "msg.copy_failed": "No hay portapapeles al que copiar (ver [clipboard] mode)",
+    "msg.oldest_change": "Ya estás en el cambio más antiguo",
+    "msg.newest_change": "Ya estás en el cambio más reciente",
+    "msg.pattern_not_found": "Patrón no encontrado: {pattern}",
+    "msg.search_match": "/{query}: coincidencia {index} de {count}",
+    "msg.search_results": "{count} notas coinciden con {query} (Esc para cerrar)",
+    "msg.no_previous_search": "No hay un patrón de búsqueda anterior",
+    "msg.yanked_lines": "{count} línea(s) copiada(s)",
+    "msg.no_pending_note": "No hay ninguna nota pendiente de cargar",
+    "msg.sidebar_toggle_editor_only": "La lista solo se puede ocultar con el editor enfocado",
+    "msg.zen_on": "Modo zen (:zen o Ctrl+W o para salir)",
+    "msg.zen_off": "Modo zen desactivado",
+    "msg.preview_on": "Vista previa en vivo activada (:preview o Ctrl+W p para ocultarla)",
+    "msg.preview_off": "Vista previa en vivo desactivada",
+    "msg.wrap_on": "Ajustando las líneas largas (:wrap o zw para desplazarlas de lado)",
+    "msg.wrap_off": "Las líneas largas se desplazan de lado",
+    "msg.demo_added": "Se añadieron {count} nota(s) de ejemplo en el cuaderno \"demo\"",
+    "msg.damaged_notes": "{count} archivo(s) de nota están dañados y no se pueden leer; :recover rescata lo que puede",
+    "msg.no_damaged_notes": "No hay archivos de nota dañados",
+    "msg.notes_salvaged": "Se rescataron {count} de {total} nota(s) dañada(s), etiquetadas #recovered; los archivos están en {path}",
+    "msg.no_other_notes": "No hay otras notas a las que cambiar",
+    "switcher.title": "Ir a la nota (escribe para filtrar, Intro abre)",
+    "switcher.no_match": "Ningún título coincide",
+    "lock.title": "termnotes está bloqueado",
+    "lock.prompt": "Frase de contraseña: ",
+    "lock.wrong": "Frase de contraseña incorrecta",
+    "lock.off": "El bloqueo está desactivado: pon enabled = true en [lock]",
+    "lock.choose_intro": "termnotes pedirá esta frase de contraseña al abrirse y tras quedar inactivo ([lock] en la configuración).",
+    "lock.choose_passphrase": "Elige una frase de contraseña de bloqueo: ",
+    "lock.not_chosen": "No se eligió ninguna frase de bloqueo; pon enabled = false en [lock] para abrir sin ella.",
+    "secure.locked_title": "Nota segura (bloqueada)",
+    "secure.locked_hint": ":unlock y escribe la frase de contraseña de las notas seguras para leerla",
+    "secure.locked_save": "Las notas seguras están bloqueadas: desbloquéalas primero con :unlock",
+    "secure.damaged": "El bloque cifrado de la nota segura está dañado: {error}",
+    "secure.wrong_passphrase": "Frase de contraseña incorrecta para las notas seguras",
+    "secure.unlock_title": "Desbloquear notas seguras",
+    "secure.new_title": "Elige una frase de contraseña para las notas seguras",
+    "secure.unlocked": "Notas seguras desbloqueadas (:lock para bloquearlas de nuevo)",
+    "secure.locked": "Notas seguras bloqueadas",
+    "secure.already_unlocked": "Las notas seguras ya están desbloqueadas",
+    "secure.none": "No hay notas seguras (:secure cifra la nota actual)",
+    "secure.on": "La nota es segura: cifrada en disco y oculta en las búsquedas mientras esté bloqueada",
+    "secure.off": "La nota ya no es segura",
+    "secure.already_on": "La nota ya es segura",
+    "secure.already_off": "La nota no es segura",
+    "msg.zen_accessible": "El modo zen no está disponible en el modo accesible, que ya muestra un panel cada vez",
+    "msg.preview_accessible": "La vista previa en vivo no está disponible en el modo accesible, que muestra un panel cada vez",
+    "msg.unknown_command": "Comando desconocido: {command}",
+    "msg.tag_usage": "Uso: :tag <nombre> o :untag <nombre>",
+    "msg.tag_added": "Nota etiquetada #{tag}",
+    "msg.tag_removed": "Etiqueta #{tag} quitada",
+    "msg.note_pinned": "Nota fijada al principio de la lista",
+    "msg.note_unpinned": "Nota desfijada",
+    "msg.note_starred": "Nota marcada como favorita",
+    "msg.note_unstarred": "Nota quitada de favoritas",
+    "msg.starred_shown": "Favoritas: {count} nota(s). {show_starred} para listar todas las notas",
+    "msg.starred_hidden": "Mostrando todas las notas",
+    "msg.color_set": "Nota etiquetada en {color}",
+    "msg.color_cleared": "Etiqueta de color quitada",
+    "msg.color_usage": "Colores: {colors} (sin color la quita)",
+    "msg.color_filter": "Color {color}: {count} nota(s)",
+    "msg.color_filter_cleared": "Filtro de color quitado",
+    "msg.reorder_needs_manual": "Configura sort = \"manual\" en [ui] (o --sort manual) para reordenar las notas",
+    "msg.tag_not_found": "La nota no tiene la etiqueta #{tag}",
+    "msg.tags_list": "Etiquetas: {tags}",
+    "msg.no_tags": "Aún no hay etiquetas. Añade una con :tag <nombre>",
+    "msg.tag_filter": "Mostrando {count} nota(s) con la etiqueta #{tag}",
+    "msg.tag_filter_cleared": "Mostrando todas las notas",
+    "msg.sort_order": "Ordenado por {order}",
+    "sort.updated": "última modificación",
+    "sort.created": "más recientes",
+    "sort.title": "título",
+    "sort.manual": "orden manual",
+    "sort.due": "fecha de vencimiento",
+    "msg.notebook_usage": "Uso: :notebook <ruta>",
+    "msg.notebook_created": "Cuaderno {path} creado",
+    "msg.note_moved": "Nota movida a {path}",
+    "msg.note_moved_top": "Nota movida al nivel superior",
+    "msg.notebooks_list": "Cuadernos: {notebooks}",
+    "msg.no_notebooks": "No hay cuadernos",
+    "msg.external_editor_failed": "No se pudo ejecutar {command}: {error}",
+    "msg.external_editor_exit": "{command} terminó con el código {code}; la nota no cambió",
+    "msg.external_editor_unchanged": "Sin cambios desde el editor externo",
+    "msg.no_history": "Aún no hay versiones guardadas de esta nota",
+    "msg.history_help": "{count} versión(es): {down}/{up} para elegir, {open} para restaurar, Esc para cerrar",
+    "msg.template_help": "{down}/{up} para elegir una plantilla, {open} para crear la nota, Esc para cancelar",
+    "msg.template_not_found": "No hay ninguna plantilla llamada {name}",
+    "msg.template_created": "Nota nueva con la plantilla {name}",
+    "msg.unsaved_restore": "¡Cambios sin guardar! :w antes de restaurar una versión",
+    "msg.revision_restored": "Versión #{rev} restaurada",
+    "msg.cancelled": "Cancelado",
+    "msg.save_before_mark": "Guarda la nota (:w) antes de marcarla",
+    "msg.marked": "{count} marcada(s): {delete} para eliminar, {archive} para archivar, :tag <nombre> para etiquetar, Esc para desmarcar",
+    "msg.marks_cleared": "Notas desmarcadas",
+    "msg.notes_trashed": "{count} nota(s) movida(s) a la papelera ({trash} para ver la papelera, {restore} allí para restaurarlas)",
+    "msg.notes_deleted": "{count} nota(s) eliminada(s)",
+    "msg.notes_archived": "{count} nota(s) archivada(s)",
+    "msg.notes_unarchived": "{count} nota(s) devuelta(s) a la lista principal",
+    "msg.notes_tagged": "{count} nota(s) etiquetada(s) #{tag}",
+    "msg.notes_untagged": "Etiqueta #{tag} quitada de {count} nota(s)",
+    "msg.replace_usage": "Uso: :replaceall /texto/reemplazo/ (r tras la última / para una expresión regular)",
+    "msg.bad_pattern": "Patrón no válido: {error}",
+    "msg.no_replacements": "Ninguna nota contiene {pattern}",
+    "msg.replace_help": "{matches} coincidencia(s) en {count} nota(s): {down}/{up} para seleccionar, {mark} para omitir una nota, {open} para reemplazar, Esc para cancelar",
+    "msg.all_skipped": "Todas las notas están omitidas; {mark} incluye la nota seleccionada",
+    "msg.unsaved_replace": "¡Cambios sin guardar! :w o :e! antes de reemplazar texto en esta nota",
+    "msg.replaced": "{matches} coincidencia(s) reemplazada(s) en {count} nota(s) ({undo} en la lista de notas para deshacer)",
+    "msg.undone": "Deshecho: {action}",
+    "msg.redone": "Rehecho: {action}",
+    "msg.nothing_to_undo": "No hay nada que deshacer",
+    "msg.nothing_to_redo": "No hay nada que rehacer",
+    "msg.undo_unsaved": "¡Cambios sin guardar! :w o :e! antes de deshacer cambios en esta nota",
+
+    # Config file settings
+    "config.unknown_theme": "Tema desconocido: {theme}",
+    "config.unknown_colors": "Valor de colors desconocido: {colors} (usa {names})",
+    "config.unknown_color_element": "Elemento desconocido en [colors]: {element}",
+    "config.invalid_color": "Estilo no válido para {element} en [colors]: {style}",
+    "config.unknown_hook": "Evento desconocido en [hooks]: {event} (eventos: {events})",
+    "config.unknown_notify": "Valor desconocido de notify en [reminders]: {notify} (usa desktop, banner u off)",
+    "config.unknown_clipboard": "Modo de [clipboard] desconocido: {mode} (usa auto, system, osc52 u off)",
+    "config.unknown_share": "Servicio de [share] desconocido: {service} (usa gist o paste)",
+    "config.unknown_ai": "Proveedor de [ai] desconocido: {provider} (usa off, openai, ollama o command)",
+    "config.unknown_sort": "Orden desconocido: {sort} (usa updated, created, title, manual o due)",
+    "config.unknown_images": "Valor de images desconocido: {images} (usa auto, kitty, iterm2, sixel u off)",
+    "config.unknown_editing": "Valor de editing desconocido: {editing} (usa vim o simple)",
+    "config.invalid_sidebar_width": "sidebar_width no válido: {width}",
+    "config.invalid_zen_width": "zen_width no válido: {width} (usa un número de columnas, mínimo 20)",
+    "config.invalid_draft_interval": "draft_interval no válido: {interval}",
+    "config.invalid_spell_languages": "Idiomas de spellcheck no válidos: {languages}",
+    "config.invalid_snippet": "Snippet no válido: {abbreviation}",
+    "config.invalid_lock_idle": "idle_minutes no válido en [lock]: {minutes}",
+
+    # Diálogo de confirmación
+    "dialog.title": "Confirmar",
+    "dialog.choices": "y: Sí   n: No",
+    "dialog.open_url": "¿Qué enlace abrir?",
+    "dialog.spell_suggest": "Reemplazar \"{word}\" por:",
+    "dialog.spell_add": "Añadir al diccionario personal",
+    "dialog.trash_note": "¿Mover \"{title}\" a la papelera?",
+    "dialog.purge_note": "¿Eliminar \"{title}\" definitivamente?",
+    "dialog.trash_marked": "¿Mover {count} nota(s) marcada(s) a la papelera?",
+    "dialog.purge_marked": "¿Eliminar definitivamente {count} nota(s) marcada(s)?",
+    "dialog.empty_trash": "¿Eliminar definitivamente {count} nota(s) de la papelera?",
+    "dialog.restore_draft": "¿Restaurar los cambios sin guardar de \"{title}\" del {time}?",
+    "dialog.salvage_notes": "{count} archivo(s) de nota están dañados, sin copia de seguridad válida. ¿Rescatar lo que se pueda leer?",
+    "dialog.ai_tags": "¿Añadir las etiquetas sugeridas {tags}?",
+    "dialog.conflict": "\"{title}\" cambió en el almacenamiento mientras la editabas",
+    "dialog.conflict_merge": "Combinar",
+    "dialog.conflict_overwrite": "Sobrescribir",
+    "dialog.conflict_discard": "Descartar los míos",
+    "dialog.choice_cancel": "Esc: Cancelar",
+
+    # Operaciones que se pueden deshacer, tras "Deshecho:" o "Rehecho:"
+    "journal.create": "crear \"{title}\"",
+    "journal.save": "guardar \"{title}\"",
+    "journal.delete": "eliminar \"{title}\"",
+    "journal.trash": "mover \"{title}\" a la papelera",
+    "journal.restore": "restaurar \"{title}\" de la papelera",
+    "journal.archive": "archivar \"{title}\"",
+    "journal.unarchive": "desarchivar \"{title}\"",
+    "journal.tag": "etiquetar \"{title}\"",
+    "journal.untag": "quitar una etiqueta de \"{title}\"",
+    "journal.pin": "fijar \"{title}\"",
+    "journal.due": "cambiar la fecha límite de \"{title}\"",
+    "journal.remind": "poner un recordatorio en \"{title}\"",
+    "journal.toggle_task": "marcar una tarea en \"{title}\"",
+    "journal.unpin": "desfijar \"{title}\"",
+    "journal.star": "marcar como favorita \"{title}\"",
+    "journal.unstar": "quitar de favoritas \"{title}\"",
+    "journal.secure": "hacer segura \"{title}\"",
+    "journal.unsecure": "dejar de hacer segura \"{title}\"",
+    "journal.color": "etiquetar \"{title}\"",
+    "journal.uncolor": "quitar la etiqueta de color de \"{title}\"",
+    "journal.move": "mover \"{title}\" a otro cuaderno",
+    "journal.reorder": "reordenar notas",
+    "journal.purge": "vaciar la papelera ({count} nota(s))",
+    "journal.revision": "restaurar una versión de \"{title}\"",
+    "journal.trash_many": "mover {count} nota(s) a la papelera",
+    "journal.delete_many": "eliminar {count} nota(s)",
+    "journal.archive_many": "archivar {count} nota(s)",
+    "journal.unarchive_many": "desarchivar {count} nota(s)",
+    "journal.tag_many": "etiquetar {count} nota(s)",
+    "journal.untag_many": "quitar una etiqueta de {count} nota(s)",
+    "journal.replace_all": "reemplazar texto en {count} nota(s)",
+    "journal.import": "importar {count} nota(s)",
+    "journal.restore_backup": "restaurar {count} nota(s) de una copia de seguridad",
+    "journal.split": "dividir \"{title}\" en notas",
+
+    # Key binding actions
+    "keys.unknown_action": "Acción desconocida en [keys]: {action}",
+    "keys.invalid": "Teclas no válidas para {action} en [keys]: {keys}",
+    "plugin.load_failed": "No se pudo cargar el plugin {plugin}: {error}",
+    "plugin.no_register": "El plugin {plugin} no tiene una función register()",
+    "plugin.builtin_action": "El plugin {plugin} no puede añadir la acción integrada {action}",
+    "plugin.invalid_keys": "Teclas no válidas para la acción de plugin {action}: {keys}",
+    "plugin.failed": "Falló el plugin {plugin}: {error}",
+    "plugin.transform_not_text": "la transformación devolvió {type}, no texto",
+    "keys.up": "Subir",
+    "keys.down": "Bajar",
+    "keys.left": "Izquierda (editor)",
+    "keys.right": "Derecha (editor)",
+    "keys.word_forward": "Palabra siguiente",
+    "keys.word_backward": "Palabra anterior",
+    "keys.word_end": "Final de palabra",
+    "keys.line_start": "Inicio de línea",
+    "keys.line_end": "Final de línea",
+    "keys.half_page_down": "Media página abajo",
+    "keys.half_page_up": "Media página arriba",
+    "keys.page_down": "Página abajo",
+    "keys.page_up": "Página arriba",
+    "keys.bottom": "Última línea",
+    "keys.focus_sidebar": "Ir a la lista de notas",
+    "keys.focus_editor": "Ir al editor",
+    "keys.toggle_focus": "Cambiar el foco entre la lista de notas y el editor",
+    "keys.toggle_zen": "Entrar o salir del modo zen (solo el editor, centrado)",
+    "keys.toggle_preview": "Mostrar u ocultar la vista previa en vivo de la nota junto al editor",
+    "keys.toggle_wrap": "Ajustar las líneas largas en el editor, o desplazarlas de lado",
+    "keys.quick_switch": "Saltar a una nota vista hace poco escribiendo parte de su título",
+    "keys.follow_link": "Mostrar la imagen o abrir la nota a la que apunta un [[enlace]] (editor)",
+    "keys.open_url": "Abrir un enlace web de la nota, eligiendo uno si hay varios",
+    "keys.toggle_checkbox": "Marcar o desmarcar la tarea - [ ] de la línea del cursor, guardando la nota (editor)",
+    "keys.paste_clipboard": "Pegar el portapapeles del sistema (editor, modo Insertar)",
+    "keys.spell_suggest": "Sugerir cómo escribir la palabra bajo el cursor",
+    "keys.spell_add": "Añadir la palabra bajo el cursor al diccionario personal",
+    "keys.spell_next": "Siguiente palabra mal escrita",
+    "keys.spell_previous": "Palabra mal escrita anterior",
+    "keys.open": "Abrir nota o cuaderno; restaurar versión en el historial",
+    "keys.new_note": "Nota nueva",
+    "keys.new_from_template": "Nota nueva a partir de una plantilla",
+    "keys.edit": "Editar la nota seleccionada en modo insertar",
+    "keys.delete_note": "Mover la nota a la papelera (eliminarla definitivamente en la papelera)",
+    "keys.mark": "Marcar o desmarcar la nota para aplicar dd, a o :tag a todas las marcadas",
+    "keys.undo": "Deshacer el último cambio en las notas (guardar, eliminar, etiquetar...)",
+    "keys.redo": "Rehacer el último cambio deshecho en las notas",
+    "keys.history": "Ver el historial de la nota",
+    "keys.trash": "Mostrar u ocultar la papelera",
+    "keys.restore": "Restaurar la nota de la papelera",
+    "keys.archive": "Archivar o desarchivar la nota",
+    "keys.show_archive": "Mostrar u ocultar el archivo",
+    "keys.tasks": "Mostrar las tareas pendientes de todas las notas",
+    "keys.outline": "Mostrar los encabezados de la nota para saltar entre ellos",
+    "keys.pin": "Fijar o desfijar la nota",
+    "keys.star": "Marcar o desmarcar la nota como favorita (sin moverla)",
+    "keys.show_starred": "Listar solo las notas favoritas, o todas",
+    "keys.cycle_color": "Etiquetar la nota con el siguiente color, o ninguno tras el último",
+    "keys.cycle_color_filter": "Listar solo las notas del siguiente color en uso, o todas",
+    "keys.move_note_up": "Subir la nota (orden manual)",
+    "keys.move_note_down": "Bajar la nota (orden manual)",
+    "keys.cycle_tag": "Recorrer el filtro de etiquetas",
+    "keys.cycle_sort": "Cambiar el orden de la lista",
+    "keys.toggle_notebook": "Expandir o contraer el cuaderno",
+    "keys.collapse_all": "Contraer todos los cuadernos",
+    "keys.expand_all": "Expandir todos los cuadernos",
+    "keys.external_editor": "Editar la nota en el editor externo",
+    "keys.copy_note": "Copiar el markdown de la nota seleccionada al portapapeles del sistema",
+    "keys.copy_note_text": "Copiar el texto de la nota seleccionada, sin markdown, al portapapeles del sistema",
+    "keys.command": "Escribir un comando",
+    "keys.search": "Buscar hacia delante",
+    "keys.search_backward": "Buscar hacia atrás",
+    "keys.next_match": "Coincidencia siguiente",
+    "keys.previous_match": "Coincidencia anterior",
+    "keys.quit": "Salir inmediatamente",
+    "keys.help": "Mostrar esta lista de teclas",
+    "keys.fixed.insert": "Insertar antes o después del cursor",
+    "keys.fixed.open_line": "Abrir una línea debajo o encima",
+    "keys.fixed.normal": "Volver al modo normal",
+    "keys.fixed.delete_char": "Borrar el carácter bajo el cursor",
+    "keys.fixed.line": "Borrar, copiar o cambiar la línea",
+    "keys.fixed.word": "Borrar, cambiar o copiar una palabra, o la palabra bajo el cursor",
+    "keys.fixed.line_end": "Borrar o cambiar hasta el final de la línea",
+    "keys.fixed.paste": "Pegar después o antes del cursor",
+    "keys.fixed.undo": "Deshacer o rehacer",
+    "keys.fixed.visual": "Seleccionar caracteres o líneas",
+    "keys.fixed.top": "Ir a la primera línea",
+    "keys.fixed.scroll": "Desplazar las líneas largas de lado",
+
+    # Help overlay
+    "help.title": "Teclas (j/k desplazan, Esc cierra)",
+    "help.navigation": "Navegación",
+    "help.editing": "Edición",
+    "help.notes": "Notas",
+    "help.search": "Búsqueda y comandos",
+    "help.plugins": "Plugins",
+
+    # Console output during startup
+    "prompt.press_enter": "Pulsa Intro para continuar...",
+    "storage.key_file_empty": "Aviso: el archivo de clave {path} está vacío, se generará de nuevo",
+    "storage.key_file_read_failed": "Aviso: no se pudo leer el archivo de clave {path}: {error}",
+    "storage.generating_passphrase": "Generando una nueva frase de cifrado...",
+    "storage.generated_passphrase": "✓ Frase generada: {passphrase}",
+    "storage.saved_to": "✓ Guardada en {path}",
+    "storage.keep_passphrase_secure": "⚠️  ¡Guarda esta frase en un lugar seguro! Anótala.",
+    "storage.passphrase_required": "⚠️  Sin ella, las notas cifradas no se pueden descifrar.",
+    "storage.key_file_save_failed": "Aviso: no se pudo guardar el archivo de clave: {error}",
+    "storage.decrypt_failed": "Aviso: no se pudo descifrar la nota {note_id}: {error}",
+    "storage.decrypt_failed_content": "[ERROR AL DESCIFRAR: {error}]",
+    "storage.migrated_notes": "✓ {count} nota(s) sin cifrar migrada(s) al almacenamiento cifrado",
+    "storage.migrate_failed": "Aviso: no se pudieron migrar las notas: {error}",
+    "storage.enter_passphrase": "Frase de las notas cifradas: ",
+    "storage.new_passphrase": "Elige una frase para las notas cifradas: ",
+    "storage.confirm_passphrase": "Vuelve a escribir la frase: ",
+    "storage.passphrase_empty": "La frase no puede estar vacía.",
+    "storage.passphrase_mismatch": "Las frases no coinciden.",
+    "storage.passphrase_wrong": "Frase incorrecta.",
+    "storage.passphrase_attempts": "Demasiados intentos fallidos.",
+    "storage.git_missing": "Error: el almacenamiento git necesita git, que no se encontró",
+    "storage.git_failed": "Error: no se pudo preparar el repositorio git: {error}",
+    "storage.git_pull_failed": "Aviso: git pull falló: {error}",
+    "storage.git_push_failed": "Aviso: git push falló: {error}",
+    "storage.sync_no_url": "Error: el almacenamiento sync necesita la url del servidor en [storage.sync]",
+    "storage.copied_filesystem_notes": "Se copiaron {count} notas de {path} a la base de datos SQLite",
+    "storage.locked": "Error: estas notas están abiertas en otro termnotes ({owner}). Ciérralo primero para que ninguno sobrescriba los cambios del otro, o comparte las notas con \"termnotes daemon\". (Archivo de bloqueo: {path})",
+    "storage.sync_failed": "Aviso: no se pudo sincronizar, se trabaja sin conexión: {error}",
+    "storage.push_failed": "Aviso: no se pudieron enviar los cambios al servidor de sincronización, quedan en cola: {error}",
+    "storage.e2e_new_passphrase": "Elige una frase de contraseña para la sincronización cifrada de extremo a extremo (se pide en cada dispositivo nuevo): ",
+    "storage.e2e_enter_passphrase": "Frase de contraseña de la sincronización cifrada de extremo a extremo: ",
+    "storage.e2e_wrong_passphrase": "frase de contraseña incorrecta para la sincronización cifrada de extremo a extremo",
+    "storage.e2e_locked": "las notas están cifradas de extremo a extremo y necesitan la frase de contraseña; reinicia termnotes en una terminal para introducirla",
+    "storage.e2e_unknown_format": "las claves de cifrado de extremo a extremo del servidor las escribió un termnotes más reciente",
+    "storage.e2e_revoked": "este dispositivo ({device}) fue revocado de la sincronización cifrada de extremo a extremo",
+    "storage.e2e_no_device": "no hay ningún dispositivo {device} en la sincronización cifrada de extremo a extremo",
+    "storage.e2e_revoke_self": "este dispositivo no puede revocarse a sí mismo; revócalo desde otro dispositivo",
+    "storage.e2e_revoke_passphrase": "revocar un dispositivo necesita una nueva frase de contraseña, ya que el dispositivo conoce la anterior",
+    "storage.e2e_unknown_key": "la nota {note_id} está cifrada con una clave que este dispositivo no tiene",
+    "storage.e2e_tampered": "la nota {note_id} no se puede descifrar: se cambió en el servidor o se cifró con otra frase de contraseña",
+    "storage.e2e_unsealed": "la nota {note_id} del servidor no está cifrada, aunque cambió después de activar el cifrado de extremo a extremo",
+    "storage.e2e_keyring_busy": "otro dispositivo cambió las claves de cifrado de extremo a extremo a la vez; inténtalo de nuevo",
+    "storage.e2e_off": "La sincronización cifrada de extremo a extremo está desactivada: pon e2e = true en [storage.sync] y usa el backend sync",
+    "storage.unknown_key_store": "Error: key_store \"{store}\" desconocido en [storage.encrypted] (usa \"file\" o \"keyring\")",
+    "storage.saved_to_keyring": "✓ Guardada en el llavero del sistema",
+    "storage.key_file_moved": "✓ La frase se movió de {path} al llavero del sistema; borra el archivo cuando tengas una copia en otro sitio.",
+    "secret.no_keyring": "El llavero del sistema necesita el paquete keyring: pip install keyring",
+    "spell.no_library": "La corrección ortográfica necesita el paquete pyspellchecker: pip install 'termnotes[spellcheck]'",
+    "spell.no_dictionary": "No hay diccionario para {languages}; añade una lista de palabras con el nombre del idioma a {directory}",
+    "spell.load_failed": "No se pudo cargar la lista de palabras {path}: {error}",
+    "secret.store_failed": "Error: el llavero del sistema no pudo guardar {name}: {error}",
+    "secret.delete_failed": "Error: el llavero del sistema no pudo quitar {name}: {error}",
+    "secret.sync": "Token del servidor de sincronización (token en [storage.sync])",
+    "secret.webdav": "Contraseña de WebDAV (password en [storage.webdav])",
+    "secret.postgres": "Contraseña de PostgreSQL (password en [storage.postgres])",
+    "secret.encryption": "Frase del almacenamiento cifrado (con key_store = \"keyring\")",
+    "secret.server": "Token que exige \"termnotes serve\" (token en [server])",
+    "secret.github": "Token de GitHub para :share gist (token en [share])",
+    "secret.paste": "Token del servicio de paste (paste_token en [share])",
+    "secret.openai": "Clave de API del proveedor de IA openai (api_key en [ai])",
+    "storage.webdav_no_url": "Error: el almacenamiento webdav necesita la url de la carpeta en [storage.webdav]",
+    "storage.webdav_http": "Error del servidor WebDAV en {url}: HTTP {status} {reason}",
+    "storage.webdav_unreachable": "No se pudo contactar con el servidor WebDAV en {url}: {error}",
+    "storage.webdav_bad_listing": "El servidor WebDAV en {url} envió un listado de carpeta que no se pudo leer",
+    "storage.postgres_no_dsn": "Error: el almacenamiento postgres necesita una cadena de conexión en [storage.postgres] dsn",
+    "storage.postgres_no_driver": "Error: el almacenamiento postgres necesita el paquete psycopg: pip install 'psycopg[binary]'",
+    "storage.postgres_connect_failed": "No se pudo conectar con la base de datos PostgreSQL: {error}",
+    "storage.postgres_newer_schema": "La base de datos PostgreSQL fue preparada por un termnotes más reciente (versión de esquema {version}); actualiza termnotes para usarla",
+    "storage.sqlite_newer_schema": "La base de datos {path} fue preparada por un termnotes más reciente (versión de esquema {version}); actualiza termnotes para usarla",
+    "storage.note_format_newer": "El archivo de nota {path} lo escribió un termnotes más reciente (versión de formato {version}); actualiza termnotes para leerlo",
+    "storage.cancelled": "Se canceló la operación",
+    "storage.deadline_exceeded": "La operación tardó demasiado y se detuvo",
+    "storage.write_failed": "No se pudo escribir un cambio en el almacenamiento, se reintentará: {error}",
+    "storage.conflict": "Alguien más cambió \"{title}\" desde que se cargó",
+    "storage.invalid_note_id": "ID de nota no válido: {note_id!r}",
+    "storage.notebook_outside": "El cuaderno {notebook!r} quedaría fuera del directorio de notas",
+    "storage.read_only": "Las notas están abiertas en solo lectura",
+    "backup.invalid": "{path} no es una copia de seguridad de termnotes, o es de una versión más reciente",
+    "archive.invalid": "{path} no es un archivo de termnotes, o es de una versión más reciente",
+    "server.listening": "Servidor de sincronización en http://{host}:{port}, notas en {path}",
+    "server.no_token": "Aviso: no hay token; cualquiera que llegue al servidor puede leer y cambiar las notas",
+    "server.feed": "Feed Atom de las notas recientes en http://{host}:{port}/v1/feed.atom",
+    "server.grpc": "API gRPC (termnotes.v1.Notes) escuchando en {host}:{port}",
+    "server.grpc_no_package": "Error: la API gRPC necesita el paquete grpcio: pip install 'termnotes[grpc]'",
+    "server.grpc_bind_failed": "Error: la API gRPC no puede escuchar en {host}:{port}",
+    "daemon.listening": "Manteniendo las notas para editores y comandos en {path} (Ctrl+C o \"termnotes daemon --stop\" para detenerlo)",
+    "daemon.stopped": "Daemon detenido",
+    "daemon.not_running": "No hay ningún daemon en marcha",
+    "daemon.start_failed": "Error: el daemon no empezó a escuchar en {path}; ejecuta \"termnotes daemon\" para ver por qué",
+    "daemon.disconnected": "El daemon en {path} ya no está; reinicia el editor",
+    "daemon.timeout": "El daemon tardó demasiado en responder ({method})",
+    "daemon.unsupported": "Error: el daemon necesita sockets unix, que este sistema no tiene",
+    "mcp.instructions": "Son las notas personales del usuario, en markdown; cada una empieza con un encabezado \"# Título\", y [[Título]] enlaza con otra nota. Búscalas o lístalas para encontrar sus IDs y lee una nota para ver su texto completo.",
+
+    # Built-in note templates
+    "template.meeting": """# {{title}}
+
+**Fecha:** {{date}} {{time}}
+**Asistentes:**
+
+## Orden del día
+- {{cursor}}
+
+## Notas
+
+## Tareas
+- [ ]
+""",
+    "template.todo": """# {{title}}
+
+- [ ] {{cursor}}
+""",
+    "template.journal": """# {{weekday}}, {{date}}
+
+{{cursor}}
+""",
+
+    # First-run tour ({keys} are the current keys of each action)
+    "tour.title": "Recorrido",
+    "tour.footer": "{step}/{total}  Intro: siguiente  Retroceso: atrás  Esc: saltar",
+    "tour.welcome.title": "Bienvenido a termnotes",
+    "tour.welcome": "Las notas son markdown y se guardan en tu equipo (o donde indique [storage] en la configuración). Este recorrido muestra las teclas que necesitarás primero; dura un minuto.",
+    "tour.list.title": "La lista de notas",
+    "tour.list": "La lista de notas está a la izquierda. {down} y {up} la recorren, {open} abre la nota seleccionada y {new_note} crea una nueva. {toggle_focus} cambia entre la lista y el editor.",
+    "tour.editor.title": "Escribir",
+    "tour.editor": "El editor funciona como vim: {edit} empieza a escribir, Esc vuelve al modo normal y {command}w guarda la nota ({command}q sale). Si la terminal se cierra, los cambios sin guardar quedan en un borrador.",
+    "tour.find.title": "Encontrar cosas",
+    "tour.find": "{search} en la lista de notas busca en todas las notas; en el editor resalta las coincidencias en la nota y {next_match} salta entre ellas. {outline} lista los encabezados de una nota y {tasks} las tareas abiertas de todas.",
+    "tour.help.title": "Ayuda",
+    "tour.help": "{help} lista todas las teclas, y la nota de bienvenida describe todas las funciones. {command}tour muestra este recorrido otra vez. ¡Feliz escritura!",
+
+    # Demo notes added by --seed-demo
+    "demo.markdown": """# Recorrido por markdown
+
+termnotes da estilo al markdown mientras escribes. Esta nota muestra lo que reconoce.
+
+## Texto
+
+Pon palabras en **negrita**, *cursiva*, ~~tachadas~~ o como `código`.
+
+> Las citas se ven así.
+
+## Listas
+
+- Viñetas con `-`
+  - y anidadas
+1. Elementos numerados
+2. que cuentan
+
+## Enlaces
+
+Enlaza a otra nota por su título: [[Plan del proyecto]]. Pulsa Intro sobre el enlace para abrirla.
+Los enlaces web también funcionan: <https://github.com/bjia56/termnotes>
+
+## Código
+
+```python
+def saludar(nombre):
+    return f"¡Hola, {nombre}!"
+```
+
+| Tecla | Hace |
+|-------|------|
+| `/` | Buscar |
+| `?` | Ayuda |
+""",
+    "demo.project": """# Plan del proyecto
+
+Esta nota está en el cuaderno "demo/project", con la etiqueta #work, y vence en tres días.
+Pulsa O en la lista de notas para saltar entre sus encabezados.
+
+## Objetivos
+
+- Publicar la primera versión
+- Escribir la documentación
+
+## Calendario
+
+1. Esbozar el diseño
+2. Construirlo
+3. Probarlo con algunos usuarios
+
+## Preguntas abiertas
+
+- ¿Qué plataformas van primero?
+
+Las tareas están en [[Lista de la compra]].
+""",
+    "demo.tasks": """# Lista de la compra
+
+Pulsa Espacio sobre una tarea en el editor para marcarla, o X en la lista de notas para ver las tareas abiertas de todas las notas.
+
+- [ ] Leche
+- [ ] Pan
+- [x] Café
+- [ ] Una libreta para notas sin conexión
+
+Volver a [[Plan del proyecto]].
+""",
+
+    # First-run welcome note
+    "welcome.content": """# ¡Bienvenido a termnotes!
+
+Una aplicación de notas para la terminal al estilo de vim, con soporte para markdown.
+
+## Guía rápida
+
+### Navegación
+- `Ctrl+W h` - Ir a la lista de notas
+- `Ctrl+W l` - Ir al editor
+- `Tab` - Cambiar entre la lista de notas y el editor; la línea de título del panel enfocado se resalta
+- `?` (lista de notas) / `F1` / `:help` - Listar todas las teclas
+- `Ctrl+P` - Saltar a una nota: las demás notas aparecen de la vista más recientemente a la menos (`Ctrl+P` `Intro` vuelve a la nota anterior), y escribir las filtra por título
+- `j/k` - Bajar/subir (en la lista y en el editor)
+- `h/l` - Izquierda/derecha en el editor
+- `w/b/e` - Moverse por palabras en el editor (`Ctrl+Izq/Der` en modo Insertar, `Ctrl+W` borra una palabra)
+
+### Crear notas
+- `:new` o `:n` - Crear una nota vacía
+- `o` - Crear una nota (con la lista enfocada)
+- `T` / `:template <nombre> [título]` - Crear una nota a partir de una plantilla (meeting, todo, journal o las tuyas)
+- `:split` - Dividir la nota actual por sus encabezados de primer nivel en una nota por sección, dejándola como índice con enlaces a ellas
+
+### Fijar y ordenar
+- `p` - Fijar la nota seleccionada al principio de la lista, o desfijarla (`:pin` / `:unpin` para la nota actual)
+- `*` - Marcar la nota seleccionada como favorita, o desmarcarla, sin moverla (`:star` / `:unstar` para la nota actual); `S` / `:starred` lista solo las favoritas (marcadas con `+`)
+- `C` - Etiquetar la nota seleccionada con el siguiente color: rojo, amarillo, verde, cian, azul, magenta y luego ninguno (`:color <nombre>` para la nota actual, `:color` solo la quita); una viñeta de color lo muestra en la lista, y `F` / `:colorfilter <nombre>` lista solo las notas de un color
+- Con `sort = "manual"` en la configuración, `J/K` bajan/suben la nota seleccionada
+- `s` - Ordenar la lista por el siguiente criterio: última modificación, más recientes, título, manual, vencimiento (la nota seleccionada sigue seleccionada)
+
+### Archivo
+- `a` - Archivar la nota seleccionada, sacándola de la lista sin eliminarla (`a` de nuevo en el archivo la devuelve)
+- `A` / `:archived` - Mostrar u ocultar las notas archivadas; `:archive` / `:unarchive` actúan sobre la nota actual
+
+### Tareas
+- `X` / `:tasks` - Listar las tareas `- [ ]` pendientes de todas las notas; `Espacio` marca una en su nota, `Enter` abre la nota en ella, `Tab` muestra también las hechas
+- `Espacio` (o `Enter` en una línea de tarea) en el editor - Marcar o desmarcar la tarea bajo el cursor y guardar la nota
+
+### Fechas límite y recordatorios
+- `:due friday` - Poner la fecha límite de la nota actual (también `2026-10-20`, `tomorrow`, `+3d`); `:due` solo la quita
+- `:remind 14:30` - Recibir un recordatorio a esa hora (también `+2h`, `2026-10-20 09:00`); `:remind` solo lo quita
+- La lista muestra cuándo vencen las notas, y `vencida` en rojo; `sort = "due"` muestra primero las más próximas
+- `termnotes agenda` muestra las notas vencidas y las que vencen esta semana, y `termnotes export --ics notes.ics` las pone en un archivo de calendario
+
+### Eliminar notas
+- `dd` - Mover la nota seleccionada a la papelera (con la lista enfocada, tras confirmar)
+- `:delete` o `:d` - Eliminar la nota actual (se confirma con otro :d)
+- `:d!` - Eliminar la nota actual sin confirmación
+- Las notas eliminadas van a la papelera: `t` la muestra (con la lista enfocada), `r` allí restaura la nota seleccionada
+- Eliminar una nota de la papelera la borra definitivamente; `:emptytrash` vacía la papelera
+- `Espacio` - Marcar la nota seleccionada; `dd`, `a` y `:tag`/`:untag` actúan entonces sobre todas las marcadas (`Esc` las desmarca)
+- `u` / `Ctrl+R` - Deshacer o rehacer el último cambio en las notas, incluso una eliminación definitiva (con la lista enfocada)
+
+### Edición
+- `i` - Entrar en modo Insertar
+- `Esc` - Volver al modo Normal
+- `dd` - Eliminar la línea actual (con el editor enfocado)
+- `yy` / `p` - Copiar la línea actual / pegarla debajo (a través del portapapeles del sistema, así que `p` también pega lo que copiaron otros programas); `dw`, `cw`, `diw`, `ciw`, `yiw` y `D`/`C` actúan sobre palabras y el resto de la línea
+- `y` / `Y` en la lista de notas - Copiar el markdown de la nota seleccionada / su texto sin markdown al portapapeles del sistema; `Ctrl+V` pega el portapapeles en modo Insertar, adjuntando una imagen (una captura de pantalla) y enlazándola
+- `v` / `V` - Seleccionar caracteres / líneas, y luego `d`, `y` o `c`
+- Pon `editing = "simple"` en `[ui]` para escribir en cuanto el editor tiene el foco (`Esc` vuelve a la lista de notas)
+- `o` - Insertar una línea debajo (con el editor enfocado)
+- `O` - Insertar una línea encima
+- `E` - Editar la nota en tu editor externo ($VISUAL o $EDITOR)
+- `:table` - Empezar una tabla, o alinear las barras de la que está bajo el cursor; al escribir en una tabla, `Tab`/`Shift+Tab` pasan de una celda a otra y `Enter` a la fila de abajo, manteniéndola alineada, y `:table row` / `:table column` añaden una fila o una columna
+- La barra de estado muestra las palabras, caracteres y tiempo de lectura de la nota seleccionada; ejecuta `termnotes stats` para ver los totales de todas las notas
+- `:zen` / `Ctrl+W o` - Modo zen para textos largos: solo la nota, centrada (`zen_width` en `[ui]`), con el número de palabras debajo
+- `:preview` / `Ctrl+W p` - Vista previa en vivo: la nota formateada junto al editor mientras escribes (`live_preview` en `[ui]` para empezar con ella)
+- `:wrap` / `zw` - Ajustar las líneas largas en las filas de abajo en vez de desplazarlas de lado (`wrap` en `[ui]` para empezar así)
+
+### Búsqueda
+- `/` - Buscar en la nota actual (editor) o en todas las notas (lista)
+- En el editor, las coincidencias se resaltan mientras escribes y la barra de estado las cuenta; `Esc` o `:noh` quitan el resaltado
+- En la lista, los resultados se filtran mientras escribes, con coincidencias aproximadas (`mtg` encuentra "Meeting") tras las palabras completas y una vista previa de la nota elegida
+- `n/N` - Ir a la coincidencia siguiente/anterior; `Esc` cierra los resultados de la lista
+- `O` / `:outline` - Listar los encabezados de la nota seleccionada / actual; `j/k` desplazan la nota a cada sección, `Enter` lleva el cursor allí, `Esc` vuelve
+- `:replaceall /antes/después/` - Reemplazar texto en todas las notas tras revisar los cambios (`Espacio` omite una nota, `Enter` reemplaza); añade `r` al final para una expresión regular
+
+### Etiquetas
+- `:tag <nombre>` / `:untag <nombre>` - Añadir/quitar una etiqueta de la nota actual
+- `:tags` - Listar todas las etiquetas
+- `#` - Recorrer las notas de cada etiqueta en la lista (con la lista enfocada)
+- `:filter <nombre>` / `:filter` - Mostrar solo las notas con una etiqueta / mostrar todas
+
+### Cuadernos
+- `:notebook <ruta>` - Crear un cuaderno (usa `trabajo/proyectos` para anidar)
+- `:move <ruta>` / `:move` - Mover la nota actual a un cuaderno / al nivel superior
+- `:notebooks` - Listar todos los cuadernos
+- `Enter` / `za` - Expandir o contraer el cuaderno seleccionado (con la lista enfocada)
+- `zM` / `zR` - Contraer / expandir todos los cuadernos
+
+### Historial
+- `h` / `:history` - Ver las versiones guardadas de la nota seleccionada / actual
+- En el historial, `j/k` eligen una versión y muestran sus cambios, `Intro` la restaura, `Esc` cierra
+
+### Adjuntos
+- `:attach <ruta>` - Adjuntar una copia de un archivo a la nota actual
+- `:attachments` - Listar los adjuntos de la nota actual
+- `:open <n>` / `:detach <n>` - Abrir un adjunto con el programa predeterminado del sistema / quitarlo
+- `Intro` sobre un enlace de imagen / `:image <n>` - Mostrar la imagen (en terminales con gráficos kitty, iTerm2 o sixel)
+
+### Comandos de vim
+- `:w` - Guardar la nota actual; si cambió en otro lugar mientras tanto, elige combinar, sobrescribir o descartar tus cambios
+- `:e!` - Descartar los cambios y recargar
+- `:q` - Salir (avisa si hay cambios sin guardar); `:q!` sale sin guardar
+- Los cambios sin guardar se copian a un borrador cada pocos segundos; si la terminal se cierra antes de guardar, termnotes ofrece restaurarlos al volver a abrirlo
+- `:recover` - Rescatar lo que se pueda leer de los archivos de nota dañados en notas etiquetadas #recovered (también se ofrece al iniciar)
+- `:wq` - Guardar y salir
+- `:sync` - Intercambiar cambios con el servidor de sincronización (con el almacenamiento sync)
+- `:lock` - Ocultar las notas hasta que se escriba la frase de contraseña (con `enabled = true` en `[lock]`)
+- `:secure` / `:unsecure` - Cifrar la nota actual en disco, tras su propia frase de contraseña / guardarla de nuevo como texto plano; `:unlock` pide la frase para leer las notas seguras y `:lock` las vuelve a bloquear
+- `:summarize`, `:gentitle`, `:suggesttags` - Pedir a un modelo de lenguaje que resuma, titule o etiquete la nota (desactivado hasta elegir un proveedor en `[ai]`)
+- `:share` - Subir la nota seleccionada como gist de GitHub (o a un servicio de pegado, `:share paste`) y copiar su URL
+
+### Teclas personalizadas
+- Cambia las teclas en la sección `[keys]` de ~/.termnotes/config.toml (p. ej. `new_note = "n"`)
+- `termnotes --print-keys` muestra cada acción y sus teclas
+- `:tour` muestra otra vez el recorrido inicial; `termnotes --seed-demo` añade unas notas de ejemplo para probar
+- `termnotes --readonly` abre las notas en solo lectura, para verlas sin cambiar nada
+- `enabled = true` en `[lock]` pide una frase de contraseña al abrir y, con `idle_minutes`, tras dejar termnotes inactivo
+- `termnotes backup` guarda todas las notas en un archivo .tar.gz y `termnotes restore <archivo>` las recupera; también se guarda una copia antes de cambios masivos como vaciar la papelera
+- `termnotes import --from joplin notes.jex` y `termnotes export --jex notes.jex` traen notas de Joplin y las llevan a Joplin
+- `termnotes daemon` deja que editores en varias terminales trabajen a la vez en las mismas notas
+- `e2e = true` en `[storage.sync]` cifra las notas antes de que lleguen al servidor de sincronización; `termnotes e2e devices` lista los dispositivos que pueden leerlas
+- `termnotes mcp` deja que asistentes de IA como Claude Desktop busquen y lean notas (`--write` para que también añadan notas)
+- `termnotes publish --out ./sitio` escribe las notas como un sitio web estático con los [[enlaces wiki]] como hipervínculos (`--notebook` o `--tag` para elegir algunas)
+- `termnotes export --archive notes.tnx` reúne todas las notas y archivos adjuntos en un solo archivo; `termnotes import --from archive notes.tnx` los recupera en otra máquina o backend
+- `colors = "light"` (o `dark`, `solarized`, `gruvbox`) en `[ui]` cambia el tema de colores, y una sección `[colors]` cambia el color de elementos concretos
+- Los plugins de Python en ~/.termnotes/plugins/ añaden comandos, teclas y `:transform`; `:plugins` los muestra
+- Una sección `[hooks]` ejecuta comandos cuando se crean, modifican o eliminan notas (p. ej. `note_updated = "~/bin/backup"`)
+
+## Ejemplo de resaltado de código
+
+termnotes resalta la sintaxis de los bloques de código entre ``` o ~~~ con el nombre del lenguaje, mientras los escribes:
+
+```python
+def fibonacci(n):
+    \"\"\"Calcula el n-ésimo número de Fibonacci\"\"\"
+    if n <= 1:
+        return n
+    return fibonacci(n - 1) + fibonacci(n - 2)
+
+# Genera los 10 primeros números de Fibonacci
+for i in range(10):
+    print(f"F({i}) = {fibonacci(i)}")
+```
+
+¡Feliz escritura!
+""",
+}
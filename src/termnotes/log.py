@@ -0,0 +1,136 @@
+"""
+Debug logging
+
+Off unless termnotes runs with --debug (or [debug] enabled), when records
+go to a log file, by default ~/.termnotes/termnotes.log, to attach to bug
+reports. Each record is one line of key=value fields after the time, level,
+component and event:
+
+    2026-10-16T13:04:31.123 DEBUG storage.composite write note_id=3f2a9c1e action=save duration_ms=4.1
+
+Modules log through get_logger("<component>") with event() and timed(),
+which keep field formatting out of the way when logging is off. Note
+contents and command arguments are never logged, only IDs, counts and
+timings.
+"""
+
+import logging
+import logging.handlers
+import os
+import time
+from contextlib import contextmanager
+from datetime import datetime
+from pathlib import Path
+from typing import Iterator
+
+ROOT = "termnotes"
+DEFAULT_PATH = "~/.termnotes/termnotes.log"
+MAX_BYTES = 5 * 1024 * 1024  # Size at which the log is rotated, keeping one old file
+
+
+class FieldFormatter(logging.Formatter):
+    """Formats records as one line of key=value fields"""
+
+    def format(self, record: logging.LogRecord) -> str:
+        """Format a record, with the fields passed to event() or timed()"""
+        stamp = datetime.fromtimestamp(record.created).isoformat(timespec="milliseconds")
+        component = record.name[len(ROOT) + 1:] if record.name.startswith(ROOT + ".") else record.name
+        parts = [stamp, record.levelname, component, record.getMessage()]
+        for key, value in getattr(record, "fields", {}).items():
+            parts.append(f"{key}={_format_value(value)}")
+        line = " ".join(parts)
+        if record.exc_info:
+            line += "\n" + self.formatException(record.exc_info)
+        return line
+
+
+def _format_value(value) -> str:
+    """Format a field value, quoting it if it has spaces or quotes"""
+    if isinstance(value, float):
+        return f"{value:.1f}"
+    text = str(value)
+    if not text or any(c in text for c in ' "=\n'):
+        return '"' + text.replace('\\', '\\\\').replace('"', '\\"').replace('\n', '\\n') + '"'
+    return text
+
+
+def get_logger(component: str) -> logging.Logger:
+    """
+    Get the logger of a part of termnotes
+
+    Args:
+        component: Dotted name, e.g. "storage.composite" or "ui"
+
+    Returns:
+        Logger under the "termnotes" root
+    """
+    return logging.getLogger(f"{ROOT}.{component}")
+
+
+def event(logger: logging.Logger, name: str, level: int = logging.DEBUG, **fields):
+    """
+    Log an event with fields
+
+    Args:
+        logger: Logger from get_logger()
+        name: Event name, e.g. "mode" or "sync"
+        level: Logging level
+        **fields: Values to record with it
+    """
+    if logger.isEnabledFor(level):
+        logger.log(level, name, extra={"fields": fields})
+
+
+@contextmanager
+def timed(logger: logging.Logger, name: str, **fields) -> Iterator[dict]:
+    """
+    Log an event with how long the block took, or the error it raised
+
+    Args:
+        logger: Logger from get_logger()
+        name: Event name
+        **fields: Values to record with it
+
+    Yields:
+        The fields, for the block to add results to (e.g. a count)
+    """
+    if not logger.isEnabledFor(logging.DEBUG):
+        yield fields
+        return
+    start = time.perf_counter()
+    try:
+        yield fields
+    except BaseException as e:
+        fields.update(duration_ms=(time.perf_counter() - start) * 1000, error=f"{type(e).__name__}: {e}")
+        logger.warning(name, extra={"fields": fields})
+        raise
+    fields["duration_ms"] = (time.perf_counter() - start) * 1000
+    logger.debug(name, extra={"fields": fields})
+
+
+def setup_logging(path: str = DEFAULT_PATH) -> Path:
+    """
+    Start writing debug records to a file
+
+    Args:
+        path: Log file; created with its directory, appended to if it exists
+
+    Returns:
+        The expanded path of the log file
+
+    Raises:
+        OSError: If the file can't be opened
+    """
+    log_path = Path(os.path.expanduser(os.path.expandvars(path)))
+    log_path.parent.mkdir(parents=True, exist_ok=True)
+    handler = logging.handlers.RotatingFileHandler(log_path, maxBytes=MAX_BYTES, backupCount=1, encoding="utf-8")
+    handler.setFormatter(FieldFormatter())
+    root = logging.getLogger(ROOT)
+    root.addHandler(handler)
+    root.setLevel(logging.DEBUG)
+    return log_path
+
+
+# Quiet unless setup_logging() is called: no "no handlers" warnings, nothing on stderr
+logging.getLogger(ROOT).addHandler(logging.NullHandler())
+logging.getLogger(ROOT).propagate = False
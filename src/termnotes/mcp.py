@@ -0,0 +1,327 @@
+"""
+MCP server: notes as tools for AI assistants
+
+`termnotes mcp` speaks the Model Context Protocol over stdio (JSON-RPC 2.0,
+one message per line), so clients such as Claude Desktop can search and read
+notes, and, when allowed, create notes and append to them:
+
+    {"mcpServers": {"termnotes": {"command": "termnotes", "args": ["mcp"]}}}
+
+What a client may do is set by an McpScope, from the `[mcp]` config section
+or the command line:
+
+- Reading is always allowed; creating and appending only with `write`.
+- With `notebooks`, only the notes in those notebooks (and the ones inside
+  them) can be seen or changed, and new notes go in the first one.
+- Notes in the trash are never seen. Nothing is ever deleted.
+
+Writes go through journal operations like the editor's, so they fire hooks
+and `u` in the editor undoes them.
+"""
+
+import json
+import sys
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional, TextIO
+from .note import Note
+from .notebook import Notebook, get_note_notebook
+from .search import HIGHLIGHT_END, HIGHLIGHT_START
+from .storage import StorageBackend
+from .i18n import t
+from . import __version__
+
+PROTOCOL_VERSIONS = ("2025-06-18", "2025-03-26", "2024-11-05")  # Newest first
+
+# JSON-RPC error codes
+PARSE_ERROR = -32700
+INVALID_REQUEST = -32600
+METHOD_NOT_FOUND = -32601
+INVALID_PARAMS = -32602
+
+SEARCH_LIMIT = 20  # Results search_notes returns unless asked for a number
+LIST_LIMIT = 50  # Notes list_notes returns unless asked for a number
+
+
+@dataclass
+class McpScope:
+    """What an MCP client may do with the notes"""
+    write: bool = False  # Whether it may create notes and append to them
+    notebooks: List[str] = field(default_factory=list)  # Notebooks it's limited to (none for every note)
+
+    def allows(self, note: Note) -> bool:
+        """Check whether a note is within the scope"""
+        if note.is_trashed or note.is_secure:
+            return False  # Secure notes are kept from assistants, locked or not
+        if not self.notebooks:
+            return True
+        paths = Notebook.ancestor_paths(get_note_notebook(note))
+        return any(Notebook.normalize_path(notebook) in paths for notebook in self.notebooks)
+
+
+class ToolError(Exception):
+    """A tool call that can't be done; its message is shown to the assistant"""
+
+
+READ_TOOLS = [
+    {
+        "name": "search_notes",
+        "description": "Search the notes for words (every word must appear; word prefixes match). "
+                       "Returns the best matches first with their IDs, titles and a snippet.",
+        "inputSchema": {
+            "type": "object",
+            "properties": {
+                "query": {"type": "string", "description": "Words to search for"},
+                "limit": {"type": "integer", "description": f"Most results to return (default {SEARCH_LIMIT})"},
+            },
+            "required": ["query"],
+        },
+    },
+    {
+        "name": "list_notes",
+        "description": "List the most recently updated notes, optionally only those in a notebook or with a tag.",
+        "inputSchema": {
+            "type": "object",
+            "properties": {
+                "notebook": {"type": "string", "description": "Notebook path, such as \"work/projects\""},
+                "tag": {"type": "string", "description": "Tag, without the #"},
+                "limit": {"type": "integer", "description": f"Most notes to return (default {LIST_LIMIT})"},
+            },
+        },
+    },
+    {
+        "name": "read_note",
+        "description": "Read a note's full markdown, with its title, tags, notebook and timestamps.",
+        "inputSchema": {
+            "type": "object",
+            "properties": {"id": {"type": "string", "description": "Note ID"}},
+            "required": ["id"],
+        },
+    },
+]
+
+WRITE_TOOLS = [
+    {
+        "name": "create_note",
+        "description": "Create a note. Returns its ID.",
+        "inputSchema": {
+            "type": "object",
+            "properties": {
+                "title": {"type": "string", "description": "Title, written as the note's first heading"},
+                "content": {"type": "string", "description": "Markdown below the title"},
+                "tags": {"type": "array", "items": {"type": "string"}, "description": "Tags, without the #"},
+            },
+            "required": ["title"],
+        },
+    },
+    {
+        "name": "append_to_note",
+        "description": "Add markdown to the end of a note, on a new line.",
+        "inputSchema": {
+            "type": "object",
+            "properties": {
+                "id": {"type": "string", "description": "Note ID"},
+                "content": {"type": "string", "description": "Markdown to add"},
+            },
+            "required": ["id", "content"],
+        },
+    },
+]
+
+
+def _summary(note: Note) -> Dict[str, Any]:
+    """Describe a note for a tool result"""
+    summary: Dict[str, Any] = {
+        "id": note.id,
+        "title": note.title,
+        "updated_at": f"{note.updated_at.isoformat()}Z",
+    }
+    if note.tags:
+        summary["tags"] = note.tags
+    if get_note_notebook(note):
+        summary["notebook"] = get_note_notebook(note)
+    return summary
+
+
+def _limit(arguments: Dict[str, Any], default: int) -> int:
+    """Get a tool call's limit argument"""
+    limit = arguments.get("limit", default)
+    if not isinstance(limit, int) or isinstance(limit, bool) or limit < 1:
+        raise ToolError("limit must be a positive integer")
+    return limit
+
+
+def _string(arguments: Dict[str, Any], name: str, required: bool = True) -> str:
+    """Get a tool call's string argument"""
+    value = arguments.get(name)
+    if value is None and not required:
+        return ""
+    if not isinstance(value, str) or (required and not value.strip()):
+        raise ToolError(f"{name} must be a non-empty string")
+    return value
+
+
+class McpServer:
+    """Answers MCP requests with the notes in a storage backend"""
+
+    def __init__(self, storage: StorageBackend, scope: McpScope):
+        """
+        Initialize the server
+
+        Args:
+            storage: Backend holding the notes
+            scope: What clients may do
+        """
+        self.storage = storage
+        self.scope = scope
+
+    def tools(self) -> List[Dict[str, Any]]:
+        """Get the tools the scope allows"""
+        return READ_TOOLS + (WRITE_TOOLS if self.scope.write else [])
+
+    def _get_note(self, note_id: str) -> Note:
+        """Get a note within the scope"""
+        note = self.storage.get_note(note_id)
+        if note is None or not self.scope.allows(note):
+            raise ToolError(f"No note with ID {note_id}")
+        return note
+
+    def call_tool(self, name: str, arguments: Dict[str, Any]) -> Any:
+        """
+        Run a tool
+
+        Args:
+            name: Tool name
+            arguments: Its arguments
+
+        Returns:
+            JSON-serializable result
+
+        Raises:
+            ToolError: If the call can't be done
+        """
+        if name not in {tool["name"] for tool in self.tools()}:
+            raise ToolError(f"Unknown tool: {name}")
+        # Pick up what the editor or another program saved meanwhile
+        self.storage.poll_changes()
+
+        if name == "search_notes":
+            results = [result for result in self.storage.search_notes(_string(arguments, "query"))
+                       if self.scope.allows(result.note)]
+            return [dict(_summary(result.note),
+                         snippet=result.snippet.replace(HIGHLIGHT_START, "").replace(HIGHLIGHT_END, ""))
+                    for result in results[:_limit(arguments, SEARCH_LIMIT)]]
+
+        if name == "list_notes":
+            notebook = Notebook.normalize_path(_string(arguments, "notebook", required=False))
+            tag = _string(arguments, "tag", required=False)
+            notes = [note for note in self.storage.get_all_notes() if self.scope.allows(note)
+                     and (not notebook or notebook in Notebook.ancestor_paths(get_note_notebook(note)))
+                     and (not tag or note.has_tag(tag))]
+            notes.sort(key=lambda note: note.updated_at, reverse=True)
+            return [_summary(note) for note in notes[:_limit(arguments, LIST_LIMIT)]]
+
+        if name == "read_note":
+            note = self._get_note(_string(arguments, "id"))
+            return dict(_summary(note), created_at=f"{note.created_at.isoformat()}Z", content=note.content)
+
+        if name == "create_note":
+            title = " ".join(_string(arguments, "title").split())
+            content = _string(arguments, "content", required=False).strip("\n")
+            tags = arguments.get("tags") or []
+            if not isinstance(tags, list) or not all(isinstance(tag, str) for tag in tags):
+                raise ToolError("tags must be a list of strings")
+            note = self.storage.create_note()
+            note.content = f"# {title}\n\n{content}\n" if content else f"# {title}\n"
+            for tag in tags:
+                note.add_tag(tag)
+            if self.scope.notebooks:
+                note.set_property("notebook", Notebook.normalize_path(self.scope.notebooks[0]))
+            with self.storage.journal_operation("create", [note.id]):
+                self.storage.save_note(note)
+            self.storage.flush()
+            return _summary(note)
+
+        # append_to_note
+        note = self._get_note(_string(arguments, "id"))
+        content = _string(arguments, "content")
+        if note.content and not note.content.endswith("\n"):
+            note.content += "\n"
+        note.content += content if content.endswith("\n") else f"{content}\n"
+        with self.storage.journal_operation("save", [note.id]):
+            self.storage.save_note(note)
+        self.storage.flush()
+        return _summary(self.storage.get_note(note.id) or note)
+
+    def handle(self, message: Any) -> Optional[Dict[str, Any]]:
+        """
+        Answer one JSON-RPC message
+
+        Args:
+            message: Decoded message
+
+        Returns:
+            Response, or None for notifications
+        """
+        if not isinstance(message, dict) or message.get("jsonrpc") != "2.0" or "method" not in message:
+            return {"jsonrpc": "2.0", "id": None,
+                    "error": {"code": INVALID_REQUEST, "message": "Invalid request"}}
+        if "id" not in message:
+            return None  # Notifications (initialized, cancelled) need no answer
+        method = message["method"]
+        params = message.get("params") or {}
+
+        def result(value: Any) -> Dict[str, Any]:
+            return {"jsonrpc": "2.0", "id": message["id"], "result": value}
+
+        def error(code: int, text: str) -> Dict[str, Any]:
+            return {"jsonrpc": "2.0", "id": message["id"], "error": {"code": code, "message": text}}
+
+        if method == "initialize":
+            requested = params.get("protocolVersion")
+            return result({
+                "protocolVersion": requested if requested in PROTOCOL_VERSIONS else PROTOCOL_VERSIONS[0],
+                "capabilities": {"tools": {}},
+                "serverInfo": {"name": "termnotes", "version": __version__},
+                "instructions": t("mcp.instructions"),
+            })
+        if method == "ping":
+            return result({})
+        if method == "tools/list":
+            return result({"tools": self.tools()})
+        if method == "tools/call":
+            name = params.get("name")
+            arguments = params.get("arguments") or {}
+            if not isinstance(name, str) or not isinstance(arguments, dict):
+                return error(INVALID_PARAMS, "Invalid tool call")
+            try:
+                value = self.call_tool(name, arguments)
+            except ToolError as e:
+                return result({"content": [{"type": "text", "text": str(e)}], "isError": True})
+            except OSError as e:
+                return result({"content": [{"type": "text", "text": f"Storage error: {e}"}], "isError": True})
+            return result({"content": [{"type": "text", "text": json.dumps(value, ensure_ascii=False, indent=2)}],
+                           "isError": False})
+        return error(METHOD_NOT_FOUND, f"Method not found: {method}")
+
+    def run(self, stdin: Optional[TextIO] = None, stdout: Optional[TextIO] = None):
+        """
+        Answer messages from stdin on stdout until stdin closes
+
+        Args:
+            stdin: Where messages come from, one per line (None for sys.stdin)
+            stdout: Where responses go, one per line (None for sys.stdout)
+        """
+        stdin = stdin or sys.stdin
+        stdout = stdout or sys.stdout
+        for line in stdin:
+            if not line.strip():
+                continue
+            try:
+                message = json.loads(line)
+            except ValueError:
+                response = {"jsonrpc": "2.0", "id": None, "error": {"code": PARSE_ERROR, "message": "Parse error"}}
+            else:
+                response = self.handle(message)
+            if response is not None:
+                stdout.write(json.dumps(response, ensure_ascii=False) + "\n")
+                stdout.flush()
@@ -0,0 +1,98 @@
+"""
+Note timestamps and metadata, for the note list and the header above the editor
+
+The note list ends each note's row with how long ago it was edited (or
+created, when sorted by creation), in the compact form of format_age();
+the header gives the dates, word count and tags of the note the editor
+pane shows.
+"""
+
+from datetime import datetime, timezone
+from typing import Optional
+from .note import Note
+from .utils import utc_now
+from .i18n import t
+
+MINUTE = 60
+HOUR = 60 * MINUTE
+DAY = 24 * HOUR
+WEEK = 7 * DAY
+
+
+def _format_date(when: datetime, now: datetime) -> str:
+    """Format a UTC time as a local date, leaving out the year if it's this year's"""
+    local_when = when.replace(tzinfo=timezone.utc).astimezone()
+    local_now = now.replace(tzinfo=timezone.utc).astimezone()
+    return local_when.strftime("%Y-%m-%d" if local_when.year != local_now.year else "%m-%d")
+
+
+def format_age(when: datetime, now: Optional[datetime] = None) -> str:
+    """
+    Describe how long ago something happened, in a few columns for the note list
+
+    Args:
+        when: The time (UTC)
+        now: Current time (UTC, default: now)
+
+    Returns:
+        "now", "5m", "3h" or "2d" within a week, else the date
+    """
+    now = now or utc_now()
+    seconds = (now - when).total_seconds()
+    if seconds < MINUTE:
+        return t("age.now")
+    if seconds < HOUR:
+        return t("age.minutes", count=int(seconds // MINUTE))
+    if seconds < DAY:
+        return t("age.hours", count=int(seconds // HOUR))
+    if seconds < WEEK:
+        return t("age.days", count=int(seconds // DAY))
+    return _format_date(when, now)
+
+
+def format_ago(when: datetime, now: Optional[datetime] = None) -> str:
+    """
+    Describe how long ago something happened, in words
+
+    Args:
+        when: The time (UTC)
+        now: Current time (UTC, default: now)
+
+    Returns:
+        "just now", "5 min ago", "3h ago" or "2 days ago" within a week, else the date
+    """
+    now = now or utc_now()
+    seconds = (now - when).total_seconds()
+    if seconds < MINUTE:
+        return t("ago.now")
+    if seconds < HOUR:
+        return t("ago.minutes", count=int(seconds // MINUTE))
+    if seconds < DAY:
+        return t("ago.hours", count=int(seconds // HOUR))
+    if seconds < WEEK:
+        return t("ago.days", count=int(seconds // DAY))
+    return t("ago.date", date=_format_date(when, now))
+
+
+def metadata_header(note: Note, words: int, now: Optional[datetime] = None) -> str:
+    """
+    Describe a note in one line: when it was created and edited, its length and tags
+
+    Args:
+        note: The note
+        words: Number of words in it (with unsaved changes, for the open note)
+        now: Current time (UTC, default: now)
+
+    Returns:
+        The line
+    """
+    now = now or utc_now()
+    created = note.created_at.replace(tzinfo=timezone.utc).astimezone()
+    parts = [
+        t("metadata.created", date=f"{created:%Y-%m-%d %H:%M}"),
+        t("metadata.edited", ago=format_ago(note.updated_at, now)),
+        t("metadata.words", count=words),
+    ]
+    if note.tags:
+        parts.append(" ".join(f"#{tag}" for tag in note.tags))
+    return " · ".join(parts)
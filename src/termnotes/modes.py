@@ -2,25 +2,35 @@
 Mode management for the editor
 """
 
+from typing import Callable
 from .editor import Mode
+from .i18n import t
+from .log import event, get_logger
+
+log = get_logger("ui")
 
 
 class ModeManager:
     """Manages editor mode state and transitions"""
 
-    def __init__(self):
+    def __init__(self, read_only: bool = False):
         self.current_mode = Mode.NORMAL
+        self.read_only = read_only  # Never enter insert mode: the notes can't be changed
+        self.can_edit: Callable[[], bool] = lambda: True  # Nor while this is False (a locked secure note is open)
         self.command_buffer = ""  # For commands like :q, :w, dd, etc.
         self.message = ""  # Status message to display
         self.search_query = ""  # Current search query
         self.last_search = ""  # Last executed search for n command
         self.last_search_direction = "forward"  # Direction of last search: "forward" or "backward"
+        self.highlight_query = ""  # Search whose matches are highlighted in the editor ("" for none)
         # Visual mode state
         self.visual_start_row = 0  # Starting row of visual selection
         self.visual_start_col = 0  # Starting column of visual selection
 
     def set_mode(self, mode: Mode):
         """Change the current mode"""
+        if mode != self.current_mode:
+            event(log, "mode", previous=self.current_mode.name, mode=mode.name)
         self.current_mode = mode
         self.command_buffer = ""
 
@@ -45,7 +55,9 @@ class ModeManager:
         return self.current_mode in (Mode.VISUAL, Mode.VISUAL_LINE)
 
     def enter_insert_mode(self):
-        """Enter insert mode"""
+        """Enter insert mode, unless the notes are read-only or the open note can't be edited"""
+        if self.read_only or not self.can_edit():
+            return
         self.set_mode(Mode.INSERT)
 
     def enter_normal_mode(self):
@@ -134,11 +146,11 @@ class ModeManager:
     def get_mode_string(self) -> str:
         """Get display string for current mode"""
         if self.current_mode == Mode.INSERT:
-            return "-- INSERT --"
+            return t("mode.insert")
         elif self.current_mode == Mode.VISUAL:
-            return "-- VISUAL --"
+            return t("mode.visual")
         elif self.current_mode == Mode.VISUAL_LINE:
-            return "-- VISUAL LINE --"
+            return t("mode.visual_line")
         elif self.command_buffer.startswith('/') or self.command_buffer.startswith('?'):
             return self.command_buffer
         elif self.command_buffer:
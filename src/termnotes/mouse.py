@@ -0,0 +1,50 @@
+"""
+Mouse support for the panes
+
+With [ui] mouse on, clicking a row of the note list selects and opens it,
+clicking in the editor focuses it and puts the cursor there (opening a web
+link clicked on), and the scroll wheel moves the selection of the list, or
+scrolls the editor, under the pointer. The panes' windows hand mouse events
+to the UI through a MouseControl.
+"""
+
+from typing import Callable
+from prompt_toolkit.layout.controls import FormattedTextControl
+from prompt_toolkit.mouse_events import MouseButton, MouseEvent, MouseEventType
+
+# Lines the editor scrolls for each step of the scroll wheel
+SCROLL_LINES = 3
+
+
+def is_click(mouse_event: MouseEvent) -> bool:
+    """Check if a mouse event ends a click of the left button (or of any button, on terminals that don't say which)"""
+    return (mouse_event.event_type == MouseEventType.MOUSE_UP and
+            mouse_event.button in (MouseButton.LEFT, MouseButton.UNKNOWN))
+
+
+def scroll_direction(mouse_event: MouseEvent) -> int:
+    """Get which way the scroll wheel turned: 1 down, -1 up, or 0 for other mouse events"""
+    if mouse_event.event_type == MouseEventType.SCROLL_DOWN:
+        return 1
+    if mouse_event.event_type == MouseEventType.SCROLL_UP:
+        return -1
+    return 0
+
+
+class MouseControl(FormattedTextControl):
+    """
+    Text control that hands mouse events to a function
+
+    The function returns whether it handled the event; if not, the event
+    goes to the control's fragments and window as usual.
+    """
+
+    def __init__(self, *args, on_mouse: Callable[[MouseEvent], bool], **kwargs):
+        super().__init__(*args, **kwargs)
+        self.on_mouse = on_mouse
+
+    def mouse_handler(self, mouse_event: MouseEvent):
+        """Handle a mouse event with on_mouse, if it takes it"""
+        if self.on_mouse(mouse_event):
+            return None
+        return super().mouse_handler(mouse_event)
@@ -2,9 +2,18 @@
 Note data model
 """
 
-from typing import Optional, Dict, Any
+from typing import Optional, Dict, Any, List
 from datetime import datetime
+import hashlib
+from .secure import SECURE_PROPERTY, is_sealed
+from .preview import describe, preview_title
+from .textwidth import truncate
 from .utils import utc_now
+from .i18n import t
+
+# Colors a note can be labeled with, in the order C cycles through them; each
+# is styled by the "color.<name>" theme element
+COLORS = ("red", "yellow", "green", "cyan", "blue", "magenta")
 
 
 class Note:
@@ -35,25 +44,119 @@ class Note:
         self.updated_at = updated_at or utc_now()
         self.properties = properties or {}
 
+    @property
+    def version(self) -> str:
+        """
+        Get a short fingerprint of the note's content
+
+        Saving with StorageBackend.update_note() checks the stored note still
+        has the version an edit started from. Only the content counts: tags,
+        pins and other properties don't change it, and timestamps differ
+        between a cache and the backend behind it.
+        """
+        return content_version(self.content)
+
     def get_preview(self, max_length: int = 25) -> str:
         """
         Get a preview of the note for display in sidebar
 
         Args:
-            max_length: Maximum number of characters to return
+            max_length: Maximum width in terminal columns (CJK and emoji take two)
 
         Returns:
-            Preview string (first line of content)
+            Preview string (the first meaningful line, without markdown; see preview.py)
         """
-        # Use first line of content, or empty string if no content
-        if not self.content:
-            preview_text = "(empty note)"
+        if self.is_locked:
+            preview_text = t("secure.locked_title")
         else:
-            preview_text = self.content.split('\n')[0]
+            preview_text = preview_title(self.content) or t("note.empty_preview")
+
+        return truncate(preview_text, max_length)
+
+    @property
+    def description(self) -> str:
+        """Get the first sentence of the note below its title, without markdown ("" if locked)"""
+        if self.is_locked:
+            return ""
+        return describe(self.content)
+
+    @property
+    def title(self) -> str:
+        """Get the note's title: its first non-blank line without markdown heading marks"""
+        if self.is_locked:
+            return t("secure.locked_title")
+        for line in self.content.split('\n'):
+            line = line.strip().lstrip('#').strip()
+            if line:
+                return line
+        return ""
+
+    @property
+    def deleted_at(self) -> Optional[datetime]:
+        """Get when the note was moved to the trash (stored in the "deleted_at" property)"""
+        value = self.properties.get("deleted_at")
+        if not value:
+            return None
+        try:
+            return datetime.fromisoformat(value)
+        except (TypeError, ValueError):
+            return None
+
+    @property
+    def due_at(self) -> Optional[datetime]:
+        """Get when the note is due (stored in the "due_at" property)"""
+        return self._get_time_property("due_at")
+
+    @property
+    def remind_at(self) -> Optional[datetime]:
+        """Get when to remind about the note (stored in the "remind_at" property)"""
+        return self._get_time_property("remind_at")
+
+    def _get_time_property(self, key: str) -> Optional[datetime]:
+        """Read a timestamp property, ignoring values that aren't timestamps"""
+        value = self.properties.get(key)
+        if not value:
+            return None
+        try:
+            return datetime.fromisoformat(value)
+        except (TypeError, ValueError):
+            return None
+
+    @property
+    def is_trashed(self) -> bool:
+        """Check if the note is in the trash"""
+        return self.has_property("deleted_at")
+
+    @property
+    def pinned(self) -> bool:
+        """Check if the note is pinned to the top of the list (the "pinned" property)"""
+        return bool(self.properties.get("pinned", False))
+
+    @property
+    def starred(self) -> bool:
+        """Check if the note is starred as a favorite (the "starred" property)"""
+        return bool(self.properties.get("starred", False))
+
+    @property
+    def color(self) -> Optional[str]:
+        """Get the note's color label (the "color" property), one of COLORS or None"""
+        color = self.properties.get("color")
+        return color if color in COLORS else None
+
+    @property
+    def is_archived(self) -> bool:
+        """Check if the note is archived out of the main list (the "archived" property)"""
+        return bool(self.properties.get("archived", False))
+
+    @property
+    def is_secure(self) -> bool:
+        """Check if the note is encrypted at rest with the secure notes' passphrase (the "secure" property)"""
+        return bool(self.properties.get(SECURE_PROPERTY, False))
 
-        if len(preview_text) > max_length:
-            return preview_text[:max_length - 3] + "..."
-        return preview_text
+    @property
+    def is_locked(self) -> bool:
+        """Check if the note is secure and its content is still encrypted, the passphrase not being known"""
+        return self.is_secure and is_sealed(self.content)
 
     def get_property(self, key: str, default: Any = None) -> Any:
         """
@@ -99,7 +202,136 @@ class Note:
         """
         self.properties.pop(key, None)
 
+    @staticmethod
+    def normalize_tag(tag: str) -> str:
+        """
+        Normalize a tag name for storage
+
+        Strips surrounding whitespace and a leading '#'.
+
+        Args:
+            tag: Tag as typed by the user (e.g. "#work")
+
+        Returns:
+            Normalized tag name (e.g. "work")
+        """
+        return tag.strip().lstrip('#').strip()
+
+    @property
+    def tags(self) -> List[str]:
+        """Get the note's tags (stored in the "tags" property)"""
+        return list(self.properties.get("tags", []))
+
+    def has_tag(self, tag: str) -> bool:
+        """
+        Check if the note has a tag
+
+        Args:
+            tag: Tag name
+
+        Returns:
+            True if the note is tagged with it
+        """
+        return self.normalize_tag(tag) in self.tags
+
+    def add_tag(self, tag: str) -> bool:
+        """
+        Add a tag to the note
+
+        Args:
+            tag: Tag name
+
+        Returns:
+            True if the tag was added, False if empty or already present
+        """
+        tag = self.normalize_tag(tag)
+        tags = self.tags
+        if not tag or tag in tags:
+            return False
+        tags.append(tag)
+        self.properties["tags"] = sorted(tags)
+        return True
+
+    def remove_tag(self, tag: str) -> bool:
+        """
+        Remove a tag from the note
+
+        Args:
+            tag: Tag name
+
+        Returns:
+            True if the tag was removed, False if the note didn't have it
+        """
+        tag = self.normalize_tag(tag)
+        tags = self.tags
+        if tag not in tags:
+            return False
+        tags.remove(tag)
+        if tags:
+            self.properties["tags"] = tags
+        else:
+            self.delete_property("tags")
+        return True
+
     def __repr__(self) -> str:
         preview = self.get_preview(20)
         props_count = len(self.properties)
         return f"Note(id={self.id}, preview={preview}, properties={props_count})"
+
+
+
+# Characters of content kept in a NoteSummary: the title and a preview
+SUMMARY_LENGTH = 300
+
+
+class NoteSummary(Note):
+    """
+    A note as the note list shows it: its properties and timestamps, but only
+    the first SUMMARY_LENGTH characters of its content
+
+    Listing summaries keeps the list fast with many long notes. Never save
+    one; get the full note with StorageBackend.get_note().
+    """
+
+    @classmethod
+    def of(cls, note: Note) -> "NoteSummary":
+        """
+        Summarize a full note
+
+        Args:
+            note: The note
+
+        Returns:
+            Its summary
+        """
+        return cls(note.id, note.content[:SUMMARY_LENGTH], note.created_at, note.updated_at, note.properties)
+
+
+def content_version(content: str) -> str:
+    """
+    Get the version of a note with some content (see Note.version)
+
+    Args:
+        content: Note content
+
+    Returns:
+        Hex fingerprint of the content
+    """
+    return hashlib.sha1(content.encode("utf-8")).hexdigest()[:16]
+
+
+def parse_color(name: str) -> Optional[str]:
+    """
+    Get the color label a user typed, in English or the active locale
+
+    Args:
+        name: Color name, e.g. "red" or "rojo"
+
+    Returns:
+        The name in COLORS, or None if it isn't one
+    """
+    name = name.strip().lower()
+    for color in COLORS:
+        if name in (color, t(f"color.{color}").lower()):
+            return color
+    return None
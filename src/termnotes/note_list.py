@@ -2,37 +2,207 @@
 Note list management
 """
 
-from typing import List, Optional
-from .note import Note
+from dataclasses import dataclass
+from datetime import datetime
+from typing import List, Optional, Set, Tuple
+from .note import COLORS, Note, NoteSummary
+from .notebook import Notebook, build_notebook_tree, get_note_notebook
+from .search import SearchResult, build_snippet, count_matches, fuzzy_search, tokenize_query
 from .storage import StorageBackend
 
+# Note list orders: most recently updated first, newest first, by title, as arranged by the user,
+# or soonest due first
+SORT_ORDERS = ("updated", "created", "title", "manual", "due")
+
+# Notes read from storage at a time when reloading the list
+PAGE_SIZE = 500
+
+
+def sort_notes(notes: List[Note], order: str) -> List[Note]:
+    """
+    Sort notes for the note list, with pinned notes first
+
+    In manual order, notes are sorted by their "position" property; notes
+    that were never moved come first, most recently updated first. In due
+    order, notes without a due date follow the others, most recently
+    updated first.
+
+    Args:
+        notes: Notes to sort
+        order: One of SORT_ORDERS
+
+    Returns:
+        Sorted copy of the notes
+    """
+    if order == "created":
+        notes = sorted(notes, key=lambda note: note.created_at, reverse=True)
+    elif order == "due":
+        notes = sorted(notes, key=lambda note: note.updated_at, reverse=True)
+        notes = sorted(notes, key=lambda note: (note.due_at is None, note.due_at or datetime.min))
+    elif order == "title":
+        notes = sorted(notes, key=lambda note: note.title.casefold())
+    elif order == "manual":
+        unplaced = [note for note in notes if note.get_property("position") is None]
+        placed = [note for note in notes if note.get_property("position") is not None]
+        notes = (sorted(unplaced, key=lambda note: note.updated_at, reverse=True)
+                 + sorted(placed, key=lambda note: note.get_property("position")))
+    else:
+        notes = sorted(notes, key=lambda note: note.updated_at, reverse=True)
+    return sorted(notes, key=lambda note: not note.pinned)
+
+
+@dataclass
+class SidebarRow:
+    """A single row in the sidebar tree: either a notebook or a note"""
+    depth: int
+    note: Optional[Note] = None
+    notebook: Optional[Notebook] = None
+    snippet: Optional[str] = None  # Highlighted excerpt for search results
+
 
 class NoteListManager:
-    """Manages a list of notes and selection state"""
+    """
+    Manages a tree of notebooks and notes, and selection state
 
-    def __init__(self, storage: StorageBackend):
+    The list holds NoteSummary objects, so thousands of long notes don't
+    all sit in memory in full; selected_note loads the selected one.
+    """
+
+    def __init__(self, storage: StorageBackend, sort_order: str = "updated"):
         """
         Initialize note list manager
 
         Args:
             storage: StorageBackend instance for persistence
+            sort_order: Order of notes in the list, one of SORT_ORDERS
         """
         self.storage = storage
-        self.notes: List[Note] = []
+        self.sort_order = sort_order
+        self.notes: List[Note] = []  # NoteSummary objects
+        self.view_ids: Set[str] = set()  # IDs of the notes in the trash, archive or other notes, before filtering
+        # (summary, full note) of the last selected note, so it isn't read again on every redraw
+        self._selected_full: Tuple[Optional[NoteSummary], Optional[Note]] = (None, None)
         self.in_memory_note: Optional[Note] = None  # Track unsaved new note
-        self.selected_index: int = 0
-        self.reload_notes()
+        self._selected_before_new: Optional[SidebarRow] = None  # Row selected when the in-memory note was made
+        self.selected_index: int = 0  # Index into get_rows()
+        self.tag_filter: Optional[str] = None  # Only list notes with this tag
+        self.collapsed_notebooks: Set[str] = set()  # Paths of collapsed notebooks
+        # Every notebook path, as of the last reload: reading it from storage takes every note
+        self.notebook_paths: List[str] = []
+        self.show_trash: bool = False  # List trashed notes instead of the others
+        self.show_archive: bool = False  # List archived notes instead of the others
+        self.show_starred: bool = False  # Only list starred notes
+        self.color_filter: Optional[str] = None  # Only list notes with this color label
+        self.marked_ids: Set[str] = set()  # Notes marked for a bulk action
 
         # Search state for sidebar search
-        self.search_matches: List[int] = []  # Indices of notes matching search
+        self.search_query: str = ""  # Query whose results are listed
+        self.search_results: List[SearchResult] = []  # Ranked results, best first
+        self.search_matches: List[str] = []  # IDs of notes matching search
         self.current_match_index: int = -1  # Index in search_matches list
 
+        self.reload_notes()
+
+    def _in_view(self, note: Note) -> bool:
+        """Check if a note belongs in the current view (the trash, the archive, or the other notes), filters aside"""
+        if self.show_trash:
+            return note.is_trashed
+        return not note.is_trashed and note.is_archived == self.show_archive
+
+    def _is_listed(self, note: Note) -> bool:
+        """Check if a note belongs in the current view: the trash, the archive, or the other notes (maybe only starred)"""
+        if self.show_trash:
+            return note.is_trashed
+        if self.show_starred and not note.starred:
+            return False
+        if self.color_filter and note.color != self.color_filter:
+            return False
+        return self._in_view(note)
+
     def reload_notes(self):
-        """Reload notes from storage"""
-        self.notes = self.storage.get_all_notes()
-        # Ensure selected_index is valid
-        if self.selected_index >= len(self.notes):
-            self.selected_index = max(0, len(self.notes) - 1)
+        """
+        Reload notes from storage, applying the trash or archive view, tag filter and search if set
+
+        The selected note or notebook stays selected if it is still listed;
+        otherwise the row now at its place is (the next note after a delete).
+        """
+        selected = self.selected_row
+        notes = self._load_summaries()
+        self.notebook_paths = self.storage.list_notebooks()
+        self.view_ids = {note.id for note in notes if self._in_view(note)}
+        if self.tag_filter:
+            notes = [NoteSummary.of(note) for note in self.storage.get_notes_by_tag(self.tag_filter)]
+        self.notes = sort_notes([note for note in notes if self._is_listed(note)], self.sort_order)
+        self.marked_ids &= {note.id for note in self.notes}
+        if self.search_query:
+            self._run_search()
+        self.clamp_selection()
+        self._reselect(selected)
+
+    def _reselect(self, row: Optional[SidebarRow]):
+        """Select the row showing the same note or notebook as an earlier row, if there is one"""
+        if row is None:
+            return
+        for i, other in enumerate(self.get_rows()):
+            if ((row.note and other.note and other.note.id == row.note.id) or
+                    (row.notebook and other.notebook and other.notebook.path == row.notebook.path)):
+                self.selected_index = i
+                return
+
+    def _load_summaries(self) -> List[NoteSummary]:
+        """Read every note's summary, a page at a time so other threads get the storage in between"""
+        notes = []
+        while True:
+            page = self.storage.list_note_summaries(len(notes), PAGE_SIZE)
+            notes.extend(page)
+            if len(page) < PAGE_SIZE:
+                return notes
+
+    def update_note(self, note: Note):
+        """
+        Put a just-saved note in its place in the list without reloading every note
+
+        While a tag filter or search is active the list comes from the
+        storage's indexes, so it is reloaded instead.
+
+        Args:
+            note: The saved note
+        """
+        if self.tag_filter or self.search_query:
+            self.reload_notes()
+            return
+        if self._in_view(note):
+            self.view_ids.add(note.id)
+        else:
+            self.view_ids.discard(note.id)
+        if not note.is_trashed:
+            # A notebook the note was moved out of stays listed until the next reload
+            self.notebook_paths = sorted(set(self.notebook_paths) |
+                                         set(Notebook.ancestor_paths(get_note_notebook(note))))
+        selected = self.selected_row
+        notes = [listed for listed in self.notes if listed.id != note.id]
+        if self._is_listed(note):
+            notes.append(note if isinstance(note, NoteSummary) else NoteSummary.of(note))
+        self.notes = sort_notes(notes, self.sort_order)
+        self.clamp_selection()
+        self._reselect(selected)
+
+    def count_notes(self) -> Tuple[int, int]:
+        """
+        Count the notes listed, and the notes in the view they're listed from
+
+        Returns:
+            (listed, in view): notes shown after the tag, color, starred and
+            search filters, and all notes in the trash, archive or other notes
+        """
+        listed = len(self.search_results) if self.search_query else len(self.notes)
+        return listed, len(self.view_ids)
+
+    def clamp_selection(self):
+        """Ensure selected_index points at an existing row"""
+        row_count = len(self.get_rows())
+        if self.selected_index >= row_count:
+            self.selected_index = max(0, row_count - 1)
 
     def get_all_notes_including_memory(self) -> List[Note]:
         """Get all notes including the in-memory note if present"""
@@ -40,49 +210,431 @@ class NoteListManager:
             return [self.in_memory_note] + self.notes
         return self.notes
 
+    def get_rows(self) -> List[SidebarRow]:
+        """
+        Get the visible sidebar rows
+
+        The in-memory note comes first, then notebooks (alphabetically, with
+        their contents unless collapsed), then notes outside any notebook.
+        Notebooks are only shown when they hold listed notes, or always when
+        not filtering by tag or showing the trash or archive. While search results are shown, the rows are the
+        matching notes in rank order instead (none if nothing matches the live filter).
+
+        Returns:
+            List of rows in display order
+        """
+        if self.search_query:
+            return [
+                SidebarRow(depth=0, note=result.note, snippet=result.snippet)
+                for result in self.search_results
+            ]
+
+        rows = []
+        if self.in_memory_note:
+            rows.append(SidebarRow(depth=0, note=self.in_memory_note))
+
+        filtered = self.tag_filter or self.color_filter or self.show_trash or self.show_archive or self.show_starred
+        extra_paths = [] if filtered else self.notebook_paths
+        root = build_notebook_tree(self.notes, extra_paths)
+        self._append_notebook_rows(root, rows, depth=0)
+        return rows
+
+    def _append_notebook_rows(self, notebook: Notebook, rows: List[SidebarRow], depth: int):
+        """Append rows for a notebook's children and notes, recursing into expanded notebooks"""
+        for child in notebook.children:
+            rows.append(SidebarRow(depth=depth, notebook=child))
+            if child.path not in self.collapsed_notebooks:
+                self._append_notebook_rows(child, rows, depth + 1)
+        for note in notebook.notes:
+            rows.append(SidebarRow(depth=depth, note=note))
+
     @property
-    def selected_note(self) -> Optional[Note]:
-        """Get the currently selected note"""
-        all_notes = self.get_all_notes_including_memory()
-        if 0 <= self.selected_index < len(all_notes):
-            return all_notes[self.selected_index]
+    def selected_row(self) -> Optional[SidebarRow]:
+        """Get the currently selected row"""
+        rows = self.get_rows()
+        if 0 <= self.selected_index < len(rows):
+            return rows[self.selected_index]
         return None
 
+    @property
+    def selected_note(self) -> Optional[Note]:
+        """
+        Get the currently selected note in full (None if a notebook is selected)
+
+        The note is read from storage the first time it is selected, and
+        again after the list is reloaded or the note updated.
+        """
+        row = self.selected_row
+        if row is None or not isinstance(row.note, NoteSummary):
+            return row.note if row else None
+        cached_summary, full = self._selected_full
+        if cached_summary is not row.note:
+            # None for a note deleted since the list was loaded: a summary must never be edited
+            full = self.storage.get_note(row.note.id)
+            self._selected_full = (row.note, full)
+        return full
+
+    @property
+    def selected_notebook(self) -> Optional[Notebook]:
+        """Get the currently selected notebook (None if a note is selected)"""
+        row = self.selected_row
+        return row.notebook if row else None
+
+    def select_note_by_id(self, note_id: str) -> bool:
+        """
+        Select the note with the given ID, expanding its notebook if collapsed
+
+        Args:
+            note_id: ID of the note to select
+
+        Returns:
+            True if the note is in the list and was selected
+        """
+        # Search results are a flat list, so only the tree needs expanding
+        if not self.search_query:
+            for note in self.get_all_notes_including_memory():
+                if note.id == note_id:
+                    self.collapsed_notebooks.difference_update(
+                        Notebook.ancestor_paths(get_note_notebook(note))
+                    )
+                    break
+
+        for i, row in enumerate(self.get_rows()):
+            if row.note and row.note.id == note_id:
+                self.selected_index = i
+                return True
+        return False
+
+    def select_notebook(self, path: str) -> bool:
+        """
+        Select the notebook with the given path, expanding its parents
+
+        Args:
+            path: Notebook path
+
+        Returns:
+            True if the notebook is in the list and was selected
+        """
+        path = Notebook.normalize_path(path)
+        self.collapsed_notebooks.difference_update(Notebook.ancestor_paths(path)[:-1])
+        for i, row in enumerate(self.get_rows()):
+            if row.notebook and row.notebook.path == path:
+                self.selected_index = i
+                return True
+        return False
+
+    def toggle_notebook(self, path: str):
+        """
+        Collapse an expanded notebook or expand a collapsed one
+
+        Args:
+            path: Notebook path
+        """
+        if path in self.collapsed_notebooks:
+            self.collapsed_notebooks.discard(path)
+        else:
+            self.collapsed_notebooks.add(path)
+        self.clamp_selection()
+
+    def collapse_all(self):
+        """Collapse every notebook, keeping a top-level row selected"""
+        row = self.selected_row
+        self.collapsed_notebooks = set(self.notebook_paths)
+        if row and row.notebook:
+            self.select_notebook(Notebook.ancestor_paths(row.notebook.path)[0])
+        elif row and row.note and get_note_notebook(row.note):
+            self.select_notebook(Notebook.ancestor_paths(get_note_notebook(row.note))[0])
+        else:
+            self.clamp_selection()
+
+    def expand_all(self):
+        """Expand every notebook, keeping the selection on the same row"""
+        row = self.selected_row
+        self.collapsed_notebooks.clear()
+        if row and row.note:
+            self.select_note_by_id(row.note.id)
+        elif row and row.notebook:
+            self.select_notebook(row.notebook.path)
+
+    def set_tag_filter(self, tag: Optional[str]):
+        """
+        Show only notes with a tag, or all notes if tag is None
+
+        Keeps the selected note selected if it is still listed.
+
+        Args:
+            tag: Tag name, or None to clear the filter
+        """
+        selected = self.selected_note
+        self.tag_filter = Note.normalize_tag(tag) if tag else None
+        self.clear_search()
+        self.reload_notes()
+        if selected is None or not self.select_note_by_id(selected.id):
+            self.selected_index = 0
+
+    def cycle_sort_order(self) -> str:
+        """
+        Sort the list by the next of SORT_ORDERS, keeping the selected note selected
+
+        Returns:
+            The new sort order
+        """
+        index = SORT_ORDERS.index(self.sort_order) if self.sort_order in SORT_ORDERS else -1
+        self.sort_order = SORT_ORDERS[(index + 1) % len(SORT_ORDERS)]
+        self.reload_notes()
+        return self.sort_order
+
+    def cycle_tag_filter(self) -> Optional[str]:
+        """
+        Advance the tag filter to the next tag in use
+
+        Cycles through all tags in alphabetical order and then back to
+        showing all notes.
+
+        Returns:
+            The new tag filter, or None if showing all notes
+        """
+        tags = self.storage.list_tags()
+        if not tags:
+            self.set_tag_filter(None)
+        elif self.tag_filter in tags:
+            index = tags.index(self.tag_filter) + 1
+            self.set_tag_filter(tags[index] if index < len(tags) else None)
+        else:
+            self.set_tag_filter(tags[0])
+        return self.tag_filter
+
+    def set_color_filter(self, color: Optional[str]):
+        """
+        Show only notes with a color label, or all notes if color is None
+
+        Args:
+            color: One of COLORS, or None to clear the filter
+        """
+        self.color_filter = color
+        self.clear_search()
+        self.reload_notes()
+        self.clamp_selection()
+
+    def cycle_color_filter(self) -> Optional[str]:
+        """
+        Advance the color filter to the next color some note has
+
+        Cycles through the colors in use in COLORS order and then back to
+        showing all notes.
+
+        Returns:
+            The new color filter, or None if showing all notes
+        """
+        in_use = {note.color for note in self._load_summaries() if not note.is_trashed}
+        colors = [color for color in COLORS if color in in_use]
+        if self.color_filter in colors:
+            index = colors.index(self.color_filter) + 1
+            self.set_color_filter(colors[index] if index < len(colors) else None)
+        else:
+            self.set_color_filter(colors[0] if colors else None)
+        return self.color_filter
+
+    def toggle_trash(self) -> bool:
+        """
+        Switch between listing trashed notes and all other notes
+
+        Returns:
+            True if the trash is now shown
+        """
+        self.show_trash = not self.show_trash
+        self.show_archive = False
+        self.marked_ids.clear()
+        self.clear_search()
+        self.reload_notes()
+        self.selected_index = 0
+        return self.show_trash
+
+    def toggle_starred(self) -> bool:
+        """
+        Switch between listing only starred notes and every note
+
+        Returns:
+            True if only starred notes are now listed
+        """
+        self.show_starred = not self.show_starred
+        self.clear_search()
+        self.reload_notes()
+        self.selected_index = 0
+        return self.show_starred
+
+    def toggle_archive(self) -> bool:
+        """
+        Switch between listing archived notes and the main list
+
+        Returns:
+            True if the archive is now shown
+        """
+        self.show_archive = not self.show_archive
+        self.show_trash = False
+        self.marked_ids.clear()
+        self.clear_search()
+        self.reload_notes()
+        self.selected_index = 0
+        return self.show_archive
+
     def move_selection_up(self):
         """Move selection up in the list"""
         if self.selected_index > 0:
             self.selected_index -= 1
 
+    def move_note(self, note_id: str, offset: int) -> bool:
+        """
+        Move a note up or down among its neighbours, for the manual sort order
+
+        Notes only move within their notebook, and pinned notes only among
+        pinned notes.
+
+        Args:
+            note_id: ID of the note to move
+            offset: Number of places to move (negative moves up)
+
+        Returns:
+            True if the note moved
+        """
+        note = next((note for note in self.notes if note.id == note_id), None)
+        if note is None:
+            return False
+
+        notebook = get_note_notebook(note)
+        siblings = [
+            other for other in self.notes
+            if get_note_notebook(other) == notebook and other.pinned == note.pinned
+        ]
+        index = siblings.index(note)
+        target = index + offset
+        if not 0 <= target < len(siblings):
+            return False
+
+        siblings.insert(target, siblings.pop(index))
+        self.storage.reorder_notes([sibling.id for sibling in siblings])
+        self.reload_notes()
+        self.select_note_by_id(note_id)
+        return True
+
     def move_selection_down(self):
         """Move selection down in the list"""
-        all_notes = self.get_all_notes_including_memory()
-        if self.selected_index < len(all_notes) - 1:
+        if self.selected_index < len(self.get_rows()) - 1:
             self.selected_index += 1
 
     def get_note_count(self) -> int:
         """Get total number of notes"""
         return len(self.get_all_notes_including_memory())
 
-    def get_note_at_index(self, index: int) -> Optional[Note]:
-        """Get note at specified index"""
-        all_notes = self.get_all_notes_including_memory()
-        if 0 <= index < len(all_notes):
-            return all_notes[index]
-        return None
+    def get_row_count(self) -> int:
+        """Get number of visible sidebar rows"""
+        return len(self.get_rows())
 
     def set_in_memory_note(self, note: Optional[Note]):
         """Set the in-memory note and select it"""
+        if note and self.in_memory_note is None:
+            self._selected_before_new = self.selected_row
         self.in_memory_note = note
         if note:
             self.selected_index = 0  # Select the in-memory note (always at top)
 
     def clear_in_memory_note(self):
-        """Clear the in-memory note"""
+        """Clear the in-memory note, selecting what was selected before it if it was selected"""
+        if self.in_memory_note is None:
+            return
+        was_selected = self.selected_index == 0 and not self.search_query
         self.in_memory_note = None
+        if was_selected:
+            # Rows moved up by one; go back to the row selected before the new note, if still listed
+            self.clamp_selection()
+            self._reselect(self._selected_before_new)
+        elif self.selected_index > 0 and not self.search_query:
+            self.selected_index -= 1
+        self._selected_before_new = None
+
+    def toggle_mark(self, note_id: str) -> bool:
+        """
+        Mark a note for a bulk action, or unmark it
+
+        Args:
+            note_id: ID of a listed note
+
+        Returns:
+            True if the note is now marked
+        """
+        if note_id in self.marked_ids:
+            self.marked_ids.discard(note_id)
+            return False
+        self.marked_ids.add(note_id)
+        return True
+
+    @property
+    def marked_notes(self) -> List[Note]:
+        """Get the marked notes, in list order"""
+        return [note for note in self.notes if note.id in self.marked_ids]
+
+    def is_showing_search_results(self) -> bool:
+        """Check if the sidebar lists search results instead of the tree"""
+        return bool(self.search_query)
+
+    def _run_search(self):
+        """
+        Query storage for the current search, keeping results within the tag filter
+
+        Full-text matches come first, in the storage's rank order, followed by
+        notes that only match fuzzily.
+        """
+        listed_ids = {note.id for note in self.notes}
+        self.search_results = [
+            result for result in self.storage.search_notes(self.search_query)
+            if result.note.id in listed_ids
+        ]
+
+        # The unsaved in-memory note isn't in storage, so match it directly
+        terms = tokenize_query(self.search_query)
+        if self.in_memory_note and count_matches(self.in_memory_note.content, terms):
+            self.search_results.insert(0, SearchResult(
+                note=self.in_memory_note,
+                snippet=build_snippet(self.in_memory_note.content, terms),
+                rank=float("-inf")
+            ))
+
+        # Fuzzy matches from the storage, which has the whole notes rather than their summaries
+        fuzzy = self.storage.fuzzy_search_notes(self.search_query)
+        if self.in_memory_note:
+            fuzzy = sorted(fuzzy_search([self.in_memory_note], self.search_query) + fuzzy,
+                           key=lambda result: result.rank)
+        matched_ids = {result.note.id for result in self.search_results}
+        self.search_results.extend(
+            result for result in fuzzy
+            if result.note.id not in matched_ids and (result.note.id in listed_ids or result.note is self.in_memory_note)
+        )
+
+        self.search_matches = [result.note.id for result in self.search_results]
+
+    def filter_notes(self, query: str):
+        """
+        Narrow the sidebar to notes matching a query as it is typed
+
+        Unlike search_notes, the results are listed even when there are none,
+        so the list empties rather than jumping back to the tree. An empty
+        query shows the tree again.
+
+        Args:
+            query: Search string typed so far
+        """
+        if not query:
+            self.clear_search()
+            return
+
+        self.search_query = query
+        self._run_search()
+        self.current_match_index = 0 if self.search_matches else -1
+        self.selected_index = 0
 
     def search_notes(self, query: str) -> bool:
         """
-        Search for query across all note contents and store matching indices.
+        Search note contents and list the ranked results in the sidebar
 
         Args:
             query: Search string
@@ -90,25 +642,20 @@ class NoteListManager:
         Returns:
             True if any matches found, False otherwise
         """
+        self.clear_search()
         if not query:
-            self.search_matches = []
-            self.current_match_index = -1
             return False
 
-        self.search_matches = []
-        all_notes = self.get_all_notes_including_memory()
-
-        # Find all notes containing the query (case-sensitive)
-        for i, note in enumerate(all_notes):
-            if query in note.content:
-                self.search_matches.append(i)
+        self.search_query = query
+        self._run_search()
 
         if self.search_matches:
             self.current_match_index = 0
-            self.selected_index = self.search_matches[0]
+            self.selected_index = 0
             return True
         else:
-            self.current_match_index = -1
+            self.search_query = ""
+            self.clamp_selection()
             return False
 
     def search_next(self) -> bool:
@@ -123,7 +670,7 @@ class NoteListManager:
 
         # Move to next match (wrap around)
         self.current_match_index = (self.current_match_index + 1) % len(self.search_matches)
-        self.selected_index = self.search_matches[self.current_match_index]
+        self.select_note_by_id(self.search_matches[self.current_match_index])
         return True
 
     def search_previous(self) -> bool:
@@ -138,10 +685,15 @@ class NoteListManager:
 
         # Move to previous match (wrap around)
         self.current_match_index = (self.current_match_index - 1) % len(self.search_matches)
-        self.selected_index = self.search_matches[self.current_match_index]
+        self.select_note_by_id(self.search_matches[self.current_match_index])
         return True
 
     def clear_search(self):
-        """Clear search state"""
+        """Clear search state and return to the notebook tree, keeping the selected note"""
+        selected = self.selected_note if self.search_query else None
+        self.search_query = ""
+        self.search_results = []
         self.search_matches = []
         self.current_match_index = -1
+        if selected:
+            self.select_note_by_id(selected.id)
@@ -0,0 +1,141 @@
+"""
+Notebook data model
+
+Notebooks organize notes into a folder-like hierarchy. A note's notebook is
+stored as a slash-separated path in its "notebook" property (e.g.
+"work/projects"), so every storage backend persists the hierarchy without
+schema changes. A notebook's parent is the path with its last component
+removed.
+"""
+
+from typing import Dict, Iterable, List, Optional
+from .note import Note
+
+
+class Notebook:
+    """A node in the notebook tree, holding child notebooks and notes"""
+
+    SEPARATOR = "/"
+
+    def __init__(self, path: str = ""):
+        """
+        Initialize a notebook
+
+        Args:
+            path: Normalized notebook path ("" for the root)
+        """
+        self.path = path
+        self.children: List["Notebook"] = []
+        self.notes: List[Note] = []
+
+    @property
+    def name(self) -> str:
+        """Get the notebook's own name (last path component)"""
+        return self.path.rsplit(self.SEPARATOR, 1)[-1]
+
+    @property
+    def parent_path(self) -> Optional[str]:
+        """Get the parent notebook's path, or None for the root"""
+        if not self.path:
+            return None
+        if self.SEPARATOR not in self.path:
+            return ""
+        return self.path.rsplit(self.SEPARATOR, 1)[0]
+
+    @property
+    def depth(self) -> int:
+        """Get nesting depth (0 for top-level notebooks and the root)"""
+        return self.path.count(self.SEPARATOR) if self.path else 0
+
+    def note_count(self) -> int:
+        """Get number of notes in this notebook and all descendants"""
+        return len(self.notes) + sum(child.note_count() for child in self.children)
+
+    @classmethod
+    def normalize_path(cls, path: Optional[str]) -> str:
+        """
+        Normalize a notebook path
+
+        Strips whitespace and empty components, so " /work//projects/ "
+        becomes "work/projects". "." and ".." components are dropped too,
+        since notebook paths from files and other machines name directories
+        in some backends ("../../x" becomes "x"); a backslash separates
+        components like a slash, as it does on Windows.
+
+        Args:
+            path: Path as typed by the user
+
+        Returns:
+            Normalized path ("" for the root)
+        """
+        if not path:
+            return ""
+        parts = [part.strip() for part in path.replace("\\", cls.SEPARATOR).split(cls.SEPARATOR)]
+        return cls.SEPARATOR.join(part for part in parts if part and part not in (".", ".."))
+
+    @classmethod
+    def ancestor_paths(cls, path: str) -> List[str]:
+        """
+        Get a path and all of its ancestors, outermost first
+
+        Args:
+            path: Normalized notebook path
+
+        Returns:
+            List of paths, e.g. ["work", "work/projects"] for "work/projects"
+        """
+        if not path:
+            return []
+        parts = path.split(cls.SEPARATOR)
+        return [cls.SEPARATOR.join(parts[:i]) for i in range(1, len(parts) + 1)]
+
+    def __repr__(self) -> str:
+        return f"Notebook(path={self.path!r}, children={len(self.children)}, notes={len(self.notes)})"
+
+
+def get_note_notebook(note: Note) -> str:
+    """
+    Get the normalized notebook path of a note
+
+    Args:
+        note: Note to inspect
+
+    Returns:
+        Notebook path ("" if the note is at the root)
+    """
+    return Notebook.normalize_path(note.get_property("notebook", ""))
+
+
+def build_notebook_tree(notes: Iterable[Note], extra_paths: Iterable[str] = ()) -> Notebook:
+    """
+    Build the notebook tree for a set of notes
+
+    Child notebooks are sorted by name; notes keep their input order.
+
+    Args:
+        notes: Notes to place in the tree
+        extra_paths: Additional (possibly empty) notebook paths to include
+
+    Returns:
+        Root notebook
+    """
+    root = Notebook("")
+    notebooks: Dict[str, Notebook] = {"": root}
+
+    def get_or_create(path: str) -> Notebook:
+        if path not in notebooks:
+            notebook = Notebook(path)
+            notebooks[path] = notebook
+            get_or_create(notebook.parent_path).children.append(notebook)
+        return notebooks[path]
+
+    for path in extra_paths:
+        get_or_create(Notebook.normalize_path(path))
+
+    for note in notes:
+        get_or_create(get_note_notebook(note)).notes.append(note)
+
+    for notebook in notebooks.values():
+        notebook.children.sort(key=lambda child: child.name.lower())
+
+    return root
@@ -0,0 +1,151 @@
+"""
+Outline: the headings of a note, for moving around long notes
+
+Headings are "#" to "######" lines outside code fences, as the editor
+styles them. The outline view lists the open note's headings in the
+sidebar, indented by level; selecting one scrolls the editor to its
+section. split_sections() cuts a note at its top-level headings for
+:split.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import List, Optional, Tuple
+
+HEADING = re.compile(r'^(#{1,6})\s+(.*?)\s*#*\s*$')
+FENCE = re.compile(r'^\s*(```|~~~)')
+
+
+@dataclass
+class Heading:
+    """One heading line in a note"""
+    line: int  # Index of the line in the note's content
+    level: int  # 1 for "#", up to 6
+    text: str  # Without the hashes
+
+
+def extract_headings(content: str) -> List[Heading]:
+    """
+    Find the headings of a note
+
+    Args:
+        content: Note content
+
+    Returns:
+        Headings in the order they appear
+    """
+    headings = []
+    in_fence = False
+    for index, line in enumerate(content.split('\n')):
+        if FENCE.match(line):
+            in_fence = not in_fence
+            continue
+        if in_fence:
+            continue
+        match = HEADING.match(line)
+        if match:
+            headings.append(Heading(index, len(match.group(1)), match.group(2)))
+    return headings
+
+
+def split_sections(content: str) -> Tuple[str, List[str]]:
+    """
+    Cut a note at its top-level headings
+
+    The top level is the outermost heading level, not counting a lone
+    heading above all others (the note's title): a note "# Title",
+    "## One", "## Two" is cut at "##".
+
+    Args:
+        content: Note content
+
+    Returns:
+        (text before the first top-level heading, [each top-level heading
+        with the text up to the next one]), without trailing blank lines
+    """
+    headings = extract_headings(content)
+    if not headings:
+        return content.rstrip(), []
+    top = [heading for heading in headings if heading.level == min(h.level for h in headings)]
+    if len(top) == 1 and top[0] is headings[0] and len(headings) > 1:
+        rest = headings[1:]
+        top = [heading for heading in rest if heading.level == min(h.level for h in rest)]
+
+    lines = content.split('\n')
+    bounds = [heading.line for heading in top] + [len(lines)]
+    preamble = '\n'.join(lines[:bounds[0]]).rstrip()
+    sections = ['\n'.join(lines[start:end]).rstrip() for start, end in zip(bounds, bounds[1:])]
+    return preamble, sections
+
+
+def heading_at(headings: List[Heading], line: int) -> int:
+    """
+    Get the heading whose section a line is in
+
+    Args:
+        headings: Headings of the note
+        line: Index of the line
+
+    Returns:
+        Index in headings of the last heading at or above the line (0 above the first)
+    """
+    index = 0
+    for i, heading in enumerate(headings):
+        if heading.line > line:
+            break
+        index = i
+    return index
+
+
+class OutlineView:
+    """State of the outline view: the headings listed and the selected one"""
+
+    def __init__(self):
+        """Initialize a closed view"""
+        self.is_open = False
+        self.headings: List[Heading] = []
+        self.selected_index = 0
+        self.top_level = 1  # Level of the outermost heading, not indented
+        # Editor cursor (row, column) and scroll offset when the view opened, restored on cancel
+        self.origin: Optional[Tuple[int, int, int]] = None
+        self.from_editor = False  # Opened with the editor focused, which gets focus back
+
+    def open(self, headings: List[Heading], selected: int, origin: Tuple[int, int, int], from_editor: bool = False):
+        """
+        Show headings
+
+        Args:
+            headings: Headings to list
+            selected: Index of the heading to select
+            origin: Editor cursor row, column and scroll offset to go back to
+            from_editor: Whether the editor had focus
+        """
+        self.is_open = True
+        self.headings = headings
+        self.selected_index = max(0, min(selected, len(headings) - 1))
+        self.top_level = min((heading.level for heading in headings), default=1)
+        self.origin = origin
+        self.from_editor = from_editor
+
+    def close(self):
+        """Close the view"""
+        self.is_open = False
+        self.headings = []
+        self.origin = None
+
+    @property
+    def selected(self) -> Optional[Heading]:
+        """Get the selected heading"""
+        if 0 <= self.selected_index < len(self.headings):
+            return self.headings[self.selected_index]
+        return None
+
+    def move_selection_down(self):
+        """Select the next heading"""
+        if self.selected_index < len(self.headings) - 1:
+            self.selected_index += 1
+
+    def move_selection_up(self):
+        """Select the previous heading"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
@@ -0,0 +1,245 @@
+"""
+Plugins: Python modules that add commands, keys and note transforms
+
+Plugins are loaded from the plugins directory (~/.termnotes/plugins/ by
+default): each .py file, or directory with an __init__.py, is a plugin
+named after the file. A plugin defines register(api), which is called with
+a PluginAPI when termnotes starts:
+
+    def register(api):
+        api.command("wc", lambda ctx, args: f"{len(ctx.text.split())} words")
+        api.key("upper", ["g U"], lambda ctx: ctx.set_text(ctx.text.upper()),
+                "Uppercase the note")
+        api.transform("sort", lambda content: "\\n".join(sorted(content.split("\\n"))))
+
+Commands run as ":<name> <args>", key actions in normal mode (and can be
+remapped in [keys] like built-in ones), and transforms rewrite the editor's
+text with ":transform <name>". Handlers get a PluginContext; commands and
+keys may return a message for the status bar.
+"""
+
+import importlib.util
+import sys
+import types
+from dataclasses import dataclass
+from pathlib import Path
+from typing import Callable, Dict, List, Optional, Tuple
+from .keymap import DEFAULT_KEYS
+from .note import Note
+from .i18n import t
+
+# Module name prefix for loaded plugins, so they can import their own submodules
+MODULE_PREFIX = "termnotes_plugins"
+
+
+@dataclass
+class PluginCommand:
+    """A ":" command added by a plugin"""
+    plugin: str
+    name: str
+    handler: Callable[["PluginContext", str], Optional[str]]
+    help: str = ""
+
+
+@dataclass
+class PluginKey:
+    """A key action added by a plugin"""
+    plugin: str
+    action: str
+    keys: List[str]  # Default key sequences, as in [keys]
+    handler: Callable[["PluginContext"], Optional[str]]
+    help: str = ""
+
+
+@dataclass
+class PluginTransform:
+    """A note transform added by a plugin, run with :transform"""
+    plugin: str
+    name: str
+    handler: Callable[[str], str]
+    help: str = ""
+
+
+class PluginContext:
+    """What plugin handlers can see and change in the running editor"""
+
+    def __init__(self, ui):
+        """
+        Initialize for an editor
+
+        Args:
+            ui: EditorUI instance
+        """
+        self._ui = ui
+
+    @property
+    def storage(self):
+        """The StorageBackend holding the notes"""
+        return self._ui.storage
+
+    @property
+    def note(self) -> Optional[Note]:
+        """The saved version of the note in the editor (None if it's new)"""
+        return self._ui.get_current_note()
+
+    @property
+    def selected_note(self) -> Optional[Note]:
+        """The note selected in the sidebar"""
+        return self._ui.note_list_manager.selected_note
+
+    @property
+    def text(self) -> str:
+        """The editor's text, including unsaved changes"""
+        return self._ui.buffer.get_text()
+
+    def set_text(self, text: str):
+        """Replace the editor's text (u undoes it; :w saves it)"""
+        self._ui.buffer.replace_text(text, self._ui.editor_window_height)
+
+    def message(self, text: str):
+        """Show a message in the status bar"""
+        self._ui.mode_manager.set_message(text)
+
+    def reload(self):
+        """Reload the sidebar after changing notes through storage"""
+        self._ui.note_list_manager.reload_notes()
+
+
+class PluginAPI:
+    """Passed to a plugin's register() to add its commands, keys and transforms"""
+
+    def __init__(self, manager: "PluginManager", plugin: str):
+        """
+        Initialize for one plugin
+
+        Args:
+            manager: Manager collecting what plugins register
+            plugin: Name of the plugin registering
+        """
+        self._manager = manager
+        self.plugin = plugin
+
+    def command(self, name: str, handler: Callable[[PluginContext, str], Optional[str]], help: str = ""):
+        """
+        Add a ":" command
+
+        Args:
+            name: Command name, typed as ":<name>"; built-in commands take precedence
+            handler: Called with the context and the text after the name; may return a message
+            help: One-line description
+        """
+        self._manager.commands[name] = PluginCommand(self.plugin, name, handler, help)
+
+    def key(self, action: str, keys: List[str], handler: Callable[[PluginContext], Optional[str]], help: str = ""):
+        """
+        Add a key action, active in normal mode
+
+        Args:
+            action: Action name, used in [keys] to remap it; must not be a built-in action
+            keys: Default key sequences ("g U" is g then U); built-in keys take precedence
+            handler: Called with the context; may return a message
+            help: One-line description, shown by --print-keys
+        """
+        if action in DEFAULT_KEYS:
+            self._manager.errors.append(t("plugin.builtin_action", plugin=self.plugin, action=action))
+            return
+        self._manager.keys[action] = PluginKey(self.plugin, action, list(keys), handler, help)
+
+    def transform(self, name: str, handler: Callable[[str], str], help: str = ""):
+        """
+        Add a note transform
+
+        Args:
+            name: Transform name, run as ":transform <name>"
+            handler: Called with the editor's text; returns the new text
+            help: One-line description
+        """
+        self._manager.transforms[name] = PluginTransform(self.plugin, name, handler, help)
+
+
+class PluginManager:
+    """Loads plugins and holds what they registered"""
+
+    def __init__(self):
+        """Initialize with no plugins loaded"""
+        self.plugins: List[str] = []  # Names of the loaded plugins
+        self.commands: Dict[str, PluginCommand] = {}
+        self.keys: Dict[str, PluginKey] = {}
+        self.transforms: Dict[str, PluginTransform] = {}
+        self.errors: List[str] = []  # Plugins that failed to load, for the status bar
+
+    def load(self, directory: str):
+        """
+        Load every plugin in a directory, in name order
+
+        A plugin that raises while loading is reported in `errors` and
+        skipped; what it registered before failing is kept.
+
+        Args:
+            directory: Plugins directory (may not exist)
+        """
+        root = Path(directory).expanduser()
+        if not root.is_dir():
+            return
+        for path in sorted(root.iterdir()):
+            if path.suffix == ".py" and path.is_file():
+                name, source = path.stem, path
+            elif (path / "__init__.py").is_file():
+                name, source = path.name, path / "__init__.py"
+            else:
+                continue
+            if name.startswith(("_", ".")):
+                continue
+            self._load_plugin(name, source)
+
+    def _load_plugin(self, name: str, source: Path):
+        """Import one plugin and call its register()"""
+        module_name = f"{MODULE_PREFIX}.{name}"
+        if MODULE_PREFIX not in sys.modules:
+            # Parent package of the plugins, for imports within a plugin package
+            package = types.ModuleType(MODULE_PREFIX)
+            package.__path__ = []
+            sys.modules[MODULE_PREFIX] = package
+        try:
+            spec = importlib.util.spec_from_file_location(
+                module_name, source,
+                submodule_search_locations=[str(source.parent)] if source.name == "__init__.py" else None
+            )
+            module = importlib.util.module_from_spec(spec)
+            sys.modules[module_name] = module
+            spec.loader.exec_module(module)
+            register = getattr(module, "register", None)
+            if not callable(register):
+                self.errors.append(t("plugin.no_register", plugin=name))
+                return
+            register(PluginAPI(self, name))
+        except Exception as e:
+            # A broken plugin mustn't stop termnotes from starting
+            sys.modules.pop(module_name, None)
+            self.errors.append(t("plugin.load_failed", plugin=name, error=e))
+            return
+        self.plugins.append(name)
+
+    def key_actions(self) -> Dict[str, Tuple[List[str], str]]:
+        """
+        Get the key actions plugins added, for the KeyMap
+
+        Returns:
+            Action name to (default key sequences, description)
+        """
+        return {action: (key.keys, key.help) for action, key in self.keys.items()}
+
+
+def load_plugins(directory: str) -> PluginManager:
+    """
+    Load the plugins in a directory
+
+    Args:
+        directory: Plugins directory (may not exist)
+
+    Returns:
+        Manager holding the plugins' commands, keys and transforms
+    """
+    manager = PluginManager()
+    manager.load(directory)
+    return manager
@@ -0,0 +1,115 @@
+"""
+Plain-text previews of notes for the note list
+
+Notes are markdown, so their first line is often "# Heading", "---" opening
+a frontmatter header, or "```python". The note list shows the first
+meaningful line instead, without its markdown (preview_title()), followed
+by the first sentence of the text below it (describe()).
+"""
+
+import re
+from typing import Iterator, Tuple
+
+FENCE = re.compile(r'^\s*(```|~~~)')
+HEADING = re.compile(r'^\s{0,3}#{1,6}(?:\s+|$)(.*?)\s*#*\s*$')
+RULE = re.compile(r'^\s{0,3}([-*_])(?:\s*\1){2,}\s*$')
+TABLE_RULE = re.compile(r'^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$')
+QUOTE = re.compile(r'^\s*(?:>\s?)+')
+LIST_ITEM = re.compile(r'^\s*(?:[-*+]|\d+[.)])\s+(?:\[[ xX]\]\s*)?')
+IMAGE = re.compile(r'!\[([^\]]*)\]\([^)]*\)')
+LINK = re.compile(r'\[([^\]]+)\]\([^)]*\)')
+WIKI_LINK = re.compile(r'\[\[([^\[\]|#\n]+)(?:#[^\[\]|\n]*)?(?:\|([^\[\]\n]*))?\]\]')
+INLINE_CODE = re.compile(r'`+([^`]*)`+')
+EMPHASIS = re.compile(r'(\*\*|__|~~|\*|_)(?=\S)(.+?)(?<=\S)\1')
+HTML_TAG = re.compile(r'</?[A-Za-z][^>]*>')
+SENTENCE_END = re.compile(r'(?<=[.!?])\s+|(?<=[。！？])')
+
+
+def strip_markdown(line: str) -> str:
+    """
+    Get the text of a line of markdown without its markup
+
+    Heading marks, quote and list markers, task boxes, emphasis, code spans,
+    HTML tags and link targets are removed; links keep their text and
+    images their alt text.
+
+    Args:
+        line: One line of markdown
+
+    Returns:
+        The line's plain text
+    """
+    heading = HEADING.match(line)
+    if heading:
+        line = heading.group(1)
+    line = LIST_ITEM.sub('', QUOTE.sub('', line))
+    line = IMAGE.sub(r'\1', line)
+    line = LINK.sub(r'\1', line)
+    line = WIKI_LINK.sub(lambda match: match.group(2) or match.group(1), line)
+    line = INLINE_CODE.sub(r'\1', line)
+    line = HTML_TAG.sub('', line)
+    previous = None
+    while previous != line:  # Nested emphasis, like ***bold italic***
+        previous, line = line, EMPHASIS.sub(r'\2', line)
+    return ' '.join(line.split())
+
+
+def _meaningful_lines(content: str) -> Iterator[Tuple[bool, str]]:
+    """
+    Get the lines of a note that say something, as plain text
+
+    Frontmatter at the top, code blocks and their fences, blank lines,
+    horizontal rules and table separators are left out.
+
+    Yields:
+        Whether each line is a heading, and its text
+    """
+    lines = content.split('\n')
+    start = 0
+    if lines and lines[0].strip() == '---':
+        closing = next((i for i in range(1, len(lines)) if lines[i].strip() in ('---', '...')), None)
+        if closing is not None:
+            start = closing + 1
+    in_fence = False
+    for line in lines[start:]:
+        if FENCE.match(line):
+            in_fence = not in_fence
+            continue
+        if in_fence or RULE.match(line) or (TABLE_RULE.match(line) and '-' in line):
+            continue
+        text = strip_markdown(line)
+        if text:
+            yield bool(HEADING.match(line)), text
+
+
+def preview_title(content: str) -> str:
+    """
+    Get the first meaningful line of a note as plain text, to list it by
+
+    Args:
+        content: Note content
+
+    Returns:
+        The line, or "" if the note has nothing to show
+    """
+    return next((text for _, text in _meaningful_lines(content)), "")
+
+
+def describe(content: str) -> str:
+    """
+    Get the first sentence of a note's text below its title, as plain text
+
+    Headings are skipped along with everything preview_title() skips.
+
+    Args:
+        content: Note content
+
+    Returns:
+        The sentence, or "" if there's no text below the title
+    """
+    lines = _meaningful_lines(content)
+    next(lines, None)  # The title
+    for is_heading, text in lines:
+        if not is_heading:
+            return SENTENCE_END.split(text, 1)[0]
+    return ""
@@ -0,0 +1,84 @@
+"""
+Recently viewed notes, for the quick switcher
+
+Each time a note is loaded into the editor, its ID and the time are
+written to a small file next to the storage lock (see
+storage_recent_path()). Viewing a note doesn't change it, so this isn't
+kept in the note, where it would be synced, committed, or move the note
+up the "updated" sort. The quick switcher lists notes most recently
+viewed first.
+"""
+
+import json
+import os
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional
+from .note import Note
+from .utils import utc_now
+
+MAX_ENTRIES = 200  # Notes remembered; the least recently viewed are forgotten first
+
+
+class RecentNotes:
+    """When each note was last viewed, kept in a file"""
+
+    def __init__(self, path: Path):
+        """
+        Initialize, reading the file if there is one
+
+        Args:
+            path: File of recently viewed notes (see storage_recent_path())
+        """
+        self.path = Path(path)
+        self._viewed: Dict[str, datetime] = self._load()
+
+    def _load(self) -> Dict[str, datetime]:
+        """Read the file, ignoring it if it's missing or can't be read"""
+        try:
+            data = json.loads(self.path.read_text(encoding="utf-8"))
+            return {note_id: datetime.fromisoformat(viewed_at) for note_id, viewed_at in data.items()}
+        except (OSError, ValueError, AttributeError, TypeError):
+            return {}
+
+    def touch(self, note_id: str):
+        """
+        Record that a note was viewed just now
+
+        Args:
+            note_id: ID of the note
+        """
+        self._viewed.pop(note_id, None)
+        self._viewed[note_id] = utc_now()
+        while len(self._viewed) > MAX_ENTRIES:
+            del self._viewed[min(self._viewed, key=self._viewed.get)]
+
+        tmp_path = self.path.with_name(f".{self.path.name}.tmp")
+        try:
+            self.path.parent.mkdir(parents=True, exist_ok=True)
+            with open(tmp_path, "w", encoding="utf-8") as f:
+                json.dump({note_id: viewed_at.isoformat() for note_id, viewed_at in self._viewed.items()}, f)
+            os.replace(tmp_path, self.path)
+        except OSError:
+            pass  # Only this session remembers it
+
+    def viewed_at(self, note_id: str) -> Optional[datetime]:
+        """Get when a note was last viewed (UTC), or None if it wasn't"""
+        return self._viewed.get(note_id)
+
+    def order(self, notes: List[Note]) -> List[Note]:
+        """
+        Sort notes by when they were viewed
+
+        Args:
+            notes: Notes to sort
+
+        Returns:
+            Most recently viewed first, then the notes never viewed, most
+            recently updated first
+        """
+        viewed = sorted((note for note in notes if note.id in self._viewed),
+                        key=lambda note: self._viewed[note.id], reverse=True)
+        unviewed = sorted((note for note in notes if note.id not in self._viewed),
+                          key=lambda note: note.updated_at, reverse=True)
+        return viewed + unviewed
@@ -0,0 +1,161 @@
+"""
+Due dates and reminders on notes
+
+A note's due date and reminder time are kept in its "due_at" and
+"remind_at" properties, as ISO timestamps in UTC like other note times.
+Times are typed in local time:
+
+- 2026-10-20, 2026-10-20 14:30 (or 2026-10-20T14:30)
+- 14:30: today, or tomorrow if that time has passed
+- today, tomorrow, or a weekday name: the next such day
+- +30m, +2h, +3d, +1w: from now
+
+A date without a time means the end of that day for a due date and 9:00
+for a reminder. When a reminder comes due the editor shows it in the
+status bar and, depending on [reminders] notify, as a desktop
+notification; it is then removed from the note.
+"""
+
+import re
+import shutil
+import subprocess
+import sys
+from datetime import date, datetime, time, timedelta, timezone
+from typing import Optional
+from .note import Note
+from .utils import utc_now, normalize_to_utc
+from .i18n import t
+
+DUE_TIME = time(23, 59)  # Time of a due date given without one
+REMIND_TIME = time(9, 0)  # Time of a reminder given without one
+NOTIFY_MODES = ("desktop", "banner", "off")
+
+RELATIVE = re.compile(r'^\+(\d+)\s*([mhdw])$')
+UNITS = {"m": "minutes", "h": "hours", "d": "days", "w": "weeks"}
+WEEKDAYS = ("monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday")
+
+
+def parse_when(text: str, default_time: time = DUE_TIME, now: Optional[datetime] = None) -> Optional[datetime]:
+    """
+    Parse a date and time typed by the user
+
+    Args:
+        text: Local date/time, relative time, or day name (see the module docstring)
+        default_time: Time to use when only a day is given
+        now: Current local time (default: now)
+
+    Returns:
+        The time in UTC (timezone-naive), or None if the text isn't understood
+    """
+    text = text.strip().lower()
+    now = now or datetime.now().astimezone()
+    if now.tzinfo is None:
+        now = now.astimezone()
+
+    match = RELATIVE.match(text)
+    if match:
+        return normalize_to_utc(now + timedelta(**{UNITS[match.group(2)]: int(match.group(1))}))
+
+    day: Optional[date] = None
+    if text == "today":
+        day = now.date()
+    elif text == "tomorrow":
+        day = now.date() + timedelta(days=1)
+    elif text in WEEKDAYS:
+        ahead = (WEEKDAYS.index(text) - now.weekday() - 1) % 7 + 1
+        day = now.date() + timedelta(days=ahead)
+    if day is not None:
+        return _local_to_utc(datetime.combine(day, default_time))
+
+    try:
+        clock = time.fromisoformat(text) if ":" in text and len(text) <= 8 else None
+    except ValueError:
+        clock = None
+    if clock is not None:
+        when = datetime.combine(now.date(), clock)
+        if when <= now.replace(tzinfo=None):
+            when += timedelta(days=1)
+        return _local_to_utc(when)
+
+    try:
+        when = datetime.fromisoformat(text.replace(" ", "T", 1))
+    except ValueError:
+        return None
+    if len(text) <= 10:
+        when = datetime.combine(when.date(), default_time)
+    if when.tzinfo is not None:
+        return normalize_to_utc(when)
+    return _local_to_utc(when)
+
+
+def _local_to_utc(when: datetime) -> datetime:
+    """Convert a naive local time to naive UTC"""
+    return normalize_to_utc(when.astimezone())
+
+
+def is_overdue(note: Note, now: Optional[datetime] = None) -> bool:
+    """Check whether a note's due date has passed"""
+    return note.due_at is not None and note.due_at < (now or utc_now())
+
+
+def format_due(due: datetime, now: Optional[datetime] = None) -> str:
+    """
+    Describe a due date briefly for the note list
+
+    Args:
+        due: Due date (UTC)
+        now: Current time (UTC, default: now)
+
+    Returns:
+        "overdue", "today", "tomorrow", a weekday within a week, or the date
+    """
+    now = now or utc_now()
+    if due < now:
+        return t("due.overdue")
+    local_due = due.replace(tzinfo=timezone.utc).astimezone()
+    local_now = now.replace(tzinfo=timezone.utc).astimezone()
+    days = (local_due.date() - local_now.date()).days
+    if days == 0:
+        return t("due.today")
+    if days == 1:
+        return t("due.tomorrow")
+    if days < 7:
+        return local_due.strftime("%a")
+    return local_due.strftime("%Y-%m-%d" if local_due.year != local_now.year else "%m-%d")
+
+
+def format_time(when: datetime) -> str:
+    """Format a UTC time in local time for messages"""
+    return when.replace(tzinfo=timezone.utc).astimezone().strftime("%Y-%m-%d %H:%M")
+
+
+def send_notification(title: str, body: str) -> bool:
+    """
+    Show a desktop notification, without waiting for it
+
+    Uses notify-send on Linux and BSD and osascript on macOS.
+
+    Args:
+        title: Notification title
+        body: Notification text
+
+    Returns:
+        True if a notification command was started
+    """
+    if sys.platform == "darwin":
+        script = f"display notification {_applescript_string(body)} with title {_applescript_string(title)}"
+        command = ["osascript", "-e", script]
+    elif shutil.which("notify-send"):
+        command = ["notify-send", "--app-name=termnotes", title, body]
+    else:
+        return False
+    try:
+        subprocess.Popen(command, stdin=subprocess.DEVNULL, stdout=subprocess.DEVNULL, stderr=subprocess.DEVNULL)
+    except OSError:
+        return False
+    return True
+
+
+def _applescript_string(text: str) -> str:
+    """Quote text as an AppleScript string literal"""
+    return '"' + text.replace("\\", "\\\\").replace('"', '\\"') + '"'
@@ -0,0 +1,95 @@
+"""
+Cache of formatted markdown for the editor
+
+Styling a line (markdown patterns, Pygments for code blocks) costs far
+more than drawing it, and the editor redraws on every key, cursor move and
+resize. The cache keeps the styled lines of the last few notes shown, keyed
+by note ID and a fingerprint of the content: an edit makes a new entry, so
+nothing needs invalidating, while moving around, selecting text or
+switching back to a note reuses the lines already styled.
+
+Lines are styled when first drawn, so a long note costs only its visible
+lines. Styling doesn't depend on the window width (lines are cut to the
+window after styling), so resizing keeps the cache.
+"""
+
+from collections import OrderedDict
+from typing import Callable, Dict, List, Optional, Tuple
+from .note import content_version
+
+MAX_NOTES = 8  # Notes whose styled lines are kept
+
+Fragments = List[Tuple[str, str]]
+
+
+class RenderedNote:
+    """Styled lines of one version of a note, filled in as they are drawn"""
+
+    def __init__(self, lines: List[str]):
+        """
+        Initialize
+
+        Args:
+            lines: The note's lines
+        """
+        self.lines = lines
+        self.code_blocks: Optional[Dict[int, dict]] = None  # Set by the editor on first draw
+        self._formatted: Dict[int, Fragments] = {}
+
+    def line(self, index: int, style: Callable[[int], Fragments]) -> Fragments:
+        """
+        Get a styled line, styling it on first use
+
+        Args:
+            index: Line number
+            style: Styles the line with that number
+
+        Returns:
+            The line's (style, text) fragments; callers mustn't change the list
+        """
+        formatted = self._formatted.get(index)
+        if formatted is None:
+            formatted = self._formatted[index] = style(index)
+        return formatted
+
+
+class RenderCache:
+    """The styled lines of the most recently shown notes"""
+
+    def __init__(self, size: int = MAX_NOTES):
+        """
+        Initialize an empty cache
+
+        Args:
+            size: Number of note versions to keep
+        """
+        self.size = size
+        self._entries: "OrderedDict[Tuple[Optional[str], str], RenderedNote]" = OrderedDict()
+
+    def get(self, note_id: Optional[str], lines: List[str]) -> RenderedNote:
+        """
+        Get the styled lines of a note, starting over if its content changed
+
+        Args:
+            note_id: ID of the note (None for text not in a note)
+            lines: Its current lines
+
+        Returns:
+            Entry for this version of the note
+        """
+        key = (note_id, content_version('\n'.join(lines)))
+        entry = self._entries.get(key)
+        if entry is None:
+            entry = self._entries[key] = RenderedNote(lines)
+            # Only the newest version of a note is drawn again
+            for old_key in [old for old in self._entries if old[0] == note_id and old != key]:
+                del self._entries[old_key]
+            while len(self._entries) > self.size:
+                self._entries.popitem(last=False)
+        else:
+            self._entries.move_to_end(key)
+        return entry
+
+    def clear(self):
+        """Forget every styled line, e.g. after the colors change"""
+        self._entries.clear()
@@ -0,0 +1,174 @@
+"""
+Search and replace across all notes, and the review view shown before applying it
+
+The :replaceall command takes sed-style arguments, "/pattern/replacement/"
+with "r" after the last delimiter for a regular expression. Any punctuation
+character can be the delimiter ("|a/b|c|"), for patterns containing "/".
+"""
+
+import re
+from dataclasses import dataclass
+from typing import List, Optional, Pattern, Set, Tuple
+from .history import diff_lines
+
+
+@dataclass
+class Replacement:
+    """A note whose content a replace-all changes"""
+    note_id: str
+    title: str
+    count: int  # Number of matches replaced
+    old_content: str
+    new_content: str
+
+    def diff(self) -> List[str]:
+        """Get the changed lines as a unified diff"""
+        return diff_lines(self.old_content, self.new_content)
+
+
+def compile_pattern(pattern: str, regex: bool) -> Pattern:
+    """
+    Compile a search pattern
+
+    Args:
+        pattern: Text to find, or a regular expression
+        regex: Whether the pattern is a regular expression
+
+    Returns:
+        Compiled pattern
+
+    Raises:
+        re.error: If the regular expression is invalid
+    """
+    return re.compile(pattern if regex else re.escape(pattern))
+
+
+def replace_in_content(content: str, pattern: Pattern, replacement: str, regex: bool) -> Tuple[str, int]:
+    """
+    Replace every match of a pattern in a note's content
+
+    Args:
+        content: Note content
+        pattern: Compiled pattern
+        replacement: Replacement text; with regex, may refer to groups as \\1 or \\g<name>
+        regex: Whether the pattern is a regular expression
+
+    Returns:
+        Tuple of (new content, number of matches replaced)
+
+    Raises:
+        re.error: If the replacement refers to a group the pattern doesn't have
+    """
+    if regex:
+        return pattern.subn(replacement, content)
+    return pattern.subn(lambda match: replacement, content)
+
+
+def parse_replace_arguments(arguments: str) -> Optional[Tuple[str, str, bool]]:
+    """
+    Parse the arguments of :replaceall
+
+    Args:
+        arguments: Text after the command, e.g. "/Acme/Globex/" or "/v(\\d+)/version \\1/r"
+
+    Returns:
+        Tuple of (pattern, replacement, regex), or None if malformed
+    """
+    arguments = arguments.strip()
+    if len(arguments) < 2 or arguments[0].isalnum() or arguments[0].isspace():
+        return None
+    parts = arguments[1:].split(arguments[0])
+    if len(parts) not in (2, 3) or not parts[0]:
+        return None
+    flags = parts[2] if len(parts) == 3 else ""
+    if flags not in ("", "r"):
+        return None
+    return parts[0], parts[1], flags == "r"
+
+
+class ReplaceView:
+    """State of the review shown before replacing text in every note"""
+
+    def __init__(self):
+        """Initialize a closed view"""
+        self.pattern: str = ""
+        self.replacement: str = ""
+        self.regex: bool = False
+        self.replacements: List[Replacement] = []
+        self.skipped: Set[str] = set()  # IDs of notes left unchanged
+        self.selected_index: int = 0
+        self.scroll_offset: int = 0  # First diff line shown
+
+    @property
+    def is_open(self) -> bool:
+        """Check if the view is showing replacements to review"""
+        return bool(self.replacements)
+
+    def open(self, pattern: str, replacement: str, regex: bool, replacements: List[Replacement]):
+        """
+        Show the notes a replace-all would change, all included
+
+        Args:
+            pattern: Text or regular expression to find
+            replacement: Replacement text
+            regex: Whether the pattern is a regular expression
+            replacements: Dry-run result for each affected note
+        """
+        self.pattern = pattern
+        self.replacement = replacement
+        self.regex = regex
+        self.replacements = replacements
+        self.skipped = set()
+        self.selected_index = 0
+        self.scroll_offset = 0
+
+    def close(self):
+        """Close the view"""
+        self.replacements = []
+        self.skipped = set()
+
+    @property
+    def selected(self) -> Optional[Replacement]:
+        """Get the selected note's replacement"""
+        if 0 <= self.selected_index < len(self.replacements):
+            return self.replacements[self.selected_index]
+        return None
+
+    @property
+    def included(self) -> List[Replacement]:
+        """Get the replacements that haven't been skipped"""
+        return [replacement for replacement in self.replacements if replacement.note_id not in self.skipped]
+
+    def toggle_selected(self):
+        """Skip the selected note, or include it again"""
+        replacement = self.selected
+        if replacement is None:
+            return
+        if replacement.note_id in self.skipped:
+            self.skipped.discard(replacement.note_id)
+        else:
+            self.skipped.add(replacement.note_id)
+
+    def move_selection_down(self):
+        """Select the next note"""
+        if self.selected_index < len(self.replacements) - 1:
+            self.selected_index += 1
+            self.scroll_offset = 0
+
+    def move_selection_up(self):
+        """Select the previous note"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
+            self.scroll_offset = 0
+
+    def scroll(self, lines: int, page_height: int):
+        """
+        Scroll the selected note's diff, keeping at least one line visible
+
+        Args:
+            lines: Number of lines to scroll (negative scrolls up)
+            page_height: Number of diff lines visible at once
+        """
+        replacement = self.selected
+        total = len(replacement.diff()) if replacement else 0
+        self.scroll_offset = min(max(0, self.scroll_offset + lines), max(0, total - page_height))
@@ -0,0 +1,249 @@
+"""
+Full-text and fuzzy search results and snippet helpers
+
+Storage backends return ranked SearchResult objects. Snippets mark matched
+terms with HIGHLIGHT_START / HIGHLIGHT_END control characters so the UI can
+style them without re-running the match.
+
+Fuzzy matching finds the characters of each query term in order within a
+single line ("mtg" matches "Meeting notes"), for filtering the note list
+as the user types.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import List, Optional, Tuple
+from .note import Note
+
+HIGHLIGHT_START = "\x02"
+HIGHLIGHT_END = "\x03"
+SNIPPET_ELLIPSIS = "…"
+
+
+@dataclass
+class SearchResult:
+    """A note matching a search query"""
+    note: Note
+    snippet: str  # Single-line excerpt with highlight markers around matches
+    rank: float  # Lower is a better match
+
+
+def tokenize_query(query: str) -> List[str]:
+    """
+    Split a search query into terms
+
+    Args:
+        query: Query as typed by the user
+
+    Returns:
+        Lowercased terms of letters, digits and underscores
+    """
+    return [term.lower() for term in re.findall(r"\w+", query)]
+
+
+def build_snippet(content: str, terms: List[str], width: int = 60) -> str:
+    """
+    Build a single-line snippet around the first match of any term
+
+    Args:
+        content: Note content
+        terms: Lowercased search terms (matched as word prefixes)
+        width: Approximate snippet length in characters
+
+    Returns:
+        Snippet with matched terms wrapped in highlight markers, or "" if
+        nothing matches
+    """
+    text = " ".join(content.split())
+    if not terms:
+        return ""
+
+    pattern = re.compile(r"\b(" + "|".join(re.escape(term) for term in terms) + r")\w*", re.IGNORECASE)
+    match = pattern.search(text)
+    if match is None:
+        return ""
+
+    start = max(0, match.start() - width // 3)
+    if start > 0:
+        # Begin at a word boundary rather than mid-word
+        space = text.find(" ", start, match.start())
+        if space != -1:
+            start = space + 1
+    end = min(len(text), start + width)
+    excerpt = pattern.sub(lambda m: f"{HIGHLIGHT_START}{m.group(0)}{HIGHLIGHT_END}", text[start:end])
+
+    prefix = SNIPPET_ELLIPSIS if start > 0 else ""
+    suffix = SNIPPET_ELLIPSIS if end < len(text) else ""
+    return f"{prefix}{excerpt}{suffix}"
+
+
+def count_matches(content: str, terms: List[str]) -> int:
+    """
+    Count term matches in content, or 0 unless every term matches
+
+    Args:
+        content: Note content
+        terms: Lowercased search terms (matched as word prefixes)
+
+    Returns:
+        Total number of matches across all terms
+    """
+    total = 0
+    for term in terms:
+        count = len(re.findall(r"\b" + re.escape(term), content, re.IGNORECASE))
+        if count == 0:
+            return 0
+        total += count
+    return total
+
+
+def fuzzy_match(text: str, term: str) -> Optional[Tuple[int, List[int]]]:
+    """
+    Fuzzy match a term against text
+
+    The term's characters must appear in order, case-insensitively. The
+    tightest such span ending at the first complete match is used.
+
+    Args:
+        text: Text to search (a single line)
+        term: Term to find
+
+    Returns:
+        (score, positions) where lower scores are better matches and
+        positions are the indexes of the matched characters, or None if the
+        term doesn't match
+    """
+    text_lower = text.lower()
+    term = term.lower()
+    if not term:
+        return None
+
+    # Find where the first complete match ends
+    matched = 0
+    end = -1
+    for index, char in enumerate(text_lower):
+        if char == term[matched]:
+            matched += 1
+            if matched == len(term):
+                end = index
+                break
+    if end < 0:
+        return None
+
+    # Walk back from the end to the latest start, for the tightest span
+    remaining = len(term) - 1
+    start = end
+    for index in range(end, -1, -1):
+        if text_lower[index] == term[remaining]:
+            remaining -= 1
+            if remaining < 0:
+                start = index
+                break
+
+    positions = []
+    matched = 0
+    for index in range(start, end + 1):
+        if matched < len(term) and text_lower[index] == term[matched]:
+            positions.append(index)
+            matched += 1
+
+    # Characters skipped inside the span count against the match; starting at a word helps
+    score = (end - start + 1) - len(term)
+    if start == 0 or not text_lower[start - 1].isalnum():
+        score -= 1
+    return score, positions
+
+
+def highlight_positions(text: str, positions: List[int]) -> str:
+    """
+    Wrap characters at the given positions in highlight markers
+
+    Args:
+        text: Text to mark up
+        positions: Indexes of characters to highlight
+
+    Returns:
+        Text with runs of highlighted characters wrapped in markers
+    """
+    marked = set(positions)
+    result = []
+    for index, char in enumerate(text):
+        if index in marked and index - 1 not in marked:
+            result.append(HIGHLIGHT_START)
+        result.append(char)
+        if index in marked and index + 1 not in marked:
+            result.append(HIGHLIGHT_END)
+    return "".join(result)
+
+
+def fuzzy_search(notes: List[Note], query: str, width: int = 60) -> List[SearchResult]:
+    """
+    Fuzzy search note titles and contents
+
+    Each whitespace-separated term of the query must fuzzy match some line
+    of a note. Matches in the first line (the title) rank higher.
+
+    Args:
+        notes: Notes to search
+        query: Query as typed by the user
+        width: Approximate snippet length in characters
+
+    Returns:
+        Matching notes, best match first, with the best matching line of the
+        first term as the snippet
+    """
+    terms = query.split()
+    if not terms:
+        return []
+
+    results = []
+    for note in notes:
+        if note.is_locked:
+            continue  # Secure notes are only searched once unlocked
+        lines = note.content.split('\n')
+        total = 0
+        snippet = ""
+        for term in terms:
+            best = None
+            for line_number, line in enumerate(lines):
+                match = fuzzy_match(line, term)
+                if match is None:
+                    continue
+                score = match[0] - (2 if line_number == 0 else 0)
+                if best is None or score < best[0]:
+                    best = (score, line, match[1])
+            if best is None:
+                break
+            total += best[0]
+            if not snippet:
+                score, line, positions = best
+                start = max(0, positions[0] - width // 3)
+                excerpt = highlight_positions(line[start:start + width], [p - start for p in positions])
+                snippet = (SNIPPET_ELLIPSIS if start > 0 else "") + " ".join(excerpt.split())
+        else:
+            results.append(SearchResult(note=note, snippet=snippet, rank=float(total)))
+
+    results.sort(key=lambda result: result.rank)
+    return results
+
+
+def split_highlights(snippet: str) -> List[Tuple[bool, str]]:
+    """
+    Split a snippet into plain and highlighted parts
+
+    Args:
+        snippet: Snippet containing highlight markers
+
+    Returns:
+        List of (is_highlighted, text) pairs
+    """
+    parts = []
+    highlighted = False
+    for piece in re.split(f"([{HIGHLIGHT_START}{HIGHLIGHT_END}])", snippet):
+        if piece == HIGHLIGHT_START:
+            highlighted = True
+        elif piece == HIGHLIGHT_END:
+            highlighted = False
+        elif piece:
+            parts.append((highlighted, piece))
+    return parts
@@ -0,0 +1,273 @@
+"""
+Secure notes: single notes encrypted at rest, read only after typing a passphrase
+
+A note with the "secure" property is stored as an armored block instead of
+its text, encrypted with a key derived from the secure notes' passphrase:
+
+    -----BEGIN TERMNOTES SECURE NOTE-----
+    Salt: <base64>
+    Iterations: 600000
+
+    <base64 of nonce + ChaCha20-Poly1305 ciphertext>
+    -----END TERMNOTES SECURE NOTE-----
+
+SecureBackend (storage/secure_backend.py) seals and opens these blocks with
+the SecureVault, so everything under it (caches, search indexes, files,
+revisions, the sync server) only ever sees the block. While the vault is
+locked, secure notes are listed with a lock and left out of search.
+
+The passphrase is checked against a verifier kept next to the notes: an
+empty block sealed under VERIFIER_ID, so it has its own salt like a note,
+written the first time the vault is unlocked. Without it a passphrase
+typed while no note is sealed would be taken as a new one.
+
+The passphrase is only kept in memory, and only until :lock or the app
+lock forgets it.
+"""
+
+import base64
+import hashlib
+import json
+import os
+from typing import Callable, Dict, Optional
+from chacha20poly1305 import ChaCha20Poly1305
+from .i18n import t
+
+SECURE_PROPERTY = "secure"
+BEGIN = "-----BEGIN TERMNOTES SECURE NOTE-----"
+END = "-----END TERMNOTES SECURE NOTE-----"
+ITERATIONS = 600_000  # PBKDF2 iterations for newly sealed notes
+KEY_SIZE = 32
+NONCE_SIZE = 12
+SALT_SIZE = 16
+LINE_LENGTH = 64  # Characters per line of the armored ciphertext
+VERIFIER_ID = "termnotes-secure-verifier"  # Sealed with the verifier's block in place of a note ID
+
+
+class SecureNoteError(ValueError):
+    """A secure note can't be opened: a wrong passphrase, or a damaged block"""
+
+
+class SecureNoteLocked(RuntimeError):
+    """A change was made to a secure note while its passphrase isn't known"""
+
+    def __init__(self):
+        """Initialize with the message shown to the user"""
+        super().__init__(t("secure.locked_save"))
+
+
+def is_sealed(content: str) -> bool:
+    """Check whether note content is an encrypted block rather than text"""
+    return content.startswith(BEGIN)
+
+
+def _armor(salt: bytes, iterations: int, sealed: bytes) -> str:
+    """Format an encrypted note as an armored block"""
+    encoded = base64.b64encode(sealed).decode("ascii")
+    lines = [encoded[i:i + LINE_LENGTH] for i in range(0, len(encoded), LINE_LENGTH)]
+    return "\n".join([
+        BEGIN,
+        f"Salt: {base64.b64encode(salt).decode('ascii')}",
+        f"Iterations: {iterations}",
+        "",
+        *lines,
+        END,
+    ])
+
+
+def _dearmor(content: str):
+    """
+    Read an armored block
+
+    Returns:
+        (salt, iterations, nonce + ciphertext)
+
+    Raises:
+        SecureNoteError: If the block is damaged
+    """
+    lines = content.strip().split("\n")
+    try:
+        if lines[0] != BEGIN or lines[-1] != END:
+            raise ValueError("missing armor")
+        blank = lines.index("")
+        headers = {}
+        for line in lines[1:blank]:
+            key, _, value = line.partition(":")
+            headers[key.strip().lower()] = value.strip()
+        body = "".join(lines[blank + 1:-1])
+        return base64.b64decode(headers["salt"]), int(headers["iterations"]), base64.b64decode(body)
+    except (ValueError, KeyError, IndexError) as e:
+        raise SecureNoteError(t("secure.damaged", error=e))
+
+
+class SecureVault:
+    """The secure notes' passphrase while they're unlocked, and the keys derived from it"""
+
+    def __init__(self):
+        """Initialize locked"""
+        self.lock()
+
+    @property
+    def unlocked(self) -> bool:
+        """Whether the passphrase is known, so secure notes can be read and saved"""
+        return self._passphrase is not None
+
+    def unlock(self, passphrase: str):
+        """Remember the passphrase until lock()"""
+        self.lock()
+        self._passphrase = passphrase
+
+    def lock(self):
+        """Forget the passphrase and every key derived from it"""
+        self._passphrase: Optional[str] = None
+        self._keys: Dict[bytes, bytes] = {}  # Derived keys by salt
+        # Salt and iterations for notes sealed in this session: those of the first note opened, or new
+        self._salt: Optional[bytes] = None
+        self._iterations = ITERATIONS
+
+    def _key(self, salt: bytes, iterations: int) -> bytes:
+        """Derive the key for a salt, once per session"""
+        key = self._keys.get(salt)
+        if key is None:
+            key = hashlib.pbkdf2_hmac("sha256", self._passphrase.encode("utf-8"), salt, iterations, dklen=KEY_SIZE)
+            self._keys[salt] = key
+        return key
+
+    def seal(self, note_id: str, text: str) -> str:
+        """
+        Encrypt a note's text into an armored block
+
+        The note's ID is sealed with it, so a block copied into another note
+        doesn't open.
+
+        Args:
+            note_id: ID of the note
+            text: Its text
+
+        Returns:
+            The armored block to store as its content
+        """
+        if self._salt is None:
+            self._salt = os.urandom(SALT_SIZE)
+        nonce = os.urandom(NONCE_SIZE)
+        plaintext = json.dumps({"id": note_id, "content": text}).encode("utf-8")
+        sealed = nonce + ChaCha20Poly1305(self._key(self._salt, self._iterations)).encrypt(nonce, plaintext)
+        return _armor(self._salt, self._iterations, sealed)
+
+    def make_verifier(self) -> str:
+        """Seal the block that checks this passphrase later, see verify()"""
+        return self.seal(VERIFIER_ID, "")
+
+    def verify(self, verifier: str) -> bool:
+        """Check the passphrase against a block from make_verifier()"""
+        try:
+            self.open(VERIFIER_ID, verifier)
+        except SecureNoteError:
+            return False
+        return True
+
+    def open(self, note_id: str, content: str) -> str:
+        """
+        Decrypt a note's armored block
+
+        Args:
+            note_id: ID of the note
+            content: The block
+
+        Returns:
+            The note's text
+
+        Raises:
+            SecureNoteError: If the passphrase is wrong or the block was changed
+        """
+        salt, iterations, sealed = _dearmor(content)
+        try:
+            cipher = ChaCha20Poly1305(self._key(salt, iterations))
+            inner = json.loads(bytes(cipher.decrypt(sealed[:NONCE_SIZE], sealed[NONCE_SIZE:])).decode("utf-8"))
+        except Exception:
+            # The cipher raises its own error types for a failed authentication
+            inner = None
+        if not isinstance(inner, dict) or inner.get("id") != note_id:
+            raise SecureNoteError(t("secure.wrong_passphrase"))
+        if self._salt is None:
+            # Seal with the same key, so it's only derived once
+            self._salt, self._iterations = salt, iterations
+        return inner["content"]
+
+
+class PassphrasePrompt:
+    """
+    State of the dialog asking for the secure notes' passphrase
+
+    A new passphrase is typed twice; the dialog closes once on_submit
+    accepts what was typed.
+    """
+
+    def __init__(self):
+        """Initialize a closed prompt"""
+        self.close()
+
+    def open(self, title: str, on_submit: Callable[[str], Optional[str]], confirm: bool = False):
+        """
+        Ask for the passphrase
+
+        Args:
+            title: What the passphrase is asked for
+            on_submit: Called with the passphrase; returns an error to show, or None to close
+            confirm: Whether it's a new passphrase, to be typed again
+        """
+        self.close()
+        self.title = title
+        self.on_submit = on_submit
+        self.confirm = confirm
+        self.is_open = True
+
+    def close(self):
+        """Close the prompt, forgetting anything typed"""
+        self.title = ""
+        self.on_submit: Optional[Callable[[str], Optional[str]]] = None
+        self.confirm = False
+        self.first: Optional[str] = None  # A new passphrase typed once, until it's typed again
+        self.typed = ""
+        self.error = ""
+        self.is_open = False
+
+    @property
+    def label(self) -> str:
+        """Label before what's typed"""
+        return t("storage.confirm_passphrase") if self.first is not None else t("lock.prompt")
+
+    def type(self, text: str):
+        """Add typed characters to the passphrase"""
+        self.typed += text
+        self.error = ""
+
+    def backspace(self):
+        """Remove the last character typed"""
+        self.typed = self.typed[:-1]
+
+    def clear(self):
+        """Remove everything typed"""
+        self.typed = ""
+
+    def submit(self):
+        """Check what was typed, then pass the passphrase to on_submit"""
+        typed, self.typed = self.typed, ""
+        if not typed:
+            self.error = t("storage.passphrase_empty")
+            return
+        if self.confirm and self.first is None:
+            self.first = typed
+            self.error = ""
+            return
+        if self.confirm and typed != self.first:
+            self.first = None
+            self.error = t("storage.passphrase_mismatch")
+            return
+        on_submit = self.on_submit
+        error = on_submit(typed) if on_submit else None
+        if error:
+            self.first = None
+            self.error = error
+        else:
+            self.close()
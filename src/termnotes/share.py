@@ -0,0 +1,107 @@
+"""
+Sharing notes by uploading them to GitHub Gist or a paste service
+
+A gist needs a GitHub token with the "gist" scope, from [share] token or,
+when that is empty, the system keyring (the "github" secret; see
+credentials.py). A paste service gets the note's markdown as the body of a
+POST and answers with the URL, as paste.rs, dpaste.com and similar services
+do; some take a token too.
+"""
+
+import json
+import urllib.error
+import urllib.request
+from .credentials import get_secret
+from .note import Note
+from .i18n import t
+
+SERVICES = ("gist", "paste")
+GIST_API = "https://api.github.com/gists"
+TIMEOUT = 10  # Seconds to wait for the service
+
+
+class ShareError(Exception):
+    """The note couldn't be uploaded"""
+
+
+def share_note(note: Note, service: str, token: str = "", public: bool = False, paste_url: str = "") -> str:
+    """
+    Upload a note
+
+    Args:
+        note: Note to share
+        service: "gist" or "paste"
+        token: GitHub token for a gist, or the paste service's token ("" for none)
+        public: Make a gist public instead of secret
+        paste_url: Endpoint of the paste service
+
+    Returns:
+        URL of the shared note
+
+    Raises:
+        ShareError: If the service can't be reached or refuses the note
+    """
+    if service == "gist":
+        return _share_gist(note, token or get_secret("github"), public)
+    if service == "paste":
+        return _share_paste(note, paste_url, token or get_secret("paste"))
+    raise ShareError(t("share.unknown_service", service=service, services=", ".join(SERVICES)))
+
+
+def _file_name(note: Note) -> str:
+    """Name a note's file in a gist after its title"""
+    title = "".join(c if c.isalnum() or c in " -_." else "_" for c in note.title).strip()
+    return f"{title or 'note'}.md"
+
+
+def _share_gist(note: Note, token: str, public: bool) -> str:
+    """Create a gist holding the note"""
+    if not token:
+        raise ShareError(t("share.no_token"))
+    body = {
+        "description": note.title,
+        "public": public,
+        "files": {_file_name(note): {"content": note.content or "\n"}},
+    }
+    request = urllib.request.Request(GIST_API, data=json.dumps(body).encode("utf-8"), method="POST")
+    request.add_header("Accept", "application/vnd.github+json")
+    request.add_header("Content-Type", "application/json")
+    request.add_header("Authorization", f"Bearer {token}")
+    response = _send(request, GIST_API)
+    try:
+        return json.loads(response)["html_url"]
+    except (ValueError, KeyError, TypeError):
+        raise ShareError(t("share.bad_response", url=GIST_API))
+
+
+def _share_paste(note: Note, url: str, token: str) -> str:
+    """Post the note to a paste service, which answers with its URL"""
+    if not url:
+        raise ShareError(t("share.no_paste_url"))
+    request = urllib.request.Request(url, data=note.content.encode("utf-8"), method="POST")
+    request.add_header("Content-Type", "text/plain; charset=utf-8")
+    if token:
+        request.add_header("Authorization", f"Bearer {token}")
+    response = _send(request, url).strip()
+    # Services answer with the URL as text, or JSON with a "url" field
+    if response.startswith("{"):
+        try:
+            response = str(json.loads(response).get("url", ""))
+        except ValueError:
+            response = ""
+    location = response.split("\n")[0].strip()
+    if not location.startswith(("http://", "https://")):
+        raise ShareError(t("share.bad_response", url=url))
+    return location
+
+
+def _send(request: urllib.request.Request, url: str) -> str:
+    """Send a request, returning the response body"""
+    try:
+        with urllib.request.urlopen(request, timeout=TIMEOUT) as response:
+            return response.read().decode("utf-8", "replace")
+    except urllib.error.HTTPError as e:
+        raise ShareError(f"{url}: HTTP {e.code} {e.reason}")
+    except (urllib.error.URLError, OSError) as e:
+        raise ShareError(f"{url}: {getattr(e, 'reason', e)}")
+
@@ -0,0 +1,97 @@
+"""
+Snippets: abbreviations expanded while typing in the editor
+
+The [snippets] section of the config maps an abbreviation to the text it
+stands for, or to a note template ({template = "meeting"}). Typing the
+abbreviation in insert mode at the start of a line or after a space, then
+Space, Enter or Tab, replaces it with the text, placeholders filled in as in
+templates ({{date}}, {{time}}, {{datetime}}, {{weekday}}; see templates.py).
+Tab only expands; Space and Enter are typed after the text, unless it has a
+{{cursor}}, which is where the cursor goes instead.
+"""
+
+from dataclasses import dataclass
+from typing import Any, Dict, List, Optional, Tuple
+from .i18n import t
+from .templates import CURSOR, Template, find_template, list_templates, render_template
+
+
+@dataclass
+class Snippet:
+    """Text or a template an abbreviation expands to"""
+    abbreviation: str
+    text: str = ""
+    template: str = ""  # Name of a template, used instead of text
+
+
+@dataclass
+class Expansion:
+    """What a snippet expanded to"""
+    text: str
+    cursor: Optional[Tuple[int, int]]  # (row, column) in the text of its {{cursor}}, if it has one
+
+
+class Snippets:
+    """The abbreviations of the [snippets] section"""
+
+    def __init__(self, settings: Dict[str, Any], templates_directory: str):
+        """
+        Read the abbreviations
+
+        Invalid entries are skipped and described in `errors`.
+
+        Args:
+            settings: Abbreviation to text, or to a table with a "template" name
+            templates_directory: Where templates named by snippets are looked for
+        """
+        self.templates_directory = templates_directory
+        self.errors: List[str] = []
+        self.snippets: Dict[str, Snippet] = {}
+        for abbreviation, value in settings.items():
+            if not abbreviation or any(ch.isspace() for ch in abbreviation):
+                self.errors.append(t("config.invalid_snippet", abbreviation=abbreviation))
+            elif isinstance(value, str):
+                if value:  # Empty turns a snippet off
+                    self.snippets[abbreviation] = Snippet(abbreviation, text=value)
+            elif isinstance(value, dict) and isinstance(value.get("template"), str):
+                self.snippets[abbreviation] = Snippet(abbreviation, template=value["template"])
+            else:
+                self.errors.append(t("config.invalid_snippet", abbreviation=abbreviation))
+
+    def find(self, line: str, col: int) -> Optional[Snippet]:
+        """
+        Find the abbreviation just before a column of a line
+
+        The abbreviation has to start the line or follow whitespace, so
+        ";date" inside "x;date" isn't one.
+
+        Args:
+            line: Line being typed
+            col: Cursor column
+
+        Returns:
+            The longest snippet whose abbreviation ends at the column, or None
+        """
+        before = line[:col]
+        for abbreviation in sorted(self.snippets, key=len, reverse=True):
+            start = col - len(abbreviation)
+            if before.endswith(abbreviation) and (start == 0 or before[start - 1].isspace()):
+                return self.snippets[abbreviation]
+        return None
+
+    def expand(self, snippet: Snippet) -> Optional[Expansion]:
+        """
+        Fill in a snippet's placeholders
+
+        Returns:
+            The expansion, or None if the template it names doesn't exist
+        """
+        if snippet.template:
+            template = find_template(list_templates(self.templates_directory), snippet.template)
+            if template is None:
+                return None
+        else:
+            template = Template(snippet.abbreviation, snippet.text)
+        text, row, col = render_template(template)
+        # A template without a cursor mark puts the cursor on its first line; a snippet's goes after its text
+        return Expansion(text, (row, col) if CURSOR in template.content else None)
@@ -0,0 +1,186 @@
+"""
+Spellchecking in the editor
+
+With [spellcheck] enabled, the editor underlines words that none of the
+configured languages' dictionaries know while it has focus. z= on a word
+offers suggestions to replace it with, zg adds it to the personal dictionary
+and ]s / [s move to the next or previous misspelled word.
+
+Dictionaries come from the pyspellchecker package (pip install
+'termnotes[spellcheck]'), which ships English, Spanish, French, Portuguese,
+German, Italian, Russian, Arabic, Basque, Latvian, Dutch and Persian. A
+language it doesn't ship, or one to replace, is a word list named after it
+("sv.txt") in the dictionaries directory. The personal dictionary is a plain
+text file of one word per line.
+
+Code, links, URLs, HTML tags, hashtags and words in capitals (acronyms) are
+left alone.
+"""
+
+import re
+from pathlib import Path
+from typing import Dict, Iterable, List, Optional, Tuple
+from .i18n import t
+from .links import URL
+
+WORD = re.compile(r"[^\W\d_]+(?:['’][^\W\d_]+)*")
+
+# Parts of a line that aren't prose
+SKIP = re.compile(
+    r'`+[^`]*`+'  # Code spans
+    r'|\[\[[^\]\n]*\]\]'  # Wiki links
+    r'|\]\([^)\n]*\)'  # Link and image targets
+    r'|</?[A-Za-z][^>\n]*>'  # HTML tags and autolinks
+    r'|(?<![\w&])#[^\s#]+'  # Hashtags
+    r'|' + URL.pattern,
+    re.IGNORECASE
+)
+
+# Suggestions offered for a misspelled word, at most
+MAX_SUGGESTIONS = 9
+
+
+class SpellcheckError(Exception):
+    """Raised when the dictionaries can't be loaded"""
+
+
+def word_spans(line: str) -> List[Tuple[int, int]]:
+    """
+    Find the words of a line to check
+
+    Args:
+        line: Line of a note
+
+    Returns:
+        (start, end) of each word, leaving out those in code, links and
+        the like, single letters and words in capitals
+    """
+    skipped = [match.span() for match in SKIP.finditer(line)]
+    spans = []
+    for match in WORD.finditer(line):
+        start, end = match.span()
+        word = match.group()
+        if len(word) < 2 or word.isupper():
+            continue
+        if any(skip_start < end and start < skip_end for skip_start, skip_end in skipped):
+            continue
+        spans.append((start, end))
+    return spans
+
+
+def word_at(line: str, col: int) -> Optional[Tuple[int, int]]:
+    """
+    Find the word to check at a column of a line
+
+    Returns:
+        (start, end) of the word, or None if the column isn't on one
+    """
+    for start, end in word_spans(line):
+        if start <= col < end:
+            return start, end
+    return None
+
+
+class Spellchecker:
+    """Dictionaries of the configured languages and the personal dictionary"""
+
+    def __init__(self, languages: Iterable[str], dictionaries: Path, personal: Path):
+        """
+        Initialize the spellchecker, without loading its dictionaries yet
+
+        Args:
+            languages: Language codes ("en", "es"...); a word any of them knows is spelled right
+            dictionaries: Directory of word lists for languages pyspellchecker doesn't ship
+            personal: Personal dictionary file
+        """
+        self.languages = list(languages)
+        self.dictionaries = dictionaries
+        self.personal = personal
+        self._checker = None
+        self._known: Dict[str, bool] = {}  # Words looked up so far
+
+    def load(self):
+        """
+        Load the dictionaries and the personal dictionary
+
+        Raises:
+            SpellcheckError: If pyspellchecker isn't installed, or a
+                language has neither a built-in dictionary nor a word list
+        """
+        try:
+            from spellchecker import SpellChecker
+        except ImportError:
+            raise SpellcheckError(t("spell.no_library"))
+
+        word_lists = {language: self.dictionaries / f"{language}.txt" for language in self.languages}
+        built_in = [language for language, path in word_lists.items() if not path.is_file()]
+        try:
+            checker = SpellChecker(language=built_in or None)
+        except ValueError:
+            raise SpellcheckError(t("spell.no_dictionary", languages=", ".join(built_in),
+                                    directory=self.dictionaries))
+        for path in word_lists.values():
+            if path.is_file():
+                try:
+                    checker.word_frequency.load_text_file(str(path))
+                except (OSError, UnicodeDecodeError) as e:
+                    raise SpellcheckError(t("spell.load_failed", path=path, error=e))
+        checker.word_frequency.load_words(self._personal_words())
+        self._checker = checker
+        self._known.clear()
+
+    def _personal_words(self) -> List[str]:
+        """Read the personal dictionary (empty if there isn't one yet)"""
+        try:
+            return [word for word in self.personal.read_text(encoding="utf-8").split() if word]
+        except (OSError, UnicodeDecodeError):
+            return []
+
+    def is_known(self, word: str) -> bool:
+        """Check if a word is spelled right (always, before the dictionaries are loaded)"""
+        if self._checker is None:
+            return True
+        known = self._known.get(word)
+        if known is None:
+            key = word.replace("’", "'").lower()
+            known = key in self._checker or key.replace("'", "") in self._checker
+            self._known[word] = known
+        return known
+
+    def misspelled(self, line: str) -> List[Tuple[int, int]]:
+        """
+        Find the misspelled words of a line
+
+        Returns:
+            (start, end) of each
+        """
+        return [(start, end) for start, end in word_spans(line) if not self.is_known(line[start:end])]
+
+    def suggestions(self, word: str) -> List[str]:
+        """
+        Get the words a misspelled word was likely meant to be, most common first
+
+        The suggestions follow the word's capitalization ("Teh" gives "The").
+        """
+        if self._checker is None:
+            return []
+        candidates = self._checker.candidates(word.lower()) or set()
+        candidates.discard(word.lower())
+        ranked = sorted(candidates, key=lambda candidate: (-self._checker.word_usage_frequency(candidate), candidate))
+        if word[:1].isupper():
+            ranked = [candidate[:1].upper() + candidate[1:] for candidate in ranked]
+        return ranked[:MAX_SUGGESTIONS]
+
+    def add_word(self, word: str):
+        """
+        Add a word to the personal dictionary, so it's no longer underlined
+
+        Raises:
+            OSError: If the personal dictionary can't be written
+        """
+        self.personal.parent.mkdir(parents=True, exist_ok=True)
+        with open(self.personal, "a", encoding="utf-8") as f:
+            f.write(word + "\n")
+        if self._checker is not None:
+            self._checker.word_frequency.load_words([word])
+        self._known.clear()
@@ -0,0 +1,92 @@
+"""
+Note statistics: word counts, reading time, and totals across all notes
+
+Words are runs of letters and digits, so markdown markup ("#", "-", "**")
+isn't counted. Reading time assumes 200 words a minute.
+"""
+
+import math
+import re
+from dataclasses import dataclass, field
+from datetime import timezone
+from typing import Dict, Iterable
+from .note import Note
+
+WORDS_PER_MINUTE = 200
+WORD = re.compile(r"\w+(?:['’.-]\w+)*")
+
+
+@dataclass
+class TextStats:
+    """Size of one note's content"""
+    words: int
+    characters: int  # Not counting line breaks
+
+    @property
+    def reading_minutes(self) -> int:
+        """Estimated minutes to read the text, at least 1 unless it's empty"""
+        return math.ceil(self.words / WORDS_PER_MINUTE)
+
+
+@dataclass
+class NoteStats:
+    """Totals across all notes, reported by the stats command"""
+    notes: int = 0  # Not counting the trash
+    archived: int = 0
+    trashed: int = 0
+    words: int = 0
+    characters: int = 0
+    tags: Dict[str, int] = field(default_factory=dict)  # Notes with each tag
+    created_per_month: Dict[str, int] = field(default_factory=dict)  # "YYYY-MM" (local time), in order
+
+    @property
+    def reading_minutes(self) -> int:
+        """Estimated minutes to read every note"""
+        return math.ceil(self.words / WORDS_PER_MINUTE)
+
+
+def text_stats(content: str) -> TextStats:
+    """
+    Count the words and characters of a note's content
+
+    Args:
+        content: Note content
+
+    Returns:
+        Word and character counts
+    """
+    return TextStats(
+        words=len(WORD.findall(content)),
+        characters=len(content) - content.count("\n")
+    )
+
+
+def note_stats(notes: Iterable[Note]) -> NoteStats:
+    """
+    Total up notes; trashed notes are only counted as trashed
+
+    Args:
+        notes: Every note, including trashed and archived ones
+
+    Returns:
+        Totals, notes per tag, and notes created each month
+    """
+    stats = NoteStats()
+    months: Dict[str, int] = {}
+    for note in notes:
+        if note.is_trashed:
+            stats.trashed += 1
+            continue
+        stats.notes += 1
+        if note.is_archived:
+            stats.archived += 1
+        size = text_stats(note.content)
+        stats.words += size.words
+        stats.characters += size.characters
+        for tag in note.tags:
+            stats.tags[tag] = stats.tags.get(tag, 0) + 1
+        month = note.created_at.replace(tzinfo=timezone.utc).astimezone().strftime("%Y-%m")
+        months[month] = months.get(month, 0) + 1
+    stats.tags = dict(sorted(stats.tags.items(), key=lambda item: (-item[1], item[0])))
+    stats.created_per_month = dict(sorted(months.items()))
+    return stats
@@ -6,29 +6,66 @@ Provides flexible storage with multiple backend implementations:
 - FilesystemBackend: JSON files on disk
 - GoogleDriveBackend: JSON files in Google Drive
 - CompositeBackend: Combines multiple backends (cache + persistent)
+- MarkdownBackend: Markdown files named after note titles, with frontmatter
+- GitBackend: Markdown files in a git repository, committed on every change
+- SyncBackend: Local SQLite copy synced with a "termnotes serve" server
+- WebDAVBackend: Markdown files on a WebDAV server such as Nextcloud
+- PostgresBackend: PostgreSQL database shared by several editors
 - EncryptedBackend: Wraps another backend with encryption/decryption
+- ReadOnlyBackend: Wraps another backend, refusing every change
+- SecureBackend: Wraps another backend, encrypting notes marked secure
+- DaemonBackend: Notes held by a "termnotes daemon" process, shared by several editors
 """
 
+import socket
+import subprocess
+import sys
+import time
 import uuid
-from .base import StorageBackend
+from pathlib import Path
+from typing import Optional
+from .base import NoteConflict, StorageBackend
 from .sqlite_backend import SQLiteBackend
-from .filesystem_backend import FilesystemBackend
+from .filesystem_backend import FilesystemBackend, NoteFormatError
 from .composite_backend import CompositeBackend
 from .gdrive_backend import GoogleDriveBackend
+from .markdown_backend import MarkdownBackend
+from .git_backend import GitBackend
+from .sync_backend import SyncBackend
+from .webdav_backend import WebDAVBackend, WebDAVError
+from .postgres_backend import PostgresBackend
 from .encrypted_backend import EncryptedBackend
+from .readonly_backend import ReadOnlyBackend, ReadOnlyStorage
+from .secure_backend import SecureBackend
+from .daemon_backend import DaemonBackend, DaemonError
+from .lock import StorageLock, StorageLocked
+from .context import Context, OperationCancelled, current_context, use_context
+from .journal import OperationJournal
+from ..sync.protocol import SyncError
+from ..sync.e2e import Device, SyncKeys
+from ..backup import AutoBackup
+from ..credentials import SecretsError, get_secret, set_secret
+from ..hooks import HookRunner
 from ..note import Note
 from ..config import get_config
+from ..i18n import t
+from ..log import event, get_logger
+
+log = get_logger("storage")
 
 # Backward compatibility alias
 NoteStorage = SQLiteBackend
 
+# Seconds to wait for a daemon started by connect_daemon() to listen
+DAEMON_START_TIMEOUT = 10
+
 
 def _create_backend(backend_type: str, config) -> StorageBackend:
     """
     Create a storage backend by type.
 
     Args:
-        backend_type: Type of backend ("sqlite", "filesystem", "gdrive")
+        backend_type: Type of backend ("sqlite", "filesystem", "gdrive", "markdown", "git", "sync", "webdav", "postgres")
         config: Config instance
 
     Returns:
@@ -43,11 +80,267 @@ def _create_backend(backend_type: str, config) -> StorageBackend:
             app_folder=config.gdrive_folder_name
         )
     elif backend_type == "filesystem":
-        return FilesystemBackend(config.filesystem_directory)
+        return FilesystemBackend(
+            config.filesystem_directory,
+            fsync=config.filesystem_fsync,
+            coalesce_window=config.filesystem_coalesce_ms / 1000,
+            paranoid=config.filesystem_paranoid,
+            backup=config.filesystem_backup
+        )
+    elif backend_type == "markdown":
+        return MarkdownBackend(config.markdown_directory)
+    elif backend_type == "git":
+        return GitBackend(
+            config.git_directory,
+            remote=config.git_remote,
+            pull_on_start=config.git_pull_on_start,
+            push_on_exit=config.git_push_on_exit
+        )
+    elif backend_type == "sync":
+        if not config.sync_url:
+            raise RuntimeError(t("storage.sync_no_url"))
+        keys = None
+        if config.sync_e2e:
+            keys = SyncKeys(Device.load(sync_device_path(config), config.sync_device_name))
+        return SyncBackend(
+            SQLiteBackend(config.sync_path),
+            config.sync_url,
+            state_path=str(Path(config.sync_path).with_suffix(".state.json")),
+            token=config.sync_token or get_secret("sync"),
+            keys=keys,
+            ask_passphrase=prompt_sync_passphrase if sys.stdin.isatty() else None
+        )
+    elif backend_type == "webdav":
+        if not config.webdav_url:
+            raise RuntimeError(t("storage.webdav_no_url"))
+        return WebDAVBackend(config.webdav_url, config.webdav_username,
+                             config.webdav_password or get_secret("webdav"))
+    elif backend_type == "postgres":
+        if not config.postgres_dsn:
+            raise RuntimeError(t("storage.postgres_no_dsn"))
+        return PostgresBackend(config.postgres_dsn, config.postgres_password or get_secret("postgres"))
     else:
         raise ValueError(f"Unknown storage backend: {backend_type}")
 
 
+def sync_device_path(config=None) -> Path:
+    """
+    Get the file holding this device's end-to-end encryption key for sync
+
+    Kept next to the sync backend's local copy; it must never be synced,
+    as the key is what sets this device apart from the others.
+    """
+    config = config or get_config()
+    return Path(config.sync_path).with_suffix(".device.json")
+
+
+def find_backend(storage: StorageBackend, backend_class: type) -> Optional[StorageBackend]:
+    """Find a backend of a class under a backend's cache, encryption and read-only wrappers, if there is one"""
+    while storage is not None and not isinstance(storage, backend_class):
+        storage = getattr(storage, "persistent", None) or getattr(storage, "backend", None)
+    return storage
+
+
+def find_sync_backend(storage: StorageBackend) -> Optional[SyncBackend]:
+    """Find the SyncBackend under a backend's wrappers, if there is one"""
+    return find_backend(storage, SyncBackend)
+
+
+def _storage_location(config) -> Path:
+    """Get the database file or notes directory of the configured backend"""
+    backend_type = config.storage_backend
+    if backend_type == "encrypted":
+        backend_type = config.encrypted_wraps
+    location = {
+        "sqlite": config.sqlite_path,
+        "sync": config.sync_path,
+        "gdrive": config.gdrive_token_path,
+        "filesystem": config.filesystem_directory,
+        "markdown": config.markdown_directory,
+        "git": config.git_directory,
+        # The database is on a server; lock per machine, next to the local files
+        "postgres": str(Path(config.sqlite_path).with_name("postgres")),
+    }.get(backend_type, config.sqlite_path)
+    return Path(location.rstrip("/\\"))
+
+
+def storage_lock_path(config=None) -> Path:
+    """
+    Get the lock file for the configured notes location
+
+    The lock sits next to the database file or notes directory, so editors
+    using the same notes share it, and it stays out of directories that are
+    synced or committed.
+
+    Args:
+        config: Config instance (defaults to the global config)
+
+    Returns:
+        Path of the lock file
+    """
+    location = _storage_location(config or get_config())
+    return location.with_name(location.name + ".lock")
+
+
+def storage_draft_path(config=None) -> Path:
+    """
+    Get the draft file for the configured notes location
+
+    Kept next to the lock file, for the same reasons: one editor at a time
+    works on these notes, and drafts shouldn't be synced or committed.
+
+    Args:
+        config: Config instance (defaults to the global config)
+
+    Returns:
+        Path of the draft file
+    """
+    location = _storage_location(config or get_config())
+    return location.with_name(location.name + ".draft")
+
+
+def storage_recent_path(config=None) -> Path:
+    """
+    Get the file of recently viewed notes for the configured notes location
+
+    Kept next to the lock file like drafts: viewing a note doesn't change
+    it, so when it was viewed shouldn't be synced or committed.
+
+    Args:
+        config: Config instance (defaults to the global config)
+
+    Returns:
+        Path of the recently viewed notes file
+    """
+    location = _storage_location(config or get_config())
+    return location.with_name(location.name + ".recent")
+
+
+def storage_secure_path(config=None) -> Path:
+    """
+    Get the file checking the secure notes' passphrase for the configured notes location
+
+    Kept next to the lock file like drafts: it only holds a check value,
+    and another machine makes its own from a secure note the first time
+    it unlocks them.
+
+    Args:
+        config: Config instance (defaults to the global config)
+
+    Returns:
+        Path of the verifier file
+    """
+    location = _storage_location(config or get_config())
+    return location.with_name(location.name + ".secure")
+
+
+def storage_socket_path(config=None) -> Path:
+    """
+    Get the socket of the daemon serving the configured notes
+
+    Kept next to the lock file the daemon holds, unless `[daemon] socket`
+    says otherwise.
+
+    Args:
+        config: Config instance (defaults to the global config)
+
+    Returns:
+        Path of the unix socket
+    """
+    config = config or get_config()
+    if config.daemon_socket:
+        return Path(config.daemon_socket).expanduser()
+    location = _storage_location(config)
+    return location.with_name(location.name + ".sock")
+
+
+def connect_daemon(config=None, start: bool = False) -> Optional[StorageBackend]:
+    """
+    Use the notes through the daemon serving them, if there is one
+
+    Args:
+        config: Config instance (defaults to the global config)
+        start: Start the daemon in the background if none is running
+
+    Returns:
+        DaemonBackend set up like create_default_storage() sets up backends,
+        or None if no daemon is running (and none was started)
+
+    Raises:
+        RuntimeError: If a daemon was started but didn't start listening
+    """
+    config = config or get_config()
+    path = str(storage_socket_path(config))
+    try:
+        backend = DaemonBackend(path)
+    except (OSError, AttributeError):  # AttributeError: no unix sockets (Windows)
+        if not start or not hasattr(socket, "AF_UNIX"):
+            return None
+        backend = _start_daemon(path)
+    event(log, "open", backend="daemon", socket=path)
+    return _finish_storage(backend, config)
+
+
+def _start_daemon(path: str) -> DaemonBackend:
+    """
+    Start "termnotes daemon" in the background and connect to it
+
+    Args:
+        path: Socket it will listen on
+
+    Returns:
+        Backend connected to it
+
+    Raises:
+        RuntimeError: If it exits or doesn't listen within DAEMON_START_TIMEOUT
+    """
+    process = subprocess.Popen(
+        [sys.executable, "-m", "termnotes", "daemon"],
+        stdin=subprocess.DEVNULL, stdout=subprocess.DEVNULL, stderr=subprocess.DEVNULL,
+        start_new_session=True,  # Outlives the terminal that started it
+    )
+    deadline = time.monotonic() + DAEMON_START_TIMEOUT
+    while process.poll() is None and time.monotonic() < deadline:
+        time.sleep(0.05)
+        try:
+            return DaemonBackend(path)
+        except OSError:
+            continue
+    raise RuntimeError(t("daemon.start_failed", path=path))
+
+
+def open_storage(lock: Optional[StorageLock] = None) -> StorageBackend:
+    """
+    Open the notes for an editor or command
+
+    Goes through the daemon if one is running (starting it first with
+    `[daemon] enabled`), and otherwise opens the storage directly.
+
+    Args:
+        lock: Lock to take before opening the storage directly, for
+            commands that write notes (the daemon holds the lock itself);
+            release it after closing the storage
+
+    Returns:
+        The backend to use
+
+    Raises:
+        StorageLocked: If the lock is given and an editor has the notes open
+    """
+    config = get_config()
+    storage = connect_daemon(config, start=config.daemon_enabled)
+    if storage is not None:
+        return storage
+    if lock is not None:
+        lock.acquire()
+    try:
+        return create_default_storage()
+    except BaseException:
+        if lock is not None:
+            lock.release()
+        raise
+
+
 def _get_or_create_passphrase(config) -> str:
     """
     Get passphrase from key file or generate new one.
@@ -68,6 +361,11 @@ def _get_or_create_passphrase(config) -> str:
     import os
     from pathlib import Path
 
+    if config.encrypted_key_store == "keyring":
+        return _get_or_create_keyring_passphrase(config)
+    if config.encrypted_key_store != "file":
+        raise RuntimeError(t("storage.unknown_key_store", store=config.encrypted_key_store))
+
     key_file_path = Path(config.encrypted_key_file)
 
     # Try to read from key file
@@ -78,12 +376,12 @@ def _get_or_create_passphrase(config) -> str:
             if passphrase:
                 return passphrase
             else:
-                print(f"Warning: Key file {key_file_path} is empty, regenerating")
+                print(t("storage.key_file_empty", path=key_file_path))
         except Exception as e:
-            print(f"Warning: Failed to read key file {key_file_path}: {e}")
+            print(t("storage.key_file_read_failed", path=key_file_path, error=e))
 
     # Generate new passphrase
-    print(f"Generating new encryption passphrase...")
+    print(t("storage.generating_passphrase"))
     passphrase = EncryptedBackend.generate_passphrase()
 
     # Save to key file
@@ -94,41 +392,202 @@ def _get_or_create_passphrase(config) -> str:
         # Set restrictive permissions (owner read/write only)
         os.chmod(key_file_path, 0o600)
 
-        print(f"✓ Generated passphrase: {passphrase}")
-        print(f"✓ Saved to {key_file_path}")
-        print(f"⚠️  Keep this passphrase secure! Write it down in a safe place.")
-        print(f"⚠️  Without it, encrypted notes cannot be decrypted.")
+        print(t("storage.generated_passphrase", passphrase=passphrase))
+        print(t("storage.saved_to", path=key_file_path))
+        print(t("storage.keep_passphrase_secure"))
+        print(t("storage.passphrase_required"))
     except Exception as e:
-        print(f"Warning: Failed to save key file: {e}")
+        print(t("storage.key_file_save_failed", error=e))
 
-    print("Press Enter to continue...")
+    print(t("prompt.press_enter"))
     input()
 
     return passphrase
 
 
-def create_default_storage() -> StorageBackend:
+def _get_or_create_keyring_passphrase(config) -> str:
+    """
+    Get the passphrase from the system keyring's "encryption" secret
+
+    The first time, a passphrase already in the key file is moved there (the
+    file is left for the user to delete), or a new one is generated.
+
+    Args:
+        config: Config instance
+
+    Returns:
+        Passphrase string
+
+    Raises:
+        RuntimeError: If the keyring can't store the passphrase
+    """
+    passphrase = get_secret("encryption")
+    if passphrase:
+        return passphrase
+
+    key_file_path = Path(config.encrypted_key_file)
+    try:
+        with open(key_file_path, "r", encoding="utf-8") as f:
+            passphrase = f.read().strip()
+    except OSError:
+        passphrase = ""
+    generated = not passphrase
+    if generated:
+        print(t("storage.generating_passphrase"))
+        passphrase = EncryptedBackend.generate_passphrase()
+
+    try:
+        set_secret("encryption", passphrase)
+    except SecretsError as e:
+        raise RuntimeError(str(e))
+
+    if generated:
+        print(t("storage.generated_passphrase", passphrase=passphrase))
+        print(t("storage.saved_to_keyring"))
+        print(t("storage.keep_passphrase_secure"))
+        print(t("storage.passphrase_required"))
+        print(t("prompt.press_enter"))
+        input()
+    else:
+        print(t("storage.key_file_moved", path=key_file_path))
+    return passphrase
+
+
+def _prompt_for_encrypted_backend(wrapped_backend: StorageBackend, max_attempts: int = 3,
+                                  migrate: bool = True) -> EncryptedBackend:
+    """
+    Ask for the passphrase on the terminal and open the encrypted backend.
+
+    If the backend already holds encrypted notes, the passphrase is checked
+    against them and asked for again when wrong. Otherwise the user chooses a
+    new passphrase and enters it twice.
+
+    Args:
+        wrapped_backend: Backend holding the encrypted notes
+        max_attempts: Number of tries before giving up
+        migrate: Encrypt notes that aren't encrypted yet
+
+    Returns:
+        EncryptedBackend using the entered passphrase
+
+    Raises:
+        SystemExit: If no valid passphrase is entered
+    """
+    import sys
+    from prompt_toolkit import prompt
+
+    is_new = not EncryptedBackend.has_encrypted_notes(wrapped_backend)
+
+    try:
+        for _ in range(max_attempts):
+            passphrase = prompt(
+                t("storage.new_passphrase" if is_new else "storage.enter_passphrase"),
+                is_password=True
+            )
+            if not passphrase:
+                print(t("storage.passphrase_empty"))
+                continue
+
+            if is_new:
+                if prompt(t("storage.confirm_passphrase"), is_password=True) != passphrase:
+                    print(t("storage.passphrase_mismatch"))
+                    continue
+                return EncryptedBackend(wrapped_backend, passphrase, auto_migrate=migrate)
+
+            backend = EncryptedBackend(wrapped_backend, passphrase, auto_migrate=False)
+            if backend.verify_passphrase():
+                if migrate:
+                    backend._migrate_unencrypted_notes()
+                return backend
+            print(t("storage.passphrase_wrong"))
+    except (EOFError, KeyboardInterrupt):
+        sys.exit(1)
+
+    sys.exit(t("storage.passphrase_attempts"))
+
+
+def prompt_sync_passphrase(new: bool) -> str:
+    """
+    Ask for the end-to-end encryption passphrase of sync on the terminal
+
+    Args:
+        new: Whether the user is choosing it (entered twice)
+
+    Returns:
+        The passphrase, or "" if none was given
+    """
+    from prompt_toolkit import prompt
+
+    try:
+        passphrase = prompt(t("storage.e2e_new_passphrase" if new else "storage.e2e_enter_passphrase"),
+                            is_password=True)
+        if new and passphrase and prompt(t("storage.confirm_passphrase"), is_password=True) != passphrase:
+            print(t("storage.passphrase_mismatch"))
+            return ""
+    except (EOFError, KeyboardInterrupt):
+        return ""
+    return passphrase
+
+
+def create_default_storage(background_sync: bool = False) -> StorageBackend:
     """
     Create the default storage backend for termnotes.
 
     Returns a composite backend with:
     - SQLite in-memory cache (fast reads/writes)
-    - Configured persistent storage (filesystem, sqlite, gdrive, or encrypted)
+    - Configured persistent storage (filesystem, sqlite, gdrive, markdown, git, sync, webdav, or encrypted)
 
-    For encrypted backend, automatically generates and saves encryption key if needed.
+    The sqlite backend is used directly, without a cache: its database file is
+    as fast to query as the cache would be. When that file is first created,
+    notes saved by the filesystem backend are copied into it.
 
-    If the storage is empty, populates it with a welcome note.
+    For encrypted backend, automatically generates and saves encryption key if needed,
+    or asks for the passphrase on startup if configured to.
+
+    The sync backend pulls changes from the server before the storage is
+    returned, unless background_sync is set: the editor syncs in the
+    background once it runs, so a slow server doesn't hold it up.
+
+    If the storage is empty, populates it with a welcome note (not for a
+    sync backend that has never pulled from its server, whose notes may be
+    on the way). With `read_only` in [storage], it is wrapped in a
+    ReadOnlyBackend instead, and nothing is written while opening it.
+
+    Args:
+        background_sync: Leave the first sync to the caller
 
     Returns:
-        CompositeBackend configured with SQLite cache + persistent storage
+        CompositeBackend configured with SQLite cache + persistent storage,
+        or the SQLiteBackend or PostgresBackend for the sqlite and postgres backends
+        (wrapped in a ReadOnlyBackend if read-only)
     """
     config = get_config()
-    cache = SQLiteBackend(":memory:")
 
     # Create persistent backend based on configuration
     backend_type = config.storage_backend
-
     if backend_type == "encrypted":
+        event(log, "open", backend=backend_type, wraps=config.encrypted_wraps)
+    else:
+        event(log, "open", backend=backend_type)
+
+    if backend_type == "sqlite":
+        is_new = not Path(config.sqlite_path).exists()
+        storage = SQLiteBackend(config.sqlite_path)
+        if is_new and not config.storage_read_only:
+            _migrate_filesystem_notes(storage, config.filesystem_directory)
+        return _finish_storage(storage, config)
+
+    if backend_type == "postgres":
+        # No cache: reading straight from the server shows other editors' changes
+        storage = _create_backend(backend_type, config)
+        return _finish_storage(storage, config)
+
+    cache = SQLiteBackend(":memory:")
+    if backend_type == "encrypted" and config.encrypted_prompt_passphrase:
+        # Ask for the passphrase instead of keeping it in a key file
+        wrapped_backend = _create_backend(config.encrypted_wraps, config)
+        persistent = _prompt_for_encrypted_backend(wrapped_backend, migrate=not config.storage_read_only)
+    elif backend_type == "encrypted":
         # Get or create passphrase (salt will be derived from passphrase)
         passphrase = _get_or_create_passphrase(config)
 
@@ -138,80 +597,118 @@ def create_default_storage() -> StorageBackend:
 
         # Wrap with encryption (passphrase will be converted to key via PBKDF2)
         # Salt is derived from passphrase using BLAKE2b
-        persistent = EncryptedBackend(wrapped_backend, passphrase)
+        persistent = EncryptedBackend(wrapped_backend, passphrase, auto_migrate=not config.storage_read_only)
     else:
         # Standard backend (no encryption)
         persistent = _create_backend(backend_type, config)
 
-    storage = CompositeBackend(cache, persistent)
-
-    # Insert welcome note if storage is empty
-    if len(storage.get_all_notes()) == 0:
-        welcome_content = """# Welcome to termnotes!
-
-A vim-like terminal note-taking application with markdown support.
-
-## Quick Start Guide
+    storage = CompositeBackend(cache, persistent, write_delay=config.write_delay_ms / 1000)
+    if not background_sync and find_sync_backend(storage) is not None:
+        try:
+            storage.sync()
+        except SyncError as e:
+            print(t("storage.sync_failed", error=e))
+    return _finish_storage(storage, config)
 
-### Navigation
-- `Ctrl+W h` - Switch to sidebar
-- `Ctrl+W l` - Switch to editor
-- `j/k` - Move down/up (in both sidebar and editor)
-- `h/l` - Move left/right in editor
 
-### Creating Notes
-- `:new` or `:n` - Create new empty note
-- `o` - Create new note (when sidebar is focused)
+def _finish_storage(storage: StorageBackend, config) -> StorageBackend:
+    """
+    Set up an opened backend from the config
 
-### Deleting Notes
-- `dd` - Delete selected note (when sidebar is focused, confirms with second dd)
-- `:delete` or `:d` - Delete current note (confirms with second :d)
-- `:d!` - Force delete current note without confirmation
+    Sets the attachments directory and wraps it in the SecureBackend, then
+    sets either the undo journal, hooks, automatic backups and welcome note,
+    or for read-only storage the ReadOnlyBackend wrapper (with nothing to
+    undo or back up and no changes to run hooks for).
 
-### Editing
-- `i` - Enter Insert mode
-- `Esc` - Return to Normal mode
-- `dd` - Delete current line (when editor is focused)
-- `o` - Insert new line below (when editor is focused)
-- `O` - Insert new line above
+    Args:
+        storage: Opened backend
+        config: Config to read the settings from
 
-### Vim Commands
-- `:w` - Save current note
-- `:e!` - Discard changes and reload
-- `:q` - Quit (prompts if unsaved changes)
-- `:wq` - Save and quit
+    Returns:
+        The backend to use
+    """
+    storage.attachments_dir = Path(config.attachments_directory)
+    storage = SecureBackend(storage, storage_secure_path(config))
+    if config.storage_read_only:
+        return ReadOnlyBackend(storage)
+    if config.undo_levels > 0:
+        storage.journal = OperationJournal(config.undo_levels)
+    if config.hooks:
+        storage.hooks = HookRunner(config.hooks)
+    if config.backup_auto and config.backup_keep > 0:
+        storage.backups = AutoBackup(config.backup_directory, config.backup_keep)
+    _add_welcome_note(storage)
+    return storage
 
-## Code Highlighting Example
 
-termnotes supports syntax highlighting for code blocks:
+def _add_welcome_note(storage: StorageBackend):
+    """Insert the welcome note if the storage is empty, marking it as a first run"""
+    sync = find_sync_backend(storage)
+    if sync is not None and not sync.has_synced:
+        return  # The server may have notes for it
+    if len(storage.get_all_notes()) == 0:
+        welcome_note = Note(note_id=str(uuid.uuid4()), content=t("welcome.content"))
+        storage.save_note(welcome_note)
+        storage.first_run = True
 
-```python
-def fibonacci(n):
-    \"\"\"Calculate the nth Fibonacci number\"\"\"
-    if n <= 1:
-        return n
-    return fibonacci(n - 1) + fibonacci(n - 2)
 
-# Generate first 10 Fibonacci numbers
-for i in range(10):
-    print(f"F({i}) = {fibonacci(i)}")
-```
+def _migrate_filesystem_notes(storage: SQLiteBackend, directory: str):
+    """
+    Copy notes saved by the filesystem backend into a new SQLite database
 
-Happy note-taking!
-"""
-        welcome_note = Note(note_id=str(uuid.uuid4()), content=welcome_content)
-        storage.save_note(welcome_note)
+    Lets users of the filesystem backend switch to sqlite without losing
+    notes. The JSON files are left in place.
 
-    return storage
+    Args:
+        storage: Newly created SQLite database
+        directory: The filesystem backend's directory
+    """
+    if not Path(directory).is_dir():
+        return
+    notes = FilesystemBackend(directory).get_all_notes()
+    if notes:
+        storage.import_notes(notes)
+        print(t("storage.copied_filesystem_notes", count=len(notes), path=directory))
 
 
 __all__ = [
     "StorageBackend",
+    "NoteConflict",
     "SQLiteBackend",
     "FilesystemBackend",
+    "NoteFormatError",
     "GoogleDriveBackend",
+    "MarkdownBackend",
+    "GitBackend",
+    "SyncBackend",
+    "WebDAVBackend",
+    "WebDAVError",
+    "PostgresBackend",
     "CompositeBackend",
     "EncryptedBackend",
+    "ReadOnlyBackend",
+    "ReadOnlyStorage",
+    "SecureBackend",
+    "DaemonBackend",
+    "DaemonError",
     "NoteStorage",
     "create_default_storage",
+    "connect_daemon",
+    "open_storage",
+    "storage_lock_path",
+    "storage_draft_path",
+    "storage_recent_path",
+    "storage_secure_path",
+    "storage_socket_path",
+    "sync_device_path",
+    "find_backend",
+    "find_sync_backend",
+    "prompt_sync_passphrase",
+    "StorageLock",
+    "StorageLocked",
+    "OperationJournal",
+    "Context",
+    "OperationCancelled",
+    "current_context",
+    "use_context",
 ]
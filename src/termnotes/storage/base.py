@@ -3,13 +3,207 @@ Abstract base class for storage backends
 """
 
 from abc import ABC, abstractmethod
-from typing import List, Optional
+from contextlib import contextmanager
+from datetime import datetime, timedelta
+from pathlib import Path
+from typing import Any, Collection, Iterable, Iterator, List, Optional, Set
+import bisect
+import functools
+import shutil
+import threading
 import uuid
-from ..note import Note
+from .context import current_context
+from .journal import Change, Operation, OperationJournal, snapshot
+from ..backup import BULK_ACTIONS, AutoBackup
+from ..attachments import ATTACHMENTS_PROPERTY, Attachment, note_attachments, unique_name
+from ..note import Note, NoteSummary
+from ..notebook import Notebook, get_note_notebook
+from ..search import SearchResult, build_snippet, count_matches, fuzzy_search, tokenize_query
+from ..history import Revision
+from ..hooks import HookRunner
+from ..links import link_targets, normalize_link
+from ..outline import split_sections
+from ..replace import Replacement, compile_pattern, replace_in_content
+from ..stats import NoteStats, note_stats
+from ..tasks import Task, collect_tasks, toggle_task_line
+from ..utils import utc_now
+from ..i18n import t
+from ..log import get_logger, timed
+
+log = get_logger("storage")
+
+# Gap left between the positions of the manual order, so moving a note only changes its own
+POSITION_STEP = 1024
+
+
+def journaled(action: str):
+    """
+    Record a StorageBackend method that changes one note in the operation journal
+
+    The decorated method's first argument must be the note's ID.
+
+    Args:
+        action: Kind of operation, naming its "journal.<action>" description
+    """
+    def decorator(method):
+        @functools.wraps(method)
+        def wrapper(self, note_id, *args, **kwargs):
+            with self.journal_operation(action, [note_id]):
+                return method(self, note_id, *args, **kwargs)
+        return wrapper
+    return decorator
+
+
+def synchronized(method):
+    """
+    Run a StorageBackend method holding the backend's lock
+
+    Keeps threads sharing a backend from interleaving the statements of
+    their reads and writes. The lock is reentrant, so synchronized methods
+    may call each other.
+    """
+    @functools.wraps(method)
+    def wrapper(self, *args, **kwargs):
+        with self.lock:
+            return method(self, *args, **kwargs)
+    return wrapper
+
+
+def check_note_id(note_id: str) -> str:
+    """
+    Make sure a note ID is safe to name files and directories after
+
+    IDs come from sync servers and imported files as well as from here, and
+    backends keep notes and attachments in paths made from them.
+
+    Args:
+        note_id: ID to check
+
+    Returns:
+        The ID
+
+    Raises:
+        ValueError: If the ID is empty, "." or "..", or has a path separator or a NUL
+    """
+    if not note_id or note_id in (".", "..") or any(ch in note_id for ch in "/\\\0"):
+        raise ValueError(t("storage.invalid_note_id", note_id=note_id))
+    return note_id
+
+
+def _increasing_run(values: List[Optional[int]]) -> List[int]:
+    """Get the indexes of a longest run of values, not necessarily adjacent, that increase (None skipped)"""
+    tails: List[int] = []  # Smallest last value of a run of each length
+    tail_indexes: List[int] = []
+    previous: List[Optional[int]] = [None] * len(values)
+    for index, value in enumerate(values):
+        if value is None:
+            continue
+        length = bisect.bisect_left(tails, value)
+        if length == len(tails):
+            tails.append(value)
+            tail_indexes.append(index)
+        else:
+            tails[length] = value
+            tail_indexes[length] = index
+        previous[index] = tail_indexes[length - 1] if length else None
+    run = []
+    index = tail_indexes[-1] if tail_indexes else None
+    while index is not None:
+        run.append(index)
+        index = previous[index]
+    return run[::-1]
+
+
+def order_positions(positions: List[Any]) -> List[int]:
+    """
+    Number notes for the manual order, changing as few of their positions as possible
+
+    The longest run of positions already in order is kept, and the notes
+    between get positions spread over the gaps around them; only when a gap
+    is too small is every note numbered again, POSITION_STEP apart.
+
+    Args:
+        positions: The notes' "position" properties, in their new order (None for none)
+
+    Returns:
+        The position for each note
+    """
+    numbers = [value if isinstance(value, int) and not isinstance(value, bool) else None for value in positions]
+    kept = _increasing_run(numbers)
+    if not kept:
+        return [index * POSITION_STEP for index in range(len(positions))]
+    result = list(numbers)
+    bounds = [-1] + kept + [len(positions)]
+    for before, after in zip(bounds, bounds[1:]):
+        count = after - before - 1
+        if not count:
+            continue
+        low = numbers[before] if before >= 0 else numbers[after] - (count + 1) * POSITION_STEP
+        high = numbers[after] if after < len(positions) else low + (count + 1) * POSITION_STEP
+        if high - low <= count:
+            return [index * POSITION_STEP for index in range(len(positions))]
+        step = (high - low) // (count + 1)
+        for offset in range(1, count + 1):
+            result[before + offset] = low + step * offset
+    return result
+
+
+class NoteConflict(RuntimeError):
+    """A note changed in storage since the version an edit started from"""
+
+    def __init__(self, stored: Note):
+        """
+        Initialize
+
+        Args:
+            stored: The note as it is stored now
+        """
+        super().__init__(t("storage.conflict", title=stored.title))
+        self.stored = stored
 
 
 class StorageBackend(ABC):
-    """Abstract interface for note storage backends"""
+    """
+    Abstract interface for note storage backends
+
+    Backends are used from one thread unless they say otherwise. SQLiteBackend
+    and CompositeBackend may be shared between threads: each call is atomic
+    (see synchronized), though a read followed by a write is not.
+    """
+
+    # Whether the backend records a revision of a note on every save
+    supports_revisions = False
+
+    # Whether sync() exchanges changes with another machine
+    supports_sync = False
+
+    # Where attached files are copied, in a subdirectory per note
+    attachments_dir = Path("~/.local/share/termnotes/attachments").expanduser()
+
+    # Operations that can be undone; None to not record them
+    journal: Optional[OperationJournal] = None
+
+    # Shell commands run when notes change; None to not run any
+    hooks: Optional[HookRunner] = None
+
+    # Backups written before bulk operations (see backup.BULK_ACTIONS); None to not write any
+    backups: Optional[AutoBackup] = None
+
+    # Whether the store was empty when opened, so the welcome note was just added
+    first_run = False
+
+    # True inside journal_operation, so nested operations are part of the outer one
+    _operation_open = False
+
+    def __init__(self):
+        """Initialize the state every backend keeps; subclasses call this first"""
+        self._empty_notebooks: Set[str] = set()  # Notebooks created this session, maybe with no notes yet
+        self._lock = threading.RLock()
+
+    @property
+    def lock(self) -> threading.RLock:
+        """Get the lock synchronized methods hold"""
+        return self._lock
 
     @abstractmethod
     def get_all_notes(self) -> List[Note]:
@@ -21,6 +215,45 @@ class StorageBackend(ABC):
         """
         pass
 
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """
+        Get a page of notes to list, without their full contents
+
+        The default cuts down get_all_notes(); database backends only read
+        the start of each note's content.
+
+        Args:
+            offset: Number of notes to skip
+            limit: Most notes to return (None for all the rest)
+
+        Returns:
+            Summaries in get_all_notes() order, most recently updated first
+        """
+        notes = self.get_all_notes()
+        end = None if limit is None else offset + limit
+        return [NoteSummary.of(note) for note in notes[offset:end]]
+
+    def notes_at_rest(self) -> List[Note]:
+        """
+        Get every note as it's stored, for backups
+
+        The default is get_all_notes(); wrappers that decrypt notes on the
+        way out return them still encrypted.
+
+        Returns:
+            List of notes
+        """
+        return self.get_all_notes()
+
+    def count_notes(self) -> int:
+        """
+        Count the stored notes, including trashed and archived ones
+
+        Returns:
+            Number of notes
+        """
+        return len(self.get_all_notes())
+
     @abstractmethod
     def get_note(self, note_id: str) -> Optional[Note]:
         """
@@ -44,13 +277,62 @@ class StorageBackend(ABC):
         """
         pass
 
-    def create_note(self) -> Note:
-        """Create a new empty note with a unique ID"""
+    @synchronized
+    def update_note(self, note: Note, expected_version: Optional[str]):
+        """
+        Save a note unless someone else changed it since an edit started (optimistic locking)
+
+        A note that has been deleted meanwhile is saved again. The default
+        checks and saves holding the backend's lock; backends that other
+        programs write to at the same time override it to check atomically.
+
+        Args:
+            note: Note object to save
+            expected_version: Note.version of the stored note the edit started
+                from (None to save regardless, e.g. for a new note)
+
+        Raises:
+            NoteConflict: If the stored note has another version
+        """
+        if expected_version is not None:
+            stored = self.get_note(note.id)
+            if stored is not None and stored.version != expected_version:
+                raise NoteConflict(stored)
+        self.save_note(note)
+
+    def create_note(self, content: str = "") -> Note:
+        """
+        Create a new note with a unique ID, not yet saved
+
+        Args:
+            content: Starting content, e.g. a rendered template
+
+        Returns:
+            The new note
+        """
         # Generate a UUID v4 for the note
         note_id = str(uuid.uuid4())
-        note = Note(note_id=note_id, content="")
+        note = Note(note_id=note_id, content=content)
         return note
 
+    def import_notes(self, notes: List[Note]) -> int:
+        """
+        Store many notes at once, keeping their timestamps
+
+        Used by importers. The default saves each note in turn, which sets its
+        updated time to now; backends override this to write the whole batch
+        together with the notes' own created and updated times.
+
+        Args:
+            notes: Notes to store
+
+        Returns:
+            Number of notes stored
+        """
+        for note in notes:
+            self.save_note(note)
+        return len(notes)
+
     @abstractmethod
     def delete_note(self, note_id: str):
         """
@@ -61,7 +343,982 @@ class StorageBackend(ABC):
         """
         pass
 
+    def delete_notes(self, note_ids: List[str]) -> int:
+        """
+        Delete several notes as one operation, e.g. the notes marked in the list
+
+        Args:
+            note_ids: IDs of the notes to delete
+
+        Returns:
+            Number of notes deleted (IDs of missing notes are skipped)
+        """
+        existing = [note_id for note_id in note_ids if self.get_note(note_id) is not None]
+        with self.journal_operation("delete_many", existing):
+            if existing:
+                self._delete_batch(existing)
+        return len(existing)
+
+    def _delete_batch(self, note_ids: List[str]):
+        """
+        Delete existing notes for delete_notes()
+
+        The default deletes them one by one; backends override this to delete
+        them together.
+
+        Args:
+            note_ids: IDs of stored notes
+        """
+        for note_id in note_ids:
+            self.delete_note(note_id)
+
+    @journaled("tag")
+    def add_tag(self, note_id: str, tag: str) -> Optional[Note]:
+        """
+        Add a tag to a stored note
+
+        Args:
+            note_id: ID of the note to tag
+            tag: Tag name
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        if note.add_tag(tag):
+            self.save_note(note)
+        return note
+
+    @journaled("untag")
+    def remove_tag(self, note_id: str, tag: str) -> Optional[Note]:
+        """
+        Remove a tag from a stored note
+
+        Args:
+            note_id: ID of the note
+            tag: Tag name
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        if note.remove_tag(tag):
+            self.save_note(note)
+        return note
+
+    def set_pinned(self, note_id: str, pinned: bool) -> Optional[Note]:
+        """
+        Pin a note to the top of the list, or unpin it
+
+        Only where the note is listed changes, so it is stored keeping its
+        updated time and without a revision, like reorder_notes().
+
+        Args:
+            note_id: ID of the note
+            pinned: Whether the note should be pinned
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        with self.journal_operation("pin" if pinned else "unpin", [note_id]):
+            note = self.get_note(note_id)
+            if note is None:
+                return None
+            if note.pinned != pinned:
+                if pinned:
+                    note.set_property("pinned", True)
+                else:
+                    note.delete_property("pinned")
+                self.import_notes([note])
+            return note
+
+    def set_starred(self, note_id: str, starred: bool) -> Optional[Note]:
+        """
+        Star a note as a favorite, or unstar it
+
+        Unlike pinning, starring doesn't move the note in the list; the
+        starred view lists only starred notes.
+
+        Args:
+            note_id: ID of the note
+            starred: Whether the note should be starred
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        with self.journal_operation("star" if starred else "unstar", [note_id]):
+            note = self.get_note(note_id)
+            if note is None:
+                return None
+            if note.starred != starred:
+                if starred:
+                    note.set_property("starred", True)
+                else:
+                    note.delete_property("starred")
+                self.save_note(note)
+            return note
+
+    def set_color(self, note_id: str, color: Optional[str]) -> Optional[Note]:
+        """
+        Set or clear a note's color label
+
+        Args:
+            note_id: ID of the note
+            color: One of COLORS, or None to clear it
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        with self.journal_operation("color" if color else "uncolor", [note_id]):
+            note = self.get_note(note_id)
+            if note is None:
+                return None
+            if note.color != color:
+                if color:
+                    note.set_property("color", color)
+                else:
+                    note.delete_property("color")
+                self.save_note(note)
+            return note
+
+    @journaled("due")
+    def set_due(self, note_id: str, due_at: Optional[datetime]) -> Optional[Note]:
+        """
+        Set or clear a note's due date
+
+        Args:
+            note_id: ID of the note
+            due_at: Due date in UTC, or None to clear it
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        return self._set_time_property(note_id, "due_at", due_at)
+
+    @journaled("remind")
+    def set_reminder(self, note_id: str, remind_at: Optional[datetime]) -> Optional[Note]:
+        """
+        Set or clear when to remind about a note
+
+        Args:
+            note_id: ID of the note
+            remind_at: Reminder time in UTC, or None to clear it
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        return self._set_time_property(note_id, "remind_at", remind_at)
+
+    def _set_time_property(self, note_id: str, key: str, value: Optional[datetime]) -> Optional[Note]:
+        """Store a timestamp property as ISO text, or delete it for None"""
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        text = value.isoformat() if value else None
+        if note.get_property(key) != text:
+            if text:
+                note.set_property(key, text)
+            else:
+                note.delete_property(key)
+            self.save_note(note)
+        return note
+
+    def overdue_notes(self, now: Optional[datetime] = None) -> List[Note]:
+        """
+        Get the notes whose due date has passed, outside the trash
+
+        Args:
+            now: Current time in UTC (default: now)
+
+        Returns:
+            Notes, most overdue first
+        """
+        now = now or utc_now()
+        notes = [note for note in self.get_all_notes()
+                 if not note.is_trashed and note.due_at is not None and note.due_at < now]
+        return sorted(notes, key=lambda note: note.due_at)
+
+    def upcoming_notes(self, within: timedelta, now: Optional[datetime] = None) -> List[Note]:
+        """
+        Get the notes due soon, outside the trash
+
+        Args:
+            within: How far ahead to look
+            now: Current time in UTC (default: now)
+
+        Returns:
+            Notes due between now and now + within, soonest first
+        """
+        now = now or utc_now()
+        notes = [note for note in self.get_all_notes()
+                 if not note.is_trashed and note.due_at is not None and now <= note.due_at <= now + within]
+        return sorted(notes, key=lambda note: note.due_at)
+
+    def pop_due_reminders(self, now: Optional[datetime] = None) -> List[Note]:
+        """
+        Get the notes whose reminder time has come, removing their reminders
+
+        Reminders of trashed notes are removed without being returned. The
+        change isn't recorded in the journal, so undo doesn't bring a shown
+        reminder back.
+
+        Args:
+            now: Current time in UTC (default: now)
+
+        Returns:
+            Notes to remind about, earliest reminder first
+        """
+        now = now or utc_now()
+        due = sorted((note for note in self.get_all_notes() if note.remind_at is not None and note.remind_at <= now),
+                     key=lambda note: note.remind_at)
+        for note in due:
+            note.delete_property("remind_at")
+            self.save_note(note)
+        return [note for note in due if not note.is_trashed]
+
+    def tasks(self, include_done: bool = False) -> List[Task]:
+        """
+        Get the checkbox tasks of every note outside the trash and the archive
+
+        Args:
+            include_done: Include checked tasks
+
+        Returns:
+            Tasks grouped by note, most recently updated note first
+        """
+        notes = [note for note in self.get_all_notes() if not note.is_trashed and not note.is_archived]
+        return collect_tasks(notes, include_done)
+
+    @journaled("toggle_task")
+    def toggle_task(self, note_id: str, line: int) -> Optional[Note]:
+        """
+        Check or uncheck a task in a note
+
+        Args:
+            note_id: ID of the note
+            line: Index of the task's line in the note's content
+
+        Returns:
+            The updated note, or None if the note doesn't exist or the line isn't a task
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        content = toggle_task_line(note.content, line)
+        if content is None:
+            return None
+        note.content = content
+        self.save_note(note)
+        return note
+
+    def reorder_notes(self, note_ids: List[str]):
+        """
+        Store a manual order for notes, used by the "manual" sort order
+
+        Each note's "position" property is set from order_positions(), so
+        moving one note only changes its own. The notes that changed are
+        stored together with import_notes(): their updated times stay, and
+        the order makes no revision.
+
+        Args:
+            note_ids: IDs of the notes in their new order
+        """
+        with self.journal_operation("reorder", note_ids):
+            notes = [note for note in map(self.get_note, note_ids) if note is not None]
+            changed = []
+            for note, position in zip(notes, order_positions([note.get_property("position") for note in notes])):
+                if note.get_property("position") != position:
+                    note.set_property("position", position)
+                    changed.append(note)
+            if changed:
+                self.import_notes(changed)
+
+    def list_tags(self) -> List[str]:
+        """
+        Get every tag in use on notes outside the trash
+
+        Returns:
+            Sorted list of distinct tag names
+        """
+        tags = set()
+        for note in self.get_all_notes():
+            if not note.is_trashed:
+                tags.update(note.tags)
+        return sorted(tags)
+
+    def stats(self) -> NoteStats:
+        """
+        Get statistics about all notes
+
+        Returns:
+            Note, word and character totals, notes per tag, and notes created each month
+        """
+        return note_stats(self.get_all_notes())
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """
+        Get all notes with a tag
+
+        Args:
+            tag: Tag name
+
+        Returns:
+            Matching notes, sorted by most recently updated first
+        """
+        return [note for note in self.get_all_notes() if note.has_tag(tag)]
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """
+        Get the notes that link to a note with a [[title]] link
+
+        This default scans all notes; backends with a link index override it.
+
+        Args:
+            note_id: ID of the linked note
+
+        Returns:
+            Linking notes (including trashed ones), most recently updated first
+        """
+        note = self.get_note(note_id)
+        if note is None or not note.title:
+            return []
+        target = normalize_link(note.title)
+        notes = [other for other in self.get_all_notes()
+                 if other.id != note_id and target in link_targets(other.content)]
+        notes.sort(key=lambda other: other.updated_at, reverse=True)
+        return notes
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """
+        Search note contents
+
+        Every word in the query must appear in a note (case-insensitive,
+        matching word prefixes). This default scans all notes and ranks by
+        number of matches; backends with a search index override it.
+
+        Args:
+            query: Search query
+
+        Returns:
+            Matching notes, best match first
+        """
+        terms = tokenize_query(query)
+        if not terms:
+            return []
+
+        context = current_context()
+        results = []
+        for note in self.get_all_notes():
+            context.check()
+            matches = count_matches(note.content, terms)
+            if matches:
+                results.append(SearchResult(
+                    note=note,
+                    snippet=build_snippet(note.content, terms),
+                    rank=-float(matches)
+                ))
+        results.sort(key=lambda result: result.rank)
+        return results
+
+    def fuzzy_search_notes(self, query: str) -> List[SearchResult]:
+        """
+        Fuzzy search the whole title and content of every note, see search.fuzzy_search()
+
+        The sidebar only holds the start of each note, so it asks the
+        storage instead of matching what it lists. Wrappers pass the call on
+        through get_all_notes(), so secure notes are only matched while the
+        vault is unlocked.
+
+        Args:
+            query: Query as typed by the user
+
+        Returns:
+            Matching notes, best match first
+        """
+        return fuzzy_search(self.get_all_notes(), query)
+
+    def replace_all(
+        self,
+        pattern: str,
+        replacement: str,
+        regex: bool = False,
+        dry_run: bool = False,
+        note_ids: Optional[Collection[str]] = None
+    ) -> List[Replacement]:
+        """
+        Replace text in every note outside the trash, as one operation
+
+        Args:
+            pattern: Text to find, or a regular expression if regex is set
+            replacement: Replacement text (with regex, \1 refers to a group)
+            regex: Whether the pattern is a regular expression
+            dry_run: Only work out the changes, for a preview, without saving them
+            note_ids: Only change these notes (default: every note)
+
+        Returns:
+            The change to each affected note, most recently updated first
+
+        Raises:
+            re.error: If the regular expression or a group in the replacement is invalid
+            OperationCancelled: If the context in use ends before the changes are worked out
+        """
+        compiled = compile_pattern(pattern, regex)
+        context = current_context()
+        replacements = []
+        for note in self.get_all_notes():
+            # Only the scan can be cancelled; once saving starts, every change is saved
+            context.check()
+            if note.is_trashed or (note_ids is not None and note.id not in note_ids):
+                continue
+            content, count = replace_in_content(note.content, compiled, replacement, regex)
+            if count and content != note.content:
+                replacements.append(Replacement(note.id, note.title, count, note.content, content))
+
+        if not dry_run:
+            with self.journal_operation("replace_all", [change.note_id for change in replacements]):
+                for change in replacements:
+                    note = self.get_note(change.note_id)
+                    note.content = change.new_content
+                    self.save_note(note)
+        return replacements
+
+    def split_note(self, note_id: str) -> List[Note]:
+        """
+        Split a note into a note per top-level section, as one operation
+
+        The note becomes an index of the new notes: the text before the first
+        section (or a heading naming it), then a [[link]] to each new note in
+        order. The new notes get the note's notebook and tags, and end with a
+        link back to the index.
+
+        Args:
+            note_id: ID of the note to split
+
+        Returns:
+            The new notes in order; none if the note has fewer than two sections
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return []
+        preamble, sections = split_sections(note.content)
+        if len(sections) < 2:
+            return []
+
+        if preamble:
+            title = Note(note.id, preamble).title
+        else:
+            title = t("split.index_title", title=note.title)
+            preamble = f"# {title}"
+        back_link = t("split.back_link", link=f"[[{title}]]")
+        new_notes = []
+        for section in sections:
+            new_note = self.create_note(f"{section}\n\n{back_link}\n")
+            if note.get_property("notebook"):
+                new_note.set_property("notebook", note.get_property("notebook"))
+            if note.tags:
+                new_note.set_property("tags", note.tags)
+            new_notes.append(new_note)
+        links = "\n".join(f"- [[{new_note.title}]]" for new_note in new_notes)
+
+        with self.journal_operation("split", [note.id] + [new_note.id for new_note in new_notes]):
+            for new_note in new_notes:
+                self.save_note(new_note)
+            note.content = f"{preamble}\n\n{links}\n"
+            self.save_note(note)
+        return new_notes
+
+    def create_notebook(self, path: str) -> str:
+        """
+        Create a notebook
+
+        Notebooks are defined by the notes in them, so a notebook with no
+        notes only lasts for the current session; it is persisted once a note
+        is moved into it.
+
+        Args:
+            path: Slash-separated notebook path (parents are created implicitly)
+
+        Returns:
+            Normalized notebook path
+        """
+        path = Notebook.normalize_path(path)
+        if path:
+            self._empty_notebooks.add(path)
+        return path
+
+    def list_notebooks(self) -> List[str]:
+        """
+        Get every notebook path, including parents of nested notebooks
+
+        Returns:
+            Sorted list of notebook paths
+        """
+        paths = set()
+        for path in self._empty_notebooks:
+            paths.update(Notebook.ancestor_paths(path))
+        for note in self.get_all_notes():
+            if not note.is_trashed:
+                paths.update(Notebook.ancestor_paths(get_note_notebook(note)))
+        return sorted(paths)
+
+    @journaled("move")
+    def move_note(self, note_id: str, notebook_path: str) -> Optional[Note]:
+        """
+        Move a note into a notebook
+
+        Args:
+            note_id: ID of the note to move
+            notebook_path: Destination notebook path ("" for the root)
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        notebook_path = Notebook.normalize_path(notebook_path)
+        if notebook_path == get_note_notebook(note):
+            return note
+
+        if notebook_path:
+            note.set_property("notebook", notebook_path)
+        else:
+            note.delete_property("notebook")
+        self.save_note(note)
+        return note
+
+    def get_notes_in_notebook(self, notebook_path: str, recursive: bool = False) -> List[Note]:
+        """
+        Get the notes in a notebook
+
+        Args:
+            notebook_path: Notebook path ("" for the root)
+            recursive: Also include notes in nested notebooks
+
+        Returns:
+            Matching notes, sorted by most recently updated first
+        """
+        notebook_path = Notebook.normalize_path(notebook_path)
+        prefix = notebook_path + Notebook.SEPARATOR
+        result = []
+        for note in self.get_all_notes():
+            path = get_note_notebook(note)
+            if path == notebook_path or (recursive and (not notebook_path or path.startswith(prefix))):
+                result.append(note)
+        return result
+
+    @journaled("trash")
+    def trash_note(self, note_id: str) -> Optional[Note]:
+        """
+        Move a note to the trash
+
+        Trashed notes stay in storage, marked with a "deleted_at" property,
+        until the trash is purged.
+
+        Args:
+            note_id: ID of the note to trash
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        if not note.is_trashed:
+            note.set_property("deleted_at", utc_now().isoformat())
+            self.save_note(note)
+        return note
+
+    @journaled("restore")
+    def restore_note(self, note_id: str) -> Optional[Note]:
+        """
+        Take a note out of the trash
+
+        Args:
+            note_id: ID of the note to restore
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        if note.is_trashed:
+            note.delete_property("deleted_at")
+            self.save_note(note)
+        return note
+
+    @journaled("archive")
+    def archive_note(self, note_id: str) -> Optional[Note]:
+        """
+        Archive a note, taking it out of the main list without deleting it
+
+        Args:
+            note_id: ID of the note to archive
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        if not note.is_archived:
+            note.set_property("archived", True)
+            self.save_note(note)
+        return note
+
+    @journaled("unarchive")
+    def unarchive_note(self, note_id: str) -> Optional[Note]:
+        """
+        Move an archived note back to the main list
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        if note.is_archived:
+            note.delete_property("archived")
+            self.save_note(note)
+        return note
+
+    def list_trash(self) -> List[Note]:
+        """
+        Get the notes in the trash
+
+        Returns:
+            Trashed notes, sorted by most recently updated first
+        """
+        return [note for note in self.get_all_notes() if note.is_trashed]
+
+    def purge_trash(self) -> int:
+        """
+        Permanently delete every note in the trash, with their attached files
+
+        Returns:
+            Number of notes deleted
+        """
+        trashed = self.list_trash()
+        note_ids = [note.id for note in trashed]
+        with self.journal_operation("purge", note_ids):
+            self.delete_notes(note_ids)
+        for note_id in note_ids:
+            self.delete_attachments(note_id)
+        return len(trashed)
+
+    def add_attachment(self, note_id: str, source: str) -> Optional[Attachment]:
+        """
+        Attach a copy of a file to a note
+
+        The file keeps its name unless the note already has a file by that
+        name, in which case a number is added ("photo 2.jpg").
+
+        Args:
+            note_id: ID of the note
+            source: Path of the file to attach
+
+        Returns:
+            The new attachment, or None if the note doesn't exist
+
+        Raises:
+            OSError: If the file can't be read or copied
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        source_path = Path(source).expanduser()
+        attachments = note_attachments(note)
+        name = unique_name(source_path.name, [attachment.name for attachment in attachments])
+        target = self.attachment_path(note_id, name)
+        target.parent.mkdir(parents=True, exist_ok=True)
+        shutil.copyfile(source_path, target)
+
+        attachment = Attachment(name=name, size=target.stat().st_size, added_at=utc_now())
+        attachments.append(attachment)
+        note.set_property(ATTACHMENTS_PROPERTY, [entry.to_dict() for entry in attachments])
+        self.save_note(note)
+        return attachment
+
+    def list_attachments(self, note_id: str) -> List[Attachment]:
+        """
+        Get the files attached to a note
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            Attachments in the order they were added (empty if the note doesn't exist)
+        """
+        note = self.get_note(note_id)
+        return note_attachments(note) if note else []
+
+    def attachment_path(self, note_id: str, name: str) -> Path:
+        """
+        Get where an attached file is kept
+
+        Args:
+            note_id: ID of the note
+            name: Attachment name
+
+        Returns:
+            Path of the copy in the attachments directory
+
+        Raises:
+            ValueError: If the note ID isn't safe to name a directory after
+        """
+        return self.attachments_dir / check_note_id(note_id) / name
+
+    def remove_attachment(self, note_id: str, name: str) -> Optional[Note]:
+        """
+        Remove a file from a note and delete its copy
+
+        Args:
+            note_id: ID of the note
+            name: Attachment name
+
+        Returns:
+            The updated note, or None if the note or attachment doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+        attachments = note_attachments(note)
+        remaining = [attachment for attachment in attachments if attachment.name != name]
+        if len(remaining) == len(attachments):
+            return None
+
+        path = self.attachment_path(note_id, name)
+        if path.exists():
+            path.unlink()
+        if remaining:
+            note.set_property(ATTACHMENTS_PROPERTY, [entry.to_dict() for entry in remaining])
+        else:
+            note.delete_property(ATTACHMENTS_PROPERTY)
+        self.save_note(note)
+        return note
+
+    def delete_attachments(self, note_id: str):
+        """
+        Delete the copies of every file attached to a note, after the note is deleted
+
+        A note whose ID isn't safe to name a directory after (like those the
+        markdown backend once made from subfolder paths) can't have had any.
+
+        Args:
+            note_id: ID of the deleted note
+        """
+        try:
+            directory = self.attachments_dir / check_note_id(note_id)
+        except ValueError:
+            return
+        shutil.rmtree(directory, ignore_errors=True)
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """
+        Get the saved versions of a note
+
+        Backends that don't keep version history return an empty list.
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            Revisions, oldest first
+        """
+        return []
+
+    @journaled("revision")
+    def restore_revision(self, note_id: str, rev: int) -> Optional[Note]:
+        """
+        Roll a note's content back to an earlier revision
+
+        The restored content is saved as a new revision, so the rollback can
+        itself be undone. Tags and other properties are kept as they are.
+
+        Args:
+            note_id: ID of the note
+            rev: Revision number to restore
+
+        Returns:
+            The updated note, or None if the note or revision doesn't exist
+        """
+        note = self.get_note(note_id)
+        if note is None:
+            return None
+
+        for revision in self.list_revisions(note_id):
+            if revision.rev == rev:
+                note.content = revision.content
+                self.save_note(note)
+                return note
+        return None
+
+    @contextmanager
+    def journal_operation(self, action: str, note_ids: Iterable[str]) -> Iterator[None]:
+        """
+        Record the changes made to notes inside the block as one operation that can be undone
+
+        Operations inside another are part of the outer one. Nothing is
+        recorded without a journal, if the block raises, or if no note changed.
+        The note hooks are run for each note the operation changed. A bulk
+        operation first writes a backup of every note, if backups are set.
+
+        Args:
+            action: Kind of operation, naming its "journal.<action>" description
+            note_ids: IDs of the notes the operation may change
+        """
+        journal = self.journal
+        if self._operation_open:
+            yield
+            return
+        if self.backups is not None and action in BULK_ACTIONS:
+            self.backups.write(self.notes_at_rest(), self.attachments_dir, action)
+        if journal is None and self.hooks is None:
+            # Still marked open, so a bulk operation inside doesn't write another backup
+            self._operation_open = True
+            try:
+                with timed(log, "operation", action=action):
+                    yield
+            finally:
+                self._operation_open = False
+            return
+
+        before = {note_id: snapshot(self.get_note(note_id)) for note_id in note_ids}
+        self._operation_open = True
+        try:
+            with timed(log, "operation", action=action, notes=len(before)):
+                yield
+        finally:
+            self._operation_open = False
+
+        changes = [Change(note_id, note, snapshot(self.get_note(note_id))) for note_id, note in before.items()]
+        changes = [change for change in changes if not change.is_noop]
+        if changes and journal is not None:
+            first = changes[0].after or changes[0].before
+            description = t(f"journal.{action}", title=first.title, count=len(changes))
+            journal.record(Operation(description, changes))
+        if changes and self.hooks is not None:
+            self.hooks.notes_changed(changes)
+
+    def undo(self) -> Optional[Operation]:
+        """
+        Undo the most recent operation in the journal
+
+        Notes it changed or deleted are saved as they were before, and notes
+        it created are deleted.
+
+        Returns:
+            The undone operation, or None if there's nothing to undo
+        """
+        operation = self.journal.pop_undo() if self.journal else None
+        if operation:
+            self._write_journaled_states([change.before for change in operation.changes], operation)
+            if self.hooks is not None:
+                self.hooks.notes_changed(Change(change.note_id, change.after, change.before)
+                                         for change in operation.changes)
+        return operation
+
+    def redo(self) -> Optional[Operation]:
+        """
+        Redo the most recently undone operation
+
+        Returns:
+            The redone operation, or None if there's nothing to redo
+        """
+        operation = self.journal.pop_redo() if self.journal else None
+        if operation:
+            self._write_journaled_states([change.after for change in operation.changes], operation)
+            if self.hooks is not None:
+                self.hooks.notes_changed(operation.changes)
+        return operation
+
+    def _write_journaled_states(self, states: List[Optional[Note]], operation: Operation):
+        """
+        Put an operation's notes in the given states (None: deleted)
+
+        Attached files are copies that the journal doesn't keep, so
+        attachments whose files are gone are dropped from restored notes.
+        """
+        for change, state in zip(operation.changes, states):
+            note = snapshot(state)
+            if note is None:
+                if self.get_note(change.note_id) is not None:
+                    self.delete_note(change.note_id)
+                continue
+
+            attachments = note_attachments(note)
+            existing = [attachment for attachment in attachments
+                        if self.attachment_path(note.id, attachment.name).exists()]
+            if len(existing) != len(attachments):
+                if existing:
+                    note.set_property(ATTACHMENTS_PROPERTY, [entry.to_dict() for entry in existing])
+                else:
+                    note.delete_property(ATTACHMENTS_PROPERTY)
+            self.save_note(note)
+
+    def poll_changes(self) -> bool:
+        """
+        Check whether another program changed the stored notes since the last check
+
+        Changes this backend made itself don't count. Backends that can't
+        tell always answer False.
+
+        Returns:
+            True if the notes should be reloaded
+        """
+        return False
+
+    def flush(self):
+        """
+        Write any changes the backend is still holding back (nothing by default)
+
+        Backends that defer writes (coalescing saves, queuing them for a
+        background thread, or a daemon doing either) write them here, and
+        wrappers pass it on to the backend they wrap.
+        """
+        pass
+
+    def sync(self) -> int:
+        """
+        Exchange changes with a sync server, for backends that support it
+
+        Returns:
+            Number of local notes changed by the sync (0 if not supported)
+
+        Raises:
+            SyncError: If the server can't be reached
+        """
+        return 0
+
+    def pending_sync(self) -> int:
+        """
+        Count the local changes not pushed to the sync server yet
+
+        Returns:
+            Number of notes with changes waiting (0 if syncing isn't supported)
+        """
+        return 0
+
     @abstractmethod
     def close(self):
-        """Clean up any resources (database connections, file handles, etc.)"""
+        """
+        Clean up any resources (database connections, file handles, etc.)
+
+        Must write what flush() would first: it's the last call on every
+        exit, including Ctrl+C, SIGTERM and SIGHUP, so held-back changes
+        aren't lost.
+        """
         pass
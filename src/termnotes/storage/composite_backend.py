@@ -2,9 +2,20 @@
 Composite storage backend that combines multiple backends
 """
 
-from typing import List, Optional
+import sys
+import threading
+from typing import Callable, Dict, List, Optional
 from .base import StorageBackend
-from ..note import Note
+from .journal import snapshot
+from ..history import Revision
+from ..search import SearchResult
+from ..note import Note, NoteSummary
+from ..i18n import t
+from ..log import event, get_logger, timed
+
+log = get_logger("storage.composite")
+
+RETRY_DELAY = 10.0  # Seconds before trying failed writes again
 
 
 class CompositeBackend(StorageBackend):
@@ -13,35 +24,131 @@ class CompositeBackend(StorageBackend):
 
     Uses a fast in-memory cache (SQLite) backed by persistent storage (filesystem).
     - Reads: Try cache first, fall back to persistent storage
-    - Writes: Write to both backends
+    - Writes: Write to the cache, then to persistent storage (in the background
+      with a write delay)
     - On init: Load all persistent notes into cache
+
+    With a write delay, saves and deletions are queued and a worker thread
+    writes them to persistent storage once no change has come for that
+    long, so saving doesn't wait for the disk or the network. A later change
+    to a queued note replaces the earlier one. flush() writes the queue
+    right away, and close() does before closing. A failed write stays queued,
+    is tried again after RETRY_DELAY, and is reported through on_write_error.
+
+    Safe to share between threads: the cache locks itself, and every call to
+    the persistent backend, which needn't be thread-safe, holds persistent_lock.
     """
 
-    def __init__(self, cache: StorageBackend, persistent: StorageBackend):
+    def __init__(self, cache: StorageBackend, persistent: StorageBackend, write_delay: float = 0.0):
         """
         Initialize composite backend
 
         Args:
             cache: Fast in-memory backend (e.g., SQLiteBackend with :memory:)
             persistent: Persistent storage backend (e.g., FilesystemBackend)
+            write_delay: Seconds to wait for more changes before writing them to
+                persistent storage (0 = write before returning)
         """
+        super().__init__()
         self.cache = cache
         self.persistent = persistent
+        self.write_delay = write_delay
+        self.persistent_lock = threading.RLock()
+        self.on_write_error: Callable[[str], None] = self.print_write_error  # Told when a queued write fails
+
+        # Changes waiting to be written (note ID -> note to save, or None to delete it)
+        self._pending: Dict[str, Optional[Note]] = {}
+        self._pending_lock = threading.Lock()
+        self._write_timer: Optional[threading.Timer] = None
 
         # Populate cache from persistent storage on startup
         self._populate_cache()
 
     def _populate_cache(self):
         """Load all notes from persistent storage into cache"""
-        persistent_notes = self.persistent.get_all_notes()
+        with self.persistent_lock, timed(log, "load", backend=type(self.persistent).__name__) as fields:
+            persistent_notes = self.persistent.get_all_notes()
+            fields["notes"] = len(persistent_notes)
 
         for note in persistent_notes:
             self.cache.save_note(note)
 
+    def _queue_write(self, note_id: str, note: Optional[Note]):
+        """
+        Queue a change for persistent storage, restarting the write delay
+
+        Args:
+            note_id: ID of the note
+            note: Note to save, or None to delete it
+        """
+        with self._pending_lock:
+            # A copy, so later edits to the caller's note don't leak into the write
+            self._pending[note_id] = snapshot(note)
+            self._schedule_flush(self.write_delay)
+
+    def _schedule_flush(self, delay: float):
+        """Start (or restart) the timer writing the queue; the pending lock must be held"""
+        if self._write_timer is not None:
+            self._write_timer.cancel()
+        self._write_timer = threading.Timer(delay, self.flush)
+        self._write_timer.daemon = True
+        self._write_timer.start()
+
+    def _is_pending_delete(self, note_id: str) -> bool:
+        """Whether a deletion of the note is waiting to be written"""
+        with self._pending_lock:
+            return note_id in self._pending and self._pending[note_id] is None
+
+    def flush(self):
+        """Write the queued changes to persistent storage now"""
+        with self.persistent_lock:
+            with self._pending_lock:
+                pending = self._pending
+                self._pending = {}
+                if self._write_timer is not None:
+                    self._write_timer.cancel()
+                    self._write_timer = None
+
+            for note_id, note in pending.items():
+                try:
+                    with timed(log, "write", note_id=note_id, action="delete" if note is None else "save"):
+                        if note is None:
+                            self.persistent.delete_note(note_id)
+                        else:
+                            self.persistent.save_note(note)
+                except Exception as e:
+                    # Any backend error (disk full, server down): keep the change
+                    # for the next flush, unless a newer one was queued meanwhile
+                    with self._pending_lock:
+                        self._pending.setdefault(note_id, note)
+                        if self._write_timer is None:
+                            self._schedule_flush(RETRY_DELAY)
+                    self.on_write_error(t("storage.write_failed", error=e))
+            self.persistent.flush()
+
+    @property
+    def has_pending_writes(self) -> bool:
+        """Whether changes are waiting to be written to persistent storage"""
+        with self._pending_lock:
+            return bool(self._pending)
+
+    @staticmethod
+    def print_write_error(message: str):
+        """Report a failed write on stderr, outside the interface"""
+        print(message, file=sys.stderr)
+
     def get_all_notes(self) -> List[Note]:
         """Get all notes from cache (already loaded from persistent storage)"""
         return self.cache.get_all_notes()
 
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """Get a page of notes to list from cache"""
+        return self.cache.list_note_summaries(offset, limit)
+
+    def count_notes(self) -> int:
+        """Count the notes in cache"""
+        return self.cache.count_notes()
+
     def get_note(self, note_id: str) -> Optional[Note]:
         """
         Get a specific note by ID
@@ -50,11 +157,12 @@ class CompositeBackend(StorageBackend):
         """
         # Try cache first (fast)
         note = self.cache.get_note(note_id)
-        if note:
+        if note or self._is_pending_delete(note_id):
             return note
 
         # Cache miss - try persistent storage
-        note = self.persistent.get_note(note_id)
+        with self.persistent_lock:
+            note = self.persistent.get_note(note_id)
         if note:
             # Populate cache for next time
             self.cache.save_note(note)
@@ -65,20 +173,143 @@ class CompositeBackend(StorageBackend):
         """
         Save note to both cache and persistent storage
 
-        Write-through cache: updates both immediately
+        Write-through cache: updates both immediately, or persistent storage
+        after the write delay
         """
         # Save to cache (fast)
         self.cache.save_note(note)
 
         # Save to persistent storage (slower but durable)
-        self.persistent.save_note(note)
+        if self.write_delay > 0:
+            self._queue_write(note.id, note)
+            return
+        with self.persistent_lock:
+            self.persistent.save_note(note)
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Store notes in both cache and persistent storage, without delay"""
+        self.cache.import_notes(notes)
+        with self.persistent_lock:
+            self.flush()  # So an older queued save can't overwrite an imported note
+            return self.persistent.import_notes(notes)
 
     def delete_note(self, note_id: str):
         """Delete note from both cache and persistent storage"""
         self.cache.delete_note(note_id)
-        self.persistent.delete_note(note_id)
+        if self.write_delay > 0:
+            self._queue_write(note_id, None)
+            return
+        with self.persistent_lock:
+            self.persistent.delete_note(note_id)
+
+    def _delete_batch(self, note_ids: List[str]):
+        """Delete notes from both cache and persistent storage, a batch at a time"""
+        self.cache._delete_batch(note_ids)
+        if self.write_delay > 0:
+            for note_id in note_ids:
+                self._queue_write(note_id, None)
+            return
+        with self.persistent_lock:
+            self.persistent._delete_batch(note_ids)
+
+    def list_tags(self) -> List[str]:
+        """Get every tag in use from cache"""
+        return self.cache.list_tags()
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get all notes with a tag from cache"""
+        return self.cache.get_notes_by_tag(tag)
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes linking to a note from cache"""
+        return self.cache.get_backlinks(note_id)
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search notes using the cache's index"""
+        return self.cache.search_notes(query)
+
+    @property
+    def supports_revisions(self) -> bool:
+        """Whether history is available from either backend"""
+        return self._history_backend().supports_revisions
+
+    def _history_backend(self) -> StorageBackend:
+        """
+        Get the backend to read history from
+
+        The persistent backend's history survives restarts; if it doesn't
+        record revisions, the cache's history of this session is used.
+        """
+        return self.persistent if self.persistent.supports_revisions else self.cache
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get the saved versions of a note, including queued saves"""
+        with self.persistent_lock:
+            self.flush()
+            return self._history_backend().list_revisions(note_id)
+
+    @property
+    def supports_sync(self) -> bool:
+        """Whether the persistent backend syncs with a server"""
+        return self.persistent.supports_sync
+
+    def sync(self) -> int:
+        """Write queued changes and sync the persistent backend, then refresh the cache with the changes"""
+        with self.persistent_lock, timed(log, "sync") as fields:
+            self.flush()
+            changed = self.persistent.sync()
+            fields["changed"] = changed
+            if changed:
+                self._refresh_cache()
+        return changed
+
+    def pending_sync(self) -> int:
+        """Count the changes waiting to be pushed: writes still queued, and the persistent backend's"""
+        if not self.persistent.supports_sync:
+            return 0
+        with self._pending_lock:
+            queued = len(self._pending)
+        # Without persistent_lock: it only reads the size of the sync queue, and
+        # shouldn't wait for a write in progress
+        return queued + self.persistent.pending_sync()
+
+    def poll_changes(self) -> bool:
+        """Check the persistent backend for changes by other programs, refreshing the cache if so"""
+        # Not while changes are queued or being written: the cache is ahead of
+        # persistent storage until they are, and the next check comes soon
+        if not self.persistent_lock.acquire(blocking=False):
+            return False
+        try:
+            if self.has_pending_writes or not self.persistent.poll_changes():
+                return False
+            event(log, "changed_externally")
+            self._refresh_cache()
+            return True
+        finally:
+            self.persistent_lock.release()
+
+    def _refresh_cache(self):
+        """Make the cache match the persistent backend after it changed underneath"""
+        with self.persistent_lock:
+            persistent_notes = self.persistent.get_all_notes()
+        with self._pending_lock:
+            pending = set(self._pending)  # The cache is ahead of persistent storage for these
+        persistent_ids = {note.id for note in persistent_notes}
+        for note in self.cache.get_all_notes():
+            if note.id not in persistent_ids and note.id not in pending:
+                self.cache.delete_note(note.id)
+        for note in persistent_notes:
+            if note.id not in pending:
+                self.cache.save_note(note)
 
     def close(self):
-        """Close both backends"""
+        """Write queued changes, then close both backends"""
+        self.flush()
+        with self._pending_lock:
+            # Changes that still failed are lost; don't retry on a closed backend
+            if self._write_timer is not None:
+                self._write_timer.cancel()
+                self._write_timer = None
         self.cache.close()
-        self.persistent.close()
+        with self.persistent_lock:
+            self.persistent.close()
@@ -0,0 +1,130 @@
+"""
+Deadlines and cancellation for storage operations
+
+A Context bounds the storage calls made while it is in use:
+
+    context = Context(timeout=5)
+    with use_context(context):
+        notes = storage.search_notes(query)
+
+Every backend call in the block gives up once the deadline passes or
+another thread calls context.cancel(), raising OperationCancelled. SQLite
+queries are interrupted mid-statement, PostgreSQL statements get a
+statement_timeout, and network backends shorten their request timeouts
+to what is left. Outside use_context() calls run unbounded, as before.
+
+The context is per thread, so threads serving different requests (the
+sync server's) each have their own.
+"""
+
+import threading
+import time
+from contextlib import contextmanager
+from typing import Iterator, Optional
+from ..i18n import t
+
+
+class OperationCancelled(RuntimeError):
+    """A storage operation was cancelled or ran past its deadline"""
+
+
+class Context:
+    """A deadline and a cancel switch for the storage calls made under it"""
+
+    def __init__(self, timeout: Optional[float] = None):
+        """
+        Initialize
+
+        Args:
+            timeout: Seconds from now until the deadline (None for no deadline)
+        """
+        self.deadline = time.monotonic() + timeout if timeout is not None else None
+        self._cancelled = threading.Event()
+
+    def cancel(self):
+        """Stop the operations using this context (safe to call from any thread)"""
+        self._cancelled.set()
+
+    def remaining(self) -> Optional[float]:
+        """Get the seconds left until the deadline (None for no deadline)"""
+        if self.deadline is None:
+            return None
+        return max(0.0, self.deadline - time.monotonic())
+
+    def timeout(self, default: float) -> float:
+        """
+        Get how long a blocking call may wait
+
+        Args:
+            default: The caller's own timeout
+
+        Returns:
+            The default, or the time left if the deadline is sooner
+        """
+        remaining = self.remaining()
+        return default if remaining is None else min(default, remaining)
+
+    def error(self) -> Optional[OperationCancelled]:
+        """Get the error for a cancelled or expired context (None while it is live)"""
+        if self._cancelled.is_set():
+            return OperationCancelled(t("storage.cancelled"))
+        if self.deadline is not None and time.monotonic() >= self.deadline:
+            return OperationCancelled(t("storage.deadline_exceeded"))
+        return None
+
+    @property
+    def done(self) -> bool:
+        """Whether operations should stop"""
+        return self.error() is not None
+
+    def check(self):
+        """
+        Raise if operations should stop
+
+        Raises:
+            OperationCancelled: If the context was cancelled or its deadline passed
+        """
+        error = self.error()
+        if error is not None:
+            raise error
+
+
+# Context of calls made outside use_context(): never done
+BACKGROUND = Context()
+
+_local = threading.local()
+
+
+def current_context() -> Context:
+    """Get the context in use on this thread"""
+    return getattr(_local, "context", BACKGROUND)
+
+
+@contextmanager
+def use_context(context: Context) -> Iterator[Context]:
+    """
+    Bound the storage calls made in a block by a context
+
+    An error raised because the context ended (an interrupted query, a
+    timed-out request) is turned into OperationCancelled, so callers have
+    one exception to handle.
+
+    Args:
+        context: Context to use
+
+    Yields:
+        The context
+    """
+    previous = current_context()
+    _local.context = context
+    try:
+        yield context
+    except OperationCancelled:
+        raise
+    except Exception as e:
+        error = context.error()
+        if error is not None:
+            raise error from e
+        raise
+    finally:
+        _local.context = previous
@@ -0,0 +1,237 @@
+"""
+Daemon storage backend: notes held by a "termnotes daemon" process
+
+The daemon (see daemon.py) owns the configured storage and its lock; any
+number of editors and commands use it through this backend over a unix
+socket, so they can work on the same notes at once without overwriting one
+another's files.
+
+Requests and responses are JSON objects, one per line:
+
+    -> {"id": <n>, "method": "get_note", "params": {"note_id": "..."}}
+    <- {"id": <n>, "result": ...}
+    <- {"id": <n>, "error": {"type": "conflict" | "read_only" | "sync" | "error", "message": "...",
+                             "stored": <the stored note, for a conflict>}}
+
+and the daemon sends {"event": "changed"} to every other client after a
+change, which poll_changes() reports so editors reload right away.
+"""
+
+import json
+import socket
+import threading
+import time
+from datetime import datetime
+from typing import Any, Callable, Dict, List, Optional
+from .base import NoteConflict, StorageBackend
+from .context import current_context
+from .readonly_backend import ReadOnlyStorage
+from ..history import Revision
+from ..note import Note
+from ..search import SearchResult
+from ..sync.protocol import SyncError, note_from_dict, note_to_dict
+from ..i18n import t
+from ..log import get_logger, timed
+
+log = get_logger("storage.daemon")
+
+
+class DaemonError(OSError):
+    """The daemon couldn't be reached or failed a request"""
+
+
+class DaemonBackend(StorageBackend):
+    """
+    Storage backend that forwards every call to a termnotes daemon
+
+    Safe to share between threads: requests may be sent from any thread, and
+    a reader thread matches the responses to them and notes the daemon's
+    change events.
+    """
+
+    def __init__(self, path: str, timeout: float = 30):
+        """
+        Connect to the daemon
+
+        Args:
+            path: The daemon's socket
+            timeout: Seconds to wait for a response
+
+        Raises:
+            OSError: If no daemon is listening on the socket
+        """
+        super().__init__()
+        self.path = path
+        self.timeout = timeout
+        self._socket = socket.socket(socket.AF_UNIX, socket.SOCK_STREAM)
+        try:
+            self._socket.connect(path)
+        except OSError:
+            self._socket.close()
+            raise
+        self._send_lock = threading.Lock()
+        self._responses = threading.Condition()
+        self._pending: Dict[int, dict] = {}  # Responses not yet picked up, by request ID
+        self._next_id = 0
+        self._connected = True
+        self._changed = False
+        # Called from the reader thread when another client changes the notes
+        self.on_change: Optional[Callable[[], None]] = None
+        threading.Thread(target=self._read, name="termnotes-daemon-client", daemon=True).start()
+
+        try:
+            hello = self._call("hello")
+        except OSError:
+            self._socket.close()
+            raise
+        self.supports_revisions = hello.get("supports_revisions", False)
+        self.supports_sync = hello.get("supports_sync", False)
+        self.first_run = hello.get("first_run", False)
+
+    def _read(self):
+        """Receive responses and events until the connection closes"""
+        try:
+            with self._socket.makefile("r", encoding="utf-8") as stream:
+                for line in stream:
+                    try:
+                        message = json.loads(line)
+                    except ValueError:
+                        continue
+                    if "event" in message:
+                        with self._responses:
+                            self._changed = True
+                        if self.on_change:
+                            self.on_change()
+                        continue
+                    with self._responses:
+                        self._pending[message.get("id")] = message
+                        self._responses.notify_all()
+        except (OSError, ValueError):
+            pass  # Closed
+        with self._responses:
+            self._connected = False
+            self._responses.notify_all()
+
+    def _call(self, method: str, **params) -> Any:
+        """
+        Send a request and wait for its response
+
+        Args:
+            method: Storage method the daemon should call
+            **params: Its arguments
+
+        Returns:
+            The decoded result
+
+        Raises:
+            NoteConflict: If the daemon refused an update_note
+            ReadOnlyStorage: If the daemon's storage is read-only
+            SyncError: If the daemon's sync failed
+            DaemonError: If the daemon is gone, too slow or failed
+        """
+        with self._responses:
+            if not self._connected:
+                raise DaemonError(t("daemon.disconnected", path=self.path))
+            self._next_id += 1
+            request_id = self._next_id
+        line = json.dumps({"id": request_id, "method": method, "params": params}) + "\n"
+        deadline = time.monotonic() + current_context().timeout(self.timeout)
+        with timed(log, "request", method=method):
+            try:
+                with self._send_lock:
+                    self._socket.sendall(line.encode("utf-8"))
+            except OSError:
+                raise DaemonError(t("daemon.disconnected", path=self.path))
+            with self._responses:
+                while request_id not in self._pending:
+                    remaining = deadline - time.monotonic()
+                    if not self._connected:
+                        raise DaemonError(t("daemon.disconnected", path=self.path))
+                    if remaining <= 0:
+                        raise DaemonError(t("daemon.timeout", method=method))
+                    self._responses.wait(remaining)
+                response = self._pending.pop(request_id)
+
+        error = response.get("error")
+        if error is None:
+            return response.get("result")
+        if error.get("type") == "conflict":
+            raise NoteConflict(note_from_dict(error["stored"]))
+        if error.get("type") == "read_only":
+            raise ReadOnlyStorage()
+        if error.get("type") == "sync":
+            raise SyncError(error.get("message", "?"))
+        raise DaemonError(error.get("message", "?"))
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes from the daemon"""
+        return [note_from_dict(data) for data in self._call("get_all_notes")]
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a note from the daemon"""
+        data = self._call("get_note", note_id=note_id)
+        return note_from_dict(data) if data is not None else None
+
+    def save_note(self, note: Note):
+        """Have the daemon save a note"""
+        self._call("save_note", note=note_to_dict(note))
+
+    def update_note(self, note: Note, expected_version: Optional[str]):
+        """Have the daemon save a note unless it changed since expected_version, checked there atomically"""
+        self._call("update_note", note=note_to_dict(note), expected_version=expected_version)
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Have the daemon store notes with their own timestamps"""
+        return self._call("import_notes", notes=[note_to_dict(note) for note in notes])
+
+    def delete_note(self, note_id: str):
+        """Have the daemon delete a note"""
+        self._call("delete_note", note_id=note_id)
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search with the daemon's storage, and its index if it has one"""
+        return [SearchResult(note=note_from_dict(result["note"]), snippet=result["snippet"], rank=result["rank"])
+                for result in self._call("search_notes", query=query)]
+
+    def fuzzy_search_notes(self, query: str) -> List[SearchResult]:
+        """Fuzzy search with the daemon's storage, so the notes aren't sent over first"""
+        return [SearchResult(note=note_from_dict(result["note"]), snippet=result["snippet"], rank=result["rank"])
+                for result in self._call("fuzzy_search_notes", query=query)]
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get a note's revisions from the daemon's storage"""
+        return [Revision(note_id, revision["rev"], revision["content"], datetime.fromisoformat(revision["saved_at"]))
+                for revision in self._call("list_revisions", note_id=note_id)]
+
+    def poll_changes(self) -> bool:
+        """Check whether another client, or another program, changed the notes since the last check"""
+        with self._responses:
+            changed = self._changed
+            self._changed = False
+        return changed
+
+    def flush(self):
+        """Have the daemon write any changes it's holding back"""
+        self._call("flush")
+
+    def sync(self) -> int:
+        """Have the daemon sync its storage"""
+        return self._call("sync")
+
+    def pending_sync(self) -> int:
+        """Count the daemon's storage's changes waiting to be pushed"""
+        return self._call("pending_sync")
+
+    def close(self):
+        """Have the daemon write held-back changes, then disconnect (the daemon keeps running)"""
+        try:
+            self.flush()
+        except DaemonError:
+            pass
+        with self._responses:
+            self._connected = False
+        try:
+            self._socket.shutdown(socket.SHUT_RDWR)
+        except OSError:
+            pass
+        self._socket.close()
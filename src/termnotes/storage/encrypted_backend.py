@@ -5,10 +5,12 @@ Encrypted storage backend that wraps another backend
 import os
 import base64
 import hashlib
+import hmac
 from typing import List, Optional, Union
 from chacha20poly1305 import ChaCha20Poly1305
 from .base import StorageBackend
 from ..note import Note
+from ..i18n import t
 
 
 class EncryptedBackend(StorageBackend):
@@ -43,6 +45,7 @@ class EncryptedBackend(StorageBackend):
         Raises:
             ValueError: If password is invalid
         """
+        super().__init__()
         self.backend = backend
 
         # Derive salt from password using a different KDF
@@ -51,6 +54,8 @@ class EncryptedBackend(StorageBackend):
 
         # Derive encryption key from password using the derived salt
         encryption_key = self._derive_key(password, self.salt)
+        # Kept to check a passphrase typed later (see matches_passphrase), not the key itself
+        self._key_digest = hashlib.sha256(encryption_key).digest()
 
         try:
             self.cipher = ChaCha20Poly1305(encryption_key)
@@ -173,6 +178,47 @@ class EncryptedBackend(StorageBackend):
 
         return plaintext_bytes.decode('utf-8')
 
+    @staticmethod
+    def has_encrypted_notes(backend: StorageBackend) -> bool:
+        """
+        Check if a backend holds any encrypted notes (no passphrase needed)
+
+        Args:
+            backend: Backend that an EncryptedBackend would wrap
+
+        Returns:
+            True if at least one note has encrypted content
+        """
+        return any(
+            note.get_property("encrypted") == True and note.content
+            for note in backend.get_all_notes()
+        )
+
+    def verify_passphrase(self) -> bool:
+        """
+        Check the passphrase against an existing encrypted note
+
+        Returns:
+            True if an encrypted note decrypts (or there are none), False otherwise
+        """
+        for note in self.backend.get_all_notes():
+            if note.get_property("encrypted") == True and note.content:
+                try:
+                    self._decrypt_content(note.content)
+                    return True
+                except Exception:
+                    return False
+        return True
+
+    def matches_passphrase(self, password: Union[str, bytes]) -> bool:
+        """
+        Check whether a passphrase is the one the backend was opened with
+
+        Used by the app lock; takes as long as opening the backend.
+        """
+        candidate = hashlib.sha256(self._derive_key(password, self._derive_salt(password))).digest()
+        return hmac.compare_digest(candidate, self._key_digest)
+
     def get_all_notes(self) -> List[Note]:
         """
         Get all notes with decrypted content
@@ -203,11 +249,11 @@ class EncryptedBackend(StorageBackend):
             except Exception as e:
                 # Log error but continue with other notes
                 # Could add logging here if needed
-                print(f"Warning: Failed to decrypt note {note.id}: {e}")
+                print(t("storage.decrypt_failed", note_id=note.id, error=e))
                 # Add note with error marker
                 error_note = Note(
                     note_id=note.id,
-                    content=f"[DECRYPTION FAILED: {str(e)}]",
+                    content=t("storage.decrypt_failed_content", error=e),
                     created_at=note.created_at,
                     updated_at=note.updated_at,
                     properties=note.properties
@@ -248,21 +294,24 @@ class EncryptedBackend(StorageBackend):
             )
         except Exception as e:
             # Return note with error marker if decryption fails
-            print(f"Warning: Failed to decrypt note {note_id}: {e}")
+            print(t("storage.decrypt_failed", note_id=note_id, error=e))
             return Note(
                 note_id=encrypted_note.id,
-                content=f"[DECRYPTION FAILED: {str(e)}]",
+                content=t("storage.decrypt_failed_content", error=e),
                 created_at=encrypted_note.created_at,
                 updated_at=encrypted_note.updated_at,
                 properties=encrypted_note.properties
             )
 
-    def save_note(self, note: Note):
+    def _encrypt_note(self, note: Note) -> Note:
         """
-        Save note with encrypted content
+        Get the encrypted form of a note to store in the wrapped backend
 
         Args:
             note: Note object with plain text content
+
+        Returns:
+            Copy of the note with encrypted content, marked as encrypted
         """
         encrypted_content = self._encrypt_content(note.content)
 
@@ -272,7 +321,7 @@ class EncryptedBackend(StorageBackend):
         encrypted_properties["encrypted"] = True
         encrypted_properties["encryption_method"] = "chacha20poly1305-pbkdf2"
 
-        encrypted_note = Note(
+        return Note(
             note_id=note.id,
             content=encrypted_content,
             created_at=note.created_at,
@@ -280,7 +329,18 @@ class EncryptedBackend(StorageBackend):
             properties=encrypted_properties
         )
 
-        self.backend.save_note(encrypted_note)
+    def save_note(self, note: Note):
+        """
+        Save note with encrypted content
+
+        Args:
+            note: Note object with plain text content
+        """
+        self.backend.save_note(self._encrypt_note(note))
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Encrypt notes and store them in the wrapped backend together"""
+        return self.backend.import_notes([self._encrypt_note(note) for note in notes])
 
     def delete_note(self, note_id: str):
         """
@@ -291,6 +351,31 @@ class EncryptedBackend(StorageBackend):
         """
         self.backend.delete_note(note_id)
 
+    def _delete_batch(self, note_ids: List[str]):
+        """Delete notes from the wrapped backend together"""
+        self.backend._delete_batch(note_ids)
+
+    @property
+    def supports_sync(self) -> bool:
+        """Whether the wrapped backend syncs with a server"""
+        return self.backend.supports_sync
+
+    def poll_changes(self) -> bool:
+        """Check the wrapped backend for changes by other programs"""
+        return self.backend.poll_changes()
+
+    def flush(self):
+        """Write the wrapped backend's held back changes"""
+        self.backend.flush()
+
+    def sync(self) -> int:
+        """Sync the wrapped backend; notes travel encrypted"""
+        return self.backend.sync()
+
+    def pending_sync(self) -> int:
+        """Count the wrapped backend's changes waiting to be pushed"""
+        return self.backend.pending_sync()
+
     def close(self):
         """Clean up underlying backend resources"""
         self.backend.close()
@@ -345,13 +430,13 @@ class EncryptedBackend(StorageBackend):
                 self.backend.save_note(encrypted_note)
 
             if unencrypted_count > 0:
-                print(f"✓ Migrated {unencrypted_count} unencrypted note(s) to encrypted storage")
-                print("Press Enter to continue...")
+                print(t("storage.migrated_notes", count=unencrypted_count))
+                print(t("prompt.press_enter"))
                 input()
 
         except Exception as e:
-            print(f"Warning: Failed to migrate notes: {e}")
-            print("Press Enter to continue...")
+            print(t("storage.migrate_failed", error=e))
+            print(t("prompt.press_enter"))
             input()
 
     @staticmethod
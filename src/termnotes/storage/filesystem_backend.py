@@ -2,49 +2,274 @@
 Filesystem-based note storage backend using JSON files
 """
 
+import hashlib
 import json
 import os
+import re
+import shutil
+import threading
 from pathlib import Path
-from typing import List, Optional
+from typing import Dict, List, Optional, Set
 from datetime import datetime
-from .base import StorageBackend
+from .base import StorageBackend, check_note_id, synchronized
+from .watch import FileSnapshot
 from ..utils import utc_now
 from ..note import Note
+from ..i18n import t
+
+# Format of the note files: version 1 was the bare note object, version 2
+# wraps it as {"version": 2, "checksum": "...", "note": {...}}, the checksum
+# (see _checksum(); optional when reading) catching a note that was damaged
+# but still parses. Files in an older format are
+# read as they are and rewritten in this one when the note is next saved;
+# add a version (and its case in _unwrap()) for any change to the note object.
+FORMAT_VERSION = 2
+
+
+# Folder in the notes directory where salvaged note files are moved aside
+DAMAGED_DIR = "damaged"
+
+
+class NoteFormatError(RuntimeError):
+    """A note file is in a format version newer than this termnotes reads"""
+
+
+def _checksum(data: dict) -> str:
+    """Get the checksum of a serialized note, over its canonical JSON"""
+    canonical = json.dumps(data, sort_keys=True, separators=(",", ":"), ensure_ascii=False)
+    return hashlib.sha256(canonical.encode("utf-8")).hexdigest()
+
+
+def _partial_string(text: str, key: str) -> Optional[str]:
+    """
+    Read a string field out of JSON that may break off partway through it
+
+    Args:
+        text: The damaged JSON
+        key: Name of the field
+
+    Returns:
+        The field's value, up to where the text breaks off, or None if it can't be found
+    """
+    match = re.search(r'"%s"\s*:\s*"((?:[^"\\]|\\.)*)' % re.escape(key), text, re.DOTALL)
+    if match is None:
+        return None
+    raw = re.sub(r'\\u[0-9a-fA-F]{0,3}$', '', match.group(1))  # An escape cut off by the break
+    try:
+        return json.loads(f'"{raw}"', strict=False)
+    except ValueError:
+        return None
+
+
+def salvage_note(text: str, note_id: str) -> Optional[Note]:
+    """
+    Recover what can be read of a damaged note file
+
+    The content is read up to where the file breaks off; the timestamps and
+    properties are kept if they're intact. The note is tagged "recovered".
+
+    Args:
+        text: The file's text
+        note_id: ID of the note (the file's name)
+
+    Returns:
+        The salvaged note, or None if no content could be read
+    """
+    content = _partial_string(text, "content")
+    if not content:
+        return None
+    now = utc_now()
+    timestamps = []
+    for key in ("created_at", "updated_at"):
+        try:
+            timestamps.append(datetime.fromisoformat(_partial_string(text, key) or ""))
+        except ValueError:
+            timestamps.append(now)
+    properties = {}
+    match = re.search(r'"properties"\s*:\s*', text)
+    if match is not None:
+        try:
+            properties, _ = json.JSONDecoder().raw_decode(text, match.end())
+        except ValueError:
+            pass
+        if not isinstance(properties, dict):
+            properties = {}
+    note = Note(note_id, content, timestamps[0], timestamps[1], properties)
+    note.add_tag("recovered")
+    return note
 
 
 class FilesystemBackend(StorageBackend):
-    """Filesystem implementation of storage backend using JSON files"""
+    """
+    Filesystem implementation of storage backend using JSON files
+
+    Each note is written to a temporary file and atomically renamed over the
+    previous version, so a crash mid-write never leaves a half-written note.
+    Durability can be tuned:
+    - fsync: flush file contents to disk before the rename, and the directory after it
+    - coalesce_window: batch repeated saves of a note into one write
+    - paranoid: re-read and parse the temporary file before replacing the old one
+    - backup: keep the previous version of each note in a hidden ".<id>.json.bak"
+      file, read if the note's file can't be parsed or fails its checksum
+
+    Every write to the notes directory holds the backend's lock, so the
+    coalescing timer's flush can't interleave with a save, delete, import or
+    close on another thread; a coalesced save stays readable from _pending
+    until its file is written.
+
+    Notes read from their backups are listed in from_backup, and files that
+    can't be read at all (nor their backups) in damaged, as of the last
+    get_all_notes(); salvage_damaged() recovers what it can of the latter.
 
-    def __init__(self, notes_dir: str = None):
+    A note file in a newer format (see FORMAT_VERSION) raises NoteFormatError
+    rather than being skipped or replaced by its backup, so an older
+    termnotes never drops or overwrites notes a newer one wrote.
+    """
+
+    def __init__(
+        self,
+        notes_dir: str = None,
+        fsync: bool = True,
+        coalesce_window: float = 0.0,
+        paranoid: bool = False,
+        backup: bool = True
+    ):
         """
         Initialize filesystem storage backend
 
         Args:
             notes_dir: Directory to store note files. Defaults to ~/.termnotes/notes
+            fsync: Whether to fsync each note file before replacing the old one
+            coalesce_window: Seconds to wait for more saves before writing (0 = write immediately)
+            paranoid: Whether to verify each written file parses back before replacing the old one
+            backup: Whether to keep the previous version of each note as a backup file
         """
+        super().__init__()
         if notes_dir is None:
             notes_dir = os.path.expanduser("~/.termnotes/notes")
 
         self.notes_dir = Path(notes_dir)
         self.notes_dir.mkdir(parents=True, exist_ok=True)
 
+        self.fsync = fsync
+        self.coalesce_window = coalesce_window
+        self.paranoid = paranoid
+        self.backup = backup
+
+        # Saves waiting for the coalescing window to elapse (note_id -> serialized note)
+        self._pending: Dict[str, dict] = {}
+        self._pending_lock = threading.Lock()
+        self._flush_timer: Optional[threading.Timer] = None
+
+        # Notes read from their backups, their files being damaged
+        self.from_backup: Set[str] = set()
+        # Note files that can't be read, with no good backup (note_id -> path)
+        self.damaged: Dict[str, Path] = {}
+
+        # Note files as last seen, to notice changes made by other programs
+        self._snapshot = FileSnapshot(lambda: self.notes_dir.glob("*.json"))
+
+    @property
+    def damaged_dir(self) -> Path:
+        """Folder where salvage_damaged() moves damaged note files"""
+        return self.notes_dir / DAMAGED_DIR
+
     def _get_note_path(self, note_id: str) -> Path:
         """Get the file path for a note"""
-        return self.notes_dir / f"{note_id}.json"
+        return self.notes_dir / f"{check_note_id(note_id)}.json"
+
+    def _get_backup_path(self, note_id: str) -> Path:
+        """Get the path of a note's backup of its previous version"""
+        return self.notes_dir / f".{check_note_id(note_id)}.json.bak"
+
+    def _read_note_file(self, note_id: str) -> Optional[Note]:
+        """
+        Read a note's file, falling back to its backup if the file is damaged
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            The note, or None if neither file can be read
+
+        Raises:
+            NoteFormatError: If a newer termnotes wrote the file
+        """
+        note_path = self._get_note_path(note_id)
+        for path in (note_path, self._get_backup_path(note_id)):
+            try:
+                note = self._read_file(path)
+            except (json.JSONDecodeError, KeyError, TypeError, ValueError, OSError):
+                continue
+            if path == note_path:
+                self.from_backup.discard(note_id)
+            else:
+                self.from_backup.add(note_id)
+            self.damaged.pop(note_id, None)
+            return note
+        self.from_backup.discard(note_id)
+        if note_path.exists():
+            self.damaged[note_id] = note_path
+        return None
+
+    def _read_file(self, path: Path) -> Note:
+        """
+        Read a note file or backup
+
+        Raises:
+            ValueError, KeyError, TypeError, OSError: If the file is damaged or can't be read
+            NoteFormatError: If a newer termnotes wrote the file
+        """
+        with open(path, 'r') as f:
+            return self._note_from_dict(self._unwrap(json.load(f), path))
+
+    def _unwrap(self, data, path: Path) -> dict:
+        """
+        Get the note object out of a note file's contents, in any format version up to FORMAT_VERSION
+
+        Args:
+            data: Parsed contents of the file
+            path: The file, for the error message
+
+        Returns:
+            The note as _note_to_dict() produces it
+
+        Raises:
+            ValueError: If the contents aren't a note file, or fail the checksum (it's damaged)
+            NoteFormatError: If a newer termnotes wrote the file
+        """
+        if not isinstance(data, dict):
+            raise ValueError("not a JSON object")
+        version = data.get("version", 1)  # Version 1 files are the bare note, with no version
+        if not isinstance(version, int) or isinstance(version, bool) or version < 1:
+            raise ValueError(f"invalid format version {version!r}")
+        if version > FORMAT_VERSION:
+            raise NoteFormatError(t("storage.note_format_newer", path=path, version=version))
+        if version == 1:
+            return data
+        note = data["note"]
+        if "checksum" in data and data["checksum"] != _checksum(note):
+            raise ValueError("checksum mismatch")
+        return note
 
     def get_all_notes(self) -> List[Note]:
         """Get all notes from the filesystem"""
         notes = []
 
+        with self._pending_lock:
+            pending = dict(self._pending)
+
+        # Notes waiting to be written take precedence over what is on disk
+        for data in pending.values():
+            notes.append(self._note_from_dict(data))
+
+        self.damaged = {}
         for note_file in self.notes_dir.glob("*.json"):
-            try:
-                with open(note_file, 'r') as f:
-                    data = json.load(f)
-                    note = self._note_from_dict(data)
-                    notes.append(note)
-            except (json.JSONDecodeError, KeyError, OSError):
-                # Skip corrupted files
+            if note_file.stem in pending:
                 continue
+            note = self._read_note_file(note_file.stem)
+            if note is not None:  # Skip files that are damaged and have no backup (see damaged)
+                notes.append(note)
 
         # Sort by updated_at, most recent first
         notes.sort(key=lambda n: n.updated_at, reverse=True)
@@ -52,38 +277,207 @@ class FilesystemBackend(StorageBackend):
 
     def get_note(self, note_id: str) -> Optional[Note]:
         """Get a specific note by ID"""
-        note_path = self._get_note_path(note_id)
+        with self._pending_lock:
+            data = self._pending.get(note_id)
+        if data is not None:
+            return self._note_from_dict(data)
 
-        if not note_path.exists():
-            return None
-
-        try:
-            with open(note_path, 'r') as f:
-                data = json.load(f)
-                return self._note_from_dict(data)
-        except (json.JSONDecodeError, KeyError, OSError):
+        if not self._get_note_path(note_id).exists():
             return None
+        return self._read_note_file(note_id)
 
     def save_note(self, note: Note):
         """Save or update a note"""
         # Update the updated_at timestamp
         note.updated_at = utc_now()
 
-        note_path = self._get_note_path(note.id)
         data = self._note_to_dict(note)
 
-        with open(note_path, 'w') as f:
-            json.dump(data, f, indent=2)
+        if self.coalesce_window <= 0:
+            with self.lock:
+                self._write_note(data)
+            return
+
+        # Defer the write; later saves of the same note replace this one
+        with self._pending_lock:
+            self._pending[note.id] = data
+            if self._flush_timer is None:
+                self._flush_timer = threading.Timer(self.coalesce_window, self.flush)
+                self._flush_timer.daemon = True
+                self._flush_timer.start()
+
+    @synchronized
+    def import_notes(self, notes: List[Note]) -> int:
+        """Write notes straight to disk, keeping their timestamps"""
+        self.flush()  # So an older coalesced save can't overwrite an imported note
+        for note in notes:
+            self._write_note(self._note_to_dict(note))
+        return len(notes)
+
+    @synchronized
+    def flush(self):
+        """Write any saves still waiting in the coalescing window, on the timer's thread or the caller's"""
+        with self._pending_lock:
+            pending = dict(self._pending)
+            if self._flush_timer is not None:
+                self._flush_timer.cancel()
+                self._flush_timer = None
 
+        for note_id, data in pending.items():
+            self._write_note(data)  # A failed write stays pending for the next flush
+            with self._pending_lock:
+                # Taken off only now, so get_note() doesn't read the old file meanwhile,
+                # and kept if the note was saved again since
+                if self._pending.get(note_id) is data:
+                    del self._pending[note_id]
+
+    def _write_note(self, data: dict):
+        """
+        Write a serialized note to disk atomically
+
+        The note is written to a temporary file in the notes directory and
+        renamed over the existing file, so readers see either the old or the
+        new version but never a partial one.
+
+        Args:
+            data: Note dictionary as produced by _note_to_dict, written in a
+                  FORMAT_VERSION envelope
+
+        Raises:
+            OSError: If paranoid verification of the written file fails
+        """
+        note_path = self._get_note_path(data["id"])
+        tmp_path = note_path.with_name(f".{note_path.name}.tmp")
+        serialized = json.dumps({"version": FORMAT_VERSION, "checksum": _checksum(data), "note": data}, indent=2)
+
+        try:
+            with open(tmp_path, 'w') as f:
+                f.write(serialized)
+                if self.fsync:
+                    f.flush()
+                    os.fsync(f.fileno())
+
+            if self.paranoid:
+                self._verify_written(tmp_path, json.loads(serialized))
+
+            if self.backup:
+                self._rotate_backup(data["id"])
+            os.replace(tmp_path, note_path)
+            self._snapshot.written(note_path)
+            if self.fsync:
+                self._fsync_directory()
+        except BaseException:
+            # Leave the previous version untouched and clean up the partial write
+            if tmp_path.exists():
+                tmp_path.unlink()
+            raise
+
+    def _rotate_backup(self, note_id: str):
+        """
+        Make a note's current file its backup, replacing the previous backup
+
+        The file is hard linked where possible, so the note's file stays in
+        place until the new version is renamed over it. A damaged file is
+        left out, keeping the last good version as the backup.
+
+        Args:
+            note_id: ID of the note about to be written
+        """
+        note_path = self._get_note_path(note_id)
+        if not note_path.exists():
+            return
+        try:
+            self._read_file(note_path)
+        except (json.JSONDecodeError, KeyError, TypeError, ValueError, OSError):
+            return
+        backup_path = self._get_backup_path(note_id)
+        tmp_path = backup_path.with_name(f"{backup_path.name}.tmp")
+        try:
+            if tmp_path.exists():
+                tmp_path.unlink()
+            os.link(note_path, tmp_path)
+        except OSError:
+            shutil.copy2(note_path, tmp_path)  # No hard links on this file system
+        os.replace(tmp_path, backup_path)
+
+    def _fsync_directory(self):
+        """Flush the notes directory so renames in it survive a crash"""
+        try:
+            fd = os.open(self.notes_dir, os.O_RDONLY)
+        except OSError:
+            return  # Directories can't be opened on Windows; renames there are durable
+        try:
+            os.fsync(fd)
+        except OSError:
+            pass
+        finally:
+            os.close(fd)
+
+    def _verify_written(self, path: Path, expected: dict):
+        """
+        Check that a written note file parses back to the expected data
+
+        Args:
+            path: File to verify
+            expected: Data that should have been written
+
+        Raises:
+            OSError: If the file cannot be parsed or does not match
+        """
+        try:
+            with open(path, 'r') as f:
+                written = json.load(f)
+            self._note_from_dict(self._unwrap(written, path))
+        except (json.JSONDecodeError, KeyError, TypeError, ValueError) as e:
+            raise OSError(f"Verification of {path} failed: {e}")
+
+        if written != expected:
+            raise OSError(f"Verification of {path} failed: content does not match")
+
+    def salvage_damaged(self) -> List[Note]:
+        """
+        Recover what can be read of the damaged note files (see damaged)
+
+        Each file is moved into damaged_dir, where it's kept but no
+        longer read. The notes salvaged from them keep their IDs; they aren't
+        saved, so the caller can save them through its storage stack.
+
+        Returns:
+            The salvaged notes
+        """
+        notes = []
+        for note_id, path in sorted(self.damaged.items()):
+            try:
+                text = path.read_text(errors="replace")
+                self.damaged_dir.mkdir(exist_ok=True)
+                os.replace(path, self.damaged_dir / path.name)
+            except OSError:
+                continue
+            self._snapshot.removed(path)
+            note = salvage_note(text, note_id)
+            if note is not None:
+                notes.append(note)
+        self.damaged = {}
+        return notes
+
+    @synchronized
     def delete_note(self, note_id: str):
         """Delete a note by ID"""
-        note_path = self._get_note_path(note_id)
-        if note_path.exists():
-            note_path.unlink()
+        with self._pending_lock:
+            self._pending.pop(note_id, None)
+
+        for path in (self._get_note_path(note_id), self._get_backup_path(note_id)):
+            if path.exists():
+                path.unlink()
+        self._snapshot.removed(self._get_note_path(note_id))
+
+    def poll_changes(self) -> bool:
+        """Check whether another program changed the note files since the last check"""
+        return self._snapshot.changed()
 
     def close(self):
-        """Clean up resources (no-op for filesystem)"""
-        pass
+        """Write any pending saves"""
+        self.flush()
 
     def _note_to_dict(self, note: Note) -> dict:
         """Convert Note object to dictionary for JSON storage"""
@@ -0,0 +1,119 @@
+"""
+Markdown serialization of notes with a frontmatter header
+
+A note is written as its content preceded by a header of "key: value" lines
+between "---" fences. Values are JSON, which is also valid YAML, so other
+tools that read YAML frontmatter understand the header:
+
+    ---
+    id: "3f2c..."
+    created_at: "2025-01-01T12:00:00"
+    updated_at: "2025-01-02T08:30:00"
+    properties: {"tags": ["work"]}
+    ---
+    # Note title
+    ...
+"""
+
+import json
+from datetime import datetime
+from typing import Any, Dict, Optional, Tuple
+from ..note import Note
+from ..utils import utc_now
+
+FENCE = "---"
+
+
+def note_to_markdown(note: Note) -> str:
+    """
+    Serialize a note to markdown with a frontmatter header
+
+    Args:
+        note: Note to serialize
+
+    Returns:
+        File content
+    """
+    header = {
+        "id": note.id,
+        "created_at": note.created_at.isoformat(),
+        "updated_at": note.updated_at.isoformat(),
+    }
+    if note.properties:
+        header["properties"] = note.properties
+
+    lines = [FENCE]
+    lines.extend(f"{key}: {json.dumps(value, ensure_ascii=False)}" for key, value in header.items())
+    lines.append(FENCE)
+    return "\n".join(lines) + "\n" + note.content
+
+
+def split_frontmatter(text: str) -> Tuple[Dict[str, Any], str]:
+    """
+    Split file content into its frontmatter header and body
+
+    Header values that aren't JSON (e.g. hand-written "title: My note") are
+    kept as plain strings.
+
+    Args:
+        text: File content
+
+    Returns:
+        Tuple of (header dict, body); the header is empty if there is none
+    """
+    if not text.startswith(FENCE + "\n"):
+        return {}, text
+
+    end = text.find("\n" + FENCE, len(FENCE))
+    if end == -1:
+        return {}, text
+
+    header = {}
+    for line in text[len(FENCE) + 1:end].split("\n"):
+        key, sep, value = line.partition(":")
+        if not sep or not key.strip():
+            continue
+        value = value.strip()
+        try:
+            header[key.strip()] = json.loads(value)
+        except json.JSONDecodeError:
+            header[key.strip()] = value
+
+    body_start = end + len(FENCE) + 1
+    if text.startswith("\n", body_start):
+        body_start += 1
+    return header, text[body_start:]
+
+
+def note_from_markdown(text: str, fallback_id: str, fallback_time: Optional[datetime] = None) -> Note:
+    """
+    Parse a note from markdown with an optional frontmatter header
+
+    Files created by other tools may have no header or only some fields;
+    missing fields fall back to the given defaults.
+
+    Args:
+        text: File content
+        fallback_id: Note ID to use if the header has none (e.g. the file name)
+        fallback_time: Timestamp to use if the header has none (e.g. the file's mtime)
+
+    Returns:
+        Parsed note
+    """
+    header, body = split_frontmatter(text)
+    fallback_time = fallback_time or utc_now()
+
+    def parse_time(key: str) -> datetime:
+        try:
+            return datetime.fromisoformat(str(header[key]))
+        except (KeyError, ValueError):
+            return fallback_time
+
+    properties = header.get("properties")
+    return Note(
+        note_id=str(header.get("id") or fallback_id),
+        content=body,
+        created_at=parse_time("created_at"),
+        updated_at=parse_time("updated_at"),
+        properties=properties if isinstance(properties, dict) else {}
+    )
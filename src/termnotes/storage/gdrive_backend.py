@@ -43,6 +43,7 @@ class GoogleDriveBackend(StorageBackend):
                        Default: ~/.termnotes/google_token.json
             app_folder: Name of folder in Drive root to store notes
         """
+        super().__init__()
         # Set up paths
         config_dir = Path.home() / ".termnotes"
         config_dir.mkdir(parents=True, exist_ok=True)
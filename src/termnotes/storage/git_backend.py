@@ -0,0 +1,209 @@
+"""
+Git-backed note storage backend using markdown files
+"""
+
+import subprocess
+from datetime import datetime, timezone
+from typing import List
+from .frontmatter import note_from_markdown
+from .markdown_backend import MarkdownBackend
+from ..history import Revision
+from ..note import Note
+from ..i18n import t
+
+
+class GitBackend(MarkdownBackend):
+    """
+    Storage backend that keeps notes as markdown files in a git repository
+
+    Files are laid out as in MarkdownBackend (named after the note title,
+    notebooks as subdirectories), and every save or delete is committed, so
+    the repository's history is the notes' version history. With a remote
+    configured, changes are pulled on startup and optionally pushed on exit
+    for syncing between machines.
+
+    Requires the git command line tool.
+    """
+
+    supports_revisions = True
+
+    def __init__(
+        self,
+        repo_dir: str,
+        remote: str = "",
+        pull_on_start: bool = True,
+        push_on_exit: bool = False
+    ):
+        """
+        Initialize git storage backend
+
+        Args:
+            repo_dir: Repository directory (created and initialized if needed)
+            remote: URL of the "origin" remote to sync with ("" for none)
+            pull_on_start: Whether to pull from the remote on startup
+            push_on_exit: Whether to push to the remote when closed
+
+        Raises:
+            RuntimeError: If git is not installed or the repository can't be initialized
+        """
+        super().__init__(repo_dir)
+        self.repo_dir = self.notes_dir
+        self.remote = remote
+        self.push_on_exit = push_on_exit
+
+        try:
+            if not (self.repo_dir / ".git").exists():
+                self._git("init", "-q")
+        except FileNotFoundError:
+            raise RuntimeError(t("storage.git_missing"))
+        except subprocess.CalledProcessError as e:
+            raise RuntimeError(t("storage.git_failed", error=e.stderr.strip()))
+
+        # Commit as termnotes if the user has no git identity configured
+        self._identity = []
+        if not self._git("config", "user.email", check=False).stdout.strip():
+            self._identity = ["-c", "user.name=termnotes", "-c", "user.email=termnotes@localhost"]
+
+        if remote:
+            if self._git("remote", "get-url", "origin", check=False).returncode != 0:
+                self._git("remote", "add", "origin", remote)
+            else:
+                self._git("remote", "set-url", "origin", remote)
+            if pull_on_start:
+                self.pull()
+
+    def _git(self, *args: str, check: bool = True) -> subprocess.CompletedProcess:
+        """
+        Run a git command in the repository
+
+        Args:
+            *args: git arguments
+            check: Raise CalledProcessError if the command fails
+
+        Returns:
+            Completed process with captured text output
+        """
+        return subprocess.run(
+            ["git", "-C", str(self.repo_dir), *args],
+            capture_output=True,
+            text=True,
+            check=check
+        )
+
+    def _commit(self, message: str):
+        """
+        Stage every change in the repository and commit it
+
+        Staging everything also picks up edits made outside termnotes and
+        changes left over from an earlier failed commit. Failures are ignored:
+        the files are already written and will be in the next commit.
+
+        Args:
+            message: Commit message
+        """
+        self._git("add", "-A", check=False)
+        if self._git("diff", "--cached", "--quiet", check=False).returncode == 0:
+            return  # Nothing changed
+        self._git(*self._identity, "commit", "-q", "-m", message, check=False)
+
+    def pull(self) -> bool:
+        """
+        Pull changes from the remote, rebasing local commits on top
+
+        Returns:
+            True if the pull succeeded, False otherwise (a warning is printed)
+        """
+        if not self.remote:
+            return False
+        if not self._git("ls-remote", "--heads", "origin", check=False).stdout.strip():
+            return True  # Remote is empty; nothing to pull yet
+        self._commit("Save notes before pull")
+        result = self._git("pull", "-q", "--rebase", "origin", "HEAD", check=False)
+        if result.returncode != 0:
+            print(t("storage.git_pull_failed", error=result.stderr.strip()))
+            return False
+        return True
+
+    def push(self) -> bool:
+        """
+        Push commits to the remote
+
+        Returns:
+            True if the push succeeded, False otherwise (a warning is printed)
+        """
+        if not self.remote:
+            return False
+        result = self._git("push", "-q", "origin", "HEAD", check=False)
+        if result.returncode != 0:
+            print(t("storage.git_push_failed", error=result.stderr.strip()))
+            return False
+        return True
+
+    def save_note(self, note: Note):
+        """Save or update a note and commit it"""
+        is_new = self._find_path(note.id) is None
+        super().save_note(note)
+
+        title = note.get_preview(50)
+        self._commit(f"Add note: {title}" if is_new else f"Update note: {title}")
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Write notes to their files and commit them together"""
+        count = super().import_notes(notes)
+        self._commit(f"Import {count} notes")
+        return count
+
+    def delete_note(self, note_id: str):
+        """Delete a note by ID and commit the removal"""
+        note = self.get_note(note_id)
+        if note is None:
+            return
+
+        super().delete_note(note_id)
+        self._commit(f"Delete note: {note.get_preview(50)}")
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """
+        Get the versions of a note from the commits that changed its file
+
+        Commits that only changed the frontmatter (e.g. tags) are skipped.
+        """
+        path = self._find_path(note_id)
+        if path is None:
+            return []
+
+        # Follow renames; each commit is a "\0<hash> <time>" line, then the file's path at that commit
+        result = self._git(
+            "-c", "core.quotePath=false",
+            "log", "--follow", "--format=%x00%H %ct", "--name-only", "--",
+            path.relative_to(self.repo_dir).as_posix(),
+            check=False
+        )
+        commits = []
+        for entry in result.stdout.split("\0")[1:]:
+            lines = entry.strip().split("\n")
+            if len(lines) < 2:
+                continue
+            commit, timestamp = lines[0].split()
+            commits.append((commit, int(timestamp), lines[-1]))
+
+        revisions = []
+        for commit, timestamp, file_path in reversed(commits):
+            show = self._git("show", f"{commit}:{file_path}", check=False)
+            if show.returncode != 0:
+                continue
+            content = note_from_markdown(show.stdout, note_id).content
+            if revisions and revisions[-1].content == content:
+                continue
+            revisions.append(Revision(
+                note_id=note_id,
+                rev=len(revisions) + 1,
+                content=content,
+                saved_at=datetime.fromtimestamp(timestamp, timezone.utc).replace(tzinfo=None)
+            ))
+        return revisions
+
+    def close(self):
+        """Push to the remote if configured to"""
+        if self.push_on_exit:
+            self.push()
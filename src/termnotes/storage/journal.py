@@ -0,0 +1,100 @@
+"""
+Operation journal for undoing changes to notes
+
+Each operation records how the notes it touched looked before and after,
+so undoing it writes the "before" versions back (deleting notes it created)
+and redoing it writes the "after" versions. Only the most recent operations
+are kept.
+"""
+
+import copy
+from dataclasses import dataclass
+from typing import List, Optional
+from ..note import Note
+
+
+def snapshot(note: Optional[Note]) -> Optional[Note]:
+    """Copy a note so later changes to it don't alter the journal"""
+    if note is None:
+        return None
+    return Note(
+        note_id=note.id,
+        content=note.content,
+        created_at=note.created_at,
+        updated_at=note.updated_at,
+        properties=copy.deepcopy(note.properties)
+    )
+
+
+@dataclass
+class Change:
+    """One note's state before and after an operation (None: didn't exist)"""
+    note_id: str
+    before: Optional[Note]
+    after: Optional[Note]
+
+    @property
+    def is_noop(self) -> bool:
+        """Whether the operation left the note as it was"""
+        if self.before is None or self.after is None:
+            return self.before is self.after
+        return (self.before.content == self.after.content and
+                self.before.properties == self.after.properties)
+
+
+@dataclass
+class Operation:
+    """A change to one or more notes that can be undone as a unit"""
+    description: str  # Shown when the operation is undone or redone
+    changes: List[Change]
+
+
+class OperationJournal:
+    """Undo and redo stacks of operations, oldest dropped past the limit"""
+
+    def __init__(self, limit: int = 50):
+        """
+        Initialize an empty journal
+
+        Args:
+            limit: Most operations to keep for undo
+        """
+        self.limit = limit
+        self.undo_stack: List[Operation] = []
+        self.redo_stack: List[Operation] = []
+
+    def record(self, operation: Operation):
+        """
+        Add a new operation, clearing the operations that could be redone
+
+        Args:
+            operation: Operation just performed
+        """
+        self.undo_stack.append(operation)
+        if len(self.undo_stack) > self.limit:
+            self.undo_stack.pop(0)
+        self.redo_stack.clear()
+
+    def next_undo(self) -> Optional[Operation]:
+        """Get the operation undo would undo, without undoing it"""
+        return self.undo_stack[-1] if self.undo_stack else None
+
+    def next_redo(self) -> Optional[Operation]:
+        """Get the operation redo would redo, without redoing it"""
+        return self.redo_stack[-1] if self.redo_stack else None
+
+    def pop_undo(self) -> Optional[Operation]:
+        """Take the most recent operation to undo, moving it to the redo stack"""
+        if not self.undo_stack:
+            return None
+        operation = self.undo_stack.pop()
+        self.redo_stack.append(operation)
+        return operation
+
+    def pop_redo(self) -> Optional[Operation]:
+        """Take the most recently undone operation, moving it back to the undo stack"""
+        if not self.redo_stack:
+            return None
+        operation = self.redo_stack.pop()
+        self.undo_stack.append(operation)
+        return operation
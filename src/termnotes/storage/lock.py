@@ -0,0 +1,86 @@
+"""
+Advisory lock keeping two termnotes editors off the same notes
+
+Each editor would otherwise overwrite the other's changes with its own
+copy of a note. The lock is an OS file lock (flock on Unix, a byte-range
+lock on Windows) on a file next to the notes, so it is released when the
+process exits, even after a crash; the file itself is left behind.
+"""
+
+import os
+import socket
+from pathlib import Path
+from ..i18n import t
+
+try:
+    import fcntl
+except ImportError:  # Windows
+    fcntl = None
+    import msvcrt
+
+
+class StorageLocked(RuntimeError):
+    """Another termnotes process holds the lock"""
+
+
+class StorageLock:
+    """Exclusive lock on a notes location, held while an editor is open"""
+
+    def __init__(self, path: str):
+        """
+        Initialize the lock (not yet acquired)
+
+        Args:
+            path: Lock file to create
+        """
+        self.path = Path(path)
+        self._file = None
+
+    def _try_lock(self, fd: int) -> bool:
+        """Lock a file descriptor without waiting; False if another process holds it"""
+        try:
+            if fcntl:
+                fcntl.flock(fd, fcntl.LOCK_EX | fcntl.LOCK_NB)
+            else:
+                msvcrt.locking(fd, msvcrt.LK_NBLCK, 1)
+            return True
+        except (BlockingIOError, PermissionError):
+            return False
+
+    def acquire(self):
+        """
+        Take the lock, recording this process in the lock file
+
+        File systems without locking (some network mounts) are treated as
+        unlocked rather than keeping termnotes from starting.
+
+        Raises:
+            StorageLocked: If another termnotes process holds the lock
+        """
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        lock_file = open(self.path, "a+", encoding="utf-8")
+        lock_file.seek(0)  # Windows locks bytes from the current position
+        try:
+            locked = self._try_lock(lock_file.fileno())
+        except OSError:
+            locked = True  # Locking unsupported here
+        if not locked:
+            try:
+                owner = lock_file.read().strip() or "?"
+            except OSError:
+                owner = "?"  # Windows doesn't allow reading locked bytes
+            lock_file.close()
+            raise StorageLocked(t("storage.locked", path=self.path, owner=owner))
+
+        # Note who holds the lock, for the message another process shows
+        lock_file.seek(0)
+        lock_file.truncate()
+        lock_file.write(f"pid {os.getpid()} on {socket.gethostname()}")
+        lock_file.flush()
+        self._file = lock_file
+
+    def release(self):
+        """Release the lock if held"""
+        if self._file is not None:
+            self._file.close()  # Closing the file releases the OS lock
+            self._file = None
@@ -0,0 +1,238 @@
+"""
+Markdown directory note storage backend
+"""
+
+import os
+import re
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Dict, Iterator, List, Optional
+from .base import StorageBackend
+from .watch import FileSnapshot
+from .frontmatter import note_from_markdown, note_to_markdown
+from ..utils import utc_now
+from ..note import Note
+from ..notebook import get_note_notebook
+from ..i18n import t
+
+
+class MarkdownBackend(StorageBackend):
+    """
+    Storage backend that keeps each note as a .md file named after its title
+
+    The note ID and timestamps live in a frontmatter header, so files can be
+    renamed, grepped, and edited by other tools (e.g. Obsidian) without losing
+    track of the note. Notebooks map to subdirectories. Files and directories
+    starting with "." (such as .git or .obsidian) are ignored.
+    """
+
+    MAX_FILENAME_LENGTH = 80
+
+    def __init__(self, notes_dir: str):
+        """
+        Initialize markdown storage backend
+
+        Args:
+            notes_dir: Directory to store note files in
+        """
+        super().__init__()
+        self.notes_dir = Path(notes_dir)
+        self.notes_dir.mkdir(parents=True, exist_ok=True)
+
+        # Where each note was last seen (note_id -> file path)
+        self._paths: Dict[str, Path] = {}
+
+        # Note files as last seen, to notice changes made by other programs
+        self._snapshot = FileSnapshot(self._iter_note_files)
+
+    @classmethod
+    def filename_for(cls, note: Note) -> str:
+        """
+        Get the file name (without extension) for a note based on its title
+
+        Args:
+            note: Note to name
+
+        Returns:
+            File system safe name, "Untitled" if the note has no title
+        """
+        name = re.sub(r'[\\/:*?"<>|\x00-\x1f]', " ", note.title)
+        name = " ".join(name.split()).strip(". ")
+        return name[:cls.MAX_FILENAME_LENGTH].rstrip(". ") or "Untitled"
+
+    @staticmethod
+    def fallback_id(relative: Path) -> str:
+        """
+        Get the ID of a note file without one in its header, from its path
+
+        Separators are escaped (and "%", so no two paths share an ID), as
+        IDs name attachment directories; "work/todo.md" becomes "work%2Ftodo".
+
+        Args:
+            relative: Path of the file in the notes directory
+
+        Returns:
+            The note ID, the same on every read
+        """
+        name = relative.with_suffix("").as_posix()
+        return name.replace("%", "%25").replace("/", "%2F").replace("\\", "%5C")
+
+    def _iter_note_files(self) -> Iterator[Path]:
+        """Yield every note file, skipping hidden files and directories"""
+        for path in sorted(self.notes_dir.rglob("*.md")):
+            relative = path.relative_to(self.notes_dir)
+            if not any(part.startswith(".") for part in relative.parts):
+                yield path
+
+    def _read_note(self, path: Path) -> Optional[Note]:
+        """
+        Read a note file
+
+        The note's notebook comes from the file's directory, so moving files
+        between folders in another tool moves the notes between notebooks.
+
+        Args:
+            path: File to read
+
+        Returns:
+            Note, or None if the file can't be read
+        """
+        try:
+            text = path.read_text(encoding="utf-8")
+            mtime = datetime.fromtimestamp(path.stat().st_mtime, timezone.utc).replace(tzinfo=None)
+        except (OSError, UnicodeDecodeError):
+            return None
+
+        relative = path.relative_to(self.notes_dir)
+        note = note_from_markdown(text, fallback_id=self.fallback_id(relative), fallback_time=mtime)
+
+        notebook = relative.parent.as_posix()
+        if notebook == ".":
+            note.delete_property("notebook")
+        else:
+            note.set_property("notebook", notebook)
+        return note
+
+    def _scan(self) -> List[Note]:
+        """Read every note file, refreshing the ID to path index"""
+        notes = []
+        paths = {}
+        for path in self._iter_note_files():
+            note = self._read_note(path)
+            if note is not None and note.id not in paths:
+                notes.append(note)
+                paths[note.id] = path
+        self._paths = paths
+        return notes
+
+    def _find_path(self, note_id: str) -> Optional[Path]:
+        """Get the file holding a note, rescanning if it has moved"""
+        path = self._paths.get(note_id)
+        if path is None or not path.exists():
+            self._scan()
+            path = self._paths.get(note_id)
+        return path
+
+    def _choose_path(self, note: Note, current: Optional[Path]) -> Path:
+        """
+        Choose the file path for a note, avoiding other notes' files
+
+        Args:
+            note: Note being saved
+            current: File the note is currently stored in, if any
+
+        Returns:
+            Path in the note's notebook directory named after its title
+
+        Raises:
+            ValueError: If the notebook's directory would be outside the notes directory
+        """
+        notebook = get_note_notebook(note)
+        directory = self.notes_dir / notebook if notebook else self.notes_dir
+        root = self.notes_dir.resolve()
+        if directory.resolve() != root and root not in directory.resolve().parents:
+            raise ValueError(t("storage.notebook_outside", notebook=notebook))
+        base = self.filename_for(note)
+        candidate = directory / f"{base}.md"
+        counter = 2
+        while candidate.exists() and candidate != current:
+            candidate = directory / f"{base} {counter}.md"
+            counter += 1
+        return candidate
+
+    def _write_note(self, note: Note) -> Optional[Path]:
+        """
+        Write a note to the file matching its title and notebook
+
+        Args:
+            note: Note to write
+
+        Returns:
+            The note's previous file if it was renamed or moved, else None
+        """
+        current = self._find_path(note.id)
+        path = self._choose_path(note, current)
+        path.parent.mkdir(parents=True, exist_ok=True)
+
+        # The directory records the notebook, so keep it out of the header
+        properties = {k: v for k, v in note.properties.items() if k != "notebook"}
+        stored = Note(note.id, note.content, note.created_at, note.updated_at, properties)
+
+        tmp_path = path.with_name(f".{path.name}.tmp")
+        try:
+            tmp_path.write_text(note_to_markdown(stored), encoding="utf-8")
+            os.replace(tmp_path, path)
+        except BaseException:
+            if tmp_path.exists():
+                tmp_path.unlink()
+            raise
+
+        self._paths[note.id] = path
+        self._snapshot.written(path)
+        if current is not None and current != path and current.exists():
+            current.unlink()
+            self._snapshot.removed(current)
+            return current
+        return None
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes from the directory"""
+        notes = self._scan()
+
+        # Sort by updated_at, most recent first
+        notes.sort(key=lambda n: n.updated_at, reverse=True)
+        return notes
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a specific note by ID"""
+        path = self._find_path(note_id)
+        if path is None:
+            return None
+        return self._read_note(path)
+
+    def save_note(self, note: Note):
+        """Save or update a note, renaming its file if the title changed"""
+        note.updated_at = utc_now()
+        self._write_note(note)
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Write notes to their files, keeping their timestamps"""
+        for note in notes:
+            self._write_note(note)
+        return len(notes)
+
+    def delete_note(self, note_id: str):
+        """Delete a note by ID"""
+        path = self._find_path(note_id)
+        if path is not None and path.exists():
+            path.unlink()
+            self._snapshot.removed(path)
+        self._paths.pop(note_id, None)
+
+    def poll_changes(self) -> bool:
+        """Check whether another program changed the note files since the last check"""
+        return self._snapshot.changed()
+
+    def close(self):
+        """Nothing to clean up; every save is written immediately"""
+        pass
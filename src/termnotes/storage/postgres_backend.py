@@ -0,0 +1,366 @@
+"""
+PostgreSQL storage backend, for notes shared by several people through one database
+"""
+
+import json
+from typing import List, Optional
+from .base import NoteConflict, StorageBackend
+from .context import current_context
+from ..utils import utc_now
+from ..note import SUMMARY_LENGTH, Note, NoteSummary
+from ..history import Revision
+from ..links import link_targets, normalize_link
+from ..search import SearchResult, build_snippet, tokenize_query
+from ..i18n import t
+
+# Schema changes, applied in order to bring a database up to date. The
+# termnotes_schema table records how many have been applied; add new
+# migrations at the end and never edit one that has been released.
+MIGRATIONS = [
+    # 1: notes and the tag, link and revision indexes, as in SQLiteBackend
+    """
+    CREATE TABLE notes (
+        id TEXT PRIMARY KEY,
+        content TEXT NOT NULL,
+        created_at TIMESTAMP NOT NULL,
+        updated_at TIMESTAMP NOT NULL,
+        properties JSONB NOT NULL DEFAULT '{}'
+    );
+    CREATE INDEX idx_notes_updated_at ON notes (updated_at DESC);
+    CREATE TABLE note_tags (
+        note_id TEXT NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+        tag TEXT NOT NULL,
+        PRIMARY KEY (note_id, tag)
+    );
+    CREATE INDEX idx_note_tags_tag ON note_tags (tag);
+    CREATE TABLE note_links (
+        note_id TEXT NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+        target TEXT NOT NULL,
+        PRIMARY KEY (note_id, target)
+    );
+    CREATE INDEX idx_note_links_target ON note_links (target);
+    CREATE TABLE note_revisions (
+        note_id TEXT NOT NULL REFERENCES notes (id) ON DELETE CASCADE,
+        rev INTEGER NOT NULL,
+        content TEXT NOT NULL,
+        saved_at TIMESTAMP NOT NULL,
+        PRIMARY KEY (note_id, rev)
+    );
+    """,
+    # 2: a counter bumped by every change, so editors notice each other's changes
+    """
+    CREATE SEQUENCE notes_version;
+    CREATE FUNCTION notes_bump_version() RETURNS trigger LANGUAGE plpgsql AS $$
+    BEGIN
+        PERFORM nextval('notes_version');
+        RETURN NULL;
+    END
+    $$;
+    CREATE TRIGGER notes_changed AFTER INSERT OR UPDATE OR DELETE ON notes
+        FOR EACH STATEMENT EXECUTE FUNCTION notes_bump_version();
+    """,
+    # 3: full-text search over note contents
+    """
+    ALTER TABLE notes ADD COLUMN search TSVECTOR
+        GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;
+    CREATE INDEX idx_notes_search ON notes USING GIN (search);
+    """,
+]
+
+# Key of the advisory lock held while migrating, so two editors starting at
+# once don't both apply a migration
+MIGRATION_LOCK = 0x7465726D  # "term"
+
+NOTE_COLUMNS = "id, content, created_at, updated_at, properties"
+JOINED_NOTE_COLUMNS = "n.id, n.content, n.created_at, n.updated_at, n.properties"
+
+
+class PostgresBackend(StorageBackend):
+    """
+    PostgreSQL implementation of storage backend
+
+    Used directly, without the in-memory cache other backends get, so every
+    editor sees the same notes. The schema is created and upgraded on
+    connect through MIGRATIONS. Requires the psycopg package (version 3).
+    The deadline of the context in use becomes the statement_timeout, and a
+    cancelled context stops before the next statement.
+    """
+
+    supports_revisions = True
+
+    def __init__(self, dsn: str, password: str = ""):
+        """
+        Connect to the database and bring its schema up to date
+
+        Args:
+            dsn: Connection string, a URL ("postgresql://user@host/notes") or
+                 "key=value" pairs; libpq's PG* variables and ~/.pgpass apply
+            password: Password, if it isn't in the connection string
+
+        Raises:
+            RuntimeError: If psycopg isn't installed or the database can't be reached
+        """
+        super().__init__()
+        try:
+            import psycopg
+        except ImportError:
+            raise RuntimeError(t("storage.postgres_no_driver"))
+        self._errors = psycopg.Error
+        try:
+            # Autocommit, so reads see other editors' changes; writes use transactions
+            self.conn = psycopg.connect(dsn, autocommit=True, **({"password": password} if password else {}))
+        except psycopg.Error as e:
+            raise RuntimeError(t("storage.postgres_connect_failed", error=str(e).strip()))
+        self._statement_timeout = 0  # Milliseconds, as last set on the connection
+        self._migrate()
+        self._version = self._get_version()
+
+    def _migrate(self):
+        """Apply the migrations the database doesn't have yet, in one transaction"""
+        with self.conn.transaction():
+            cursor = self.conn.cursor()
+            cursor.execute("SELECT pg_advisory_xact_lock(%s)", (MIGRATION_LOCK,))
+            cursor.execute("CREATE TABLE IF NOT EXISTS termnotes_schema (version INTEGER NOT NULL)")
+            cursor.execute("SELECT version FROM termnotes_schema")
+            row = cursor.fetchone()
+            if row is None:
+                cursor.execute("INSERT INTO termnotes_schema (version) VALUES (0)")
+                version = 0
+            else:
+                version = row[0]
+            if version > len(MIGRATIONS):
+                raise RuntimeError(t("storage.postgres_newer_schema", version=version))
+            for migration in MIGRATIONS[version:]:
+                cursor.execute(migration)
+            cursor.execute("UPDATE termnotes_schema SET version = %s", (len(MIGRATIONS),))
+
+    def _apply_deadline(self):
+        """
+        Limit the next statements to the time left in the context in use
+
+        Called outside transactions, since a rolled back transaction would
+        undo the setting.
+
+        Raises:
+            OperationCancelled: If the context has already ended
+        """
+        context = current_context()
+        context.check()
+        remaining = context.remaining()
+        timeout = 0 if remaining is None else max(1, int(remaining * 1000))
+        if timeout != self._statement_timeout:
+            self.conn.execute("SELECT set_config('statement_timeout', %s, false)", (str(timeout),))
+            self._statement_timeout = timeout
+
+    def _execute(self, query: str, params=None):
+        """Run a statement outside a transaction, bounded by the context in use"""
+        self._apply_deadline()
+        return self.conn.execute(query, params)
+
+    def _note_from_row(self, row) -> Note:
+        """Build a note from a row of NOTE_COLUMNS"""
+        properties = row[4]
+        if isinstance(properties, str):
+            properties = json.loads(properties)
+        return Note(
+            note_id=row[0],
+            content=row[1],
+            created_at=row[2],
+            updated_at=row[3],
+            properties=properties or {}
+        )
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes from the database"""
+        rows = self._execute(f"SELECT {NOTE_COLUMNS} FROM notes ORDER BY updated_at DESC").fetchall()
+        return [self._note_from_row(row) for row in rows]
+
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """Get a page of notes with only the start of their content"""
+        rows = self._execute("""
+            SELECT id, left(content, %s), created_at, updated_at, properties
+            FROM notes
+            ORDER BY updated_at DESC
+            LIMIT %s OFFSET %s
+        """, (SUMMARY_LENGTH, limit, offset)).fetchall()
+        return [NoteSummary.of(self._note_from_row(row)) for row in rows]
+
+    def count_notes(self) -> int:
+        """Count the notes in the database"""
+        return self._execute("SELECT COUNT(*) FROM notes").fetchone()[0]
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a specific note by ID"""
+        row = self._execute(f"SELECT {NOTE_COLUMNS} FROM notes WHERE id = %s", (note_id,)).fetchone()
+        return self._note_from_row(row) if row else None
+
+    def save_note(self, note: Note):
+        """Save or update a note"""
+        note.updated_at = utc_now()
+        self._apply_deadline()
+        with self.conn.transaction():
+            self._write_notes([note])
+        self._version = self._get_version()
+
+    def update_note(self, note: Note, expected_version: Optional[str]):
+        """Save a note unless its stored version changed, locking its row while checking"""
+        note.updated_at = utc_now()
+        self._apply_deadline()
+        with self.conn.transaction():
+            if expected_version is not None:
+                row = self.conn.execute(
+                    f"SELECT {NOTE_COLUMNS} FROM notes WHERE id = %s FOR UPDATE", (note.id,)
+                ).fetchone()
+                if row is not None:
+                    stored = self._note_from_row(row)
+                    if stored.version != expected_version:
+                        raise NoteConflict(stored)
+            self._write_notes([note])
+        self._version = self._get_version()
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Insert notes in a single transaction, keeping their timestamps"""
+        self._apply_deadline()
+        with self.conn.transaction():
+            self._write_notes(notes)
+        self._version = self._get_version()
+        return len(notes)
+
+    def _write_notes(self, notes: List[Note]):
+        """Upsert notes with their tag and link index rows and a revision, inside a transaction"""
+        cursor = self.conn.cursor()
+        cursor.executemany(f"""
+            INSERT INTO notes ({NOTE_COLUMNS})
+            VALUES (%s, %s, %s, %s, %s::jsonb)
+            ON CONFLICT (id) DO UPDATE SET
+                content = excluded.content,
+                updated_at = excluded.updated_at,
+                properties = excluded.properties
+        """, [
+            (note.id, note.content, note.created_at, note.updated_at, json.dumps(note.properties))
+            for note in notes
+        ])
+        ids = [note.id for note in notes]
+        cursor.execute("DELETE FROM note_tags WHERE note_id = ANY(%s)", (ids,))
+        cursor.executemany(
+            "INSERT INTO note_tags (note_id, tag) VALUES (%s, %s) ON CONFLICT DO NOTHING",
+            [(note.id, tag) for note in notes for tag in note.tags]
+        )
+        cursor.execute("DELETE FROM note_links WHERE note_id = ANY(%s)", (ids,))
+        cursor.executemany(
+            "INSERT INTO note_links (note_id, target) VALUES (%s, %s) ON CONFLICT DO NOTHING",
+            [(note.id, target) for note in notes for target in link_targets(note.content)]
+        )
+        for note in notes:
+            self._record_revision(cursor, note)
+
+    def _record_revision(self, cursor, note: Note):
+        """Add a revision for the note's content unless it matches the latest one"""
+        latest = cursor.execute(
+            "SELECT rev, content FROM note_revisions WHERE note_id = %s ORDER BY rev DESC LIMIT 1",
+            (note.id,)
+        ).fetchone()
+        if latest and latest[1] == note.content:
+            return
+        cursor.execute(
+            "INSERT INTO note_revisions (note_id, rev, content, saved_at) VALUES (%s, %s, %s, %s)",
+            (note.id, latest[0] + 1 if latest else 1, note.content, note.updated_at)
+        )
+
+    def delete_note(self, note_id: str):
+        """Delete a note by ID (its index rows and revisions go with it)"""
+        self._delete_batch([note_id])
+
+    def _delete_batch(self, note_ids: List[str]):
+        """Delete several notes in one transaction"""
+        self._apply_deadline()
+        with self.conn.transaction():
+            self.conn.execute("DELETE FROM notes WHERE id = ANY(%s)", (list(note_ids),))
+        self._version = self._get_version()
+
+    def list_tags(self) -> List[str]:
+        """Get every tag in use on notes outside the trash, using the note_tags index"""
+        rows = self._execute("""
+            SELECT DISTINCT t.tag
+            FROM note_tags t
+            JOIN notes n ON n.id = t.note_id
+            WHERE NOT n.properties ? 'deleted_at'
+            ORDER BY t.tag
+        """).fetchall()
+        return [row[0] for row in rows]
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get all notes with a tag, using the note_tags index"""
+        rows = self._execute(f"""
+            SELECT {JOINED_NOTE_COLUMNS}
+            FROM notes n
+            JOIN note_tags t ON t.note_id = n.id
+            WHERE t.tag = %s
+            ORDER BY n.updated_at DESC
+        """, (Note.normalize_tag(tag),)).fetchall()
+        return [self._note_from_row(row) for row in rows]
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes that link to a note, using the note_links index"""
+        note = self.get_note(note_id)
+        if note is None or not note.title:
+            return []
+        rows = self._execute(f"""
+            SELECT {JOINED_NOTE_COLUMNS}
+            FROM notes n
+            JOIN note_links l ON l.note_id = n.id
+            WHERE l.target = %s AND n.id != %s
+            ORDER BY n.updated_at DESC
+        """, (normalize_link(note.title), note_id)).fetchall()
+        return [self._note_from_row(row) for row in rows]
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search note contents with the full-text index, ranked by ts_rank"""
+        terms = tokenize_query(query)
+        if not terms:
+            return []
+
+        # Prefix-match every term so results update while typing a word;
+        # quoting makes tsquery operators in a term literal
+        match_query = " & ".join("'" + term.replace("\\", "\\\\").replace("'", "''") + "':*" for term in terms)
+        rows = self._execute(f"""
+            SELECT {JOINED_NOTE_COLUMNS}, ts_rank(n.search, q) AS rank
+            FROM notes n, to_tsquery('simple', %s) q
+            WHERE n.search @@ q
+            ORDER BY rank DESC
+        """, (match_query,)).fetchall()
+        return [
+            SearchResult(
+                note=self._note_from_row(row),
+                snippet=build_snippet(row[1], terms),
+                rank=-row[5]  # ts_rank is higher for better matches
+            )
+            for row in rows
+        ]
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get the saved versions of a note from the note_revisions table"""
+        rows = self._execute(
+            "SELECT rev, content, saved_at FROM note_revisions WHERE note_id = %s ORDER BY rev",
+            (note_id,)
+        ).fetchall()
+        return [Revision(note_id=note_id, rev=row[0], content=row[1], saved_at=row[2]) for row in rows]
+
+    def _get_version(self) -> int:
+        """Get the counter every change to the notes table bumps"""
+        return self._execute("SELECT last_value FROM notes_version").fetchone()[0]
+
+    def poll_changes(self) -> bool:
+        """Check whether another editor changed the notes since the last check"""
+        try:
+            version = self._get_version()
+        except self._errors:
+            return False  # Connection trouble; try again on the next check
+        changed = version != self._version
+        self._version = version
+        return changed
+
+    def close(self):
+        """Close the database connection"""
+        self.conn.close()
+
@@ -0,0 +1,133 @@
+"""
+Read-only storage backend that wraps another backend
+"""
+
+from typing import List, Optional
+from datetime import datetime
+from .base import StorageBackend
+from ..attachments import Attachment
+from ..history import Revision
+from ..note import Note, NoteSummary
+from ..search import SearchResult
+from ..i18n import t
+
+
+class ReadOnlyStorage(RuntimeError):
+    """A change was asked of storage opened read-only"""
+
+    def __init__(self):
+        """Initialize with the message shown to the user"""
+        super().__init__(t("storage.read_only"))
+
+
+class ReadOnlyBackend(StorageBackend):
+    """
+    Storage backend wrapper that lets notes be read but never changed
+
+    Reads go to the wrapped backend, so other programs' changes (a synced
+    directory, another editor) still show up. Every way of creating,
+    changing or deleting a note raises ReadOnlyStorage before anything is
+    written, including attached files; the UI hides the keys that would
+    try, and shows the error for anything else.
+    """
+
+    def __init__(self, backend: StorageBackend):
+        """
+        Initialize read-only backend
+
+        Args:
+            backend: Underlying storage backend to wrap
+        """
+        super().__init__()
+        self.backend = backend
+        self.attachments_dir = backend.attachments_dir
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes from the wrapped backend"""
+        return self.backend.get_all_notes()
+
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """Get a page of notes to list from the wrapped backend"""
+        return self.backend.list_note_summaries(offset, limit)
+
+    def count_notes(self) -> int:
+        """Count the notes in the wrapped backend"""
+        return self.backend.count_notes()
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a note from the wrapped backend"""
+        return self.backend.get_note(note_id)
+
+    def list_tags(self) -> List[str]:
+        """Get every tag in use from the wrapped backend"""
+        return self.backend.list_tags()
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get the notes with a tag from the wrapped backend"""
+        return self.backend.get_notes_by_tag(tag)
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes linking to a note from the wrapped backend"""
+        return self.backend.get_backlinks(note_id)
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search the wrapped backend"""
+        return self.backend.search_notes(query)
+
+    @property
+    def supports_revisions(self) -> bool:
+        """Whether the wrapped backend keeps revisions, which can be viewed but not restored"""
+        return self.backend.supports_revisions
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get a note's revisions from the wrapped backend"""
+        return self.backend.list_revisions(note_id)
+
+    def save_note(self, note: Note):
+        """Refuse to save a note"""
+        raise ReadOnlyStorage()
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Refuse to add notes"""
+        raise ReadOnlyStorage()
+
+    def delete_note(self, note_id: str):
+        """Refuse to delete a note"""
+        raise ReadOnlyStorage()
+
+    def _delete_batch(self, note_ids: List[str]):
+        """Refuse to delete notes"""
+        raise ReadOnlyStorage()
+
+    def create_notebook(self, path: str) -> str:
+        """Refuse to create a notebook"""
+        raise ReadOnlyStorage()
+
+    def add_attachment(self, note_id: str, source: str) -> Optional[Attachment]:
+        """Refuse to attach a file, before copying it"""
+        raise ReadOnlyStorage()
+
+    def remove_attachment(self, note_id: str, name: str) -> Optional[Note]:
+        """Refuse to remove an attached file, before deleting it"""
+        raise ReadOnlyStorage()
+
+    def delete_attachments(self, note_id: str):
+        """Refuse to delete attached files"""
+        raise ReadOnlyStorage()
+
+    def pop_due_reminders(self, now: Optional[datetime] = None) -> List[Note]:
+        """
+        Get no reminders: showing one clears it from its note, which would be a change
+
+        Returns:
+            An empty list
+        """
+        return []
+
+    def poll_changes(self) -> bool:
+        """Check whether the wrapped backend's notes changed"""
+        return self.backend.poll_changes()
+
+    def close(self):
+        """Close the wrapped backend"""
+        self.backend.close()
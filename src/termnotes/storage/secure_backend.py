@@ -0,0 +1,309 @@
+"""
+Secure notes storage backend that wraps another backend
+"""
+
+import dataclasses
+from pathlib import Path
+from typing import Collection, List, Optional
+from .base import NoteConflict, StorageBackend
+from ..history import Revision
+from ..note import Note, NoteSummary
+from ..replace import Replacement
+from ..search import SearchResult, build_snippet, count_matches, tokenize_query
+from ..secure import SECURE_PROPERTY, SecureNoteError, SecureNoteLocked, SecureVault, is_sealed
+from ..i18n import t
+
+
+class SecureBackend(StorageBackend):
+    """
+    Storage backend wrapper that encrypts and decrypts secure notes
+
+    Notes with the "secure" property reach the wrapped backend as armored
+    blocks (see secure.py), so its caches, search index and revisions never
+    hold their text. While the vault is unlocked they're decrypted on the way
+    out; while it's locked they pass through as blocks, the note list shows
+    them locked, search leaves them out, and saving one with changed text
+    raises SecureNoteLocked.
+
+    The passphrase is checked against the verifier file when there is one,
+    or else against a sealed note, and the file is written once it passes.
+    """
+
+    def __init__(self, backend: StorageBackend, verifier_path: Optional[Path] = None):
+        """
+        Initialize secure notes backend, locked
+
+        Args:
+            backend: Underlying storage backend to wrap
+            verifier_path: File checking the passphrase (None: only sealed notes check it)
+        """
+        super().__init__()
+        self.backend = backend
+        self.verifier_path = verifier_path
+        self.vault = SecureVault()
+        self.attachments_dir = backend.attachments_dir
+        self.first_run = backend.first_run
+
+    def _open(self, note: Optional[Note]) -> Optional[Note]:
+        """Get a stored note with its text, if it's a secure note the vault can open"""
+        if note is None or not self.vault.unlocked or not note.is_locked:
+            return note
+        try:
+            content = self.vault.open(note.id, note.content)
+        except SecureNoteError:
+            return note  # Sealed with another passphrase, or damaged: it stays locked
+        return Note(note.id, content, note.created_at, note.updated_at, dict(note.properties))
+
+    def _seal(self, note: Note) -> Note:
+        """
+        Get the form of a note to store in the wrapped backend
+
+        Raises:
+            SecureNoteLocked: If it's a secure note with text to encrypt, but the vault is locked
+        """
+        if not note.is_secure or is_sealed(note.content):
+            return note
+        if not self.vault.unlocked:
+            raise SecureNoteLocked()
+        return Note(note.id, self.vault.seal(note.id, note.content), note.created_at, note.updated_at,
+                    dict(note.properties))
+
+    def unlock(self, passphrase: str) -> bool:
+        """
+        Unlock the secure notes with a passphrase
+
+        The verifier checks it, or any sealed note if there's no verifier
+        yet; with neither, the passphrase is a new one. The verifier is
+        written once it passes.
+
+        Args:
+            passphrase: The passphrase typed
+
+        Returns:
+            False if it's wrong (the vault stays locked)
+        """
+        self.vault.unlock(passphrase)
+        verifier = self._read_verifier()
+        if verifier is not None:
+            if self.vault.verify(verifier):
+                return True
+            self.vault.lock()
+            return False
+        sealed = next((note for note in self.backend.get_all_notes() if note.is_locked), None)
+        if sealed is not None:
+            try:
+                self.vault.open(sealed.id, sealed.content)
+            except SecureNoteError:
+                self.vault.lock()
+                return False
+        self._write_verifier()
+        return True
+
+    def _read_verifier(self) -> Optional[str]:
+        """Get the verifier block, or None if there's none to read"""
+        if self.verifier_path is None:
+            return None
+        try:
+            return self.verifier_path.read_text(encoding="utf-8")
+        except FileNotFoundError:
+            return None
+
+    def _write_verifier(self):
+        """Store a verifier for the passphrase just unlocked with, readable only by the user"""
+        if self.verifier_path is None:
+            return
+        self.verifier_path.parent.mkdir(parents=True, exist_ok=True)
+        temp_path = self.verifier_path.with_name(self.verifier_path.name + ".tmp")
+        temp_path.write_text(self.vault.make_verifier(), encoding="utf-8")
+        temp_path.chmod(0o600)
+        temp_path.replace(self.verifier_path)
+
+    def has_secure_notes(self) -> bool:
+        """Check whether any note is secure, or a passphrase was chosen, so there's a passphrase to unlock"""
+        if self._read_verifier() is not None:
+            return True
+        return any(note.is_secure for note in self.backend.get_all_notes())
+
+    def set_secure(self, note_id: str, secure: bool) -> Optional[Note]:
+        """
+        Encrypt a note at rest as a secure note, or store it as plain text again
+
+        Args:
+            note_id: ID of the note
+            secure: Whether the note should be secure
+
+        Returns:
+            The updated note, or None if the note doesn't exist
+
+        Raises:
+            SecureNoteLocked: If the vault is locked
+        """
+        if not self.vault.unlocked:
+            raise SecureNoteLocked()
+        with self.journal_operation("secure" if secure else "unsecure", [note_id]):
+            note = self.get_note(note_id)
+            if note is None:
+                return None
+            if note.is_secure != secure:
+                if note.is_locked:
+                    raise SecureNoteError(t("secure.wrong_passphrase"))
+                if secure:
+                    note.set_property(SECURE_PROPERTY, True)
+                else:
+                    note.delete_property(SECURE_PROPERTY)
+                self.save_note(note)
+            return note
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes, decrypting secure notes while unlocked"""
+        return [self._open(note) for note in self.backend.get_all_notes()]
+
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """Get a page of notes to list, summarizing secure notes from their text while unlocked"""
+        summaries = self.backend.list_note_summaries(offset, limit)
+        if not self.vault.unlocked:
+            return summaries
+        result = []
+        for summary in summaries:
+            note = self.get_note(summary.id) if summary.is_secure else None
+            result.append(NoteSummary.of(note) if note is not None else summary)
+        return result
+
+    def count_notes(self) -> int:
+        """Count the notes in the wrapped backend"""
+        return self.backend.count_notes()
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a note, decrypting it if it's secure and the vault is unlocked"""
+        return self._open(self.backend.get_note(note_id))
+
+    def save_note(self, note: Note):
+        """Save a note, encrypting it first if it's secure"""
+        self.backend.save_note(self._seal(note))
+
+    def update_note(self, note: Note, expected_version: Optional[str]):
+        """
+        Save a note unless it changed since expected_version
+
+        The version of a secure note is that of its text, which the wrapped
+        backend doesn't know, so it's checked here against the stored note's
+        text, then the wrapped backend checks the block still is that one.
+        """
+        if not note.is_secure or expected_version is None:
+            self.backend.update_note(self._seal(note), expected_version)
+            return
+        stored = self.backend.get_note(note.id)
+        if stored is not None and self._open(stored).version != expected_version:
+            raise NoteConflict(self._open(stored))
+        try:
+            self.backend.update_note(self._seal(note), stored.version if stored is not None else None)
+        except NoteConflict as e:
+            raise NoteConflict(self._open(e.stored))
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Encrypt secure notes and store the notes in the wrapped backend together"""
+        return self.backend.import_notes([self._seal(note) for note in notes])
+
+    def delete_note(self, note_id: str):
+        """Delete a note from the wrapped backend"""
+        self.backend.delete_note(note_id)
+
+    def _delete_batch(self, note_ids: List[str]):
+        """Delete notes from the wrapped backend"""
+        self.backend._delete_batch(note_ids)
+
+    def list_tags(self) -> List[str]:
+        """Get every tag in use from the wrapped backend (tags aren't encrypted)"""
+        return self.backend.list_tags()
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get the notes with a tag, decrypting secure notes while unlocked"""
+        return [self._open(note) for note in self.backend.get_notes_by_tag(tag)]
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes linking to a note; while unlocked, secure notes are scanned for links too"""
+        if self.vault.unlocked:
+            return super().get_backlinks(note_id)
+        return self.backend.get_backlinks(note_id)
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """
+        Search the wrapped backend's index, which never holds secure notes' text
+
+        While unlocked, secure notes are searched here as well, by scanning
+        their text.
+        """
+        results = [result for result in self.backend.search_notes(query) if not result.note.is_secure]
+        terms = tokenize_query(query)
+        if not self.vault.unlocked or not terms:
+            return results
+        for note in self.get_all_notes():
+            matches = count_matches(note.content, terms) if note.is_secure and not note.is_locked else 0
+            if matches:
+                results.append(SearchResult(note=note, snippet=build_snippet(note.content, terms),
+                                            rank=-float(matches)))
+        results.sort(key=lambda result: result.rank)
+        return results
+
+    def replace_all(
+        self,
+        pattern: str,
+        replacement: str,
+        regex: bool = False,
+        dry_run: bool = False,
+        note_ids: Optional[Collection[str]] = None
+    ) -> List[Replacement]:
+        """Replace text in every note outside the trash, leaving locked secure notes' blocks alone"""
+        unlocked = {note.id for note in self.get_all_notes() if not note.is_locked}
+        if note_ids is not None:
+            unlocked &= set(note_ids)
+        return super().replace_all(pattern, replacement, regex, dry_run, unlocked)
+
+    @property
+    def supports_revisions(self) -> bool:
+        """Whether the wrapped backend keeps revisions"""
+        return self.backend.supports_revisions
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get a note's revisions from the wrapped backend, decrypting a secure note's while unlocked"""
+        revisions = self.backend.list_revisions(note_id)
+        if not self.vault.unlocked:
+            return revisions
+        opened = []
+        for revision in revisions:
+            if is_sealed(revision.content):
+                try:
+                    revision = dataclasses.replace(revision, content=self.vault.open(note_id, revision.content))
+                except SecureNoteError:
+                    pass  # Sealed with an earlier passphrase
+            opened.append(revision)
+        return opened
+
+    def notes_at_rest(self) -> List[Note]:
+        """Get the notes as the wrapped backend stores them, secure notes encrypted"""
+        return self.backend.notes_at_rest()
+
+    @property
+    def supports_sync(self) -> bool:
+        """Whether the wrapped backend syncs with a server"""
+        return self.backend.supports_sync
+
+    def sync(self) -> int:
+        """Sync the wrapped backend"""
+        return self.backend.sync()
+
+    def pending_sync(self) -> int:
+        """Count the wrapped backend's changes waiting to be pushed"""
+        return self.backend.pending_sync()
+
+    def poll_changes(self) -> bool:
+        """Check whether the wrapped backend's notes changed"""
+        return self.backend.poll_changes()
+
+    def flush(self):
+        """Write the wrapped backend's held-back changes"""
+        self.backend.flush()
+
+    def close(self):
+        """Close the wrapped backend"""
+        self.backend.close()
@@ -5,15 +5,85 @@ SQLite-based note storage backend
 import json
 import sqlite3
 from pathlib import Path
-from typing import List, Optional
+from typing import Callable, List, Optional
 from datetime import datetime
-from .base import StorageBackend
+from .base import StorageBackend, synchronized
+from .context import current_context
+from ..i18n import t
+from ..log import event, get_logger
 from ..utils import utc_now
-from ..note import Note
+from ..note import SUMMARY_LENGTH, Note, NoteSummary
+from ..history import Revision
+from ..links import link_targets, normalize_link
+from ..search import HIGHLIGHT_END, HIGHLIGHT_START, SNIPPET_ELLIPSIS, SearchResult, tokenize_query
+
+log = get_logger("storage")
+
+# SQLite virtual machine steps between checks of the context in use
+PROGRESS_STEPS = 1000
+
+
+def _create_tables(cursor: sqlite3.Cursor):
+    """Create the notes, note_tags, note_links and note_revisions tables"""
+    # IF NOT EXISTS: databases from before schema_version already have them
+    cursor.execute("""
+        CREATE TABLE IF NOT EXISTS notes (
+            id TEXT PRIMARY KEY,
+            content TEXT NOT NULL,
+            created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            properties TEXT DEFAULT '{}'
+        )
+    """)
+    # Tags live in the note's properties; this join table indexes them
+    cursor.execute("""
+        CREATE TABLE IF NOT EXISTS note_tags (
+            note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+            tag TEXT NOT NULL,
+            PRIMARY KEY (note_id, tag)
+        )
+    """)
+    cursor.execute("CREATE INDEX IF NOT EXISTS idx_note_tags_tag ON note_tags(tag)")
+    # [[title]] links in each note's content, by normalized target title
+    cursor.execute("""
+        CREATE TABLE IF NOT EXISTS note_links (
+            note_id TEXT NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+            target TEXT NOT NULL,
+            PRIMARY KEY (note_id, target)
+        )
+    """)
+    cursor.execute("CREATE INDEX IF NOT EXISTS idx_note_links_target ON note_links(target)")
+    # Every saved version of each note's content, numbered per note
+    cursor.execute("""
+        CREATE TABLE IF NOT EXISTS note_revisions (
+            note_id TEXT NOT NULL,
+            rev INTEGER NOT NULL,
+            content TEXT NOT NULL,
+            saved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+            PRIMARY KEY (note_id, rev)
+        )
+    """)
+
+
+def _index_updated_at(cursor: sqlite3.Cursor):
+    """Index the notes by when they were last updated, the order they're listed in"""
+    cursor.execute("CREATE INDEX IF NOT EXISTS idx_notes_updated_at ON notes(updated_at DESC)")
+
+
+# Schema changes, applied in order to bring a database up to date. The
+# schema_version table records how many have been applied; add new
+# migrations at the end and never edit one that has been released. Each
+# runs in the same transaction as the others, so it must not commit.
+MIGRATIONS: List[Callable[[sqlite3.Cursor], None]] = [
+    _create_tables,     # 1: notes and the tag, link and revision indexes
+    _index_updated_at,  # 2: the note list's order
+]
 
 
 class SQLiteBackend(StorageBackend):
-    """SQLite implementation of storage backend"""
+    """SQLite implementation of storage backend, safe to share between threads"""
+
+    supports_revisions = True
 
     def __init__(self, db_path: str = ":memory:"):
         """
@@ -22,6 +92,7 @@ class SQLiteBackend(StorageBackend):
         Args:
             db_path: Path to SQLite database file, or ":memory:" for in-memory DB
         """
+        super().__init__()
         self.db_path = db_path
 
         # Create parent directory if path is not in-memory
@@ -29,23 +100,117 @@ class SQLiteBackend(StorageBackend):
             db_file = Path(db_path)
             db_file.parent.mkdir(parents=True, exist_ok=True)
 
-        self.conn = sqlite3.connect(db_path)
-        self._create_tables()
+        # Shared between threads under the backend's lock (see synchronized)
+        self.conn = sqlite3.connect(db_path, check_same_thread=False)
+        # A cancelled or expired context interrupts a running query; writes
+        # (inside a transaction) finish, so a save isn't left half done
+        self.conn.set_progress_handler(
+            lambda: not self.conn.in_transaction and current_context().done, PROGRESS_STEPS
+        )
+        if db_path != ":memory:":
+            # Write-ahead logging: a save appends to the log instead of rewriting
+            # pages, and a crash mid-save leaves the database intact
+            self.conn.execute("PRAGMA journal_mode=WAL")
+            self.conn.execute("PRAGMA synchronous=NORMAL")
+        self._migrate()
+        self._rebuild_indexes()
+        self.fts_enabled = self._create_fts_index()
+        self._data_version = self._get_data_version()
 
-    def _create_tables(self):
-        """Create the notes table if it doesn't exist"""
+    def _migrate(self):
+        """
+        Apply the migrations the database doesn't have yet, in one transaction
+
+        BEGIN IMMEDIATE takes the write lock first, so two editors opening
+        the same file at once don't both apply a migration.
+
+        Raises:
+            RuntimeError: If a newer termnotes set up the database
+        """
         cursor = self.conn.cursor()
+        cursor.execute("BEGIN IMMEDIATE")
+        try:
+            cursor.execute("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)")
+            cursor.execute("SELECT version FROM schema_version")
+            row = cursor.fetchone()
+            if row is None:
+                cursor.execute("INSERT INTO schema_version (version) VALUES (0)")
+                version = 0
+            else:
+                version = row[0]
+            if version > len(MIGRATIONS):
+                raise RuntimeError(t("storage.sqlite_newer_schema", path=self.db_path, version=version))
+            for migration in MIGRATIONS[version:]:
+                migration(cursor)
+            cursor.execute("UPDATE schema_version SET version = ?", (len(MIGRATIONS),))
+        except BaseException:
+            self.conn.rollback()
+            raise
+        self.conn.commit()
+        if version < len(MIGRATIONS):
+            event(log, "migrate", path=self.db_path, version=version, to=len(MIGRATIONS))
+
+    def _create_fts_index(self) -> bool:
+        """
+        Create the FTS5 full-text index over note contents
+
+        The index is an external-content table kept in sync with the notes
+        table by triggers. It's rebuilt on startup since it is keyed by the
+        notes table's implicit rowid, which VACUUM may renumber.
+
+        Returns:
+            True if the index is available, False if SQLite lacks FTS5
+        """
+        cursor = self.conn.cursor()
+        try:
+            cursor.execute("""
+                CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
+                    content, content='notes', content_rowid='rowid'
+                )
+            """)
+        except sqlite3.OperationalError:
+            return False
+
         cursor.execute("""
-            CREATE TABLE IF NOT EXISTS notes (
-                id TEXT PRIMARY KEY,
-                content TEXT NOT NULL,
-                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-                updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-                properties TEXT DEFAULT '{}'
-            )
+            CREATE TRIGGER IF NOT EXISTS notes_fts_insert AFTER INSERT ON notes BEGIN
+                INSERT INTO notes_fts (rowid, content) VALUES (new.rowid, new.content);
+            END
+        """)
+        cursor.execute("""
+            CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+                INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+            END
+        """)
+        cursor.execute("""
+            CREATE TRIGGER IF NOT EXISTS notes_fts_update AFTER UPDATE OF content ON notes BEGIN
+                INSERT INTO notes_fts (notes_fts, rowid, content) VALUES ('delete', old.rowid, old.content);
+                INSERT INTO notes_fts (rowid, content) VALUES (new.rowid, new.content);
+            END
         """)
+        cursor.execute("INSERT INTO notes_fts (notes_fts) VALUES ('rebuild')")
+        self.conn.commit()
+        return True
+
+    def _rebuild_indexes(self):
+        """Rebuild the note_tags and note_links tables from note properties and contents"""
+        cursor = self.conn.cursor()
+        cursor.execute("SELECT id, content, properties FROM notes")
+        rows = cursor.fetchall()
+        cursor.execute("DELETE FROM note_tags")
+        cursor.execute("DELETE FROM note_links")
+        for note_id, content, props_str in rows:
+            tags = self._parse_properties(props_str).get("tags", [])
+            cursor.executemany(
+                "INSERT OR IGNORE INTO note_tags (note_id, tag) VALUES (?, ?)",
+                [(note_id, tag) for tag in tags]
+            )
+            cursor.executemany(
+                "INSERT OR IGNORE INTO note_links (note_id, target) VALUES (?, ?)",
+                [(note_id, target) for target in link_targets(content)]
+            )
         self.conn.commit()
 
+    @synchronized
     def get_all_notes(self) -> List[Note]:
         """Get all notes from the database"""
         cursor = self.conn.cursor()
@@ -66,6 +231,33 @@ class SQLiteBackend(StorageBackend):
             for row in rows
         ]
 
+    @synchronized
+    def list_note_summaries(self, offset: int = 0, limit: Optional[int] = None) -> List[NoteSummary]:
+        """Get a page of notes with only the start of their content"""
+        cursor = self.conn.cursor()
+        cursor.execute("""
+            SELECT id, substr(content, 1, ?), created_at, updated_at, properties
+            FROM notes
+            ORDER BY updated_at DESC
+            LIMIT ? OFFSET ?
+        """, (SUMMARY_LENGTH, -1 if limit is None else limit, offset))
+        return [
+            NoteSummary(
+                note_id=row[0],
+                content=row[1],
+                created_at=self._parse_timestamp(row[2]),
+                updated_at=self._parse_timestamp(row[3]),
+                properties=self._parse_properties(row[4])
+            )
+            for row in cursor.fetchall()
+        ]
+
+    @synchronized
+    def count_notes(self) -> int:
+        """Count the notes in the database"""
+        return self.conn.execute("SELECT COUNT(*) FROM notes").fetchone()[0]
+
+    @synchronized
     def get_note(self, note_id: str) -> Optional[Note]:
         """Get a specific note by ID"""
         cursor = self.conn.cursor()
@@ -84,6 +276,7 @@ class SQLiteBackend(StorageBackend):
             )
         return None
 
+    @synchronized
     def save_note(self, note: Note):
         """Save or update a note"""
         cursor = self.conn.cursor()
@@ -96,14 +289,221 @@ class SQLiteBackend(StorageBackend):
                 updated_at = CURRENT_TIMESTAMP,
                 properties = excluded.properties
         """, (note.id, note.content, note.created_at, properties_json))
+        cursor.execute("DELETE FROM note_tags WHERE note_id = ?", (note.id,))
+        cursor.executemany(
+            "INSERT OR IGNORE INTO note_tags (note_id, tag) VALUES (?, ?)",
+            [(note.id, tag) for tag in note.tags]
+        )
+        cursor.execute("DELETE FROM note_links WHERE note_id = ?", (note.id,))
+        cursor.executemany(
+            "INSERT OR IGNORE INTO note_links (note_id, target) VALUES (?, ?)",
+            [(note.id, target) for target in link_targets(note.content)]
+        )
+        self._record_revision(note)
         self.conn.commit()
 
+    @synchronized
+    def import_notes(self, notes: List[Note]) -> int:
+        """Insert notes in a single transaction, keeping their timestamps"""
+        cursor = self.conn.cursor()
+        cursor.executemany("""
+            INSERT INTO notes (id, content, created_at, updated_at, properties)
+            VALUES (?, ?, ?, ?, ?)
+            ON CONFLICT(id) DO UPDATE SET
+                content = excluded.content,
+                updated_at = excluded.updated_at,
+                properties = excluded.properties
+        """, [
+            (note.id, note.content, note.created_at, note.updated_at, json.dumps(note.properties))
+            for note in notes
+        ])
+        cursor.executemany(
+            "DELETE FROM note_tags WHERE note_id = ?",
+            [(note.id,) for note in notes]
+        )
+        cursor.executemany(
+            "INSERT OR IGNORE INTO note_tags (note_id, tag) VALUES (?, ?)",
+            [(note.id, tag) for note in notes for tag in note.tags]
+        )
+        cursor.executemany(
+            "DELETE FROM note_links WHERE note_id = ?",
+            [(note.id,) for note in notes]
+        )
+        cursor.executemany(
+            "INSERT OR IGNORE INTO note_links (note_id, target) VALUES (?, ?)",
+            [(note.id, target) for note in notes for target in link_targets(note.content)]
+        )
+        for note in notes:
+            self._record_revision(note)
+        self.conn.commit()
+        return len(notes)
+
+    def _record_revision(self, note: Note):
+        """Add a revision for the note's content unless it matches the latest one"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            "SELECT rev, content FROM note_revisions WHERE note_id = ? ORDER BY rev DESC LIMIT 1",
+            (note.id,)
+        )
+        latest = cursor.fetchone()
+        if latest and latest[1] == note.content:
+            return
+        cursor.execute(
+            "INSERT INTO note_revisions (note_id, rev, content) VALUES (?, ?, ?)",
+            (note.id, latest[0] + 1 if latest else 1, note.content)
+        )
+
+    @synchronized
     def delete_note(self, note_id: str):
         """Delete a note by ID"""
         cursor = self.conn.cursor()
+        cursor.execute("DELETE FROM note_tags WHERE note_id = ?", (note_id,))
+        cursor.execute("DELETE FROM note_links WHERE note_id = ?", (note_id,))
+        cursor.execute("DELETE FROM note_revisions WHERE note_id = ?", (note_id,))
         cursor.execute("DELETE FROM notes WHERE id = ?", (note_id,))
         self.conn.commit()
 
+    @synchronized
+    def _delete_batch(self, note_ids: List[str]):
+        """Delete several notes in one transaction"""
+        rows = [(note_id,) for note_id in note_ids]
+        cursor = self.conn.cursor()
+        cursor.executemany("DELETE FROM note_tags WHERE note_id = ?", rows)
+        cursor.executemany("DELETE FROM note_links WHERE note_id = ?", rows)
+        cursor.executemany("DELETE FROM note_revisions WHERE note_id = ?", rows)
+        cursor.executemany("DELETE FROM notes WHERE id = ?", rows)
+        self.conn.commit()
+
+    @synchronized
+    def list_tags(self) -> List[str]:
+        """Get every tag in use on notes outside the trash, using the note_tags index"""
+        cursor = self.conn.cursor()
+        cursor.execute("""
+            SELECT DISTINCT t.tag
+            FROM note_tags t
+            JOIN notes n ON n.id = t.note_id
+            WHERE json_type(n.properties, '$.deleted_at') IS NULL
+            ORDER BY t.tag
+        """)
+        return [row[0] for row in cursor.fetchall()]
+
+    @synchronized
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get all notes with a tag, using the note_tags index"""
+        cursor = self.conn.cursor()
+        cursor.execute("""
+            SELECT n.id, n.content, n.created_at, n.updated_at, n.properties
+            FROM notes n
+            JOIN note_tags t ON t.note_id = n.id
+            WHERE t.tag = ?
+            ORDER BY n.updated_at DESC
+        """, (Note.normalize_tag(tag),))
+        return [
+            Note(
+                note_id=row[0],
+                content=row[1],
+                created_at=self._parse_timestamp(row[2]),
+                updated_at=self._parse_timestamp(row[3]),
+                properties=self._parse_properties(row[4])
+            )
+            for row in cursor.fetchall()
+        ]
+
+    @synchronized
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes that link to a note, using the note_links index"""
+        note = self.get_note(note_id)
+        if note is None or not note.title:
+            return []
+        cursor = self.conn.cursor()
+        cursor.execute("""
+            SELECT n.id, n.content, n.created_at, n.updated_at, n.properties
+            FROM notes n
+            JOIN note_links l ON l.note_id = n.id
+            WHERE l.target = ? AND n.id != ?
+            ORDER BY n.updated_at DESC
+        """, (normalize_link(note.title), note_id))
+        return [
+            Note(
+                note_id=row[0],
+                content=row[1],
+                created_at=self._parse_timestamp(row[2]),
+                updated_at=self._parse_timestamp(row[3]),
+                properties=self._parse_properties(row[4])
+            )
+            for row in cursor.fetchall()
+        ]
+
+    @synchronized
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search note contents using the FTS5 index, ranked by BM25"""
+        if not self.fts_enabled:
+            return super().search_notes(query)
+
+        terms = tokenize_query(query)
+        if not terms:
+            return []
+
+        # Quote each term so FTS5 syntax characters are taken literally,
+        # and match as prefixes so results update while typing a word
+        match_query = " ".join(f'"{term}"*' for term in terms)
+        cursor = self.conn.cursor()
+        cursor.execute("""
+            SELECT n.id, n.content, n.created_at, n.updated_at, n.properties,
+                   snippet(notes_fts, 0, ?, ?, ?, 10), notes_fts.rank
+            FROM notes_fts
+            JOIN notes n ON n.rowid = notes_fts.rowid
+            WHERE notes_fts MATCH ?
+            ORDER BY notes_fts.rank
+        """, (HIGHLIGHT_START, HIGHLIGHT_END, SNIPPET_ELLIPSIS, match_query))
+        return [
+            SearchResult(
+                note=Note(
+                    note_id=row[0],
+                    content=row[1],
+                    created_at=self._parse_timestamp(row[2]),
+                    updated_at=self._parse_timestamp(row[3]),
+                    properties=self._parse_properties(row[4])
+                ),
+                snippet=" ".join(row[5].split()),
+                rank=row[6]
+            )
+            for row in cursor.fetchall()
+        ]
+
+    @synchronized
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get the saved versions of a note from the note_revisions table"""
+        cursor = self.conn.cursor()
+        cursor.execute(
+            "SELECT rev, content, saved_at FROM note_revisions WHERE note_id = ? ORDER BY rev",
+            (note_id,)
+        )
+        return [
+            Revision(
+                note_id=note_id,
+                rev=row[0],
+                content=row[1],
+                saved_at=self._parse_timestamp(row[2])
+            )
+            for row in cursor.fetchall()
+        ]
+
+    def _get_data_version(self) -> int:
+        """Get SQLite's counter of commits made through other connections"""
+        return self.conn.execute("PRAGMA data_version").fetchone()[0]
+
+    @synchronized
+    def poll_changes(self) -> bool:
+        """Check whether another process committed to the database file since the last check"""
+        if self.db_path == ":memory:":
+            return False
+        version = self._get_data_version()
+        changed = version != self._data_version
+        self._data_version = version
+        return changed
+
+    @synchronized
     def close(self):
         """Close the database connection"""
         self.conn.close()
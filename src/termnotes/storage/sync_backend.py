@@ -0,0 +1,574 @@
+"""
+Sync storage backend: a local store kept in sync with a termnotes sync server
+"""
+
+import json
+import sys
+import threading
+import urllib.error
+import urllib.request
+from pathlib import Path
+from typing import Callable, Dict, List, Optional, Tuple
+from urllib.parse import quote
+from .base import StorageBackend, check_note_id
+from .context import current_context
+from ..history import Revision
+from ..note import Note
+from ..search import SearchResult
+from ..sync.e2e import E2EError, SyncKeys, list_devices
+from ..sync.protocol import API_PREFIX, SyncError, note_from_dict, note_to_dict
+from ..i18n import t
+from ..log import event, get_logger, timed
+
+log = get_logger("storage.sync")
+
+
+class SyncBackend(StorageBackend):
+    """
+    Storage backend that keeps notes in a local backend and syncs them with a server
+
+    Reads and writes go to the local backend, so termnotes works offline.
+    Each change is queued and pushed to the server by a worker thread right
+    after, so saving doesn't wait for the network; if the server can't be
+    reached, the change stays queued for the next sync and the failure is
+    reported through on_sync_error. Changes from other machines are pulled
+    with sync(), which create_default_storage() calls when the notes are
+    opened (the editor calls it in the background once it runs).
+
+    The server refuses a write if the note changed there since this machine
+    last saw it. The server's version then replaces the local one, and the
+    local edits are kept as a new note tagged "conflict" so nothing is lost.
+    A note deleted on one machine and edited on another is kept.
+
+    Sync state (the pull cursor, the server's updated_at for each note and
+    the queue of unpushed changes) is kept in a JSON file. Changes to it are
+    appended to a journal next to the file, so a save doesn't rewrite the
+    state of every note; the journal is folded into the file after each sync,
+    on close, and once it grows past COMPACT_AFTER entries.
+
+    With keys (end-to-end encryption, see sync/e2e.py), notes are encrypted
+    before they're pushed and decrypted when pulled. The passphrase may only
+    be asked for while the backend is created, before the editor runs, so
+    the keyring is fetched then.
+
+    Safe to share between threads: whatever talks to the server holds
+    sync_lock, and the sync state is only changed under lock, never held
+    across a request, so saving doesn't wait for a push or sync in progress.
+    """
+
+    supports_sync = True
+
+    # Journal entries after which the state file is rewritten
+    COMPACT_AFTER = 500
+
+    # Tries at storing the keyring when other devices keep changing it
+    KEYRING_ATTEMPTS = 3
+
+    def __init__(self, local: StorageBackend, url: str, state_path: str, token: str = "", timeout: float = 10,
+                 keys: Optional[SyncKeys] = None, ask_passphrase: Optional[Callable[[bool], str]] = None):
+        """
+        Initialize sync backend, unlocking the end-to-end encryption keys if there are any
+
+        Args:
+            local: Backend holding the local copy of the notes
+            url: Base URL of the sync server (e.g. "http://notes.example.com:8765")
+            state_path: Path to the JSON file for sync state
+            token: Token the server requires ("" for none)
+            timeout: Seconds to wait for the server
+            keys: This device's end-to-end encryption keys (None to sync notes as they are)
+            ask_passphrase: Asks for the end-to-end encryption passphrase
+                (see SyncKeys.unlock); None if it can't be asked for
+        """
+        super().__init__()
+        self.local = local
+        self.url = url.rstrip("/")
+        self.token = token
+        self.timeout = timeout
+        self.keys = keys
+        self._ask_passphrase = ask_passphrase
+        self.state_path = Path(state_path)
+        self.journal_path = self.state_path.with_name(self.state_path.name + ".log")
+
+        self._cursor = 0
+        self._base: Dict[str, str] = {}  # Note ID to the server's updated_at of the last synced version
+        self._pending: Dict[str, str] = {}  # Note ID to "save" or "delete" not yet pushed
+        self._changed = 0
+        self._journal_entries = 0
+        self._synced = False  # sync() has pulled from the server during this run
+        self._load_state()
+
+        self.sync_lock = threading.RLock()  # Held while talking to the server
+        self.on_sync_error: Callable[[str], None] = self.print_sync_error  # Told when a queued push fails
+        self._versions: Dict[str, int] = {}  # Note ID to changes queued this run, to spot one made during its push
+        self._push_timer: Optional[threading.Timer] = None
+        self._timer_lock = threading.Lock()
+        self._failing = False  # The last push failed; reported once until one succeeds
+
+        if self.keys is not None and ask_passphrase is not None:
+            try:
+                self._unlock()
+            except SyncError as e:
+                print(t("storage.sync_failed", error=e))
+        self._ask_passphrase = None  # The editor is about to take over the terminal
+
+    def _load_state(self):
+        """Read the sync state file, if there is one, and replay the journal over it"""
+        try:
+            with open(self.state_path, "r", encoding="utf-8") as f:
+                state = json.load(f)
+            self._cursor = state.get("cursor", 0)
+            self._base = state.get("base", {})
+            self._pending = state.get("pending", {})
+        except (OSError, json.JSONDecodeError):
+            pass
+
+        try:
+            with open(self.journal_path, "r", encoding="utf-8") as f:
+                lines = f.readlines()
+        except OSError:
+            return
+        for line in lines:
+            try:
+                entry = json.loads(line)
+            except json.JSONDecodeError:
+                break  # Cut short by a crash; later entries can't exist
+            self._apply(entry)
+            self._journal_entries += 1
+
+    def _apply(self, entry: dict):
+        """Apply a journal entry to the in-memory state"""
+        if "cursor" in entry:
+            self._cursor = entry["cursor"]
+        for field, values in (("base", self._base), ("pending", self._pending)):
+            if field in entry:
+                note_id, value = entry[field]
+                if value is None:
+                    values.pop(note_id, None)
+                else:
+                    values[note_id] = value
+
+    def _record(self, **entry):
+        """
+        Change the sync state and append the change to the journal
+
+        Args:
+            entry: One of cursor=<seq>, base=[note_id, updated_at or None],
+                or pending=[note_id, "save", "delete" or None]
+        """
+        with self.lock:
+            self._apply(entry)
+            self.state_path.parent.mkdir(parents=True, exist_ok=True)
+            with open(self.journal_path, "a", encoding="utf-8") as f:
+                f.write(json.dumps(entry) + "\n")
+            self._journal_entries += 1
+            if self._journal_entries >= self.COMPACT_AFTER:
+                self._save_state()
+
+    def _save_state(self):
+        """Write the whole sync state file and clear the journal"""
+        with self.lock:
+            self.state_path.parent.mkdir(parents=True, exist_ok=True)
+            temp_path = self.state_path.with_suffix(".tmp")
+            with open(temp_path, "w", encoding="utf-8") as f:
+                json.dump({"cursor": self._cursor, "base": self._base, "pending": self._pending}, f)
+            temp_path.replace(self.state_path)
+            if self.journal_path.exists():
+                self.journal_path.unlink()
+            self._journal_entries = 0
+
+    def _queue(self, note_id: str, action: str):
+        """Queue a local change ("save" or "delete") and have the worker thread push it"""
+        with self.lock:
+            self._versions[note_id] = self._versions.get(note_id, 0) + 1
+            self._record(pending=[note_id, action])
+        self._schedule_push()
+
+    def _schedule_push(self):
+        """Start the worker thread pushing the queue (after a push in progress)"""
+        with self._timer_lock:
+            if self._push_timer is not None:
+                self._push_timer.cancel()
+            self._push_timer = threading.Timer(0, self._push_queued)
+            self._push_timer.daemon = True
+            self._push_timer.start()
+
+    def _push_queued(self):
+        """Push every queued change; on the worker thread"""
+        with self.sync_lock:
+            with self.lock:
+                queued = list(self._pending)
+            try:
+                for note_id in queued:
+                    self._push(note_id, resolve=False)
+            except SyncError as e:
+                if not self._failing:
+                    self._failing = True
+                    self.on_sync_error(t("storage.push_failed", error=e))
+                return
+            self._failing = False
+
+    @staticmethod
+    def print_sync_error(message: str):
+        """Report a failed push on stderr, outside the interface"""
+        print(message, file=sys.stderr)
+
+    @property
+    def has_synced(self) -> bool:
+        """Whether notes were ever pulled from the server, during this run or before"""
+        return self._synced or self._cursor > 0
+
+    def _request(self, method: str, path: str, body: Optional[dict] = None) -> Tuple[int, dict]:
+        """
+        Send a request to the sync server
+
+        Args:
+            method: HTTP method
+            path: Path after the API prefix, with any query string
+            body: JSON body to send
+
+        Returns:
+            (status, response JSON); status is 200 or 409
+
+        Raises:
+            SyncError: If the server can't be reached or returns another status
+            OperationCancelled: If the context in use has ended
+        """
+        context = current_context()
+        context.check()
+        data = json.dumps(body).encode("utf-8") if body is not None else None
+        request = urllib.request.Request(f"{self.url}{API_PREFIX}{path}", data=data, method=method)
+        request.add_header("Content-Type", "application/json")
+        if self.token:
+            request.add_header("Authorization", f"Bearer {self.token}")
+        with timed(log, "request", method=method, path=path) as fields:
+            try:
+                with urllib.request.urlopen(request, timeout=context.timeout(self.timeout)) as response:
+                    fields["status"] = response.status
+                    return response.status, json.load(response)
+            except urllib.error.HTTPError as e:
+                fields["status"] = e.code
+                if e.code == 409:
+                    return e.code, json.load(e)
+                raise SyncError(f"{self.url}: HTTP {e.code} {e.reason}")
+            except (urllib.error.URLError, OSError, json.JSONDecodeError) as e:
+                raise SyncError(f"{self.url}: {getattr(e, 'reason', e)}")
+
+    def _unlock(self, refresh: bool = False):
+        """
+        Fetch the keyring and unlock the note keys, storing the keyring if that changed it
+
+        Args:
+            refresh: Fetch it again even if the keys are unlocked, for a key
+                another device added since
+
+        Raises:
+            SyncError: If the server can't be reached
+            E2EError: If the keys can't be unlocked
+        """
+        if self.keys is None or (self.keys.unlocked and not refresh):
+            return
+        ask_passphrase = self._ask_passphrase or (lambda new: "")
+        for _ in range(self.KEYRING_ATTEMPTS):
+            status, data = self._request("GET", "/keys")
+            keyring = self.keys.unlock(data["keys"], ask_passphrase)
+            if keyring is None:
+                return
+            status, _ = self._request("PUT", "/keys", {"keys": keyring, "base": data["updated_at"]})
+            if status != 409:
+                if data["keys"] is None:
+                    self._queue_all()  # Encryption was just turned on: replace the plain notes
+                return
+        raise E2EError(t("storage.e2e_keyring_busy"))
+
+    def _queue_all(self):
+        """Queue every local note to be pushed again, encrypted with the current key"""
+        with self.lock:
+            for note in self.local.get_all_notes():
+                self._pending.setdefault(note.id, "save")
+            self._save_state()
+
+    def _seal(self, note: Note) -> dict:
+        """Encode a note to push, encrypted if end-to-end encryption is on"""
+        data = note_to_dict(note)
+        if self.keys is None:
+            return data
+        self._unlock()
+        return self.keys.seal(data)
+
+    def _open(self, data: dict) -> Note:
+        """Decode a note pulled from the server, decrypting it if it's encrypted"""
+        if self.keys is None:
+            return note_from_dict(data)
+        if self.keys.needs_key(data):
+            self._unlock(refresh=True)
+        return note_from_dict(self.keys.open(data))
+
+    def _push(self, note_id: str, resolve: bool = True):
+        """
+        Push a queued change to the server
+
+        Args:
+            note_id: ID of the note
+            resolve: Resolve a conflict if the server refuses the change;
+                otherwise it stays queued for the next sync
+
+        Raises:
+            SyncError: If the server can't be reached (the change stays queued)
+        """
+        with self.lock:
+            action = self._pending.get(note_id)
+            if action is None:
+                return  # Pushed meanwhile
+            base = self._base.get(note_id)
+            version = self._versions.get(note_id, 0)
+            note = None if action == "delete" else self.local.get_note(note_id)
+            if action == "save" and note is None:
+                self._record(pending=[note_id, None])
+                return
+        if action == "delete":
+            query = f"?base={quote(base)}" if base else ""
+            status, data = self._request("DELETE", f"/notes/{quote(note_id)}{query}")
+        else:
+            status, data = self._request("PUT", f"/notes/{quote(note_id)}", {"note": self._seal(note), "base": base})
+
+        if status == 409:
+            if resolve:
+                self._resolve_conflict(note_id, data.get("note"), data.get("updated_at"))
+            return
+
+        with self.lock:
+            if self._versions.get(note_id, 0) == version:
+                self._record(pending=[note_id, None])  # Else changed again during the push: pushed next time
+            self._record(base=[note_id, None if action == "delete" else data["updated_at"]])
+
+    def _resolve_conflict(self, note_id: str, remote: Optional[dict], updated_at: Optional[str]):
+        """
+        Handle a change the server refused because the note changed there first
+
+        Args:
+            note_id: ID of the note
+            remote: The server's current version (None if deleted there)
+            updated_at: The server's updated_at for it
+        """
+        action = self._pending[note_id]
+        self._record(pending=[note_id, None])
+        local_note = self.local.get_note(note_id)
+
+        if remote is None:
+            # Deleted on the server: push local edits as a new version; a local delete is done
+            self._record(base=[note_id, None])
+            if action == "save" and local_note:
+                self._record(pending=[note_id, "save"])
+                self._push(note_id)
+            return
+
+        # Keep local edits as a separate note, then take the server's version
+        conflict_copy = None
+        remote_note = self._open(remote)
+        if action == "save" and local_note and local_note.content != remote_note.content:
+            conflict_copy = self.local.create_note()
+            conflict_copy.content = local_note.content
+            conflict_copy.properties = dict(local_note.properties)
+            conflict_copy.set_property("conflict_of", note_id)
+            conflict_copy.add_tag("conflict")
+            self.local.save_note(conflict_copy)
+            self._record(pending=[conflict_copy.id, "save"])
+
+        self.local.save_note(remote_note)
+        self._record(base=[note_id, updated_at])
+        self._changed += 1
+
+        if conflict_copy:
+            self._push(conflict_copy.id)
+
+    def sync(self) -> int:
+        """
+        Push queued changes, then pull changes made on other machines
+
+        Returns:
+            Number of local notes changed, deleted or added by the sync
+
+        Raises:
+            SyncError: If the server can't be reached
+        """
+        with self.sync_lock:
+            self._changed = 0
+            self._unlock()
+            with self.lock:
+                queued = list(self._pending)
+            for note_id in queued:
+                self._push(note_id)
+
+            status, data = self._request("GET", f"/changes?since={self._cursor}")
+            for change in data["changes"]:
+                note_id = change["id"]
+                try:
+                    check_note_id(note_id)
+                except ValueError:
+                    event(log, "skip_invalid_id")
+                    continue  # Not a note this machine could keep
+                if note_id in self._pending or self._base.get(note_id) == change["updated_at"]:
+                    continue  # Unpushed local change, or a change this machine made
+                remote = None if change["note"] is None else self._open(change["note"])
+                with self.lock:
+                    if note_id in self._pending:
+                        continue  # Saved here while it was decrypted
+                    if remote is None:
+                        self._base.pop(note_id, None)
+                        if self.local.get_note(note_id):
+                            self.local.delete_note(note_id)
+                            self._changed += 1
+                    else:
+                        self.local.save_note(remote)
+                        self._base[note_id] = change["updated_at"]
+                        self._changed += 1
+            with self.lock:
+                self._cursor = data["cursor"]
+                self._save_state()  # Written in one go rather than journaled change by change
+            self._synced = True
+            self._failing = False
+            return self._changed
+
+    def _change_keyring(self, passphrase: str, revoke: Optional[str] = None, new_passphrase: str = ""):
+        """Rotate the note key (revoking a device, changing the passphrase), then push every note encrypted with the new key"""
+        with self.sync_lock:
+            self._rotate_keyring(passphrase, revoke, new_passphrase)
+
+    def _rotate_keyring(self, passphrase: str, revoke: Optional[str], new_passphrase: str):
+        """Store the rotated keyring and sync; sync_lock must be held"""
+        if self.keys is None:
+            raise E2EError(t("storage.e2e_off"))
+        status, data = self._request("GET", "/keys")
+        if data["keys"] is None:
+            raise E2EError(t("storage.e2e_off"))
+        keyring = self.keys.rotate(data["keys"], passphrase, revoke, new_passphrase)
+        status, _ = self._request("PUT", "/keys", {"keys": keyring, "base": data["updated_at"]})
+        if status == 409:
+            raise E2EError(t("storage.e2e_keyring_busy"))
+        self.keys.unlock(keyring, lambda new: new_passphrase or passphrase)
+        self._queue_all()
+        self.sync()
+
+    def rotate_key(self, passphrase: str):
+        """
+        Encrypt notes with a new key from now on
+
+        Other devices pick the key up on their next sync.
+
+        Args:
+            passphrase: The end-to-end encryption passphrase
+
+        Raises:
+            SyncError: If the server can't be reached
+            E2EError: If encryption is off or the passphrase is wrong
+        """
+        self._change_keyring(passphrase)
+
+    def revoke_device(self, device_id: str, passphrase: str, new_passphrase: str):
+        """
+        Stop a device from reading notes written from now on
+
+        Notes are encrypted with a new key the device never gets, and the
+        passphrase it knows is replaced; other devices ask for the new
+        passphrase once to get the key.
+
+        Args:
+            device_id: ID of the device (see list_devices())
+            passphrase: The end-to-end encryption passphrase
+            new_passphrase: The passphrase from now on, not the same as before
+
+        Raises:
+            SyncError: If the server can't be reached
+            E2EError: If encryption is off, the passphrase is wrong or not
+                new, or there's no such device
+        """
+        self._change_keyring(passphrase, revoke=device_id, new_passphrase=new_passphrase)
+
+    def list_devices(self) -> List[dict]:
+        """
+        Get the devices that can read the notes
+
+        Returns:
+            {"id", "name", "added_at", "current"} for each device, oldest
+            first; "current" is True for this one
+
+        Raises:
+            SyncError: If the server can't be reached
+            E2EError: If encryption is off
+        """
+        if self.keys is None:
+            raise E2EError(t("storage.e2e_off"))
+        status, data = self._request("GET", "/keys")
+        return [dict(device, current=device["id"] == self.keys.device.id) for device in list_devices(data["keys"])]
+
+    def pending_sync(self) -> int:
+        """Count the notes with changes queued for the server"""
+        return len(self._pending)
+
+    def get_all_notes(self) -> List[Note]:
+        """Get all notes from the local copy"""
+        return self.local.get_all_notes()
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Get a note from the local copy"""
+        return self.local.get_note(note_id)
+
+    def save_note(self, note: Note):
+        """Save a note locally and queue it for the server"""
+        self.local.save_note(note)
+        self._queue(note.id, "save")
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Store notes locally and queue them for the server"""
+        count = self.local.import_notes(notes)
+        with self.lock:
+            for note in notes:
+                self._versions[note.id] = self._versions.get(note.id, 0) + 1
+                self._pending[note.id] = "save"
+            self._save_state()  # One write for the whole batch
+        self._schedule_push()
+        return count
+
+    def delete_note(self, note_id: str):
+        """Delete a note locally and queue the deletion for the server"""
+        self.local.delete_note(note_id)
+        self._queue(note_id, "delete")
+
+    def list_tags(self) -> List[str]:
+        """Get every tag in use from the local copy"""
+        return self.local.list_tags()
+
+    def get_notes_by_tag(self, tag: str) -> List[Note]:
+        """Get all notes with a tag from the local copy"""
+        return self.local.get_notes_by_tag(tag)
+
+    def get_backlinks(self, note_id: str) -> List[Note]:
+        """Get the notes linking to a note using the local copy's index"""
+        return self.local.get_backlinks(note_id)
+
+    def search_notes(self, query: str) -> List[SearchResult]:
+        """Search notes using the local copy's index"""
+        return self.local.search_notes(query)
+
+    @property
+    def supports_revisions(self) -> bool:
+        """Whether the local copy records history"""
+        return self.local.supports_revisions
+
+    def list_revisions(self, note_id: str) -> List[Revision]:
+        """Get the saved versions of a note from the local copy"""
+        return self.local.list_revisions(note_id)
+
+    def close(self):
+        """Try once more to push queued changes, then close the local copy"""
+        with self._timer_lock:
+            if self._push_timer is not None:
+                self._push_timer.cancel()
+        with self.sync_lock:
+            try:
+                for note_id in list(self._pending):
+                    self._push(note_id, resolve=False)
+            except SyncError:
+                pass  # Still queued for the next run
+            self._save_state()
+            self.local.close()
@@ -0,0 +1,61 @@
+"""
+Noticing changes other programs make to notes stored in files
+"""
+
+import os
+from pathlib import Path
+from typing import Callable, Dict, Iterable, Tuple
+
+
+class FileSnapshot:
+    """
+    Modification times and sizes of a backend's note files
+
+    The backend reports its own writes and deletions, so changed() only
+    answers True for changes made by something else: another process, a
+    sync tool or an editor.
+    """
+
+    def __init__(self, list_files: Callable[[], Iterable[Path]]):
+        """
+        Take the first snapshot
+
+        Args:
+            list_files: Returns the note files to watch
+        """
+        self._list_files = list_files
+        self._stats = self._take()
+
+    @staticmethod
+    def _stat(path: Path) -> Tuple[int, int]:
+        """Get a file's modification time and size"""
+        stat = os.stat(path)
+        return stat.st_mtime_ns, stat.st_size
+
+    def _take(self) -> Dict[Path, Tuple[int, int]]:
+        """Stat every watched file"""
+        stats = {}
+        for path in self._list_files():
+            try:
+                stats[path] = self._stat(path)
+            except OSError:
+                continue  # Removed while listing
+        return stats
+
+    def written(self, path: Path):
+        """Record a write made by the backend itself"""
+        try:
+            self._stats[path] = self._stat(path)
+        except OSError:
+            self._stats.pop(path, None)
+
+    def removed(self, path: Path):
+        """Record a deletion made by the backend itself"""
+        self._stats.pop(path, None)
+
+    def changed(self) -> bool:
+        """Check whether the files changed since the last check, other than by the backend"""
+        current = self._take()
+        changed = current != self._stats
+        self._stats = current
+        return changed
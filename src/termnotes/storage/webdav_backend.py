@@ -0,0 +1,208 @@
+"""
+WebDAV storage backend, for notes kept on Nextcloud, ownCloud or any WebDAV server
+"""
+
+import base64
+import urllib.error
+import urllib.request
+import xml.etree.ElementTree as ElementTree
+from datetime import datetime
+from email.utils import parsedate_to_datetime
+from typing import Dict, List, Optional, Tuple
+from urllib.parse import quote, unquote, urlparse
+from .base import StorageBackend
+from .context import current_context
+from .frontmatter import note_from_markdown, note_to_markdown
+from ..note import Note
+from ..utils import normalize_to_utc, utc_now
+from ..i18n import t
+from ..log import get_logger, timed
+
+log = get_logger("storage.webdav")
+
+DAV = "{DAV:}"
+
+# Properties asked for when listing the notes folder
+PROPFIND_BODY = (
+    '<?xml version="1.0" encoding="utf-8"?>'
+    '<d:propfind xmlns:d="DAV:"><d:prop><d:getlastmodified/><d:resourcetype/></d:prop></d:propfind>'
+).encode("utf-8")
+
+
+class WebDAVError(RuntimeError):
+    """The WebDAV server couldn't be reached or refused a request"""
+
+
+class WebDAVBackend(StorageBackend):
+    """
+    Storage backend keeping each note as a markdown file in a WebDAV folder
+
+    Notes are "<id>.md" files with a frontmatter header, like the markdown
+    backend's, so they can be read and edited in Nextcloud's web interface
+    or its Notes app. Other .md files put in the folder are read as notes
+    too, with the file name as their ID.
+
+    Every read and write goes to the server; create_default_storage() puts
+    an in-memory cache in front, so notes are downloaded once on startup.
+    """
+
+    def __init__(self, url: str, username: str = "", password: str = "", timeout: float = 10):
+        """
+        Initialize WebDAV backend, creating the notes folder if it doesn't exist
+
+        Args:
+            url: URL of the notes folder, e.g.
+                 "https://cloud.example.com/remote.php/dav/files/me/Notes/"
+            username: Account name ("" for no authentication)
+            password: Password, or an app token (Nextcloud: Settings > Security)
+            timeout: Seconds to wait for the server
+
+        Raises:
+            WebDAVError: If the server can't be reached or refuses the credentials
+        """
+        super().__init__()
+        self.url = url.rstrip("/") + "/"
+        self.username = username
+        self.password = password
+        self.timeout = timeout
+        self._ensure_folder()
+
+    def _request(self, method: str, url: str, body: Optional[bytes] = None,
+                 headers: Optional[Dict[str, str]] = None) -> Tuple[int, bytes]:
+        """
+        Send a request to the server
+
+        Args:
+            method: HTTP or WebDAV method
+            url: Full URL
+            body: Request body
+            headers: Extra headers
+
+        Returns:
+            (status, response body); a 404 is returned rather than raised
+
+        Raises:
+            WebDAVError: If the server can't be reached or returns another error
+            OperationCancelled: If the context in use has ended
+        """
+        context = current_context()
+        context.check()
+        request = urllib.request.Request(url, data=body, method=method)
+        for name, value in (headers or {}).items():
+            request.add_header(name, value)
+        if self.username:
+            credentials = base64.b64encode(f"{self.username}:{self.password}".encode("utf-8")).decode("ascii")
+            request.add_header("Authorization", f"Basic {credentials}")
+        with timed(log, "request", method=method, path=urlparse(url).path) as fields:
+            try:
+                with urllib.request.urlopen(request, timeout=context.timeout(self.timeout)) as response:
+                    fields["status"] = response.status
+                    return response.status, response.read()
+            except urllib.error.HTTPError as e:
+                fields["status"] = e.code
+                if e.code == 404:
+                    return e.code, b""
+                raise WebDAVError(t("storage.webdav_http", url=url, status=e.code, reason=e.reason))
+            except (urllib.error.URLError, OSError) as e:
+                raise WebDAVError(t("storage.webdav_unreachable", url=url, error=getattr(e, "reason", e)))
+
+    def _ensure_folder(self):
+        """Create the notes folder if the server doesn't have it"""
+        status, _ = self._request("PROPFIND", self.url, PROPFIND_BODY, {"Depth": "0"})
+        if status == 404:
+            self._request("MKCOL", self.url)
+
+    def _note_url(self, note_id: str) -> str:
+        """Get the URL of a note's file"""
+        return f"{self.url}{quote(note_id, safe='')}.md"
+
+    def _list_files(self) -> Dict[str, Optional[datetime]]:
+        """
+        List the markdown files in the notes folder
+
+        Returns:
+            Note ID (file name without ".md") to the file's modification time (UTC)
+        """
+        _, body = self._request(
+            "PROPFIND", self.url, PROPFIND_BODY,
+            {"Depth": "1", "Content-Type": "application/xml; charset=utf-8"}
+        )
+        try:
+            root = ElementTree.fromstring(body)
+        except ElementTree.ParseError:
+            raise WebDAVError(t("storage.webdav_bad_listing", url=self.url))
+
+        files = {}
+        for response in root.iter(f"{DAV}response"):
+            href = response.findtext(f"{DAV}href", "")
+            name = unquote(urlparse(href).path.rstrip("/").rsplit("/", 1)[-1])
+            if not name.endswith(".md") or response.find(f".//{DAV}collection") is not None:
+                continue
+            modified = None
+            text = response.findtext(f".//{DAV}getlastmodified")
+            if text:
+                try:
+                    modified = normalize_to_utc(parsedate_to_datetime(text))
+                except (TypeError, ValueError):
+                    pass
+            files[name[:-3]] = modified
+        return files
+
+    def get_all_notes(self) -> List[Note]:
+        """Download every note in the folder"""
+        notes = []
+        for note_id, modified in self._list_files().items():
+            note = self._download(note_id, modified)
+            if note:
+                notes.append(note)
+        notes.sort(key=lambda n: n.updated_at, reverse=True)
+        return notes
+
+    def get_note(self, note_id: str) -> Optional[Note]:
+        """Download a note by ID"""
+        return self._download(note_id)
+
+    def _download(self, note_id: str, modified: Optional[datetime] = None) -> Optional[Note]:
+        """
+        Download and parse a note's file
+
+        Args:
+            note_id: ID of the note
+            modified: File modification time, for files without a frontmatter header
+
+        Returns:
+            The note, or None if there's no such file
+        """
+        status, body = self._request("GET", self._note_url(note_id))
+        if status == 404:
+            return None
+        note = note_from_markdown(body.decode("utf-8", "replace"), note_id, modified)
+        # The file name decides which note a file is, even if a copied header says otherwise
+        note.id = note_id
+        return note
+
+    def _upload(self, note: Note):
+        """Write a note's file"""
+        self._request(
+            "PUT", self._note_url(note.id), note_to_markdown(note).encode("utf-8"),
+            {"Content-Type": "text/markdown; charset=utf-8"}
+        )
+
+    def save_note(self, note: Note):
+        """Save or update a note"""
+        note.updated_at = utc_now()
+        self._upload(note)
+
+    def import_notes(self, notes: List[Note]) -> int:
+        """Upload notes, keeping their timestamps"""
+        for note in notes:
+            self._upload(note)
+        return len(notes)
+
+    def delete_note(self, note_id: str):
+        """Delete a note's file (a missing file is already deleted)"""
+        self._request("DELETE", self._note_url(note_id))
+
+    def close(self):
+        """Nothing to clean up: every request uses its own connection"""
+        pass
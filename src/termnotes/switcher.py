@@ -0,0 +1,84 @@
+"""
+Quick switcher: jump to a note by typing part of its title
+
+Ctrl+P opens a box over the editor listing the other notes, most recently
+viewed first, so Ctrl+P then Enter goes back to the previous note. Typing
+narrows the list to titles fuzzy-matching what was typed, still in order
+of recency.
+"""
+
+from typing import List, Optional, Tuple
+from .note import Note
+from .search import fuzzy_match
+
+
+class QuickSwitcher:
+    """State of the quick switcher: what was typed and the notes matching it"""
+
+    def __init__(self):
+        """Initialize a closed switcher"""
+        self.is_open = False
+        self.query = ""
+        self.notes: List[Note] = []  # Every note listed, most recently viewed first
+        self.matches: List[Tuple[Note, List[int]]] = []  # Notes matching the query, positions of matched characters
+        self.selected_index = 0
+
+    def open(self, notes: List[Note]):
+        """
+        Show the switcher with nothing typed
+
+        Args:
+            notes: Notes to choose from, in the order to list them
+        """
+        self.is_open = True
+        self.notes = notes
+        self.set_query("")
+
+    def close(self):
+        """Close the switcher"""
+        self.is_open = False
+        self.notes = []
+        self.matches = []
+
+    def set_query(self, query: str):
+        """
+        List the notes whose titles match a query, selecting the first
+
+        Args:
+            query: Text typed; its characters must appear in the title in order
+        """
+        self.query = query
+        self.selected_index = 0
+        if not query:
+            self.matches = [(note, []) for note in self.notes]
+            return
+        self.matches = []
+        for note in self.notes:
+            match = fuzzy_match(note.title, query)
+            if match:
+                self.matches.append((note, match[1]))
+
+    def type(self, text: str):
+        """Add typed text to the query"""
+        self.set_query(self.query + text)
+
+    def backspace(self):
+        """Remove the last character of the query"""
+        self.set_query(self.query[:-1])
+
+    @property
+    def selected(self) -> Optional[Note]:
+        """Get the selected note"""
+        if 0 <= self.selected_index < len(self.matches):
+            return self.matches[self.selected_index][0]
+        return None
+
+    def move_selection_down(self):
+        """Select the next note"""
+        if self.selected_index < len(self.matches) - 1:
+            self.selected_index += 1
+
+    def move_selection_up(self):
+        """Select the previous note"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
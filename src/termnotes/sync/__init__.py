@@ -0,0 +1,9 @@
+"""
+Syncing notes between machines through a central server
+
+- protocol: JSON encoding of notes and the HTTP API shared by both sides
+- server: the "termnotes serve" server storing notes in a SQLite database
+- grpc_server: the server's gRPC API (notes.proto), encoded by wire
+
+The client side is SyncBackend in the storage package.
+"""
@@ -0,0 +1,392 @@
+"""
+End-to-end encryption of synced notes
+
+With `[storage.sync] e2e = true`, SyncBackend encrypts each note's content
+and properties before they leave the machine, so the sync server, and
+anyone who can read its database, only sees note IDs, times and ciphertext.
+The local copy is kept as it is. A plain note from the server is refused
+unless it was last changed before the keyring was made, so the server can't
+slip in notes of its own.
+
+Keys:
+
+- Notes are encrypted with a random note key (ChaCha20-Poly1305). Rotating
+  makes a new current note key; notes are then encrypted again with it, and
+  older keys are kept to read what other devices haven't re-encrypted yet.
+- The passphrase derives a master key (PBKDF2-HMAC-SHA256, random salt)
+  that wraps every note key, so a new device can join with the passphrase.
+- Each device has its own random device key, kept only in a file on that
+  device, that wraps the note keys it has unlocked, so it syncs without
+  asking for the passphrase again.
+- A rotated note key is also wrapped with the one before it, so every device
+  follows a rotation on its own.
+
+The server keeps this keyring (wrapped keys only) at /v1/keys. Revoking a
+device removes it from the keyring, changes the passphrase (a new salt and
+master key wrap every note key) and rotates to a key that *isn't* wrapped
+with the one before. The revoked device knows the old passphrase and keys,
+but neither unwraps the new key, so it can't read notes written afterwards;
+the other devices ask for the new passphrase once to pick it up.
+"""
+
+import base64
+import copy
+import hashlib
+import json
+import os
+import socket
+import uuid
+from dataclasses import dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import Callable, Dict, List, Optional
+from chacha20poly1305 import ChaCha20Poly1305
+from .protocol import SyncError
+from ..utils import normalize_to_utc, utc_now
+from ..i18n import t
+
+KEYRING_FORMAT = 1
+KEY_SIZE = 32
+NONCE_SIZE = 12
+SALT_SIZE = 16
+ITERATIONS = 600_000  # PBKDF2 iterations for new keyrings
+PASSPHRASE_ATTEMPTS = 3
+PROPERTY = "e2e"  # Note property holding the ciphertext on the server
+
+
+class E2EError(SyncError):
+    """Notes can't be encrypted or decrypted: no passphrase, a wrong one, or a revoked device"""
+
+
+def _encrypt(key: bytes, plaintext: bytes) -> str:
+    """Encrypt bytes, returning base64(nonce + ciphertext)"""
+    nonce = os.urandom(NONCE_SIZE)
+    return base64.b64encode(nonce + ChaCha20Poly1305(key).encrypt(nonce, plaintext)).decode("ascii")
+
+
+def _decrypt(key: bytes, sealed: str) -> bytes:
+    """
+    Decrypt what _encrypt() returned
+
+    Raises:
+        ValueError: If the key is wrong or the data was changed
+    """
+    try:
+        data = base64.b64decode(sealed.encode("ascii"))
+        return bytes(ChaCha20Poly1305(key).decrypt(data[:NONCE_SIZE], data[NONCE_SIZE:]))
+    except Exception as e:
+        # The cipher raises its own error types for a failed authentication
+        raise ValueError(f"can't decrypt: {e}")
+
+
+def derive_master_key(passphrase: str, keyring: dict) -> bytes:
+    """Derive a keyring's master key from the passphrase"""
+    salt = base64.b64decode(keyring["salt"])
+    return hashlib.pbkdf2_hmac("sha256", passphrase.encode("utf-8"), salt, keyring["iterations"], dklen=KEY_SIZE)
+
+
+@dataclass
+class Device:
+    """This machine as one of the devices sharing the notes"""
+    id: str
+    name: str
+    key: bytes  # Never leaves the machine
+
+    @classmethod
+    def load(cls, path: Path, name: str = "") -> "Device":
+        """
+        Read the device file, creating it (with a new device key) the first time
+
+        Args:
+            path: Device file, readable only by the user
+            name: Name shown in the device list ("" for the host name)
+
+        Returns:
+            The device
+        """
+        try:
+            with open(path, "r", encoding="utf-8") as f:
+                data = json.load(f)
+            return cls(data["id"], name or data["name"], base64.b64decode(data["key"]))
+        except (OSError, ValueError, KeyError):
+            pass
+        device = cls(uuid.uuid4().hex[:12], name or socket.gethostname(), os.urandom(KEY_SIZE))
+        path.parent.mkdir(parents=True, exist_ok=True)
+        temp_path = path.with_suffix(".tmp")
+        fd = os.open(temp_path, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+        with os.fdopen(fd, "w", encoding="utf-8") as f:
+            json.dump({"id": device.id, "name": device.name, "key": base64.b64encode(device.key).decode("ascii")}, f)
+        temp_path.replace(path)
+        return device
+
+
+class SyncKeys:
+    """The note keys this device has unlocked from the keyring"""
+
+    def __init__(self, device: Device):
+        """
+        Initialize with nothing unlocked
+
+        Args:
+            device: This device
+        """
+        self.device = device
+        self.keys: Dict[str, bytes] = {}
+        self.current: Optional[str] = None
+        self.created_at: Optional[datetime] = None  # When the keyring was made; later notes must be sealed
+
+    @property
+    def unlocked(self) -> bool:
+        """Whether the current note key is known, so notes can be encrypted"""
+        return self.current in self.keys
+
+    def _add_device_wraps(self, keyring: dict, keys: Dict[str, bytes]) -> bool:
+        """Wrap note keys for this device; True if the keyring changed"""
+        changed = self.device.id not in keyring["devices"]
+        entry = keyring["devices"].setdefault(self.device.id, {
+            "name": self.device.name, "added_at": utc_now().isoformat(), "keys": {},
+        })
+        if entry["name"] != self.device.name:
+            entry["name"] = self.device.name
+            changed = True
+        for key_id, key in keys.items():
+            if key_id not in entry["keys"]:
+                entry["keys"][key_id] = _encrypt(self.device.key, key)
+                changed = True
+        return changed
+
+    @staticmethod
+    def _unlock_with_master(keyring: dict, master: bytes) -> Dict[str, bytes]:
+        """
+        Unwrap every note key with the master key
+
+        Raises:
+            E2EError: If the passphrase was wrong
+        """
+        keys = {}
+        for key_id, entry in keyring["keys"].items():
+            try:
+                keys[key_id] = _decrypt(master, entry["master"])
+            except ValueError:
+                raise E2EError(t("storage.e2e_wrong_passphrase"))
+        return keys
+
+    def unlock(self, keyring: Optional[dict], ask_passphrase: Callable[[bool], str]) -> Optional[dict]:
+        """
+        Unlock the note keys, creating the keyring or joining it with the passphrase if needed
+
+        Args:
+            keyring: The server's keyring (None if there's none yet)
+            ask_passphrase: Asks the user for the passphrase, given whether
+                it's a new one to choose; returns "" if none was given
+
+        Returns:
+            The keyring with changes to upload (a new keyring, this device
+            joining or wrapping keys it got another way), or None
+
+        Raises:
+            E2EError: If this device was revoked, or no right passphrase was given
+        """
+        if keyring is None:
+            passphrase = ask_passphrase(True)
+            if not passphrase:
+                raise E2EError(t("storage.e2e_locked"))
+            key_id = uuid.uuid4().hex[:12]
+            keyring = {
+                "format": KEYRING_FORMAT,
+                "created_at": utc_now().isoformat(),
+                "salt": base64.b64encode(os.urandom(SALT_SIZE)).decode("ascii"),
+                "iterations": ITERATIONS,
+                "current": key_id,
+                "keys": {},
+                "devices": {},
+                "revoked": [],
+            }
+            self.keys = {key_id: os.urandom(KEY_SIZE)}
+            self.current = key_id
+            keyring["keys"][key_id] = {"master": _encrypt(derive_master_key(passphrase, keyring), self.keys[key_id])}
+            self._add_device_wraps(keyring, self.keys)
+            self.created_at = _keyring_created_at(keyring)
+            return keyring
+
+        if keyring.get("format") != KEYRING_FORMAT:
+            raise E2EError(t("storage.e2e_unknown_format"))
+        if self.device.id in keyring.get("revoked", []):
+            raise E2EError(t("storage.e2e_revoked", device=self.device.name))
+        keyring = copy.deepcopy(keyring)
+        self.current = keyring["current"]
+        self.created_at = _keyring_created_at(keyring)
+
+        # Keys wrapped for this device, then the keys rotated from those
+        for key_id, wrapped in keyring["devices"].get(self.device.id, {}).get("keys", {}).items():
+            try:
+                self.keys[key_id] = _decrypt(self.device.key, wrapped)
+            except ValueError:
+                continue  # Wrapped for an earlier device file
+        found = True
+        while found:
+            found = False
+            for key_id, entry in keyring["keys"].items():
+                previous = entry.get("previous")
+                if key_id not in self.keys and previous in self.keys:
+                    try:
+                        self.keys[key_id] = _decrypt(self.keys[previous], entry["chain"])
+                        found = True
+                    except ValueError:
+                        continue
+
+        if not self.unlocked or self.device.id not in keyring["devices"]:
+            # A new device, or the current key was rotated when a device was revoked
+            for attempt in range(PASSPHRASE_ATTEMPTS):
+                passphrase = ask_passphrase(False)
+                if not passphrase:
+                    break
+                try:
+                    self.keys.update(self._unlock_with_master(keyring, derive_master_key(passphrase, keyring)))
+                    break
+                except E2EError:
+                    if attempt == PASSPHRASE_ATTEMPTS - 1:
+                        raise
+            if not self.unlocked:
+                raise E2EError(t("storage.e2e_locked"))
+        return keyring if self._add_device_wraps(keyring, self.keys) else None
+
+    def rotate(self, keyring: dict, passphrase: str, revoke: Optional[str] = None, new_passphrase: str = "") -> dict:
+        """
+        Make a new current note key
+
+        The keys unlocked here don't change; unlock() the uploaded keyring
+        to use the new key.
+
+        Args:
+            keyring: The server's keyring
+            passphrase: The passphrase, to wrap the new key with the master key
+            revoke: ID of a device to revoke: it's removed, and the new key
+                isn't wrapped with the current one, which it knows
+            new_passphrase: The passphrase from now on, needed to revoke as
+                the revoked device knows the old one: every note key is
+                wrapped again with its master key, from a new salt
+
+        Returns:
+            The keyring to upload
+
+        Raises:
+            E2EError: If the passphrase is wrong, there's no such device, or
+                a device is revoked without a new passphrase
+        """
+        keyring = copy.deepcopy(keyring)
+        master = derive_master_key(passphrase, keyring)
+        keys = self._unlock_with_master(keyring, master)
+        if revoke is not None:
+            if revoke not in keyring["devices"]:
+                raise E2EError(t("storage.e2e_no_device", device=revoke))
+            if revoke == self.device.id:
+                raise E2EError(t("storage.e2e_revoke_self"))
+            if not new_passphrase or new_passphrase == passphrase:
+                raise E2EError(t("storage.e2e_revoke_passphrase"))
+            del keyring["devices"][revoke]
+            keyring["revoked"].append(revoke)
+        if new_passphrase:
+            keyring["salt"] = base64.b64encode(os.urandom(SALT_SIZE)).decode("ascii")
+            keyring["iterations"] = ITERATIONS
+            master = derive_master_key(new_passphrase, keyring)
+            for key_id, entry in keyring["keys"].items():
+                entry["master"] = _encrypt(master, keys[key_id])
+
+        key_id = uuid.uuid4().hex[:12]
+        keys[key_id] = os.urandom(KEY_SIZE)
+        entry = {"master": _encrypt(master, keys[key_id])}
+        if revoke is None:
+            entry["previous"] = keyring["current"]
+            entry["chain"] = _encrypt(keys[keyring["current"]], keys[key_id])
+        keyring["keys"][key_id] = entry
+        keyring["current"] = key_id
+        self._add_device_wraps(keyring, keys)
+        return keyring
+
+    def seal(self, data: dict) -> dict:
+        """
+        Encrypt a note in protocol JSON for the server
+
+        The ID and times stay readable; content and properties are encrypted
+        with the current key.
+        """
+        plaintext = json.dumps({"id": data["id"], "content": data["content"], "properties": data["properties"]})
+        return {
+            "id": data["id"],
+            "content": "",
+            "created_at": data["created_at"],
+            "updated_at": data["updated_at"],
+            "properties": {PROPERTY: {"key": self.current, "data": _encrypt(self.keys[self.current],
+                                                                             plaintext.encode("utf-8"))}},
+        }
+
+    def needs_key(self, data: dict) -> bool:
+        """Check whether a note from the server is encrypted with a key this device hasn't unlocked"""
+        sealed = data.get("properties", {}).get(PROPERTY)
+        return isinstance(sealed, dict) and sealed.get("key") not in self.keys
+
+    def open(self, data: dict) -> dict:
+        """
+        Decrypt a note from the server
+
+        Notes last changed before encryption was turned on pass through as
+        they are; any other plain note was put there by something without
+        the keys.
+
+        Raises:
+            E2EError: If the key isn't unlocked, or the note was tampered with
+                or isn't encrypted
+        """
+        sealed = data.get("properties", {}).get(PROPERTY)
+        if not isinstance(sealed, dict):
+            if self.created_at is not None and _parse_time(data.get("updated_at")) < self.created_at:
+                return data
+            raise E2EError(t("storage.e2e_unsealed", note_id=data["id"]))
+        key = self.keys.get(sealed.get("key"))
+        if key is None:
+            raise E2EError(t("storage.e2e_unknown_key", note_id=data["id"]))
+        try:
+            inner = json.loads(_decrypt(key, sealed["data"]).decode("utf-8"))
+        except (ValueError, KeyError, TypeError):
+            inner = None
+        if not isinstance(inner, dict) or inner.get("id") != data["id"]:
+            # Wrong key, changed on the server, or another note's ciphertext put in its place
+            raise E2EError(t("storage.e2e_tampered", note_id=data["id"]))
+        return dict(data, content=inner["content"], properties=inner["properties"])
+
+
+def _parse_time(value) -> datetime:
+    """Parse a time from the keyring or a note, the latest possible if it isn't one"""
+    try:
+        return normalize_to_utc(datetime.fromisoformat(value))
+    except (TypeError, ValueError):
+        return datetime.max
+
+
+def _keyring_created_at(keyring: dict) -> Optional[datetime]:
+    """
+    Get when a keyring was made, to the second the server keeps note times in
+
+    Keyrings from before it was recorded were made with their first device.
+    """
+    if "created_at" in keyring:
+        created = _parse_time(keyring["created_at"])
+    else:
+        added = [_parse_time(entry.get("added_at")) for entry in keyring.get("devices", {}).values()]
+        if not added:
+            return None
+        created = min(added)
+    return created.replace(microsecond=0)
+
+
+def list_devices(keyring: Optional[dict]) -> List[dict]:
+    """
+    Get the devices in a keyring
+
+    Returns:
+        {"id", "name", "added_at"} for each device, oldest first
+    """
+    devices = (keyring or {}).get("devices", {})
+    return sorted(({"id": device_id, "name": entry["name"], "added_at": entry["added_at"]}
+                   for device_id, entry in devices.items()), key=lambda device: device["added_at"])
@@ -0,0 +1,269 @@
+"""
+gRPC API of the sync server
+
+"termnotes serve --grpc" serves the Notes service in notes.proto on a second
+port, next to the HTTP API and over the same database, for services that
+would rather generate a client than speak the HTTP protocol. Messages are
+encoded by wire.py, so only the grpcio package is needed:
+
+    pip install 'termnotes[grpc]'
+
+Like HTTP requests, each call is handled under a storage Context with the
+server's request timeout; a call that runs out of time fails with
+UNAVAILABLE. WatchChanges streams hold a worker thread for as long as the
+client watches, so at most MAX_WORKERS calls (streams included) are served
+at once.
+"""
+
+import hmac
+import json
+import threading
+from concurrent import futures
+from datetime import datetime
+from typing import Any, Dict, List, Optional, Tuple
+from .protocol import note_from_dict
+from .server import SyncStore
+from .wire import WireError, decode, encode
+from ..notebook import Notebook, get_note_notebook
+from ..search import build_snippet, count_matches, tokenize_query, HIGHLIGHT_END, HIGHLIGHT_START
+from ..storage.context import Context, OperationCancelled, use_context
+from ..utils import utc_now
+from ..i18n import t
+
+SERVICE = "termnotes.v1.Notes"
+MAX_WORKERS = 16
+WATCH_INTERVAL = 1.0  # Seconds between checks for new changes while watching
+LIST_LIMIT = 50  # Notes ListNotes returns unless asked for a number
+SEARCH_LIMIT = 20  # Results Search returns unless asked for a number
+
+
+def _import_grpc():
+    """Import grpcio, which is optional"""
+    try:
+        import grpc
+    except ImportError:
+        raise RuntimeError(t("server.grpc_no_package"))
+    return grpc
+
+
+def note_message(data: dict, revision: str) -> Dict[str, Any]:
+    """Encode a note in protocol JSON as a Note message"""
+    note = note_from_dict(data)
+    return {
+        "id": note.id,
+        "content": note.content,
+        "created_at": data["created_at"],
+        "updated_at": data["updated_at"],
+        "properties_json": json.dumps(note.properties, ensure_ascii=False),
+        "title": note.title,
+        "tags": note.tags,
+        "revision": revision,
+    }
+
+
+def note_data(message: Dict[str, Any]) -> dict:
+    """
+    Decode a Note message into protocol JSON
+
+    Raises:
+        ValueError: If the note has no ID or its times or properties are invalid
+    """
+    if not message["id"]:
+        raise ValueError("note has no id")
+    now = utc_now().isoformat()
+    created_at = message["created_at"] or now
+    updated_at = message["updated_at"] or now
+    datetime.fromisoformat(created_at)
+    datetime.fromisoformat(updated_at)
+    properties = json.loads(message["properties_json"] or "{}")
+    if not isinstance(properties, dict):
+        raise ValueError("properties_json isn't an object")
+    return {
+        "id": message["id"],
+        "content": message["content"],
+        "created_at": created_at,
+        "updated_at": updated_at,
+        "properties": properties,
+    }
+
+
+class NotesService:
+    """Handlers of the Notes service's calls, over a sync server's store"""
+
+    def __init__(self, grpc, store: SyncStore, token: str = "", request_timeout: Optional[float] = 10):
+        """
+        Initialize the service
+
+        Args:
+            grpc: The grpc module
+            store: Notes to serve
+            token: Token clients must send ("" allows anyone)
+            request_timeout: Seconds a call may spend on the database (None for no limit)
+        """
+        self.grpc = grpc
+        self.store = store
+        self.token = token
+        self.request_timeout = request_timeout
+
+    def _authorize(self, context):
+        """End the call with UNAUTHENTICATED unless it has the token (if the server has one)"""
+        if not self.token:
+            return
+        header = dict(context.invocation_metadata()).get("authorization", "")
+        if not hmac.compare_digest(header.encode("utf-8"), f"Bearer {self.token}".encode("utf-8")):
+            context.abort(self.grpc.StatusCode.UNAUTHENTICATED, "unauthorized")
+
+    def _call_store(self, context, method, *args):
+        """Call a store method under the request timeout, ending the call with UNAVAILABLE if it runs out"""
+        try:
+            with use_context(Context(self.request_timeout)):
+                return method(*args)
+        except OperationCancelled:
+            context.abort(self.grpc.StatusCode.UNAVAILABLE, "timeout")
+
+    def _current_notes(self, context) -> List[Tuple[dict, str]]:
+        """Get every note that isn't deleted, with its revision"""
+        _, changes = self._call_store(context, self.store.changes_since, 0)
+        return [(change["note"], change["updated_at"]) for change in changes if change["note"] is not None]
+
+    def _write(self, context, note_id: str, data: Optional[dict], base: str) -> Dict[str, Any]:
+        """Apply a save or delete, returning a WriteResponse"""
+        accepted, current, updated_at = self._call_store(context, self.store.write, note_id, data, base or None)
+        response: Dict[str, Any] = {"accepted": accepted, "updated_at": updated_at or ""}
+        if current is not None:
+            response["current"] = note_message(current, updated_at)
+        return response
+
+    def get_note(self, request: Dict[str, Any], context) -> Dict[str, Any]:
+        """Handle GetNote"""
+        self._authorize(context)
+        data, revision = self._call_store(context, self.store.get, request["id"])
+        if data is None:
+            context.abort(self.grpc.StatusCode.NOT_FOUND, f"no note with id {request['id']}")
+        return note_message(data, revision)
+
+    def list_notes(self, request: Dict[str, Any], context) -> Dict[str, Any]:
+        """Handle ListNotes: the most recently updated notes outside the trash"""
+        self._authorize(context)
+        notebook = Notebook.normalize_path(request["notebook"])
+        notes = []
+        for data, revision in self._current_notes(context):
+            note = note_from_dict(data)
+            if (not note.is_trashed
+                    and (not notebook or notebook in Notebook.ancestor_paths(get_note_notebook(note)))
+                    and (not request["tag"] or note.has_tag(request["tag"]))):
+                notes.append((note.updated_at, data, revision))
+        notes.sort(key=lambda entry: entry[0], reverse=True)
+        limit = request["limit"] if request["limit"] > 0 else LIST_LIMIT
+        return {"notes": [note_message(data, revision) for _, data, revision in notes[:limit]]}
+
+    def put_note(self, request: Dict[str, Any], context) -> Dict[str, Any]:
+        """Handle PutNote"""
+        self._authorize(context)
+        if request["note"] is None:
+            context.abort(self.grpc.StatusCode.INVALID_ARGUMENT, "no note")
+        try:
+            data = note_data(request["note"])
+        except ValueError as e:
+            context.abort(self.grpc.StatusCode.INVALID_ARGUMENT, f"invalid note: {e}")
+        return self._write(context, data["id"], data, request["base"])
+
+    def delete_note(self, request: Dict[str, Any], context) -> Dict[str, Any]:
+        """Handle DeleteNote"""
+        self._authorize(context)
+        if not request["id"]:
+            context.abort(self.grpc.StatusCode.INVALID_ARGUMENT, "no id")
+        return self._write(context, request["id"], None, request["base"])
+
+    def search(self, request: Dict[str, Any], context) -> Dict[str, Any]:
+        """Handle Search: notes outside the trash with every word, ranked by number of matches"""
+        self._authorize(context)
+        terms = tokenize_query(request["query"])
+        results = []
+        for data, revision in (self._current_notes(context) if terms else []):
+            note = note_from_dict(data)
+            matches = 0 if note.is_trashed else count_matches(note.content, terms)
+            if matches:
+                snippet = build_snippet(note.content, terms).replace(HIGHLIGHT_START, "").replace(HIGHLIGHT_END, "")
+                results.append((matches, {"note": note_message(data, revision), "snippet": snippet}))
+        results.sort(key=lambda result: result[0], reverse=True)
+        limit = request["limit"] if request["limit"] > 0 else SEARCH_LIMIT
+        return {"results": [result for _, result in results[:limit]]}
+
+    def watch_changes(self, request: Dict[str, Any], context):
+        """Handle WatchChanges: yield Changes until the client cancels or the server stops"""
+        self._authorize(context)
+        stopped = threading.Event()
+        context.add_callback(stopped.set)
+        cursor, changes = self._call_store(context, self.store.changes_since, request["since"])
+        first = True
+        while True:
+            if changes or first:
+                yield {
+                    "cursor": cursor,
+                    "changes": [
+                        {"id": change["id"], "updated_at": change["updated_at"],
+                         "note": note_message(change["note"], change["updated_at"]) if change["note"] else None}
+                        for change in changes
+                    ],
+                }
+                first = False
+            if stopped.wait(WATCH_INTERVAL) or not context.is_active():
+                return
+            cursor, changes = self._call_store(context, self.store.changes_since, cursor)
+
+
+def _deserializer(message: str):
+    """Make a request deserializer for a message"""
+    def deserialize(data: bytes) -> Dict[str, Any]:
+        try:
+            return decode(message, data)
+        except WireError as e:
+            raise ValueError(f"invalid {message}: {e}")
+    return deserialize
+
+
+def _serializer(message: str):
+    """Make a response serializer for a message"""
+    return lambda values: encode(message, values)
+
+
+def create_grpc_server(store: SyncStore, host: str, port: int, token: str = "",
+                       request_timeout: Optional[float] = 10) -> Tuple[Any, int]:
+    """
+    Create a gRPC server for the Notes service
+
+    Args:
+        store: Notes to serve, shared with the HTTP server
+        host: Address to listen on
+        port: Port to listen on (0 picks a free one)
+        token: Token clients must send ("" allows anyone)
+        request_timeout: Seconds a call may spend on the database (None for no limit)
+
+    Returns:
+        (server, port): the server, not yet started, and the port it's bound to
+
+    Raises:
+        RuntimeError: If grpcio isn't installed or the port can't be bound
+    """
+    grpc = _import_grpc()
+    service = NotesService(grpc, store, token, request_timeout)
+    unary = grpc.unary_unary_rpc_method_handler
+    handlers = {
+        "GetNote": unary(service.get_note, _deserializer("GetNoteRequest"), _serializer("Note")),
+        "ListNotes": unary(service.list_notes, _deserializer("ListNotesRequest"), _serializer("ListNotesResponse")),
+        "PutNote": unary(service.put_note, _deserializer("PutNoteRequest"), _serializer("WriteResponse")),
+        "DeleteNote": unary(service.delete_note, _deserializer("DeleteNoteRequest"), _serializer("WriteResponse")),
+        "Search": unary(service.search, _deserializer("SearchRequest"), _serializer("SearchResponse")),
+        "WatchChanges": grpc.unary_stream_rpc_method_handler(
+            service.watch_changes, _deserializer("WatchRequest"), _serializer("Changes")),
+    }
+    server = grpc.server(futures.ThreadPoolExecutor(max_workers=MAX_WORKERS))
+    server.add_generic_rpc_handlers((grpc.method_handlers_generic_handler(SERVICE, handlers),))
+    try:
+        bound = server.add_insecure_port(f"{host}:{port}")
+    except RuntimeError:
+        bound = 0
+    if not bound:
+        raise RuntimeError(t("server.grpc_bind_failed", host=host, port=port))
+    return server, bound
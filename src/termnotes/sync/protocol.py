@@ -0,0 +1,71 @@
+"""
+Sync protocol shared by the sync server and SyncBackend
+
+Notes travel as JSON over HTTP. The server stamps every accepted change with
+its own updated_at time and an increasing sequence number:
+
+    GET    /v1/changes?since=<seq>
+           -> {"cursor": <seq>, "changes": [{"id", "note" (null if deleted), "updated_at"}]}
+    PUT    /v1/notes/<id>  {"note": {...}, "base": <updated_at or null>}
+           -> {"updated_at": <new updated_at>}
+    DELETE /v1/notes/<id>?base=<updated_at>
+           -> {"updated_at": <deletion time>}
+    GET    /v1/keys
+           -> {"keys": <keyring or null>, "updated_at": <its updated_at or null>}
+    PUT    /v1/keys  {"keys": {...}, "base": <updated_at or null>}
+           -> {"updated_at": <new updated_at>}
+    GET    /v1/calendar.ics
+           -> the dated notes as an iCalendar feed (see calendar.py)
+    GET    /v1/feed.atom?tag=<tag>&limit=<n>
+           -> the recently updated notes as an Atom feed (see feed.py), if
+              the server was started with one
+
+"base" is the server's updated_at for the version of the note the client
+last saw (null for a note the client created). If the note has changed on
+the server since, the write is refused with 409 Conflict and
+{"note": <current note or null if deleted>, "updated_at": <its updated_at>}.
+
+The keyring holds the wrapped keys of end-to-end encrypted sync (see
+e2e.py); the server only stores it. A keyring write based on an outdated
+version is refused with 409 and {"keys": <current keyring>, "updated_at": ...}.
+Encrypted notes travel like any other, with empty content and the
+ciphertext in their "e2e" property.
+
+When the server has a token, requests need an "Authorization: Bearer <token>"
+header; the calendar and Atom feeds also take it as "?token=<token>", as
+calendar apps and feed readers subscribe by URL alone. A public Atom feed
+needs no token.
+
+The server can also serve the same notes over gRPC; see notes.proto.
+"""
+
+from datetime import datetime
+from ..note import Note
+
+API_PREFIX = "/v1"
+
+
+class SyncError(Exception):
+    """The sync server couldn't be reached or refused a request"""
+
+
+def note_to_dict(note: Note) -> dict:
+    """Encode a note for the sync protocol"""
+    return {
+        "id": note.id,
+        "content": note.content,
+        "created_at": note.created_at.isoformat(),
+        "updated_at": note.updated_at.isoformat(),
+        "properties": note.properties,
+    }
+
+
+def note_from_dict(data: dict) -> Note:
+    """Decode a note from the sync protocol"""
+    return Note(
+        note_id=data["id"],
+        content=data["content"],
+        created_at=datetime.fromisoformat(data["created_at"]),
+        updated_at=datetime.fromisoformat(data["updated_at"]),
+        properties=data.get("properties", {})
+    )
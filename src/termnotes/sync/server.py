@@ -0,0 +1,511 @@
+"""
+Sync server: stores notes centrally for SyncBackend clients
+
+Run with "termnotes serve". Notes are kept in a SQLite database as protocol
+JSON, with deleted notes left as tombstones so other clients learn of the
+deletion. See protocol.py for the API, and grpc_server.py for the same
+notes over gRPC.
+
+Each request is handled under a storage Context with the server's request
+timeout, so a request stuck waiting for the database lock or on a slow
+query is answered with 503 instead of holding its thread.
+"""
+
+import hmac
+import json
+import sqlite3
+import threading
+from contextlib import contextmanager
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from pathlib import Path
+from typing import Iterator, List, Optional, Tuple
+from urllib.parse import parse_qs, unquote, urlencode, urlparse
+from .protocol import API_PREFIX, note_from_dict
+from ..calendar import notes_to_ics
+from ..feed import FeedSettings, notes_to_atom
+from ..storage.context import Context, OperationCancelled, current_context, use_context
+from ..utils import utc_now
+from ..i18n import t
+
+KEYRING = "keys"  # Name of the end-to-end encryption keyring in sync_meta
+MAX_BODY = 16 * 1024 * 1024  # Largest request body read, in bytes; a note's attachments aren't synced
+
+
+class SyncStore:
+    """Notes stored by the sync server, with a change sequence for incremental pulls"""
+
+    def __init__(self, db_path: str):
+        """
+        Open or create the server database
+
+        Args:
+            db_path: Path to the SQLite database file
+        """
+        if db_path != ":memory:":
+            Path(db_path).parent.mkdir(parents=True, exist_ok=True)
+        self.conn = sqlite3.connect(db_path, check_same_thread=False)
+        # Reads give up when the request's context ends; writes finish
+        self.conn.set_progress_handler(
+            lambda: not self.conn.in_transaction and current_context().done, 1000
+        )
+        self.lock = threading.Lock()
+        self.conn.execute("""
+            CREATE TABLE IF NOT EXISTS sync_notes (
+                id TEXT PRIMARY KEY,
+                data TEXT,
+                updated_at TEXT NOT NULL,
+                seq INTEGER NOT NULL
+            )
+        """)
+        self.conn.execute("CREATE INDEX IF NOT EXISTS sync_notes_seq ON sync_notes (seq)")
+        # Documents other than notes, such as the end-to-end encryption keyring (see e2e.py)
+        self.conn.execute("""
+            CREATE TABLE IF NOT EXISTS sync_meta (
+                name TEXT PRIMARY KEY,
+                data TEXT NOT NULL,
+                updated_at TEXT NOT NULL
+            )
+        """)
+        self.conn.commit()
+
+    @contextmanager
+    def _locked(self) -> Iterator[None]:
+        """
+        Hold the database lock, waiting no longer than the context in use allows
+
+        Raises:
+            OperationCancelled: If the context ends before the lock is free
+        """
+        context = current_context()
+        remaining = context.remaining()
+        if not self.lock.acquire(timeout=-1 if remaining is None else remaining):
+            raise context.error() or OperationCancelled(t("storage.deadline_exceeded"))
+        try:
+            yield
+        finally:
+            self.lock.release()
+
+    def changes_since(self, seq: int) -> Tuple[int, List[dict]]:
+        """
+        Get the notes changed after a sequence number
+
+        Args:
+            seq: Cursor from an earlier pull (0 for everything)
+
+        Returns:
+            (cursor, changes) where cursor is the latest sequence number
+        """
+        with self._locked():
+            rows = self.conn.execute(
+                "SELECT id, data, updated_at, seq FROM sync_notes WHERE seq > ? ORDER BY seq",
+                (seq,)
+            ).fetchall()
+            cursor = self.conn.execute("SELECT COALESCE(MAX(seq), 0) FROM sync_notes").fetchone()[0]
+        changes = [
+            {"id": row[0], "note": json.loads(row[1]) if row[1] else None, "updated_at": row[2]}
+            for row in rows
+        ]
+        return cursor, changes
+
+    def get(self, note_id: str) -> Tuple[Optional[dict], Optional[str]]:
+        """
+        Get one note
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            (note, updated_at): the note in protocol JSON (None if it doesn't
+            exist or was deleted) and the server's updated_at for it
+        """
+        with self._locked():
+            row = self.conn.execute(
+                "SELECT data, updated_at FROM sync_notes WHERE id = ?", (note_id,)
+            ).fetchone()
+        if row is None:
+            return None, None
+        return json.loads(row[0]) if row[0] else None, row[1]
+
+    def all_notes(self) -> List[dict]:
+        """Get every note that isn't deleted, in protocol JSON"""
+        with self._locked():
+            rows = self.conn.execute("SELECT data FROM sync_notes WHERE data IS NOT NULL").fetchall()
+        return [json.loads(row[0]) for row in rows]
+
+    def write(self, note_id: str, data: Optional[dict], base: Optional[str]) -> Tuple[bool, Optional[dict], Optional[str]]:
+        """
+        Save or delete a note if it hasn't changed since the client's base version
+
+        Args:
+            note_id: ID of the note
+            data: Note in protocol JSON, or None to delete it
+            base: Server updated_at of the version the client last saw
+
+        Returns:
+            (accepted, note, updated_at): on success the new updated_at; on a
+            conflict the server's current note (None if deleted) and its updated_at
+        """
+        with self._locked():
+            row = self.conn.execute(
+                "SELECT data, updated_at FROM sync_notes WHERE id = ?", (note_id,)
+            ).fetchone()
+            current = json.loads(row[0]) if row and row[0] else None
+
+            if current is not None and base != row[1]:
+                return False, current, row[1]
+            if current is None and data is not None and base is not None and row is not None:
+                # Deleted since the client last saw it
+                return False, None, row[1]
+            if current is None and data is None:
+                return True, None, row[1] if row else None
+
+            updated_at = utc_now().isoformat()
+            seq = self.conn.execute("SELECT COALESCE(MAX(seq), 0) + 1 FROM sync_notes").fetchone()[0]
+            self.conn.execute(
+                """
+                INSERT INTO sync_notes (id, data, updated_at, seq) VALUES (?, ?, ?, ?)
+                ON CONFLICT(id) DO UPDATE SET
+                    data = excluded.data, updated_at = excluded.updated_at, seq = excluded.seq
+                """,
+                (note_id, json.dumps(data) if data is not None else None, updated_at, seq)
+            )
+            self.conn.commit()
+            return True, None, updated_at
+
+    def get_meta(self, name: str) -> Tuple[Optional[dict], Optional[str]]:
+        """
+        Get a document
+
+        Args:
+            name: Name of the document
+
+        Returns:
+            (document, updated_at), both None if there's none
+        """
+        with self._locked():
+            row = self.conn.execute("SELECT data, updated_at FROM sync_meta WHERE name = ?", (name,)).fetchone()
+        if row is None:
+            return None, None
+        return json.loads(row[0]), row[1]
+
+    def put_meta(self, name: str, data: dict, base: Optional[str]) -> Tuple[bool, Optional[dict], Optional[str]]:
+        """
+        Store a document if it hasn't changed since the client's base version
+
+        Args:
+            name: Name of the document
+            data: The document
+            base: Server updated_at of the version the client last saw (None if it saw none)
+
+        Returns:
+            (accepted, document, updated_at): on success the new updated_at;
+            on a conflict the current document and its updated_at
+        """
+        with self._locked():
+            row = self.conn.execute("SELECT data, updated_at FROM sync_meta WHERE name = ?", (name,)).fetchone()
+            if (row[1] if row else None) != base:
+                return False, json.loads(row[0]) if row else None, row[1] if row else None
+            updated_at = utc_now().isoformat()
+            self.conn.execute(
+                "INSERT INTO sync_meta (name, data, updated_at) VALUES (?, ?, ?) "
+                "ON CONFLICT(name) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at",
+                (name, json.dumps(data), updated_at)
+            )
+            self.conn.commit()
+            return True, None, updated_at
+
+    def close(self):
+        """Close the database"""
+        self.conn.close()
+
+
+class SyncRequestHandler(BaseHTTPRequestHandler):
+    """HTTP handler for the sync API; the server has `store`, `token`, `request_timeout` and `feed` attributes"""
+
+    def _send_json(self, status: int, body: dict):
+        """Send a JSON response"""
+        payload = json.dumps(body).encode("utf-8")
+        self.send_response(status)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+    def _authorized(self, query_token: Optional[str] = None) -> bool:
+        """
+        Check the bearer token if the server has one, replying 401 if it's wrong
+
+        Args:
+            query_token: Token given in the URL instead, for clients that
+                can't send headers (calendar apps and feed readers subscribing)
+        """
+        token = self.server.token
+        if not token:
+            return True
+        header = self.headers.get("Authorization", "")
+        if hmac.compare_digest(header.encode("utf-8"), f"Bearer {token}".encode("utf-8")):
+            return True
+        if query_token is not None and hmac.compare_digest(query_token.encode("utf-8"), token.encode("utf-8")):
+            return True
+        self._send_json(401, {"error": "unauthorized"})
+        return False
+
+    def _note_id(self, path: str) -> Optional[str]:
+        """Get the note ID from a /v1/notes/<id> path"""
+        prefix = f"{API_PREFIX}/notes/"
+        if path.startswith(prefix) and len(path) > len(prefix):
+            return unquote(path[len(prefix):])
+        return None
+
+    def _call_store(self, method, *args):
+        """
+        Call a store method under the request timeout
+
+        Returns:
+            The method's result, or None if it ran out of time (503 has been sent)
+        """
+        try:
+            with use_context(Context(self.server.request_timeout)):
+                return method(*args)
+        except OperationCancelled:
+            self._send_json(503, {"error": "timeout"})
+            return None
+
+    def _write(self, note_id: str, data: Optional[dict], base: Optional[str]):
+        """Apply a save or delete and send the result"""
+        result = self._call_store(self.server.store.write, note_id, data, base)
+        if result is None:
+            return
+        accepted, note, updated_at = result
+        if accepted:
+            self._send_json(200, {"updated_at": updated_at})
+        else:
+            self._send_json(409, {"note": note, "updated_at": updated_at})
+
+    def _send_calendar(self):
+        """Send the calendar feed of the dated notes"""
+        data = self._call_store(self.server.store.all_notes)
+        if data is None:
+            return
+        payload = notes_to_ics(note_from_dict(entry) for entry in data).encode("utf-8")
+        self.send_response(200)
+        self.send_header("Content-Type", "text/calendar; charset=utf-8")
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+    def _send_feed(self, query: dict):
+        """Send the Atom feed of recently updated notes, narrowed by ?tag= and ?limit="""
+        feed = self.server.feed
+        try:
+            limit = min(int(query.get("limit", [feed.limit])[0]), feed.limit)
+        except ValueError:
+            self._send_json(400, {"error": "invalid limit"})
+            return
+        data = self._call_store(self.server.store.all_notes)
+        if data is None:
+            return
+        tags = ([feed.tag] if feed.tag else []) + query.get("tag", [])
+        host = self.headers.get("Host")
+        url = None
+        if host:
+            url = f"http://{host}{API_PREFIX}/feed.atom"
+            if query.get("tag"):
+                url += f"?{urlencode({'tag': query['tag']}, doseq=True)}"
+        payload = notes_to_atom((note_from_dict(entry) for entry in data), feed.title, tags, limit,
+                                url).encode("utf-8")
+        self.send_response(200)
+        self.send_header("Content-Type", "application/atom+xml; charset=utf-8")
+        self.send_header("Content-Length", str(len(payload)))
+        self.end_headers()
+        self.wfile.write(payload)
+
+    def _body_fits(self) -> bool:
+        """Check the request body is at most MAX_BODY bytes, replying 413 without reading it if not"""
+        try:
+            length = int(self.headers.get("Content-Length", "0"))
+        except ValueError:
+            return True  # Left for _read_body to reject
+        if length <= MAX_BODY:
+            return True
+        self.close_connection = True  # The unread body would be taken for the next request
+        self._send_json(413, {"error": "request too large"})
+        return False
+
+    def _read_body(self) -> dict:
+        """
+        Read the JSON body of a request
+
+        Raises:
+            ValueError: If it isn't a JSON object
+        """
+        length = int(self.headers.get("Content-Length", "0"))
+        if length < 0:
+            raise ValueError("negative Content-Length")
+        body = json.loads(self.rfile.read(length))
+        if not isinstance(body, dict):
+            raise ValueError("body isn't an object")
+        return body
+
+    def _send_keys(self):
+        """Send the end-to-end encryption keyring"""
+        result = self._call_store(self.server.store.get_meta, KEYRING)
+        if result is not None:
+            keys, updated_at = result
+            self._send_json(200, {"keys": keys, "updated_at": updated_at})
+
+    def _put_keys(self):
+        """Store the end-to-end encryption keyring unless another client changed it first"""
+        try:
+            body = self._read_body()
+            if not isinstance(body["keys"], dict):
+                raise ValueError("keys isn't an object")
+        except (ValueError, KeyError):
+            self._send_json(400, {"error": "invalid keys"})
+            return
+        result = self._call_store(self.server.store.put_meta, KEYRING, body["keys"], body.get("base"))
+        if result is None:
+            return
+        accepted, keys, updated_at = result
+        if accepted:
+            self._send_json(200, {"updated_at": updated_at})
+        else:
+            self._send_json(409, {"keys": keys, "updated_at": updated_at})
+
+    def do_GET(self):
+        """Handle GET /v1/changes?since=<seq>, GET /v1/keys, GET /v1/calendar.ics and GET /v1/feed.atom"""
+        url = urlparse(self.path)
+        if url.path == f"{API_PREFIX}/calendar.ics":
+            if self._authorized(parse_qs(url.query).get("token", [None])[0]):
+                self._send_calendar()
+            return
+        if url.path == f"{API_PREFIX}/feed.atom" and self.server.feed is not None:
+            query = parse_qs(url.query)
+            if self.server.feed.public or self._authorized(query.get("token", [None])[0]):
+                self._send_feed(query)
+            return
+        if not self._authorized():
+            return
+        if url.path == f"{API_PREFIX}/keys":
+            self._send_keys()
+            return
+        if url.path != f"{API_PREFIX}/changes":
+            self._send_json(404, {"error": "not found"})
+            return
+        try:
+            since = int(parse_qs(url.query).get("since", ["0"])[0])
+        except ValueError:
+            self._send_json(400, {"error": "invalid since"})
+            return
+        result = self._call_store(self.server.store.changes_since, since)
+        if result is None:
+            return
+        cursor, changes = result
+        self._send_json(200, {"cursor": cursor, "changes": changes})
+
+    def do_PUT(self):
+        """Handle PUT /v1/notes/<id> and PUT /v1/keys"""
+        if not self._authorized():
+            return
+        if not self._body_fits():
+            return
+        path = urlparse(self.path).path
+        if path == f"{API_PREFIX}/keys":
+            self._put_keys()
+            return
+        note_id = self._note_id(path)
+        if note_id is None:
+            self._send_json(404, {"error": "not found"})
+            return
+        try:
+            body = self._read_body()
+            data = body["note"]
+            if data.get("id") != note_id:
+                raise ValueError("note ID doesn't match the URL")
+        except (ValueError, KeyError, TypeError, AttributeError):
+            self._send_json(400, {"error": "invalid note"})
+            return
+        self._write(note_id, data, body.get("base"))
+
+    def do_DELETE(self):
+        """Handle DELETE /v1/notes/<id>?base=<updated_at>"""
+        if not self._authorized():
+            return
+        url = urlparse(self.path)
+        note_id = self._note_id(url.path)
+        if note_id is None:
+            self._send_json(404, {"error": "not found"})
+            return
+        self._write(note_id, None, parse_qs(url.query).get("base", [None])[0])
+
+
+def create_server(db_path: str, host: str, port: int, token: str = "",
+                  request_timeout: Optional[float] = 10,
+                  feed: Optional[FeedSettings] = None) -> ThreadingHTTPServer:
+    """
+    Create a sync server
+
+    Args:
+        db_path: Path to the server's SQLite database
+        host: Address to listen on
+        port: Port to listen on (0 picks a free one)
+        token: Token clients must send ("" allows anyone)
+        request_timeout: Seconds a request may spend on the database (None for no limit)
+        feed: What /v1/feed.atom publishes (None to not serve it)
+
+    Returns:
+        Server ready for serve_forever()
+    """
+    server = ThreadingHTTPServer((host, port), SyncRequestHandler)
+    server.store = SyncStore(db_path)
+    server.token = token
+    server.request_timeout = request_timeout
+    server.feed = feed
+    return server
+
+
+def serve(db_path: str, host: str, port: int, token: str = "", request_timeout: Optional[float] = 10,
+          feed: Optional[FeedSettings] = None, grpc_port: Optional[int] = None):
+    """
+    Run the sync server until interrupted
+
+    Args:
+        db_path: Path to the server's SQLite database
+        host: Address to listen on
+        port: Port to listen on
+        token: Token clients must send ("" allows anyone)
+        request_timeout: Seconds a request may spend on the database (None for no limit)
+        feed: What /v1/feed.atom publishes (None to not serve it)
+        grpc_port: Port for the gRPC API as well (None to not serve it)
+
+    Raises:
+        RuntimeError: If the gRPC API can't be served
+    """
+    server = create_server(db_path, host, port, token, request_timeout, feed)
+    grpc_server = None
+    if grpc_port is not None:
+        # Imported here, as grpc_server imports this module
+        from .grpc_server import create_grpc_server
+        try:
+            grpc_server, grpc_port = create_grpc_server(server.store, host, grpc_port, token, request_timeout)
+        except RuntimeError:
+            server.server_close()
+            server.store.close()
+            raise
+        grpc_server.start()
+    print(t("server.listening", host=host, port=server.server_address[1], path=db_path))
+    if grpc_server is not None:
+        print(t("server.grpc", host=host, port=grpc_port))
+    if not token:
+        print(t("server.no_token"))
+    if feed is not None:
+        print(t("server.feed", host=host, port=server.server_address[1]))
+    try:
+        server.serve_forever()
+    except KeyboardInterrupt:
+        pass
+    finally:
+        if grpc_server is not None:
+            grpc_server.stop(1).wait()
+        server.server_close()
+        server.store.close()
@@ -0,0 +1,194 @@
+"""
+Protocol Buffers encoding of the gRPC API's messages
+
+The messages in notes.proto are few and flat, so they're encoded here from
+MESSAGES rather than with code generated by protoc: the server needs only
+the grpcio package. Messages are dicts keyed by field name; decoding fills
+in proto3's defaults for missing fields and skips unknown ones, so newer
+clients keep working.
+
+MESSAGES must match notes.proto.
+"""
+
+from typing import Any, Dict, Tuple
+
+# Message name to {field name: (field number, type, repeated)}; a type is a
+# scalar ("string", "bool", "int32", "int64") or another message's name
+MESSAGES: Dict[str, Dict[str, Tuple[int, str, bool]]] = {
+    "Note": {
+        "id": (1, "string", False),
+        "content": (2, "string", False),
+        "created_at": (3, "string", False),
+        "updated_at": (4, "string", False),
+        "properties_json": (5, "string", False),
+        "title": (6, "string", False),
+        "tags": (7, "string", True),
+        "revision": (8, "string", False),
+    },
+    "GetNoteRequest": {"id": (1, "string", False)},
+    "ListNotesRequest": {
+        "tag": (1, "string", False),
+        "notebook": (2, "string", False),
+        "limit": (3, "int32", False),
+    },
+    "ListNotesResponse": {"notes": (1, "Note", True)},
+    "PutNoteRequest": {"note": (1, "Note", False), "base": (2, "string", False)},
+    "DeleteNoteRequest": {"id": (1, "string", False), "base": (2, "string", False)},
+    "WriteResponse": {
+        "accepted": (1, "bool", False),
+        "updated_at": (2, "string", False),
+        "current": (3, "Note", False),
+    },
+    "SearchRequest": {"query": (1, "string", False), "limit": (2, "int32", False)},
+    "SearchResult": {"note": (1, "Note", False), "snippet": (2, "string", False)},
+    "SearchResponse": {"results": (1, "SearchResult", True)},
+    "WatchRequest": {"since": (1, "int64", False)},
+    "Change": {
+        "id": (1, "string", False),
+        "note": (2, "Note", False),
+        "updated_at": (3, "string", False),
+    },
+    "Changes": {"cursor": (1, "int64", False), "changes": (2, "Change", True)},
+}
+
+SCALAR_DEFAULTS = {"string": "", "bool": False, "int32": 0, "int64": 0}
+
+# Wire types
+VARINT = 0
+FIXED64 = 1
+LENGTH_DELIMITED = 2
+FIXED32 = 5
+
+
+class WireError(ValueError):
+    """Bytes that aren't a valid encoding of the message"""
+
+
+def _varint(value: int) -> bytes:
+    """Encode an unsigned varint; negative numbers as 64-bit two's complement"""
+    if value < 0:
+        value += 1 << 64
+    out = bytearray()
+    while True:
+        byte = value & 0x7F
+        value >>= 7
+        if value:
+            out.append(byte | 0x80)
+        else:
+            out.append(byte)
+            return bytes(out)
+
+
+def _read_varint(data: bytes, pos: int) -> Tuple[int, int]:
+    """Decode a varint, returning it and the position after it"""
+    result = 0
+    shift = 0
+    while True:
+        if pos >= len(data) or shift > 63:
+            raise WireError("truncated varint")
+        byte = data[pos]
+        pos += 1
+        result |= (byte & 0x7F) << shift
+        if not byte & 0x80:
+            return result, pos
+        shift += 7
+
+
+def encode(message: str, values: Dict[str, Any]) -> bytes:
+    """
+    Encode a message
+
+    Args:
+        message: Message name in MESSAGES
+        values: Field values; missing ones and proto3 defaults aren't written
+
+    Returns:
+        Protocol Buffers bytes
+    """
+    out = bytearray()
+    for name, (number, kind, repeated) in MESSAGES[message].items():
+        value = values.get(name)
+        if value is None:
+            continue
+        for item in (value if repeated else [value]):
+            if kind in MESSAGES:
+                payload = encode(kind, item)
+                out += _varint(number << 3 | LENGTH_DELIMITED) + _varint(len(payload)) + payload
+            elif kind == "string":
+                if item == "" and not repeated:
+                    continue
+                payload = item.encode("utf-8")
+                out += _varint(number << 3 | LENGTH_DELIMITED) + _varint(len(payload)) + payload
+            else:
+                if not item and not repeated:
+                    continue
+                out += _varint(number << 3 | VARINT) + _varint(int(item))
+    return bytes(out)
+
+
+def decode(message: str, data: bytes) -> Dict[str, Any]:
+    """
+    Decode a message
+
+    Args:
+        message: Message name in MESSAGES
+        data: Protocol Buffers bytes
+
+    Returns:
+        Field values: scalars default to proto3's zero values, repeated
+        fields to [], and missing message fields to None
+
+    Raises:
+        WireError: If the bytes can't be decoded as the message
+    """
+    fields = MESSAGES[message]
+    by_number = {number: (name, kind, repeated) for name, (number, kind, repeated) in fields.items()}
+    values: Dict[str, Any] = {}
+    for name, (_, kind, repeated) in fields.items():
+        values[name] = [] if repeated else SCALAR_DEFAULTS.get(kind)
+
+    pos = 0
+    while pos < len(data):
+        key, pos = _read_varint(data, pos)
+        number, wire_type = key >> 3, key & 7
+        if wire_type == VARINT:
+            raw, pos = _read_varint(data, pos)
+        elif wire_type == LENGTH_DELIMITED:
+            length, pos = _read_varint(data, pos)
+            if pos + length > len(data):
+                raise WireError("truncated field")
+            raw, pos = data[pos:pos + length], pos + length
+        elif wire_type == FIXED64:
+            raw, pos = None, pos + 8
+        elif wire_type == FIXED32:
+            raw, pos = None, pos + 4
+        else:
+            raise WireError(f"unsupported wire type {wire_type}")
+        if pos > len(data):
+            raise WireError("truncated field")
+
+        if number not in by_number:
+            continue  # A field from a newer version
+        name, kind, repeated = by_number[number]
+        expected = LENGTH_DELIMITED if kind in MESSAGES or kind == "string" else VARINT
+        if wire_type != expected:
+            raise WireError(f"{message}.{name} has the wrong wire type")
+        if kind in MESSAGES:
+            value: Any = decode(kind, raw)
+        elif kind == "string":
+            try:
+                value = raw.decode("utf-8")
+            except UnicodeDecodeError:
+                raise WireError(f"{message}.{name} isn't UTF-8")
+        elif kind == "bool":
+            value = bool(raw)
+        else:
+            # Signed: negative numbers arrive as 64-bit two's complement
+            value = raw - (1 << 64) if raw >= 1 << 63 else raw
+            if kind == "int32":
+                value = (value + (1 << 31)) % (1 << 32) - (1 << 31)
+        if repeated:
+            values[name].append(value)
+        else:
+            values[name] = value
+    return values
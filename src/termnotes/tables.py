@@ -0,0 +1,228 @@
+"""
+Markdown tables in the editor
+
+A table is a header row, a delimiter row ("| --- | :-: |") and body rows,
+cells separated by pipes ("\\|" is a pipe inside a cell). While the cursor
+is in one in insert mode, the editor is in table mode: Tab and Shift+Tab
+move to the next and previous cell and Enter to the cell below, adding a row
+past the last one, and each move lines the pipes up again (format_table()).
+:table aligns the table under the cursor or starts a new one, and
+:table row / :table column add a row below the cursor or a column after it.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import List, Optional, Tuple
+from .textwidth import text_width
+
+DELIMITER_CELL = re.compile(r'^:?-+:?$')
+
+# Columns a cell takes at least, so the delimiter row has room for ":-:"
+MIN_WIDTH = 3
+
+
+def find_pipes(line: str) -> List[int]:
+    """Get the indexes of the pipes separating a row's cells (not escaped ones)"""
+    pipes = []
+    escaped = False
+    for index, ch in enumerate(line):
+        if ch == '|' and not escaped:
+            pipes.append(index)
+        escaped = ch == '\\' and not escaped
+    return pipes
+
+
+def _cell_bounds(line: str) -> List[Tuple[int, int]]:
+    """
+    Find the cells of a row
+
+    Returns:
+        (start, end) of each cell's text, between its pipes, outer pipes
+        left out
+    """
+    pipes = find_pipes(line)
+    edges = [-1] + pipes + [len(line)]
+    if pipes and not line[:pipes[0]].strip():
+        edges = edges[1:]
+    if pipes and not line[pipes[-1] + 1:].strip() and len(edges) > 2:
+        edges = edges[:-1]
+    return [(edges[i] + 1, edges[i + 1]) for i in range(len(edges) - 1)]
+
+
+def split_row(line: str) -> List[str]:
+    """Get the text of each cell of a row, without the spaces around it"""
+    return [line[start:end].strip() for start, end in _cell_bounds(line)]
+
+
+def _is_delimiter(line: str) -> bool:
+    """Check if a line is a table's delimiter row"""
+    cells = split_row(line)
+    return '|' in line and bool(cells) and all(DELIMITER_CELL.match(cell) for cell in cells)
+
+
+def _alignment(cell: str) -> str:
+    """Get a column's alignment from its delimiter cell: "left", "center", "right" or "" (none given)"""
+    if cell.startswith(':') and cell.endswith(':') and len(cell) > 1:
+        return "center"
+    if cell.startswith(':'):
+        return "left"
+    if cell.endswith(':'):
+        return "right"
+    return ""
+
+
+@dataclass
+class Table:
+    """A table of a note"""
+    start: int  # Line of the header row
+    end: int  # Last line, as found in the note
+    indent: str
+    rows: List[List[str]]  # Cells of the header row, then the body rows
+    alignments: List[str]
+
+    @property
+    def columns(self) -> int:
+        """Get the number of columns, the longest row's"""
+        return max(len(self.alignments), *(len(row) for row in self.rows))
+
+    def line_of(self, row: int) -> int:
+        """Get the line of a row (0 is the header, 1 the first body row)"""
+        return self.start + (row if row == 0 else row + 1)
+
+    def row_of(self, line: int) -> int:
+        """Get the row on a line (the delimiter row counting as the header)"""
+        return max(0, line - self.start - 1)
+
+    def add_row(self, after: int):
+        """Add an empty row below another"""
+        self.rows.insert(after + 1, [""] * self.columns)
+
+    def add_column(self, after: int):
+        """Add an empty column to the right of another"""
+        for row in self.rows:
+            row.insert(min(after + 1, len(row)), "")
+        self.alignments.insert(min(after + 1, len(self.alignments)), "")
+
+
+def find_table(lines: List[str], row: int) -> Optional[Table]:
+    """
+    Find the table a line is part of
+
+    Args:
+        lines: The note's lines
+        row: Line to look at
+
+    Returns:
+        The table, or None if the line isn't in one
+    """
+    def is_row(index: int) -> bool:
+        return '|' in lines[index] and bool(lines[index].strip())
+
+    if not is_row(row):
+        return None
+    start = row
+    while start > 0 and is_row(start - 1):
+        start -= 1
+    end = row
+    while end + 1 < len(lines) and is_row(end + 1):
+        end += 1
+    # The header is the line above the delimiter row; lines above it aren't part of the table
+    header = next((index for index in range(min(row, end - 1), start - 1, -1) if _is_delimiter(lines[index + 1])), None)
+    if header is None:
+        return None
+    line = lines[header]
+    return Table(
+        start=header,
+        end=end,
+        indent=line[:len(line) - len(line.lstrip())],
+        rows=[split_row(lines[index]) for index in range(header, end + 1) if index != header + 1],
+        alignments=[_alignment(cell) for cell in split_row(lines[header + 1])],
+    )
+
+
+def _pad(text: str, width: int, alignment: str) -> str:
+    """Pad a cell's text to a number of columns, as its column is aligned"""
+    room = max(0, width - text_width(text))
+    if alignment == "right":
+        return " " * room + text
+    if alignment == "center":
+        return " " * (room // 2) + text + " " * (room - room // 2)
+    return text + " " * room
+
+
+def _delimiter(width: int, alignment: str) -> str:
+    """Get the delimiter cell of a column"""
+    if alignment == "center":
+        return ":" + "-" * (width - 2) + ":"
+    if alignment == "left":
+        return ":" + "-" * (width - 1)
+    if alignment == "right":
+        return "-" * (width - 1) + ":"
+    return "-" * width
+
+
+def format_table(table: Table) -> List[str]:
+    """
+    Lay out a table with its pipes lined up
+
+    Every row gets the same number of cells, each padded to its column's
+    widest (in terminal columns) and aligned as the delimiter row says.
+
+    Returns:
+        The table's lines: header, delimiter row, body rows
+    """
+    columns = table.columns
+    rows = [row + [""] * (columns - len(row)) for row in table.rows]
+    alignments = table.alignments + [""] * (columns - len(table.alignments))
+    widths = [max(MIN_WIDTH, *(text_width(row[column]) for row in rows)) for column in range(columns)]
+
+    def line(cells: List[str]) -> str:
+        return table.indent + "| " + " | ".join(cells) + " |"
+
+    lines = [line([_pad(text, widths[i], alignments[i]) for i, text in enumerate(row)]) for row in rows]
+    lines.insert(1, line([_delimiter(widths[i], alignments[i]) for i in range(columns)]))
+    return lines
+
+
+def cell_at(line: str, col: int) -> Tuple[int, int]:
+    """
+    Find the cell of a row a column is in
+
+    Args:
+        line: The row's line
+        col: Cursor column
+
+    Returns:
+        (cell index, offset of the column in the cell's text, without its
+        leading spaces)
+    """
+    bounds = _cell_bounds(line)
+    for index, (start, end) in enumerate(bounds):
+        if col <= end or index == len(bounds) - 1:
+            text_start = start + len(line[start:end]) - len(line[start:end].lstrip())
+            return index, max(0, col - text_start)
+    return 0, 0
+
+
+def cell_cursor(line: str, index: int, offset: Optional[int] = None) -> int:
+    """
+    Get the column to put the cursor on in a cell of a row
+
+    Args:
+        line: The row's line
+        index: Cell index
+        offset: Offset in the cell's text (None for the end of the text)
+
+    Returns:
+        The column
+    """
+    bounds = _cell_bounds(line)
+    if not bounds:
+        return len(line)
+    start, end = bounds[min(index, len(bounds) - 1)]
+    cell = line[start:end]
+    text_start = start + len(cell) - len(cell.lstrip())
+    text_length = len(cell.strip())
+    if not text_length:
+        return min(start + 1, end)
+    return text_start + (text_length if offset is None else min(offset, text_length))
@@ -0,0 +1,159 @@
+"""
+Tasks: markdown checkboxes collected from every note
+
+A task is a list item starting with a checkbox, "- [ ] open" or
+"- [x] done" ("*" and "+" bullets too). Checkboxes inside code fences
+aren't tasks. The tasks view lists the open tasks of all notes with the
+note each came from, and toggling one there writes the checkbox back.
+"""
+
+import re
+from dataclasses import dataclass
+from typing import Iterable, List, Optional
+from .note import Note
+
+TASK = re.compile(r'^(\s*[-*+] \[)([ xX])(\] ?)(.*)$')
+FENCE = re.compile(r'^\s*(```|~~~)')
+
+
+@dataclass
+class Task:
+    """One checkbox line in a note"""
+    note_id: str
+    note_title: str
+    line: int  # Index of the line in the note's content
+    text: str  # Text after the checkbox
+    done: bool
+
+
+def extract_tasks(note: Note) -> List[Task]:
+    """
+    Find the checkboxes in a note
+
+    Args:
+        note: Note to read
+
+    Returns:
+        Tasks in the order they appear
+    """
+    tasks = []
+    in_fence = False
+    for index, line in enumerate(note.content.split('\n')):
+        if FENCE.match(line):
+            in_fence = not in_fence
+            continue
+        if in_fence:
+            continue
+        match = TASK.match(line)
+        if match:
+            tasks.append(Task(note.id, note.title, index, match.group(4).strip(), match.group(2) != " "))
+    return tasks
+
+
+def task_on_line(note: Note, line: int) -> Optional[Task]:
+    """
+    Get the task on one line of a note
+
+    Args:
+        note: Note to read
+        line: Index of the line
+
+    Returns:
+        The task, or None if the line isn't one (or is in a code fence)
+    """
+    return next((task for task in extract_tasks(note) if task.line == line), None)
+
+
+def collect_tasks(notes: Iterable[Note], include_done: bool = False) -> List[Task]:
+    """
+    Gather the tasks of many notes
+
+    Args:
+        notes: Notes to read, in the order their tasks should be listed
+        include_done: Include checked tasks
+
+    Returns:
+        Tasks grouped by note
+    """
+    return [task for note in notes for task in extract_tasks(note) if include_done or not task.done]
+
+
+def toggle_task_line(content: str, line: int) -> Optional[str]:
+    """
+    Check or uncheck the checkbox on a line
+
+    Args:
+        content: Note content
+        line: Index of the task's line
+
+    Returns:
+        The new content, or None if the line isn't a task
+    """
+    lines = content.split('\n')
+    if not 0 <= line < len(lines):
+        return None
+    match = TASK.match(lines[line])
+    if match is None:
+        return None
+    mark = " " if match.group(2) != " " else "x"
+    lines[line] = f"{match.group(1)}{mark}{match.group(3)}{match.group(4)}"
+    return '\n'.join(lines)
+
+
+class TasksView:
+    """State of the tasks view: the tasks listed and the selected one"""
+
+    def __init__(self):
+        """Initialize a closed view"""
+        self.is_open = False
+        self.tasks: List[Task] = []
+        self.selected_index = 0
+        self.show_done = False  # List checked tasks too
+
+    def open(self, tasks: List[Task]):
+        """
+        Show tasks, selecting the first
+
+        Args:
+            tasks: Tasks to list
+        """
+        self.is_open = True
+        self.tasks = tasks
+        self.selected_index = 0
+
+    def refresh(self, tasks: List[Task]):
+        """
+        Replace the listed tasks, keeping the selection near where it was
+
+        Args:
+            tasks: Tasks to list
+        """
+        self.tasks = tasks
+        self.selected_index = max(0, min(self.selected_index, len(tasks) - 1))
+
+    def close(self):
+        """Close the view"""
+        self.is_open = False
+        self.tasks = []
+
+    @property
+    def selected(self) -> Optional[Task]:
+        """Get the selected task"""
+        if 0 <= self.selected_index < len(self.tasks):
+            return self.tasks[self.selected_index]
+        return None
+
+    def row_of_selected(self) -> int:
+        """Get the sidebar row of the selected task, counting each note's heading row"""
+        notes = len({task.note_id for task in self.tasks[:self.selected_index + 1]})
+        return self.selected_index + notes
+
+    def move_selection_down(self):
+        """Select the next task"""
+        if self.selected_index < len(self.tasks) - 1:
+            self.selected_index += 1
+
+    def move_selection_up(self):
+        """Select the previous task"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
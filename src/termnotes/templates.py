@@ -0,0 +1,138 @@
+"""
+Note templates: starting content for new notes
+
+Templates are markdown files in the templates directory; the file name
+without ".md" is the template's name. Built-in meeting, todo and journal
+templates are available until a file with the same name replaces them.
+
+Placeholders in double braces are filled in when a note is created:
+
+- {{title}}: the title given when creating the note (default: the template name)
+- {{date}}, {{time}}, {{datetime}}: the current local date and time
+- {{weekday}}: the current day of the week
+- {{cursor}}: removed; the cursor starts there
+
+Unknown placeholders are left as they are.
+"""
+
+import re
+from dataclasses import dataclass
+from datetime import datetime
+from pathlib import Path
+from typing import List, Optional, Tuple
+from .i18n import t
+
+BUILTIN_TEMPLATES = ("meeting", "todo", "journal")
+CURSOR = "{{cursor}}"
+PLACEHOLDER = re.compile(r'\{\{\s*(\w+)\s*\}\}')
+
+
+@dataclass
+class Template:
+    """A named note template"""
+    name: str
+    content: str
+    path: Optional[Path] = None  # None for a built-in template
+
+
+def list_templates(directory: str) -> List[Template]:
+    """
+    Get the available templates, sorted by name
+
+    Args:
+        directory: Templates directory (may not exist)
+
+    Returns:
+        Built-in templates and the directory's .md files, files replacing
+        built-ins of the same name
+    """
+    templates = {name: Template(name, t(f"template.{name}")) for name in BUILTIN_TEMPLATES}
+    root = Path(directory).expanduser()
+    if root.is_dir():
+        for path in root.glob("*.md"):
+            try:
+                templates[path.stem] = Template(path.stem, path.read_text(encoding="utf-8"), path)
+            except OSError:
+                continue  # Unreadable; leave it out
+    return [templates[name] for name in sorted(templates, key=str.lower)]
+
+
+def find_template(templates: List[Template], name: str) -> Optional[Template]:
+    """Find a template by name, ignoring case"""
+    return next((template for template in templates if template.name.lower() == name.lower()), None)
+
+
+def render_template(template: Template, title: str = "", now: Optional[datetime] = None) -> Tuple[str, int, int]:
+    """
+    Fill in a template's placeholders
+
+    Args:
+        template: Template to render
+        title: Title of the new note (default: the template name)
+        now: Time to fill in (default: now, local time)
+
+    Returns:
+        Tuple of (content, cursor row, cursor column)
+    """
+    now = now or datetime.now()
+    values = {
+        "title": title or template.name.replace("-", " ").replace("_", " ").capitalize(),
+        "date": now.strftime("%Y-%m-%d"),
+        "time": now.strftime("%H:%M"),
+        "datetime": now.strftime("%Y-%m-%d %H:%M"),
+        "weekday": now.strftime("%A"),
+    }
+
+    def fill(text: str) -> str:
+        return PLACEHOLDER.sub(lambda match: values.get(match.group(1).lower(), match.group(0)), text)
+
+    content = template.content
+    cursor = content.find(CURSOR)
+    if cursor == -1:
+        return fill(content), 0, 0
+    before = fill(content[:cursor])
+    lines = before.split("\n")
+    return before + fill(content[cursor + len(CURSOR):]), len(lines) - 1, len(lines[-1])
+
+
+class TemplatePicker:
+    """State of the template picker shown when creating a note from a template"""
+
+    def __init__(self):
+        """Initialize a closed picker"""
+        self.templates: List[Template] = []
+        self.selected_index: int = 0
+        self.is_open = False
+
+    def open(self, templates: List[Template]):
+        """
+        Show the templates, selecting the first
+
+        Args:
+            templates: Templates to choose from
+        """
+        self.templates = templates
+        self.selected_index = 0
+        self.is_open = True
+
+    def close(self):
+        """Close the picker"""
+        self.templates = []
+        self.is_open = False
+
+    @property
+    def selected_template(self) -> Optional[Template]:
+        """Get the selected template"""
+        if 0 <= self.selected_index < len(self.templates):
+            return self.templates[self.selected_index]
+        return None
+
+    def move_selection_down(self):
+        """Select the next template"""
+        if self.selected_index < len(self.templates) - 1:
+            self.selected_index += 1
+
+    def move_selection_up(self):
+        """Select the previous template"""
+        if self.selected_index > 0:
+            self.selected_index -= 1
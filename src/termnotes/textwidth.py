@@ -0,0 +1,206 @@
+"""
+Display width and grapheme clusters of text, for CJK and emoji
+
+Python strings index code points, but a terminal shows East Asian wide
+characters and emoji in two columns, and draws a base character with the
+combining marks, variation selectors and zero-width joiners after it as one
+grapheme cluster. Widths here are measured as prompt_toolkit's renderer
+measures them, so padding and truncation line up with what it draws.
+"""
+
+import unicodedata
+from typing import Iterator, List, Tuple
+from prompt_toolkit.utils import get_cwidth
+
+ZERO_WIDTH_JOINER = "\u200d"
+
+
+def _is_extending(ch: str) -> bool:
+    """Check if a character belongs to the grapheme cluster before it"""
+    code = ord(ch)
+    return (
+        unicodedata.category(ch) in ("Mn", "Me", "Mc")  # Combining marks
+        or ch == ZERO_WIDTH_JOINER
+        or 0xFE00 <= code <= 0xFE0F  # Variation selectors
+        or 0x1F3FB <= code <= 0x1F3FF  # Emoji skin tone modifiers
+        or 0xE0020 <= code <= 0xE007F  # Emoji tag sequences (subdivision flags)
+        or 0xE0100 <= code <= 0xE01EF  # Variation selectors supplement
+    )
+
+
+def _is_regional_indicator(ch: str) -> bool:
+    """Check if a character is one of the letters that pair up into a flag"""
+    return 0x1F1E6 <= ord(ch) <= 0x1F1FF
+
+
+def cluster_end(text: str, index: int) -> int:
+    """
+    Find where the grapheme cluster starting at an index ends
+
+    Args:
+        text: Text to look in
+        index: Index of the cluster's first code point
+
+    Returns:
+        Index just after the cluster (index itself at the end of the text)
+    """
+    if index >= len(text):
+        return index
+    end = index + 1
+    if _is_regional_indicator(text[index]) and end < len(text) and _is_regional_indicator(text[end]):
+        end += 1
+    while end < len(text):
+        if text[end - 1] == ZERO_WIDTH_JOINER or _is_extending(text[end]):
+            end += 1
+        else:
+            break
+    return end
+
+
+def cluster_start(text: str, index: int) -> int:
+    """
+    Find where the grapheme cluster containing an index starts
+
+    Args:
+        text: Text to look in
+        index: Index of any code point in the cluster
+
+    Returns:
+        Index of the cluster's first code point
+    """
+    for start, cluster in clusters(text):
+        if start + len(cluster) > index:
+            return start
+    return len(text)
+
+
+def clusters(text: str) -> Iterator[Tuple[int, str]]:
+    """
+    Split text into grapheme clusters
+
+    Args:
+        text: Text to split
+
+    Yields:
+        Each cluster's start index and text
+    """
+    index = 0
+    while index < len(text):
+        end = cluster_end(text, index)
+        yield index, text[index:end]
+        index = end
+
+
+def text_width(text: str) -> int:
+    """Get the number of terminal columns text takes"""
+    return sum(get_cwidth(ch) for ch in text)
+
+
+def truncate(text: str, width: int, ellipsis: str = "...") -> str:
+    """
+    Shorten text to fit a number of columns, cutting between grapheme clusters
+
+    Args:
+        text: Text to shorten
+        width: Columns available
+        ellipsis: Added to show text was cut off, if it fits
+
+    Returns:
+        The text if it fits, else as much of it as fits with the ellipsis
+    """
+    if text_width(text) <= width:
+        return text
+    room = width - text_width(ellipsis)
+    if room < 0:
+        return fit(text, width)
+    return fit(text, room) + ellipsis
+
+
+def fit(text: str, width: int) -> str:
+    """
+    Cut text off at a number of columns, between grapheme clusters
+
+    Args:
+        text: Text to cut
+        width: Columns available
+
+    Returns:
+        The longest start of the text that fits
+    """
+    used = 0
+    for start, cluster in clusters(text):
+        used += text_width(cluster)
+        if used > width:
+            return text[:start]
+    return text
+
+
+def pad(text: str, width: int) -> str:
+    """Pad text with spaces to fill a number of columns"""
+    return text + " " * max(0, width - text_width(text))
+
+
+def column_of(text: str, index: int) -> int:
+    """Get the column where the code point at an index is drawn"""
+    return text_width(text[:index])
+
+
+def index_at_column(text: str, column: int) -> int:
+    """Get the index of the grapheme cluster drawn at a column (the text's length past its end)"""
+    used = 0
+    for start, cluster in clusters(text):
+        used += text_width(cluster)
+        if used > column:
+            return start
+    return len(text)
+
+
+def slice_columns(text: str, start: int, end: int, column: int = 0) -> str:
+    """
+    Get the part of text drawn between two columns
+
+    A wide character cut by either edge is replaced by spaces, so what's
+    left still lines up.
+
+    Args:
+        text: Text to slice
+        start: First column to keep
+        end: Column to stop before
+        column: Column where the text starts
+
+    Returns:
+        The text between the columns
+    """
+    result = []
+    for _, cluster in clusters(text):
+        width = text_width(cluster)
+        if column >= end:
+            break
+        if column >= start and column + width <= end:
+            result.append(cluster)
+        elif column + width > start:
+            result.append(" " * (min(end, column + width) - max(start, column)))
+        column += width
+    return "".join(result)
+
+
+def wrap_starts(text: str, width: int) -> List[int]:
+    """
+    Break text into rows of a number of columns, between grapheme clusters
+
+    Args:
+        text: Text to break
+        width: Columns of a row
+
+    Returns:
+        Index where each row starts, [0] for text that fits
+    """
+    starts = [0]
+    used = 0
+    for start, cluster in clusters(text):
+        cluster_width = text_width(cluster)
+        if used + cluster_width > width and used:
+            starts.append(start)
+            used = 0
+        used += cluster_width
+    return starts
@@ -0,0 +1,246 @@
+"""
+Color themes for the interface
+
+A theme gives each element of the interface (markdown headings, the
+selected note, the status bar...) a prompt_toolkit style string. Fragments
+are styled with "class:<element>" and EditorUI builds the application's
+Style from the theme, so no colors are hard-coded elsewhere.
+
+The [ui] colors setting picks a built-in theme; "auto", "solarized" and
+"gruvbox" choose the dark or light variant from the terminal background.
+The [colors] section overrides single elements:
+
+    [colors]
+    heading = "#ff8700 bold"
+    selection = "bg:#3a3a3a"
+"""
+
+import os
+from dataclasses import dataclass
+from typing import Dict, List, Mapping, Optional, Tuple
+from prompt_toolkit.styles import Style
+from .i18n import t
+
+# Styles of the dark theme, which uses the terminal's ANSI palette; every
+# element a theme may set is listed here
+DARK = {
+    "heading": "#ansicyan bold",
+    "code": "#ansigreen",  # Inline code and code fences
+    "quote": "#ansiyellow",
+    "bullet": "#ansimagenta bold",  # List bullets and numbers
+    "rule": "#ansicyan",
+    "emphasis": "#ansired",  # Bold and italic text, also made bold or italic
+    "link": "#ansiblue underline",
+    "image": "#ansimagenta underline",
+    "backlink": "#ansiblue",  # Titles under "Linked from"
+    "muted": "#ansibrightblack",  # Tags, "Linked from", search excerpts
+    "match": "#ansiyellow bold",  # Search terms in results and previews
+    "search": "bg:#ansiyellow #ansiblack",  # Matches of the last search in the editor
+    "misspelled": "#ansired underline",  # Words spellcheck doesn't know, in the editor
+    "cursor": "reverse",
+    "selection": "bg:#44475a",  # Visual mode selection
+    "selected": "reverse",  # Selected row of the sidebar when it has focus
+    "notebook": "bold",
+    "due": "#ansiyellow",  # Due date in the note list
+    "overdue": "#ansired bold",
+    "diff.added": "#ansigreen",
+    "diff.removed": "#ansired",
+    "diff.hunk": "#ansicyan",
+    "status": "reverse",
+    "status.info": "#ansibrightblack",  # Line under the status bar
+    "status.pending": "#ansiyellow",  # Changes not synced yet, on that line
+    "border": "#ansibrightblack",  # Pane title lines and the line between the panes
+    "border.focused": "#ansicyan bold",  # Title line of the pane with focus
+    "zen.footer": "#ansibrightblack",  # Word count below the editor in zen mode
+    "color.red": "#ansired",  # Bullet of a note with a color label in the note list
+    "color.yellow": "#ansiyellow",
+    "color.green": "#ansigreen",
+    "color.cyan": "#ansicyan",
+    "color.blue": "#ansiblue",
+    "color.magenta": "#ansimagenta",
+    "toast.info": "reverse",  # Notices over the bottom right corner
+    "toast.success": "#ansigreen reverse",
+    "toast.error": "#ansired reverse bold",
+    "label": "bold",  # Pane label and dialog choices
+    "dialog": "",
+    "syntax.keyword": "#ansicyan bold",  # Code with [ui] theme = "ansi"
+    "syntax.string": "#ansigreen",
+    "syntax.comment": "#ansibrightblack italic",
+    "syntax.number": "#ansiyellow",
+    "syntax.function": "#ansiblue",
+    "syntax.class": "#ansimagenta bold",
+    "syntax.operator": "#ansired",
+    "syntax.builtin": "#ansicyan",
+}
+
+# Light terminals: the same palette, with darker accents and a light selection
+LIGHT = {
+    **DARK,
+    "heading": "#ansiblue bold",
+    "quote": "#ansimagenta",
+    "rule": "#ansiblue",
+    "match": "#ansired bold underline",
+    "selection": "bg:#d0d0e8",
+    "syntax.keyword": "#ansiblue bold",
+    "syntax.number": "#ansimagenta",
+    "syntax.builtin": "#ansiblue",
+}
+
+
+def _palette_theme(base: Mapping[str, str], colors: Mapping[str, str], selection: str) -> Dict[str, str]:
+    """
+    Build a theme from a palette of named accent colors
+
+    Args:
+        base: Theme to take the elements without a color from
+        colors: Hex colors for "red", "green", "yellow", "blue", "magenta", "cyan" and "gray"
+        selection: Background color of the visual mode selection
+
+    Returns:
+        Element styles
+    """
+    return {
+        **base,
+        "heading": f"{colors['blue']} bold",
+        "code": colors["green"],
+        "quote": colors["yellow"],
+        "bullet": f"{colors['magenta']} bold",
+        "rule": colors["cyan"],
+        "emphasis": colors["red"],
+        "link": f"{colors['blue']} underline",
+        "image": f"{colors['magenta']} underline",
+        "backlink": colors["blue"],
+        "muted": colors["gray"],
+        "match": f"{colors['yellow']} bold",
+        "misspelled": f"{colors['red']} underline",
+        "selection": f"bg:{selection}",
+        "due": colors["yellow"],
+        "overdue": f"{colors['red']} bold",
+        "diff.added": colors["green"],
+        "diff.removed": colors["red"],
+        "diff.hunk": colors["cyan"],
+        "zen.footer": colors["gray"],
+        "status.info": colors["gray"],
+        "status.pending": colors["yellow"],
+        "color.red": colors["red"],
+        "color.yellow": colors["yellow"],
+        "color.green": colors["green"],
+        "color.cyan": colors["cyan"],
+        "color.blue": colors["blue"],
+        "color.magenta": colors["magenta"],
+        "border": colors["gray"],
+        "border.focused": f"{colors['blue']} bold",
+        "toast.success": f"{colors['green']} reverse",
+        "toast.error": f"{colors['red']} reverse bold",
+        "syntax.keyword": f"{colors['green']} bold",
+        "syntax.string": colors["cyan"],
+        "syntax.comment": f"{colors['gray']} italic",
+        "syntax.number": colors["magenta"],
+        "syntax.function": colors["blue"],
+        "syntax.class": f"{colors['yellow']} bold",
+        "syntax.operator": colors["red"],
+        "syntax.builtin": colors["blue"],
+    }
+
+
+SOLARIZED = {
+    "red": "#dc322f", "green": "#859900", "yellow": "#b58900", "blue": "#268bd2",
+    "magenta": "#d33682", "cyan": "#2aa198", "gray": "#839496",
+}
+GRUVBOX_DARK = {
+    "red": "#fb4934", "green": "#b8bb26", "yellow": "#fabd2f", "blue": "#83a598",
+    "magenta": "#d3869b", "cyan": "#8ec07c", "gray": "#928374",
+}
+GRUVBOX_LIGHT = {
+    "red": "#9d0006", "green": "#79740e", "yellow": "#b57614", "blue": "#076678",
+    "magenta": "#8f3f71", "cyan": "#427b58", "gray": "#7c6f64",
+}
+
+
+@dataclass
+class Theme:
+    """Styles for the interface and the Pygments style for code blocks"""
+    name: str
+    styles: Dict[str, str]
+    code_style: str = "ansi"  # Pygments style name, or "ansi" for the syntax.* styles
+
+    def style(self) -> Style:
+        """Build the prompt_toolkit style for the application"""
+        return Style.from_dict(self.styles)
+
+
+THEMES: Dict[str, Theme] = {
+    theme.name: theme for theme in (
+        Theme("dark", DARK),
+        Theme("light", LIGHT),
+        Theme("solarized-dark", _palette_theme(DARK, SOLARIZED, "#073642"), "solarized-dark"),
+        Theme("solarized-light", _palette_theme(LIGHT, {**SOLARIZED, "gray": "#657b83"}, "#eee8d5"),
+              "solarized-light"),
+        Theme("gruvbox-dark", _palette_theme(DARK, GRUVBOX_DARK, "#504945"), "gruvbox-dark"),
+        Theme("gruvbox-light", _palette_theme(LIGHT, GRUVBOX_LIGHT, "#d5c4a1"), "gruvbox-light"),
+    )
+}
+
+# Names choosing a variant by the terminal background
+FAMILIES = ("auto", "solarized", "gruvbox")
+
+
+def detect_background(environ: Optional[Mapping[str, str]] = None) -> str:
+    """
+    Guess whether the terminal has a dark or light background
+
+    Uses $COLORFGBG ("foreground;background" palette indexes), which
+    rxvt, Konsole, iTerm2 and others set; dark if it isn't set.
+
+    Args:
+        environ: Environment variables (default: os.environ)
+
+    Returns:
+        "dark" or "light"
+    """
+    environ = os.environ if environ is None else environ
+    background = environ.get("COLORFGBG", "").split(";")[-1]
+    if background.isdigit() and (int(background) == 7 or int(background) >= 9):
+        return "light"
+    return "dark"
+
+
+def theme_names() -> List[str]:
+    """Get the values the [ui] colors setting accepts"""
+    return list(FAMILIES) + list(THEMES)
+
+
+def load_theme(name: str, overrides: Mapping[str, str], background: Optional[str] = None) -> Tuple[Theme, List[str]]:
+    """
+    Get a built-in theme with the [colors] overrides applied
+
+    Args:
+        name: Theme name from the [ui] colors setting
+        overrides: Element styles from the [colors] section
+        background: "dark" or "light" (default: detected)
+
+    Returns:
+        Tuple of (theme, error messages); unknown names and invalid styles
+        are reported and left out
+    """
+    errors = []
+    if name in FAMILIES:
+        background = background or detect_background()
+        name = background if name == "auto" else f"{name}-{background}"
+    theme = THEMES.get(name)
+    if theme is None:
+        errors.append(t("config.unknown_colors", colors=name, names=", ".join(theme_names())))
+        theme = THEMES["dark"]
+
+    styles = dict(theme.styles)
+    for element, style in overrides.items():
+        if element not in DARK:
+            errors.append(t("config.unknown_color_element", element=element))
+            continue
+        try:
+            Style.from_dict({element: str(style)})
+        except ValueError:
+            errors.append(t("config.invalid_color", element=element, style=style))
+            continue
+        styles[element] = str(style)
+    return Theme(theme.name, styles, theme.code_style), errors
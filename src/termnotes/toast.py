@@ -0,0 +1,93 @@
+"""
+Toasts: short-lived notices shown over the bottom right corner of the screen
+
+Unlike the status bar message, which belongs to the command just run, a
+toast reports something that happened on its own or matters in every view:
+a save that failed, a background write or sync, a confirmation. Toasts can
+be shown from any thread; they disappear after a few seconds, errors
+later than the rest.
+"""
+
+import threading
+import time
+from dataclasses import dataclass
+from typing import Callable, List, Optional
+
+LEVELS = ("info", "success", "error")
+
+# Seconds a toast stays up, by level
+DURATIONS = {"info": 3.0, "success": 3.0, "error": 8.0}
+
+MAX_TOASTS = 3  # Shown at once; older ones make way
+
+
+@dataclass
+class Toast:
+    """One notice"""
+    text: str
+    level: str  # One of LEVELS
+    expires_at: float  # time.monotonic() when it goes away
+
+
+class ToastManager:
+    """The toasts currently shown"""
+
+    def __init__(self):
+        """Initialize with no toasts"""
+        self._toasts: List[Toast] = []
+        self._lock = threading.Lock()
+        self.on_change: Optional[Callable[[], None]] = None  # Told when a toast is shown, to redraw
+
+    def show(self, text: str, level: str = "info", duration: Optional[float] = None):
+        """
+        Show a toast
+
+        Args:
+            text: Text of the notice (one line)
+            level: "info", "success" or "error"
+            duration: Seconds to show it (default: by level)
+        """
+        duration = DURATIONS.get(level, DURATIONS["info"]) if duration is None else duration
+        toast = Toast(text.split('\n')[0], level, time.monotonic() + duration)
+        with self._lock:
+            # The same notice again only restarts its timer
+            self._toasts = [other for other in self._toasts if other.text != toast.text]
+            self._toasts = (self._toasts + [toast])[-MAX_TOASTS:]
+        if self.on_change:
+            self.on_change()
+
+    def info(self, text: str):
+        """Show an informational toast"""
+        self.show(text, "info")
+
+    def success(self, text: str):
+        """Show a confirmation toast"""
+        self.show(text, "success")
+
+    def error(self, text: str):
+        """Show an error toast"""
+        self.show(text, "error")
+
+    @property
+    def toasts(self) -> List[Toast]:
+        """Get the toasts still shown, oldest first"""
+        now = time.monotonic()
+        with self._lock:
+            self._toasts = [toast for toast in self._toasts if toast.expires_at > now]
+            return list(self._toasts)
+
+    def expire(self) -> bool:
+        """
+        Drop toasts whose time is up
+
+        Returns:
+            True if any went away, so the screen needs a redraw
+        """
+        with self._lock:
+            count = len(self._toasts)
+        return len(self.toasts) != count
+
+    def dismiss(self):
+        """Take every toast down"""
+        with self._lock:
+            self._toasts = []
@@ -0,0 +1,50 @@
+"""
+Tour: a few pages about the main keys, shown on first run
+
+It opens over the editor the first time termnotes runs on an empty notes
+store (or with --seed-demo), unless `tour = false` in [ui]; :tour shows
+it again. Each step's text comes from the locale ("tour.<step>") and is
+filled in with the current keys, so it stays right after remapping.
+"""
+
+from typing import Optional
+
+# Steps, in order; each has "tour.<step>.title" and "tour.<step>" locale strings
+TOUR_STEPS = ("welcome", "list", "editor", "find", "help")
+
+
+class TourView:
+    """State of the tour overlay: which step is shown"""
+
+    def __init__(self):
+        """Initialize a closed tour"""
+        self.is_open = False
+        self.step_index = 0
+
+    def open(self):
+        """Show the tour from its first step"""
+        self.is_open = True
+        self.step_index = 0
+
+    def close(self):
+        """Close the tour"""
+        self.is_open = False
+
+    @property
+    def step(self) -> Optional[str]:
+        """Get the name of the step shown"""
+        if self.is_open:
+            return TOUR_STEPS[self.step_index]
+        return None
+
+    def next(self):
+        """Show the next step, closing the tour after the last"""
+        if self.step_index < len(TOUR_STEPS) - 1:
+            self.step_index += 1
+        else:
+            self.close()
+
+    def previous(self):
+        """Show the previous step"""
+        if self.step_index > 0:
+            self.step_index -= 1
@@ -2,41 +2,317 @@
 UI components using prompt_toolkit
 """
 
+import asyncio
+import os
 import re
-from prompt_toolkit.application import Application
-from prompt_toolkit.layout import Layout, HSplit, VSplit, Window, FormattedTextControl, ConditionalContainer
+import shlex
+import shutil
+import signal
+import subprocess
+import sys
+import tempfile
+import textwrap
+import time
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Callable, List, Optional, Tuple
+from urllib.parse import quote, unquote
+from prompt_toolkit.application import Application, run_in_terminal
+from prompt_toolkit.layout import (
+    Layout, HSplit, VSplit, Window, FormattedTextControl, ConditionalContainer, Float, FloatContainer, Dimension
+)
+from prompt_toolkit.widgets import Frame
 from prompt_toolkit.formatted_text import FormattedText
 from prompt_toolkit.filters import Condition
+from prompt_toolkit.data_structures import Point
 from pygments import lex
 from pygments.lexers import get_lexer_by_name
 from pygments.lexers.special import TextLexer
 from pygments.util import ClassNotFound
+from pygments.styles import get_style_by_name
 from pygments.token import Token
 
 from .editor import EditorBuffer
 from .modes import ModeManager
 from .key_bindings import create_key_bindings
-from .note_list import NoteListManager
+from .note_list import NoteListManager, SORT_ORDERS
 from .focus import FocusManager
-from .storage import create_default_storage
-from .note import Note
+from .keymap import KeyMap
+from .history import HistoryView, diff_lines, merge_versions
+from .dialog import CHOICE_KEYS, ConfirmDialog
+from .replace import ReplaceView, parse_replace_arguments
+from .tasks import TasksView, task_on_line, toggle_task_line
+from .outline import OutlineView, extract_headings, heading_at
+from .help import HelpView
+from .tour import TOUR_STEPS, TourView
+from .demo import add_demo_notes
+from .recent import RecentNotes
+from .switcher import QuickSwitcher
+from .templates import Template, TemplatePicker, find_template, list_templates, render_template
+from .storage import (
+    CompositeBackend, DaemonBackend, EncryptedBackend, FilesystemBackend, NoteConflict, SecureBackend, StorageLock,
+    connect_daemon, create_default_storage, find_backend, find_sync_backend, storage_draft_path, storage_lock_path,
+    storage_recent_path
+)
+from .applock import AppLock, choose_lock_passphrase, load_lock_file
+from .secure import SECURE_PROPERTY, PassphrasePrompt, SecureNoteError, is_sealed
+from .config import get_config
+from .note import COLORS, Note, content_version
+from .notebook import Notebook
+from .i18n import t
+from .sync.protocol import SyncError
+from .share import SERVICES as SHARE_SERVICES, ShareError, share_note
+from .ai import PROVIDERS as AI_PROVIDERS, AIError, create_provider, suggest_tags, suggest_title, summarize, \
+    with_summary, with_title
+from .clipboard import MODES as CLIPBOARD_MODES, Clipboard, markdown_to_text
+from .attachments import find_attachment, format_size, open_with_system
+from .images import (
+    PROTOCOLS, ImageLink, clear_images, detect_protocol, find_image_links, image_format, image_link_at, image_size,
+    render_image
+)
+from .links import find_linked_note, find_urls, link_at, url_at
+from .mouse import SCROLL_LINES, MouseControl, is_click, scroll_direction
+from .lists import INDENT, parse_list_item
+from .livepreview import MIN_WIDTH as PREVIEW_MIN_WIDTH, LivePreview
+from .snippets import Snippets
+from .tables import Table, cell_at, cell_cursor, find_table, format_table
+from .spellcheck import SpellcheckError, Spellchecker, word_at
+from .search import fuzzy_match, highlight_positions, split_highlights
+from .stats import TextStats, text_stats
+from .metadata import format_age, format_ago, metadata_header
+from .theme import load_theme
+from .textwidth import cluster_end, column_of, fit, index_at_column, slice_columns, text_width, truncate, wrap_starts
+from .toast import ToastManager
+from .render_cache import RenderCache, RenderedNote
+from .drafts import Draft, DraftFile
+from .plugins import PluginContext, PluginManager, load_plugins
+from .reminders import (
+    DUE_TIME, NOTIFY_MODES, REMIND_TIME, format_due, format_time, is_overdue, parse_when, send_notification
+)
+from .utils import utc_now
+
+
+# Seconds between checks for notes changed by other programs
+LIVE_RELOAD_INTERVAL = 1.0
+
+# Seconds between checks for reminders that have come due
+REMINDER_INTERVAL = 30.0
+
+# Seconds between checks whether the editor has been idle long enough to lock
+IDLE_CHECK_INTERVAL = 1.0
+
+# Seconds between checks for toasts that have timed out
+TOAST_CHECK_INTERVAL = 0.5
+
+# Seconds between checks whether the live preview has edits to catch up with
+LIVE_PREVIEW_INTERVAL = 0.1
+
+# Seconds the count of changes not synced yet is shown before asking the storage again
+SYNC_STATUS_INTERVAL = 2.0
+
+# Widest the tour's text gets, in columns (with a space on each side)
+TOUR_WIDTH = 64
+
+# Color label of a note in the note list
+COLOR_BULLET = "\u25cf "
+
+# Fewest columns worth showing a note's description in, after its title
+MIN_DESCRIPTION_WIDTH = 8
+
+# Notes the quick switcher lists at once, and its width in columns
+SWITCHER_ROWS = 10
+SWITCHER_WIDTH = 60
+
+# Opening or closing line of a fenced code block: the fence, then the language
+# (any word, e.g. "python", "c++", "objective-c")
+CODE_FENCE = re.compile(r'^\s*(`{3,}|~{3,})\s*([\w+#.-]*)')
 
 
 class EditorUI:
     """Main editor UI using prompt_toolkit"""
 
-    def __init__(self, initial_text: str = ""):
+    def __init__(self, initial_text: str = "", accessible: bool = None, alt_screen: bool = None,
+                 seed_demo: bool = False):
+        """
+        Initialize the editor UI
+
+        Args:
+            initial_text: Text to load into the editor instead of the first note
+            accessible: Use the screen-reader-friendly layout (None = use config)
+            alt_screen: Draw on the terminal's alternate screen (None = use config)
+            seed_demo: Add the demo notes (see demo.py) and show the tour
+        """
+        config = get_config()
+        self.accessible = config.accessibility_enabled if accessible is None else accessible
+        self.alt_screen = config.alt_screen if alt_screen is None else alt_screen
+        self.live_reload = config.live_reload
+        # Notes can be browsed but not changed (see ReadOnlyBackend)
+        self.read_only = config.storage_read_only
+
+        config_errors = []
+
+        # Interface colors, which also suggest a style for code blocks
+        self.theme, theme_errors = load_theme(config.colors, config.color_overrides)
+        config_errors.extend(theme_errors)
+
+        # Code block colors: None uses the theme's syntax.* styles
+        self.code_style = None
+        code_theme = self.theme.code_style if config.theme == "auto" else config.theme
+        if code_theme != "ansi":
+            try:
+                self.code_style = get_style_by_name(code_theme)
+            except ClassNotFound:
+                config_errors.append(t("config.unknown_theme", theme=code_theme))
+
+        # Graphics protocol for showing images; None shows a placeholder
+        if config.images not in PROTOCOLS + ("auto", "off"):
+            config_errors.append(t("config.unknown_images", images=config.images))
+        self.image_protocol = detect_protocol(config.images)
+
+        # How reminders are shown when they come due
+        if config.reminders_notify not in NOTIFY_MODES:
+            config_errors.append(t("config.unknown_notify", notify=config.reminders_notify))
+        self.reminders_notify = config.reminders_notify if config.reminders_notify in NOTIFY_MODES else "desktop"
+
+        # System clipboard for y in the note list and, with [clipboard] yank, editor yanks
+        if config.clipboard_mode not in CLIPBOARD_MODES:
+            config_errors.append(t("config.unknown_clipboard", mode=config.clipboard_mode))
+        self.clipboard = Clipboard(config.clipboard_mode if config.clipboard_mode in CLIPBOARD_MODES else "auto")
+        self.clipboard_yank = config.clipboard_yank
+        self.clipboard_images = config.clipboard_images
+
+        # Where :share uploads notes
+        if config.share_service not in SHARE_SERVICES:
+            config_errors.append(t("config.unknown_share", service=config.share_service))
+        if config.ai_provider not in AI_PROVIDERS:
+            config_errors.append(t("config.unknown_ai", provider=config.ai_provider))
+
+        # Without modal editing the editor is always in insert mode, so never when read-only
+        if config.editing not in ("vim", "simple"):
+            config_errors.append(t("config.unknown_editing", editing=config.editing))
+        self.modal_editing = config.editing != "simple" or self.read_only
+
+        sort_order = config.sort_order
+        if sort_order not in SORT_ORDERS:
+            config_errors.append(t("config.unknown_sort", sort=sort_order))
+            sort_order = "updated"
+
+        self.sidebar_width_setting = config.sidebar_width
+        if not isinstance(self.sidebar_width_setting, (int, float)) or self.sidebar_width_setting <= 0:
+            config_errors.append(t("config.invalid_sidebar_width", width=self.sidebar_width_setting))
+            self.sidebar_width_setting = 30
+
+        self.borders = bool(config.borders)
+        self.descriptions = bool(config.descriptions)
+        self.metadata = bool(config.metadata)
+        self.status_line = bool(config.status_line)
+        self.mouse = bool(config.mouse)
+        # Underlines misspelled words while the editor has focus; None when off
+        self.spellchecker: Optional[Spellchecker] = None
+        if config.spellcheck_enabled:
+            languages = config.spellcheck_languages
+            if not isinstance(languages, list) or not all(isinstance(language, str) for language in languages):
+                config_errors.append(t("config.invalid_spell_languages", languages=languages))
+                languages = ["en"]
+            spellchecker = Spellchecker(languages, Path(config.spellcheck_dictionaries),
+                                        Path(config.spellcheck_personal_dictionary))
+            try:
+                spellchecker.load()
+                self.spellchecker = spellchecker
+            except SpellcheckError as e:
+                config_errors.append(str(e))
+        self.zen_width = config.zen_width
+        if not isinstance(self.zen_width, int) or isinstance(self.zen_width, bool) or self.zen_width < 20:
+            config_errors.append(t("config.invalid_zen_width", width=self.zen_width))
+            self.zen_width = 80
+        self.live_preview = bool(config.live_preview)
+        self.wrap_lines = bool(config.wrap_lines)  # Long lines wrap onto more rows instead of scrolling sideways
+        self.live_preview_pane = LivePreview()
+        self.live_preview_width = 0  # Columns of the live preview pane, 0 while it's hidden
+
+        # Unsaved edits are copied to a draft file, offered back after a crash;
+        # not with encryption, where the copy would reach the disk in the clear
+        self.draft_interval = config.draft_interval
+        if not isinstance(self.draft_interval, (int, float)) or self.draft_interval < 0:
+            config_errors.append(t("config.invalid_draft_interval", interval=self.draft_interval))
+            self.draft_interval = 5
+        if config.storage_backend == "encrypted" or self.read_only:
+            self.draft_interval = 0
+
+        # Editors share the notes through the daemon when there is one (see
+        # daemon.py); otherwise only one at a time may change them (raises StorageLocked)
+        self.storage = connect_daemon(config, start=config.daemon_enabled)
+        self.lock = StorageLock(storage_lock_path(config))
+        if self.storage is None:
+            self.lock.acquire()
+        self.drafts = DraftFile(storage_draft_path(config))
+        self.recent = RecentNotes(storage_recent_path(config))
+        # Draft left by a session that didn't exit normally, until the user restores or declines it
+        self.pending_draft: Optional[Draft] = self.drafts.load() if self.draft_interval else None
+        # Set by :q!, so quitting leaves no draft of the edits it threw away
+        self.discarding_edits = False
+
         # Core components
-        self.storage = create_default_storage()  # Composite: SQLite cache + filesystem
-        self.mode_manager = ModeManager()
+        if self.storage is None:
+            # Composite: SQLite cache + filesystem; a sync server is pulled from once the editor runs
+            self.storage = create_default_storage(background_sync=True)
+        demo_count = add_demo_notes(self.storage) if seed_demo and not self.read_only else 0
+        # Notes encrypted at rest, read after typing their passphrase (see secure.py)
+        self.secure_notes: SecureBackend = find_backend(self.storage, SecureBackend)
+        self.note_files: Optional[FilesystemBackend] = find_backend(self.storage, FilesystemBackend)
+        # Shown on the status line
+        self.storage_label = self._storage_label(config)
+        self.last_saved_at: Optional[datetime] = None  # When this session last saved a note
+        self._pending_sync: Tuple[Optional[float], int] = (None, 0)  # (time.monotonic() of the last check, count)
+        self.secure_prompt = PassphrasePrompt()
+
+        # Lock screen over the notes on launch and when idle
+        self.app_lock: Optional[AppLock] = None
+        if config.lock_enabled:
+            idle_minutes = config.lock_idle_minutes
+            if not isinstance(idle_minutes, (int, float)) or isinstance(idle_minutes, bool) or idle_minutes < 0:
+                config_errors.append(t("config.invalid_lock_idle", minutes=idle_minutes))
+                idle_minutes = 0
+            self.app_lock = self._create_app_lock(config, idle_minutes * 60)
+        self.mode_manager = ModeManager(read_only=self.read_only)
+        self.mode_manager.can_edit = lambda: not self.is_current_note_locked()
         self.buffer = EditorBuffer(initial_text, self.mode_manager)
-        self.note_list_manager = NoteListManager(self.storage)
+        if self.clipboard_yank:
+            self.buffer.on_yank = self.clipboard.copy
+        self.buffer.on_load = self.recent.touch
+        self.note_list_manager = NoteListManager(self.storage, sort_order)
         self.focus_manager = FocusManager()
+        self.history_view = HistoryView()
+        self.template_picker = TemplatePicker()
+        self.confirm_dialog = ConfirmDialog()
+        self.replace_view = ReplaceView()
+        self.tasks_view = TasksView()
+        self.outline_view = OutlineView()
+        self.help_view = HelpView()
+        self.tour = TourView()
+        self.switcher = QuickSwitcher()
+        self.toasts = ToastManager()
+        self.templates_directory = config.templates_directory
+        # Abbreviations expanded while typing
+        self.snippets = Snippets(config.snippets, config.templates_directory)
+        config_errors.extend(self.snippets.errors)
+        # Plugins add commands, key actions (remappable like built-in ones) and transforms
+        self.plugins = load_plugins(config.plugins_directory) if config.plugins_enabled else PluginManager()
+        self.plugin_context = PluginContext(self)
+        self.keymap = KeyMap(config.keys, self.plugins.key_actions(), read_only=self.read_only)
+        config_errors.extend(self.plugins.errors)
         self.pending_note_switch = None  # For handling unsaved changes confirmation
+        self.pending_new_note = ("", (0, 0))  # Content and cursor for the next new note
         self.pending_deletion = None  # For handling deletion confirmation
+        self._stats_cache: Tuple[str, TextStats] = ("", text_stats(""))  # Last counted content
+        self.render_cache = RenderCache()  # Styled lines of the notes shown lately
+        self._lexers = {}  # Pygments lexers by code block language
         self.editor_window_height = 24  # Default, will be updated dynamically
         self.editor_window_width = 80  # Default, will be updated dynamically
 
+        # (note ID, note list, notes linking to it); refreshed when either changes
+        self._backlinks = (None, None, [])
+
         # Load first note into editor if no initial text
         if not initial_text and self.note_list_manager.selected_note:
             first_note = self.note_list_manager.selected_note
@@ -51,152 +327,2890 @@ class EditorUI:
             self  # Pass UI instance for save/load operations
         )
 
+        if self.pending_draft and not initial_text:
+            self.offer_draft(self.pending_draft)
+        else:
+            self.pending_draft = None
+
+        # Report damaged note files, offering to salvage them unless a draft is being offered
+        self.report_damaged_notes(offer=not self.confirm_dialog.is_open)
+
+        # On first run, or trying the demo, show the tour (after any draft to restore)
+        if demo_count:
+            self.mode_manager.set_message(t("msg.demo_added", count=demo_count))
+        if (self.storage.first_run or seed_demo) and config.tour and not self.confirm_dialog.is_open:
+            self.tour.open()
+
+        # Report invalid config settings, including entries in the [keys] and [hooks] sections
+        config_errors.extend(self.keymap.errors)
+        if self.storage.hooks:
+            config_errors.extend(self.storage.hooks.errors)
+        if config_errors:
+            self.mode_manager.set_message("; ".join(config_errors))
+
+    def _create_app_lock(self, config, idle_timeout: float) -> AppLock:
+        """
+        Set up the lock screen, unlocked by the encryption passphrase or the lock passphrase
+
+        Starts unlocked when the passphrase was just typed: asked for to open
+        the encrypted backend, or chosen on this first launch.
+        """
+        encrypted = find_backend(self.storage, EncryptedBackend)
+        if encrypted is not None:
+            return AppLock(encrypted.matches_passphrase, idle_timeout, locked=not config.encrypted_prompt_passphrase)
+        verify = load_lock_file(Path(config.lock_file))
+        if verify is not None:
+            return AppLock(verify, idle_timeout)
+        try:
+            verify = choose_lock_passphrase(Path(config.lock_file))
+        except SystemExit:
+            self.storage.close()
+            self.lock.release()
+            raise
+        return AppLock(verify, idle_timeout, locked=False)
+
+    def lock_app(self):
+        """Hide the notes behind the lock screen, keeping unsaved edits in the draft, and lock the secure notes"""
+        secure_locked = self.lock_secure_notes()
+        if self.app_lock is None:
+            self.mode_manager.set_message(t("secure.locked" if secure_locked else "lock.off"))
+            return
+        if self.draft_interval:
+            self.save_draft()
+        self.app_lock.lock()
+
+    def is_locked(self) -> bool:
+        """Check if the lock screen hides the notes"""
+        return self.app_lock is not None and self.app_lock.is_locked
+
+    async def _watch_idle(self, app: Application):
+        """Lock the editor once it has been idle for the lock's idle timeout"""
+        while True:
+            await asyncio.sleep(IDLE_CHECK_INTERVAL)
+            if self.app_lock.is_idle():
+                self.lock_app()
+                app.invalidate()
+
+    def unlock_secure_notes(self, then: Optional[Callable[[], None]] = None):
+        """
+        Ask for the secure notes' passphrase, then show them decrypted
+
+        Args:
+            then: Action to run once unlocked; with no secure notes yet, the
+                passphrase asked for is a new one, typed twice
+        """
+        secure = self.secure_notes
+        if secure.vault.unlocked:
+            if then is not None:
+                then()
+            else:
+                self.mode_manager.set_message(t("secure.already_unlocked"))
+            return
+        new = not secure.has_secure_notes()
+        if new and then is None:
+            self.mode_manager.set_message(t("secure.none"))
+            return
+
+        def submit(passphrase: str) -> Optional[str]:
+            if not secure.unlock(passphrase):
+                return t("secure.wrong_passphrase")
+            self._show_secure_notes()
+            if then is not None:
+                then()
+            else:
+                self.mode_manager.set_message(t("secure.unlocked"))
+            return None
+
+        self.secure_prompt.open(t("secure.new_title" if new else "secure.unlock_title"), submit, confirm=new)
+
+    def lock_secure_notes(self) -> bool:
+        """
+        Forget the secure notes' passphrase, saving edits to an open secure note first
+
+        Returns:
+            False if they weren't unlocked
+        """
+        secure = self.secure_notes
+        if not secure.vault.unlocked:
+            return False
+        note = self.get_current_note()
+        if self.buffer.is_dirty and note is not None and note.is_secure:
+            self.save_current_note()
+        secure.vault.lock()
+        self._show_secure_notes()
+        return True
+
+    def _show_secure_notes(self):
+        """Show the secure notes decrypted or locked, after unlocking or locking them"""
+        selected = self.note_list_manager.selected_note
+        self.note_list_manager.reload_notes()
+        if selected:
+            self.note_list_manager.select_note_by_id(selected.id)
+        self._show_stored_note()
+
+    def set_note_secure(self, note: Note, secure: bool):
+        """
+        Make a note secure, encrypted at rest, or store it as plain text again
+
+        Asks for the secure notes' passphrase first while they're locked. A
+        new unsaved note is made secure in memory until it is saved.
+
+        Args:
+            note: Note to change
+            secure: Whether it should be secure
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if self.read_only:
+            self.mode_manager.set_message(t("storage.read_only"))
+            return
+        if note.is_secure == secure:
+            self.mode_manager.set_message(t("secure.already_on" if secure else "secure.already_off"))
+            return
+        if not self.secure_notes.vault.unlocked:
+            in_memory = note is self.note_list_manager.in_memory_note
+            self.unlock_secure_notes(
+                lambda: self.set_note_secure(note if in_memory else self.storage.get_note(note.id), secure)
+            )
+            return
+
+        if note is self.note_list_manager.in_memory_note:
+            if secure:
+                note.set_property(SECURE_PROPERTY, True)
+            else:
+                note.delete_property(SECURE_PROPERTY)
+        else:
+            try:
+                self.secure_notes.set_secure(note.id, secure)
+            except SecureNoteError as e:
+                self.mode_manager.set_message(str(e))
+                return
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self.mode_manager.set_message(t("secure.on" if secure else "secure.off"))
+
+    def is_current_note_locked(self) -> bool:
+        """Check if the open note is a secure note that can't be read until it's unlocked"""
+        if not is_sealed(self.buffer.base_content or ""):
+            return False
+        note = self.get_current_note()
+        return note is not None and note.is_locked
+
+    def get_locked_note_content(self):
+        """Get formatted text for the editor in place of a locked secure note's encrypted block"""
+        return FormattedText([
+            ('class:label', t("secure.locked_title")), ('', '\n\n'),
+            ('', t("secure.locked_hint")),
+        ])
+
+    def get_secure_prompt_content(self):
+        """Get formatted text for the secure notes' passphrase prompt: a dot for each character typed, and any error"""
+        prompt = self.secure_prompt
+        result = [
+            ('', f" {prompt.label}"), ('', '\u2022' * len(prompt.typed)), ('[SetCursorPosition]', ''), ('', ' '),
+        ]
+        if prompt.error:
+            result.extend([('', '\n '), ('class:toast.error', prompt.error)])
+        return FormattedText(result)
+
+    def get_lock_content(self):
+        """Get formatted text for the lock screen: a dot for each character typed, and any error"""
+        app_lock = self.app_lock
+        result = [
+            ('class:label', t("lock.title")), ('', '\n\n'),
+            ('', t("lock.prompt")), ('', '\u2022' * len(app_lock.typed)), ('[SetCursorPosition]', ''), ('', '\n'),
+        ]
+        if app_lock.error:
+            result.extend([('', '\n'), ('class:toast.error', app_lock.error)])
+        return FormattedText(result)
+
+    def get_current_note(self):
+        """
+        Get the note loaded in the editor as it is stored
+
+        Returns the in-memory note for a new unsaved note. The content is the
+        last saved version, not the buffer's; use it for metadata.
+
+        Returns:
+            Note if one is loaded, None otherwise
+        """
+        note_id = self.buffer.current_note_id
+        if not note_id:
+            return None
+
+        in_memory_note = self.note_list_manager.in_memory_note
+        if in_memory_note and in_memory_note.id == note_id:
+            return in_memory_note
+
+        return self.storage.get_note(note_id)
+
     def save_current_note(self):
         """Save the current buffer content to the database"""
+        if self.read_only:
+            self.mode_manager.set_message(t("storage.read_only"))
+            return
+        if self.is_current_note_locked():
+            # Its encrypted block can only be stored as it is
+            self.mode_manager.set_message(t("secure.locked_save"))
+            return
         if self.buffer.current_note_id:
+            # Keep metadata (tags, creation time) from the stored note
+            existing = self.get_current_note()
             note = Note(
                 note_id=self.buffer.current_note_id,
-                content=self.buffer.get_text()
+                content=self.buffer.get_text(),
+                created_at=existing.created_at if existing else None,
+                properties=existing.properties if existing else None
             )
-            self.storage.save_note(note)
+            action = "create" if self.buffer.is_new_unsaved else "save"
+            # Unless the note changed in storage since the edits started (another
+            # editor on a shared database, a sync, a program writing the files)
+            expected = None if self.buffer.is_new_unsaved else content_version(self.buffer.base_content)
+            try:
+                with self.storage.journal_operation(action, [note.id]):
+                    self.storage.update_note(note, expected)
+            except NoteConflict as e:
+                self.resolve_conflict(e.stored)
+                return
+            except Exception as e:
+                # Any backend error (disk full, server down): keep the edits and say so
+                self.toasts.error(t("toast.save_failed", error=e))
+                return
             self.buffer.mark_clean()
+            self.buffer.base_content = note.content
+
+            # If this was a new unsaved note, it's now in storage
+            if self.buffer.is_new_unsaved:
+                self.buffer.is_new_unsaved = False
+                # Clear the in-memory note from sidebar
+                self.note_list_manager.clear_in_memory_note()
+
+            self.note_list_manager.update_note(note)
+
+            # Update selection to point to the saved note (it may have moved in the list)
+            self.note_list_manager.select_note_by_id(self.buffer.current_note_id)
+
+            self.drafts.clear()
+            self.last_saved_at = utc_now()
+            self._pending_sync = (None, self._pending_sync[1])
+            self.mode_manager.clear_message()
+            self.toasts.success(t("msg.note_saved"))
+        else:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+
+    def resolve_conflict(self, stored: Note):
+        """
+        Ask what to do with edits to a note that changed in storage meanwhile
+
+        Args:
+            stored: The note as it is stored now
+        """
+        self.pending_deletion = None
+        self.confirm_dialog.choose(t("dialog.conflict", title=stored.title or t("note.empty_preview")), [
+            ("m", t("dialog.conflict_merge"), lambda: self.merge_conflict(stored)),
+            ("o", t("dialog.conflict_overwrite"), lambda: self.overwrite_conflict(stored)),
+            ("d", t("dialog.conflict_discard"), lambda: self.discard_conflict(stored)),
+        ])
+        self.mode_manager.clear_message()
+
+    def merge_conflict(self, stored: Note):
+        """
+        Combine the edits with the stored version, leaving the result unsaved to check
+
+        Args:
+            stored: The note as it is stored now
+        """
+        merged, conflicted = merge_versions(
+            self.buffer.base_content, self.buffer.get_text(), stored.content,
+            t("merge.yours"), t("merge.stored")
+        )
+        row, col = self.buffer.cursor_row, self.buffer.cursor_col
+        self.buffer.load_content(merged, stored.id)
+        self.buffer.cursor_row = min(row, len(self.buffer.lines) - 1)
+        self.buffer.cursor_col = min(col, len(self.buffer.lines[self.buffer.cursor_row]))
+        self.buffer.base_content = stored.content
+        self.buffer.mark_dirty()
+        self.mode_manager.set_message(t("msg.merge_conflicts" if conflicted else "msg.merged"))
+
+    def overwrite_conflict(self, stored: Note):
+        """
+        Save the edits over the stored version
+
+        Args:
+            stored: The note as it is stored now
+        """
+        self.buffer.base_content = stored.content
+        self.save_current_note()
+
+    def discard_conflict(self, stored: Note):
+        """
+        Drop the edits and show the stored version
+
+        Args:
+            stored: The note as it is stored now
+        """
+        self.buffer.load_content(stored.content, stored.id)
+        self.drafts.clear()
+        self.note_list_manager.update_note(stored)
+        self.mode_manager.set_message(t("msg.conflict_discarded"))
+
+    def tag_current_note(self, tag: str, remove: bool = False):
+        """
+        Add or remove a tag on the note loaded in the editor
+
+        Tags on a new unsaved note are kept in memory until it is saved.
+
+        Args:
+            tag: Tag name
+            remove: Remove the tag instead of adding it
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        tag = Note.normalize_tag(tag)
+        if not tag:
+            self.mode_manager.set_message(t("msg.tag_usage"))
+            return
+
+        if remove and not note.has_tag(tag):
+            self.mode_manager.set_message(t("msg.tag_not_found", tag=tag))
+            return
+
+        if self.buffer.is_new_unsaved:
+            if remove:
+                note.remove_tag(tag)
+            else:
+                note.add_tag(tag)
+        elif remove:
+            self.storage.remove_tag(note.id, tag)
+        else:
+            self.storage.add_tag(note.id, tag)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+
+        if remove:
+            self.mode_manager.set_message(t("msg.tag_removed", tag=tag))
+        else:
+            self.mode_manager.set_message(t("msg.tag_added", tag=tag))
+
+    def tag_marked_notes(self, tag: str, remove: bool = False):
+        """
+        Add or remove a tag on every note marked in the sidebar
+
+        Args:
+            tag: Tag name
+            remove: Remove the tag instead of adding it
+        """
+        tag = Note.normalize_tag(tag)
+        if not tag:
+            self.mode_manager.set_message(t("msg.tag_usage"))
+            return
+
+        notes = self.note_list_manager.marked_notes
+        with self.storage.journal_operation("untag_many" if remove else "tag_many", [note.id for note in notes]):
+            for note in notes:
+                if remove:
+                    self.storage.remove_tag(note.id, tag)
+                else:
+                    self.storage.add_tag(note.id, tag)
+        self.note_list_manager.reload_notes()
+        self.mode_manager.set_message(t(
+            "msg.notes_untagged" if remove else "msg.notes_tagged",
+            count=len(notes),
+            tag=tag
+        ))
+
+    def set_note_pinned(self, note: Note, pinned: bool):
+        """
+        Pin a note to the top of the list, or unpin it
+
+        A new unsaved note is pinned in memory until it is saved.
+
+        Args:
+            note: Note to pin or unpin
+            pinned: Whether the note should be pinned
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        if note is self.note_list_manager.in_memory_note:
+            if pinned:
+                note.set_property("pinned", True)
+            else:
+                note.delete_property("pinned")
+        else:
+            self.storage.set_pinned(note.id, pinned)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self.mode_manager.set_message(t("msg.note_pinned" if pinned else "msg.note_unpinned"))
+
+    def set_note_starred(self, note: Note, starred: bool):
+        """
+        Star a note as a favorite, or unstar it
+
+        A new unsaved note is starred in memory until it is saved.
+
+        Args:
+            note: Note to star or unstar
+            starred: Whether the note should be starred
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        if note is self.note_list_manager.in_memory_note:
+            if starred:
+                note.set_property("starred", True)
+            else:
+                note.delete_property("starred")
+        else:
+            self.storage.set_starred(note.id, starred)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self.mode_manager.set_message(t("msg.note_starred" if starred else "msg.note_unstarred"))
+
+    def toggle_starred(self):
+        """Switch the sidebar between only the starred notes and every note"""
+        if self.note_list_manager.toggle_starred():
+            self.mode_manager.set_message(t(
+                "msg.starred_shown",
+                count=len(self.note_list_manager.notes),
+                show_starred=self.keymap.label("show_starred")
+            ))
+        else:
+            self.mode_manager.set_message(t("msg.starred_hidden"))
+
+    def set_note_color(self, note: Note, color: Optional[str]):
+        """
+        Set or clear a note's color label
+
+        A new unsaved note keeps its color in memory until it is saved.
+
+        Args:
+            note: Note to label
+            color: One of COLORS, or None to clear the label
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        if note is self.note_list_manager.in_memory_note:
+            if color:
+                note.set_property("color", color)
+            else:
+                note.delete_property("color")
+        else:
+            self.storage.set_color(note.id, color)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        if color:
+            self.mode_manager.set_message(t("msg.color_set", color=t(f"color.{color}")))
+        else:
+            self.mode_manager.set_message(t("msg.color_cleared"))
+
+    def cycle_note_color(self, note: Note):
+        """Give a note the next color label in COLORS, or none after the last"""
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        index = COLORS.index(note.color) + 1 if note.color else 0
+        self.set_note_color(note, COLORS[index] if index < len(COLORS) else None)
+
+    def set_color_filter(self, color: Optional[str]):
+        """
+        List only the notes with a color label, or every note
+
+        Args:
+            color: One of COLORS, or None to clear the filter
+        """
+        self.note_list_manager.set_color_filter(color)
+        self._report_color_filter()
+
+    def cycle_color_filter(self):
+        """Filter the sidebar by the next color label in use, then show every note again"""
+        self.note_list_manager.cycle_color_filter()
+        self._report_color_filter()
+
+    def _report_color_filter(self):
+        """Say which color the sidebar is filtered by"""
+        color = self.note_list_manager.color_filter
+        if color:
+            self.mode_manager.set_message(t(
+                "msg.color_filter",
+                color=t(f"color.{color}"),
+                count=len(self.note_list_manager.notes)
+            ))
+        else:
+            self.mode_manager.set_message(t("msg.color_filter_cleared"))
+
+    def schedule_current_note(self, kind: str, when: str):
+        """
+        Set or clear the due date or reminder of the note loaded in the editor
+
+        A new unsaved note keeps them in memory until it is saved.
+
+        Args:
+            kind: "due" or "remind"
+            when: Time as typed by the user (see reminders.parse_when), or "" to clear
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        when = when.strip()
+        at = None
+        if when:
+            at = parse_when(when, DUE_TIME if kind == "due" else REMIND_TIME)
+            if at is None:
+                self.mode_manager.set_message(t("msg.when_usage", text=when))
+                return
+
+        if note is self.note_list_manager.in_memory_note:
+            key = "due_at" if kind == "due" else "remind_at"
+            if at:
+                note.set_property(key, at.isoformat())
+            else:
+                note.delete_property(key)
+        elif kind == "due":
+            self.storage.set_due(note.id, at)
+        else:
+            self.storage.set_reminder(note.id, at)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        if at:
+            self.mode_manager.set_message(t(f"msg.{kind}_set", when=format_time(at)))
+        else:
+            self.mode_manager.set_message(t(f"msg.{kind}_cleared"))
+
+    def check_reminders(self) -> bool:
+        """
+        Show the reminders that have come due, removing them from their notes
+
+        Returns:
+            True if any reminder was shown
+        """
+        notes = self.storage.pop_due_reminders()
+        if not notes:
+            return False
+        if self.reminders_notify == "desktop":
+            for note in notes:
+                send_notification(t("msg.reminder_title"), note.title or t("note.empty_preview"))
+        self.mode_manager.set_message(t(
+            "msg.reminder", titles=", ".join(note.title or t("note.empty_preview") for note in notes)
+        ))
+        self.note_list_manager.reload_notes()
+        return True
+
+    async def _watch_reminders(self, app: Application):
+        """Check for reminders that have come due while the editor runs"""
+        while True:
+            try:
+                shown = self.check_reminders()
+            except OSError:
+                shown = False  # Try again on the next check
+            if shown:
+                app.invalidate()
+            await asyncio.sleep(REMINDER_INTERVAL)
+
+    def move_selected_note(self, offset: int):
+        """
+        Move the note selected in the sidebar up or down, in the manual sort order
+
+        Args:
+            offset: Number of places to move (negative moves up)
+        """
+        note_list = self.note_list_manager
+        note = note_list.selected_note
+        if note is None or note is note_list.in_memory_note or note_list.is_showing_search_results():
+            return
+        if note_list.sort_order != "manual":
+            self.mode_manager.set_message(t("msg.reorder_needs_manual"))
+            return
+        note_list.move_note(note.id, offset)
+
+    def attach_file(self, path: str):
+        """
+        Attach a copy of a file to the note loaded in the editor
+
+        Args:
+            path: File to attach
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if self.buffer.is_new_unsaved:
+            self.mode_manager.set_message(t("msg.attach_unsaved"))
+            return
+        path = path.strip()
+        if not path:
+            self.mode_manager.set_message(t("msg.attach_usage"))
+            return
+
+        try:
+            attachment = self.storage.add_attachment(note.id, path)
+        except OSError as e:
+            self.mode_manager.set_message(t("msg.attach_failed", error=e))
+            return
+        self.note_list_manager.update_note(self.storage.get_note(note.id))
+        self.mode_manager.set_message(t("msg.attached", name=attachment.name, size=format_size(attachment.size)))
+
+    def list_attachments(self):
+        """Show the files attached to the note loaded in the editor"""
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        attachments = self.storage.list_attachments(note.id)
+        if not attachments:
+            self.mode_manager.set_message(t("msg.no_attachments"))
+            return
+        listing = "  ".join(
+            f"{i}. {attachment.name} ({format_size(attachment.size)})"
+            for i, attachment in enumerate(attachments, start=1)
+        )
+        self.mode_manager.set_message(t("msg.attachments_list", attachments=listing))
+
+    def _find_attachment(self, key: str):
+        """
+        Find an attachment of the loaded note by number or name, reporting problems
+
+        With no key, a note's only attachment is used.
+
+        Args:
+            key: Attachment number (from 1) or name
+
+        Returns:
+            Tuple of (note, attachment), or None after showing why not
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return None
+        attachments = self.storage.list_attachments(note.id)
+        if not attachments:
+            self.mode_manager.set_message(t("msg.no_attachments"))
+            return None
+        key = key.strip()
+        if not key and len(attachments) > 1:
+            self.mode_manager.set_message(t("msg.attachment_which"))
+            return None
+        attachment = find_attachment(attachments, key) if key else attachments[0]
+        if attachment is None:
+            self.mode_manager.set_message(t("msg.attachment_not_found", name=key))
+            return None
+        return note, attachment
+
+    def open_attachment(self, key: str):
+        """
+        Open a file attached to the loaded note with the system's default program
+
+        Args:
+            key: Attachment number (from 1) or name; may be empty if there's only one
+        """
+        found = self._find_attachment(key)
+        if found is None:
+            return
+        note, attachment = found
+        path = self.storage.attachment_path(note.id, attachment.name)
+        if not path.exists():
+            self.mode_manager.set_message(t("msg.attachment_missing", path=path))
+            return
+        try:
+            open_with_system(path)
+        except OSError as e:
+            self.mode_manager.set_message(t("msg.attachment_open_failed", error=e))
+            return
+        self.mode_manager.set_message(t("msg.attachment_opened", name=attachment.name))
+
+    def remove_attachment(self, key: str):
+        """
+        Remove a file from the loaded note, deleting its copy
+
+        Args:
+            key: Attachment number (from 1) or name; may be empty if there's only one
+        """
+        found = self._find_attachment(key)
+        if found is None:
+            return
+        note, attachment = found
+        self.storage.remove_attachment(note.id, attachment.name)
+        self.note_list_manager.update_note(self.storage.get_note(note.id))
+        self.mode_manager.set_message(t("msg.attachment_removed", name=attachment.name))
+
+    def get_external_editor_command(self) -> List[str]:
+        """Get the external editor command from config, $VISUAL, or $EDITOR (default vi)"""
+        command = (
+            get_config().external_editor
+            or os.environ.get("VISUAL")
+            or os.environ.get("EDITOR")
+            or "vi"
+        )
+        return shlex.split(command)
+
+    def edit_in_external_editor(self):
+        """
+        Edit a note in the external editor, suspending the UI until it exits
+
+        Edits the note selected in the sidebar, or the note loaded in the
+        editor. Unsaved changes in the editor are included and saved along
+        with the external edits.
+        """
+        if self.focus_manager.is_sidebar_focused():
+            note = self.note_list_manager.selected_note
+        else:
+            note = self.get_current_note()
+
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        run_in_terminal(lambda: self._run_external_editor(note))
+
+    def copy_selected_note(self, rendered: bool = False):
+        """
+        Copy the note selected in the sidebar to the system clipboard
+
+        The open note is copied as shown in the editor, unsaved edits included.
+
+        Args:
+            rendered: Copy the text without markdown instead of the markdown
+        """
+        note = self.note_list_manager.selected_note
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        content = self.buffer.get_text() if note.id == self.buffer.current_note_id else note.content
+        copied = self.clipboard.copy(markdown_to_text(content) if rendered else content)
+        if copied is None:
+            self.mode_manager.set_message(t("msg.copy_failed"))
+            return
+        title = note.title or t("note.empty_preview")
+        self.mode_manager.set_message(t(
+            "msg.copied_osc52" if copied == "osc52" else "msg.copied_text" if rendered else "msg.copied",
+            title=title
+        ))
+
+    def load_clipboard(self):
+        """
+        Take text another program put on the system clipboard into the yank register
+
+        Does nothing unless [clipboard] yank is on, or when the clipboard holds
+        what termnotes last copied (the register has it already).
+        """
+        if not self.clipboard_yank:
+            return
+        text = self.clipboard.paste()
+        if not text:
+            return
+        # Some tools add or drop a final newline on the way through the clipboard
+        if text.rstrip('\n') != (self.clipboard.last_copied or "").rstrip('\n'):
+            self.buffer.load_register(text)
+            self.clipboard.last_copied = text
+
+    def paste_clipboard(self):
+        """Paste the system clipboard (or the yank register) at the cursor in insert mode, attaching an image on it"""
+        if self.clipboard_images:
+            image = self.clipboard.paste_image()
+            if image is not None:
+                self.paste_image(image)
+                return
+        self.load_clipboard()
+        if not self.buffer.yank_register:
+            self.mode_manager.set_message(t("msg.nothing_to_paste"))
+            return
+        self.buffer.paste_text(self.buffer.yank_register, self.editor_window_height)
+
+    def paste_image(self, data: bytes):
+        """
+        Attach an image to the note in the editor and link it at the cursor
+
+        The image is named after when it was pasted ("image-20261016-143000.png"),
+        as Typora and Obsidian name pasted screenshots.
+
+        Args:
+            data: Image file content
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if self.buffer.is_new_unsaved:
+            self.mode_manager.set_message(t("msg.attach_unsaved"))
+            return
+        extension = {"jpeg": "jpg"}.get(image_format(data), image_format(data))
+        name = f"image-{datetime.now():%Y%m%d-%H%M%S}.{extension}"
+
+        try:
+            with tempfile.TemporaryDirectory(prefix="termnotes-") as directory:
+                path = Path(directory) / name
+                path.write_bytes(data)
+                attachment = self.storage.add_attachment(note.id, str(path))
+        except OSError as e:
+            self.mode_manager.set_message(t("msg.attach_failed", error=e))
+            return
+        if attachment is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        self.note_list_manager.update_note(self.storage.get_note(note.id))
+        # The link names the attachment, which is where show_image() looks for it
+        link = f"![{Path(attachment.name).stem}]({quote(attachment.name)})"
+        self.buffer.paste_text(link, self.editor_window_height)
+        self.mode_manager.set_message(t("msg.image_pasted", name=attachment.name, size=format_size(attachment.size)))
+
+    def _run_external_editor(self, note: Note):
+        """
+        Open a note in the external editor via a temp file and save the result
+
+        Args:
+            note: Note to edit
+        """
+        is_loaded = note.id == self.buffer.current_note_id
+        content = self.buffer.get_text() if is_loaded else note.content
+        command = self.get_external_editor_command()
+
+        fd, path = tempfile.mkstemp(prefix="termnotes-", suffix=".md")
+        try:
+            with os.fdopen(fd, "w", encoding="utf-8") as f:
+                f.write(content)
+
+            try:
+                returncode = subprocess.call(command + [path])
+            except OSError as e:
+                self.mode_manager.set_message(t("msg.external_editor_failed", command=command[0], error=e))
+                return
+            if returncode != 0:
+                self.mode_manager.set_message(t("msg.external_editor_exit", command=command[0], code=returncode))
+                return
+
+            with open(path, "r", encoding="utf-8") as f:
+                new_content = f.read()
+        finally:
+            if os.path.exists(path):
+                os.unlink(path)
+
+        # Most editors end the file with a newline; don't add a blank last line
+        if new_content.endswith("\n") and not content.endswith("\n"):
+            new_content = new_content[:-1]
+
+        if new_content == content and not (is_loaded and self.buffer.is_dirty):
+            self.mode_manager.set_message(t("msg.external_editor_unchanged"))
+            return
+
+        if is_loaded:
+            # Reload the buffer, keeping the cursor near where it was, then save
+            row, col = self.buffer.cursor_row, self.buffer.cursor_col
+            is_new, base_content = self.buffer.is_new_unsaved, self.buffer.base_content
+            self.buffer.load_content(new_content, note.id, is_new=is_new)
+            self.buffer.base_content = base_content
+            self.buffer.cursor_row = min(row, len(self.buffer.lines) - 1)
+            self.buffer.cursor_col = min(col, len(self.buffer.lines[self.buffer.cursor_row]))
+            self.save_current_note()
+        else:
+            note.content = new_content
+            with self.storage.journal_operation("save", [note.id]):
+                self.storage.save_note(note)
+            self.note_list_manager.update_note(note)
+            self.note_list_manager.select_note_by_id(note.id)
+            self.toasts.success(t("msg.note_saved"))
+
+    def create_notebook(self, path: str):
+        """
+        Create a notebook and select it in the sidebar
+
+        Args:
+            path: Slash-separated notebook path
+        """
+        path = self.storage.create_notebook(path)
+        if not path:
+            self.mode_manager.set_message(t("msg.notebook_usage"))
+            return
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_notebook(path)
+        self.mode_manager.set_message(t("msg.notebook_created", path=path))
+
+    def move_current_note(self, path: str):
+        """
+        Move the note loaded in the editor into a notebook
+
+        A new unsaved note keeps its notebook in memory until it is saved.
+
+        Args:
+            path: Destination notebook path ("" for the top level)
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        path = Notebook.normalize_path(path)
+        if self.buffer.is_new_unsaved:
+            if path:
+                note.set_property("notebook", path)
+            else:
+                note.delete_property("notebook")
+        else:
+            self.storage.move_note(note.id, path)
+
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+
+        if path:
+            self.mode_manager.set_message(t("msg.note_moved", path=path))
+        else:
+            self.mode_manager.set_message(t("msg.note_moved_top"))
+
+    def open_history(self, note: Note):
+        """
+        Show the version history of a note
+
+        Args:
+            note: Note whose history to show
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+
+        revisions = self.storage.list_revisions(note.id)
+        if not revisions:
+            self.mode_manager.set_message(t("msg.no_history"))
+            return
+
+        self.history_view.open(note.id, revisions)
+        self.focus_manager.switch_to_sidebar()
+        self.mode_manager.set_message(t(
+            "msg.history_help",
+            count=len(revisions),
+            down=self.keymap.label("down"),
+            up=self.keymap.label("up"),
+            open=self.keymap.label("open")
+        ))
+
+    def close_history(self):
+        """Close the history viewer"""
+        self.history_view.close()
+        self.mode_manager.clear_message()
+
+    def restore_selected_revision(self):
+        """Roll the note shown in the history viewer back to the selected revision"""
+        revision = self.history_view.selected_revision
+        if revision is None:
+            return
+
+        if self.buffer.current_note_id == revision.note_id and self.buffer.is_dirty:
+            self.mode_manager.set_message(t("msg.unsaved_restore"))
+            return
+
+        note = self.storage.restore_revision(revision.note_id, revision.rev)
+        self.history_view.close()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_history"))
+            return
+
+        if self.buffer.current_note_id == note.id:
+            self.buffer.load_content(note.content, note.id)
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self.mode_manager.set_message(t("msg.revision_restored", rev=revision.rev))
+
+    def replace_all_notes(self, arguments: str):
+        """
+        Work out a replacement in every note and show the changes for review
+
+        Args:
+            arguments: sed-style "/pattern/replacement/" with an optional "r" flag
+        """
+        parsed = parse_replace_arguments(arguments)
+        if parsed is None:
+            self.mode_manager.set_message(t("msg.replace_usage"))
+            return
+
+        pattern, replacement, regex = parsed
+        try:
+            replacements = self.storage.replace_all(pattern, replacement, regex, dry_run=True)
+        except re.error as e:
+            self.mode_manager.set_message(t("msg.bad_pattern", error=e))
+            return
+        if not replacements:
+            self.mode_manager.set_message(t("msg.no_replacements", pattern=pattern))
+            return
+
+        self.replace_view.open(pattern, replacement, regex, replacements)
+        self.focus_manager.switch_to_sidebar()
+        self.mode_manager.set_message(t(
+            "msg.replace_help",
+            count=len(replacements),
+            matches=sum(change.count for change in replacements),
+            down=self.keymap.label("down"),
+            up=self.keymap.label("up"),
+            mark=self.keymap.label("mark"),
+            open=self.keymap.label("open")
+        ))
+
+    def close_replace_view(self):
+        """Close the replacement review without changing any note"""
+        self.replace_view.close()
+        self.mode_manager.clear_message()
+
+    def apply_replacements(self):
+        """Replace the text in the notes left included in the review"""
+        view = self.replace_view
+        note_ids = {change.note_id for change in view.included}
+        if not note_ids:
+            self.mode_manager.set_message(t("msg.all_skipped", mark=self.keymap.label("mark")))
+            return
+        if self.buffer.is_dirty and self.buffer.current_note_id in note_ids:
+            self.mode_manager.set_message(t("msg.unsaved_replace"))
+            return
+
+        replacements = self.storage.replace_all(view.pattern, view.replacement, view.regex, note_ids=note_ids)
+        view.close()
+        self.note_list_manager.reload_notes()
+        self._show_stored_note()
+        self.mode_manager.set_message(t(
+            "msg.replaced",
+            matches=sum(change.count for change in replacements),
+            count=len(replacements),
+            undo=self.keymap.label("undo")
+        ))
+
+    def open_tasks(self):
+        """Show the open tasks of every note"""
+        tasks = self.storage.tasks(self.tasks_view.show_done)
+        if not tasks and not self.tasks_view.show_done:
+            self.mode_manager.set_message(t("msg.no_tasks"))
+            return
+
+        self.tasks_view.open(tasks)
+        self.focus_manager.switch_to_sidebar()
+        self.mode_manager.set_message(t(
+            "msg.tasks_help",
+            count=len(tasks),
+            down=self.keymap.label("down"),
+            up=self.keymap.label("up"),
+            mark=self.keymap.label("mark"),
+            open=self.keymap.label("open")
+        ))
+
+    def close_tasks(self):
+        """Close the tasks view"""
+        self.tasks_view.close()
+        self.mode_manager.clear_message()
+
+    def refresh_tasks(self):
+        """List the tasks again after notes changed"""
+        self.tasks_view.refresh(self.storage.tasks(self.tasks_view.show_done))
+
+    def toggle_done_tasks(self):
+        """Show or hide checked tasks in the tasks view"""
+        self.tasks_view.show_done = not self.tasks_view.show_done
+        self.refresh_tasks()
+        self.mode_manager.set_message(t("msg.tasks_done_shown" if self.tasks_view.show_done else "msg.tasks_done_hidden"))
+
+    def toggle_selected_task(self):
+        """Check or uncheck the selected task, writing it back to its note"""
+        task = self.tasks_view.selected
+        if task is None:
+            return
+        if self.buffer.is_dirty and self.buffer.current_note_id == task.note_id:
+            self.mode_manager.set_message(t("msg.unsaved_task"))
+            return
+
+        note = self.storage.toggle_task(task.note_id, task.line)
+        if note is None:
+            # The note changed since the list was made
+            self.refresh_tasks()
+            self.mode_manager.set_message(t("msg.task_moved"))
+            return
+        self.note_list_manager.update_note(note)
+        self._show_stored_note()
+        self.refresh_tasks()
+        self.mode_manager.set_message(t(
+            "msg.task_undone" if task.done else "msg.task_done",
+            task=task.text,
+            undo=self.keymap.label("undo")
+        ))
+
+    def undo_task_toggle(self):
+        """Undo the last operation from the tasks view and list the tasks again"""
+        self.undo_operation()
+        self.refresh_tasks()
+
+    def open_selected_task(self):
+        """Close the tasks view and open the selected task's note at its line"""
+        task = self.tasks_view.selected
+        if task is None:
+            return
+        note = self.storage.get_note(task.note_id)
+        self.close_tasks()
+        if note is None:
+            return
+
+        self.note_list_manager.select_note_by_id(note.id)
+        if self.buffer.current_note_id != note.id:
+            self.load_note(note)
+            if self.buffer.current_note_id != note.id:
+                return  # Asked about unsaved changes first
+        self.buffer.cursor_row = min(task.line, len(self.buffer.lines) - 1)
+        self.buffer.cursor_col = 0
+        self.buffer.adjust_scroll(self.editor_window_height)
+        self.focus_editor()
+
+    def open_outline(self):
+        """List the headings of the open note (from the sidebar, the selected note) to jump between them"""
+        from_editor = self.focus_manager.is_editor_focused()
+        if not from_editor:
+            note = self.note_list_manager.selected_note
+            if note is None:
+                self.mode_manager.set_message(t("msg.no_note_loaded"))
+                return
+            if self.buffer.current_note_id != note.id:
+                self.load_note(note)
+                if self.buffer.current_note_id != note.id:
+                    return  # Asked about unsaved changes first
+
+        headings = extract_headings(self.buffer.get_text())
+        if not headings:
+            self.mode_manager.set_message(t("msg.no_headings"))
+            return
+
+        origin = (self.buffer.cursor_row, self.buffer.cursor_col, self.buffer.scroll_offset)
+        self.outline_view.open(headings, heading_at(headings, self.buffer.cursor_row), origin, from_editor)
+        self.focus_manager.switch_to_sidebar()
+        self.show_selected_heading()
+        self.mode_manager.set_message(t(
+            "msg.outline_help",
+            count=len(headings),
+            down=self.keymap.label("down"),
+            up=self.keymap.label("up"),
+            open=self.keymap.label("open")
+        ))
+
+    def show_selected_heading(self):
+        """Scroll the editor so the selected heading of the outline is at the top"""
+        heading = self.outline_view.selected
+        if heading is None:
+            return
+        self.update_editor_window_height()
+        self.buffer.cursor_row = min(heading.line, self.buffer.line_count - 1)
+        self.buffer.cursor_col = 0
+        self.buffer.scroll_offset = max(0, min(heading.line, self.buffer.line_count - self.editor_window_height))
+
+    def close_outline(self, jump: bool = False):
+        """
+        Close the outline view
+
+        Args:
+            jump: Leave the cursor at the selected heading and focus the editor;
+                  otherwise go back to where the cursor was
+        """
+        view = self.outline_view
+        if not jump and view.origin:
+            self.buffer.cursor_row, self.buffer.cursor_col, self.buffer.scroll_offset = view.origin
+            self.buffer.clamp_cursor()
+        from_editor = view.from_editor
+        view.close()
+        self.mode_manager.clear_message()
+        if jump or from_editor:
+            self.focus_editor()
+
+    def open_help(self):
+        """Show every key binding in the help overlay"""
+        self.help_view.open(self.keymap.help_sections())
+        self.mode_manager.clear_message()
+
+    def open_switcher(self):
+        """Show the quick switcher, listing the other notes most recently viewed first"""
+        notes = [note for note in self.storage.get_all_notes()
+                 if not note.is_trashed and not note.is_archived and note.id != self.buffer.current_note_id]
+        if not notes:
+            self.mode_manager.set_message(t("msg.no_other_notes"))
+            return
+        self.switcher.open(self.recent.order(notes))
+        self.mode_manager.clear_message()
+
+    def open_switched_note(self):
+        """Close the quick switcher and open the selected note in the editor"""
+        note = self.switcher.selected
+        self.switcher.close()
+        if note is None:
+            return
+        if self.note_list_manager.show_trash or self.note_list_manager.show_archive:
+            self.note_list_manager.show_trash = False
+            self.note_list_manager.show_archive = False
+            self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self.load_note(note)
+        if self.buffer.current_note_id == note.id:
+            self.focus_editor()
+
+    def get_switcher_content(self):
+        """Get formatted text for the quick switcher: what was typed, then the matching notes"""
+        switcher = self.switcher
+        result = [('class:label', " > "), ('', switcher.query), ('[SetCursorPosition]', ''), ('', '\n')]
+        if not switcher.matches:
+            result.append(('class:label', f" {t('switcher.no_match')}"))
+            return FormattedText(result)
+
+        # Scroll so the selected note is listed
+        start = max(0, switcher.selected_index - SWITCHER_ROWS + 1)
+        width = SWITCHER_WIDTH - 4
+        for index, (note, positions) in enumerate(switcher.matches[start:start + SWITCHER_ROWS], start):
+            selected = index == switcher.selected_index
+            title = fit(note.title or t("note.empty_preview"), width)
+            base = 'class:selected' if selected else ''
+            line = [(base, " > " if selected else "   ")]
+            visible = highlight_positions(title, [position for position in positions if position < len(title)])
+            for highlighted, text in split_highlights(visible):
+                line.append((f"{base} class:match".strip() if highlighted else base, text))
+            line.append((base, " " * (width - text_width(title))))
+            if index > start:
+                result.append(('', '\n'))
+            result.extend(line)
+        return FormattedText(result)
+
+    def open_tour(self):
+        """Show the tour from its first step"""
+        self.tour.open()
+        self.mode_manager.clear_message()
+
+    def get_tour_content(self):
+        """Get formatted text for the tour's current step, with the current keys filled in"""
+        step = self.tour.step
+        if step is None:
+            return FormattedText([])
+        label = self.keymap.label
+        text = t(
+            f"tour.{step}",
+            down=label("down"), up=label("up"), open=label("open"), new_note=label("new_note"),
+            toggle_focus=label("toggle_focus"), focus_sidebar=label("focus_sidebar"), edit=label("edit"),
+            search=label("search"), next_match=label("next_match"), outline=label("outline"),
+            tasks=label("tasks"), help=label("help"), command=label("command")
+        )
+        # Wrapped here at word boundaries; the window only wraps (mid-word) when narrower
+        lines = textwrap.wrap(text, TOUR_WIDTH - 2)
+        return FormattedText([
+            ('class:heading', f" {t(f'tour.{step}.title')} "),
+            ('', "\n\n" + "".join(f" {line} \n" for line in lines) + "\n"),
+            ('class:label', f" {t('tour.footer', step=self.tour.step_index + 1, total=len(TOUR_STEPS))} "),
+        ])
+
+    def get_help_height(self) -> int:
+        """Get the number of help lines shown at once, leaving room for the frame"""
+        try:
+            import shutil
+            terminal_height = shutil.get_terminal_size().lines
+        except:
+            terminal_height = 24
+        if self.accessible:
+            return max(1, terminal_height - 2)
+        return max(1, min(self.help_view.line_count, terminal_height - 4))
+
+    def get_help_content(self):
+        """Get formatted text for the visible part of the help overlay: keys, then what they do"""
+        keys_width = min(30, max((len(keys) for _, entries in self.help_view.sections for keys, _ in entries),
+                                 default=0))
+        lines = []
+        for title, entries in self.help_view.sections:
+            if lines:
+                lines.append([])
+            lines.append([('class:heading', f" {title}")])
+            for keys, description in entries:
+                lines.append([('class:label', f"   {keys:<{keys_width}}"), ('', f"  {description} ")])
+
+        start = self.help_view.scroll_offset
+        result = []
+        for line in lines[start:start + self.get_help_height()]:
+            if result:
+                result.append(('', '\n'))
+            result.extend(line)
+        return FormattedText(result)
+
+    def load_note(self, note: Note):
+        """
+        Load a note into the editor
+
+        Args:
+            note: The note to load
+        """
+        if self.buffer.is_dirty or self.buffer.is_new_unsaved:
+            # Store pending switch and prompt user
+            self.pending_note_switch = note
+            if self.buffer.is_new_unsaved:
+                self.mode_manager.set_message(t("msg.new_unsaved_load"))
+            else:
+                self.mode_manager.set_message(t("msg.unsaved_load"))
+        else:
+            # Load the note
+            self.buffer.load_content(note.content, note.id)
+            self.mode_manager.clear_message()
+
+    def open_selected_row(self):
+        """Load the note selected in the sidebar into the editor, or expand or collapse the notebook (focus stays)"""
+        selected_notebook = self.note_list_manager.selected_notebook
+        if selected_notebook:
+            self.note_list_manager.toggle_notebook(selected_notebook.path)
+            return
+
+        selected_note = self.note_list_manager.selected_note
+        if selected_note:
+            # load_note checks for unsaved changes
+            self.load_note(selected_note)
+
+    def force_load_note(self, note: Note):
+        """Force load a note, discarding any unsaved changes"""
+        # If we're discarding an in-memory note, clear it from sidebar
+        if self.buffer.is_new_unsaved:
+            self.note_list_manager.clear_in_memory_note()
+
+        self.buffer.load_content(note.content, note.id)
+        self.drafts.clear()
+        self.pending_note_switch = None
+        self.mode_manager.clear_message()
+
+    def focus_editor(self):
+        """Switch focus to the editor, in insert mode unless editing is modal"""
+        self.focus_manager.switch_to_editor()
+        if not self.modal_editing:
+            self.mode_manager.enter_insert_mode()
+
+    def toggle_zen(self):
+        """Enter or leave zen mode: the editor alone, centered, with a word count below"""
+        if self.accessible:
+            # The accessible layout already shows one pane at a time
+            self.mode_manager.set_message(t("msg.zen_accessible"))
+            return
+        self.focus_manager.toggle_zen()
+        self.update_editor_window_width()
+        self.buffer.adjust_horizontal_scroll(self.editor_window_width)
+        self.mode_manager.set_message(t("msg.zen_on" if self.focus_manager.zen else "msg.zen_off"))
+
+    def is_overlay_open(self) -> bool:
+        """Check if the help, the tour or the quick switcher covers the panes (in accessible mode, replaces them)"""
+        return self.help_view.is_open or self.tour.is_open or self.switcher.is_open
+
+    def is_zen(self) -> bool:
+        """Check if zen mode is showing, i.e. on and no view needing the sidebar is open"""
+        return self.focus_manager.zen and not (
+            self.history_view.is_open or self.template_picker.is_open or self.replace_view.is_open or
+            self.tasks_view.is_open or self.outline_view.is_open
+        )
+
+    def create_new_note(self, content: str = "", cursor: Tuple[int, int] = (0, 0)):
+        """
+        Create a new note and load it into the editor
+
+        Args:
+            content: Starting content, e.g. a rendered template
+            cursor: (row, column) to start the cursor at
+        """
+        if self.read_only:
+            self.mode_manager.set_message(t("storage.read_only"))
+            return
+        self.pending_new_note = (content, cursor)
+        if self.buffer.is_dirty or self.buffer.is_new_unsaved:
+            # Store that we want to create a new note
+            self.pending_note_switch = "NEW_NOTE"
+            if self.buffer.is_new_unsaved:
+                self.mode_manager.set_message(t("msg.new_unsaved_create"))
+            else:
+                self.mode_manager.set_message(t("msg.unsaved_create"))
+        else:
+            self._do_create_new_note()
+
+    def _do_create_new_note(self):
+        """Internal method to actually create and load a new note"""
+        # Create in the notebook selected in the sidebar, if any
+        notebook = self.note_list_manager.selected_notebook
+
+        # New notes aren't trashed or archived, so go back to the main list
+        if self.note_list_manager.show_trash:
+            self.note_list_manager.toggle_trash()
+        elif self.note_list_manager.show_archive:
+            self.note_list_manager.toggle_archive()
+
+        # Clear any existing in-memory note first (if we're replacing it)
+        self.note_list_manager.clear_in_memory_note()
+
+        # Create new note ID (but don't save to storage yet)
+        content, (row, col) = self.pending_new_note
+        new_note = self.storage.create_note(content)
+        if notebook:
+            new_note.set_property("notebook", notebook.path)
+
+        # Add to note list manager as in-memory note
+        self.note_list_manager.set_in_memory_note(new_note)
+
+        # Load the note into editor with is_new flag
+        # This marks it as in-memory only until first save
+        self.buffer.load_content(new_note.content, new_note.id, is_new=True)
+        self.buffer.cursor_row, self.buffer.cursor_col = row, col
+        self.drafts.clear()  # Any edits to the previous note were discarded
+
+        # Switch focus to editor
+        self.focus_editor()
+
+        # Clear any messages and pending state
+        self.mode_manager.clear_message()
+        self.pending_note_switch = None
+        self.pending_new_note = ("", (0, 0))
+
+    def new_note_from_template(self, arguments: str):
+        """
+        Create a note from a template named in a command, or pick one
+
+        Args:
+            arguments: Template name, optionally followed by the note's title;
+                empty to open the template picker
+        """
+        name, _, title = arguments.strip().partition(" ")
+        if not name:
+            self.open_template_picker()
+            return
+        template = find_template(list_templates(self.templates_directory), name)
+        if template is None:
+            self.mode_manager.set_message(t("msg.template_not_found", name=name))
+            return
+        self._create_from_template(template, title.strip())
+
+    def open_template_picker(self):
+        """Show the templates in the sidebar with a preview of the selected one"""
+        self.template_picker.open(list_templates(self.templates_directory))
+        self.focus_manager.switch_to_sidebar()
+        self.mode_manager.set_message(t(
+            "msg.template_help",
+            down=self.keymap.label("down"),
+            up=self.keymap.label("up"),
+            open=self.keymap.label("open")
+        ))
+
+    def close_template_picker(self):
+        """Close the template picker without creating a note"""
+        self.template_picker.close()
+        self.mode_manager.clear_message()
+
+    def choose_template(self):
+        """Create a note from the template selected in the picker"""
+        template = self.template_picker.selected_template
+        self.template_picker.close()
+        if template is not None:
+            self._create_from_template(template)
+
+    def _create_from_template(self, template: Template, title: str = ""):
+        """
+        Create a new note with a template's content filled in, in insert mode
+
+        Args:
+            template: Template to use
+            title: Title for the {{title}} placeholder (default: the template name)
+        """
+        content, row, col = render_template(template, title)
+        self.create_new_note(content, (row, col))
+        if self.pending_note_switch is None:
+            self.mode_manager.enter_insert_mode()
+            self.mode_manager.set_message(t("msg.template_created", name=template.name))
+
+    def delete_note(self, note_id: str):
+        """
+        Move a note to the trash, or delete it permanently if already trashed
+
+        Args:
+            note_id: ID of the note to delete
+        """
+        # Check if this is the in-memory note
+        if (self.note_list_manager.in_memory_note and
+            self.note_list_manager.in_memory_note.id == note_id):
+            # Just discard the in-memory note
+            self.note_list_manager.clear_in_memory_note()
+            self.buffer.load_content("", None)
+            self.pending_deletion = None
+            self.mode_manager.set_message(t("msg.new_note_discarded"))
+
+            # Show the note that was selected before the new one was made
+            self._load_selected_if_empty()
+            return
+
+        if self.is_note_trashed(note_id):
+            with self.storage.journal_operation("delete", [note_id]):
+                self.storage.delete_note(note_id)
+                self.storage.delete_attachments(note_id)
+            message = t("msg.note_deleted")
+        else:
+            self.storage.trash_note(note_id)
+            message = t(
+                "msg.note_trashed",
+                trash=self.keymap.label("trash"),
+                restore=self.keymap.label("restore")
+            )
+
+        # If we're deleting the currently loaded note, clear the buffer
+        if self.buffer.current_note_id == note_id:
+            self.buffer.load_content("", None)
+
+        # Reload note list, showing the next note (reload_notes keeps the index in bounds)
+        self.note_list_manager.reload_notes()
+        self._load_selected_if_empty()
+
+        # Clear pending deletion state
+        self.pending_deletion = None
+        self.mode_manager.set_message(message)
+
+    def _load_selected_if_empty(self):
+        """Show the note selected in the sidebar if the editor has no note loaded"""
+        if self.buffer.current_note_id is None:
+            selected_note = self.note_list_manager.selected_note
+            if selected_note:
+                self.buffer.load_content(selected_note.content, selected_note.id)
+
+    def ask_confirmation(self, question: str, on_confirm: Callable[[], None]):
+        """
+        Ask the user to confirm an action in a dialog
+
+        Args:
+            question: What the user is asked to confirm
+            on_confirm: Action to run if the user confirms
+        """
+        self.pending_deletion = None
+        self.confirm_dialog.open(question, on_confirm)
+        self.mode_manager.clear_message()
+
+    def cancel_confirmation(self):
+        """Close the confirmation dialog without running its action"""
+        self.confirm_dialog.close()
+        self.mode_manager.set_message(t("msg.cancelled"))
+
+    def toggle_mark(self):
+        """Mark the selected note for a bulk action, or unmark it, and select the next row"""
+        note = self.note_list_manager.selected_note
+        if note is None:
+            return
+        if note is self.note_list_manager.in_memory_note:
+            self.mode_manager.set_message(t("msg.save_before_mark"))
+            return
+
+        self.note_list_manager.toggle_mark(note.id)
+        self.note_list_manager.move_selection_down()
+        count = len(self.note_list_manager.marked_ids)
+        if count:
+            self.mode_manager.set_message(t(
+                "msg.marked",
+                count=count,
+                delete=self.keymap.label("delete_note"),
+                archive=self.keymap.label("archive")
+            ))
+        else:
+            self.mode_manager.clear_message()
+
+    def clear_marks(self):
+        """Unmark every marked note"""
+        self.note_list_manager.marked_ids.clear()
+        self.mode_manager.set_message(t("msg.marks_cleared"))
+
+    def delete_marked_notes(self):
+        """Move the marked notes to the trash, or delete them for good in the trash, after confirming"""
+        note_ids = [note.id for note in self.note_list_manager.marked_notes]
+        if self.note_list_manager.show_trash:
+            question = t("dialog.purge_marked", count=len(note_ids))
+        else:
+            question = t("dialog.trash_marked", count=len(note_ids))
+        self.ask_confirmation(question, lambda: self.delete_notes(note_ids))
+
+    def delete_notes(self, note_ids: List[str]):
+        """
+        Move stored notes to the trash, or delete them permanently if the trash is shown
+
+        Args:
+            note_ids: IDs of the notes to delete
+        """
+        if self.note_list_manager.show_trash:
+            count = self.storage.delete_notes(note_ids)
+            for note_id in note_ids:
+                self.storage.delete_attachments(note_id)
+            message = t("msg.notes_deleted", count=count)
+        else:
+            with self.storage.journal_operation("trash_many", note_ids):
+                for note_id in note_ids:
+                    self.storage.trash_note(note_id)
+            message = t(
+                "msg.notes_trashed",
+                count=len(note_ids),
+                trash=self.keymap.label("trash"),
+                restore=self.keymap.label("restore")
+            )
+
+        if self.buffer.current_note_id in note_ids:
+            self.buffer.load_content("", None)
+        self.note_list_manager.marked_ids.clear()
+        self.note_list_manager.reload_notes()
+        self._load_selected_if_empty()
+        self.mode_manager.set_message(message)
+
+    def archive_marked_notes(self):
+        """Archive the marked notes, or move them back to the main list in the archive"""
+        note_ids = [note.id for note in self.note_list_manager.marked_notes]
+        archive = not self.note_list_manager.show_archive
+        with self.storage.journal_operation("archive_many" if archive else "unarchive_many", note_ids):
+            for note_id in note_ids:
+                if archive:
+                    self.storage.archive_note(note_id)
+                else:
+                    self.storage.unarchive_note(note_id)
+        self.note_list_manager.marked_ids.clear()
+        self.note_list_manager.reload_notes()
+        self.mode_manager.set_message(t(
+            "msg.notes_archived" if archive else "msg.notes_unarchived",
+            count=len(note_ids)
+        ))
+
+    def run_plugin(self, plugin: str, handler: Callable, *args):
+        """
+        Call a plugin's command or key handler, showing the message it returns
+
+        Args:
+            plugin: Name of the plugin, for reporting errors
+            handler: Handler taking the PluginContext and args
+            *args: Further arguments for the handler
+        """
+        try:
+            message = handler(self.plugin_context, *args)
+        except Exception as e:
+            # Plugins are user code; report their errors instead of exiting
+            self.mode_manager.set_message(t("plugin.failed", plugin=plugin, error=e))
+            return
+        if message:
+            self.mode_manager.set_message(str(message))
+
+    def apply_transform(self, name: str):
+        """
+        Rewrite the editor's text with a plugin's transform, or list the transforms
+
+        Args:
+            name: Transform name ("" lists them)
+        """
+        name = name.strip()
+        if not name:
+            names = ", ".join(sorted(self.plugins.transforms))
+            self.mode_manager.set_message(t("msg.transforms", names=names) if names else t("msg.no_transforms"))
+            return
+
+        transform = self.plugins.transforms.get(name)
+        if transform is None:
+            self.mode_manager.set_message(t("msg.unknown_transform", name=name))
+            return
+        try:
+            text = transform.handler(self.buffer.get_text())
+            if not isinstance(text, str):
+                raise TypeError(t("plugin.transform_not_text", type=type(text).__name__))
+        except Exception as e:
+            self.mode_manager.set_message(t("plugin.failed", plugin=transform.plugin, error=e))
+            return
+        if text == self.buffer.get_text():
+            self.mode_manager.set_message(t("msg.transform_unchanged", name=name))
+            return
+        self.buffer.replace_text(text, self.editor_window_height)
+        self.mode_manager.set_message(t("msg.transformed", name=name))
+
+    def show_plugins(self):
+        """Show the loaded plugins and the commands they added"""
+        if not self.plugins.plugins:
+            self.mode_manager.set_message(t("msg.no_plugins"))
+            return
+        self.mode_manager.set_message(t(
+            "msg.plugins",
+            plugins=", ".join(self.plugins.plugins),
+            commands=", ".join(f":{name}" for name in sorted(self.plugins.commands)) or "-"
+        ))
+
+    def undo_operation(self, redo: bool = False):
+        """
+        Undo the last change to notes (saving, deleting, tagging...), or redo it
+
+        Args:
+            redo: Redo the last undone change instead
+        """
+        journal = self.storage.journal
+        operation = None
+        if journal:
+            operation = journal.next_redo() if redo else journal.next_undo()
+        if operation is None:
+            self.mode_manager.set_message(t("msg.nothing_to_redo" if redo else "msg.nothing_to_undo"))
+            return
+
+        # Don't overwrite edits to the open note that haven't been saved
+        note_id = self.buffer.current_note_id
+        if self.buffer.is_dirty and any(change.note_id == note_id for change in operation.changes):
+            self.mode_manager.set_message(t("msg.undo_unsaved"))
+            return
+        # Nor write a secure note's text without its passphrase to encrypt it
+        states = [state for change in operation.changes for state in (change.before, change.after)]
+        if not self.secure_notes.vault.unlocked and any(
+                state is not None and state.is_secure and not state.is_locked for state in states):
+            self.mode_manager.set_message(t("secure.locked_save"))
+            return
+
+        if redo:
+            self.storage.redo()
+        else:
+            self.storage.undo()
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(operation.changes[0].note_id)
+        self._show_stored_note()
+        self._load_selected_if_empty()
+        self.mode_manager.set_message(t(
+            "msg.redone" if redo else "msg.undone",
+            action=operation.description
+        ))
+
+    def is_note_trashed(self, note_id: str) -> bool:
+        """
+        Check if a stored note is in the trash
+
+        Args:
+            note_id: ID of the note
+
+        Returns:
+            True if the note exists and is trashed
+        """
+        note = self.storage.get_note(note_id)
+        return note is not None and note.is_trashed
+
+    def restore_note(self, note_id: str):
+        """
+        Take a note out of the trash
+
+        Args:
+            note_id: ID of the note to restore
+        """
+        if not self.is_note_trashed(note_id):
+            self.mode_manager.set_message(t("msg.not_in_trash"))
+            return
+
+        self.storage.restore_note(note_id)
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note_id)
+        self.mode_manager.set_message(t("msg.note_restored"))
+
+    def toggle_trash(self):
+        """Switch the sidebar between the trash and the other notes"""
+        if self.note_list_manager.toggle_trash():
+            self.mode_manager.set_message(t(
+                "msg.trash_shown",
+                count=len(self.note_list_manager.notes),
+                restore=self.keymap.label("restore"),
+                delete=self.keymap.label("delete_note"),
+                trash=self.keymap.label("trash")
+            ))
+        else:
+            self.mode_manager.set_message(t("msg.trash_hidden"))
+
+    def split_note(self, note: Note):
+        """
+        Split a note into a note per top-level section, leaving it as their index
+
+        Args:
+            note: Note to split
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if note is self.note_list_manager.in_memory_note or (
+                self.buffer.current_note_id == note.id and self.buffer.is_dirty):
+            self.mode_manager.set_message(t("msg.save_before_split"))
+            return
+
+        new_notes = self.storage.split_note(note.id)
+        if not new_notes:
+            self.mode_manager.set_message(t("msg.nothing_to_split"))
+            return
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.select_note_by_id(note.id)
+        self._show_stored_note()
+        self.mode_manager.set_message(t("msg.note_split", count=len(new_notes), undo=self.keymap.label("undo")))
+
+    def set_note_archived(self, note: Note, archived: bool):
+        """
+        Archive a note out of the main list, or move it back
+
+        Args:
+            note: Note to archive or unarchive
+            archived: Whether the note should be archived
+        """
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if note is self.note_list_manager.in_memory_note:
+            self.mode_manager.set_message(t("msg.save_before_archive"))
+            return
+        if note.is_archived == archived:
+            self.mode_manager.set_message(t("msg.already_archived" if archived else "msg.not_archived"))
+            return
+
+        if archived:
+            self.storage.archive_note(note.id)
+        else:
+            self.storage.unarchive_note(note.id)
+        self.note_list_manager.reload_notes()
+        self.note_list_manager.clamp_selection()
+        if archived:
+            self.mode_manager.set_message(t(
+                "msg.note_archived",
+                archive=self.keymap.label("show_archive")
+            ))
+        else:
+            self.mode_manager.set_message(t("msg.note_unarchived"))
+
+    def toggle_archive(self):
+        """Switch the sidebar between the archive and the main list"""
+        if self.note_list_manager.toggle_archive():
+            self.mode_manager.set_message(t(
+                "msg.archive_shown",
+                count=len(self.note_list_manager.notes),
+                archive=self.keymap.label("archive"),
+                show_archive=self.keymap.label("show_archive")
+            ))
+        else:
+            self.mode_manager.set_message(t("msg.archive_hidden"))
+
+    def empty_trash(self):
+        """Permanently delete the notes in the trash, after confirming"""
+        count = len(self.storage.list_trash())
+        if count == 0:
+            self.mode_manager.set_message(t("msg.trash_empty"))
+            return
+        self.ask_confirmation(t("dialog.empty_trash", count=count), self._purge_trash)
+
+    def _purge_trash(self):
+        """Permanently delete the notes in the trash"""
+        if self.buffer.current_note_id and self.is_note_trashed(self.buffer.current_note_id):
+            self.buffer.load_content("", None)
+        count = self.storage.purge_trash()
+        self.note_list_manager.reload_notes()
+        self.mode_manager.set_message(t("msg.trash_emptied", count=count))
+
+    def sync_notes(self):
+        """Exchange changes with the sync server and show what arrived"""
+        if not self.storage.supports_sync:
+            self.mode_manager.set_message(t("msg.sync_unsupported"))
+            return
+
+        try:
+            changed = self.storage.sync()
+        except SyncError as e:
+            self.toasts.error(t("msg.sync_failed", error=e))
+            return
+        finally:
+            self._pending_sync = (None, self._pending_sync[1])
+
+        self.note_list_manager.reload_notes()
+        if changed:
+            self._show_stored_note()
+        self.toasts.success(t("msg.synced", count=changed))
+
+    def share_selected_note(self, service: str = ""):
+        """
+        Upload the selected note and copy its URL to the clipboard
+
+        The open note is shared as shown in the editor, unsaved edits included.
+
+        Args:
+            service: "gist" or "paste" (empty: [share] service)
+        """
+        config = get_config()
+        service = service.strip() or config.share_service
+        if service not in SHARE_SERVICES:
+            self.mode_manager.set_message(
+                t("share.unknown_service", service=service, services=", ".join(SHARE_SERVICES))
+            )
+            return
+        if self.focus_manager.is_sidebar_focused():
+            note = self.note_list_manager.selected_note
+        else:
+            note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if note.id == self.buffer.current_note_id:
+            note = Note(note.id, self.buffer.get_text(), note.created_at, note.updated_at, note.properties)
+
+        try:
+            url = share_note(
+                note, service,
+                token=config.share_token if service == "gist" else config.share_paste_token,
+                public=config.share_public,
+                paste_url=config.share_paste_url
+            )
+        except ShareError as e:
+            self.mode_manager.set_message(t("msg.share_failed", error=e))
+            return
+        copied = self.clipboard.copy(url) is not None
+        self.mode_manager.set_message(t("msg.shared_copied" if copied else "msg.shared", url=url))
+
+    def assist(self, action: str):
+        """
+        Ask the [ai] provider about the note in the editor
+
+        The note is sent as shown, unsaved edits included. A summary or title
+        goes into the text as one change `u` undoes; suggested tags are
+        offered in the confirmation dialog.
+
+        Args:
+            action: "summarize", "tags" or "title"
+        """
+        note = self.get_current_note()
+        if note is None:
+            self.mode_manager.set_message(t("msg.no_note_loaded"))
+            return
+        if self.read_only:
+            self.mode_manager.set_message(t("storage.read_only"))
+            return
+        try:
+            provider = create_provider(get_config())
+            if provider is None:
+                self.mode_manager.set_message(t("msg.ai_off"))
+                return
+            text = Note(note.id, self.buffer.get_text(), note.created_at, note.updated_at, note.properties)
+            if action == "summarize":
+                self.buffer.replace_text(with_summary(text.content, summarize(provider, text)),
+                                         self.editor_window_height)
+                self.mode_manager.set_message(t("msg.ai_summarized"))
+            elif action == "title":
+                title = suggest_title(provider, text)
+                self.buffer.replace_text(with_title(text.content, title), self.editor_window_height)
+                self.mode_manager.set_message(t("msg.ai_titled", title=title))
+            else:
+                tags = suggest_tags(provider, text, self.storage.list_tags())
+                if not tags:
+                    self.mode_manager.set_message(t("msg.ai_no_tags"))
+                    return
+                self.ask_confirmation(t("dialog.ai_tags", tags=" ".join(f"#{tag}" for tag in tags)),
+                                    lambda: self._add_suggested_tags(tags))
+        except AIError as e:
+            self.mode_manager.set_message(t("msg.ai_failed", error=e))
+
+    def _add_suggested_tags(self, tags: List[str]):
+        """Add the tags :suggesttags suggested to the note in the editor"""
+        for tag in tags:
+            self.tag_current_note(tag)
+        self.mode_manager.set_message(t("msg.ai_tagged", tags=" ".join(f"#{tag}" for tag in tags)))
+
+    def follow_link(self):
+        """Show the image under the cursor, or open the note named by the [[link]] or the web link under it"""
+        image = image_link_at(self.buffer.current_line, self.buffer.cursor_col)
+        if image is not None:
+            self.show_image(image)
+            return
+        url = url_at(self.buffer.current_line, self.buffer.cursor_col)
+        if url is not None and link_at(self.buffer.current_line, self.buffer.cursor_col) is None:
+            self.open_url(url)
+            return
+
+        title = link_at(self.buffer.current_line, self.buffer.cursor_col)
+        if title is None:
+            if self._task_under_cursor() is not None:
+                self.toggle_checkbox()
+            else:
+                self.mode_manager.set_message(t("msg.no_link"))
+            return
+        note = find_linked_note(self.storage.get_all_notes(), title)
+        if note is None:
+            self.mode_manager.set_message(t("msg.link_not_found", title=title))
+            return
+        self.load_note(note)
+        if self.buffer.current_note_id == note.id:
+            self.note_list_manager.select_note_by_id(note.id)
+
+    def open_url(self, url: str):
+        """Open a web link with the system's browser"""
+        try:
+            open_with_system(url)
+        except OSError as e:
+            self.mode_manager.set_message(t("msg.url_open_failed", url=url, error=e))
+            return
+        self.mode_manager.set_message(t("msg.url_opened", url=url))
+
+    def open_note_url(self):
+        """
+        Open a web link of the note selected in the sidebar, or open in the editor
+
+        With several links, a dialog asks which; the open note's links
+        include unsaved edits.
+        """
+        if self.focus_manager.is_sidebar_focused():
+            note = self.note_list_manager.selected_note
+            if note is None:
+                self.mode_manager.set_message(t("msg.no_note_loaded"))
+                return
+            content = self.buffer.get_text() if note.id == self.buffer.current_note_id else note.content
+        else:
+            content = self.buffer.get_text()
+        urls = find_urls(content)
+        if not urls:
+            self.mode_manager.set_message(t("msg.no_urls"))
+        elif len(urls) == 1:
+            self.open_url(urls[0])
+        else:
+            width = max(20, shutil.get_terminal_size().columns - 12)
+            self.pending_deletion = None
+            self.confirm_dialog.choose(t("dialog.open_url"), [
+                (key, truncate(url, width), lambda url=url: self.open_url(url))
+                for key, url in zip(CHOICE_KEYS, urls)
+            ], listed=True)
+            self.mode_manager.clear_message()
+
+    def accepts_mouse(self) -> bool:
+        """Check if clicks and the scroll wheel may act on the panes: nothing covers them and nothing is being typed"""
+        return not (self.is_overlay_open() or self.confirm_dialog.is_open or self.secure_prompt.is_open or
+                    self.is_locked() or self.mode_manager.command_buffer.startswith(':') or
+                    self.mode_manager.is_search_mode())
+
+    def _sidebar_list(self):
+        """Get what the sidebar lists: the open view (history, templates, replace, tasks, outline) or the notes"""
+        for view in (self.history_view, self.template_picker, self.replace_view, self.tasks_view, self.outline_view):
+            if view.is_open:
+                return view
+        return self.note_list_manager
+
+    def sidebar_item_at(self, y: int) -> Optional[int]:
+        """
+        Find the item of the sidebar's list drawn on a line
+
+        Args:
+            y: Line of the sidebar, from the top
+
+        Returns:
+            Index of the item (a row of the note list, or of the open view's list), or None below the last
+        """
+        view = self._sidebar_list()
+        if view is self.note_list_manager:
+            # Search results have their excerpt on a second line
+            heights = [2 if row.snippet else 1 for row in self.note_list_manager.get_rows()]
+        elif view is self.tasks_view:
+            # Each note's first task has the note's title above it
+            tasks = self.tasks_view.tasks
+            heights = [2 if i == 0 or task.note_id != tasks[i - 1].note_id else 1 for i, task in enumerate(tasks)]
+        elif view is self.history_view:
+            heights = [1] * len(self.history_view.revisions)
+        elif view is self.template_picker:
+            heights = [1] * len(self.template_picker.templates)
+        elif view is self.replace_view:
+            heights = [1] * len(self.replace_view.replacements)
+        else:
+            heights = [1] * len(self.outline_view.headings)
+        line = 0
+        for index, height in enumerate(heights):
+            line += height
+            if y < line:
+                return index
+        return None
+
+    def move_sidebar_selection(self, index: int):
+        """Move the selection of the sidebar's list to an item, a step at a time as the arrow keys do"""
+        view = self._sidebar_list()
+        while view.selected_index != index:
+            before = view.selected_index
+            if before < index:
+                view.move_selection_down()
+            else:
+                view.move_selection_up()
+            if view.selected_index == before:
+                break
+        if view is self.outline_view:
+            self.show_selected_heading()
+
+    def handle_sidebar_mouse(self, mouse_event) -> bool:
+        """
+        Handle a mouse event on the sidebar
+
+        A click focuses it and selects the row clicked, opening it in the
+        note list; the scroll wheel moves the selection.
+
+        Returns:
+            Whether the event was handled
+        """
+        if not self.accepts_mouse():
+            return False
+        step = scroll_direction(mouse_event)
+        if step:
+            self.move_sidebar_selection(self._sidebar_list().selected_index + step)
+            return True
+        if not is_click(mouse_event):
+            return False
+
+        if self.focus_manager.is_editor_focused() and not self.mode_manager.is_normal_mode():
+            self.mode_manager.enter_normal_mode()
+            self.buffer.clamp_cursor()
+        self.focus_manager.switch_to_sidebar()
+        index = self.sidebar_item_at(mouse_event.position.y)
+        if index is not None:
+            self.move_sidebar_selection(index)
+            if self._sidebar_list() is self.note_list_manager:
+                self.open_selected_row()
+        return True
+
+    def handle_editor_mouse(self, mouse_event) -> bool:
+        """
+        Handle a mouse event on the editor pane
+
+        A click focuses the editor and puts the cursor where it was clicked,
+        opening a web link there; the scroll wheel scrolls the note, or the
+        diff of the history or replace view.
+
+        Returns:
+            Whether the event was handled
+        """
+        if not self.accepts_mouse():
+            return False
+        step = scroll_direction(mouse_event)
+        if step:
+            lines = step * SCROLL_LINES
+            if self.history_view.is_open:
+                self.history_view.scroll(lines, self.editor_window_height)
+            elif self.replace_view.is_open:
+                self.replace_view.scroll(lines, self.editor_window_height)
+            elif self._sidebar_list() is self.note_list_manager and self.get_search_preview_note() is None:
+                self.buffer.scroll_lines(lines, self.editor_window_height)
+            return True
+        if not is_click(mouse_event):
+            return False
+
+        # The pane shows a preview for the sidebar instead of the note being edited
+        if self._sidebar_list() is not self.note_list_manager or self.get_search_preview_note() is not None:
+            return True
+        self.focus_editor()
+        if self.is_current_note_locked():
+            return True
+        row = min(self.buffer.scroll_offset + mouse_event.position.y, self.buffer.line_count - 1)
+        line = self.buffer.lines[row]
+        if self.wrap_lines:
+            # Each line takes as many rows as it wraps onto
+            row, y = self.buffer.scroll_offset, mouse_event.position.y + self._hidden_wrapped_rows()
+            while row < self.buffer.line_count - 1 and y >= len(self._row_starts(row)):
+                y -= len(self._row_starts(row))
+                row += 1
+            line = self.buffer.lines[row]
+            starts = self._row_starts(row) + [len(line)]
+            y = min(y, len(starts) - 2)
+            part = line[starts[y]:starts[y + 1]]
+            index = starts[y] + min(index_at_column(part, mouse_event.position.x), len(part))
+        else:
+            # The position is a character of the visible part of the line; the text may be scrolled sideways
+            start = self.buffer.horizontal_scroll_offset
+            visible = slice_columns(line, start, start + self.editor_window_width)
+            index = index_at_column(line, start + text_width(visible[:mouse_event.position.x]))
+        self.buffer.move_cursor_to(row, index)
+        url = url_at(line, index)
+        if url is not None:
+            self.open_url(url)
+        return True
+
+    def _task_under_cursor(self):
+        """Get the task on the editor's cursor line, if there is one"""
+        return task_on_line(Note(self.buffer.current_note_id, self.buffer.get_text()), self.buffer.cursor_row)
+
+    def toggle_checkbox(self):
+        """
+        Check or uncheck the task on the cursor line
+
+        A note without unsaved edits is saved right away, so a list can be
+        ticked off without editing; otherwise only the editor's text changes.
+        """
+        task = self._task_under_cursor()
+        if task is None:
+            self.mode_manager.set_message(t("msg.no_checkbox"))
+            return
+
+        was_saved = not (self.buffer.is_dirty or self.buffer.is_new_unsaved)
+        self.buffer.replace_text(toggle_task_line(self.buffer.get_text(), task.line), self.editor_window_height)
+        if not was_saved:
+            self.mode_manager.set_message(t(
+                "msg.checkbox_unsaved_undone" if task.done else "msg.checkbox_unsaved_done", task=task.text
+            ))
+            return
+
+        note = self.storage.toggle_task(task.note_id, task.line)
+        if note is None:
+            return  # The stored note differs from the editor's; leave it to :w
+        self.buffer.mark_clean()
+        self.note_list_manager.update_note(note)
+        self.mode_manager.set_message(t(
+            "msg.task_undone" if task.done else "msg.task_done",
+            task=task.text,
+            undo=self.keymap.label("undo")
+        ))
+
+    def expand_snippet(self) -> Optional[bool]:
+        """
+        Expand the abbreviation just before the cursor, if there is one (insert mode)
+
+        The abbreviation and its expansion are one change, undone together.
+
+        Returns:
+            None if nothing was expanded, else whether the cursor went to the snippet's {{cursor}}
+        """
+        line = self.buffer.current_line
+        col = self.buffer.cursor_col
+        snippet = self.snippets.find(line, col)
+        if snippet is None:
+            return None
+        expansion = self.snippets.expand(snippet)
+        if expansion is None:
+            self.mode_manager.set_message(t("msg.template_not_found", name=snippet.template))
+            return None
+
+        row = self.buffer.cursor_row
+        start = col - len(snippet.abbreviation)
+        lines = self.buffer.get_text().split('\n')
+        lines[row] = line[:start] + expansion.text + line[col:]
+        self.buffer.replace_text('\n'.join(lines), self.editor_window_height)
+        text_lines = expansion.text.split('\n')
+        cursor_row, cursor_col = expansion.cursor or (len(text_lines) - 1, len(text_lines[-1]))
+        self.buffer.cursor_row = row + cursor_row
+        self.buffer.cursor_col = cursor_col + (start if cursor_row == 0 else 0)
+        self.buffer.adjust_scroll(self.editor_window_height)
+        return expansion.cursor is not None
+
+    def _code_blocks(self):
+        """Get the code blocks of the note in the editor, from the render cache"""
+        rendered = self.render_cache.get(self.buffer.current_note_id, self.buffer.get_display_lines())
+        if rendered.code_blocks is None:
+            rendered.code_blocks = self._identify_code_blocks(rendered.lines)
+        return rendered.code_blocks
+
+    def _list_item_under_cursor(self):
+        """Get the list item the cursor line starts with, or None if it isn't one (or is in a code block)"""
+        if self.buffer.cursor_row in self._code_blocks():
+            return None
+        return parse_list_item(self.buffer.current_line)
+
+    def _table_under_cursor(self) -> Optional[Table]:
+        """Get the table the cursor is in, or None if it isn't in one (or is in a code block)"""
+        if self.buffer.cursor_row in self._code_blocks():
+            return None
+        return find_table(self.buffer.lines, self.buffer.cursor_row)
+
+    def in_table_mode(self) -> bool:
+        """Check if the editor is in table mode: typing, with the cursor in a table"""
+        return (self.focus_manager.is_editor_focused() and self.mode_manager.is_insert_mode() and
+                self._table_under_cursor() is not None)
+
+    def _write_table(self, table: Table, row: int, column: int, offset: Optional[int] = None):
+        """
+        Replace a table's lines with it laid out again, as one change, and put the cursor in a cell
+
+        Args:
+            table: The table, with any rows or columns added
+            row: Row of the cell (0 for the header)
+            column: Column of the cell
+            offset: Offset in the cell's text (None for the end of the text)
+        """
+        lines = self.buffer.get_text().split('\n')
+        lines[table.start:table.end + 1] = format_table(table)
+        self.buffer.replace_text('\n'.join(lines), self.editor_window_height)
+        self.buffer.cursor_row = table.line_of(row)
+        self.buffer.cursor_col = cell_cursor(self.buffer.current_line, column, offset)
+        self.buffer.adjust_scroll(self.editor_window_height)
+
+    def _cursor_cell(self, table: Table) -> Tuple[int, int, int]:
+        """Get the row, column and offset in the cell's text of the cursor in a table"""
+        column, offset = cell_at(self.buffer.current_line, self.buffer.cursor_col)
+        return table.row_of(self.buffer.cursor_row), column, offset
+
+    def align_table(self) -> bool:
+        """
+        Line up the pipes of the table the cursor is in, the cursor staying on the same character
+
+        Returns:
+            False if the cursor isn't in a table
+        """
+        table = self._table_under_cursor()
+        if table is None:
+            return False
+        row, column, offset = self._cursor_cell(table)
+        self._write_table(table, row, column, offset)
+        return True
+
+    def move_table_cell(self, direction: int) -> bool:
+        """
+        Move to the next or previous cell of the table the cursor is in (Tab / Shift+Tab in table mode)
+
+        Past the last cell a row is added; the table is aligned.
+
+        Args:
+            direction: 1 for the next cell, -1 for the previous one
+
+        Returns:
+            False if the cursor isn't in a table
+        """
+        table = self._table_under_cursor()
+        if table is None:
+            return False
+        row, column, _ = self._cursor_cell(table)
+        column += direction
+        if column >= table.columns:
+            row, column = row + 1, 0
+            if row >= len(table.rows):
+                table.add_row(row - 1)
+        elif column < 0:
+            row, column = (row - 1, table.columns - 1) if row > 0 else (0, 0)
+        self._write_table(table, row, column)
+        return True
+
+    def move_table_row(self) -> bool:
+        """
+        Move to the cell below in the table the cursor is in (Enter in table mode), adding a row past the last
+
+        Returns:
+            False if the cursor isn't in a table
+        """
+        table = self._table_under_cursor()
+        if table is None:
+            return False
+        row, column, _ = self._cursor_cell(table)
+        if row + 1 >= len(table.rows):
+            table.add_row(row)
+        self._write_table(table, row + 1, column)
+        return True
+
+    def table_command(self, args: str):
+        """
+        Run :table: align the table under the cursor or start a new one, or add a row or column
+
+        Args:
+            args: "" , "row" or "column" ("col")
+        """
+        args = args.strip()
+        if args not in ("", "row", "column", "col"):
+            self.mode_manager.set_message(t("msg.table_usage"))
+            return
+        table = self._table_under_cursor()
+        if table is None:
+            if args:
+                self.mode_manager.set_message(t("msg.not_in_table"))
+                return
+            # Start a table with two columns on the cursor line if it's empty, else below it
+            new_table = format_table(Table(start=0, end=0, indent="", rows=[["", ""], ["", ""]], alignments=["", ""]))
+            lines = self.buffer.get_text().split('\n')
+            row = self.buffer.cursor_row
+            if lines[row].strip():
+                row += 1
+                lines[row:row] = new_table
+            else:
+                lines[row:row + 1] = new_table
+            self.buffer.replace_text('\n'.join(lines), self.editor_window_height)
+            self.buffer.cursor_row = row
+            self.buffer.cursor_col = cell_cursor(self.buffer.current_line, 0)
+            self.buffer.adjust_scroll(self.editor_window_height)
+            self.mode_manager.enter_insert_mode()
+            return
+        row, column, offset = self._cursor_cell(table)
+        if args == "row":
+            table.add_row(row)
+            row, column, offset = row + 1, column, None
+        elif args in ("column", "col"):
+            table.add_column(column)
+            column, offset = column + 1, None
+        self._write_table(table, row, column, offset)
+
+    def continue_list(self) -> bool:
+        """
+        Start the next list item on Enter after a list item's marker (insert mode)
+
+        An item with nothing after its marker ends the list instead: it's
+        outdented if it's nested, otherwise its marker is removed.
+
+        Returns:
+            False if the cursor isn't after a list item's marker, for Enter to insert a plain new line
+        """
+        item = self._list_item_under_cursor()
+        if item is None or self.buffer.cursor_col < item.end:
+            return False
+        line = self.buffer.current_line
+        if line[item.end:].strip():
+            self.buffer.paste_text("\n" + item.next_marker(), self.editor_window_height)
+        elif not self.buffer.outdent_line(INDENT):
+            self.buffer.delete_selection(self.buffer.cursor_row, 0, self.buffer.cursor_row, len(line) - 1)
+        return True
+
+    def indent_list_item(self) -> bool:
+        """
+        Indent the list item on the cursor line (Tab in insert mode)
+
+        Returns:
+            False if the line isn't a list item, for Tab to insert spaces
+        """
+        if self._list_item_under_cursor() is None:
+            return False
+        self.buffer.indent_line(INDENT)
+        return True
+
+    def _word_under_cursor(self) -> Optional[Tuple[int, int]]:
+        """
+        Find the word spellcheck looks at under the editor's cursor
+
+        Returns:
+            (start, end) of the word in the cursor line, or None (with a
+            message saying why) if spellcheck is off or there's no word
+        """
+        if self.spellchecker is None:
+            self.mode_manager.set_message(t("msg.spell_off"))
+            return None
+        span = word_at(self.buffer.current_line, self.buffer.cursor_col)
+        if span is None:
+            self.mode_manager.set_message(t("msg.spell_no_word"))
+        return span
+
+    def suggest_spelling(self):
+        """Offer words to replace the misspelled word under the cursor with, or to add it to the personal dictionary"""
+        span = self._word_under_cursor()
+        if span is None:
+            return
+        row = self.buffer.cursor_row
+        word = self.buffer.current_line[span[0]:span[1]]
+        if self.spellchecker.is_known(word):
+            self.mode_manager.set_message(t("msg.spell_known", word=word))
+            return
+        options = [
+            (key, suggestion, lambda suggestion=suggestion: self.replace_word(row, span, suggestion))
+            for key, suggestion in zip(CHOICE_KEYS, self.spellchecker.suggestions(word))
+        ]
+        options.append(("+", t("dialog.spell_add"), lambda: self.add_to_dictionary(word)))
+        self.pending_deletion = None
+        self.confirm_dialog.choose(t("dialog.spell_suggest", word=word), options, listed=True)
+        self.mode_manager.clear_message()
+
+    def replace_word(self, row: int, span: Tuple[int, int], replacement: str):
+        """
+        Replace a word of the note in the editor, as a change that can be undone
+
+        Args:
+            row: Line of the word
+            span: (start, end) of the word in the line
+            replacement: Text to put in its place
+        """
+        lines = self.buffer.get_text().split('\n')
+        lines[row] = lines[row][:span[0]] + replacement + lines[row][span[1]:]
+        self.buffer.replace_text('\n'.join(lines), self.editor_window_height)
+        self.buffer.move_cursor_to(row, span[0])
+
+    def add_word_under_cursor(self):
+        """Add the word under the cursor to the personal dictionary"""
+        span = self._word_under_cursor()
+        if span is not None:
+            self.add_to_dictionary(self.buffer.current_line[span[0]:span[1]])
+
+    def add_to_dictionary(self, word: str):
+        """Add a word to the personal dictionary, so spellcheck takes it as spelled right"""
+        try:
+            self.spellchecker.add_word(word)
+        except OSError as e:
+            self.toasts.error(t("msg.spell_add_failed", error=e))
+            return
+        self.mode_manager.set_message(t("msg.spell_added", word=word))
+
+    def move_to_misspelled(self, direction: int):
+        """
+        Move the cursor to the next or previous misspelled word, wrapping around the note
+
+        Args:
+            direction: 1 for the next word, -1 for the previous one
+        """
+        if self.spellchecker is None:
+            self.mode_manager.set_message(t("msg.spell_off"))
+            return
+        lines = self.buffer.lines
+        code_blocks = self._identify_code_blocks(lines)
+        found = [
+            (row, start)
+            for row, line in enumerate(lines) if row not in code_blocks
+            for start, _ in self.spellchecker.misspelled(line)
+        ]
+        if not found:
+            self.mode_manager.set_message(t("msg.spell_none"))
+            return
+        position = (self.buffer.cursor_row, self.buffer.cursor_col)
+        if direction > 0:
+            target = next((found_at for found_at in found if found_at > position), found[0])
+        else:
+            target = next((found_at for found_at in reversed(found) if found_at < position), found[-1])
+        self.buffer.move_cursor_to(*target)
+        self.buffer.adjust_scroll(self.editor_window_height)
+        self.mode_manager.clear_message()
+
+    def show_image_number(self, number: str):
+        """
+        Show one of the images linked from the note in the editor
+
+        Args:
+            number: Position of the image in the note, from 1 (empty for the first)
+        """
+        links = [link for line in self.buffer.lines for link in find_image_links(line)]
+        number = number.strip() or "1"
+        if not links:
+            self.mode_manager.set_message(t("msg.no_images"))
+        elif not number.isdigit() or not 1 <= int(number) <= len(links):
+            self.mode_manager.set_message(t("msg.image_number", count=len(links)))
+        else:
+            self.show_image(links[int(number) - 1])
+
+    def _resolve_image(self, target: str) -> Optional[Path]:
+        """
+        Find the file an image link points to
+
+        Tried in order: the path itself (relative to the working directory),
+        a file attached to the open note, and a file in the markdown or git
+        backend's directory, for vaults that keep images next to notes.
+
+        Args:
+            target: Link target as written
+
+        Returns:
+            Path of an existing file, or None
+        """
+        target = unquote(target)
+        candidates = [Path(target).expanduser()]
+        if self.buffer.current_note_id:
+            try:
+                candidates.append(self.storage.attachment_path(self.buffer.current_note_id, target))
+            except ValueError:
+                pass  # An ID no attachment directory can be named after: the note has none
+
+        config = get_config()
+        backend = config.encrypted_wraps if config.storage_backend == "encrypted" else config.storage_backend
+        notes_directory = {"markdown": config.markdown_directory, "git": config.git_directory}.get(backend)
+        if notes_directory:
+            root = Path(notes_directory)
+            candidates.append(root / target)
+            if "/" not in target:
+                # Obsidian embeds name a file anywhere in the vault
+                candidates.extend(root.rglob(target))
+
+        return next((path for path in candidates if path.is_file()), None)
+
+    def show_image(self, link: ImageLink):
+        """
+        Show a linked image on the whole screen until Enter is pressed
+
+        Terminals without a supported graphics protocol get a placeholder
+        message naming the image and its size instead.
+
+        Args:
+            link: Image link from the note
+        """
+        if re.match(r'^[a-zA-Z][a-zA-Z0-9+.-]*://', link.target):
+            self.mode_manager.set_message(t("msg.image_remote", target=link.target))
+            return
+        path = self._resolve_image(link.target)
+        if path is None:
+            self.mode_manager.set_message(t("msg.image_not_found", target=link.target))
+            return
+        try:
+            data = path.read_bytes()
+        except OSError as e:
+            self.mode_manager.set_message(t("msg.image_read_failed", error=e))
+            return
 
-            # If this was a new unsaved note, it's now in storage
-            if self.buffer.is_new_unsaved:
-                self.buffer.is_new_unsaved = False
-                # Clear the in-memory note from sidebar
-                self.note_list_manager.clear_in_memory_note()
+        size = image_size(data)
+        placeholder = t("images.placeholder", name=link.alt or path.name,
+                        size=f"{size[0]}×{size[1]}" if size else "?")
+        columns, rows = shutil.get_terminal_size()
+        escape = render_image(data, self.image_protocol, columns, rows - 2) if self.image_protocol else None
+        if escape is None:
+            self.mode_manager.set_message(t("msg.image_unsupported", image=placeholder))
+            return
 
-            self.note_list_manager.reload_notes()
+        def display():
+            sys.stdout.write("\x1b[2J\x1b[H" + escape + "\r\n" + placeholder + "  " + t("prompt.press_enter"))
+            sys.stdout.flush()
+            try:
+                input()
+            except EOFError:
+                pass
+            sys.stdout.write(clear_images(self.image_protocol))
+            sys.stdout.flush()
 
-            # Update selection to point to the saved note (now at top of list)
-            # Find the note in the reloaded list
-            for i, n in enumerate(self.note_list_manager.notes):
-                if n.id == self.buffer.current_note_id:
-                    self.note_list_manager.selected_index = i
-                    break
+        run_in_terminal(display)
 
-            self.mode_manager.set_message("Note saved")
-        else:
-            self.mode_manager.set_message("No note loaded")
+    def get_backlinks(self) -> List[Note]:
+        """
+        Get the notes linking to the open note, leaving out trashed ones
 
-    def load_note(self, note: Note):
+        The result is kept until another note is opened or the note list is
+        reloaded, which every save does, so rendering doesn't query storage.
         """
-        Load a note into the editor
+        note_id = self.buffer.current_note_id
+        notes = self.note_list_manager.notes
+        cached_id, cached_notes, backlinks = self._backlinks
+        if cached_id != note_id or cached_notes is not notes:
+            backlinks = []
+            if note_id and not self.buffer.is_new_unsaved:
+                backlinks = [note for note in self.storage.get_backlinks(note_id) if not note.is_trashed]
+            self._backlinks = (note_id, notes, backlinks)
+        return backlinks
+
+    def _get_backlinks_content(self, free_lines: int):
+        """
+        Get the "Linked from" lines shown below the end of the note
 
         Args:
-            note: The note to load
+            free_lines: Editor lines left below the note's last line
+
+        Returns:
+            list of (style, text) tuples, empty if nothing links here or there's no room
         """
-        if self.buffer.is_dirty or self.buffer.is_new_unsaved:
-            # Store pending switch and prompt user
-            self.pending_note_switch = note
-            if self.buffer.is_new_unsaved:
-                self.mode_manager.set_message("New note not saved! :w to save, :e! to discard and load")
-            else:
-                self.mode_manager.set_message("Unsaved changes! :w to save, :e! to discard and load")
-        else:
-            # Load the note
-            self.buffer.load_content(note.content, note.id)
-            self.mode_manager.clear_message()
+        backlinks = self.get_backlinks()
+        if not backlinks or free_lines < 3:
+            return []
+        result = [('', '\n'), ('', '\n'), ('class:muted', t("links.linked_from"))]
+        shown = backlinks[:free_lines - 2]
+        if len(shown) < len(backlinks):
+            shown = backlinks[:free_lines - 3]
+        for note in shown:
+            result.append(('', '\n'))
+            result.append(('class:backlink', f"  {note.title}"))
+        if len(shown) < len(backlinks):
+            result.append(('', '\n'))
+            result.append(('class:muted', "  " + t("links.more", count=len(backlinks) - len(shown))))
+        return result
 
-    def force_load_note(self, note: Note):
-        """Force load a note, discarding any unsaved changes"""
-        # If we're discarding an in-memory note, clear it from sidebar
-        if self.buffer.is_new_unsaved:
-            self.note_list_manager.clear_in_memory_note()
+    def _show_stored_note(self) -> bool:
+        """
+        Show the stored version of the open note after the storage changed underneath
 
-        self.buffer.load_content(note.content, note.id)
-        self.pending_note_switch = None
-        self.mode_manager.clear_message()
+        A note with unsaved edits is left as it is.
 
-    def create_new_note(self):
-        """Create a new empty note and load it into the editor"""
-        if self.buffer.is_dirty or self.buffer.is_new_unsaved:
-            # Store that we want to create a new note
-            self.pending_note_switch = "NEW_NOTE"
-            if self.buffer.is_new_unsaved:
-                self.mode_manager.set_message("New note not saved! :w to save, :e! to discard and create new")
-            else:
-                self.mode_manager.set_message("Unsaved changes! :w to save, :e! to discard and create new")
-        else:
-            self._do_create_new_note()
+        Returns:
+            False if the open note has unsaved edits and its stored version differs
+        """
+        note_id = self.buffer.current_note_id
+        if not note_id or self.buffer.is_new_unsaved:
+            return True
+        note = self.storage.get_note(note_id)
+        if self.buffer.is_dirty:
+            return note is not None and note.content == self.buffer.get_text()
+        if note is None:
+            self.buffer.load_content("", None)
+        elif note.content != self.buffer.get_text():
+            self.buffer.load_content(note.content, note.id)
+        return True
 
-    def _do_create_new_note(self):
-        """Internal method to actually create and load a new note"""
-        # Clear any existing in-memory note first (if we're replacing it)
-        self.note_list_manager.clear_in_memory_note()
+    def reload_changed_notes(self):
+        """Show notes another program changed, keeping the selected note selected"""
+        selected = self.note_list_manager.selected_note
+        self.note_list_manager.reload_notes()
+        if selected:
+            self.note_list_manager.select_note_by_id(selected.id)
+        if self._show_stored_note():
+            self.toasts.info(t("msg.reloaded_external"))
+        else:
+            self.toasts.info(t("msg.changed_externally"))
+
+    async def _watch_storage(self, app: Application):
+        """Check the storage for changes by other programs while the editor runs"""
+        while True:
+            await asyncio.sleep(LIVE_RELOAD_INTERVAL)
+            try:
+                changed = self.storage.poll_changes()
+            except OSError:
+                continue  # Try again on the next check
+            if changed:
+                self.reload_changed_notes()
+                app.invalidate()
+
+    def offer_draft(self, draft: Draft):
+        """
+        Ask whether to restore the unsaved edits of a session that didn't exit normally
 
-        # Create new note ID (but don't save to storage yet)
-        new_note = self.storage.create_note()
+        Args:
+            draft: Draft left in the draft file
+        """
+        title = Note(draft.note_id, draft.content).title or t("note.empty_preview")
+        saved_at = draft.saved_at.replace(tzinfo=timezone.utc).astimezone()
+        self.ask_confirmation(
+            t("dialog.restore_draft", title=title, time=f"{saved_at:%m-%d %H:%M}"),
+            lambda: self.restore_draft(draft)
+        )
 
-        # Add to note list manager as in-memory note
-        self.note_list_manager.set_in_memory_note(new_note)
+    def report_damaged_notes(self, offer: bool = True):
+        """
+        Report note files found damaged when the notes were read
 
-        # Load empty note into editor with is_new flag
-        # This marks it as in-memory only until first save
-        self.buffer.load_content(new_note.content, new_note.id, is_new=True)
+        Notes read from their backups are only mentioned; for files with no
+        good backup, the user is asked whether to salvage them.
 
-        # Switch focus to editor
-        self.focus_manager.switch_to_editor()
+        Args:
+            offer: Whether to ask now, rather than pointing to :recover
+        """
+        if self.note_files is None:
+            return
+        if self.note_files.from_backup:
+            self.toasts.info(t("toast.read_from_backup", count=len(self.note_files.from_backup)))
+        if not self.note_files.damaged:
+            return
+        if offer and not self.read_only:
+            self.recover_damaged_notes()
+        else:
+            self.mode_manager.set_message(t("msg.damaged_notes", count=len(self.note_files.damaged)))
 
-        # Clear any messages and pending state
-        self.mode_manager.clear_message()
-        self.pending_note_switch = None
+    def recover_damaged_notes(self):
+        """Ask whether to salvage the note files that can't be read, nor their backups"""
+        if self.note_files is None or not self.note_files.damaged:
+            self.mode_manager.set_message(t("msg.no_damaged_notes"))
+            return
+        if self.read_only:
+            self.mode_manager.set_message(t("storage.read_only"))
+            return
+        self.ask_confirmation(
+            t("dialog.salvage_notes", count=len(self.note_files.damaged)),
+            self.salvage_damaged_notes
+        )
 
-    def delete_note(self, note_id: str):
+    def salvage_damaged_notes(self):
         """
-        Delete a note by ID
+        Save what can be read of the damaged note files as notes tagged "recovered"
 
-        Args:
-            note_id: ID of the note to delete
+        The files are moved aside into the notes directory's "damaged" folder.
+        Text that was encrypted (by the encrypted backend or as a secure
+        note) can't be read from part of a file, so those notes stay there.
         """
-        # Check if this is the in-memory note
-        if (self.note_list_manager.in_memory_note and
-            self.note_list_manager.in_memory_note.id == note_id):
-            # Just discard the in-memory note
-            self.note_list_manager.clear_in_memory_note()
-            self.buffer.load_content("", None)
-            self.pending_deletion = None
-            self.mode_manager.set_message("New note discarded")
+        count = len(self.note_files.damaged)
+        notes = self.note_files.salvage_damaged()
+        if find_backend(self.storage, EncryptedBackend) is not None:
+            notes = []
+        notes = [note for note in notes if not note.is_secure]
+        if notes:
+            self.storage.import_notes(notes)
+            self.note_list_manager.reload_notes()
+            self.note_list_manager.select_note_by_id(notes[0].id)
+            self._load_selected_if_empty()
+        self.mode_manager.set_message(t("msg.notes_salvaged", count=len(notes), total=count,
+                                           path=self.note_files.damaged_dir))
 
-            # Select first saved note if available
-            if self.note_list_manager.notes:
-                self.note_list_manager.selected_index = 0
-                selected_note = self.note_list_manager.selected_note
-                if selected_note:
-                    self.buffer.load_content(selected_note.content, selected_note.id)
-            return
+    def restore_draft(self, draft: Draft):
+        """
+        Load a draft into the editor as unsaved edits
 
-        # Delete from storage
-        self.storage.delete_note(note_id)
+        A note that was never saved, or has been deleted since, comes back
+        as a new note with the same ID.
 
-        # If we're deleting the currently loaded note, clear the buffer
-        if self.buffer.current_note_id == note_id:
-            self.buffer.load_content("", None)
+        Args:
+            draft: Draft to restore
+        """
+        self.pending_draft = None
+        stored = None if draft.is_new else self.storage.get_note(draft.note_id)
+        if stored is None:
+            note = Note(draft.note_id, draft.content, properties=draft.properties)
+            self.note_list_manager.set_in_memory_note(note)
+            self.buffer.load_content(draft.content, note.id, is_new=True)
+        else:
+            self.note_list_manager.select_note_by_id(stored.id)
+            self.buffer.load_content(draft.content, stored.id)
+            if draft.base_content is not None:
+                self.buffer.base_content = draft.base_content
+        self.buffer.mark_dirty()
+        self.focus_editor()
+        self.toasts.info(t("toast.draft_restored"))
+
+    def discard_draft(self):
+        """Remove the draft of the open note's edits, e.g. when quitting without saving"""
+        self.pending_draft = None
+        self.discarding_edits = True
+        self.drafts.clear()
+
+    def save_draft(self):
+        """Copy the open note's unsaved edits to the draft file, or remove it if there are none"""
+        if self.pending_draft is not None:
+            if self.confirm_dialog.is_open:
+                return  # Still asking whether to restore the last one
+            self.pending_draft = None  # Declined
+        note_id = self.buffer.current_note_id
+        if not note_id or not self.buffer.is_dirty:
+            self.drafts.clear()
+            return
+        note = self.get_current_note()
+        if note is not None and note.is_secure:
+            self.drafts.clear()  # The draft file isn't encrypted
+            return
+        in_memory_note = self.note_list_manager.in_memory_note
+        properties = in_memory_note.properties if self.buffer.is_new_unsaved and in_memory_note else {}
+        try:
+            self.drafts.save(
+                note_id, self.buffer.get_text(), self.buffer.is_new_unsaved, properties, self.buffer.base_content
+            )
+        except OSError as e:
+            self.toasts.error(t("toast.draft_failed", error=e))
 
-        # Reload note list
-        self.note_list_manager.reload_notes()
+    async def _save_drafts(self):
+        """Keep the draft file up to date with unsaved edits while the editor runs"""
+        while True:
+            await asyncio.sleep(self.draft_interval)
+            self.save_draft()
 
-        # Select next note if available
-        if self.note_list_manager.notes:
-            # Keep selection index in bounds
-            if self.note_list_manager.selected_index >= len(self.note_list_manager.notes):
-                self.note_list_manager.selected_index = len(self.note_list_manager.notes) - 1
+    def _exit_on_signals(self, app: Application):
+        """
+        Quit on SIGTERM or SIGHUP (the terminal closing) as on Ctrl+C
 
-            # Load the newly selected note if buffer is empty
-            if self.buffer.current_note_id is None:
-                selected_note = self.note_list_manager.selected_note
-                if selected_note:
-                    self.buffer.load_content(selected_note.content, selected_note.id)
+        run() then copies unsaved edits to the draft file and closes the
+        storage, writing its held-back changes, instead of the process dying
+        with them. (prompt_toolkit already turns SIGINT into Ctrl+C.)
 
-        # Clear pending deletion state
-        self.pending_deletion = None
-        self.mode_manager.set_message("Note deleted")
+        Args:
+            app: The running application
+        """
+        def exit_app():
+            if app.is_running and not app.is_done:
+                app.exit()
+
+        loop = asyncio.get_event_loop()
+        for name in ("SIGTERM", "SIGHUP"):
+            if not hasattr(signal, name):
+                continue  # No SIGHUP on Windows
+            try:
+                loop.add_signal_handler(getattr(signal, name), exit_app)
+            except (NotImplementedError, RuntimeError):
+                return  # This event loop can't handle signals (Windows)
+
+    async def _sync_on_start(self, app: Application):
+        """Pull changes from the sync server in the background as the editor starts"""
+        try:
+            changed = await asyncio.get_running_loop().run_in_executor(None, self.storage.sync)
+        except SyncError as e:
+            self.toasts.error(t("msg.sync_failed", error=e))
+            return
+        if changed:
+            selected = self.note_list_manager.selected_note
+            self.note_list_manager.reload_notes()
+            if selected:
+                self.note_list_manager.select_note_by_id(selected.id)
+            self._show_stored_note()
+            self.toasts.success(t("msg.synced", count=changed))
+        app.invalidate()
+
+    async def _expire_toasts(self, app: Application):
+        """Redraw when toasts time out while the editor runs"""
+        while True:
+            await asyncio.sleep(TOAST_CHECK_INTERVAL)
+            if self.toasts.expire():
+                app.invalidate()
 
     def _apply_horizontal_scroll(self, formatted_segments, start_col: int, end_col: int):
         """
         Slice formatted text segments to show only columns [start_col, end_col)
 
+        Columns are display columns: wide characters take two, and one cut
+        by an edge shows as a space.
+
         Args:
             formatted_segments: list of (style, text) tuples
             start_col: leftmost column to display (inclusive)
@@ -206,46 +3220,139 @@ class EditorUI:
             list of (style, text) tuples with sliced text
         """
         result = []
-        char_pos = 0
+        column = 0
 
         for style, text in formatted_segments:
-            text_len = len(text)
-            segment_end = char_pos + text_len
+            segment_end = column + text_width(text)
 
             # Skip segments entirely before visible range
             if segment_end <= start_col:
-                char_pos = segment_end
+                column = segment_end
                 continue
 
             # Stop if we're past the visible range
-            if char_pos >= end_col:
+            if column >= end_col:
                 break
 
-            # Calculate slice within this segment
-            slice_start = max(0, start_col - char_pos)
-            slice_end = min(text_len, end_col - char_pos)
+            visible = slice_columns(text, start_col, end_col, column)
+            if visible:
+                result.append((style, visible))
 
-            if slice_start < slice_end:
-                result.append((style, text[slice_start:slice_end]))
+            column = segment_end
 
-            char_pos = segment_end
+        return result
+
+    def _fit_line(self, formatted_segments):
+        """
+        Fit a formatted editor line to the window's width
 
+        Scrolled sideways to the editor's horizontal scroll, or with wrap on,
+        broken onto as many rows as it takes (at wrap_starts()).
+
+        Args:
+            formatted_segments: list of (style, text) tuples of the whole line
+
+        Returns:
+            list of (style, text) tuples, rows separated by newlines
+        """
+        if not self.wrap_lines:
+            start = self.buffer.horizontal_scroll_offset
+            return self._apply_horizontal_scroll(formatted_segments, start, start + self.editor_window_width)
+        breaks = wrap_starts(''.join(text for _, text in formatted_segments), self.editor_window_width)[1:]
+        result = []
+        position = 0
+        for style, text in formatted_segments:
+            while breaks and position + len(text) > breaks[0]:
+                cut = breaks.pop(0) - position
+                if cut:
+                    result.append((style, text[:cut]))
+                result.append(('', '\n'))
+                text = text[cut:]
+                position += cut
+            if text:
+                result.append((style, text))
+            position += len(text)
         return result
 
+    def _row_starts(self, row: int) -> List[int]:
+        """
+        Get where each screen row of an editor line starts
+
+        Args:
+            row: Line number in the note
+
+        Returns:
+            Index in the line of each row's start: [0] unless wrap is on and
+            the line (with the cursor past its end) is wider than the window
+        """
+        if not self.wrap_lines:
+            return [0]
+        line = self.buffer.lines[row]
+        if row == self.buffer.cursor_row and self.buffer.cursor_col >= len(line):
+            line += ' '  # The cursor past the end takes a column too
+        return wrap_starts(line, self.editor_window_width)
+
+    def _rows_to_cursor(self) -> int:
+        """Count the screen rows from the top of the editor to the cursor's"""
+        rows = sum(len(self._row_starts(row)) for row in range(self.buffer.scroll_offset, self.buffer.cursor_row))
+        starts = self._row_starts(self.buffer.cursor_row)
+        return rows + sum(1 for start in starts[1:] if start <= self.buffer.cursor_col)
+
+    def _scroll_wrapped_cursor_into_view(self):
+        """Scroll down until the cursor's row shows, when wrapped lines above it fill the editor"""
+        rows = self._rows_to_cursor()
+        while rows >= self.editor_window_height and self.buffer.scroll_offset < self.buffer.cursor_row:
+            rows -= len(self._row_starts(self.buffer.scroll_offset))
+            self.buffer.scroll_offset += 1
+
+    def _hidden_wrapped_rows(self) -> int:
+        """Count the rows of the top line left out so the cursor shows, when its line wraps onto more rows than fit"""
+        if not self.wrap_lines:
+            return 0
+        return max(0, self._rows_to_cursor() - self.editor_window_height + 1)
+
+    def toggle_wrap(self):
+        """Wrap long lines in the editor, or scroll them sideways again"""
+        self.wrap_lines = not self.wrap_lines
+        self.buffer.horizontal_scroll_offset = 0
+        if not self.wrap_lines:
+            self.buffer.adjust_horizontal_scroll(self.editor_window_width)
+        self.mode_manager.set_message(t("msg.wrap_on" if self.wrap_lines else "msg.wrap_off"))
+
     def get_text_content(self):
         """Get formatted text content for the editor window"""
+        if self.history_view.is_open:
+            return FormattedText(self.get_history_diff_content())
+        if self.template_picker.is_open:
+            return FormattedText(self.get_template_preview_content())
+        if self.replace_view.is_open:
+            return FormattedText(self.get_replace_diff_content())
+        if self.tasks_view.is_open:
+            return FormattedText(self.get_task_preview_content())
+        if self.is_current_note_locked():
+            return self.get_locked_note_content()
+
+        preview_note = self.get_search_preview_note()
+        if preview_note:
+            return FormattedText(self.get_search_preview_content(preview_note))
+
         # Update window dimensions on each render to handle terminal resizing
         self.update_editor_window_height()
         self.update_editor_window_width()
 
-        # Adjust horizontal scroll to keep cursor visible
-        self.buffer.adjust_horizontal_scroll(self.editor_window_width)
+        # Adjust scroll to keep cursor visible: sideways, or past wrapped lines
+        if self.wrap_lines:
+            self.buffer.horizontal_scroll_offset = 0
+            self._scroll_wrapped_cursor_into_view()
+        else:
+            self.buffer.adjust_horizontal_scroll(self.editor_window_width)
 
         lines = self.buffer.get_display_lines()
+        rendered = self.render_cache.get(self.buffer.current_note_id, lines)
         result = []
 
-        # Only show cursor if editor is focused
-        show_cursor = self.focus_manager.is_editor_focused()
+        # Only show cursor if editor is focused (accessible mode uses the real terminal cursor)
+        show_cursor = self.focus_manager.is_editor_focused() and not self.accessible
 
         # Check if in visual mode and get selection range
         in_visual_mode = self.mode_manager.is_visual_mode()
@@ -264,23 +3371,31 @@ class EditorUI:
         else:
             start_row = start_col = end_row = end_col = -1
 
-        # Calculate visible line range based on scroll offset
+        # Calculate visible line range based on scroll offset, and the rows those take
         visible_start = self.buffer.scroll_offset
         visible_end = min(visible_start + self.editor_window_height, len(lines))
-
-        # First pass: identify code blocks
-        code_blocks = self._identify_code_blocks(lines)
+        visible_rows = visible_end - visible_start
+        if self.wrap_lines:
+            visible_end, visible_rows = visible_start, 0
+            while visible_end < len(lines) and visible_rows < self.editor_window_height:
+                visible_rows += len(self._row_starts(visible_end))
+                visible_end += 1
+
+        # First pass: identify code blocks (once per version of the note)
+        if rendered.code_blocks is None:
+            rendered.code_blocks = self._identify_code_blocks(lines)
+        code_blocks = rendered.code_blocks
+        highlight_query = self.get_highlight_query()
+        # Spelling is checked while editing, outside code blocks
+        check_spelling = self.spellchecker is not None and self.focus_manager.is_editor_focused()
 
         i = visible_start
         while i < visible_end:
-            line = lines[i]
-
             # Check if this line is part of a code block
             if i in code_blocks:
                 block_info = code_blocks[i]
                 block_start = block_info['start']
                 block_end = block_info['end']
-                lang = block_info['lang']
 
                 # Process the entire code block (but only visible parts)
                 for block_i in range(block_start, block_end + 1):
@@ -288,14 +3403,8 @@ class EditorUI:
                     if block_i < visible_start or block_i >= visible_end:
                         continue
 
-                    block_line = lines[block_i]
-
-                    if block_i == block_start or block_i == block_end:
-                        # Opening/closing backticks
-                        formatted_line = [('#ansigreen', block_line)]
-                    else:
-                        # Code content - use Pygments
-                        formatted_line = self._highlight_code_line(block_line, lang)
+                    formatted_line = rendered.line(block_i, lambda n: self._style_line(rendered, n))
+                    formatted_line = self._highlight_matches(formatted_line, lines[block_i], highlight_query)
 
                     # Add cursor/selection if needed
                     if in_visual_mode or in_visual_line_mode:
@@ -304,29 +3413,17 @@ class EditorUI:
                             formatted_line, block_i, start_row, start_col, end_row, end_col,
                             self.buffer.cursor_col, show_cursor
                         )
-                        # Apply horizontal scrolling
-                        scrolled_line = self._apply_horizontal_scroll(
-                            line_with_selection,
-                            self.buffer.horizontal_scroll_offset,
-                            self.buffer.horizontal_scroll_offset + self.editor_window_width
-                        )
+                        # Apply horizontal scrolling or wrapping
+                        scrolled_line = self._fit_line(line_with_selection)
                         result.extend(scrolled_line)
                     elif block_i == self.buffer.cursor_row and show_cursor:
                         line_with_cursor = self._add_cursor_to_formatted_line(formatted_line, self.buffer.cursor_col)
-                        # Apply horizontal scrolling
-                        scrolled_line = self._apply_horizontal_scroll(
-                            line_with_cursor,
-                            self.buffer.horizontal_scroll_offset,
-                            self.buffer.horizontal_scroll_offset + self.editor_window_width
-                        )
+                        # Apply horizontal scrolling or wrapping
+                        scrolled_line = self._fit_line(line_with_cursor)
                         result.extend(scrolled_line)
                     else:
-                        # Apply horizontal scrolling
-                        scrolled_line = self._apply_horizontal_scroll(
-                            formatted_line,
-                            self.buffer.horizontal_scroll_offset,
-                            self.buffer.horizontal_scroll_offset + self.editor_window_width
-                        )
+                        # Apply horizontal scrolling or wrapping
+                        scrolled_line = self._fit_line(formatted_line)
                         result.extend(scrolled_line)
 
                     # Add newline for all but last visible line
@@ -337,7 +3434,10 @@ class EditorUI:
                 i = block_end + 1
             else:
                 # Regular markdown line
-                formatted_line = self._parse_markdown_line(line)
+                formatted_line = rendered.line(i, lambda n: self._style_line(rendered, n))
+                if check_spelling:
+                    formatted_line = self._underline_misspelled(formatted_line, lines[i])
+                formatted_line = self._highlight_matches(formatted_line, lines[i], highlight_query)
 
                 if in_visual_mode or in_visual_line_mode:
                     # Apply visual selection highlighting
@@ -345,29 +3445,17 @@ class EditorUI:
                         formatted_line, i, start_row, start_col, end_row, end_col,
                         self.buffer.cursor_col, show_cursor
                     )
-                    # Apply horizontal scrolling
-                    scrolled_line = self._apply_horizontal_scroll(
-                        line_with_selection,
-                        self.buffer.horizontal_scroll_offset,
-                        self.buffer.horizontal_scroll_offset + self.editor_window_width
-                    )
+                    # Apply horizontal scrolling or wrapping
+                    scrolled_line = self._fit_line(line_with_selection)
                     result.extend(scrolled_line)
                 elif i == self.buffer.cursor_row and show_cursor:
                     line_with_cursor = self._add_cursor_to_formatted_line(formatted_line, self.buffer.cursor_col)
-                    # Apply horizontal scrolling
-                    scrolled_line = self._apply_horizontal_scroll(
-                        line_with_cursor,
-                        self.buffer.horizontal_scroll_offset,
-                        self.buffer.horizontal_scroll_offset + self.editor_window_width
-                    )
+                    # Apply horizontal scrolling or wrapping
+                    scrolled_line = self._fit_line(line_with_cursor)
                     result.extend(scrolled_line)
                 else:
-                    # Apply horizontal scrolling
-                    scrolled_line = self._apply_horizontal_scroll(
-                        formatted_line,
-                        self.buffer.horizontal_scroll_offset,
-                        self.buffer.horizontal_scroll_offset + self.editor_window_width
-                    )
+                    # Apply horizontal scrolling or wrapping
+                    scrolled_line = self._fit_line(formatted_line)
                     result.extend(scrolled_line)
 
                 # Add newline for all but last visible line
@@ -376,40 +3464,85 @@ class EditorUI:
 
                 i += 1
 
+        # Rows of a line too long for the window are shown from the cursor's
+        hidden = self._hidden_wrapped_rows()
+        if hidden:
+            newlines = [index for index, (_, text) in enumerate(result) if text == '\n']
+            result = result[newlines[hidden - 1] + 1:]
+            visible_rows -= hidden
+
+        # Notes linking here, once the end of the note is in view
+        if visible_end == len(lines):
+            result.extend(self._get_backlinks_content(self.editor_window_height - visible_rows))
+
         return FormattedText(result)
 
+    def _style_line(self, rendered: RenderedNote, index: int):
+        """
+        Style a line of the note in the editor for the render cache
+
+        Args:
+            rendered: Cache entry of the note, with its code blocks identified
+            index: Line number
+
+        Returns:
+            List of (style, text) tuples
+        """
+        line = rendered.lines[index]
+        block = rendered.code_blocks.get(index)
+        if block is None:
+            return self._parse_markdown_line(line)
+        if index == block['start'] or (index == block['end'] and block['closed']):
+            # Opening/closing fence
+            return [('class:code', line)]
+        # Code content - use Pygments
+        return self._highlight_code_line(line, block['lang'])
+
     def _identify_code_blocks(self, lines):
         """
         Identify code blocks in the text
+
+        A block opens with ``` or ~~~ and closes with a line of at least as
+        many of the same character. A block not closed yet, e.g. while it's
+        being typed, runs to the end of the note ('closed' is False), so the
+        code is highlighted as it's written.
+
         Returns a dict mapping line numbers to code block info
         """
         code_blocks = {}
-        in_code_block = False
         block_start = None
+        block_fence = None
         block_lang = None
 
         for i, line in enumerate(lines):
-            if line.strip().startswith('```'):
-                if not in_code_block:
-                    # Start of code block
-                    in_code_block = True
-                    block_start = i
-                    # Extract language if specified
-                    lang_match = re.match(r'^```(\w+)', line.strip())
-                    block_lang = lang_match.group(1) if lang_match else None
-                else:
-                    # End of code block
-                    block_end = i
-                    # Mark all lines in the block
-                    for block_i in range(block_start, block_end + 1):
-                        code_blocks[block_i] = {
-                            'start': block_start,
-                            'end': block_end,
-                            'lang': block_lang
-                        }
-                    in_code_block = False
-                    block_start = None
-                    block_lang = None
+            fence = CODE_FENCE.match(line)
+            if not fence:
+                continue
+            if block_start is None:
+                # Start of code block, with its language if specified
+                block_start = i
+                block_fence = fence.group(1)
+                block_lang = fence.group(2) or None
+            elif (fence.group(1)[0] == block_fence[0] and len(fence.group(1)) >= len(block_fence) and
+                    line.strip() == fence.group(1)):
+                # End of code block: mark all lines in the block
+                for block_i in range(block_start, i + 1):
+                    code_blocks[block_i] = {
+                        'start': block_start,
+                        'end': i,
+                        'lang': block_lang,
+                        'closed': True
+                    }
+                block_start = None
+
+        if block_start is not None:
+            for block_i in range(block_start, len(lines)):
+                code_blocks[block_i] = {
+                    'start': block_start,
+                    'end': len(lines) - 1,
+                    'lang': block_lang,
+                    'closed': False
+                }
 
         return code_blocks
 
@@ -421,14 +3554,14 @@ class EditorUI:
         if not line:
             return [('', '')]
 
-        # Get lexer
-        try:
-            if lang:
-                lexer = get_lexer_by_name(lang)
-            else:
+        # Get lexer (looking one up by name is slow, so keep it)
+        lexer = self._lexers.get(lang)
+        if lexer is None:
+            try:
+                lexer = get_lexer_by_name(lang) if lang else TextLexer()
+            except ClassNotFound:
                 lexer = TextLexer()
-        except ClassNotFound:
-            lexer = TextLexer()
+            self._lexers[lang] = lexer
 
         # Tokenize the line
         tokens = list(lex(line, lexer))
@@ -454,26 +3587,43 @@ class EditorUI:
 
     def _pygments_token_to_style(self, token_type):
         """Map Pygments token types to prompt_toolkit style strings"""
-        # Map common token types to ANSI colors
+        if self.code_style:
+            return self._pygments_theme_style(token_type)
+
+        # Map common token types to the theme's syntax styles
         if token_type in Token.Keyword:
-            return '#ansicyan bold'
+            return 'class:syntax.keyword'
         elif token_type in Token.String:
-            return '#ansigreen'
+            return 'class:syntax.string'
         elif token_type in Token.Comment:
-            return '#ansibrightblack italic'
+            return 'class:syntax.comment'
         elif token_type in Token.Number:
-            return '#ansiyellow'
+            return 'class:syntax.number'
         elif token_type in Token.Name.Function:
-            return '#ansiblue'
+            return 'class:syntax.function'
         elif token_type in Token.Name.Class:
-            return '#ansimagenta bold'
+            return 'class:syntax.class'
         elif token_type in Token.Operator:
-            return '#ansired'
+            return 'class:syntax.operator'
         elif token_type in Token.Name.Builtin:
-            return '#ansicyan'
+            return 'class:syntax.builtin'
         else:
             return ''  # Default style
 
+    def _pygments_theme_style(self, token_type):
+        """Map a Pygments token type to a prompt_toolkit style string using the configured theme"""
+        token_style = self.code_style.style_for_token(token_type)
+        parts = []
+        if token_style['color']:
+            parts.append(f"#{token_style['color']}")
+        if token_style['bold']:
+            parts.append('bold')
+        if token_style['italic']:
+            parts.append('italic')
+        if token_style['underline']:
+            parts.append('underline')
+        return ' '.join(parts)
+
     def _parse_markdown_line(self, line: str):
         """
         Parse a line for markdown syntax and return formatted text segments
@@ -484,22 +3634,22 @@ class EditorUI:
         if header_match:
             hashes, text = header_match.groups()
             level = len(hashes)
-            return [('#ansicyan bold', hashes), ('', ' '), ('#ansicyan bold', text)]
+            return [('class:heading', hashes), ('', ' '), ('class:heading', text)]
 
-        # Check for code blocks (triple backticks)
-        if line.strip().startswith('```'):
-            return [('#ansigreen', line)]
+        # Check for code fences (triple backticks or tildes)
+        if CODE_FENCE.match(line):
+            return [('class:code', line)]
 
         # Check for blockquotes
         if line.strip().startswith('>'):
-            return [('#ansiyellow', line)]
+            return [('class:quote', line)]
 
         # Check for unordered lists
         if re.match(r'^\s*[-*+]\s+', line):
             match = re.match(r'^(\s*[-*+]\s+)(.*)$', line)
             if match:
                 bullet, rest = match.groups()
-                result = [('#ansimagenta bold', bullet)]
+                result = [('class:bullet', bullet)]
                 result.extend(self._parse_inline_markdown(rest))
                 return result
 
@@ -508,13 +3658,13 @@ class EditorUI:
             match = re.match(r'^(\s*\d+\.\s+)(.*)$', line)
             if match:
                 number, rest = match.groups()
-                result = [('#ansimagenta bold', number)]
+                result = [('class:bullet', number)]
                 result.extend(self._parse_inline_markdown(rest))
                 return result
 
         # Check for horizontal rules
         if re.match(r'^\s*[-*_]{3,}\s*$', line):
-            return [('#ansicyan', line)]
+            return [('class:rule', line)]
 
         # Otherwise parse inline markdown
         return self._parse_inline_markdown(line)
@@ -530,14 +3680,17 @@ class EditorUI:
         # Pattern for inline code, bold, italic, and links
         # Order matters: try more specific patterns first
         patterns = [
-            (r'`([^`]+)`', '#ansigreen'),           # Inline code
-            (r'\*\*\*([^*]+)\*\*\*', '#ansired bold italic'),  # Bold+italic
-            (r'___([^_]+)___', '#ansired bold italic'),        # Bold+italic
-            (r'\*\*([^*]+)\*\*', '#ansired bold'),  # Bold
-            (r'__([^_]+)__', '#ansired bold'),      # Bold
-            (r'\*([^*]+)\*', '#ansired italic'),    # Italic
-            (r'_([^_]+)_', '#ansired italic'),      # Italic
-            (r'\[([^\]]+)\]\([^)]+\)', '#ansiblue underline'),  # Links
+            (r'`([^`]+)`', 'class:code'),           # Inline code
+            (r'\*\*\*([^*]+)\*\*\*', 'class:emphasis bold italic'),  # Bold+italic
+            (r'___([^_]+)___', 'class:emphasis bold italic'),        # Bold+italic
+            (r'\*\*([^*]+)\*\*', 'class:emphasis bold'),  # Bold
+            (r'__([^_]+)__', 'class:emphasis bold'),      # Bold
+            (r'\*([^*]+)\*', 'class:emphasis italic'),    # Italic
+            (r'_([^_]+)_', 'class:emphasis italic'),      # Italic
+            (r'!\[\[[^\[\]\n]+\]\]', 'class:image'),  # Embedded images
+            (r'!\[[^\]]*\]\([^)]+\)', 'class:image'),  # Images
+            (r'\[\[[^\[\]\n]+\]\]', 'class:link'),  # Wiki links to other notes
+            (r'\[([^\]]+)\]\([^)]+\)', 'class:link'),  # Links
         ]
 
         while pos < len(text):
@@ -566,17 +3719,102 @@ class EditorUI:
         if cursor_col >= len(line):
             # Cursor at end of line
             result.append(('', line))
-            result.append(('reverse', ' '))  # Show cursor as reversed space
+            result.append(('class:cursor', ' '))  # Show cursor as reversed space
         else:
             # Cursor in middle of line
             if cursor_col > 0:
                 result.append(('', line[:cursor_col]))
-            result.append(('reverse', line[cursor_col]))  # Reversed character
+            result.append(('class:cursor', line[cursor_col]))  # Reversed character
             if cursor_col < len(line) - 1:
                 result.append(('', line[cursor_col + 1:]))
 
         return result
 
+    def get_highlight_query(self) -> str:
+        """Get the text whose matches are highlighted in the editor: the search being typed, or the last one"""
+        if self.focus_manager.is_editor_focused() and self.mode_manager.is_search_mode():
+            return self.mode_manager.command_buffer[1:]
+        return self.mode_manager.highlight_query
+
+    def _highlight_matches(self, formatted_segments, line: str, query: str):
+        """
+        Highlight the matches of a search in an already-formatted line
+
+        Args:
+            formatted_segments: The line's (style, text) tuples, not changed
+            line: The line's text
+            query: Search to highlight ("" for none)
+
+        Returns:
+            The segments, split where matches start and end
+        """
+        if not query or query not in line:
+            return formatted_segments
+        spans = []
+        start = line.find(query)
+        while start != -1:
+            spans.append((start, start + len(query)))
+            start = line.find(query, start + len(query))
+        return self._style_spans(formatted_segments, line, spans, "class:search")
+
+    def _underline_misspelled(self, formatted_segments, line: str):
+        """
+        Underline the misspelled words in an already-formatted line
+
+        Args:
+            formatted_segments: The line's (style, text) tuples, not changed
+            line: The line's text
+
+        Returns:
+            The segments, split where misspelled words start and end
+        """
+        spans = self.spellchecker.misspelled(line)
+        if not spans:
+            return formatted_segments
+        return self._style_spans(formatted_segments, line, spans, "class:misspelled")
+
+    def _style_spans(self, formatted_segments, line: str, spans: List[Tuple[int, int]], span_style: str):
+        """
+        Add a style to parts of an already-formatted line
+
+        Args:
+            formatted_segments: The line's (style, text) tuples, not changed
+            line: The line's text
+            spans: (start, end) of each part, in the line's text
+            span_style: Style to add to them
+
+        Returns:
+            The segments, split where the parts start and end
+        """
+        # Styling may not keep every character (e.g. spaces after a heading's #), so pad
+        matched = [False] * max(len(line), sum(len(text) for _, text in formatted_segments))
+        for start, end in spans:
+            matched[start:end] = [True] * (end - start)
+
+        result = []
+        char_pos = 0
+        for style, text in formatted_segments:
+            # Split the segment into runs that are all inside or all outside a match
+            run_start = 0
+            for offset in range(1, len(text) + 1):
+                if offset == len(text) or matched[char_pos + offset] != matched[char_pos + run_start]:
+                    run_style = f"{style} {span_style}".strip() if matched[char_pos + run_start] else style
+                    result.append((run_style, text[run_start:offset]))
+                    run_start = offset
+            char_pos += len(text)
+        return result
+
+    def show_search_match(self, query: str):
+        """Tell which of the note's matches of a search the cursor is on"""
+        matches = self.buffer.find_matches(query)
+        position = (self.buffer.cursor_row, self.buffer.cursor_col)
+        if position in matches:
+            self.mode_manager.set_message(t(
+                "msg.search_match", query=query, index=matches.index(position) + 1, count=len(matches)
+            ))
+        else:
+            self.mode_manager.clear_message()
+
     def _add_cursor_to_formatted_line(self, formatted_segments, cursor_col: int):
         """
         Add cursor to an already-formatted line at specified column
@@ -602,19 +3840,20 @@ class EditorUI:
                 if offset > 0:
                     result.append((style, text[:offset]))
 
-                # Add cursor character
-                result.append(('reverse', text[offset]))
+                # Add cursor character, with its combining marks
+                end = cluster_end(text, offset)
+                result.append(('class:cursor', text[offset:end]))
 
                 # Add text after cursor
-                if offset < text_len - 1:
-                    result.append((style, text[offset + 1:]))
+                if end < text_len:
+                    result.append((style, text[end:]))
 
                 cursor_added = True
                 char_pos += text_len
 
         # If cursor is at end of line, add a reversed space
         if not cursor_added:
-            result.append(('reverse', ' '))
+            result.append(('class:cursor', ' '))
 
         return result
 
@@ -673,7 +3912,7 @@ class EditorUI:
                 result.append((style, text))
             elif segment_start >= sel_start and segment_end <= sel_end:
                 # Segment is entirely inside selection
-                result.append(('bg:#44475a', text))
+                result.append(('class:selection', text))
             else:
                 # Segment is partially selected - split it
                 for i, ch in enumerate(text):
@@ -682,61 +3921,680 @@ class EditorUI:
                         # Character is selected
                         if show_cursor and ch_pos == cursor_col and line_num == self.buffer.cursor_row:
                             # This is where cursor is
-                            result.append(('reverse', ch))
+                            result.append(('class:cursor', ch))
                         else:
-                            result.append(('bg:#44475a', ch))
+                            result.append(('class:selection', ch))
                     else:
                         # Character not selected
                         if show_cursor and ch_pos == cursor_col and line_num == self.buffer.cursor_row:
                             # This is where cursor is
-                            result.append(('reverse', ch))
+                            result.append(('class:cursor', ch))
                         else:
                             result.append((style, ch))
 
-            char_pos = segment_end
+            char_pos = segment_end
+
+        # Add cursor at end if needed
+        if show_cursor and line_num == self.buffer.cursor_row and cursor_col >= char_pos:
+            if sel_start <= cursor_col <= sel_end:
+                result.append(('class:selection class:cursor', ' '))
+            else:
+                result.append(('class:cursor', ' '))
+
+        return result
+
+    def get_sidebar_content(self):
+        """Get formatted text for sidebar showing the notebook tree and notes"""
+        if self.history_view.is_open:
+            return self.get_history_list_content()
+        if self.template_picker.is_open:
+            return self.get_template_list_content()
+        if self.replace_view.is_open:
+            return self.get_replace_list_content()
+        if self.tasks_view.is_open:
+            return self.get_tasks_list_content()
+        if self.outline_view.is_open:
+            return self.get_outline_list_content()
+
+        result = []
+        list_width = self.get_sidebar_width() - 2  # After the selection marker
+
+        rows = self.note_list_manager.get_rows()
+        now = utc_now()
+        for i, row in enumerate(rows):
+            indent = "  " * row.depth
+            width = max(10, list_width - 3 - len(indent))
+            tag_text, description_text, age_text = "", "", ""
+            due_text, due_style = "", ""
+            lead, bullet = "", ""  # Before the preview: the indent, then a note's color label
+
+            if row.notebook:
+                # Notebook rows show an expand/collapse marker and trailing slash
+                collapsed = row.notebook.path in self.note_list_manager.collapsed_notebooks
+                if self.accessible:
+                    marker = "+" if collapsed else "-"
+                else:
+                    marker = "\u25b8" if collapsed else "\u25be"
+                preview = fit(f"{indent}{marker} {row.notebook.name}/", list_width)
+            else:
+                note = row.note
+                if note.color:
+                    bullet = f"[{t(f'color.{note.color}')}] " if self.accessible else COLOR_BULLET
+                    width = max(10, width - text_width(bullet))
+                preview = note.get_preview(width)
+
+                # Show when it was edited, the due date and tags after the preview, shortening the preview to make room
+                if self.metadata:
+                    sorted_by_creation = self.note_list_manager.sort_order == "created"
+                    age_text = " " + format_age(note.created_at if sorted_by_creation else note.updated_at, now)
+                if note.due_at is not None:
+                    due_text = " " + format_due(note.due_at, now)
+                    due_style = 'class:overdue' if is_overdue(note, now) else 'class:due'
+                if note.tags:
+                    tag_text = " " + " ".join(f"#{tag}" for tag in note.tags)
+                if age_text or due_text or tag_text:
+                    extra_width = text_width(age_text) + text_width(due_text) + text_width(tag_text)
+                    preview = note.get_preview(max(10, width - extra_width))
+                    room = list_width - len(indent) - text_width(bullet) - text_width(preview)
+                    age_text = fit(age_text, room)
+                    due_text = fit(due_text, room - text_width(age_text))
+                    tag_text = fit(tag_text, room - text_width(age_text) - text_width(due_text))
+
+                # Add [NEW] indicator for in-memory note and a marker for pinned notes
+                if note is self.note_list_manager.in_memory_note:
+                    preview = f"{t('indicator.new')} {preview}"
+                if note.pinned:
+                    preview = f"{t('indicator.pinned')} {preview}"
+                if note.starred:
+                    preview = f"{t('indicator.starred')} {preview}"
+                if note.remind_at is not None:
+                    preview = f"{t('indicator.reminder')} {preview}"
+                if note.is_secure:
+                    preview = f"{t('indicator.secure')} {preview}"
+                if note.id in self.note_list_manager.marked_ids:
+                    preview = f"{t('indicator.marked')} {preview}"
+                lead = indent
+
+                # Follow the title with the note's first sentence where there's room
+                room = width - text_width(preview) - text_width(age_text) - text_width(due_text) - text_width(tag_text) - 2
+                description = note.description if self.descriptions and room >= MIN_DESCRIPTION_WIDTH else ""
+                if description:
+                    description_text = "  " + truncate(description, room)
+
+            # Highlight selected row
+            if i == self.note_list_manager.selected_index:
+                # Show selection indicator and highlight
+                if self.focus_manager.is_sidebar_focused():
+                    # Focused sidebar - use reverse video
+                    row_style, marker = 'class:selected', "> "
+                    tag_style = 'class:selected'
+                else:
+                    # Unfocused sidebar - just show indicator
+                    row_style, marker = '', "> "
+                    tag_style = 'class:muted'
+            else:
+                row_style, marker = 'class:notebook' if row.notebook else '', "  "
+                tag_style = 'class:muted'
+            result.append((row_style, f"{marker}{lead}"))
+            if bullet:
+                result.append((f"{row_style} class:color.{row.note.color}".strip(), bullet))
+            result.append((row_style, preview))
+            if description_text:
+                result.append((tag_style, description_text))
+            if age_text:
+                result.append((tag_style, age_text))
+
+            if due_text:
+                result.append((tag_style if tag_style == 'class:selected' else due_style, due_text))
+            if tag_text:
+                result.append((tag_style, tag_text))
+
+            # Search results show a highlighted excerpt on the following line
+            if row.snippet:
+                result.append(('', '\n    '))
+                result.extend(self._format_snippet(row.snippet, list_width - 2))
+
+            # Add newline except for last item
+            if i < len(rows) - 1:
+                result.append(('', '\n'))
+
+        return FormattedText(result)
+
+    def _format_revision(self, index: int) -> str:
+        """
+        Describe a revision in the history viewer as number, time and line counts
+
+        Args:
+            index: Index of the revision in the history viewer
+
+        Returns:
+            Single-line description
+        """
+        revisions = self.history_view.revisions
+        revision = revisions[index]
+        previous = revisions[index + 1].content if index + 1 < len(revisions) else ""
+        diff = diff_lines(previous, revision.content)
+        added = sum(1 for line in diff if line.startswith("+"))
+        removed = sum(1 for line in diff if line.startswith("-"))
+        saved_at = revision.saved_at.replace(tzinfo=timezone.utc).astimezone()
+        return f"#{revision.rev} {saved_at:%m-%d %H:%M} +{added} -{removed}"
+
+    def get_history_list_content(self):
+        """Get formatted text for the sidebar listing the revisions of a note"""
+        result = []
+        for i in range(len(self.history_view.revisions)):
+            text = fit(self._format_revision(i), self.get_sidebar_width() - 2)
+            if i == self.history_view.selected_index:
+                result.append(('class:selected', f"> {text}"))
+            else:
+                result.append(('', f"  {text}"))
+            if i < len(self.history_view.revisions) - 1:
+                result.append(('', '\n'))
+
+        # Only one pane is shown in accessible mode, so the diff follows the list
+        if self.accessible:
+            result.append(('', '\n\n'))
+            result.extend(self.get_history_diff_content())
+        return FormattedText(result)
+
+    def get_template_list_content(self):
+        """Get formatted text for the sidebar listing the templates"""
+        result = []
+        templates = self.template_picker.templates
+        for i, template in enumerate(templates):
+            text = fit(template.name, self.get_sidebar_width() - 2)
+            if i == self.template_picker.selected_index:
+                result.append(('class:selected', f"> {text}"))
+            else:
+                result.append(('', f"  {text}"))
+            if i < len(templates) - 1:
+                result.append(('', '\n'))
+
+        # Only one pane is shown in accessible mode, so the preview follows the list
+        if self.accessible:
+            result.append(('', '\n\n'))
+            result.extend(self.get_template_preview_content())
+        return FormattedText(result)
+
+    def get_template_preview_content(self):
+        """Get formatted text previewing the selected template as a new note would start"""
+        self.update_editor_window_height()
+        template = self.template_picker.selected_template
+        if template is None:
+            return []
+        content, _, _ = render_template(template)
+        result = []
+        for line in content.split('\n')[:self.editor_window_height]:
+            result.extend(self._parse_markdown_line(line))
+            result.append(('', '\n'))
+        return result
+
+    def get_history_diff_content(self):
+        """Get formatted text for the diff of the revision selected in the history viewer"""
+        return self._format_diff(self.history_view.get_diff(), self.history_view.scroll_offset)
+
+    def get_replace_list_content(self):
+        """Get formatted text for the sidebar listing the notes a replacement changes"""
+        result = []
+        replacements = self.replace_view.replacements
+        for i, change in enumerate(replacements):
+            box = "[ ]" if change.note_id in self.replace_view.skipped else "[x]"
+            text = f"{box} {change.title} ({change.count})"[:self.get_sidebar_width() - 2]
+            if i == self.replace_view.selected_index:
+                result.append(('class:selected', f"> {text}"))
+            else:
+                result.append(('', f"  {text}"))
+            if i < len(replacements) - 1:
+                result.append(('', '\n'))
+
+        if self.accessible:
+            result.append(('', '\n\n'))
+            result.extend(self.get_replace_diff_content())
+        return FormattedText(result)
+
+    def get_tasks_list_content(self):
+        """Get formatted text for the sidebar listing tasks under their notes' titles"""
+        result = []
+        width = self.get_sidebar_width() - 2
+        note_id = None
+        if not self.tasks_view.tasks:
+            result.append(('class:muted', f"  {t('msg.no_tasks')}"))
+        for i, task in enumerate(self.tasks_view.tasks):
+            if task.note_id != note_id:
+                # Heading for each note's tasks
+                note_id = task.note_id
+                result.append(('class:notebook', fit(f"  {task.note_title or t('note.empty_preview')}", width + 2)))
+                result.append(('', '\n'))
+            text = fit(f"  {'[x]' if task.done else '[ ]'} {task.text}", width)
+            if i == self.tasks_view.selected_index:
+                result.append(('class:selected', f"> {text}"))
+            else:
+                result.append(('class:muted' if task.done else '', f"  {text}"))
+            if i < len(self.tasks_view.tasks) - 1:
+                result.append(('', '\n'))
+
+        if self.accessible:
+            result.append(('', '\n\n'))
+            result.extend(self.get_task_preview_content())
+        return FormattedText(result)
 
-        # Add cursor at end if needed
-        if show_cursor and line_num == self.buffer.cursor_row and cursor_col >= char_pos:
-            if sel_start <= cursor_col <= sel_end:
-                result.append(('reverse bg:#44475a', ' '))
+    def get_outline_list_content(self):
+        """Get formatted text for the sidebar listing the open note's headings, indented by level"""
+        result = []
+        width = self.get_sidebar_width() - 2
+        view = self.outline_view
+        for i, heading in enumerate(view.headings):
+            text = fit(f"{'  ' * (heading.level - view.top_level)}{heading.text}", width)
+            if i == view.selected_index:
+                result.append(('class:selected', f"> {text}"))
             else:
-                result.append(('reverse', ' '))
+                result.append(('class:heading' if heading.level == view.top_level else '', f"  {text}"))
+            if i < len(view.headings) - 1:
+                result.append(('', '\n'))
+        return FormattedText(result)
 
+    def get_task_preview_content(self):
+        """Get formatted text showing the selected task's note, starting a few lines above the task"""
+        self.update_editor_window_height()
+        task = self.tasks_view.selected
+        note = self.storage.get_note(task.note_id) if task else None
+        if note is None:
+            return []
+        lines = note.content.split('\n')
+        start = max(0, min(task.line - 3, len(lines) - self.editor_window_height))
+        result = []
+        for index, line in enumerate(lines[start:start + self.editor_window_height], start=start):
+            if index == task.line:
+                result.append(('class:match', line))
+            else:
+                result.extend(self._parse_markdown_line(line))
+            result.append(('', '\n'))
         return result
 
-    def get_sidebar_content(self):
-        """Get formatted text for sidebar showing note list"""
+    def get_replace_diff_content(self):
+        """Get formatted text for the changes a replacement makes to the selected note"""
+        change = self.replace_view.selected
+        return self._format_diff(change.diff() if change else [], self.replace_view.scroll_offset)
+
+    def _format_diff(self, diff: List[str], start: int):
+        """
+        Format the visible part of a unified diff, colored by line type
+
+        Args:
+            diff: Diff lines
+            start: First line to show
+
+        Returns:
+            List of (style, text) fragments
+        """
+        self.update_editor_window_height()
         result = []
+        for line in diff[start:start + self.editor_window_height]:
+            if line.startswith("+"):
+                style = 'class:diff.added'
+            elif line.startswith("-"):
+                style = 'class:diff.removed'
+            elif line.startswith("@@"):
+                style = 'class:diff.hunk'
+            else:
+                style = ''
+            result.append((style, line + '\n'))
+        return result
 
-        all_notes = self.note_list_manager.get_all_notes_including_memory()
-        for i, note in enumerate(all_notes):
-            preview = note.get_preview(25)
+    def _format_snippet(self, snippet: str, width: int):
+        """
+        Format a search snippet as styled fragments, scrolled to its first match
 
-            # Add [NEW] indicator for in-memory note
-            is_in_memory = (i == 0 and self.note_list_manager.in_memory_note is not None)
-            if is_in_memory:
-                preview = f"[NEW] {preview}"
+        Args:
+            snippet: Snippet with highlight markers
+            width: Maximum number of columns to show
 
-            # Highlight selected note
-            if i == self.note_list_manager.selected_index:
-                # Show selection indicator and highlight
-                if self.focus_manager.is_sidebar_focused():
-                    # Focused sidebar - use reverse video
-                    result.append(('reverse', f"> {preview}"))
-                else:
-                    # Unfocused sidebar - just show indicator
-                    result.append(('', f"> {preview}"))
+        Returns:
+            List of (style, text) fragments
+        """
+        parts = split_highlights(snippet)
+
+        # Start a few columns before the first highlighted term
+        first_match = 0
+        offset = 0
+        for highlighted, text in parts:
+            if highlighted:
+                first_match = offset
+                break
+            offset += text_width(text)
+        start = max(0, first_match - 6)
+        end = start + width
+
+        fragments = []
+        offset = 0
+        for highlighted, text in parts:
+            visible = slice_columns(text, start, end, offset)
+            if visible:
+                fragments.append(('class:match' if highlighted else 'class:muted', visible))
+            offset += text_width(text)
+        return fragments
+
+    def get_search_preview_note(self):
+        """
+        Get the note to preview in the editor pane while browsing sidebar search results
+
+        Returns:
+            The selected result if it isn't the note in the editor, None otherwise
+        """
+        if not self.focus_manager.is_sidebar_focused() or not self.note_list_manager.is_showing_search_results():
+            return None
+        note = self.note_list_manager.selected_note
+        if note is None or note.id == self.buffer.current_note_id:
+            return None
+        return note
+
+    def get_search_preview_content(self, note: Note):
+        """
+        Get formatted text previewing a note with the search terms highlighted
+
+        The preview starts a little above the first matching line so the
+        match is in view.
+
+        Args:
+            note: Note to preview
+
+        Returns:
+            List of (style, text) fragments
+        """
+        self.update_editor_window_height()
+        self.update_editor_window_width()
+        terms = self.note_list_manager.search_query.split()
+        lines = note.content.split('\n')
+
+        highlighted_lines = []
+        first_match = None
+        for line_number, line in enumerate(lines):
+            positions = set()
+            for term in terms:
+                match = fuzzy_match(line, term)
+                if match:
+                    positions.update(match[1])
+            if positions and first_match is None:
+                first_match = line_number
+            highlighted_lines.append(positions)
+
+        start = max(0, (first_match or 0) - 2)
+        result = []
+        for line, positions in zip(lines[start:start + self.editor_window_height],
+                                   highlighted_lines[start:start + self.editor_window_height]):
+            visible = highlight_positions(line[:self.editor_window_width], sorted(positions))
+            for highlighted, text in split_highlights(visible):
+                result.append(('class:match' if highlighted else '', text))
+            result.append(('', '\n'))
+        return result
+
+    def get_editor_cursor_position(self) -> Point:
+        """Get the terminal cursor position within the editor window (accessible mode)"""
+        if self.wrap_lines:
+            starts = self._row_starts(self.buffer.cursor_row)
+            start = max(start for start in starts if start <= self.buffer.cursor_col)
+            return Point(x=column_of(self.buffer.current_line[start:], self.buffer.cursor_col - start),
+                         y=self._rows_to_cursor() - self._hidden_wrapped_rows())
+        return Point(
+            x=max(0, column_of(self.buffer.current_line, self.buffer.cursor_col) - self.buffer.horizontal_scroll_offset),
+            y=max(0, self.buffer.cursor_row - self.buffer.scroll_offset)
+        )
+
+    def get_sidebar_cursor_position(self) -> Point:
+        """Get the terminal cursor position within the sidebar window (accessible mode)"""
+        if self.history_view.is_open:
+            return Point(x=0, y=self.history_view.selected_index)
+        if self.template_picker.is_open:
+            return Point(x=0, y=self.template_picker.selected_index)
+        if self.replace_view.is_open:
+            return Point(x=0, y=self.replace_view.selected_index)
+        if self.tasks_view.is_open:
+            return Point(x=0, y=self.tasks_view.row_of_selected())
+        if self.outline_view.is_open:
+            return Point(x=0, y=self.outline_view.selected_index)
+        if self.note_list_manager.is_showing_search_results():
+            # Each search result takes two lines: preview and snippet
+            return Point(x=0, y=self.note_list_manager.selected_index * 2)
+        return Point(x=0, y=self.note_list_manager.selected_index)
+
+    def get_pane_label_content(self):
+        """Get formatted text for the pane label line shown in accessible mode"""
+        if self.confirm_dialog.is_open:
+            label = t("a11y.dialog", question=self.confirm_dialog.question, choices=self.get_dialog_choices())
+        elif self.tour.is_open:
+            label = t("a11y.pane_tour")
+        elif self.switcher.is_open:
+            label = t("a11y.pane_switcher", query=self.switcher.query)
+        elif self.help_view.is_open:
+            label = t("a11y.pane_help")
+        elif self.history_view.is_open:
+            note = self.storage.get_note(self.history_view.note_id)
+            label = t("a11y.pane_history", title=note.get_preview(40) if note else "")
+        elif self.template_picker.is_open:
+            label = t("a11y.pane_templates", count=len(self.template_picker.templates))
+        elif self.replace_view.is_open:
+            label = t(
+                "a11y.pane_replace",
+                pattern=self.replace_view.pattern,
+                replacement=self.replace_view.replacement,
+                count=len(self.replace_view.replacements)
+            )
+        elif self.tasks_view.is_open:
+            label = t("a11y.pane_tasks", count=len(self.tasks_view.tasks))
+        elif self.outline_view.is_open:
+            label = t("a11y.pane_outline", count=len(self.outline_view.headings))
+        elif self.focus_manager.is_sidebar_focused():
+            label = t("a11y.pane_notes", count=self.note_list_manager.get_note_count())
+        else:
+            if self.buffer.current_note_id:
+                title = Note(self.buffer.current_note_id, self.buffer.get_text()).get_preview(40)
             else:
-                result.append(('', f"  {preview}"))
+                title = t("msg.no_note_loaded")
+            label = t("a11y.pane_editor", title=title)
+        return FormattedText([('class:label', label)])
+
+    def get_dialog_content(self):
+        """Get formatted text for the confirmation dialog"""
+        if self.confirm_dialog.listed:
+            lines = [f" {key}: {label} " for key, label, _ in self.confirm_dialog.options]
+            return FormattedText([
+                ('', f" {self.confirm_dialog.question} \n\n"),
+                ('', "\n".join(lines) + "\n\n"),
+                ('class:label', f" {t('dialog.choice_cancel')} "),
+            ])
+        return FormattedText([
+            ('', f" {self.confirm_dialog.question} \n\n"),
+            ('class:label', f" {self.get_dialog_choices()} "),
+        ])
+
+    def get_dialog_choices(self) -> str:
+        """Get the keys the confirmation dialog takes and what they do"""
+        if not self.confirm_dialog.options:
+            return t("dialog.choices")
+        choices = [f"{key}: {label}" for key, label, _ in self.confirm_dialog.options]
+        return "   ".join(choices + [t("dialog.choice_cancel")])
+
+    def get_accessible_status_bar_content(self):
+        """
+        Get formatted text for the status bar in accessible mode
 
-            # Add newline except for last item
-            if i < len(all_notes) - 1:
-                result.append(('', '\n'))
+        Spells out mode, position, and modified state as labelled words
+        separated by " | " instead of relying on layout or color.
+        """
+        parts = []
+
+        if self.mode_manager.command_buffer:
+            parts.append(t("a11y.command", command=self.mode_manager.command_buffer))
+        elif self.mode_manager.is_insert_mode():
+            parts.append(t("a11y.mode_insert"))
+        elif self.mode_manager.is_visual_mode():
+            parts.append(t("a11y.mode_visual"))
+        elif self.mode_manager.is_visual_line_mode():
+            parts.append(t("a11y.mode_visual_line"))
+        else:
+            parts.append(t("a11y.mode_normal"))
+
+        if self.tour.is_open:
+            parts.append(t("a11y.tour_step", step=self.tour.step_index + 1, total=len(TOUR_STEPS)))
+        elif self.switcher.is_open:
+            note = self.switcher.selected
+            parts.append(t(
+                "a11y.switcher_note",
+                index=self.switcher.selected_index + 1,
+                total=len(self.switcher.matches),
+                title=note.title or t("note.empty_preview")
+            ) if note else t("switcher.no_match"))
+        elif self.help_view.is_open:
+            parts.append(t(
+                "a11y.help_lines",
+                start=self.help_view.scroll_offset + 1,
+                end=min(self.help_view.scroll_offset + self.get_help_height(), self.help_view.line_count),
+                total=self.help_view.line_count
+            ))
+        elif self.history_view.is_open:
+            parts.append(t(
+                "a11y.revision",
+                index=self.history_view.selected_index + 1,
+                total=len(self.history_view.revisions),
+                revision=self._format_revision(self.history_view.selected_index)
+            ))
+        elif self.template_picker.is_open and self.template_picker.selected_template:
+            parts.append(t(
+                "a11y.template",
+                index=self.template_picker.selected_index + 1,
+                total=len(self.template_picker.templates),
+                name=self.template_picker.selected_template.name
+            ))
+        elif self.replace_view.is_open and self.replace_view.selected:
+            change = self.replace_view.selected
+            parts.append(t(
+                "a11y.replacement_skipped" if change.note_id in self.replace_view.skipped else "a11y.replacement",
+                index=self.replace_view.selected_index + 1,
+                total=len(self.replace_view.replacements),
+                title=change.title,
+                count=change.count
+            ))
+        elif self.tasks_view.is_open and self.tasks_view.selected:
+            task = self.tasks_view.selected
+            parts.append(t(
+                "a11y.task_done" if task.done else "a11y.task",
+                index=self.tasks_view.selected_index + 1,
+                total=len(self.tasks_view.tasks),
+                task=task.text,
+                title=task.note_title
+            ))
+        elif self.outline_view.is_open and self.outline_view.selected:
+            heading = self.outline_view.selected
+            parts.append(t(
+                "a11y.heading",
+                index=self.outline_view.selected_index + 1,
+                total=len(self.outline_view.headings),
+                level=heading.level,
+                heading=heading.text
+            ))
+        elif self.focus_manager.is_sidebar_focused():
+            parts.append(t(
+                "a11y.note_position",
+                index=self.note_list_manager.selected_index + 1,
+                total=self.note_list_manager.get_row_count()
+            ))
+            notebook = self.note_list_manager.selected_notebook
+            if notebook:
+                collapsed = notebook.path in self.note_list_manager.collapsed_notebooks
+                parts.append(t(
+                    "a11y.notebook_collapsed" if collapsed else "a11y.notebook_expanded",
+                    path=notebook.path,
+                    count=notebook.note_count()
+                ))
+            row = self.note_list_manager.selected_row
+            if row and row.snippet:
+                parts.append(t(
+                    "a11y.search_match",
+                    snippet="".join(text for _, text in split_highlights(row.snippet))
+                ))
+        else:
+            parts.append(t(
+                "a11y.position",
+                row=self.buffer.cursor_row + 1,
+                total=self.buffer.line_count,
+                col=self.buffer.cursor_col + 1
+            ))
+
+            if self.mode_manager.is_any_visual_mode():
+                start_row, end_row = self.mode_manager.get_visual_line_selection(self.buffer.cursor_row)
+                parts.append(t("a11y.selection", count=end_row - start_row + 1))
+
+        if self.read_only:
+            parts.append(t("a11y.read_only"))
+        if self.note_list_manager.show_trash:
+            parts.append(t("a11y.trash"))
+        if self.note_list_manager.show_archive:
+            parts.append(t("a11y.archive"))
+        if self.note_list_manager.tag_filter:
+            parts.append(t("a11y.tag_filter", tag=self.note_list_manager.tag_filter))
+        if self.note_list_manager.color_filter:
+            parts.append(t("a11y.color_filter", color=t(f"color.{self.note_list_manager.color_filter}")))
+        if self.note_list_manager.is_showing_search_results():
+            parts.append(t(
+                "a11y.search_results",
+                query=self.note_list_manager.search_query,
+                count=len(self.note_list_manager.search_results)
+            ))
 
-        return FormattedText(result)
+        if self.buffer.is_new_unsaved:
+            parts.append(t("a11y.new_note"))
+        elif self.buffer.is_dirty:
+            parts.append(t("a11y.modified"))
+
+        stats = self.get_note_stats()
+        if stats is not None:
+            parts.append(t("a11y.note_stats", words=stats.words, characters=stats.characters,
+                           minutes=stats.reading_minutes))
+
+        if self.status_line:
+            parts.extend(text for _, text in self.get_status_line_parts())
+
+        if self.mode_manager.message:
+            parts.append(self.mode_manager.message)
+        parts.extend(toast.text for toast in self.toasts.toasts)
+
+        return FormattedText([('', " | ".join(parts))])
+
+    def get_note_stats(self) -> Optional[TextStats]:
+        """
+        Get the size of the note selected in the sidebar, or open in the editor
+
+        The open note's size includes unsaved changes.
+
+        Returns:
+            Word and character counts, or None while no note is selected
+        """
+        if (self.history_view.is_open or self.template_picker.is_open or self.replace_view.is_open or
+                self.tasks_view.is_open):
+            return None
+        content = self.buffer.get_text()
+        if self.focus_manager.is_sidebar_focused():
+            note = self.note_list_manager.selected_note
+            if note is None:
+                return None
+            if note.id != self.buffer.current_note_id:
+                content = note.content
+        return self._text_stats(content)
+
+    def _text_stats(self, content: str) -> TextStats:
+        """Count the words and characters of a note's content, remembering the last count"""
+        # Counting words on every redraw adds up in long notes
+        if self._stats_cache[0] != content:
+            self._stats_cache = (content, text_stats(content))
+        return self._stats_cache[1]
 
     def get_status_bar_content(self):
         """Get formatted text for status bar"""
+        if self.accessible:
+            return self.get_accessible_status_bar_content()
+        if self.is_zen():
+            return self.get_zen_footer_content()
+
         # Get terminal width
         try:
             import shutil
@@ -749,10 +4607,42 @@ class EditorUI:
 
         # Focus indicator
         focus_str = f"[{self.focus_manager.get_focus_name()}]"
+        if self.history_view.is_open:
+            focus_str = f"[{t('focus.history')}]"
+        elif self.template_picker.is_open:
+            focus_str = f"[{t('focus.templates')}]"
+        elif self.replace_view.is_open:
+            focus_str = f"[{t('focus.replace')}]"
+        elif self.tasks_view.is_open:
+            focus_str = f"[{t('focus.tasks')}]"
+        elif self.outline_view.is_open:
+            focus_str = f"[{t('focus.outline')}]"
+        if self.help_view.is_open:
+            focus_str = f"[{t('focus.help')}]"
+        elif self.tour.is_open:
+            focus_str = f"[{t('focus.tour')}]"
+        elif self.switcher.is_open:
+            focus_str = f"[{t('focus.switcher')}]"
+        if self.read_only:
+            focus_str += f" {t('indicator.read_only')}"
+        if self.note_list_manager.show_trash:
+            focus_str += f" {t('indicator.trash')}"
+        if self.note_list_manager.show_archive:
+            focus_str += f" {t('indicator.archive')}"
+        if self.note_list_manager.show_starred:
+            focus_str += f" {t('indicator.starred_view')}"
+        if self.note_list_manager.tag_filter:
+            focus_str += f" [#{self.note_list_manager.tag_filter}]"
+        if self.note_list_manager.color_filter:
+            focus_str += f" {t('indicator.color_filter', color=t(f'color.{self.note_list_manager.color_filter}'))}"
+        if self.note_list_manager.is_showing_search_results():
+            focus_str += f" [/{self.note_list_manager.search_query}]"
+        if self.in_table_mode():
+            focus_str += f" {t('indicator.table')}"
 
         # Dirty/new indicator
         if self.buffer.is_new_unsaved:
-            dirty_str = "[NEW]"
+            dirty_str = t("indicator.new")
         elif self.buffer.is_dirty:
             dirty_str = "[+]"
         else:
@@ -770,6 +4660,14 @@ class EditorUI:
         else:
             pos_str = f"{dirty_str} {row},{col}  {row}/{total_lines}".strip()
 
+        # Size of the selected note, left out when it doesn't fit
+        stats = self.get_note_stats()
+        if stats is not None:
+            stats_str = t("indicator.note_stats", words=stats.words, characters=stats.characters,
+                          minutes=stats.reading_minutes)
+        else:
+            stats_str = ""
+
         # Message (middle)
         message = self.mode_manager.message
 
@@ -780,64 +4678,406 @@ class EditorUI:
             left_part = f"{mode_str}  {focus_str}"
 
         # Calculate padding
-        used_width = len(left_part) + len(pos_str)
+        used_width = text_width(left_part) + text_width(pos_str)
+        if stats_str and used_width + text_width(stats_str) + 2 <= width:
+            pos_str = f"{stats_str}  {pos_str}"
+            used_width += text_width(stats_str) + 2
         padding = ' ' * max(0, width - used_width)
 
         status = f"{left_part}{padding}{pos_str}"
 
-        return FormattedText([('reverse', status)])
+        return FormattedText([('class:status', status)])
+
+    def _storage_label(self, config) -> str:
+        """Name the storage backend for the status line, with the one it encrypts and whether the daemon serves it"""
+        label = config.storage_backend
+        if label == "encrypted":
+            label = t("status.encrypted", backend=config.encrypted_wraps)
+        if find_backend(self.storage, DaemonBackend) is not None:
+            label = t("status.daemon", backend=label)
+        return label
+
+    def get_pending_sync(self) -> Optional[int]:
+        """
+        Count the changes not pushed to the sync server yet
+
+        The storage is asked at most every SYNC_STATUS_INTERVAL, since this
+        runs on every redraw; saving or syncing asks again right away.
+
+        Returns:
+            The count, or None if the storage doesn't sync
+        """
+        if not self.storage.supports_sync:
+            return None
+        checked_at, count = self._pending_sync
+        now = time.monotonic()
+        if checked_at is None or now - checked_at >= SYNC_STATUS_INTERVAL:
+            try:
+                count = self.storage.pending_sync()
+            except OSError:
+                pass  # The daemon went away; keep the last count
+            self._pending_sync = (now, count)
+        return count
+
+    def show_status_line(self) -> bool:
+        """Check whether the status line shows under the status bar"""
+        return self.status_line and not self.accessible and not self.is_zen()
+
+    def get_status_line_parts(self) -> List[Tuple[str, str]]:
+        """
+        Get what the status line says
+
+        How many notes are listed out of the notes in the view, the sort
+        order, the storage backend, how long ago a note was last saved and,
+        with storage that syncs, how many changes haven't been pushed.
+
+        Returns:
+            (style, text) of each part
+        """
+        listed, total = self.note_list_manager.count_notes()
+        parts = [
+            ('', t("status.notes_all", count=total) if listed == total else
+             t("status.notes", listed=listed, total=total)),
+            ('', t("status.sort", order=t(f"sort.{self.note_list_manager.sort_order}"))),
+            ('', self.storage_label),
+        ]
+        if self.last_saved_at is not None:
+            parts.append(('', t("status.saved", ago=format_ago(self.last_saved_at))))
+        pending = self.get_pending_sync()
+        if pending:
+            parts.append(('class:status.pending', t("status.unsynced", count=pending)))
+        elif pending == 0:
+            parts.append(('', t("status.synced")))
+        return parts
+
+    def get_status_line_content(self):
+        """Get formatted text for the line under the status bar, cut off where it doesn't fit"""
+        try:
+            import shutil
+            width = shutil.get_terminal_size().columns
+        except:
+            width = 80
+
+        fragments = []
+        for index, (style, text) in enumerate(self.get_status_line_parts()):
+            text = (" · " if index else " ") + text
+            shown = fit(text, width)
+            fragments.append((f"class:status.info {style}".rstrip(), shown))
+            width -= text_width(shown)
+            if shown != text:
+                break
+        return FormattedText(fragments)
+
+    def get_zen_footer_content(self):
+        """
+        Get formatted text for the footer shown instead of the status bar in zen mode
+
+        Only the word count and unsaved indicator, centered, unless a mode,
+        command or message needs the line.
+        """
+        try:
+            import shutil
+            width = shutil.get_terminal_size().columns
+        except:
+            width = 80
+
+        mode_str = self.mode_manager.get_mode_string()
+        message = self.mode_manager.message
+        if self.mode_manager.command_buffer or message:
+            footer = f"{mode_str}  {message}".strip() if message else mode_str
+            return FormattedText([('class:zen.footer', fit(footer, width))])
+
+        stats = self.get_note_stats()
+        footer = t("indicator.zen_words", words=stats.words if stats else 0)
+        if self.buffer.is_new_unsaved or self.buffer.is_dirty:
+            footer += " [+]"
+        if mode_str:
+            footer = f"{mode_str}  {footer}"
+        return FormattedText([('class:zen.footer', footer.center(width)[:width])])
+
+    def get_toast_content(self):
+        """Get formatted text for the toasts, one per line, newest at the bottom"""
+        fragments = []
+        for toast in self.toasts.toasts:
+            if fragments:
+                fragments.append(('', '\n'))
+            fragments.append((f'class:toast.{toast.level}', f" {toast.text} "))
+        return FormattedText(fragments)
 
     def update_editor_window_height(self):
         """Update the cached editor window height based on terminal size"""
         try:
             import shutil
             terminal_height = shutil.get_terminal_size().lines
-            # Subtract status bar (1 line)
-            self.editor_window_height = max(1, terminal_height - 1)
+            # Subtract status bar (1 line), plus the pane label in accessible mode or the pane title with borders,
+            # the metadata line and the status line
+            chrome_height = 2 if self.accessible or self.show_borders() else 1
+            if self.show_metadata_header():
+                chrome_height += 1
+            if self.show_status_line():
+                chrome_height += 1
+            self.editor_window_height = max(1, terminal_height - chrome_height)
         except:
             self.editor_window_height = 24  # Default fallback
 
+    def get_sidebar_width(self) -> int:
+        """
+        Get the sidebar width in columns
+
+        The sidebar_width setting is a number of columns, or a fraction of the
+        terminal width if below 1.
+
+        Returns:
+            Width in columns (at least 12)
+        """
+        if self.sidebar_width_setting >= 1:
+            return max(12, int(self.sidebar_width_setting))
+        try:
+            import shutil
+            terminal_width = shutil.get_terminal_size().columns
+        except:
+            terminal_width = 80
+        return max(12, int(terminal_width * self.sidebar_width_setting))
+
+    def show_borders(self) -> bool:
+        """Check if the pane title lines and the line between the panes are drawn"""
+        return self.borders and not self.accessible and not self.is_zen()
+
+    def get_metadata_note(self) -> Optional[Note]:
+        """
+        Get the note whose metadata line shows above the editor pane
+
+        Returns:
+            The note the editor pane shows, or None while it shows something
+            else, a note that isn't saved yet, or a locked secure note
+        """
+        if (self.history_view.is_open or self.template_picker.is_open or self.replace_view.is_open or
+                self.tasks_view.is_open):
+            return None
+        preview_note = self.get_search_preview_note()
+        if preview_note is not None:
+            return preview_note
+        note_id = self.buffer.current_note_id
+        if not note_id or self.buffer.is_new_unsaved or self.is_current_note_locked():
+            return None
+        # As listed, rather than asking storage on every redraw
+        selected = self.note_list_manager.selected_note
+        if selected is not None and selected.id == note_id:
+            return selected
+        return next((note for note in self.note_list_manager.notes if note.id == note_id), None)
+
+    def show_metadata_header(self) -> bool:
+        """Check whether the metadata line shows above the editor pane"""
+        return self.metadata and not self.accessible and not self.is_zen() and self.get_metadata_note() is not None
+
+    def get_metadata_content(self):
+        """Get formatted text for the line above the editor: the note's dates, word count and tags"""
+        note = self.get_metadata_note()
+        if note is None:
+            return FormattedText([])
+        content = self.buffer.get_text() if note.id == self.buffer.current_note_id else note.content
+        header = metadata_header(note, self._text_stats(content).words)
+        return FormattedText([('class:muted', fit(f" {header}", self.editor_window_width))])
+
+    def get_pane_title_content(self, sidebar: bool):
+        """
+        Get formatted text for the title line above a pane, highlighted if the pane has focus
+
+        Args:
+            sidebar: The note list's title rather than the editor's
+        """
+        if sidebar:
+            focused = self.focus_manager.is_sidebar_focused()
+            title = t("pane.notes")
+            width = self.get_sidebar_width()
+        else:
+            focused = self.focus_manager.is_editor_focused()
+            if self.buffer.current_note_id:
+                title = Note(self.buffer.current_note_id, self.buffer.get_text()).title or t("note.empty_preview")
+            else:
+                title = t("msg.no_note_loaded")
+            width = self.editor_window_width
+        # The window fills the rest of the line with the border character
+        return FormattedText([('class:border.focused' if focused else 'class:border', fit(f"─ {title} ", width))])
+
+    def toggle_pane_focus(self):
+        """Give focus to the other pane"""
+        if self.focus_manager.is_sidebar_focused():
+            self.focus_editor()
+        else:
+            self.focus_manager.switch_to_sidebar()
+
+    def get_editor_width(self) -> Dimension:
+        """Get the editor window's width: zen_width in zen mode, what the live preview leaves, otherwise what's left"""
+        if self.is_zen() or self.show_live_preview():
+            self.update_editor_window_width()
+            return Dimension.exact(self.editor_window_width)
+        return Dimension()
+
     def update_editor_window_width(self):
         """Update the cached editor window width based on terminal size"""
         try:
             import shutil
             terminal_width = shutil.get_terminal_size().columns
-            # Subtract sidebar (30 columns) only if it's visible
-            if self.focus_manager.sidebar_visible:
-                self.editor_window_width = max(1, terminal_width - 30)
+            # Zen mode centers the editor at zen_width when the terminal is wider
+            if self.is_zen():
+                self.editor_window_width = max(1, min(terminal_width, self.zen_width))
+            # Subtract the sidebar only if it's visible next to the editor
+            elif self.focus_manager.sidebar_visible and not self.accessible:
+                separator = 1 if self.show_borders() else 0
+                self.editor_window_width = max(1, terminal_width - self.get_sidebar_width() - separator)
             else:
                 self.editor_window_width = max(1, terminal_width)
+            # The live preview takes the right half, past a separator line
+            self.live_preview_width = 0
+            if self._wants_live_preview():
+                separator = 1 if self.show_borders() else 0
+                if self.editor_window_width >= 2 * PREVIEW_MIN_WIDTH + separator:
+                    self.live_preview_width = (self.editor_window_width - separator) // 2
+                    self.editor_window_width -= self.live_preview_width + separator
         except:
             self.editor_window_width = 80  # Default fallback
 
+    def _wants_live_preview(self) -> bool:
+        """Check if the live preview is on and has a note being edited to show, whether or not it fits"""
+        return (self.live_preview and not self.accessible and not self.is_zen() and
+                self.focus_manager.is_editor_focused() and bool(self.buffer.current_note_id) and
+                not (self.history_view.is_open or self.template_picker.is_open or self.replace_view.is_open or
+                     self.tasks_view.is_open or self.is_current_note_locked()))
+
+    def show_live_preview(self) -> bool:
+        """Check whether the live preview shows next to the editor: on, editing a note, and with room for both"""
+        self.update_editor_window_width()
+        return self.live_preview_width > 0
+
+    def toggle_live_preview(self):
+        """Show or hide the note rendered next to the editor"""
+        if self.accessible:
+            self.mode_manager.set_message(t("msg.preview_accessible"))
+            return
+        self.live_preview = not self.live_preview
+        self.update_editor_window_width()
+        self.buffer.adjust_horizontal_scroll(self.editor_window_width)
+        self.mode_manager.set_message(t("msg.preview_on" if self.live_preview else "msg.preview_off"))
+
+    def get_live_preview_content(self):
+        """Get formatted text for the live preview pane: the note rendered, from the line at the top of the editor"""
+        lines = self.live_preview_pane.lines(self.buffer.current_note_id, self.buffer.get_text(),
+                                             self.live_preview_width, self._highlight_code_line)
+        start = next((i for i, (line, _) in enumerate(lines) if line >= self.buffer.scroll_offset), len(lines))
+        result = []
+        for i, (_, fragments) in enumerate(lines[start:start + self.editor_window_height]):
+            if i:
+                result.append(('', '\n'))
+            result.extend(fragments)
+        return FormattedText(result)
+
+    async def _refresh_live_preview(self, app: Application):
+        """Redraw once typing pauses, for the live preview to catch up"""
+        while True:
+            await asyncio.sleep(LIVE_PREVIEW_INTERVAL)
+            if self.live_preview_pane.is_due():
+                app.invalidate()
+
     def create_layout(self):
         """Create the UI layout with sidebar and editor"""
         # Update window height when creating layout
         self.update_editor_window_height()
 
-        # Sidebar window (note list)
+        if self.accessible:
+            return self.create_accessible_layout()
+
+        show_borders = Condition(self.show_borders)
+
+        def pane_title(sidebar: bool):
+            """Title line above a pane, drawn with borders"""
+            return ConditionalContainer(
+                Window(
+                    content=FormattedTextControl(text=lambda: self.get_pane_title_content(sidebar)),
+                    height=1,
+                    char='─',
+                    style=lambda: 'class:border.focused' if (
+                        self.focus_manager.is_sidebar_focused() == sidebar) else 'class:border',
+                    always_hide_cursor=True,
+                ),
+                filter=show_borders
+            )
+
+        # Sidebar window (note list), with the line between it and the editor
         sidebar_window = ConditionalContainer(
-            Window(
-                content=FormattedTextControl(
-                    text=self.get_sidebar_content,
-                    focusable=False,
-                    show_cursor=False,
+            VSplit([
+                HSplit([
+                    pane_title(sidebar=True),
+                    Window(
+                        content=MouseControl(
+                            text=self.get_sidebar_content,
+                            focusable=False,
+                            show_cursor=False,
+                            on_mouse=self.handle_sidebar_mouse,
+                        ),
+                        width=self.get_sidebar_width,  # Configured columns or fraction of the terminal
+                        wrap_lines=False,
+                    ),
+                ]),
+                ConditionalContainer(
+                    Window(width=1, char='│', style='class:border'),
+                    filter=show_borders
                 ),
-                width=30,  # Fixed width for sidebar
-                wrap_lines=False,
-            ),
-            filter=Condition(lambda: self.focus_manager.sidebar_visible)
+            ]),
+            filter=Condition(lambda: self.focus_manager.sidebar_visible and not self.is_zen())
         )
 
         # Main editor window
         editor_window = Window(
-            content=FormattedTextControl(
+            content=MouseControl(
                 text=self.get_text_content,
                 focusable=False,
                 show_cursor=False,
+                on_mouse=self.handle_editor_mouse,
             ),
+            width=self.get_editor_width,
             wrap_lines=False,
         )
+        metadata_header = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(text=self.get_metadata_content),
+                height=1,
+                always_hide_cursor=True,
+            ),
+            filter=Condition(self.show_metadata_header)
+        )
+        editor_pane = HSplit([pane_title(sidebar=False), metadata_header, editor_window])
+
+        # Live preview of the note, right of the editor, with the line between them
+        live_preview = ConditionalContainer(
+            VSplit([
+                ConditionalContainer(
+                    Window(width=1, char='│', style='class:border'),
+                    filter=show_borders
+                ),
+                HSplit([
+                    ConditionalContainer(
+                        Window(
+                            content=FormattedTextControl(text=lambda: [
+                                ('class:border', fit(f"─ {t('pane.preview')} ", self.live_preview_width))]),
+                            height=1,
+                            char='─',
+                            style='class:border',
+                            always_hide_cursor=True,
+                        ),
+                        filter=show_borders
+                    ),
+                    Window(
+                        content=FormattedTextControl(text=self.get_live_preview_content),
+                        wrap_lines=False,
+                    ),
+                ]),
+            ]),
+            filter=Condition(self.show_live_preview)
+        )
+
+        # Empty margins either side of the editor, centering it in zen mode
+        left_margin, right_margin = (ConditionalContainer(Window(), filter=Condition(self.is_zen)) for _ in range(2))
 
         # Status bar
         status_bar = Window(
@@ -848,27 +5088,291 @@ class EditorUI:
             always_hide_cursor=True,
         )
 
-        # Combine into layout: sidebar | editor (side by side), with status bar below
+        # Note counts, sort order, storage and sync state, under the status bar
+        status_line = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(text=self.get_status_line_content),
+                height=1,
+                always_hide_cursor=True,
+            ),
+            filter=Condition(self.show_status_line)
+        )
+
+        # Toasts, over the bottom right corner above the status bar
+        toasts = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(text=self.get_toast_content),
+                dont_extend_width=True,
+                dont_extend_height=True,
+                always_hide_cursor=True,
+            ),
+            filter=Condition(lambda: bool(self.toasts.toasts))
+        )
+
+        # Key bindings, over the middle of the screen while open
+        help_overlay = ConditionalContainer(
+            Frame(
+                Window(
+                    content=FormattedTextControl(text=self.get_help_content),
+                    height=self.get_help_height,
+                    dont_extend_width=True,
+                ),
+                title=t("help.title"),
+                style="class:dialog",
+            ),
+            filter=Condition(lambda: self.help_view.is_open)
+        )
+
+        # Quick switcher, over the middle of the screen while open
+        switcher = ConditionalContainer(
+            Frame(
+                Window(
+                    content=FormattedTextControl(text=self.get_switcher_content, show_cursor=True),
+                    width=SWITCHER_WIDTH - 2,
+                    dont_extend_height=True,
+                ),
+                title=t("switcher.title"),
+                style="class:dialog",
+            ),
+            filter=Condition(lambda: self.switcher.is_open)
+        )
+
+        # First-run tour, over the middle of the screen while open
+        tour = ConditionalContainer(
+            Frame(
+                Window(
+                    content=FormattedTextControl(text=self.get_tour_content),
+                    width=Dimension(max=TOUR_WIDTH),
+                    wrap_lines=True,
+                    dont_extend_height=True,
+                ),
+                title=t("tour.title"),
+                style="class:dialog",
+            ),
+            filter=Condition(lambda: self.tour.is_open)
+        )
+
+        # Secure notes' passphrase, over the middle of the screen while asked for
+        secure_prompt = ConditionalContainer(
+            Frame(
+                Window(
+                    content=FormattedTextControl(text=self.get_secure_prompt_content, show_cursor=True),
+                    width=Dimension(min=40),
+                    dont_extend_height=True,
+                ),
+                title=lambda: self.secure_prompt.title,
+                style="class:dialog",
+            ),
+            filter=Condition(lambda: self.secure_prompt.is_open)
+        )
+
+        # Confirmation dialog, over the middle of the screen while open
+        dialog = ConditionalContainer(
+            Frame(
+                Window(
+                    content=FormattedTextControl(text=self.get_dialog_content),
+                    dont_extend_width=True,
+                    dont_extend_height=True,
+                ),
+                title=t("dialog.title"),
+                style="class:dialog",
+            ),
+            filter=Condition(lambda: self.confirm_dialog.is_open)
+        )
+
+        # Combine into layout: sidebar | editor (side by side), with status bar and status line below
         layout = Layout(
-            HSplit([
-                VSplit([
-                    sidebar_window,
-                    editor_window,
-                ]),
-                status_bar,
-            ])
+            self._with_lock_screen(HSplit([
+                FloatContainer(
+                    content=HSplit([
+                        VSplit([
+                            sidebar_window,
+                            left_margin,
+                            editor_pane,
+                            live_preview,
+                            right_margin,
+                        ]),
+                        status_bar,
+                    ]),
+                    floats=[Float(content=toasts, bottom=1, right=1), Float(content=help_overlay), Float(content=tour),
+                            Float(content=switcher, top=2), Float(content=secure_prompt), Float(content=dialog)],
+                ),
+                status_line,
+            ]))
         )
 
         return layout
 
+    def _with_lock_screen(self, content):
+        """Show the lock screen instead of everything else while locked"""
+        if self.app_lock is None:
+            return content
+        lock_screen = Window(
+            content=FormattedTextControl(text=self.get_lock_content, show_cursor=True),
+            wrap_lines=True,
+        )
+        locked = Condition(self.is_locked)
+        return HSplit([ConditionalContainer(content, filter=~locked), ConditionalContainer(lock_screen, filter=locked)])
+
+    def create_accessible_layout(self):
+        """
+        Create the screen-reader-friendly layout
+
+        Only the focused pane is shown, full width, below a label line naming
+        it, so the screen reads linearly from top to bottom. The real terminal
+        cursor tracks the selection so screen readers can follow it.
+        """
+        pane_label = Window(
+            content=FormattedTextControl(text=self.get_pane_label_content),
+            height=1,
+            always_hide_cursor=True,
+        )
+
+        sidebar_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(
+                    text=self.get_sidebar_content,
+                    focusable=False,
+                    show_cursor=True,
+                    get_cursor_position=self.get_sidebar_cursor_position,
+                ),
+                wrap_lines=False,
+            ),
+            filter=Condition(lambda: self.focus_manager.is_sidebar_focused() and not self.is_overlay_open())
+        )
+
+        editor_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(
+                    text=self.get_text_content,
+                    focusable=False,
+                    show_cursor=True,
+                    get_cursor_position=self.get_editor_cursor_position,
+                ),
+                wrap_lines=False,
+            ),
+            filter=Condition(lambda: self.focus_manager.is_editor_focused() and not self.is_overlay_open())
+        )
+
+        help_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(
+                    text=self.get_help_content,
+                    show_cursor=True,
+                    get_cursor_position=lambda: Point(x=0, y=0),
+                ),
+                wrap_lines=True,
+            ),
+            filter=Condition(lambda: self.help_view.is_open)
+        )
+
+        tour_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(
+                    text=self.get_tour_content,
+                    show_cursor=True,
+                    get_cursor_position=lambda: Point(x=0, y=0),
+                ),
+                wrap_lines=True,
+            ),
+            filter=Condition(lambda: self.tour.is_open)
+        )
+
+        switcher_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(text=self.get_switcher_content, show_cursor=True),
+                wrap_lines=False,
+            ),
+            filter=Condition(lambda: self.switcher.is_open)
+        )
+
+        secure_prompt_window = ConditionalContainer(
+            Window(
+                content=FormattedTextControl(text=self.get_secure_prompt_content, show_cursor=True),
+                wrap_lines=True,
+            ),
+            filter=Condition(lambda: self.secure_prompt.is_open)
+        )
+
+        status_bar = Window(
+            content=FormattedTextControl(
+                text=self.get_status_bar_content,
+            ),
+            height=1,
+            always_hide_cursor=True,
+        )
+
+        return Layout(
+            self._with_lock_screen(HSplit([
+                pane_label,
+                sidebar_window,
+                editor_window,
+                help_window,
+                tour_window,
+                switcher_window,
+                secure_prompt_window,
+                status_bar,
+            ]))
+        )
+
     def run(self):
         """Run the editor application"""
         app = Application(
             layout=self.create_layout(),
             key_bindings=self.kb,
-            full_screen=True,
-            mouse_support=False,
+            style=self.theme.style(),
+            full_screen=self.alt_screen,
+            mouse_support=self.mouse and not self.accessible,
         )
         app.ttimeoutlen = 0.05
+        self.toasts.on_change = app.invalidate  # Toasts may come from other threads
+        if self.app_lock is not None:
+            # Any key counts as activity, putting off the idle lock
+            app.key_processor.before_key_press += lambda sender: self.app_lock.touch()
+
+        def start_watching():
+            app.create_background_task(self._expire_toasts(app))
+            app.create_background_task(self._refresh_live_preview(app))
+            if sync is not None:
+                app.create_background_task(self._sync_on_start(app))
+            if self.draft_interval:
+                app.create_background_task(self._save_drafts())
+            if self.live_reload:
+                app.create_background_task(self._watch_storage(app))
+            if self.reminders_notify != "off":
+                app.create_background_task(self._watch_reminders(app))
+            if self.app_lock is not None and self.app_lock.idle_timeout:
+                app.create_background_task(self._watch_idle(app))
+            self._exit_on_signals(app)
+
+        hooks = self.storage.hooks
+        if hooks:
+            # Hooks finish on their own threads; show failures as toasts
+            hooks.on_error = self.toasts.error
+            hooks.run("start")
+
+        composite = find_backend(self.storage, CompositeBackend)
+        if composite is not None:
+            # Changes are written in the background; show failed writes as toasts
+            composite.on_write_error = self.toasts.error
+        sync = find_sync_backend(self.storage)
+        if sync is not None:
+            # Changes are pushed to the server in the background too
+            sync.on_sync_error = self.toasts.error
 
-        app.run()
+        try:
+            app.run(pre_run=start_watching)
+        finally:
+            # Keep unsaved edits in the draft file, and flush any deferred writes, before exiting
+            if self.draft_interval and not self.discarding_edits:
+                self.save_draft()
+            if composite is not None:
+                composite.on_write_error = composite.print_write_error
+            if sync is not None:
+                sync.on_sync_error = sync.print_sync_error
+            self.storage.close()
+            self.lock.release()
+            if hooks:
+                hooks.on_error = hooks.print_error
+                hooks.run("exit")
+                hooks.wait()